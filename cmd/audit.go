@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
 	"time"
 
 	"github.com/scotttball/tunatap/internal/audit"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/output"
+	"github.com/scotttball/tunatap/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -20,13 +28,66 @@ with timestamps, durations, and cluster information.`,
 var auditListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List recent audit events",
-	RunE:  runAuditList,
+	Long: `List audit events, oldest first, filtered by time range, cluster and
+event type.
+
+--since and --until take a duration before now, such as 24h or 7d, or a
+date, such as 2024-01-01 or 2024-01-01T15:04.
+
+Examples:
+  tunatap audit list --since 7d --cluster prod
+  tunatap audit list --since 2024-01-01 --until 2024-02-01 --type error
+  tunatap audit list --since 30d -n 0 -o csv > events.csv`,
+	Args: cobra.NoArgs,
+	RunE: runAuditList,
 }
 
 var auditSummaryCmd = &cobra.Command{
 	Use:   "summary",
 	Short: "Show audit summary statistics",
-	RunE:  runAuditSummary,
+	Long: `Show the connections, time connected and errors per cluster since
+--since, which takes a duration before now or a date as for 'audit list'.
+
+Examples:
+  tunatap audit summary --since 30d
+  tunatap audit summary --since 2024-01-01 --until 2024-02-01 -o csv`,
+	Args: cobra.NoArgs,
+	RunE: runAuditSummary,
+}
+
+var auditReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show the usage of a month",
+	Long: `Show a usage report of one calendar month, in local time: the connections,
+total tunnel time, traffic, peak concurrent connections, errors and error
+rate of each cluster. The error rate
+is the share of connections that ended in an error.
+
+Examples:
+  tunatap audit report                   # This month
+  tunatap audit report --month 2024-05
+  tunatap audit report --month 2024-05 -o csv`,
+	Args: cobra.NoArgs,
+	RunE: runAuditReport,
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the audit log for changed or removed records",
+	Long: `Check the hash chain of the audit log, written with audit_hash_chain: true,
+and the signatures of its checkpoints. Each record holds the hash of the one
+before it, so changing, removing or inserting a record breaks the chain at
+the next one; checkpoints sign the chain so far with the key in
+~/.tunatap/audit_key.
+
+Records after the last checkpoint are listed as unsigned: removing them
+cannot be detected. The command exits with code 7 when a problem is found.
+
+Examples:
+  tunatap audit verify
+  tunatap audit verify --key /secure/audit_key.pub -o json`,
+	Args: cobra.NoArgs,
+	RunE: runAuditVerify,
 }
 
 var auditShowCmd = &cobra.Command{
@@ -37,11 +98,16 @@ var auditShowCmd = &cobra.Command{
 }
 
 var (
-	auditLimit     int
-	auditCluster   string
-	auditSince     string
-	auditEventType string
-	auditJSON      bool
+	auditLimit        int
+	auditCluster      string
+	auditSince        string
+	auditUntil        string
+	auditEventType    string
+	auditJSON         bool
+	auditOutput       string
+	auditSummarySince string
+	auditReportMonth  string
+	auditVerifyKey    string
 )
 
 func init() {
@@ -49,18 +115,108 @@ func init() {
 
 	auditCmd.AddCommand(auditListCmd)
 	auditCmd.AddCommand(auditSummaryCmd)
+	auditCmd.AddCommand(auditReportCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
 	auditCmd.AddCommand(auditShowCmd)
 
-	auditListCmd.Flags().IntVarP(&auditLimit, "limit", "n", 50, "number of events to show")
+	auditListCmd.Flags().IntVarP(&auditLimit, "limit", "n", 50, "number of events to show, the most recent (0 = all)")
 	auditListCmd.Flags().StringVarP(&auditCluster, "cluster", "c", "", "filter by cluster name")
 	auditListCmd.Flags().StringVar(&auditSince, "since", "", "show events since (e.g., '24h', '7d', '2024-01-01')")
-	auditListCmd.Flags().StringVarP(&auditEventType, "type", "t", "", "filter by event type (connect, disconnect, error)")
+	auditListCmd.Flags().StringVar(&auditUntil, "until", "", "show events until (e.g., '24h', '2024-02-01')")
+	auditListCmd.Flags().StringVarP(&auditEventType, "type", "t", "", "filter by event type (connect, disconnect, error, session_refresh, exec)")
 	auditListCmd.Flags().BoolVar(&auditJSON, "json", false, "output as JSON")
+	_ = auditListCmd.Flags().MarkDeprecated("json", "use --output json")
+	addTabularOutputFlag(auditListCmd, &auditOutput)
+
+	auditSummaryCmd.Flags().StringVar(&auditSummarySince, "since", "7d", "summary period (e.g., '24h', '7d', '30d', '2024-01-01')")
+	auditSummaryCmd.Flags().StringVar(&auditUntil, "until", "", "end of the summary period (default: now)")
+	auditSummaryCmd.Flags().StringVarP(&auditCluster, "cluster", "c", "", "only summarize this cluster")
+	addTabularOutputFlag(auditSummaryCmd, &auditOutput)
+
+	auditReportCmd.Flags().StringVar(&auditReportMonth, "month", "", "month to report on, as YYYY-MM (default: this month)")
+	auditReportCmd.Flags().StringVarP(&auditCluster, "cluster", "c", "", "only report on this cluster")
+	addTabularOutputFlag(auditReportCmd, &auditOutput)
+
+	auditVerifyCmd.Flags().StringVar(&auditVerifyKey, "key", "", "public key to verify checkpoints with (default: ~/.tunatap/audit_key.pub)")
+	addOutputFlag(auditVerifyCmd, &auditOutput)
+
+	addOutputFlag(auditShowCmd, &auditOutput)
+}
+
+// auditSummaryOutput is the structured output of 'audit summary'.
+type auditSummaryOutput struct {
+	Since                time.Time            `json:"since" yaml:"since"`
+	TotalConnections     int                  `json:"total_connections" yaml:"total_connections"`
+	TotalDurationSeconds int64                `json:"total_duration_seconds" yaml:"total_duration_seconds"`
+	BytesIn              int64                `json:"bytes_in" yaml:"bytes_in"`
+	BytesOut             int64                `json:"bytes_out" yaml:"bytes_out"`
+	Errors               int                  `json:"errors" yaml:"errors"`
+	ErrorRate            float64              `json:"error_rate" yaml:"error_rate"`
+	Clusters             []auditClusterOutput `json:"clusters" yaml:"clusters"`
+}
+
+// auditReportOutput is the structured output of 'audit report'.
+type auditReportOutput struct {
+	Month              string    `json:"month" yaml:"month"`
+	Until              time.Time `json:"until" yaml:"until"`
+	auditSummaryOutput `yaml:",inline"`
+}
+
+// auditClusterOutput is the summary of one cluster in auditSummaryOutput.
+type auditClusterOutput struct {
+	Name                 string     `json:"name" yaml:"name"`
+	Connections          int        `json:"connections" yaml:"connections"`
+	TotalDurationSeconds int64      `json:"total_duration_seconds" yaml:"total_duration_seconds"`
+	BytesIn              int64      `json:"bytes_in" yaml:"bytes_in"`
+	BytesOut             int64      `json:"bytes_out" yaml:"bytes_out"`
+	PeakConnections      int64      `json:"peak_connections" yaml:"peak_connections"`
+	Errors               int        `json:"errors" yaml:"errors"`
+	ErrorRate            float64    `json:"error_rate" yaml:"error_rate"`
+	LastAccess           *time.Time `json:"last_access,omitempty" yaml:"last_access,omitempty"`
+}
 
-	auditSummaryCmd.Flags().StringVar(&auditSince, "since", "7d", "summary period (e.g., '24h', '7d', '30d')")
+// newAuditSummaryOutput converts a summary to its structured output, with
+// clusters sorted by name.
+func newAuditSummaryOutput(since time.Time, summary *audit.Summary) auditSummaryOutput {
+	out := auditSummaryOutput{
+		Since:                since,
+		TotalConnections:     summary.TotalConnections,
+		TotalDurationSeconds: int64(summary.TotalDuration.Round(time.Second).Seconds()),
+		BytesIn:              summary.BytesIn,
+		BytesOut:             summary.BytesOut,
+		Errors:               summary.ErrorCount,
+		ErrorRate:            summary.ErrorRate(),
+		Clusters:             []auditClusterOutput{},
+	}
+	for name, stat := range summary.ClusterStats {
+		c := auditClusterOutput{
+			Name:                 name,
+			Connections:          stat.ConnectionCount,
+			TotalDurationSeconds: int64(stat.TotalDuration.Round(time.Second).Seconds()),
+			BytesIn:              stat.BytesIn,
+			BytesOut:             stat.BytesOut,
+			PeakConnections:      stat.PeakConnections,
+			Errors:               stat.ErrorCount,
+			ErrorRate:            stat.ErrorRate(),
+		}
+		if !stat.LastAccess.IsZero() {
+			lastAccess := stat.LastAccess
+			c.LastAccess = &lastAccess
+		}
+		out.Clusters = append(out.Clusters, c)
+	}
+	sort.Slice(out.Clusters, func(i, j int) bool {
+		return out.Clusters[i].Name < out.Clusters[j].Name
+	})
+	return out
 }
 
 func runAuditList(cmd *cobra.Command, args []string) error {
+	format, err := tabularOutputFormat(auditOutput, auditJSON)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+
 	logDir := audit.DefaultLogDir()
 
 	// Build query
@@ -76,10 +232,17 @@ func runAuditList(cmd *cobra.Command, args []string) error {
 	if auditSince != "" {
 		startTime, err := parseSince(auditSince)
 		if err != nil {
-			return fmt.Errorf("invalid --since value: %w", err)
+			return exitcode.Wrap(exitcode.Usage, fmt.Errorf("invalid --since value: %w", err))
 		}
 		q.StartTime = startTime
 	}
+	if auditUntil != "" {
+		endTime, err := parseSince(auditUntil)
+		if err != nil {
+			return exitcode.Wrap(exitcode.Usage, fmt.Errorf("invalid --until value: %w", err))
+		}
+		q.EndTime = endTime
+	}
 
 	// Query logs
 	events, err := audit.QueryLogs(logDir, q)
@@ -87,68 +250,92 @@ func runAuditList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to query audit logs: %w", err)
 	}
 
+	if format == output.CSV {
+		return output.WriteCSV(os.Stdout, auditEventHeader, auditEventRows(events))
+	}
+	if format.Structured() {
+		if events == nil {
+			events = []audit.AuditEvent{}
+		}
+		return output.Write(os.Stdout, format, events)
+	}
+
 	if len(events) == 0 {
 		fmt.Println("No audit events found")
 		return nil
 	}
 
-	if auditJSON {
-		// JSON output
-		for i := range events {
-			fmt.Printf("%+v\n", events[i])
-		}
-	} else {
-		// Human-readable output
-		for i := range events {
-			fmt.Println(audit.FormatEvent(&events[i]))
-		}
+	for i := range events {
+		fmt.Println(audit.FormatEvent(&events[i]))
 	}
 
 	return nil
 }
 
 func runAuditSummary(cmd *cobra.Command, args []string) error {
+	format, err := tabularOutputFormat(auditOutput, false)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+
 	logDir := audit.DefaultLogDir()
 
 	// Parse since parameter
-	startTime, err := parseSince(auditSince)
+	startTime, err := parseSince(auditSummarySince)
 	if err != nil {
-		return fmt.Errorf("invalid --since value: %w", err)
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("invalid --since value: %w", err))
 	}
 
 	// Query logs
-	q := audit.Query{StartTime: startTime}
+	q := audit.Query{StartTime: startTime, ClusterName: auditCluster}
+	if auditUntil != "" {
+		endTime, err := parseSince(auditUntil)
+		if err != nil {
+			return exitcode.Wrap(exitcode.Usage, fmt.Errorf("invalid --until value: %w", err))
+		}
+		q.EndTime = endTime
+	}
 	events, err := audit.QueryLogs(logDir, q)
 	if err != nil {
 		return fmt.Errorf("failed to query audit logs: %w", err)
 	}
 
+	// Generate summary
+	summary := audit.GetSummary(events)
+	out := newAuditSummaryOutput(*startTime, summary)
+
+	if format == output.CSV {
+		return output.WriteCSV(os.Stdout, auditClusterHeader, auditClusterRows(out.Clusters))
+	}
+	if format.Structured() {
+		return output.Write(os.Stdout, format, out)
+	}
+
 	if len(events) == 0 {
 		fmt.Println("No audit events found for the specified period")
 		return nil
 	}
 
-	// Generate summary
-	summary := audit.GetSummary(events)
-
 	fmt.Printf("Audit Summary (since %s)\n", startTime.Local().Format("2006-01-02 15:04"))
 	fmt.Println("========================")
 	fmt.Printf("Total connections: %d\n", summary.TotalConnections)
 	fmt.Printf("Total time connected: %s\n", summary.TotalDuration.Round(time.Second))
+	fmt.Printf("Traffic: %s in, %s out\n", utils.FormatBytes(summary.BytesIn), utils.FormatBytes(summary.BytesOut))
 	fmt.Printf("Errors: %d\n", summary.ErrorCount)
 	fmt.Println()
 
 	if len(summary.ClusterStats) > 0 {
 		fmt.Println("By Cluster:")
-		for name, stat := range summary.ClusterStats {
-			fmt.Printf("  %s:\n", name)
-			fmt.Printf("    Connections: %d\n", stat.ConnectionCount)
-			fmt.Printf("    Total time: %s\n", stat.TotalDuration.Round(time.Second))
-			if stat.ErrorCount > 0 {
-				fmt.Printf("    Errors: %d\n", stat.ErrorCount)
+		for _, c := range out.Clusters {
+			fmt.Printf("  %s:\n", c.Name)
+			fmt.Printf("    Connections: %d\n", c.Connections)
+			fmt.Printf("    Total time: %s\n", time.Duration(c.TotalDurationSeconds)*time.Second)
+			fmt.Printf("    Traffic: %s in, %s out\n", utils.FormatBytes(c.BytesIn), utils.FormatBytes(c.BytesOut))
+			if c.Errors > 0 {
+				fmt.Printf("    Errors: %d\n", c.Errors)
 			}
-			if !stat.LastAccess.IsZero() {
-				fmt.Printf("    Last access: %s\n", stat.LastAccess.Local().Format("2006-01-02 15:04"))
+			if c.LastAccess != nil {
+				fmt.Printf("    Last access: %s\n", c.LastAccess.Local().Format("2006-01-02 15:04"))
 			}
 		}
 	}
@@ -156,7 +343,116 @@ func runAuditSummary(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runAuditReport(cmd *cobra.Command, args []string) error {
+	format, err := tabularOutputFormat(auditOutput, false)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+	start, end, err := parseMonth(auditReportMonth, time.Now())
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+
+	// The end is exclusive, and Query's inclusive
+	last := end.Add(-time.Nanosecond)
+	q := audit.Query{StartTime: &start, EndTime: &last, ClusterName: auditCluster}
+	events, err := audit.QueryLogs(audit.DefaultLogDir(), q)
+	if err != nil {
+		return fmt.Errorf("failed to query audit logs: %w", err)
+	}
+
+	out := auditReportOutput{
+		Month:              start.Format("2006-01"),
+		Until:              end,
+		auditSummaryOutput: newAuditSummaryOutput(start, audit.GetSummary(events)),
+	}
+
+	if format == output.CSV {
+		rows := auditClusterRows(out.Clusters)
+		for i := range rows {
+			rows[i] = append([]string{out.Month}, rows[i]...)
+		}
+		return output.WriteCSV(os.Stdout, append([]string{"month"}, auditClusterHeader...), rows)
+	}
+	if format.Structured() {
+		return output.Write(os.Stdout, format, out)
+	}
+
+	fmt.Printf("Usage report for %s\n\n", start.Format("January 2006"))
+	if len(out.Clusters) == 0 {
+		fmt.Println("No connections this month")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tCONNECTIONS\tTUNNEL TIME\tIN\tOUT\tPEAK CONNS\tERRORS\tERROR RATE")
+	for _, c := range out.Clusters {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%d\t%d\t%s\n", c.Name, c.Connections,
+			formatDuration(time.Duration(c.TotalDurationSeconds)*time.Second), utils.FormatBytes(c.BytesIn), utils.FormatBytes(c.BytesOut),
+			c.PeakConnections, c.Errors, formatRate(c.ErrorRate))
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%s\t%s\t%s\t\t%d\t%s\n", out.TotalConnections,
+		formatDuration(time.Duration(out.TotalDurationSeconds)*time.Second), utils.FormatBytes(out.BytesIn), utils.FormatBytes(out.BytesOut),
+		out.Errors, formatRate(out.ErrorRate))
+	return w.Flush()
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(auditOutput, false)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+
+	keyPath := auditVerifyKey
+	if keyPath == "" {
+		keyPath = audit.DefaultKeyPath() + ".pub"
+	}
+	if _, err := os.Stat(keyPath); errors.Is(err, os.ErrNotExist) {
+		return exitcode.Wrap(exitcode.NotFound, fmt.Errorf("no audit public key at %s; it is created once audit_hash_chain: true is set, or pass --key", keyPath))
+	}
+	key, err := audit.LoadPublicKey(keyPath)
+	if err != nil {
+		return err
+	}
+
+	result, err := audit.VerifyLogs(audit.DefaultLogDir(), key)
+	if err != nil {
+		return fmt.Errorf("failed to verify audit logs: %w", err)
+	}
+
+	if format.Structured() {
+		if err := output.Write(os.Stdout, format, result); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Records: %d (%d chained)\n", result.Records, result.Chained)
+		fmt.Printf("Checkpoints: %d", result.Checkpoints)
+		if result.LastCheckpoint != nil {
+			fmt.Printf(", the last at %s", result.LastCheckpoint.Local().Format("2006-01-02 15:04:05"))
+		}
+		fmt.Println()
+		if result.Unsigned > 0 {
+			fmt.Printf("Unsigned records after the last checkpoint: %d\n", result.Unsigned)
+		}
+		for _, problem := range result.Problems {
+			fmt.Printf("  ✗ %s\n", problem)
+		}
+		if result.OK() {
+			fmt.Println("Audit log verified")
+		}
+	}
+
+	if !result.OK() {
+		return exitcode.Wrap(exitcode.PreflightFailed, fmt.Errorf("audit log verification found %d problems", len(result.Problems)))
+	}
+	return nil
+}
+
 func runAuditShow(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(auditOutput, false)
+	if err != nil {
+		return err
+	}
+
 	sessionID := args[0]
 	logDir := audit.DefaultLogDir()
 
@@ -171,6 +467,10 @@ func runAuditShow(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("session '%s' not found", sessionID)
 	}
 
+	if format.Structured() {
+		return output.Write(os.Stdout, format, events)
+	}
+
 	fmt.Printf("Session: %s\n", sessionID)
 	fmt.Println("=========")
 	for i := range events {
@@ -215,3 +515,80 @@ func parseDuration(s string) (time.Duration, error) {
 
 	return time.ParseDuration(s)
 }
+
+// parseMonth parses a --month value, YYYY-MM, and returns the start of that
+// month and of the next in local time. An empty value is the month of now.
+func parseMonth(s string, now time.Time) (time.Time, time.Time, error) {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	if s != "" {
+		t, err := time.ParseInLocation("2006-01", s, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --month value '%s', expected YYYY-MM", s)
+		}
+		start = t
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// formatRate formats a rate from 0 to 1 as a percentage.
+func formatRate(rate float64) string {
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
+
+// auditEventHeader is the CSV header of audit events.
+var auditEventHeader = []string{
+	"timestamp", "event_type", "session_id", "cluster_name", "region", "local_port",
+	"duration_seconds", "bytes_in", "bytes_out", "peak_connections", "error", "command", "exit_code", "user",
+}
+
+// auditEventRows converts events to CSV rows, in the columns of
+// auditEventHeader.
+func auditEventRows(events []audit.AuditEvent) [][]string {
+	rows := make([][]string, 0, len(events))
+	for _, e := range events {
+		port, duration, bytesIn, bytesOut, peak, exitCode := "", "", "", "", "", ""
+		if e.LocalPort != 0 {
+			port = strconv.Itoa(e.LocalPort)
+		}
+		if e.Duration != nil {
+			duration = strconv.FormatInt(int64(e.Duration.Round(time.Second).Seconds()), 10)
+		}
+		if e.BytesIn != 0 || e.BytesOut != 0 || e.PeakConnections != 0 {
+			bytesIn = strconv.FormatInt(e.BytesIn, 10)
+			bytesOut = strconv.FormatInt(e.BytesOut, 10)
+			peak = strconv.FormatInt(e.PeakConnections, 10)
+		}
+		if e.ExitCode != nil {
+			exitCode = strconv.Itoa(*e.ExitCode)
+		}
+		rows = append(rows, []string{
+			e.Timestamp.UTC().Format(time.RFC3339), string(e.EventType), e.SessionID, e.ClusterName, e.Region, port,
+			duration, bytesIn, bytesOut, peak, e.Error, e.Command, exitCode, e.User,
+		})
+	}
+	return rows
+}
+
+// auditClusterHeader is the CSV header of per-cluster summaries.
+var auditClusterHeader = []string{
+	"cluster", "connections", "total_duration_seconds", "bytes_in", "bytes_out", "peak_connections",
+	"errors", "error_rate", "last_access",
+}
+
+// auditClusterRows converts per-cluster summaries to CSV rows, in the
+// columns of auditClusterHeader.
+func auditClusterRows(clusters []auditClusterOutput) [][]string {
+	rows := make([][]string, 0, len(clusters))
+	for _, c := range clusters {
+		lastAccess := ""
+		if c.LastAccess != nil {
+			lastAccess = c.LastAccess.UTC().Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			c.Name, strconv.Itoa(c.Connections), strconv.FormatInt(c.TotalDurationSeconds, 10),
+			strconv.FormatInt(c.BytesIn, 10), strconv.FormatInt(c.BytesOut, 10), strconv.FormatInt(c.PeakConnections, 10),
+			strconv.Itoa(c.Errors), strconv.FormatFloat(c.ErrorRate, 'f', 4, 64), lastAccess,
+		})
+	}
+	return rows
+}
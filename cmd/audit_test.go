@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/audit"
+	"github.com/scotttball/tunatap/internal/output"
+)
+
+func TestNewAuditSummaryOutput(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastAccess := since.Add(time.Hour)
+	summary := &audit.Summary{
+		TotalConnections: 3,
+		TotalDuration:    90*time.Second + 400*time.Millisecond,
+		ErrorCount:       1,
+		ClusterStats: map[string]audit.ClusterStat{
+			"staging": {ConnectionCount: 1, TotalDuration: 30 * time.Second},
+			"prod":    {ConnectionCount: 2, TotalDuration: time.Minute, BytesIn: 2048, BytesOut: 512, PeakConnections: 3, ErrorCount: 1, LastAccess: lastAccess},
+		},
+	}
+
+	out := newAuditSummaryOutput(since, summary)
+	if out.TotalConnections != 3 || out.Errors != 1 || out.TotalDurationSeconds != 90 {
+		t.Errorf("totals = %+v", out)
+	}
+	if len(out.Clusters) != 2 || out.Clusters[0].Name != "prod" || out.Clusters[1].Name != "staging" {
+		t.Fatalf("clusters not sorted by name: %+v", out.Clusters)
+	}
+	if out.Clusters[0].LastAccess == nil || !out.Clusters[0].LastAccess.Equal(lastAccess) {
+		t.Errorf("prod last access = %v", out.Clusters[0].LastAccess)
+	}
+	if out.Clusters[1].LastAccess != nil {
+		t.Errorf("staging last access = %v, want nil", out.Clusters[1].LastAccess)
+	}
+	if out.Clusters[0].ErrorRate != 0.5 || out.Clusters[1].ErrorRate != 0 {
+		t.Errorf("error rates = %v and %v, want 0.5 and 0", out.Clusters[0].ErrorRate, out.Clusters[1].ErrorRate)
+	}
+
+	rows := auditClusterRows(out.Clusters)
+	if got := strings.Join(rows[0], ","); got != "prod,2,60,2048,512,3,1,0.5000,2024-01-01T01:00:00Z" {
+		t.Errorf("prod CSV row = %q", got)
+	}
+	if len(rows[1]) != len(auditClusterHeader) || rows[1][8] != "" {
+		t.Errorf("staging CSV row = %q", rows[1])
+	}
+}
+
+func TestAuditEventRows(t *testing.T) {
+	duration := 90 * time.Second
+	exitCode := 2
+	rows := auditEventRows([]audit.AuditEvent{
+		{Timestamp: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC), EventType: audit.EventTypeDisconnect, SessionID: "s1", ClusterName: "prod", LocalPort: 6443, Duration: &duration, BytesIn: 4096, BytesOut: 100, PeakConnections: 2},
+		{EventType: audit.EventTypeExec, ClusterName: "prod", Command: "kubectl get pods", ExitCode: &exitCode},
+	})
+
+	if got := strings.Join(rows[0], ","); got != "2024-05-01T09:00:00Z,disconnect,s1,prod,,6443,90,4096,100,2,,,," {
+		t.Errorf("disconnect CSV row = %q", got)
+	}
+	if rows[1][11] != "kubectl get pods" || rows[1][12] != "2" || rows[1][5] != "" || rows[1][7] != "" {
+		t.Errorf("exec CSV row = %q", rows[1])
+	}
+	for _, row := range rows {
+		if len(row) != len(auditEventHeader) {
+			t.Errorf("row has %d columns, header %d", len(row), len(auditEventHeader))
+		}
+	}
+}
+
+func TestParseMonth(t *testing.T) {
+	now := time.Date(2024, 12, 15, 10, 0, 0, 0, time.Local)
+
+	start, end, err := parseMonth("", now)
+	if err != nil || !start.Equal(time.Date(2024, 12, 1, 0, 0, 0, 0, time.Local)) || !end.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("parseMonth(\"\") = %v, %v, %v, want December 2024", start, end, err)
+	}
+	start, end, err = parseMonth("2024-02", now)
+	if err != nil || !start.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)) || !end.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("parseMonth(2024-02) = %v, %v, %v", start, end, err)
+	}
+	if _, _, err := parseMonth("2024-13", now); err == nil {
+		t.Error("parseMonth(2024-13) should fail")
+	}
+}
+
+func TestTabularOutputFormat(t *testing.T) {
+	if f, err := tabularOutputFormat("CSV", false); err != nil || f != output.CSV {
+		t.Errorf("tabularOutputFormat(CSV) = %q, %v", f, err)
+	}
+	if f, err := tabularOutputFormat("csv", true); err != nil || f != output.JSON {
+		t.Errorf("--json should win over --output csv, got %q, %v", f, err)
+	}
+	if _, err := tabularOutputFormat("xml", false); err == nil || !strings.Contains(err.Error(), "csv") {
+		t.Errorf("tabularOutputFormat(xml) error = %v", err)
+	}
+	if _, err := outputFormat("csv", false); err == nil {
+		t.Error("outputFormat should not accept csv")
+	}
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ocibastion "github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bastionAllowCIDRs []string
+	bastionSubnet     string
+	bastionTTL        time.Duration
+	bastionRegionHint string
+	bastionExpired    bool
+)
+
+var bastionCmd = &cobra.Command{
+	Use:   "bastion",
+	Short: "Create and delete bastions for clusters",
+	Long: `Commands for managing bastions created by tunatap.
+
+Bastions created by tunatap are tagged so that 'tunatap bastion delete' only
+ever removes its own bastions.`,
+}
+
+var bastionCreateCmd = &cobra.Command{
+	Use:   "create <cluster>",
+	Short: "Create a standard bastion for a cluster",
+	Long: `Create a standard bastion in the cluster's compartment that reaches the
+cluster's API endpoint subnet, and wait for it to become active.
+
+Only the client CIDR blocks from --allow-cidr (or bastion_allow_cidrs in
+config) may connect to the bastion. With --ttl (or bastion_ttl_hours) the
+bastion is tagged to expire, and 'tunatap bastion delete --expired' removes
+it once that time has passed.
+
+Examples:
+  tunatap bastion create prod-cluster --allow-cidr 203.0.113.7/32
+  tunatap bastion create prod-cluster --allow-cidr 203.0.113.0/24 --ttl 8h`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBastionCreate,
+}
+
+var bastionDeleteCmd = &cobra.Command{
+	Use:   "delete <cluster|bastion-ocid>",
+	Short: "Delete bastions created by tunatap",
+	Long: `Delete the bastions tunatap created for a cluster, or a single bastion by
+OCID. Bastions not created by tunatap are never deleted.
+
+With --expired, only bastions whose TTL has passed are deleted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBastionDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(bastionCmd)
+	bastionCmd.AddCommand(bastionCreateCmd)
+	bastionCmd.AddCommand(bastionDeleteCmd)
+
+	bastionCreateCmd.Flags().StringArrayVar(&bastionAllowCIDRs, "allow-cidr", nil, "client CIDR block allowed to connect (repeatable; overrides bastion_allow_cidrs in config)")
+	bastionCreateCmd.Flags().StringVar(&bastionSubnet, "subnet", "", "subnet OCID for the bastion (default: the cluster's API endpoint subnet)")
+	bastionCreateCmd.Flags().DurationVar(&bastionTTL, "ttl", 0, "tag the bastion to expire after this long, e.g. 8h (overrides bastion_ttl_hours in config)")
+	bastionCreateCmd.Flags().StringVarP(&bastionRegionHint, "region", "r", "", "region hint for cluster discovery (optional)")
+
+	bastionDeleteCmd.Flags().BoolVar(&bastionExpired, "expired", false, "only delete bastions whose TTL has passed")
+	bastionDeleteCmd.Flags().StringVarP(&bastionRegionHint, "region", "r", "", "region hint for cluster discovery (optional)")
+}
+
+func runBastionCreate(cmd *cobra.Command, args []string) error {
+	cfg := loadBastionConfig()
+
+	discovered, ociClient, err := discoverClusterOnly(cmd.Context(), cfg, args[0], bastionRegionHint)
+	if err != nil {
+		return err
+	}
+
+	existing, err := discovery.NewDiscoverer(ociClient, nil).DiscoverBastion(cmd.Context(), discovered)
+	if err == nil {
+		return fmt.Errorf("cluster '%s' already has bastion '%s' (%s)", discovered.Name, existing.Name, existing.OCID)
+	}
+	if !errors.Is(err, discovery.ErrNoBastionFound) {
+		return fmt.Errorf("failed to discover bastion: %w", err)
+	}
+
+	if bastionSubnet != "" {
+		discovered.SubnetID = bastionSubnet
+	}
+	ttl := bastionTTL
+	if ttl == 0 {
+		ttl = time.Duration(cfg.GetBastionTTLHours()) * time.Hour
+	}
+
+	created, err := createClusterBastion(cmd.Context(), cfg, ociClient, discovered, bastionAllowCIDRs, ttl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bastion '%s' is active: %s\n", *created.Name, *created.Id)
+	if expires := created.FreeformTags[bastion.ExpiresTag]; expires != "" {
+		fmt.Printf("It expires at %s; remove it with: tunatap bastion delete %s --expired\n", expires, discovered.Name)
+	} else {
+		fmt.Printf("Remove it with: tunatap bastion delete %s\n", discovered.Name)
+	}
+	return nil
+}
+
+func runBastionDelete(cmd *cobra.Command, args []string) error {
+	cfg := loadBastionConfig()
+	ref := args[0]
+
+	var (
+		ociClient   *client.OCIClient
+		bastions    []ocibastion.BastionSummary
+		clusterName string
+		err         error
+	)
+	if utils.IsBastionOCID(ref) {
+		ociClient, err = cluster.NewDiscoveryClient(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI client: %w", err)
+		}
+		region := bastionRegionHint
+		if region == "" {
+			region = utils.ExtractRegionFromOCID(ref)
+		}
+		ociClient = ociClient.WithRegion(region)
+
+		b, err := ociClient.GetBastion(cmd.Context(), ref)
+		if err != nil {
+			return err
+		}
+		bastions = []ocibastion.BastionSummary{{Id: b.Id, Name: b.Name, FreeformTags: b.FreeformTags}}
+		clusterName = b.FreeformTags[bastion.ClusterTag]
+	} else {
+		var discovered *discovery.DiscoveredCluster
+		discovered, ociClient, err = discoverClusterOnly(cmd.Context(), cfg, ref, bastionRegionHint)
+		if err != nil {
+			return err
+		}
+		clusterName = discovered.Name
+
+		bastions, err = bastion.CreatedBastions(cmd.Context(), ociClient, discovered.CompartmentID, discovered.Name)
+		if err != nil {
+			return err
+		}
+		if len(bastions) == 0 {
+			fmt.Printf("No bastions created by tunatap for cluster '%s'.\n", discovered.Name)
+			return nil
+		}
+	}
+
+	now := time.Now()
+	deleted := 0
+	for _, b := range bastions {
+		if bastionExpired && !bastion.IsExpired(b.FreeformTags, now) {
+			log.Info().Msgf("Bastion %s has not expired, skipping", *b.Id)
+			continue
+		}
+		if err := bastion.DeleteBastion(cmd.Context(), ociClient, *b.Id); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted bastion %s\n", *b.Id)
+		deleted++
+	}
+
+	// Drop the cached bastion so discovery does not keep returning it
+	if deleted > 0 && clusterName != "" {
+		if cache, err := discovery.OpenCache(cfg); err == nil {
+			if err := cache.Invalidate(clusterName); err != nil {
+				log.Warn().Err(err).Msg("Failed to invalidate discovery cache")
+			}
+		}
+	}
+	return nil
+}
+
+// loadBastionConfig reads the config file, falling back to defaults.
+func loadBastionConfig() *config.Config {
+	cfg, err := config.ReadConfig(GetConfigFile())
+	if err != nil {
+		log.Debug().Msg("No config file found, using defaults")
+		return config.DefaultConfig()
+	}
+	return cfg
+}
+
+// discoverClusterOnly looks a cluster up by name or OCID without resolving
+// its bastion. The returned client is the one for the cluster's region.
+func discoverClusterOnly(ctx context.Context, cfg *config.Config, name, region string) (*discovery.DiscoveredCluster, *client.OCIClient, error) {
+	ociClient, err := cluster.NewDiscoveryClient(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OCI client: %w", err)
+	}
+
+	discoverer := cluster.NewDiscoverer(ociClient, nil)
+	var discovered *discovery.DiscoveredCluster
+	if discovery.IsClusterOCID(name) {
+		discovered, err = discoverer.DiscoverClusterByOCID(ctx, name)
+	} else {
+		var hints *discovery.DiscoveryHints
+		hints, err = cluster.NewDiscoveryHints(cfg, region)
+		if err != nil {
+			return nil, nil, err
+		}
+		discovered, err = discoverer.DiscoverClusterWithHints(ctx, name, hints)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return discovered, ociClient.WithRegion(discovered.Region), nil
+}
+
+// createClusterBastion creates a standard bastion reaching the discovered
+// cluster's subnet. allowCIDRs falls back to bastion_allow_cidrs in config.
+func createClusterBastion(ctx context.Context, cfg *config.Config, ociClient client.OCIClientInterface, discovered *discovery.DiscoveredCluster, allowCIDRs []string, ttl time.Duration) (*ocibastion.Bastion, error) {
+	if len(allowCIDRs) == 0 {
+		allowCIDRs = cfg.BastionAllowCidrs
+	}
+
+	created, err := bastion.CreateBastion(ctx, ociClient, &bastion.CreateOptions{
+		ClusterName:   discovered.Name,
+		CompartmentID: discovered.CompartmentID,
+		SubnetID:      discovered.SubnetID,
+		AllowCIDRs:    allowCIDRs,
+		TTL:           ttl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bastion: %w", err)
+	}
+	return created, nil
+}
+
+// resolveClusterCreatingBastion resolves a cluster, or another resource
+// type, like resolveResource and, if discovery finds no bastion for it,
+// creates one and resolves again.
+func resolveClusterCreatingBastion(ctx context.Context, cfg *config.Config, cfgLoaded bool, name, region string, skipCache bool, resourceType discovery.ResourceType, allowCIDRs []string) (*config.Cluster, *client.OCIClient, error) {
+	selectedCluster, ociClient, err := resolveResource(ctx, cfg, cfgLoaded, name, region, skipCache, resourceType)
+	var noBastion *cluster.NoBastionError
+	if !errors.As(err, &noBastion) {
+		return selectedCluster, ociClient, err
+	}
+
+	log.Info().Msgf("No bastion found for cluster '%s', creating one", noBastion.Cluster.Name)
+	createClient, err := cluster.NewDiscoveryClient(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OCI client: %w", err)
+	}
+	createClient = createClient.WithRegion(noBastion.Cluster.Region)
+
+	ttl := time.Duration(cfg.GetBastionTTLHours()) * time.Hour
+	if _, err := createClusterBastion(ctx, cfg, createClient, noBastion.Cluster, allowCIDRs, ttl); err != nil {
+		return nil, nil, err
+	}
+
+	return resolveResource(ctx, cfg, cfgLoaded, name, region, skipCache, resourceType)
+}
@@ -2,12 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/scotttball/tunatap/internal/config"
 	"github.com/scotttball/tunatap/internal/discovery"
-	"github.com/scotttball/tunatap/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -18,10 +18,12 @@ var cacheCmd = &cobra.Command{
 }
 
 var cacheShowCmd = &cobra.Command{
-	Use:   "show",
-	Short: "Show cached entries",
-	Long:  `Display all cached cluster and bastion discovery entries.`,
-	RunE:  runCacheShow,
+	Use:     "list",
+	Aliases: []string{"show"},
+	Short:   "List cached entries",
+	Long: `Display all cached cluster and bastion discovery entries, and the
+cluster names that discovery recently did not find.`,
+	RunE: runCacheShow,
 }
 
 var cacheClearCmd = &cobra.Command{
@@ -34,45 +36,71 @@ Otherwise, the entire cache is cleared.`,
 	RunE: runCacheClear,
 }
 
+var cacheInvalidateCmd = &cobra.Command{
+	Use:   "invalidate <cluster>",
+	Short: "Forget what discovery found for a cluster",
+	Long: `Remove a cluster's cached location, bastion and choices, or the record of
+its name not being found, so that the next connection discovers it afresh.
+Use this when a cluster has moved compartments or was just created.`,
+	RunE: runCacheInvalidate,
+	Args: cobra.ExactArgs(1),
+}
+
 func init() {
 	rootCmd.AddCommand(cacheCmd)
 	cacheCmd.AddCommand(cacheShowCmd)
 	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheInvalidateCmd)
 }
 
-func runCacheShow(cmd *cobra.Command, args []string) error {
-	// Load config to get TTL setting
+// loadCache opens the discovery cache with the configured TTLs.
+func loadCache() (*discovery.Cache, error) {
+	// Load config to get TTL settings
 	cfg, _ := config.ReadConfig(GetConfigFile())
 	if cfg == nil {
 		cfg = config.DefaultConfig()
 	}
 
-	ttl := time.Duration(cfg.GetCacheTTLHours()) * time.Hour
-	cache, err := discovery.NewCache(utils.DefaultTunatapDir(), ttl)
+	cache, err := discovery.OpenCache(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to load cache: %w", err)
+		return nil, fmt.Errorf("failed to load cache: %w", err)
+	}
+	return cache, nil
+}
+
+func runCacheShow(cmd *cobra.Command, args []string) error {
+	cache, err := loadCache()
+	if err != nil {
+		return err
 	}
 
 	clusters := cache.GetAllClusters()
+	notFound := cache.GetAllNotFound()
 
-	if len(clusters) == 0 {
+	if len(clusters) == 0 && len(notFound) == 0 {
 		fmt.Println("Cache is empty.")
 		fmt.Printf("Cache file: %s\n", cache.Path())
 		return nil
 	}
 
 	fmt.Printf("Discovery Cache (%s)\n", cache.Path())
+	if cache.Encrypted() {
+		fmt.Println("Encrypted with the key in the OS keychain")
+	}
 	fmt.Println("═════════════════════════════════════════════════════════════")
 	fmt.Println()
 
-	fmt.Println("Clusters:")
-	fmt.Println("─────────────────────────────────────────────────────────────")
-	for name, entry := range clusters {
+	if len(clusters) > 0 {
+		fmt.Println("Clusters:")
+		fmt.Println("─────────────────────────────────────────────────────────────")
+	}
+	for _, name := range sortedKeys(clusters) {
+		entry := clusters[name]
 		ttlRemaining := cache.GetClusterTTL(name)
 		fmt.Printf("  %s\n", name)
 		fmt.Printf("    OCID:        %s\n", entry.OCID)
 		fmt.Printf("    Region:      %s\n", entry.Region)
-		fmt.Printf("    Compartment: %s\n", entry.CompartmentOCID)
+		fmt.Printf("    Compartment: %s\n", cachedCompartment(cache, entry))
 		if entry.EndpointIP != "" {
 			fmt.Printf("    Endpoint:    %s:%d\n", entry.EndpointIP, entry.EndpointPort)
 		}
@@ -82,56 +110,94 @@ func runCacheShow(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show bastion entries too
-	bastionCount := 0
-	for name := range clusters {
-		if bastion := cache.GetBastion(name); bastion != nil {
-			if bastionCount == 0 {
-				fmt.Println("Bastions:")
-				fmt.Println("─────────────────────────────────────────────────────────────")
-			}
-			bastionCount++
-			fmt.Printf("  %s (for cluster: %s)\n", bastion.OCID, name)
-			fmt.Printf("    Region:      %s\n", bastion.Region)
-			fmt.Printf("    Cached:      %s\n", bastion.CachedAt.Format(time.RFC3339))
-			fmt.Println()
-		}
+	bastions := cache.GetAllBastions()
+	if len(bastions) > 0 {
+		fmt.Println("Bastions:")
+		fmt.Println("─────────────────────────────────────────────────────────────")
+	}
+	for _, name := range sortedKeys(bastions) {
+		bastion := bastions[name]
+		fmt.Printf("  %s (for cluster: %s)\n", bastion.OCID, name)
+		fmt.Printf("    Region:      %s\n", bastion.Region)
+		fmt.Printf("    Cached:      %s\n", bastion.CachedAt.Format(time.RFC3339))
+		fmt.Println()
+	}
+
+	if len(notFound) > 0 {
+		fmt.Println("Not found:")
+		fmt.Println("─────────────────────────────────────────────────────────────")
+	}
+	for _, name := range sortedKeys(notFound) {
+		entry := notFound[name]
+		fmt.Printf("  %s\n", name)
+		fmt.Printf("    Searched:    %s\n", entry.CachedAt.Format(time.RFC3339))
+		fmt.Printf("    Expires in:  %s\n", cache.Remaining(entry).Round(time.Second))
+		fmt.Println()
 	}
 
 	return nil
 }
 
-func runCacheClear(cmd *cobra.Command, args []string) error {
-	// Load config to get TTL setting
-	cfg, _ := config.ReadConfig(GetConfigFile())
-	if cfg == nil {
-		cfg = config.DefaultConfig()
+// cachedCompartment describes the compartment of a cached cluster by its
+// path and OCID, or just its OCID if the path is not cached.
+func cachedCompartment(cache *discovery.Cache, entry *discovery.CacheEntry) string {
+	path := entry.CompartmentPath
+	if path == "" {
+		path = cache.CompartmentPath(entry.CompartmentOCID)
 	}
+	if path == "" || entry.CompartmentOCID == "" {
+		return entry.CompartmentOCID
+	}
+	return fmt.Sprintf("%s (%s)", path, entry.CompartmentOCID)
+}
+
+// sortedKeys returns the names of cache entries in order.
+func sortedKeys(entries map[string]*discovery.CacheEntry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-	ttl := time.Duration(cfg.GetCacheTTLHours()) * time.Hour
-	cache, err := discovery.NewCache(utils.DefaultTunatapDir(), ttl)
+func runCacheInvalidate(cmd *cobra.Command, args []string) error {
+	return invalidateCachedCluster(args[0])
+}
+
+// invalidateCachedCluster removes everything cached for clusterName.
+func invalidateCachedCluster(clusterName string) error {
+	cache, err := loadCache()
 	if err != nil {
-		return fmt.Errorf("failed to load cache: %w", err)
+		return err
 	}
 
+	if !cache.Contains(clusterName) {
+		log.Warn().Msgf("Cluster '%s' not found in cache", clusterName)
+		return nil
+	}
+
+	if err := cache.Invalidate(clusterName); err != nil {
+		return fmt.Errorf("failed to clear cache for '%s': %w", clusterName, err)
+	}
+	fmt.Printf("Cleared cache for cluster: %s\n", clusterName)
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		// Clear specific cluster
-		clusterName := args[0]
-		if cache.GetCluster(clusterName) == nil {
-			log.Warn().Msgf("Cluster '%s' not found in cache", clusterName)
-			return nil
-		}
+		return invalidateCachedCluster(args[0])
+	}
 
-		if err := cache.Invalidate(clusterName); err != nil {
-			return fmt.Errorf("failed to clear cache for '%s': %w", clusterName, err)
-		}
-		fmt.Printf("Cleared cache for cluster: %s\n", clusterName)
-	} else {
-		// Clear all
-		if err := cache.InvalidateAll(); err != nil {
-			return fmt.Errorf("failed to clear cache: %w", err)
-		}
-		fmt.Println("Cache cleared.")
+	cache, err := loadCache()
+	if err != nil {
+		return err
 	}
 
+	if err := cache.InvalidateAll(); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	fmt.Println("Cache cleared.")
 	return nil
 }
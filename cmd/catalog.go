@@ -2,10 +2,8 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 
-	"github.com/rs/zerolog/log"
 	"github.com/scotttball/tunatap/internal/catalog"
 	"github.com/scotttball/tunatap/internal/config"
 	"github.com/spf13/cobra"
@@ -141,6 +139,8 @@ func runCatalogFetch(cmd *cobra.Command, args []string) error {
 	// Create catalog manager
 	cacheDir := getCatalogCacheDir()
 	manager := catalog.NewCatalogManager(cfg.CatalogSources, cacheDir)
+	manager.SetSecretResolver(secretResolver(cfg))
+	manager.SetEncrypted(cfg.CacheEncryption)
 
 	// Fetch all catalogs
 	fmt.Println("Fetching catalogs...")
@@ -168,6 +168,8 @@ func runCatalogRefresh(cmd *cobra.Command, args []string) error {
 
 	cacheDir := getCatalogCacheDir()
 	manager := catalog.NewCatalogManager(cfg.CatalogSources, cacheDir)
+	manager.SetSecretResolver(secretResolver(cfg))
+	manager.SetEncrypted(cfg.CacheEncryption)
 
 	fmt.Println("Refreshing catalog cache...")
 	if err := manager.RefreshCatalogs(cmd.Context()); err != nil {
@@ -182,7 +184,7 @@ func runCatalogAdd(cmd *cobra.Command, args []string) error {
 	name := args[0]
 	urlStr := args[1]
 
-	cfg, err := config.ReadConfig(GetConfigFile())
+	cfg, err := config.ReadConfigFile(GetConfigFile())
 	if err != nil {
 		cfg = config.DefaultConfig()
 	}
@@ -217,7 +219,7 @@ func runCatalogAdd(cmd *cobra.Command, args []string) error {
 func runCatalogRemove(cmd *cobra.Command, args []string) error {
 	name := args[0]
 
-	cfg, err := config.ReadConfig(GetConfigFile())
+	cfg, err := config.ReadConfigFile(GetConfigFile())
 	if err != nil {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
@@ -272,6 +274,8 @@ func runCatalogShow(cmd *cobra.Command, args []string) error {
 	// Fetch catalog
 	cacheDir := getCatalogCacheDir()
 	manager := catalog.NewCatalogManager(cfg.CatalogSources, cacheDir)
+	manager.SetSecretResolver(secretResolver(cfg))
+	manager.SetEncrypted(cfg.CacheEncryption)
 
 	catalogData, err := manager.FetchSource(cmd.Context(), source)
 	if err != nil {
@@ -313,11 +317,7 @@ func runCatalogSample(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// getCatalogCacheDir returns the catalog cache of the config profile in use.
 func getCatalogCacheDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		log.Warn().Err(err).Msg("Could not get home directory")
-		return ""
-	}
-	return filepath.Join(home, ".tunatap", "cache", "catalogs")
+	return filepath.Join(configDir(), "cache", "catalogs")
 }
@@ -0,0 +1,670 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/scotttball/tunatap/internal/catalog"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and edit the config file",
+	Long: `Read and edit the config file without the setup wizard or a text editor.
+
+Options are the top-level settings, such as session_ttl_minutes, and the
+settings nested under retry, ssh_connection_pool_autoscale and
+remote_config, such as retry.max_attempts. Each can also be overridden for
+one run by a TUNATAP_* environment variable: TUNATAP_SESSION_TTL_MINUTES,
+TUNATAP_RETRY_MAX_ATTEMPTS and so on.
+
+Commands that change the config validate it before saving.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <option>",
+	Short: "Print the value of an option",
+	Long: `Print the value of an option, including any TUNATAP_* environment
+override. Lists are printed one item per line. Fails if the option is not
+set.
+
+Examples:
+  tunatap config get session_ttl_minutes
+  tunatap config get retry.max_attempts`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <option> <value>",
+	Short: "Set an option",
+	Long: `Set an option in the config file. Lists are given comma-separated.
+
+Examples:
+  tunatap config set session_ttl_minutes 120
+  tunatap config set ssh_host_key_policy accept-new
+  tunatap config set discovery_regions us-ashburn-1,us-phoenix-1`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <option>",
+	Short: "Remove an option so its default applies",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigUnset,
+}
+
+var configAddClusterCmd = &cobra.Command{
+	Use:   "add-cluster <name>",
+	Short: "Add a cluster, or update the given fields of one",
+	Long: `Add a cluster to the config file. If the cluster is already configured,
+only the fields given by flags are changed. --endpoint may be repeated and
+replaces the endpoint of the same name.
+
+Examples:
+  tunatap config add-cluster prod --region us-ashburn-1 --ocid ocid1.cluster.oc1.iad.xxx
+  tunatap config add-cluster prod --endpoint 10.0.0.5:6443 --local-port 6443
+  tunatap config add-cluster prod --bastion prod-bastion
+  tunatap config add-cluster prod --alias pp --alias payments-prod --group payments`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigAddCluster,
+}
+
+var configRemoveClusterCmd = &cobra.Command{
+	Use:   "remove-cluster <name>",
+	Short: "Remove a cluster",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigRemoveCluster,
+}
+
+var configAddEndpointCmd = &cobra.Command{
+	Use:   "add-endpoint <cluster> <name> <host:port>",
+	Short: "Add an endpoint to a cluster, or replace one",
+	Long: `Add an endpoint to a configured cluster, replacing any endpoint of the
+same name. The host is an IP address or a DNS name resolved on the bastion
+side.
+
+Examples:
+  tunatap config add-endpoint prod private 10.0.0.5:6443
+  tunatap config add-endpoint prod metrics 10.0.0.9:9090 --local-port 9090`,
+	Args: cobra.ExactArgs(3),
+	RunE: runConfigAddEndpoint,
+}
+
+var configRemoveEndpointCmd = &cobra.Command{
+	Use:   "remove-endpoint <cluster> <name>",
+	Short: "Remove an endpoint from a cluster",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigRemoveEndpoint,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config for mistakes",
+	Long: `Check the config, with any TUNATAP_* environment overrides, for mistakes
+that would otherwise only show up when connecting, or not at all: unknown
+keys (such as ssh_private_keyfile for ssh_private_key_file), values of the
+wrong type, missing cluster fields, duplicate names, invalid OCIDs and
+regions, bad ports and unknown option values. Each problem is reported with
+its line in the config file.
+
+The same problems are logged as warnings whenever the config is loaded; set
+strict_config: true (or TUNATAP_STRICT_CONFIG=true) to make them errors.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the config file",
+	Long: `Print a JSON Schema of the config file, for editors that complete and
+check YAML with one. With the YAML language server (VS Code, Neovim and
+others), save it and point the config at it from its first line:
+
+  tunatap config schema > ~/.tunatap/config.schema.json
+  # yaml-language-server: $schema=config.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigSchema,
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the config file to a newer layout",
+	Long: `Migrate the config file to a newer version of its layout, keeping the
+original as config.yaml.bak. The version is the config's version key; files
+without one are version 1.
+
+Config files are migrated to the current version when they are loaded, so
+this is only needed to migrate one step at a time with --to, or to see what
+a migration changes with --dry-run.
+
+Versions:
+  1  the tenancies map and tenancy_list side by side
+  2  tenancies only in tenancy_list
+
+Examples:
+  tunatap config migrate
+  tunatap config migrate --dry-run
+  tunatap config migrate --to 2`,
+	Args: cobra.NoArgs,
+	RunE: runConfigMigrate,
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the config",
+	Long: `Print the config file. With --effective, print the config
+tunatap uses instead: the config file with defaults filled in, TUNATAP_*
+environment overrides applied and the clusters and tenancies of enabled
+catalogs merged in.
+
+Examples:
+  tunatap config view
+  tunatap config view --effective`,
+	Args: cobra.NoArgs,
+	RunE: runConfigView,
+}
+
+var (
+	configClusterRegion          string
+	configClusterOcid            string
+	configClusterCompartment     string
+	configClusterCompartmentOcid string
+	configClusterTenant          string
+	configClusterBastion         string
+	configClusterBastionID       string
+	configClusterLocalPort       int
+	configClusterEndpoints       []string
+	configClusterAliases         []string
+	configClusterGroup           string
+	configEndpointLocalPort      int
+	configEndpointProtocol       string
+	configViewEffective          bool
+	configMigrateTo              int
+	configMigrateDryRun          bool
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configAddClusterCmd)
+	configCmd.AddCommand(configRemoveClusterCmd)
+	configCmd.AddCommand(configAddEndpointCmd)
+	configCmd.AddCommand(configRemoveEndpointCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configCmd.AddCommand(configViewCmd)
+
+	f := configAddClusterCmd.Flags()
+	f.StringVarP(&configClusterRegion, "region", "r", "", "OCI region of the cluster (required for a new cluster)")
+	f.StringVar(&configClusterOcid, "ocid", "", "cluster OCID")
+	f.StringVar(&configClusterCompartment, "compartment", "", "compartment path, e.g. parent/child")
+	f.StringVar(&configClusterCompartmentOcid, "compartment-ocid", "", "compartment OCID")
+	f.StringVar(&configClusterTenant, "tenant", "", "tenancy name from the tenancies map")
+	f.StringVar(&configClusterBastion, "bastion", "", "bastion name")
+	f.StringVar(&configClusterBastionID, "bastion-id", "", "bastion OCID")
+	f.IntVarP(&configClusterLocalPort, "local-port", "p", 0, "local port of the tunnel")
+	f.StringArrayVarP(&configClusterEndpoints, "endpoint", "e", nil, "endpoint as [name=]host:port (default name: default)")
+	f.StringArrayVar(&configClusterAliases, "alias", nil, "other name of the cluster (repeatable; replaces the aliases)")
+	f.StringVar(&configClusterGroup, "group", "", "group of the cluster, for connect --group")
+
+	configAddEndpointCmd.Flags().IntVarP(&configEndpointLocalPort, "local-port", "p", 0, "local port to forward the endpoint on alongside the primary one")
+	configAddEndpointCmd.Flags().StringVar(&configEndpointProtocol, "protocol", "", "tcp (default) or udp")
+
+	configViewCmd.Flags().BoolVar(&configViewEffective, "effective", false, "print the merged config tunatap uses")
+
+	configMigrateCmd.Flags().IntVar(&configMigrateTo, "to", 0, "version to migrate to (default: the current version)")
+	configMigrateCmd.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "print the migrated config without writing it")
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	value, err := config.GetOption(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return fmt.Errorf("%s is not set", args[0])
+	}
+	if items, ok := value.([]string); ok {
+		for _, item := range items {
+			fmt.Println(item)
+		}
+		return nil
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadConfigFile(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := config.SetOption(cfg, args[0], args[1]); err != nil {
+		return err
+	}
+	if err := saveEditedConfig(cfg, true); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set %s\n", args[0])
+	warnEnvOverride(args[0])
+	return nil
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadConfigFile(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := config.UnsetOption(cfg, args[0]); err != nil {
+		return err
+	}
+	if err := saveEditedConfig(cfg, false); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unset %s\n", args[0])
+	warnEnvOverride(args[0])
+	return nil
+}
+
+func runConfigAddCluster(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadConfigFile(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	c := config.FindClusterByName(cfg, args[0])
+	added := c == nil
+	if added {
+		if configClusterRegion == "" {
+			return fmt.Errorf("--region is required for a new cluster")
+		}
+		c = &config.Cluster{ClusterName: args[0]}
+		cfg.Clusters = append(cfg.Clusters, c)
+	}
+
+	flags := cmd.Flags()
+	setString := func(flag string, p **string, value string) {
+		if flags.Changed(flag) {
+			if value == "" {
+				*p = nil
+			} else {
+				*p = utils.StringPtr(value)
+			}
+		}
+	}
+	if flags.Changed("region") {
+		c.Region = configClusterRegion
+	}
+	setString("ocid", &c.Ocid, configClusterOcid)
+	setString("compartment", &c.Compartment, configClusterCompartment)
+	setString("compartment-ocid", &c.CompartmentOcid, configClusterCompartmentOcid)
+	setString("tenant", &c.Tenant, configClusterTenant)
+	setString("bastion", &c.Bastion, configClusterBastion)
+	setString("bastion-id", &c.BastionId, configClusterBastionID)
+	if flags.Changed("local-port") {
+		if configClusterLocalPort == 0 {
+			c.LocalPort = nil
+		} else {
+			port := configClusterLocalPort
+			c.LocalPort = &port
+		}
+	}
+	if flags.Changed("alias") {
+		c.Aliases = nil
+		for _, alias := range configClusterAliases {
+			if alias != "" {
+				c.Aliases = append(c.Aliases, alias)
+			}
+		}
+	}
+	if flags.Changed("group") {
+		c.Group = configClusterGroup
+	}
+	for _, spec := range configClusterEndpoints {
+		name, addr, ok := strings.Cut(spec, "=")
+		if !ok {
+			name, addr = "default", spec
+		}
+		ep, err := parseEndpoint(name, addr)
+		if err != nil {
+			return err
+		}
+		setEndpoint(c, ep)
+	}
+
+	if err := saveEditedConfig(cfg, true); err != nil {
+		return err
+	}
+
+	if added {
+		fmt.Printf("Added cluster '%s'\n", c.ClusterName)
+	} else {
+		fmt.Printf("Updated cluster '%s'\n", c.ClusterName)
+	}
+	return nil
+}
+
+func runConfigRemoveCluster(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadConfigFile(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	c, err := findEditedCluster(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	clusters := make([]*config.Cluster, 0, len(cfg.Clusters))
+	for _, other := range cfg.Clusters {
+		if other != c {
+			clusters = append(clusters, other)
+		}
+	}
+	cfg.Clusters = clusters
+
+	if err := saveEditedConfig(cfg, false); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed cluster '%s'\n", c.ClusterName)
+	return nil
+}
+
+func runConfigAddEndpoint(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadConfigFile(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	c, err := findEditedCluster(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	ep, err := parseEndpoint(args[1], args[2])
+	if err != nil {
+		return err
+	}
+	if configEndpointLocalPort != 0 {
+		port := configEndpointLocalPort
+		ep.LocalPort = &port
+	}
+	ep.Protocol = configEndpointProtocol
+	setEndpoint(c, ep)
+
+	if err := saveEditedConfig(cfg, true); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set endpoint '%s' of cluster '%s'\n", ep.Name, c.ClusterName)
+	return nil
+}
+
+func runConfigRemoveEndpoint(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadConfigFile(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	c, err := findEditedCluster(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	endpoints := make([]*config.ClusterEndpoint, 0, len(c.Endpoints))
+	for _, ep := range c.Endpoints {
+		if !strings.EqualFold(ep.Name, args[1]) {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	if len(endpoints) == len(c.Endpoints) {
+		return fmt.Errorf("cluster '%s' has no endpoint '%s'", c.ClusterName, args[1])
+	}
+	c.Endpoints = endpoints
+
+	if err := saveEditedConfig(cfg, false); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed endpoint '%s' of cluster '%s'\n", args[1], c.ClusterName)
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	// Read the file after the config, which migrates it, so that lines
+	// match the file as it is now
+	cfg, readErr := config.ReadConfigFile(GetConfigFile())
+	data, err := os.ReadFile(GetConfigFile())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// A file that does not parse is still checked, so that every problem
+	// is reported with its line rather than only the first
+	var errs []error
+	var included []string
+	if err := readErr; err != nil {
+		if errs = config.CheckSchema(data); len(errs) == 0 {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+	} else {
+		if _, err := config.ApplyEnv(cfg); err != nil {
+			return err
+		}
+		errs = config.Check(cfg, data)
+		var includeErrs []error
+		included, includeErrs = config.LoadIncludes(cfg, GetConfigFile())
+		errs = append(errs, includeErrs...)
+	}
+
+	if len(errs) == 0 {
+		if len(included) > 0 {
+			fmt.Printf("%s and %d included file(s) are valid\n", GetConfigFile(), len(included))
+		} else {
+			fmt.Printf("%s is valid\n", GetConfigFile())
+		}
+		return nil
+	}
+	for _, err := range errs {
+		fmt.Printf("✗ %v\n", err)
+	}
+	return fmt.Errorf("config has %d problem(s)", len(errs))
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	path := GetConfigFile()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return exitcode.Wrap(exitcode.NotFound, fmt.Errorf("no config file at %s", path))
+		}
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	from, err := config.ConfigVersion(data)
+	if err != nil {
+		return err
+	}
+
+	to := configMigrateTo
+	if to == 0 {
+		to = config.CurrentVersion
+	}
+	migrated, steps, err := config.Migrate(data, to)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+	if len(steps) == 0 {
+		fmt.Printf("%s is already at version %d\n", path, from)
+		return nil
+	}
+
+	if configMigrateDryRun {
+		_, err := os.Stdout.Write(migrated)
+		return err
+	}
+	if _, err := config.MigrateFile(path, to); err != nil {
+		return err
+	}
+	fmt.Printf("Migrated %s from version %d to %d:\n", path, from, to)
+	for _, step := range steps {
+		fmt.Printf("  %s\n", step)
+	}
+	fmt.Printf("The original is in %s\n", path+config.BackupSuffix)
+	return nil
+}
+
+func runConfigSchema(cmd *cobra.Command, args []string) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(config.JSONSchema())
+}
+
+func runConfigView(cmd *cobra.Command, args []string) error {
+	if !configViewEffective {
+		data, err := os.ReadFile(GetConfigFile())
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	cfg, err := config.ReadConfigFile(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	applied, err := config.ApplyEnv(cfg)
+	if err != nil {
+		return err
+	}
+	included, _ := config.LoadIncludes(cfg, GetConfigFile())
+
+	var merged []string
+	if len(cfg.CatalogSources) > 0 {
+		manager := catalog.NewCatalogManager(cfg.CatalogSources, getCatalogCacheDir())
+		manager.SetSecretResolver(secretResolver(cfg))
+		manager.SetEncrypted(cfg.CacheEncryption)
+		catalogs, err := manager.FetchAll(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to fetch catalogs: %w", err)
+		}
+		for _, c := range catalogs {
+			merged = append(merged, c.Name)
+		}
+		cfg = catalog.MergeCatalogs(cfg, catalogs)
+	}
+
+	fmt.Printf("# file: %s\n", GetConfigFile())
+	for _, file := range included {
+		fmt.Printf("# included: %s\n", file)
+	}
+	if len(merged) > 0 {
+		fmt.Printf("# catalogs: %s\n", strings.Join(merged, ", "))
+	}
+	if len(applied) > 0 {
+		fmt.Printf("# environment: %s\n", strings.Join(applied, ", "))
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return enc.Close()
+}
+
+// findEditedCluster returns the cluster named name of cfg, read from the
+// config file alone, for a config command to change. A cluster configured
+// in an included file is reported with that file, which is where it has to
+// be changed.
+func findEditedCluster(cfg *config.Config, name string) (*config.Cluster, error) {
+	if c := config.FindClusterByName(cfg, name); c != nil {
+		return c, nil
+	}
+	if file := config.ClusterIncludedFrom(cfg, GetConfigFile(), name); file != "" {
+		return nil, fmt.Errorf("cluster '%s' is defined in %s; edit it there", name, file)
+	}
+	return nil, fmt.Errorf("cluster '%s' not found in config", name)
+}
+
+// saveEditedConfig saves a config changed by a config command. With
+// validate, a config with problems is not saved.
+func saveEditedConfig(cfg *config.Config, validate bool) error {
+	if validate {
+		if errs := config.Validate(cfg); len(errs) > 0 {
+			return fmt.Errorf("not saving an invalid config: %w", errors.Join(errs...))
+		}
+	}
+	if err := config.SaveConfig(GetConfigFile(), cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+// warnEnvOverride tells the user when an option they changed is overridden
+// by its environment variable.
+func warnEnvOverride(key string) {
+	name := config.OptionEnvVar(key)
+	if _, ok := os.LookupEnv(name); ok {
+		fmt.Printf("Note: %s is set and overrides this option\n", name)
+	}
+}
+
+// parseEndpoint parses a host:port endpoint. Hosts that are not IP
+// addresses are kept as FQDNs and resolved on the bastion side.
+func parseEndpoint(name, addr string) (*config.ClusterEndpoint, error) {
+	if name == "" {
+		return nil, fmt.Errorf("endpoint '%s' has no name", addr)
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint '%s': expected host:port", addr)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid port in endpoint '%s'", addr)
+	}
+	if host == "" {
+		return nil, fmt.Errorf("invalid endpoint '%s': no host", addr)
+	}
+
+	ep := &config.ClusterEndpoint{Name: name, Port: port}
+	if net.ParseIP(host) != nil {
+		ep.Ip = host
+	} else {
+		ep.Fqdn = host
+	}
+	return ep, nil
+}
+
+// setEndpoint adds ep to a cluster, replacing the endpoint of the same name.
+func setEndpoint(c *config.Cluster, ep *config.ClusterEndpoint) {
+	for i, existing := range c.Endpoints {
+		if strings.EqualFold(existing.Name, ep.Name) {
+			c.Endpoints[i] = ep
+			return
+		}
+	}
+	c.Endpoints = append(c.Endpoints, ep)
+}
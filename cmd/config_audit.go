@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/scotttball/tunatap/internal/autofix"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var configAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check the config for risky security settings",
+	Long: `Check the config for settings that weaken security, each with a severity
+and a suggested fix:
+
+  config_permissions   the config file can be read by other users
+  ssh_key_passphrase   an SSH private key is not encrypted
+  plaintext_secret     a passphrase or password is not a secret reference
+  kubeconfig_ca        a cluster's CA cannot be fetched, as it has no OCID
+  kubeconfig_insecure  a cluster's kubeconfigs skip TLS verification
+  audit_logging        audit logging is disabled
+
+--fix applies the fixes that need no confirmation, as 'doctor --auto-fix'
+does; the others are listed with what to do by hand. The command exits
+with code 7 when a finding is at least as severe as --fail-on.
+
+Examples:
+  tunatap config audit
+  tunatap config audit --fix
+  tunatap config audit --fail-on medium -o json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigAudit,
+}
+
+var (
+	configAuditFix    bool
+	configAuditDryRun bool
+	configAuditFailOn string
+	configAuditOutput string
+)
+
+func init() {
+	configCmd.AddCommand(configAuditCmd)
+
+	f := configAuditCmd.Flags()
+	f.BoolVar(&configAuditFix, "fix", false, "apply the fixes that need no confirmation")
+	f.BoolVar(&configAuditDryRun, "dry-run", false, "with --fix, show the fixes without applying them")
+	f.StringVar(&configAuditFailOn, "fail-on", string(autofix.SeverityHigh), "exit with an error on findings of this severity or higher: high, medium, low")
+	addOutputFlag(configAuditCmd, &configAuditOutput)
+}
+
+func runConfigAudit(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(configAuditOutput, false)
+	if err != nil {
+		return err
+	}
+	failOn := autofix.Severity(strings.ToLower(configAuditFailOn))
+	switch failOn {
+	case autofix.SeverityHigh, autofix.SeverityMedium, autofix.SeverityLow:
+	default:
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("--fail-on must be high, medium or low, not '%s'", configAuditFailOn))
+	}
+	if format.Structured() && configAuditFix {
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("--fix cannot be combined with --output %s", format))
+	}
+
+	cfg, err := config.ReadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	fixer := autofix.NewFixer(GetConfigFile(), configAuditDryRun)
+	findings := fixer.Audit(cfg)
+
+	if format.Structured() {
+		if findings == nil {
+			findings = []*autofix.Finding{}
+		}
+		if err := output.Write(os.Stdout, format, findings); err != nil {
+			return err
+		}
+		return auditFailure(findings, failOn)
+	}
+
+	if len(findings) == 0 {
+		fmt.Printf("%s has no risky settings\n", GetConfigFile())
+		return nil
+	}
+	for _, fd := range findings {
+		fmt.Printf("[%s] %s\n", strings.ToUpper(string(fd.Severity)), fd.Message)
+		fix := "fix"
+		if fd.Fixable() {
+			fix = "fix (--fix)"
+		}
+		fmt.Printf("    %s: %s\n", fix, fd.Suggestion)
+	}
+
+	if !configAuditFix {
+		return auditFailure(findings, failOn)
+	}
+
+	fmt.Println()
+	results := fixer.ApplySafe()
+	if len(results) == 0 {
+		fmt.Println("No findings can be fixed automatically.")
+	}
+	for _, result := range results {
+		fmt.Println(autofix.FormatResult(result))
+	}
+	if configAuditDryRun {
+		return auditFailure(findings, failOn)
+	}
+
+	// Judge by what is left once the fixes are applied
+	cfg, err = config.ReadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	return auditFailure(autofix.NewFixer(GetConfigFile(), true).Audit(cfg), failOn)
+}
+
+// auditFailure returns an error if any of findings is at least as severe
+// as failOn.
+func auditFailure(findings []*autofix.Finding, failOn autofix.Severity) error {
+	if autofix.HasSeverity(findings, failOn) {
+		return exitcode.Wrap(exitcode.PreflightFailed, fmt.Errorf("config audit found risks of %s severity or higher", failOn))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configProfile       string
+	activeConfigProfile string
+	profileDir          string
+
+	configUseCreate      bool
+	configUseFrom        string
+	configProfilesOutput string
+)
+
+var configUseCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "Set the config profile used by default",
+	Long: `Set the config profile used when none is given with --config-profile or
+TUNATAP_PROFILE. Each config profile has its own config file, with its own
+OCI profile and clusters, and its own discovery and catalog caches, in
+~/.tunatap/profiles/<profile>. The default profile is ~/.tunatap/config.yaml.
+
+With --create, the profile is created first, empty or with a copy of the
+config file given by --from.
+
+Examples:
+  tunatap config use work --create
+  tunatap config use customer-x --create --from ./customer-x.yaml
+  tunatap config use default`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigUse,
+}
+
+var configProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List the config profiles",
+	Long: `List the config profiles and their directories. The one in use is
+marked with '*'.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigProfiles,
+}
+
+func init() {
+	configCmd.AddCommand(configUseCmd)
+	configCmd.AddCommand(configProfilesCmd)
+
+	configUseCmd.Flags().BoolVar(&configUseCreate, "create", false, "create the profile first")
+	configUseCmd.Flags().StringVar(&configUseFrom, "from", "", "config file to copy into the created profile")
+	addOutputFlag(configProfilesCmd, &configProfilesOutput)
+}
+
+// selectConfigProfile picks the config profile of the run: the one given by
+// --config-profile or TUNATAP_PROFILE, else the one set with 'tunatap config
+// use'. --config names a config file directly and skips profiles.
+func selectConfigProfile() error {
+	activeConfigProfile, profileDir = "", ""
+	if cfgFile != "" {
+		if configProfile != "" {
+			return exitcode.Wrap(exitcode.Usage, fmt.Errorf("--config and --config-profile cannot be used together"))
+		}
+		return nil
+	}
+
+	name := configProfile
+	if name == "" {
+		name = os.Getenv(config.ConfigProfileEnv)
+	}
+	if name == "" {
+		current, err := config.CurrentConfigProfile(homePath)
+		if err != nil {
+			return err
+		}
+		if config.ValidateConfigProfileName(current) != nil || !config.ConfigProfileExists(homePath, current) {
+			log.Warn().Msgf("Config profile '%s' set with 'tunatap config use' does not exist, using the %s one", current, config.DefaultConfigProfile)
+			current = config.DefaultConfigProfile
+		}
+		name = current
+	}
+
+	if err := config.ValidateConfigProfileName(name); err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+	if !config.ConfigProfileExists(homePath, name) {
+		return exitcode.Wrap(exitcode.NotFound, fmt.Errorf("config profile '%s' does not exist (create it with 'tunatap config use %s --create')", name, name))
+	}
+	activeConfigProfile = name
+	profileDir = config.ConfigProfileDir(homePath, name)
+	return nil
+}
+
+// configDir returns the directory of the config profile in use, which holds
+// its config file and caches.
+func configDir() string {
+	if profileDir != "" {
+		return profileDir
+	}
+	return homePath
+}
+
+func runConfigUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if configUseFrom != "" && !configUseCreate {
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("--from requires --create"))
+	}
+
+	if configUseCreate {
+		var data []byte
+		if configUseFrom != "" {
+			var err error
+			if data, err = os.ReadFile(configUseFrom); err != nil {
+				return fmt.Errorf("failed to read config: %w", err)
+			}
+		}
+		if err := config.CreateConfigProfile(homePath, name, data); err != nil {
+			return err
+		}
+		fmt.Printf("Created config profile '%s' in %s\n", name, config.ConfigProfileDir(homePath, name))
+	} else if config.ValidateConfigProfileName(name) == nil && !config.ConfigProfileExists(homePath, name) {
+		return exitcode.Wrap(exitcode.NotFound, fmt.Errorf("config profile '%s' does not exist (create it with --create)", name))
+	}
+
+	if err := config.UseConfigProfile(homePath, name); err != nil {
+		return err
+	}
+	fmt.Printf("Using config profile '%s'\n", name)
+	if env := os.Getenv(config.ConfigProfileEnv); env != "" && env != name {
+		fmt.Printf("Note: %s=%s still selects '%s' in this shell\n", config.ConfigProfileEnv, env, env)
+	}
+	return nil
+}
+
+// configProfileEntry is a config profile as listed by 'config profiles'.
+type configProfileEntry struct {
+	Name   string `json:"name" yaml:"name"`
+	Dir    string `json:"dir" yaml:"dir"`
+	Active bool   `json:"active" yaml:"active"`
+}
+
+func runConfigProfiles(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(configProfilesOutput, false)
+	if err != nil {
+		return err
+	}
+
+	names, err := config.ListConfigProfiles(homePath)
+	if err != nil {
+		return err
+	}
+	entries := make([]configProfileEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, configProfileEntry{
+			Name:   name,
+			Dir:    config.ConfigProfileDir(homePath, name),
+			Active: name == activeConfigProfile,
+		})
+	}
+
+	if format.Structured() {
+		return output.Write(os.Stdout, format, entries)
+	}
+	for _, e := range entries {
+		mark := " "
+		if e.Active {
+			mark = "*"
+		}
+		fmt.Printf("%s %-20s %s\n", mark, e.Name, e.Dir)
+	}
+	if cfgFile != "" {
+		fmt.Printf("\nNo profile is in use: --config %s was given\n", cfgFile)
+	}
+	return nil
+}
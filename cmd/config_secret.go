@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/keychain"
+	"github.com/scotttball/tunatap/internal/secrets"
+	"github.com/scotttball/tunatap/internal/tunnel"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var configSetSecretCmd = &cobra.Command{
+	Use:   "set-secret <keychain:service/account>",
+	Short: "Store a secret in the OS keychain for a config reference",
+	Long: `Store a secret in the OS keychain, so that the config can refer to it
+instead of holding it in plain text. The secret is read from the terminal
+without echo, or from standard input when it is not a terminal.
+
+Options that hold secrets, such as ssh_private_key_passphrase,
+ssh_socks_proxy_password and the auth_token of catalog sources, take either
+a plain value or a reference resolved when it is used:
+
+  keychain:<service>/<account>   a secret stored with this command
+  vault:<secret OCID>            the current version of an OCI Vault secret
+
+Examples:
+  tunatap config set-secret keychain:tunatap/ssh-passphrase
+  tunatap config set ssh_private_key_passphrase keychain:tunatap/ssh-passphrase
+  echo "$TOKEN" | tunatap config set-secret keychain:tunatap/catalog-token`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigSetSecret,
+}
+
+func init() {
+	configCmd.AddCommand(configSetSecretCmd)
+}
+
+func runConfigSetSecret(cmd *cobra.Command, args []string) error {
+	service, account, err := secrets.ParseKeychain(args[0])
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+
+	secret, err := readSecret(fmt.Sprintf("Secret for %s: ", args[0]))
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("the secret is empty"))
+	}
+
+	if err := keychain.System().Set(service, account, secret); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	fmt.Printf("Stored %s in the keychain\n", args[0])
+	return nil
+}
+
+// readSecret reads a secret from the terminal without echo, or the first
+// line of standard input when it is not a terminal.
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		secret, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return string(secret), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// secretResolver returns a resolver of the secret references in cfg,
+// reading Vault secrets with its OCI credentials.
+func secretResolver(cfg *config.Config) *secrets.Resolver {
+	return secrets.NewResolver(func(region string) (secrets.VaultReader, error) {
+		return cluster.NewOCIClient(cfg, region)
+	})
+}
+
+// configureCredentials resolves the SSH key passphrase and SOCKS proxy
+// password of cfg, which may be secret references, for the SSH
+// connections of tunnels.
+func configureCredentials(ctx context.Context, cfg *config.Config) error {
+	resolver := secretResolver(cfg)
+
+	passphrase, err := resolver.Resolve(ctx, cfg.SshPrivateKeyPassphrase)
+	if err != nil {
+		return fmt.Errorf("ssh_private_key_passphrase: %w", err)
+	}
+	tunnel.SetKeyPassphrase(passphrase)
+
+	password, err := resolver.Resolve(ctx, cfg.SshSocksProxyPassword)
+	if err != nil {
+		return fmt.Errorf("ssh_socks_proxy_password: %w", err)
+	}
+	tunnel.SetSocksAuth(cfg.SshSocksProxyUser, password)
+	return nil
+}
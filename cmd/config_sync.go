@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/configsync"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configSyncPush   bool
+	configSyncPrefer string
+	configSyncDryRun bool
+)
+
+var configSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the config with the team config in Object Storage",
+	Long: `Pull the team config named by remote_config from OCI Object Storage and
+merge it with the local config. Only the keys listed in remote_config.keys
+are shared (default: clusters, tenancies, tenancy_list and profiles); the
+rest of each config is left alone.
+
+Changes are three-way merged against the remote config as of the last
+sync: a value changed on one side only takes that side's value, and
+clusters and other named items merge one by one. A value changed on both
+sides stops the sync, unless --prefer says which side wins.
+
+With --push, local changes are uploaded too. The upload only succeeds if
+the remote config has not changed since it was read, so a concurrent push
+is never overwritten. --push also creates the remote config if it does not
+exist yet.
+
+Examples:
+  tunatap config sync
+  tunatap config sync --dry-run
+  tunatap config sync --push
+  tunatap config sync --prefer remote`,
+	Args: cobra.NoArgs,
+	RunE: runConfigSync,
+}
+
+func init() {
+	configCmd.AddCommand(configSyncCmd)
+
+	configSyncCmd.Flags().BoolVar(&configSyncPush, "push", false, "upload local changes to the remote config")
+	configSyncCmd.Flags().StringVar(&configSyncPrefer, "prefer", "", "side kept when a value changed on both: local or remote")
+	configSyncCmd.Flags().BoolVar(&configSyncDryRun, "dry-run", false, "show what would change without writing anything")
+}
+
+func runConfigSync(cmd *cobra.Command, args []string) error {
+	prefer, err := configsync.ParsePreference(configSyncPrefer)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, err)
+	}
+
+	cfg, err := config.ReadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	rc := cfg.RemoteConfig
+	if rc == nil || rc.Region == "" || rc.TenancyOcid == "" || rc.Bucket == "" || rc.Object == "" {
+		return fmt.Errorf("no remote config: set remote_config.region, tenancy_ocid, bucket and object first")
+	}
+
+	ociClient, err := cluster.NewOCIClient(cfg, rc.Region)
+	if err != nil {
+		return fmt.Errorf("failed to create OCI client: %w", err)
+	}
+	namespace, err := ociClient.GetNamespace(cmd.Context(), rc.TenancyOcid)
+	if err != nil {
+		return client.WrapOCIError(err, "get Object Storage namespace")
+	}
+
+	store := &objectStore{client: ociClient, namespace: namespace, bucket: rc.Bucket, object: rc.Object}
+	location := fmt.Sprintf("oci://%s/%s/%s", namespace, rc.Bucket, rc.Object)
+	syncer := configsync.NewSyncer(store, location, GetConfigFile(), rc.Keys)
+	syncer.Prefer = prefer
+	syncer.Push = configSyncPush
+	syncer.DryRun = configSyncDryRun
+	local, _ := os.ReadFile(GetConfigFile())
+	syncer.Validate = func(merged []byte) error {
+		return newConfigProblems(local, merged)
+	}
+
+	result, err := syncer.Sync(cmd.Context())
+	var conflict *configsync.ConflictError
+	if errors.As(err, &conflict) {
+		fmt.Println("Conflicting changes:")
+		for _, path := range conflict.Paths {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	printSyncResult(result, location)
+	return nil
+}
+
+// printSyncResult tells what a sync did, or would do with --dry-run.
+func printSyncResult(result *configsync.Result, location string) {
+	would := func(did, would string) string {
+		if configSyncDryRun {
+			return would
+		}
+		return did
+	}
+
+	if len(result.Conflicts) > 0 {
+		fmt.Printf("%s %d conflicting change(s) in favour of the %s config: %s\n",
+			would("Settled", "Would settle"), len(result.Conflicts), configSyncPrefer, strings.Join(result.Conflicts, ", "))
+	}
+	switch {
+	case result.Pulled:
+		fmt.Printf("%s remote changes from %s into %s\n", would("Merged", "Would merge"), location, GetConfigFile())
+	default:
+		fmt.Println("Local config already has the remote changes")
+	}
+	switch {
+	case result.Created && configSyncPush:
+		fmt.Printf("%s %s from the local config\n", would("Created", "Would create"), location)
+	case result.Pushed || (configSyncDryRun && configSyncPush && result.Unpushed):
+		fmt.Printf("%s local changes to %s\n", would("Pushed", "Would push"), location)
+	case result.Unpushed:
+		fmt.Println("The local config has changes the remote config does not; share them with --push")
+	}
+}
+
+// newConfigProblems returns the problems Validate finds in the merged
+// config that the local config did not already have, so that a sync is
+// not refused for mistakes it did not make.
+func newConfigProblems(local, merged []byte) error {
+	problems := func(data []byte) ([]error, error) {
+		cfg := &config.Config{}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, err
+		}
+		return config.Validate(cfg), nil
+	}
+
+	after, err := problems(merged)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	if before, err := problems(local); err == nil {
+		for _, e := range before {
+			known[e.Error()] = true
+		}
+	}
+
+	var added []error
+	for _, e := range after {
+		if !known[e.Error()] {
+			added = append(added, e)
+		}
+	}
+	return errors.Join(added...)
+}
+
+// objectStore is the remote config of a sync, as an Object Storage object.
+type objectStore struct {
+	client    *client.OCIClient
+	namespace string
+	bucket    string
+	object    string
+}
+
+func (s *objectStore) Get(ctx context.Context) ([]byte, string, error) {
+	data, etag, err := s.client.GetObjectWithETag(ctx, s.namespace, s.bucket, s.object)
+	if err != nil {
+		if client.IsNotFoundError(err) {
+			return nil, "", configsync.ErrNoRemote
+		}
+		return nil, "", client.WrapOCIError(err, "get remote config")
+	}
+	return data, etag, nil
+}
+
+func (s *objectStore) Put(ctx context.Context, data []byte, etag string) (string, error) {
+	newETag, err := s.client.PutObject(ctx, s.namespace, s.bucket, s.object, data, etag)
+	if err != nil {
+		if client.IsPreconditionFailedError(err) {
+			return "", configsync.ErrRemoteChanged
+		}
+		return "", client.WrapOCIError(err, "put remote config")
+	}
+	return newETag, nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/config"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		addr     string
+		wantIP   string
+		wantFqdn string
+		wantPort int
+		wantErr  bool
+	}{
+		{addr: "10.0.0.5:6443", wantIP: "10.0.0.5", wantPort: 6443},
+		{addr: "db.example.oraclevcn.com:1521", wantFqdn: "db.example.oraclevcn.com", wantPort: 1521},
+		{addr: "[fd00::5]:443", wantIP: "fd00::5", wantPort: 443},
+		{addr: "10.0.0.5", wantErr: true},
+		{addr: "10.0.0.5:0", wantErr: true},
+		{addr: ":6443", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			ep, err := parseEndpoint("private", tt.addr)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseEndpoint(%q) should fail", tt.addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEndpoint(%q) error = %v", tt.addr, err)
+			}
+			if ep.Ip != tt.wantIP || ep.Fqdn != tt.wantFqdn || ep.Port != tt.wantPort || ep.Name != "private" {
+				t.Errorf("parseEndpoint(%q) = %+v", tt.addr, ep)
+			}
+		})
+	}
+}
+
+func TestSetEndpoint(t *testing.T) {
+	c := &config.Cluster{Endpoints: []*config.ClusterEndpoint{{Name: "private", Ip: "10.0.0.5", Port: 6443}}}
+
+	setEndpoint(c, &config.ClusterEndpoint{Name: "Private", Ip: "10.0.0.6", Port: 6443})
+	setEndpoint(c, &config.ClusterEndpoint{Name: "metrics", Ip: "10.0.0.9", Port: 9090})
+
+	if len(c.Endpoints) != 2 {
+		t.Fatalf("endpoints = %d, want 2", len(c.Endpoints))
+	}
+	if c.Endpoints[0].Ip != "10.0.0.6" {
+		t.Errorf("endpoint of the same name not replaced: %+v", c.Endpoints[0])
+	}
+	if c.Endpoints[1].Name != "metrics" {
+		t.Errorf("new endpoint not appended: %+v", c.Endpoints[1])
+	}
+}
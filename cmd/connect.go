@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -17,32 +21,113 @@ import (
 	"github.com/scotttball/tunatap/internal/cluster"
 	"github.com/scotttball/tunatap/internal/config"
 	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/events"
+	"github.com/scotttball/tunatap/internal/exitcode"
 	"github.com/scotttball/tunatap/internal/health"
+	"github.com/scotttball/tunatap/internal/ports"
 	"github.com/scotttball/tunatap/internal/preflight"
 	"github.com/scotttball/tunatap/internal/state"
+	"github.com/scotttball/tunatap/internal/tracing"
+	"github.com/scotttball/tunatap/internal/tunnel"
+	"github.com/scotttball/tunatap/internal/ui"
 	"github.com/scotttball/tunatap/pkg/utils"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
 )
 
 var (
-	clusterName       string
-	localPort         int
-	bastionName       string
-	endpointName      string
-	noBastion         bool
-	connectPreflight  bool
-	skipPreflight     bool
-	regionHint        string
-	noCache           bool
-	connectOCIProfile string
+	clusterName         string
+	localPort           int
+	bastionName         string
+	endpointName        string
+	noBastion           bool
+	connectPreflight    bool
+	skipPreflight       bool
+	regionHint          string
+	noCache             bool
+	connectOCIProfile   string
+	connectDetach       bool
+	connectSocksPort    int
+	connectAllEndpoints bool
+	connectMaxBandwidth string
+	connectBind         string
+	connectPortStrategy string
+	connectProfile      string
+	connectEventsJSON   bool
+	connectCreate       bool
+	connectAllowCIDRs   []string
+	connectDryRun       bool
+	connectType         string
+	connectTags         []string
+	connectGroup        string
+	connectHealth       string
+	connectSave         bool
+
+	connectProfileDiscovery bool
+
+	connectWatchEndpoint    time.Duration
+	connectOnEndpointChange string
+
+	connectRetryInitialInterval time.Duration
+	connectRetryMultiplier      float64
+	connectRetryMaxAttempts     int
 )
 
 var connectCmd = &cobra.Command{
-	Use:   "connect [cluster]",
-	Short: "Connect to a cluster through bastion",
+	Use:   "connect [cluster...]",
+	Short: "Connect to clusters through bastion",
 	Long: `Establish an SSH tunnel to a cluster through OCI Bastion service.
 
-If no cluster name is provided, an interactive selector will be shown.`,
+If no cluster name is provided, an interactive selector will be shown.
+
+With several cluster names, a tunnel to each is opened in this one process,
+every cluster on its own local port, and Ctrl-C closes them all. A table of
+the tunnels is printed once they are all ready.
+
+With --detach, the tunnel is handed off to the background daemon (started
+automatically if needed) and the command returns once the tunnel is ready.
+Use 'tunatap status' and 'tunatap stop <cluster>' to manage it.
+
+With --socks, a local SOCKS5 proxy is also exposed that routes arbitrary
+destinations through the bastion SSH connection (like ssh -D). Destinations
+must be reachable from the bastion and permitted by the bastion session.
+
+With --all-endpoints, every endpoint configured for the cluster is forwarded
+over the same bastion session. The selected endpoint uses the cluster's local
+port; every other endpoint must set its own local_port in config.
+
+With --dry-run, discovery and preflight run as usual, then the bastion
+session that would be used and the equivalent ssh command are printed
+without creating anything in OCI.
+
+With --type, a private resource other than an OKE cluster is discovered by
+name and tunnelled to the same way: a DB system's SCAN listener (dbsystem),
+an Autonomous Database's private endpoint (adb) or an instance's SSH port
+(instance).
+
+With --tag instead of a name, the cluster carrying every given freeform
+(key=value) or defined (namespace.key=value) tag is found with OCI Resource
+Search. When several carry the tags, they are listed to choose from.
+
+With --group, a tunnel is opened to every configured cluster of the group,
+as if each were named. Clusters can also be named by their aliases.
+
+With --save, a cluster found by discovery is added to the config file, with
+its OCID, region, endpoint and bastion, so that connecting to it again skips
+discovery. save_discovered_clusters: prompt offers this on a terminal, and
+always does it without --save.
+
+With --watch-endpoint, the cluster's private endpoint and bastion are
+re-fetched at that interval while the tunnel runs, warning when they change.
+With --on-endpoint-change reconnect, the tunnel also moves to the new
+endpoint when a cluster upgrade or endpoint migration changes its IP.
+
+Examples:
+  tunatap connect prod-cluster
+  tunatap connect payments-prod --save
+  tunatap connect --tag env=prod --tag team=payments
+  tunatap connect --group payments
+  tunatap connect --type adb --tag Operations.app=orders`,
 	RunE: runConnect,
 }
 
@@ -51,7 +136,7 @@ func init() {
 
 	connectCmd.Flags().StringVarP(&clusterName, "cluster", "c", "", "cluster name to connect to")
 	connectCmd.Flags().IntVarP(&localPort, "port", "p", 0, "local port for the tunnel (0 or negative for auto)")
-	connectCmd.Flags().StringVarP(&bastionName, "bastion", "b", "", "bastion name to use")
+	connectCmd.Flags().StringVarP(&bastionName, "bastion", "b", "", "bastion name or OCID to use")
 	connectCmd.Flags().StringVarP(&endpointName, "endpoint", "e", "", "endpoint name (e.g., 'private', 'public')")
 	connectCmd.Flags().BoolVar(&noBastion, "no-bastion", false, "connect directly without bastion")
 	connectCmd.Flags().BoolVar(&connectPreflight, "preflight", false, "run preflight checks before connecting")
@@ -59,164 +144,237 @@ func init() {
 	connectCmd.Flags().StringVarP(&regionHint, "region", "r", "", "region hint for cluster discovery (optional)")
 	connectCmd.Flags().BoolVar(&noCache, "no-cache", false, "skip cache and force fresh discovery")
 	connectCmd.Flags().StringVar(&connectOCIProfile, "oci-profile", "", "OCI config profile to use (overrides config)")
+	connectCmd.Flags().IntVar(&connectSocksPort, "socks", 0, "also expose a local SOCKS5 proxy on this port that routes through the bastion")
+	connectCmd.Flags().BoolVar(&connectAllEndpoints, "all-endpoints", false, "forward every configured endpoint of the cluster on its own local port")
+	connectCmd.Flags().StringVar(&connectMaxBandwidth, "max-bandwidth", "", "cap total tunnel throughput, e.g. 512K, 10M (overrides max_bandwidth in config)")
+	connectCmd.Flags().BoolVar(&connectEventsJSON, "events-json", false, "write tunnel lifecycle events to stdout as JSON lines")
+	connectCmd.Flags().StringVar(&connectProfile, "profile", "", "apply a named profile of forwards and kubeconfig settings from config")
+	connectCmd.Flags().StringVar(&connectPortStrategy, "port-strategy", "", "what to do when the local port is busy: increment, fail or takeover (overrides port_strategy in config)")
+	connectCmd.Flags().StringVar(&connectBind, "bind", "", "local IPv4 address to listen on, e.g. 0.0.0.0 (overrides bind_address in config; default localhost)")
+	connectCmd.Flags().StringVar(&connectType, "type", "", "type of resource to discover: oke (default), dbsystem, adb, instance, mysql or postgresql")
+	connectCmd.Flags().StringArrayVar(&connectTags, "tag", nil, "select the cluster by tag, key=value or namespace.key=value (repeatable; all must match)")
+	connectCmd.Flags().StringVar(&connectGroup, "group", "", "connect to every configured cluster in this group")
+	connectCmd.Flags().DurationVar(&connectWatchEndpoint, "watch-endpoint", 0, "re-fetch the cluster's private endpoint at this interval while connected, e.g. 5m (overrides endpoint_watch_interval_seconds in config)")
+	connectCmd.Flags().StringVar(&connectOnEndpointChange, "on-endpoint-change", "", "what to do when the watched endpoint moves: warn or reconnect (overrides endpoint_change_action in config)")
+	connectCmd.Flags().BoolVar(&connectProfileDiscovery, "profile-discovery", false, "report time spent, API calls and rate limits per region and compartment after discovery")
+	connectCmd.Flags().BoolVar(&connectSave, "save", false, "add a cluster found by discovery to the config file")
+	connectCmd.Flags().BoolVar(&connectCreate, "create-bastion", false, "create a standard bastion if discovery finds none for the cluster")
+	connectCmd.Flags().StringArrayVar(&connectAllowCIDRs, "allow-cidr", nil, "client CIDR block allowed to connect to a created bastion (repeatable; overrides bastion_allow_cidrs in config)")
+	connectCmd.Flags().BoolVarP(&connectDetach, "detach", "d", false, "hand the tunnel off to the background daemon and return")
+	connectCmd.Flags().StringVar(&connectHealth, "health-endpoint", "", "address for this invocation's health server, e.g. localhost:9091, or \"off\" (overrides health_endpoint in config)")
+	connectCmd.Flags().BoolVar(&connectDryRun, "dry-run", false, "print the session and ssh command that would be used without creating anything in OCI")
+	connectCmd.Flags().DurationVar(&connectRetryInitialInterval, "retry-initial-interval", 0, "wait before the first retry of a failed tunnel, e.g. 10s (overrides retry in config)")
+	connectCmd.Flags().Float64Var(&connectRetryMultiplier, "retry-multiplier", 0, "factor the wait between retries grows by (overrides retry in config)")
+	connectCmd.Flags().IntVar(&connectRetryMaxAttempts, "retry-max-attempts", 0, "retries of a failed tunnel before giving up, 0 for unlimited (overrides retry in config)")
 }
 
-func runConnect(cmd *cobra.Command, args []string) error {
+func runConnect(cmd *cobra.Command, args []string) (err error) {
+	if connectGroup != "" {
+		if len(args) > 0 || len(connectTags) > 0 || cmd.Flags().Changed("cluster") {
+			return fmt.Errorf("--group cannot be used with cluster names, --cluster or --tag")
+		}
+		cfg, _, err := loadConnectConfig()
+		if err != nil {
+			return err
+		}
+		if args, err = groupClusterNames(cfg, connectGroup); err != nil {
+			return err
+		}
+	}
+
 	// Handle cluster name from args
 	if len(args) > 0 {
 		clusterName = args[0]
 	}
 
-	// Try to load configuration (non-fatal if missing for zero-touch mode)
-	cfg, cfgErr := config.ReadConfig(GetConfigFile())
-	if cfgErr != nil {
-		// Use default config for zero-touch mode
-		log.Debug().Msg("No config file found, using zero-touch mode")
-		cfg = config.DefaultConfig()
-	} else {
-		// Configure globals from config
-		if err := config.ConfigureGlobals(cfg); err != nil {
-			return fmt.Errorf("failed to configure globals: %w", err)
+	maxBandwidth, err := utils.ParseBandwidth(connectMaxBandwidth)
+	if err != nil {
+		return fmt.Errorf("invalid --max-bandwidth: %w", err)
+	}
+	resourceType, err := discovery.ParseResourceType(connectType)
+	if err != nil {
+		return fmt.Errorf("invalid --type: %w", err)
+	}
+	tags, err := parseTagSelectors(connectTags)
+	if err != nil {
+		return err
+	}
+	if connectOnEndpointChange != "" {
+		if _, err := bastion.ParseEndpointChangeAction(connectOnEndpointChange); err != nil {
+			return fmt.Errorf("invalid --on-endpoint-change: %w", err)
 		}
 	}
-
-	// Override OCI profile if specified via flag
-	if connectOCIProfile != "" {
-		cfg.OCIProfile = connectOCIProfile
-		log.Debug().Str("profile", connectOCIProfile).Msg("Using OCI profile from flag")
+	if len(tags) > 0 {
+		if clusterName != "" {
+			return fmt.Errorf("--tag cannot be used with a cluster name")
+		}
+		if connectDetach {
+			return fmt.Errorf("--tag cannot be used with --detach")
+		}
+		if connectCreate {
+			return fmt.Errorf("--tag cannot be used with --create-bastion")
+		}
 	}
 
-	var selectedCluster *config.Cluster
-	var ociClient *client.OCIClient
-	var err error
-
-	// Try to find cluster in config first (if we have a config)
-	if clusterName != "" && cfgErr == nil && !cfg.SkipDiscovery {
-		selectedCluster = config.FindClusterByName(cfg, clusterName)
+	if len(args) > 1 {
+		if err := checkMultiConnectFlags(cmd); err != nil {
+			return err
+		}
 	}
-
-	// If not found in config, try discovery
-	if selectedCluster == nil && clusterName != "" {
-		// Create OCI client with auto-detection for discovery
-		ociClient, err = createOCIClientForDiscovery(cfg)
-		if err != nil {
-			ociErr := client.ClassifyOCIError(err, "create OCI client")
-			if ociErr.Suggestion != "" {
-				return fmt.Errorf("failed to create OCI client: %s\n\n%s", ociErr.Message, ociErr.Suggestion)
-			}
-			return fmt.Errorf("failed to create OCI client: %w", err)
+	if connectSave {
+		if connectDryRun {
+			return fmt.Errorf("--save cannot be used with --dry-run")
 		}
-
-		// Initialize cache
-		var cache *discovery.Cache
-		if !noCache {
-			ttl := time.Duration(cfg.GetCacheTTLHours()) * time.Hour
-			cache, _ = discovery.NewCache(utils.DefaultTunatapDir(), ttl)
+		if connectDetach {
+			return fmt.Errorf("--save cannot be used with --detach")
 		}
+		if resourceType != discovery.ResourceCluster {
+			return fmt.Errorf("--save cannot be used with --type %s", resourceType)
+		}
+	}
+	if connectDryRun {
+		if connectDetach {
+			return fmt.Errorf("--dry-run cannot be used with --detach")
+		}
+		if connectCreate {
+			return fmt.Errorf("--dry-run cannot be used with --create-bastion")
+		}
+	}
 
-		discoverer := discovery.NewDiscoverer(ociClient, cache)
-
-		var discovered *discovery.DiscoveredCluster
-
-		// Check if the input is an OCID - use direct lookup if so
-		if discovery.IsClusterOCID(clusterName) {
-			log.Info().Msgf("Detected cluster OCID, performing direct lookup...")
-			discovered, err = discoverer.DiscoverClusterByOCID(cmd.Context(), clusterName)
-			if err != nil {
-				// Error messages from DiscoverClusterByOCID are already well-formatted
+	if connectDetach {
+		if resourceType != discovery.ResourceCluster {
+			return fmt.Errorf("--type cannot be used with --detach")
+		}
+		if insecureHostKey {
+			return fmt.Errorf("--insecure-host-key cannot be used with --detach")
+		}
+		if connectEventsJSON {
+			return fmt.Errorf("--events-json cannot be used with --detach")
+		}
+		if connectWatchEndpoint != 0 || connectOnEndpointChange != "" {
+			return fmt.Errorf("--watch-endpoint and --on-endpoint-change cannot be used with --detach; set endpoint_watch_interval_seconds and endpoint_change_action in config")
+		}
+		if connectProfileDiscovery {
+			return fmt.Errorf("--profile-discovery cannot be used with --detach")
+		}
+		if connectHealth != "" && connectHealth != "off" {
+			return fmt.Errorf("--health-endpoint cannot be used with --detach; pass it to 'tunatap daemon run'")
+		}
+		if len(args) <= 1 {
+			return runConnectDetached(cmd)
+		}
+		// Each cluster becomes its own daemon tunnel
+		for _, name := range args {
+			clusterName = name
+			if err := runConnectDetached(cmd); err != nil {
 				return err
 			}
-		} else {
-			log.Info().Msgf("Cluster '%s' not found in config, attempting discovery...", clusterName)
-
-			// Perform name-based discovery
-			hints := &discovery.DiscoveryHints{Region: regionHint}
-			discovered, err = discoverer.DiscoverClusterWithHints(cmd.Context(), clusterName, hints)
-			if err != nil {
-				// Check if multiple clusters found - offer interactive selection
-				if errors.Is(err, discovery.ErrMultipleClustersFound) {
-					return err // The error message already contains all matches
-				}
-
-				// Provide better error messages for common failures
-				if errors.Is(err, discovery.ErrClusterNotFound) {
-					return fmt.Errorf("cluster '%s' not found\n\n"+
-						"To find available clusters, try:\n"+
-						"  tunatap list\n\n"+
-						"If the cluster exists, you may need to:\n"+
-						"  - Check that you have IAM policies to list clusters\n"+
-						"  - Specify the region with --region if searching is slow\n"+
-						"  - Use the cluster OCID directly instead of the name", clusterName)
-				}
-
-				if errors.Is(err, discovery.ErrClusterAccessDenied) {
-					return err // Already has good suggestion
-				}
-
-				// Check for auth errors
-				ociErr := client.ClassifyOCIError(err, "cluster discovery")
-				if ociErr.Type == client.ErrorTypeNotAuthenticated {
-					return fmt.Errorf("authentication failed during discovery\n\n%s", ociErr.Suggestion)
-				}
-
-				return fmt.Errorf("discovery failed: %w", err)
-			}
 		}
+		return nil
+	}
 
-		// Discover bastion
-		bastionInfo, err := discoverer.DiscoverBastion(cmd.Context(), discovered)
-		if err != nil {
-			if errors.Is(err, discovery.ErrNoBastionFound) {
-				return fmt.Errorf("no bastion found for cluster '%s'\n\n"+
-					"A bastion is required to connect to private OKE clusters.\n"+
-					"Please ensure:\n"+
-					"  1. A bastion exists in the cluster's compartment\n"+
-					"  2. The bastion is in ACTIVE state\n"+
-					"  3. You have IAM policies to read bastions\n\n"+
-					"To create a bastion, visit the OCI Console:\n"+
-					"  https://cloud.oracle.com/bastion", discovered.Name)
-			}
+	if len(args) > 1 {
+		return runConnectMulti(cmd, args, maxBandwidth)
+	}
 
-			ociErr := client.ClassifyOCIError(err, "bastion discovery")
-			if ociErr.Suggestion != "" {
-				return fmt.Errorf("failed to discover bastion: %s\n\n%s", ociErr.Message, ociErr.Suggestion)
+	// Events go to stdout; logs already go to stderr
+	var eventWriter *events.Writer
+	eventCluster := clusterName
+	if connectEventsJSON {
+		eventWriter = events.NewWriter(os.Stdout)
+		defer func() {
+			closed := events.Event{Type: events.Closed, Cluster: eventCluster}
+			if err != nil && !errors.Is(err, context.Canceled) {
+				closed.Error = err.Error()
 			}
-			return fmt.Errorf("failed to discover bastion: %w", err)
-		}
+			eventWriter.Emit(closed)
+		}()
+	}
 
-		// Convert to config.Cluster
-		selectedCluster, err = discoverer.ResolveToConfig(discovered, bastionInfo)
-		if err != nil {
-			return fmt.Errorf("failed to resolve cluster config: %w", err)
-		}
+	cfg, cfgLoaded, err := loadConnectConfig()
+	if err != nil {
+		return err
+	}
 
-		// Set region on OCI client
-		ociClient.SetRegion(discovered.Region)
-	} else if selectedCluster == nil {
-		// Interactive selection from config (or error if no clusters)
-		selectedCluster, err = selectCluster(cfg, clusterName)
-		if err != nil {
-			return err
-		}
+	// The connect span ends once the tunnel is ready, or when connecting fails
+	defer startTracing(cmd.Context(), cfg)()
+	traceCtx, connectSpan := tracing.Start(cmd.Context(), "connect")
+	var endConnectOnce sync.Once
+	endConnect := func(err error) {
+		endConnectOnce.Do(func() { tracing.End(connectSpan, err) })
+	}
+	defer func() { endConnect(err) }()
+
+	profile, err := loadProfile(cfg, connectProfile)
+	if err != nil {
+		return err
+	}
+	name, endpointToUse, socksPort, allEndpoints := clusterName, endpointName, connectSocksPort, connectAllEndpoints
+	applyProfileDefaults(profile, &name, &endpointToUse, &socksPort, &allEndpoints)
+
+	discovered := len(tags) > 0 || (name != "" && cluster.NeedsDiscovery(cfg, cfgLoaded, name))
+	if name != "" && (resourceType != discovery.ResourceCluster || discovered) {
+		eventWriter.Emit(events.Event{Type: events.Discovering, Cluster: name})
+	}
+	var (
+		selectedCluster *config.Cluster
+		ociClient       *client.OCIClient
+	)
+	resolveCtx, discoveryProfile := withDiscoveryProfile(traceCtx)
+	resolveCtx, discoverySpan := tracing.Start(resolveCtx, "discovery")
+	switch {
+	case len(tags) > 0:
+		eventWriter.Emit(events.Event{Type: events.Discovering, Cluster: discovery.FormatTags(tags)})
+		selectedCluster, ociClient, err = cluster.ResolveTagged(resolveCtx, cfg, tags, regionHint, noCache, resourceType)
+	case connectCreate:
+		selectedCluster, ociClient, err = resolveClusterCreatingBastion(resolveCtx, cfg, cfgLoaded, name, regionHint, noCache, resourceType, connectAllowCIDRs)
+	default:
+		selectedCluster, ociClient, err = resolveResource(resolveCtx, cfg, cfgLoaded, name, regionHint, noCache, resourceType)
+	}
+	tracing.End(discoverySpan, err)
+	discoveryProfile.Report(os.Stderr)
+	if err != nil {
+		return err
+	}
+	eventCluster = selectedCluster.ClusterName
+	connectSpan.SetAttributes(tracing.ClusterKey.String(selectedCluster.ClusterName))
+	if discovered && resourceType == discovery.ResourceCluster && !connectDryRun {
+		saveDiscoveredCluster(selectedCluster)
 	}
 
 	// Override bastion if specified
 	if bastionName != "" {
 		selectedCluster.Bastion = &bastionName
 	}
+	if connectBind != "" {
+		selectedCluster.BindAddress = &connectBind
+	}
+	if connectPortStrategy != "" {
+		selectedCluster.PortStrategy = &connectPortStrategy
+	}
+	selectedCluster.Retry = config.MergeRetryPolicy(selectedCluster.Retry, retryFlagPolicy(cmd))
 
 	// Get endpoint
-	endpoint := config.GetClusterEndpoint(selectedCluster, endpointName)
+	endpoint := config.GetClusterEndpoint(selectedCluster, endpointToUse)
 	if endpoint == nil {
 		return fmt.Errorf("no endpoints configured for cluster '%s'", selectedCluster.ClusterName)
 	}
 
 	log.Info().Msgf("Connecting to cluster: %s", selectedCluster.ClusterName)
-	log.Info().Msgf("Endpoint: %s:%d", endpoint.Ip, endpoint.Port)
+	log.Info().Msgf("Endpoint: %s:%d", endpoint.Host(), endpoint.Port)
+
+	additionalEndpoints, err := resolveAdditionalEndpoints(selectedCluster, endpoint, allEndpoints)
+	if err != nil {
+		return err
+	}
+	additionalEndpoints, err = withProfileForwards(additionalEndpoints, profile)
+	if err != nil {
+		return err
+	}
 
 	// Create OCI client if not already created (for config-based flow)
 	if ociClient == nil {
-		ociClient, err = createOCIClient(cfg, selectedCluster.Region)
+		ociClient, err = createOCIClient(cfg, selectedCluster)
 		if err != nil {
 			return fmt.Errorf("failed to create OCI client: %w", err)
 		}
@@ -224,7 +382,7 @@ func runConnect(cmd *cobra.Command, args []string) error {
 
 	// Validate and update cluster configuration
 	useBastion := !noBastion
-	if err := cluster.ValidateAndUpdateCluster(cmd.Context(), ociClient, selectedCluster, useBastion, localPort); err != nil {
+	if err := cluster.ValidateAndUpdateCluster(traceCtx, ociClient, selectedCluster, useBastion, localPort); err != nil {
 		return fmt.Errorf("failed to validate cluster: %w", err)
 	}
 
@@ -239,23 +397,59 @@ func runConnect(cmd *cobra.Command, args []string) error {
 			Timeout:   10 * time.Second,
 		}
 		checker := preflight.NewChecker(opts)
-		results := checker.RunAll(cmd.Context())
+		results := checker.RunAll(traceCtx)
 		preflight.PrintResults(results, true)
 
 		if preflight.HasErrors(results) {
-			return fmt.Errorf("preflight checks failed - fix errors before connecting")
+			return exitcode.Wrap(exitcode.PreflightFailed, fmt.Errorf("preflight checks failed - fix errors before connecting"))
 		}
 	} else if !skipPreflight {
 		// Quick check - just verify bastion is healthy
-		if err := preflight.RunQuickCheck(cmd.Context(), ociClient, selectedCluster); err != nil {
+		checkCtx, span := tracing.Start(traceCtx, "preflight")
+		err := preflight.RunQuickCheck(checkCtx, ociClient, selectedCluster)
+		tracing.End(span, err)
+		if err != nil {
 			log.Warn().Err(err).Msg("Quick preflight check failed (use --skip-preflight to ignore)")
 		}
 	}
 
-	log.Info().Msgf("Local port: %d", *selectedCluster.LocalPort)
+	opts := &bastion.TunnelOptions{
+		SocksPort:           socksPort,
+		AdditionalEndpoints: additionalEndpoints,
+		MaxBandwidth:        maxBandwidth,
+		OnReady:             profileKubeconfigHook(cfg, selectedCluster, profile),
+		Events:              eventWriter,
+
+		EndpointWatchInterval: connectWatchEndpoint,
+		EndpointChangeAction:  bastion.EndpointChangeAction(connectOnEndpointChange),
+	}
+	if connectDryRun {
+		plan, err := bastion.PlanTunnel(traceCtx, ociClient, cfg, selectedCluster, endpoint, opts)
+		if err != nil {
+			return err
+		}
+		printTunnelPlan(selectedCluster, endpoint, plan)
+		return nil
+	}
+	onReady := opts.OnReady
+	opts.OnReady = func(port int) {
+		endConnect(nil)
+		if onReady != nil {
+			onReady(port)
+		}
+	}
+	defer claimLocalPort(selectedCluster, selectedCluster.ClusterName, "")()
+
+	if selectedCluster.LocalSocket != nil && *selectedCluster.LocalSocket != "" {
+		log.Info().Msgf("Local socket: %s", *selectedCluster.LocalSocket)
+	} else if selectedCluster.BindAddress != nil && *selectedCluster.BindAddress != "" {
+		log.Info().Msgf("Local address: %s:%d", *selectedCluster.BindAddress, *selectedCluster.LocalPort)
+	} else {
+		log.Info().Msgf("Local port: %d", *selectedCluster.LocalPort)
+	}
 
 	// Set up signal handling for graceful shutdown
-	ctx, cancel := context.WithCancel(cmd.Context())
+	ctx, cancel := context.WithCancel(traceCtx)
 	defer cancel()
 
 	sigChan := make(chan os.Signal, 1)
@@ -277,29 +471,14 @@ func runConnect(cmd *cobra.Command, args []string) error {
 	}
 
 	// Set up audit logging if enabled
-	var auditLogger *audit.Logger
-	if cfg.IsAuditLoggingEnabled() {
-		// Use configured home path from state, fall back to default
-		homePath := state.GetInstance().GetHomePath()
-		if homePath == "" {
-			homePath = utils.DefaultTunatapDir()
-		}
-		audit.SetHomePath(homePath)
-
-		var err error
-		auditLogger, err = audit.NewLogger(audit.DefaultLogDir())
-		if err != nil {
-			log.Warn().Err(err).Msg("Failed to create audit logger")
-		} else {
-			defer auditLogger.Close()
-		}
+	auditLogger := newAuditLogger(ctx, cfg)
+	if auditLogger != nil {
+		defer auditLogger.Close()
 	}
 
 	// Start the tunnel
 	if useBastion {
-		opts := &bastion.TunnelOptions{
-			AuditLogger: auditLogger,
-		}
+		opts.AuditLogger = auditLogger
 		return bastion.TunnelThroughBastionWithOptions(ctx, ociClient, cfg, selectedCluster, endpoint, opts)
 	}
 
@@ -307,20 +486,361 @@ func runConnect(cmd *cobra.Command, args []string) error {
 	return fmt.Errorf("direct connection without bastion not yet implemented")
 }
 
-// createOCIClientForDiscovery creates an OCI client for discovery operations.
-// Uses auto-detection of authentication without requiring config values.
-func createOCIClientForDiscovery(cfg *config.Config) (*client.OCIClient, error) {
-	configPath := cfg.OCIConfigPath
-	if configPath == "" {
-		configPath = utils.DefaultOCIConfigPath()
+// retryFlagPolicy returns the retry settings given on the command line, or
+// nil if there are none.
+func retryFlagPolicy(cmd *cobra.Command) *config.RetryPolicy {
+	flags := cmd.Flags()
+	if !flags.Changed("retry-initial-interval") && !flags.Changed("retry-multiplier") && !flags.Changed("retry-max-attempts") {
+		return nil
 	}
 
-	profile := cfg.OCIProfile
-	if profile == "" {
-		profile = "DEFAULT"
+	policy := &config.RetryPolicy{}
+	if flags.Changed("retry-initial-interval") {
+		seconds := int(connectRetryInitialInterval / time.Second)
+		policy.InitialIntervalSeconds = &seconds
 	}
+	if flags.Changed("retry-multiplier") {
+		policy.Multiplier = &connectRetryMultiplier
+	}
+	if flags.Changed("retry-max-attempts") {
+		policy.MaxAttempts = &connectRetryMaxAttempts
+	}
+	return policy
+}
 
-	return client.NewOCIClientAuto(configPath, profile)
+// printTunnelPlan prints what connecting to endpoint of c would do.
+func printTunnelPlan(c *config.Cluster, endpoint *config.ClusterEndpoint, plan *bastion.TunnelPlan) {
+	fmt.Printf("Dry run for cluster %s: nothing was created in OCI.\n", c.ClusterName)
+	bastionDesc := plan.BastionID
+	if plan.BastionName != "" {
+		bastionDesc = fmt.Sprintf("%s (%s)", plan.BastionName, plan.BastionID)
+	}
+	fmt.Printf("  Bastion:   %s, %s\n", bastionDesc, plan.BastionType)
+	if len(plan.FallbackBastionIDs) > 0 {
+		fmt.Printf("  Fallbacks: %s\n", strings.Join(plan.FallbackBastionIDs, ", "))
+	}
+	fmt.Printf("  Endpoint:  %s:%d\n", endpoint.Host(), endpoint.Port)
+	switch {
+	case plan.ExistingSessionID != "":
+		fmt.Printf("  Session:   reuse active session %s targeting %s\n", plan.ExistingSessionID, plan.Target)
+	case plan.BastionType == "INTERNAL":
+		fmt.Printf("  Session:   none, internal bastions log in to the jump box at %s\n", plan.Target)
+	default:
+		fmt.Printf("  Session:   create %s targeting %s for %s\n", plan.DisplayName, plan.Target, plan.TTL)
+	}
+	fmt.Printf("  Key:       %s\n", plan.KeySource)
+	fmt.Printf("  Local:     %s\n", multiTunnelLocal(c))
+	fmt.Printf("Equivalent ssh command:\n  %s\n", plan.SSHCommand)
+}
+
+// readConfig reads the config file at path for a command that uses
+// clusters and configures the global state from it. A missing file reads
+// as the defaults of zero-touch mode; a file that cannot be read or parsed,
+// or that breaks strict_config, is an error. It reports whether the file
+// exists.
+func readConfig(path string) (*config.Config, bool, error) {
+	cfg, err := config.ReadConfig(path)
+	if err != nil {
+		return nil, false, err
+	}
+	loaded := true
+	if _, err := os.Stat(utils.ExpandPath(path)); errors.Is(err, fs.ErrNotExist) {
+		log.Debug().Msg("No config file found, using zero-touch mode")
+		loaded = false
+	}
+	if err := config.ConfigureGlobals(cfg); err != nil {
+		return nil, false, fmt.Errorf("failed to configure globals: %w", err)
+	}
+	return cfg, loaded, nil
+}
+
+// loadConnectConfig reads the config file for connecting as readConfig
+// does, and applies the host key policy, --oci-profile, --health-endpoint
+// and the SSH credentials. It reports whether a config file was loaded.
+func loadConnectConfig() (*config.Config, bool, error) {
+	cfg, cfgLoaded, err := readConfig(GetConfigFile())
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := configureHostKeys(cfg, true); err != nil {
+		return nil, false, err
+	}
+
+	// Override OCI profile if specified via flag
+	if connectOCIProfile != "" {
+		cfg.OverrideOCIProfile(connectOCIProfile)
+		log.Debug().Str("profile", connectOCIProfile).Msg("Using OCI profile from flag")
+	}
+	switch connectHealth {
+	case "":
+	case "off":
+		cfg.HealthEndpoint = ""
+	default:
+		cfg.HealthEndpoint = connectHealth
+	}
+	if err := configureCredentials(context.Background(), cfg); err != nil {
+		return nil, false, err
+	}
+
+	return cfg, cfgLoaded, nil
+}
+
+// resolveAdditionalEndpoints returns the endpoints to forward alongside the
+// primary one when all is set, or nil otherwise.
+func resolveAdditionalEndpoints(c *config.Cluster, primary *config.ClusterEndpoint, all bool) ([]*config.ClusterEndpoint, error) {
+	if !all {
+		return nil, nil
+	}
+
+	endpoints, err := config.GetAdditionalEndpoints(c, primary)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ep := range endpoints {
+		protocol := "tcp"
+		if ep.IsUDP() {
+			protocol = "udp"
+		}
+		log.Info().Msgf("Additional endpoint %s: %s:%d/%s on local port %d", ep.Name, ep.Host(), ep.Port, protocol, *ep.LocalPort)
+	}
+	return endpoints, nil
+}
+
+// newAuditLogger creates an audit logger if audit logging is enabled,
+// shipping events to the configured audit sinks. Returns nil if logging is
+// disabled or the logger could not be created.
+func newAuditLogger(ctx context.Context, cfg *config.Config) *audit.Logger {
+	if !cfg.IsAuditLoggingEnabled() {
+		return nil
+	}
+
+	// Use configured home path from state, fall back to default
+	homePath := state.GetInstance().GetHomePath()
+	if homePath == "" {
+		homePath = utils.DefaultTunatapDir()
+	}
+	audit.SetHomePath(homePath)
+
+	auditLogger, err := audit.NewLogger(audit.DefaultLogDir())
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to create audit logger")
+		return nil
+	}
+	if cfg.AuditHashChain {
+		if err := enableAuditHashChain(auditLogger); err != nil {
+			log.Warn().Err(err).Msg("Failed to enable the audit log hash chain; events are logged unchained")
+		}
+	}
+	if err := auditLogger.AddSinks(ctx, cfg.AuditSinks, auditSinkDeps(cfg)); err != nil {
+		log.Warn().Err(err).Msg("Failed to create audit sinks; events are still logged locally")
+	}
+	return auditLogger
+}
+
+// startTracing exports traces of connecting as cfg configures, returning a
+// function that flushes them. Failing to set it up only warns.
+func startTracing(ctx context.Context, cfg *config.Config) func() {
+	stop, err := tracing.Setup(ctx, cfg.Tracing, version, secretResolver(cfg).Resolve)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to set up tracing; connecting without it")
+		return func() {}
+	}
+	return stop
+}
+
+// enableAuditHashChain chains the records of auditLogger and signs them with
+// the audit signing key, creating it on first use.
+func enableAuditHashChain(auditLogger *audit.Logger) error {
+	key, err := audit.LoadOrCreateKey(audit.DefaultKeyPath())
+	if err != nil {
+		return err
+	}
+	return auditLogger.EnableHashChain(key)
+}
+
+// auditSinkDeps resolves the secrets of audit sinks and puts OCI Logging
+// entries with the OCI credentials of cfg.
+func auditSinkDeps(cfg *config.Config) audit.SinkDeps {
+	resolver := secretResolver(cfg)
+	return audit.SinkDeps{
+		Resolve: resolver.Resolve,
+		LogClient: func(region string) (audit.LogPutter, error) {
+			return cluster.NewOCIClient(cfg, region)
+		},
+	}
+}
+
+// configureHostKeys sets how bastion host keys are verified from config and
+// --insecure-host-key. With interactive set, unknown keys can be confirmed on
+// the terminal.
+func configureHostKeys(cfg *config.Config, interactive bool) error {
+	mode, err := tunnel.ParseHostKeyMode(cfg.SshHostKeyPolicy)
+	if err != nil {
+		return err
+	}
+
+	policy := tunnel.DefaultHostKeyPolicy()
+	policy.Mode = mode
+	policy.Insecure = insecureHostKey
+	if interactive && ui.CanPrompt() {
+		policy.Prompt = promptHostKey
+	}
+
+	tunnel.SetHostKeyPolicy(policy)
+	return nil
+}
+
+// promptHostKey asks on the terminal whether to trust a bastion host key.
+func promptHostKey(host string, key ssh.PublicKey) (bool, error) {
+	fmt.Fprintf(os.Stderr, "The authenticity of bastion host %s can't be established.\n", host)
+	fmt.Fprintf(os.Stderr, "%s key fingerprint is %s.\n", key.Type(), ssh.FingerprintSHA256(key))
+	fmt.Fprint(os.Stderr, "Trust this key and continue connecting? [y/N]: ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// claimLocalPort records this process in the port registry as the holder of
+// the cluster's local port, so a later --port-strategy takeover can stop it.
+// daemonSocket is set for daemon-managed tunnels. The returned function
+// releases the claim.
+func claimLocalPort(c *config.Cluster, name, daemonSocket string) func() {
+	if c.LocalPort == nil || (c.LocalSocket != nil && *c.LocalSocket != "") {
+		return func() {}
+	}
+
+	port := *c.LocalPort
+	registry := ports.Default()
+	entry := ports.Entry{PID: os.Getpid(), Cluster: name, DaemonSocket: daemonSocket}
+	if err := registry.Claim(port, entry); err != nil {
+		log.Warn().Err(err).Msgf("Failed to record port %d in the port registry", port)
+		return func() {}
+	}
+	return func() { registry.Release(port, name) }
+}
+
+// withDiscoveryProfile returns ctx carrying a new discovery profile to
+// report, if --profile-discovery is set.
+func withDiscoveryProfile(ctx context.Context) (context.Context, *discovery.Profile) {
+	if !connectProfileDiscovery {
+		return ctx, nil
+	}
+	profile := discovery.NewProfile()
+	return discovery.WithProfile(ctx, profile), profile
+}
+
+// resolveCluster finds the named cluster in config or through discovery, or
+// lets the user pick a configured cluster when no name is given. The OCI
+// client is only returned when discovery created one.
+func resolveCluster(ctx context.Context, cfg *config.Config, cfgLoaded bool, name, region string, skipCache bool) (*config.Cluster, *client.OCIClient, error) {
+	if name == "" {
+		// Interactive selection from config (or error if no clusters)
+		selectedCluster, err := selectCluster(cfg, name)
+		return selectedCluster, nil, err
+	}
+	return cluster.Resolve(ctx, cfg, cfgLoaded, name, region, skipCache)
+}
+
+// resolveResource is resolveCluster for a private resource of the given
+// type, which is always discovered by name.
+func resolveResource(ctx context.Context, cfg *config.Config, cfgLoaded bool, name, region string, skipCache bool, resourceType discovery.ResourceType) (*config.Cluster, *client.OCIClient, error) {
+	if resourceType == discovery.ResourceCluster {
+		return resolveCluster(ctx, cfg, cfgLoaded, name, region, skipCache)
+	}
+	if name == "" {
+		return nil, nil, fmt.Errorf("a name is required with --type %s", resourceType)
+	}
+	return cluster.ResolveResource(ctx, cfg, cfgLoaded, name, region, skipCache, resourceType)
+}
+
+// saveDiscoveredCluster adds c, just found by discovery, to the config
+// file when --save is given or save_discovered_clusters allows it, so that
+// connecting to it again skips discovery. Failing to save only warns, as
+// the tunnel does not depend on it.
+func saveDiscoveredCluster(c *config.Cluster) {
+	fileCfg, err := config.ReadConfigFile(GetConfigFile())
+	if err != nil {
+		log.Warn().Err(err).Msg("Not saving the discovered cluster")
+		return
+	}
+	mode := fileCfg.SaveDiscoveredClusters
+	if connectSave {
+		mode = "always"
+	}
+	if mode != "prompt" && mode != "always" {
+		return
+	}
+	if config.FindClusterByName(fileCfg, c.ClusterName) != nil {
+		log.Debug().Str("cluster", c.ClusterName).Msg("Discovered cluster is already in the config file")
+		return
+	}
+
+	if mode == "prompt" {
+		if !ui.CanPrompt() {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Save cluster '%s' to %s so later connects skip discovery? [y/N]: ", c.ClusterName, GetConfigFile())
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		if answer != "y" && answer != "yes" {
+			return
+		}
+	}
+
+	saved := &config.Cluster{
+		ClusterName:        c.ClusterName,
+		Region:             c.Region,
+		Ocid:               c.Ocid,
+		CompartmentOcid:    c.CompartmentOcid,
+		BastionId:          c.BastionId,
+		BastionType:        c.BastionType,
+		FallbackBastionIds: c.FallbackBastionIds,
+		Endpoints:          c.Endpoints,
+	}
+	fileCfg.Clusters = append(fileCfg.Clusters, saved)
+	if err := saveEditedConfig(fileCfg, true); err != nil {
+		log.Warn().Err(err).Msgf("Could not save cluster '%s' to config", c.ClusterName)
+		return
+	}
+	log.Info().Msgf("Saved cluster '%s' to %s", c.ClusterName, GetConfigFile())
+}
+
+// groupClusterNames returns the names of the configured clusters in group,
+// for --group.
+func groupClusterNames(cfg *config.Config, group string) ([]string, error) {
+	clusters := config.FindClustersByGroup(cfg, group)
+	if len(clusters) == 0 {
+		return nil, exitcode.Wrap(exitcode.NotFound, fmt.Errorf("no configured cluster is in group '%s'", group))
+	}
+	names := make([]string, len(clusters))
+	for i, c := range clusters {
+		names[i] = c.ClusterName
+	}
+	return names, nil
+}
+
+// parseTagSelectors parses the values of --tag.
+func parseTagSelectors(values []string) ([]discovery.TagSelector, error) {
+	tags := make([]discovery.TagSelector, 0, len(values))
+	for _, v := range values {
+		t, err := discovery.ParseTagSelector(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// createOCIClientForDiscovery creates an OCI client for discovery operations.
+func createOCIClientForDiscovery(cfg *config.Config) (*client.OCIClient, error) {
+	return cluster.NewDiscoveryClient(cfg)
 }
 
 func selectCluster(cfg *config.Config, name string) (*config.Cluster, error) {
@@ -341,6 +861,14 @@ func selectCluster(cfg *config.Config, name string) (*config.Cluster, error) {
 		return cfg.Clusters[0], nil
 	}
 
+	if !ui.CanPrompt() {
+		names := make([]string, len(cfg.Clusters))
+		for i, c := range cfg.Clusters {
+			names[i] = c.ClusterName
+		}
+		return nil, ui.PromptError("choosing a cluster", "name one of: "+strings.Join(names, ", "))
+	}
+
 	f, err := fzf.New()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create selector: %w", err)
@@ -350,36 +878,14 @@ func selectCluster(cfg *config.Config, name string) (*config.Cluster, error) {
 		return fmt.Sprintf("%s (%s)", cfg.Clusters[i].ClusterName, cfg.Clusters[i].Region)
 	})
 	if err != nil || len(idxs) == 0 {
-		return nil, fmt.Errorf("no cluster selected")
+		return nil, exitcode.Wrap(exitcode.Cancelled, fmt.Errorf("no cluster selected"))
 	}
 
 	return cfg.Clusters[idxs[0]], nil
 }
 
-func createOCIClient(cfg *config.Config, region string) (*client.OCIClient, error) {
-	// Determine auth type
-	authType := client.AuthTypeAuto
-	if cfg.OCIAuthType != "" {
-		authType = client.AuthType(cfg.OCIAuthType)
-	}
-
-	// Determine config path and profile
-	configPath := cfg.OCIConfigPath
-	if configPath == "" {
-		configPath = utils.DefaultOCIConfigPath()
-	}
-
-	profile := cfg.OCIProfile
-	if profile == "" {
-		profile = "DEFAULT"
-	}
-
-	// Create client with appropriate auth type
-	ociClient, err := client.NewOCIClientWithAuthType(authType, configPath, profile)
-	if err != nil {
-		return nil, err
-	}
-
-	ociClient.SetRegion(region)
-	return ociClient, nil
+// createOCIClient creates an OCI client for the region of c with the
+// credentials configured for it.
+func createOCIClient(cfg *config.Config, c *config.Cluster) (*client.OCIClient, error) {
+	return cluster.NewClusterOCIClient(cfg, c, c.Region)
 }
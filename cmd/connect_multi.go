@@ -0,0 +1,352 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/events"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/health"
+	"github.com/scotttball/tunatap/internal/ports"
+	"github.com/scotttball/tunatap/internal/preflight"
+	"github.com/scotttball/tunatap/internal/tracing"
+	"github.com/spf13/cobra"
+)
+
+// multiTunnel is one of the tunnels opened by a multi-cluster connect.
+type multiTunnel struct {
+	cluster             *config.Cluster
+	endpoint            *config.ClusterEndpoint
+	additionalEndpoints []*config.ClusterEndpoint
+	ociClient           *client.OCIClient
+}
+
+// checkMultiConnectFlags rejects flags that only make sense for a single
+// cluster.
+func checkMultiConnectFlags(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("cluster") {
+		return fmt.Errorf("--cluster cannot be used with several clusters")
+	}
+	if localPort != 0 {
+		return fmt.Errorf("--port cannot be used with several clusters; set local_port for each cluster in config")
+	}
+	if connectSocksPort != 0 {
+		return fmt.Errorf("--socks cannot be used with several clusters")
+	}
+	if connectProfile != "" {
+		return fmt.Errorf("--profile cannot be used with several clusters")
+	}
+	if cmd.Flags().Changed("type") {
+		return fmt.Errorf("--type cannot be used with several clusters")
+	}
+	if len(connectTags) > 0 {
+		return fmt.Errorf("--tag cannot be used with cluster names")
+	}
+	return nil
+}
+
+// runConnectMulti opens a tunnel to each named cluster in this process. All
+// clusters are resolved and given distinct local ports before any tunnel
+// starts, and one shutdown signal closes them all.
+func runConnectMulti(cmd *cobra.Command, names []string, maxBandwidth int64) (err error) {
+	if noBastion {
+		return fmt.Errorf("direct connection without bastion not yet implemented")
+	}
+
+	var eventWriter *events.Writer
+	if connectEventsJSON {
+		eventWriter = events.NewWriter(os.Stdout)
+	}
+
+	cfg, cfgLoaded, err := loadConnectConfig()
+	if err != nil {
+		return err
+	}
+
+	// The connect span ends once every tunnel is ready, or when connecting
+	// fails
+	defer startTracing(cmd.Context(), cfg)()
+	traceCtx, connectSpan := tracing.Start(cmd.Context(), "connect", tracing.ClusterKey.StringSlice(names))
+	var endConnectOnce sync.Once
+	endConnect := func(err error) {
+		endConnectOnce.Do(func() { tracing.End(connectSpan, err) })
+	}
+	defer func() { endConnect(err) }()
+
+	usedPorts := make(map[int]string)
+	tunnels := make([]*multiTunnel, 0, len(names))
+	resolveCtx, discoveryProfile := withDiscoveryProfile(traceCtx)
+	for _, name := range names {
+		t, err := prepareMultiTunnel(resolveCtx, cmd, cfg, cfgLoaded, name, usedPorts, eventWriter)
+		if err != nil {
+			discoveryProfile.Report(os.Stderr)
+			return fmt.Errorf("cluster '%s': %w", name, err)
+		}
+		tunnels = append(tunnels, t)
+	}
+	discoveryProfile.Report(os.Stderr)
+
+	if connectDryRun {
+		for _, t := range tunnels {
+			opts := &bastion.TunnelOptions{AdditionalEndpoints: t.additionalEndpoints, MaxBandwidth: maxBandwidth}
+			plan, err := bastion.PlanTunnel(traceCtx, t.ociClient, cfg, t.cluster, t.endpoint, opts)
+			if err != nil {
+				return fmt.Errorf("cluster '%s': %w", t.cluster.ClusterName, err)
+			}
+			printTunnelPlan(t.cluster, t.endpoint, plan)
+		}
+		return nil
+	}
+	for _, t := range tunnels {
+		defer claimLocalPort(t.cluster, t.cluster.ClusterName, "")()
+	}
+
+	// Set up signal handling for graceful shutdown
+	ctx, cancel := context.WithCancel(traceCtx)
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Info().Msg("Received shutdown signal, closing tunnels...")
+		cancel()
+	}()
+
+	// Start health server if configured
+	if cfg.HealthEndpoint != "" {
+		stopHealth, err := health.StartHealthServer(cfg.HealthEndpoint)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to start health server")
+		} else {
+			defer stopHealth()
+		}
+	}
+
+	// Set up audit logging if enabled
+	auditLogger := newAuditLogger(ctx, cfg)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	// Print the table once every tunnel has been ready at least once
+	var readyWG sync.WaitGroup
+	readyWG.Add(len(tunnels))
+	allReady := make(chan struct{})
+	go func() {
+		readyWG.Wait()
+		close(allReady)
+	}()
+	go func() {
+		select {
+		case <-allReady:
+			endConnect(nil)
+			printMultiTunnelTable(tunnels)
+		case <-ctx.Done():
+		}
+	}()
+
+	errs := make([]error, len(tunnels))
+	var wg sync.WaitGroup
+	for i, t := range tunnels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var readyOnce sync.Once
+			opts := &bastion.TunnelOptions{
+				AuditLogger:         auditLogger,
+				AdditionalEndpoints: t.additionalEndpoints,
+				MaxBandwidth:        maxBandwidth,
+				OnReady:             func(int) { readyOnce.Do(readyWG.Done) },
+				Events:              eventWriter,
+
+				EndpointWatchInterval: connectWatchEndpoint,
+				EndpointChangeAction:  bastion.EndpointChangeAction(connectOnEndpointChange),
+			}
+			err := bastion.TunnelThroughBastionWithOptions(ctx, t.ociClient, cfg, t.cluster, t.endpoint, opts)
+
+			closed := events.Event{Type: events.Closed, Cluster: t.cluster.ClusterName}
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Error().Err(err).Msgf("Tunnel to %s closed", t.cluster.ClusterName)
+				closed.Error = err.Error()
+				errs[i] = fmt.Errorf("cluster '%s': %w", t.cluster.ClusterName, err)
+			}
+			eventWriter.Emit(closed)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// prepareMultiTunnel resolves and validates one cluster of a multi-cluster
+// connect, moving it off any local port already given to another cluster.
+func prepareMultiTunnel(ctx context.Context, cmd *cobra.Command, cfg *config.Config, cfgLoaded bool, name string, usedPorts map[int]string, eventWriter *events.Writer) (*multiTunnel, error) {
+	discovered := cluster.NeedsDiscovery(cfg, cfgLoaded, name)
+	if discovered {
+		eventWriter.Emit(events.Event{Type: events.Discovering, Cluster: name})
+	}
+
+	var (
+		selectedCluster *config.Cluster
+		ociClient       *client.OCIClient
+		err             error
+	)
+	resolveCtx, span := tracing.Start(ctx, "discovery", tracing.ClusterKey.String(name))
+	if connectCreate {
+		selectedCluster, ociClient, err = resolveClusterCreatingBastion(resolveCtx, cfg, cfgLoaded, name, regionHint, noCache, discovery.ResourceCluster, connectAllowCIDRs)
+	} else {
+		selectedCluster, ociClient, err = resolveCluster(resolveCtx, cfg, cfgLoaded, name, regionHint, noCache)
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	if discovered && !connectDryRun {
+		saveDiscoveredCluster(selectedCluster)
+	}
+
+	if bastionName != "" {
+		selectedCluster.Bastion = &bastionName
+	}
+	if connectBind != "" {
+		selectedCluster.BindAddress = &connectBind
+	}
+	if connectPortStrategy != "" {
+		selectedCluster.PortStrategy = &connectPortStrategy
+	}
+	selectedCluster.Retry = config.MergeRetryPolicy(selectedCluster.Retry, retryFlagPolicy(cmd))
+
+	endpoint := config.GetClusterEndpoint(selectedCluster, endpointName)
+	if endpoint == nil {
+		return nil, fmt.Errorf("no endpoints configured for cluster '%s'", selectedCluster.ClusterName)
+	}
+
+	additionalEndpoints, err := resolveAdditionalEndpoints(selectedCluster, endpoint, connectAllEndpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	if ociClient == nil {
+		ociClient, err = createOCIClient(cfg, selectedCluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	if err := cluster.ValidateAndUpdateCluster(ctx, ociClient, selectedCluster, true, 0); err != nil {
+		return nil, fmt.Errorf("failed to validate cluster: %w", err)
+	}
+	if err := reserveLocalPort(selectedCluster, usedPorts); err != nil {
+		return nil, err
+	}
+
+	if connectPreflight {
+		checker := preflight.NewChecker(&preflight.CheckOptions{
+			Config:    cfg,
+			Cluster:   selectedCluster,
+			OCIClient: ociClient,
+			Verbose:   true,
+			Timeout:   10 * time.Second,
+		})
+		results := checker.RunAll(ctx)
+		preflight.PrintResults(results, true)
+		if preflight.HasErrors(results) {
+			return nil, exitcode.Wrap(exitcode.PreflightFailed, fmt.Errorf("preflight checks failed - fix errors before connecting"))
+		}
+	} else if !skipPreflight {
+		if err := preflight.RunQuickCheck(ctx, ociClient, selectedCluster); err != nil {
+			log.Warn().Err(err).Msg("Quick preflight check failed (use --skip-preflight to ignore)")
+		}
+	}
+
+	return &multiTunnel{
+		cluster:             selectedCluster,
+		endpoint:            endpoint,
+		additionalEndpoints: additionalEndpoints,
+		ociClient:           ociClient,
+	}, nil
+}
+
+// reserveLocalPort records the cluster's local port in used, keyed by port.
+// Tunnels of one command do not listen yet when ports are chosen, so a port
+// given to an earlier cluster still looks free; such a port is treated as
+// busy and handled by the cluster's port strategy.
+func reserveLocalPort(c *config.Cluster, used map[int]string) error {
+	if c.LocalPort == nil || (c.LocalSocket != nil && *c.LocalSocket != "") {
+		return nil
+	}
+
+	strategyName := ""
+	if c.PortStrategy != nil {
+		strategyName = *c.PortStrategy
+	}
+	strategy, err := ports.ParseStrategy(strategyName)
+	if err != nil {
+		return err
+	}
+
+	port := *c.LocalPort
+	for used[port] != "" {
+		if strategy != ports.StrategyIncrement {
+			return fmt.Errorf("local port %d is also used by cluster '%s'; set a different local_port", port, used[port])
+		}
+		if port, err = cluster.FindAvailablePort(port + 1); err != nil {
+			return err
+		}
+	}
+
+	if port != *c.LocalPort {
+		log.Info().Msgf("Local port %d is used by cluster '%s', using %d for '%s'", *c.LocalPort, used[*c.LocalPort], port, c.ClusterName)
+		c.LocalPort = &port
+	}
+	used[port] = c.ClusterName
+	return nil
+}
+
+// printMultiTunnelTable prints the tunnels of a multi-cluster connect with
+// their health from the registry.
+func printMultiTunnelTable(tunnels []*multiTunnel) {
+	healthy := make(map[string]bool)
+	for _, status := range health.GetRegistry().GetStatus().Tunnels {
+		healthy[status.Cluster] = status.Healthy
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tLOCAL\tENDPOINT\tSTATUS")
+	for _, t := range tunnels {
+		status := "unhealthy"
+		if healthy[t.cluster.ClusterName] {
+			status = "ready"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s:%d\t%s\n", t.cluster.ClusterName, multiTunnelLocal(t.cluster), t.endpoint.Host(), t.endpoint.Port, status)
+	}
+	w.Flush()
+	fmt.Println("Press Ctrl-C to close all tunnels.")
+}
+
+// multiTunnelLocal returns where a cluster's tunnel listens.
+func multiTunnelLocal(c *config.Cluster) string {
+	if c.LocalSocket != nil && *c.LocalSocket != "" {
+		return *c.LocalSocket
+	}
+	bind := "localhost"
+	if c.BindAddress != nil && *c.BindAddress != "" {
+		bind = *c.BindAddress
+	}
+	return fmt.Sprintf("%s:%d", bind, *c.LocalPort)
+}
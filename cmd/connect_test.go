@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/ui"
+	"github.com/spf13/cobra"
 )
 
 func TestSelectClusterByName(t *testing.T) {
@@ -67,13 +72,30 @@ func TestSelectClusterSingleCluster(t *testing.T) {
 	}
 }
 
+func TestSelectClusterNonInteractive(t *testing.T) {
+	cfg := &config.Config{
+		Clusters: []*config.Cluster{
+			{ClusterName: "cluster-1", Region: "us-ashburn-1"},
+			{ClusterName: "cluster-2", Region: "eu-frankfurt-1"},
+		},
+	}
+
+	ui.SetNonInteractive(true)
+	defer ui.SetNonInteractive(false)
+
+	_, err := selectCluster(cfg, "")
+	if err == nil || !strings.Contains(err.Error(), "cluster-1, cluster-2") {
+		t.Errorf("selectCluster() error = %v, want the cluster names", err)
+	}
+}
+
 func TestConnectCommandExists(t *testing.T) {
 	if connectCmd == nil {
 		t.Fatal("connectCmd is nil")
 	}
 
-	if connectCmd.Use != "connect [cluster]" {
-		t.Errorf("connectCmd.Use = %q, want %q", connectCmd.Use, "connect [cluster]")
+	if connectCmd.Use != "connect [cluster...]" {
+		t.Errorf("connectCmd.Use = %q, want %q", connectCmd.Use, "connect [cluster...]")
 	}
 }
 
@@ -103,4 +125,107 @@ func TestConnectCommandFlags(t *testing.T) {
 	if flag == nil {
 		t.Error("--no-bastion flag not found")
 	}
+
+	flag = connectCmd.Flags().Lookup("detach")
+	if flag == nil {
+		t.Error("--detach flag not found")
+	}
+
+	flag = connectCmd.Flags().Lookup("all-endpoints")
+	if flag == nil {
+		t.Error("--all-endpoints flag not found")
+	}
+}
+
+func TestApplyProfileDefaults(t *testing.T) {
+	profile := &config.Profile{
+		Name:         "debug",
+		Cluster:      "prod",
+		Endpoint:     "private",
+		SocksPort:    1080,
+		AllEndpoints: true,
+	}
+
+	name, endpoint, socksPort, all := "", "", 0, false
+	applyProfileDefaults(profile, &name, &endpoint, &socksPort, &all)
+	if name != "prod" || endpoint != "private" || socksPort != 1080 || !all {
+		t.Errorf("applyProfileDefaults() = (%q, %q, %d, %v), want (prod, private, 1080, true)", name, endpoint, socksPort, all)
+	}
+
+	// Command-line values win over the profile
+	name, endpoint, socksPort, all = "staging", "public", 1081, false
+	applyProfileDefaults(profile, &name, &endpoint, &socksPort, &all)
+	if name != "staging" || endpoint != "public" || socksPort != 1081 {
+		t.Errorf("applyProfileDefaults() = (%q, %q, %d), want flags to take precedence", name, endpoint, socksPort)
+	}
+}
+
+func TestLoadProfileNotFound(t *testing.T) {
+	cfg := &config.Config{Profiles: []*config.Profile{{Name: "debug"}}}
+
+	if p, err := loadProfile(cfg, ""); p != nil || err != nil {
+		t.Errorf("loadProfile(\"\") = %v, %v, want nil, nil", p, err)
+	}
+	if _, err := loadProfile(cfg, "metrics"); err == nil {
+		t.Error("loadProfile() should error for an unknown profile")
+	}
+}
+
+func TestStrictConfigFailsCommands(t *testing.T) {
+	origCfgFile := cfgFile
+	defer func() { cfgFile = origCfgFile }()
+
+	cfgFile = filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgFile, []byte("sesion_ttl_minutes: 60\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TUNATAP_STRICT_CONFIG", "true")
+
+	// Flags parsed as cobra would, so that exec finds the command after --
+	parsed := func(args ...string) (*cobra.Command, []string) {
+		c := &cobra.Command{}
+		if err := c.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return c, c.Flags().Args()
+	}
+	commands := map[string]func() error{
+		"connect":    func() error { _, _, err := loadConnectConfig(); return err },
+		"exec":       func() error { return runExec(parsed("prod", "--", "kubectl", "get", "nodes")) },
+		"ssh":        func() error { return runSSH(parsed("ocid1.instance.oc1..a")) },
+		"daemon run": func() error { return runDaemonRun(parsed()) },
+	}
+	for name, run := range commands {
+		if err := run(); err == nil || !strings.Contains(err.Error(), "strict_config") {
+			t.Errorf("%s of an invalid config with strict_config error = %v, want the config error", name, err)
+		}
+	}
+}
+
+func TestReserveLocalPort(t *testing.T) {
+	used := make(map[int]string)
+	port := 16443
+
+	first := &config.Cluster{ClusterName: "prod-a", LocalPort: &port}
+	if err := reserveLocalPort(first, used); err != nil {
+		t.Fatalf("reserveLocalPort() error = %v", err)
+	}
+	if *first.LocalPort != 16443 {
+		t.Errorf("LocalPort = %d, want 16443", *first.LocalPort)
+	}
+
+	samePort := port
+	second := &config.Cluster{ClusterName: "prod-b", LocalPort: &samePort}
+	if err := reserveLocalPort(second, used); err != nil {
+		t.Fatalf("reserveLocalPort() error = %v", err)
+	}
+	if *second.LocalPort <= 16443 {
+		t.Errorf("LocalPort = %d, want a port after 16443", *second.LocalPort)
+	}
+
+	failPort, strategy := port, "fail"
+	third := &config.Cluster{ClusterName: "staging", LocalPort: &failPort, PortStrategy: &strategy}
+	if err := reserveLocalPort(third, used); err == nil {
+		t.Error("reserveLocalPort() error = nil, want an error with port_strategy fail")
+	}
 }
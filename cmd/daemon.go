@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/events"
+	"github.com/scotttball/tunatap/internal/health"
+	"github.com/scotttball/tunatap/internal/notify"
+	"github.com/scotttball/tunatap/internal/preflight"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonBackground     bool
+	daemonHealthEndpoint string
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Manage the background tunnel daemon",
+	Long: `The tunatap daemon is a long-lived background process that owns tunnels
+started with 'tunatap connect --detach'. It listens on a local unix socket
+(~/.tunatap/daemon.sock) and logs to ~/.tunatap/daemon.log.
+
+The daemon is started automatically by 'connect --detach'; these commands
+are only needed to manage it explicitly.`,
+}
+
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the daemon in the foreground",
+	RunE:  runDaemonRun,
+}
+
+var daemonStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the daemon in the background",
+	RunE:  runDaemonStart,
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop all background tunnels and the daemon",
+	RunE:  runDaemonStop,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonRunCmd)
+	daemonCmd.AddCommand(daemonStartCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+
+	daemonRunCmd.Flags().BoolVar(&daemonBackground, "background", false, "write plain logs suitable for a log file")
+	_ = daemonRunCmd.Flags().MarkHidden("background")
+	daemonRunCmd.Flags().StringVar(&daemonHealthEndpoint, "health-endpoint", "", "address for a health server covering the daemon's tunnels, e.g. localhost:9090")
+}
+
+func runDaemonRun(cmd *cobra.Command, args []string) error {
+	if daemonBackground {
+		setConsoleLog(zerolog.ConsoleWriter{Out: os.Stderr, NoColor: true})
+	}
+
+	// Tunnels read the config again when they start; tracing is set up once
+	cfg, err := config.ReadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Info().Msg("Received shutdown signal, stopping daemon...")
+		cancel()
+	}()
+
+	if daemonHealthEndpoint != "" {
+		stopHealth, err := health.StartHealthServer(daemonHealthEndpoint)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to start health server")
+		} else {
+			defer stopHealth()
+		}
+	}
+
+	defer startTracing(ctx, cfg)()
+
+	server := daemon.NewServer(daemon.DefaultSocketPath(), startDaemonTunnel)
+	return server.Serve(ctx)
+}
+
+func runDaemonStart(cmd *cobra.Command, args []string) error {
+	socketPath := daemon.DefaultSocketPath()
+	if daemon.IsRunning(socketPath) {
+		fmt.Printf("Daemon already running (%s)\n", socketPath)
+		return nil
+	}
+
+	if err := ensureDaemon(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Daemon started (%s)\n", socketPath)
+	fmt.Printf("Logs: %s\n", daemon.DefaultLogPath())
+	return nil
+}
+
+func runDaemonStop(cmd *cobra.Command, args []string) error {
+	client := daemon.NewClient(daemon.DefaultSocketPath())
+	if err := client.Shutdown(); err != nil {
+		return err
+	}
+
+	fmt.Println("Daemon stopped")
+	return nil
+}
+
+// ensureDaemon starts the background daemon if it is not already running.
+func ensureDaemon() error {
+	socketPath := daemon.DefaultSocketPath()
+	if daemon.IsRunning(socketPath) {
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate tunatap executable: %w", err)
+	}
+
+	args := []string{"daemon", "run", "--background"}
+	if debug {
+		args = append(args, "--debug")
+	}
+
+	log.Info().Msg("Starting tunatap daemon...")
+	return daemon.Spawn(executable, args, socketPath, daemon.DefaultLogPath())
+}
+
+// runConnectDetached hands the tunnel described by the connect flags to the daemon.
+func runConnectDetached(cmd *cobra.Command) error {
+	name := clusterName
+	if name == "" {
+		// The daemon cannot prompt, so pick the cluster here
+		cfg, err := config.ReadConfig(GetConfigFile())
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		profile, err := loadProfile(cfg, connectProfile)
+		if err != nil {
+			return err
+		}
+		if profile != nil && profile.Cluster != "" {
+			name = profile.Cluster
+		} else {
+			selected, err := selectCluster(cfg, "")
+			if err != nil {
+				return err
+			}
+			name = selected.ClusterName
+		}
+	}
+
+	configFile, err := filepath.Abs(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	if err := ensureDaemon(); err != nil {
+		return err
+	}
+
+	req := &daemon.ConnectRequest{
+		Cluster:       name,
+		Endpoint:      endpointName,
+		Bastion:       bastionName,
+		LocalPort:     localPort,
+		Region:        regionHint,
+		OCIProfile:    connectOCIProfile,
+		NoCache:       noCache,
+		SkipPreflight: skipPreflight,
+		ConfigFile:    configFile,
+		SocksPort:     connectSocksPort,
+		AllEndpoints:  connectAllEndpoints,
+		MaxBandwidth:  connectMaxBandwidth,
+		BindAddress:   connectBind,
+		PortStrategy:  connectPortStrategy,
+		Profile:       connectProfile,
+		CreateBastion: connectCreate,
+		AllowCIDRs:    connectAllowCIDRs,
+	}
+	if retry := retryFlagPolicy(cmd); retry != nil {
+		req.RetryInitialIntervalSeconds = retry.InitialIntervalSeconds
+		req.RetryMultiplier = retry.Multiplier
+		req.RetryMaxAttempts = retry.MaxAttempts
+	}
+
+	log.Info().Msgf("Handing tunnel to %s off to the daemon...", name)
+	info, err := daemon.NewClient(daemon.DefaultSocketPath()).Connect(req)
+	if err != nil {
+		return fmt.Errorf("daemon failed to start tunnel: %w", err)
+	}
+
+	if info.State == daemon.StateReady {
+		fmt.Printf("Tunnel to %s running in background on localhost:%d\n", info.Cluster, info.LocalPort)
+	} else {
+		fmt.Printf("Tunnel to %s is %s in background (check 'tunatap status')\n", info.Cluster, info.State)
+	}
+	fmt.Printf("Stop it with: tunatap stop %s\n", info.Cluster)
+	return nil
+}
+
+// startDaemonTunnel resolves a cluster and runs its tunnel inside the daemon.
+// It mirrors runConnect but takes all inputs from the request so that several
+// tunnels can run concurrently.
+func startDaemonTunnel(ctx context.Context, req *daemon.ConnectRequest, onReady daemon.ReadyFunc) error {
+	maxBandwidth, err := utils.ParseBandwidth(req.MaxBandwidth)
+	if err != nil {
+		return fmt.Errorf("invalid max bandwidth: %w", err)
+	}
+
+	configFile := req.ConfigFile
+	if configFile == "" {
+		configFile = GetConfigFile()
+	}
+
+	cfg, cfgLoaded, err := readConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	// The daemon has no terminal, so unknown host keys cannot be confirmed
+	if err := configureHostKeys(cfg, false); err != nil {
+		return err
+	}
+
+	if req.OCIProfile != "" {
+		cfg.OverrideOCIProfile(req.OCIProfile)
+	}
+	if err := configureCredentials(ctx, cfg); err != nil {
+		return err
+	}
+
+	profile, err := loadProfile(cfg, req.Profile)
+	if err != nil {
+		return err
+	}
+	name, endpointToUse, socksPort, allEndpoints := req.Cluster, req.Endpoint, req.SocksPort, req.AllEndpoints
+	applyProfileDefaults(profile, &name, &endpointToUse, &socksPort, &allEndpoints)
+
+	var (
+		selectedCluster *config.Cluster
+		ociClient       *client.OCIClient
+	)
+	if req.CreateBastion {
+		selectedCluster, ociClient, err = resolveClusterCreatingBastion(ctx, cfg, cfgLoaded, name, req.Region, req.NoCache, discovery.ResourceCluster, req.AllowCIDRs)
+	} else {
+		selectedCluster, ociClient, err = resolveCluster(ctx, cfg, cfgLoaded, name, req.Region, req.NoCache)
+	}
+	if err != nil {
+		return err
+	}
+
+	if req.Bastion != "" {
+		selectedCluster.Bastion = &req.Bastion
+	}
+	if req.BindAddress != "" {
+		selectedCluster.BindAddress = &req.BindAddress
+	}
+	if req.PortStrategy != "" {
+		selectedCluster.PortStrategy = &req.PortStrategy
+	}
+	selectedCluster.Retry = config.MergeRetryPolicy(selectedCluster.Retry, &config.RetryPolicy{
+		InitialIntervalSeconds: req.RetryInitialIntervalSeconds,
+		Multiplier:             req.RetryMultiplier,
+		MaxAttempts:            req.RetryMaxAttempts,
+	})
+
+	endpoint := config.GetClusterEndpoint(selectedCluster, endpointToUse)
+	if endpoint == nil {
+		return fmt.Errorf("no endpoints configured for cluster '%s'", selectedCluster.ClusterName)
+	}
+
+	additionalEndpoints, err := resolveAdditionalEndpoints(selectedCluster, endpoint, allEndpoints)
+	if err != nil {
+		return err
+	}
+	additionalEndpoints, err = withProfileForwards(additionalEndpoints, profile)
+	if err != nil {
+		return err
+	}
+
+	if ociClient == nil {
+		ociClient, err = createOCIClient(cfg, selectedCluster)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	if err := cluster.ValidateAndUpdateCluster(ctx, ociClient, selectedCluster, true, req.LocalPort); err != nil {
+		return fmt.Errorf("failed to validate cluster: %w", err)
+	}
+	defer claimLocalPort(selectedCluster, req.Cluster, daemon.DefaultSocketPath())()
+
+	if !req.SkipPreflight {
+		if err := preflight.RunQuickCheck(ctx, ociClient, selectedCluster); err != nil {
+			log.Warn().Err(err).Msgf("Quick preflight check failed for %s", selectedCluster.ClusterName)
+		}
+	}
+
+	auditLogger := newAuditLogger(ctx, cfg)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	// Nobody sees the daemon's log, so tell the desktop when tunnels drop
+	var eventWriter *events.Writer
+	if cfg.DesktopNotifications {
+		eventWriter = events.NewHandlerWriter(notify.New(notify.Desktop).Handle)
+	}
+
+	writeKubeconfig := profileKubeconfigHook(cfg, selectedCluster, profile)
+	opts := &bastion.TunnelOptions{
+		AuditLogger:         auditLogger,
+		SocksPort:           socksPort,
+		AdditionalEndpoints: additionalEndpoints,
+		MaxBandwidth:        maxBandwidth,
+		OnReady: func(port int) {
+			writeKubeconfig(port)
+			onReady(port, endpoint.Host(), endpoint.Port)
+		},
+		Events: eventWriter,
+	}
+	err = bastion.TunnelThroughBastionWithOptions(ctx, ociClient, cfg, selectedCluster, endpoint, opts)
+
+	closed := events.Event{Type: events.Closed, Cluster: selectedCluster.ClusterName}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		closed.Error = err.Error()
+	}
+	eventWriter.Emit(closed)
+	return err
+}
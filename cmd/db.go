@@ -0,0 +1,411 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbType           string
+	dbLocalPort      int
+	dbBastionName    string
+	dbRegionHint     string
+	dbNoCache        bool
+	dbLaunch         bool
+	dbClient         string
+	dbUser           string
+	dbDatabase       string
+	dbPasswordEnv    string
+	dbPasswordSecret string
+	dbDryRun         bool
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db <name> [-- client args...]",
+	Short: "Open a tunnel to a private database and optionally start its client",
+	Long: `Discover a database by name, open a tunnel to its private endpoint and
+print how to connect to it on localhost.
+
+Without --type, DB systems, Autonomous Databases, MySQL and PostgreSQL DB
+systems are searched in that order. With --launch, the database's client is
+started against the tunnel and the tunnel is closed when it exits: sqlplus
+for DB systems and Autonomous Databases, mysql for MySQL and psql for
+PostgreSQL, unless --client is given. Arguments after -- are passed to the
+client.
+
+The password is read from the environment variable named by --password-env
+or from the OCI Vault secret given by --password-secret. It is handed to the
+client through PGPASSWORD, MYSQL_PWD or sqlplus's standard input, never on
+its command line.
+
+Examples:
+  tunatap db orders-db
+  tunatap db ledger --launch --user app --database ledger --password-env LEDGER_PASSWORD
+  tunatap db orders-db --type adb --launch --user admin --database orders_low \
+    --password-secret ocid1.vaultsecret.oc1.iad.xxx
+  tunatap db inventory --launch --user app -- --ssl-mode=REQUIRED`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runDB,
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+
+	dbCmd.Flags().StringVar(&dbType, "type", "", "database type: dbsystem, adb, mysql or postgresql (default: search all)")
+	dbCmd.Flags().IntVarP(&dbLocalPort, "port", "p", 0, "local port to listen on (default: automatic)")
+	dbCmd.Flags().StringVarP(&dbBastionName, "bastion", "b", "", "bastion name or OCID to use")
+	dbCmd.Flags().StringVarP(&dbRegionHint, "region", "r", "", "region hint for database discovery (optional)")
+	dbCmd.Flags().BoolVar(&dbNoCache, "no-cache", false, "skip cache and force fresh discovery")
+	dbCmd.Flags().BoolVar(&dbLaunch, "launch", false, "start the database client against the tunnel")
+	dbCmd.Flags().StringVar(&dbClient, "client", "", "client to start with --launch: psql, mysql, sqlplus or sql (default: by database type)")
+	dbCmd.Flags().StringVarP(&dbUser, "user", "u", "", "database user")
+	dbCmd.Flags().StringVarP(&dbDatabase, "database", "d", "", "database name, or service name for Oracle databases")
+	dbCmd.Flags().StringVar(&dbPasswordEnv, "password-env", "", "environment variable holding the database password")
+	dbCmd.Flags().StringVar(&dbPasswordSecret, "password-secret", "", "OCID of an OCI Vault secret holding the database password")
+	dbCmd.Flags().BoolVar(&dbDryRun, "dry-run", false, "print the session and ssh command that would be used without creating anything in OCI")
+}
+
+func runDB(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	clientArgs := args[1:]
+	if dash := cmd.ArgsLenAtDash(); len(args) > 1 && dash != 1 {
+		return fmt.Errorf("db takes one name; pass client arguments after --")
+	}
+	if len(clientArgs) > 0 && !dbLaunch {
+		return fmt.Errorf("client arguments need --launch")
+	}
+	if dbPasswordEnv != "" && dbPasswordSecret != "" {
+		return fmt.Errorf("--password-env and --password-secret cannot be used together")
+	}
+	if dbClient != "" && !dbClients[dbClient] {
+		return fmt.Errorf("unknown --client %q, expected psql, mysql, sqlplus or sql", dbClient)
+	}
+
+	types := discovery.DatabaseTypes
+	if dbType != "" {
+		resourceType, err := discovery.ParseResourceType(dbType)
+		if err != nil {
+			return fmt.Errorf("invalid --type: %w", err)
+		}
+		if !isDatabaseType(resourceType) {
+			return fmt.Errorf("invalid --type: %s is not a database", resourceType)
+		}
+		types = []discovery.ResourceType{resourceType}
+	}
+
+	cfg, cfgLoaded, err := loadConnectConfig()
+	if err != nil {
+		return err
+	}
+
+	selected, resourceType, ociClient, err := resolveDatabase(cmd.Context(), cfg, cfgLoaded, name, types)
+	if err != nil {
+		return err
+	}
+
+	if dbBastionName != "" {
+		selected.Bastion = &dbBastionName
+	}
+	// The tunnel is read by a local client, so it always listens on localhost
+	selected.LocalSocket = nil
+	selected.BindAddress = nil
+
+	endpoint := config.GetClusterEndpoint(selected, "")
+	if endpoint == nil {
+		return fmt.Errorf("no endpoint found for database '%s'", selected.ClusterName)
+	}
+
+	if ociClient == nil {
+		ociClient, err = createOCIClient(cfg, selected)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	if err := cluster.ValidateAndUpdateCluster(cmd.Context(), ociClient, selected, true, dbLocalPort); err != nil {
+		return fmt.Errorf("failed to validate database: %w", err)
+	}
+
+	if dbDryRun {
+		plan, err := bastion.PlanTunnel(cmd.Context(), ociClient, cfg, selected, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		printTunnelPlan(selected, endpoint, plan)
+		return nil
+	}
+
+	var password string
+	if dbLaunch {
+		if password, err = dbPassword(cmd.Context(), ociClient); err != nil {
+			return err
+		}
+	}
+
+	code, err := runDBSession(cmd.Context(), cfg, ociClient, selected, endpoint, resourceType, password, clientArgs)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// runDBSession opens the tunnel and either waits for Ctrl-C or runs the
+// database client until it exits. It returns the client's exit code.
+func runDBSession(ctx context.Context, cfg *config.Config, ociClient *client.OCIClient, selected *config.Cluster, endpoint *config.ClusterEndpoint, resourceType discovery.ResourceType, password string, clientArgs []string) (int, error) {
+	defer claimLocalPort(selected, selected.ClusterName, "")()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Ctrl-C aborts connecting, but once the client runs it belongs to the
+	// client, which sees it too
+	clientStarted := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		for {
+			select {
+			case sig := <-sigChan:
+				select {
+				case <-clientStarted:
+					if sig == syscall.SIGINT {
+						continue
+					}
+				default:
+				}
+				log.Info().Msg("Received shutdown signal, closing tunnel...")
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	auditLogger := newAuditLogger(ctx, cfg)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	tunnelErr := make(chan error, 1)
+	tunnelReady := make(chan int, 1)
+	opts := &bastion.TunnelOptions{
+		AuditLogger: auditLogger,
+		OnReady: func(port int) {
+			select {
+			case tunnelReady <- port:
+			default:
+			}
+		},
+	}
+	go func() {
+		tunnelErr <- bastion.TunnelThroughBastionWithOptions(ctx, ociClient, cfg, selected, endpoint, opts)
+	}()
+
+	var port int
+	select {
+	case port = <-tunnelReady:
+	case err := <-tunnelErr:
+		return 0, fmt.Errorf("tunnel failed to start: %w", err)
+	case <-ctx.Done():
+		<-tunnelErr
+		return 0, nil
+	}
+
+	if !dbLaunch {
+		printDBConnection(selected.ClusterName, resourceType, port)
+		fmt.Println("Press Ctrl-C to close the tunnel.")
+		if err := <-tunnelErr; err != nil && !errors.Is(err, context.Canceled) {
+			return 0, err
+		}
+		return 0, nil
+	}
+	defer func() {
+		cancel()
+		<-tunnelErr
+	}()
+
+	program := dbClient
+	if program == "" {
+		program = defaultDBClient(resourceType)
+	}
+	inv := dbClientInvocation(program, resourceType, port, dbUser, dbDatabase, password, clientArgs)
+
+	clientCmd := exec.CommandContext(ctx, inv.Program, inv.Args...)
+	clientCmd.Env = append(os.Environ(), inv.Env...)
+	clientCmd.Stdin = os.Stdin
+	if inv.Stdin != "" {
+		clientCmd.Stdin = io.MultiReader(strings.NewReader(inv.Stdin), os.Stdin)
+	}
+	clientCmd.Stdout = os.Stdout
+	clientCmd.Stderr = os.Stderr
+
+	fmt.Fprintf(os.Stderr, "Tunnel to %s on localhost:%d. Exit %s to close it.\n", selected.ClusterName, port, inv.Program)
+	close(clientStarted)
+	if err := clientCmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, fmt.Errorf("failed to start %s: %w", inv.Program, err)
+	}
+	return 0, nil
+}
+
+// resolveDatabase discovers the named database among types, in order, and
+// returns it with the type it was found as.
+func resolveDatabase(ctx context.Context, cfg *config.Config, cfgLoaded bool, name string, types []discovery.ResourceType) (*config.Cluster, discovery.ResourceType, *client.OCIClient, error) {
+	for _, resourceType := range types {
+		selected, ociClient, err := cluster.ResolveResource(ctx, cfg, cfgLoaded, name, dbRegionHint, dbNoCache, resourceType)
+		if err == nil {
+			return selected, resourceType, ociClient, nil
+		}
+		if len(types) == 1 || !errors.Is(err, discovery.ErrClusterNotFound) {
+			return nil, "", nil, err
+		}
+		log.Debug().Msgf("No %s named %s", resourceType, name)
+	}
+	return nil, "", nil, fmt.Errorf("%w: no database named %q", discovery.ErrClusterNotFound, name)
+}
+
+// isDatabaseType reports whether resourceType is one of the database types.
+func isDatabaseType(resourceType discovery.ResourceType) bool {
+	for _, t := range discovery.DatabaseTypes {
+		if t == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// dbPassword returns the database password from --password-env or
+// --password-secret, or "" if neither is given.
+func dbPassword(ctx context.Context, ociClient client.OCIClientInterface) (string, error) {
+	switch {
+	case dbPasswordEnv != "":
+		password, ok := os.LookupEnv(dbPasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", dbPasswordEnv)
+		}
+		return password, nil
+	case dbPasswordSecret != "":
+		password, err := ociClient.GetSecretContent(ctx, dbPasswordSecret)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password secret: %w", err)
+		}
+		return strings.TrimRight(password, "\r\n"), nil
+	default:
+		return "", nil
+	}
+}
+
+// printDBConnection prints how to reach the database through the tunnel.
+func printDBConnection(name string, resourceType discovery.ResourceType, port int) {
+	fmt.Printf("Database %s is on localhost:%d\n", name, port)
+	switch resourceType {
+	case discovery.ResourcePostgres:
+		fmt.Printf("  psql -h 127.0.0.1 -p %d -U <user> -d <database>\n", port)
+	case discovery.ResourceMySQL:
+		fmt.Printf("  mysql -h 127.0.0.1 -P %d -u <user> -p\n", port)
+	case discovery.ResourceAutonomousDB:
+		fmt.Printf("  sqlplus '<user>@tcps://127.0.0.1:%d/<service>?ssl_server_dn_match=false'\n", port)
+	default:
+		fmt.Printf("  sqlplus <user>@//127.0.0.1:%d/<service>\n", port)
+	}
+}
+
+// dbClients are the clients --client accepts.
+var dbClients = map[string]bool{"psql": true, "mysql": true, "sqlplus": true, "sql": true}
+
+// defaultDBClient returns the client started for a database type.
+func defaultDBClient(resourceType discovery.ResourceType) string {
+	switch resourceType {
+	case discovery.ResourcePostgres:
+		return "psql"
+	case discovery.ResourceMySQL:
+		return "mysql"
+	default:
+		return "sqlplus"
+	}
+}
+
+// dbInvocation is a database client command line.
+type dbInvocation struct {
+	Program string
+	Args    []string
+
+	// Env is added to the client's environment.
+	Env []string
+
+	// Stdin is written to the client before the terminal's input.
+	Stdin string
+}
+
+// dbClientInvocation builds the command line starting program against the
+// tunnel on port, keeping the password out of its arguments.
+func dbClientInvocation(program string, resourceType discovery.ResourceType, port int, user, database, password string, extra []string) *dbInvocation {
+	inv := &dbInvocation{Program: program}
+	portStr := strconv.Itoa(port)
+
+	switch program {
+	case "psql":
+		inv.Args = []string{"-h", "127.0.0.1", "-p", portStr}
+		if user != "" {
+			inv.Args = append(inv.Args, "-U", user)
+		}
+		if database != "" {
+			inv.Args = append(inv.Args, "-d", database)
+		}
+		if password != "" {
+			inv.Env = append(inv.Env, "PGPASSWORD="+password)
+		}
+	case "mysql":
+		inv.Args = []string{"-h", "127.0.0.1", "-P", portStr}
+		if user != "" {
+			inv.Args = append(inv.Args, "-u", user)
+		}
+		if database != "" {
+			inv.Args = append(inv.Args, "-D", database)
+		}
+		if password != "" {
+			inv.Env = append(inv.Env, "MYSQL_PWD="+password)
+		}
+	default:
+		// sqlplus and SQLcl take the password in a CONNECT read from stdin
+		inv.Args = []string{"-L", "/nolog"}
+		connect := "//127.0.0.1:" + portStr + "/" + database
+		if resourceType == discovery.ResourceAutonomousDB {
+			connect = `"tcps://127.0.0.1:` + portStr + "/" + database + `?ssl_server_dn_match=false"`
+		}
+		if user != "" {
+			credentials := user
+			if password != "" {
+				credentials += `/"` + password + `"`
+			}
+			inv.Stdin = "CONNECT " + credentials + "@" + connect + "\n"
+		}
+	}
+
+	inv.Args = append(inv.Args, extra...)
+	return inv
+}
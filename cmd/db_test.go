@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/discovery"
+)
+
+func TestDBClientInvocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		program      string
+		resourceType discovery.ResourceType
+		extra        []string
+		wantArgs     []string
+		wantEnv      []string
+		wantStdin    string
+	}{
+		{
+			name:         "psql",
+			program:      "psql",
+			resourceType: discovery.ResourcePostgres,
+			wantArgs:     []string{"-h", "127.0.0.1", "-p", "15432", "-U", "app", "-d", "ledger"},
+			wantEnv:      []string{"PGPASSWORD=s3cret"},
+		},
+		{
+			name:         "mysql with extra args",
+			program:      "mysql",
+			resourceType: discovery.ResourceMySQL,
+			extra:        []string{"--ssl-mode=REQUIRED"},
+			wantArgs:     []string{"-h", "127.0.0.1", "-P", "15432", "-u", "app", "-D", "ledger", "--ssl-mode=REQUIRED"},
+			wantEnv:      []string{"MYSQL_PWD=s3cret"},
+		},
+		{
+			name:         "sqlplus on a DB system",
+			program:      "sqlplus",
+			resourceType: discovery.ResourceDBSystem,
+			wantArgs:     []string{"-L", "/nolog"},
+			wantStdin:    "CONNECT app/\"s3cret\"@//127.0.0.1:15432/ledger\n",
+		},
+		{
+			name:         "sqlplus on an Autonomous Database",
+			program:      "sqlplus",
+			resourceType: discovery.ResourceAutonomousDB,
+			wantArgs:     []string{"-L", "/nolog"},
+			wantStdin:    "CONNECT app/\"s3cret\"@\"tcps://127.0.0.1:15432/ledger?ssl_server_dn_match=false\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := dbClientInvocation(tt.program, tt.resourceType, 15432, "app", "ledger", "s3cret", tt.extra)
+			if !reflect.DeepEqual(inv.Args, tt.wantArgs) {
+				t.Errorf("Args = %q, want %q", inv.Args, tt.wantArgs)
+			}
+			if !reflect.DeepEqual(inv.Env, tt.wantEnv) {
+				t.Errorf("Env = %q, want %q", inv.Env, tt.wantEnv)
+			}
+			if inv.Stdin != tt.wantStdin {
+				t.Errorf("Stdin = %q, want %q", inv.Stdin, tt.wantStdin)
+			}
+			for _, arg := range inv.Args {
+				if strings.Contains(arg, "s3cret") {
+					t.Errorf("password in argument %q", arg)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultDBClient(t *testing.T) {
+	tests := map[discovery.ResourceType]string{
+		discovery.ResourcePostgres:     "psql",
+		discovery.ResourceMySQL:        "mysql",
+		discovery.ResourceDBSystem:     "sqlplus",
+		discovery.ResourceAutonomousDB: "sqlplus",
+	}
+	for resourceType, want := range tests {
+		if got := defaultDBClient(resourceType); got != want {
+			t.Errorf("defaultDBClient(%s) = %q, want %q", resourceType, got, want)
+		}
+	}
+}
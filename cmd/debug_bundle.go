@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/audit"
+	"github.com/scotttball/tunatap/internal/bundle"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/preflight"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	debugBundleOutput  string
+	debugBundleLines   int
+	debugBundleCluster string
+)
+
+var debugBundleCmd = &cobra.Command{
+	Use:   "debug-bundle",
+	Short: "Collect diagnostics into a tarball for a support ticket",
+	Long: `Collect what is needed to diagnose a problem into one gzipped tarball:
+version and build info, the OS and ssh-agent state, the config file and OCI
+config, doctor results, preflight results for a cluster, recent audit events
+and the last lines of the tunatap, daemon and service logs.
+
+Private keys, credentials in URLs and the values of keys named like
+password, secret, token or fingerprint are redacted from every file. Review
+the bundle before attaching it anywhere public.
+
+Examples:
+  tunatap debug-bundle
+  tunatap debug-bundle --cluster prod-cluster -n 500 -o /tmp/tunatap-debug.tar.gz`,
+	Args: cobra.NoArgs,
+	RunE: runDebugBundle,
+}
+
+func init() {
+	rootCmd.AddCommand(debugBundleCmd)
+
+	debugBundleCmd.Flags().StringVarP(&debugBundleOutput, "output", "o", "", "file to write (default: tunatap-debug-<time>.tar.gz in the current directory)")
+	debugBundleCmd.Flags().IntVarP(&debugBundleLines, "lines", "n", 200, "number of log lines and audit events to include")
+	debugBundleCmd.Flags().StringVarP(&debugBundleCluster, "cluster", "c", "", "also run preflight checks for this configured cluster")
+}
+
+func runDebugBundle(cmd *cobra.Command, args []string) error {
+	name := "tunatap-debug-" + time.Now().Format("20060102-150405")
+	output := debugBundleOutput
+	if output == "" {
+		output = name + ".tar.gz"
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer f.Close()
+
+	w := bundle.NewWriter(f, name)
+	for _, file := range collectDebugFiles(cmd.Context()) {
+		if err := w.Add(file.name, file.data); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	fmt.Printf("Wrote %s\n", output)
+	fmt.Println("Secrets are redacted, but review the bundle before sharing it.")
+	return nil
+}
+
+// debugFile is a file of the debug bundle.
+type debugFile struct {
+	name string
+	data []byte
+}
+
+// collectDebugFiles gathers the files of the debug bundle. What cannot be
+// collected is noted in the bundle rather than failing it.
+func collectDebugFiles(ctx context.Context) []debugFile {
+	var files []debugFile
+	add := func(name string, data []byte) {
+		files = append(files, debugFile{name: name, data: data})
+	}
+	addFile := func(name, path string, tail bool) {
+		var data []byte
+		var err error
+		if tail {
+			data, err = bundle.TailFile(path, debugBundleLines)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			log.Debug().Err(err).Msgf("Not bundling %s", path)
+			if !os.IsNotExist(err) {
+				add(name+".error", []byte(err.Error()+"\n"))
+			}
+			return
+		}
+		add(name, data)
+	}
+
+	add("version.txt", debugVersionInfo())
+	add("environment.txt", debugEnvironment(ctx))
+	add("doctor.txt", debugDoctorResults())
+	if debugBundleCluster != "" {
+		add("preflight.txt", debugPreflightResults(ctx))
+	}
+
+	addFile("config.yaml", GetConfigFile(), false)
+	addFile("oci-config", utils.DefaultOCIConfigPath(), false)
+	add("audit.jsonl", debugAuditEvents())
+
+	addFile("logs/tunatap.log", filepath.Join(homePath, "tunatap.log"), true)
+	if logFile := config.ReadLogFileConfig(GetConfigFile()); logFile.IsEnabled() {
+		addFile("logs/debug.log", logFilePath(logFile), true)
+	}
+	addFile("logs/daemon.log", daemon.DefaultLogPath(), true)
+	addFile("logs/service.log", filepath.Join(utils.DefaultTunatapDir(), "service.log"), true)
+	return files
+}
+
+// debugVersionInfo describes the tunatap build.
+func debugVersionInfo() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "version: %s\n", version)
+	fmt.Fprintf(&b, "commit:  %s\n", commit)
+	fmt.Fprintf(&b, "built:   %s\n", date)
+	fmt.Fprintf(&b, "go:      %s\n", runtime.Version())
+	fmt.Fprintf(&b, "oci sdk: %s\n", common.Version())
+	fmt.Fprintf(&b, "created: %s\n", time.Now().Format(time.RFC3339))
+	return b.Bytes()
+}
+
+// debugEnvironmentVars are the environment variables bundled, by prefix or
+// exact name.
+var debugEnvironmentVars = []string{"TUNATAP_", "OCI_", "SSH_AUTH_SOCK", "KUBECONFIG", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy", "SHELL"}
+
+// debugEnvironment describes the OS, the relevant environment variables and
+// the ssh-agent and tool state.
+func debugEnvironment(ctx context.Context) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "os:   %s\n", runtime.GOOS)
+	fmt.Fprintf(&b, "arch: %s\n", runtime.GOARCH)
+	fmt.Fprintf(&b, "home: %s\n", homePath)
+
+	agent := preflight.CheckSSHAgentAvailable(ctx, &preflight.CheckOptions{})
+	fmt.Fprintf(&b, "ssh-agent: %s (%s)\n", agent.Status, agent.Message)
+
+	for _, tool := range []string{"ssh", "oci", "kubectl"} {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			path = "not found"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", tool, path)
+	}
+
+	b.WriteString("\nenvironment:\n")
+	var vars []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		for _, want := range debugEnvironmentVars {
+			if key == want || (strings.HasSuffix(want, "_") && strings.HasPrefix(key, want)) {
+				vars = append(vars, kv)
+				break
+			}
+		}
+	}
+	sort.Strings(vars)
+	for _, kv := range vars {
+		b.WriteString(kv + "\n")
+	}
+	return b.Bytes()
+}
+
+// debugDoctorResults runs the basic doctor checks.
+func debugDoctorResults() []byte {
+	var b bytes.Buffer
+	for _, r := range []checkResult{checkConfigFile(), checkOCIConfig(), checkSSHKeys(), checkOCICLI(), checkClustersConfig()} {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", r.status, r.name, r.message)
+	}
+	return b.Bytes()
+}
+
+// debugPreflightResults runs the preflight checks of --cluster.
+func debugPreflightResults(ctx context.Context) []byte {
+	var b bytes.Buffer
+	cfg, err := config.ReadConfig(GetConfigFile())
+	if err != nil {
+		fmt.Fprintf(&b, "failed to read config: %v\n", err)
+		return b.Bytes()
+	}
+	c := config.FindClusterByName(cfg, debugBundleCluster)
+	if c == nil {
+		fmt.Fprintf(&b, "cluster '%s' not found in config\n", debugBundleCluster)
+		return b.Bytes()
+	}
+
+	opts := &preflight.CheckOptions{Config: cfg, Cluster: c, Verbose: true, Timeout: 10 * time.Second}
+	if opts.OCIClient, err = createOCIClient(cfg, c); err != nil {
+		fmt.Fprintf(&b, "could not create OCI client: %v\n", err)
+	}
+
+	fmt.Fprintf(&b, "cluster: %s\n\n", c.ClusterName)
+	for _, r := range preflight.NewChecker(opts).RunForCluster(ctx) {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", r.Status, r.Name, r.Message)
+		if r.Details != "" {
+			fmt.Fprintf(&b, "    %s\n", r.Details)
+		}
+		if r.Suggestion != "" {
+			fmt.Fprintf(&b, "    suggestion: %s\n", r.Suggestion)
+		}
+	}
+	return b.Bytes()
+}
+
+// debugAuditEvents returns the most recent audit events as JSON lines.
+func debugAuditEvents() []byte {
+	events, err := audit.QueryLogs(audit.DefaultLogDir(), audit.Query{Limit: debugBundleLines})
+	if err != nil {
+		return []byte(fmt.Sprintf("failed to query audit logs: %v\n", err))
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for i := range events {
+		_ = enc.Encode(&events[i])
+	}
+	return b.Bytes()
+}
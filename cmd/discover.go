@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/catalog"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	discoverAll         bool
+	discoverOutput      string
+	discoverFile        string
+	discoverCatalogName string
+	discoverRegion      string
+	discoverCompartment string
+	discoverType        string
+	discoverProfile     bool
+)
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover --all",
+	Short: "Export every accessible cluster as a shared catalog",
+	Long: `Walk every accessible compartment in every subscribed region and write the
+clusters found, with their OCIDs, private endpoints and bastions, as a shared
+catalog ready to check into a team catalog repository.
+
+Discovery settings from config apply: discovery_method, and the compartment
+filters of the compartment walk. Clusters without a bastion are included
+without one. Pass --region to export a single region, or --compartment with a
+compartment OCID to export only that compartment and those in it.
+
+Examples:
+  tunatap discover --all
+  tunatap discover --all --region us-ashburn-1 --file clusters.yaml
+  tunatap discover --all --compartment ocid1.compartment.oc1..xxx
+  tunatap discover --all --type adb --name team-databases
+  tunatap discover --all --profile-discovery`,
+	Args: cobra.NoArgs,
+	RunE: runDiscover,
+}
+
+func init() {
+	rootCmd.AddCommand(discoverCmd)
+
+	discoverCmd.Flags().BoolVar(&discoverAll, "all", false, "discover every accessible cluster")
+	discoverCmd.Flags().StringVarP(&discoverOutput, "output", "o", "yaml", "output format (yaml)")
+	discoverCmd.Flags().StringVarP(&discoverFile, "file", "f", "", "file to write the catalog to (default: stdout)")
+	discoverCmd.Flags().StringVar(&discoverCatalogName, "name", "discovered", "name of the catalog")
+	discoverCmd.Flags().StringVarP(&discoverRegion, "region", "r", "", "only discover this region")
+	discoverCmd.Flags().StringVar(&discoverCompartment, "compartment", "", "only discover this compartment, by OCID, and those in it")
+	discoverCmd.Flags().StringVar(&discoverType, "type", "", "type of resource to discover: oke (default), dbsystem, adb, instance, mysql or postgresql")
+	discoverCmd.Flags().BoolVar(&discoverProfile, "profile-discovery", false, "report time spent, API calls and rate limits per region and compartment")
+}
+
+func runDiscover(cmd *cobra.Command, args []string) error {
+	if !discoverAll {
+		return fmt.Errorf("--all is required")
+	}
+	if discoverOutput != "yaml" {
+		return fmt.Errorf("unsupported --output %q (expected yaml)", discoverOutput)
+	}
+	resourceType, err := discovery.ParseResourceType(discoverType)
+	if err != nil {
+		return fmt.Errorf("invalid --type: %w", err)
+	}
+	if discoverCompartment != "" && !utils.IsCompartmentOCID(discoverCompartment) {
+		return fmt.Errorf("invalid --compartment %q: expected a compartment OCID", discoverCompartment)
+	}
+
+	cfg, _, err := readConfig(GetConfigFile())
+	if err != nil {
+		return err
+	}
+
+	hints, err := cluster.NewDiscoveryHints(cfg, discoverRegion)
+	if err != nil {
+		return err
+	}
+	hints.Type = resourceType
+	hints.CompartmentOCID = discoverCompartment
+
+	ociClient, err := cluster.NewDiscoveryClient(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create OCI client: %w", err)
+	}
+
+	// Export what is there now rather than what was cached
+	discoverer := discovery.NewDiscoverer(ociClient, nil)
+	var profile *discovery.Profile
+	if discoverProfile {
+		profile = discovery.NewProfile()
+		discoverer.SetProfile(profile)
+	}
+	clusters, err := discoverer.DiscoverAll(cmd.Context(), hints)
+	profile.Report(os.Stderr)
+	if err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+
+	shared := &catalog.SharedCatalog{
+		Version:  "1.0",
+		Name:     discoverCatalogName,
+		Updated:  time.Now().UTC().Format(time.RFC3339),
+		Clusters: clusters,
+	}
+	data, err := yaml.Marshal(shared)
+	if err != nil {
+		return fmt.Errorf("failed to encode catalog: %w", err)
+	}
+
+	if discoverFile == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(discoverFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	log.Info().Msgf("Wrote %d clusters to %s", len(clusters), discoverFile)
+	return nil
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/scotttball/tunatap/internal/autofix"
 	"github.com/scotttball/tunatap/internal/client"
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/output"
 	"github.com/scotttball/tunatap/internal/preflight"
 	"github.com/scotttball/tunatap/pkg/utils"
 	"github.com/spf13/cobra"
@@ -40,7 +42,10 @@ Examples:
   tunatap doctor --auto-fix
 
   # Show what auto-fix would do
-  tunatap doctor --auto-fix --dry-run`,
+  tunatap doctor --auto-fix --dry-run
+
+  # Results as JSON for other tools
+  tunatap doctor --cluster my-cluster -o json`,
 	RunE: runDoctor,
 }
 
@@ -50,6 +55,7 @@ var (
 	doctorPreflight bool
 	doctorAutoFix   bool
 	doctorDryRun    bool
+	doctorOutput    string
 )
 
 func init() {
@@ -59,6 +65,7 @@ func init() {
 	doctorCmd.Flags().BoolVar(&doctorPreflight, "preflight", false, "run full preflight checks (requires --cluster)")
 	doctorCmd.Flags().BoolVar(&doctorAutoFix, "auto-fix", false, "automatically fix safe issues")
 	doctorCmd.Flags().BoolVar(&doctorDryRun, "dry-run", false, "show what auto-fix would do without making changes")
+	addOutputFlag(doctorCmd, &doctorOutput)
 }
 
 type checkResult struct {
@@ -67,31 +74,37 @@ type checkResult struct {
 	message string
 }
 
-func runDoctor(cmd *cobra.Command, args []string) error {
-	fmt.Println("Running tunatap diagnostics...")
-	fmt.Println()
-
-	results := []checkResult{}
-
-	// Check 1: Configuration file
-	results = append(results, checkConfigFile())
-
-	// Check 2: OCI configuration
-	results = append(results, checkOCIConfig())
-
-	// Check 3: SSH keys
-	results = append(results, checkSSHKeys())
+// doctorCheck is a basic diagnostic in doctorReport.
+type doctorCheck struct {
+	Name    string `json:"name" yaml:"name"`
+	Status  string `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+}
 
-	// Check 4: OCI CLI
-	results = append(results, checkOCICLI())
+// doctorReport is the structured output of doctor.
+type doctorReport struct {
+	Checks    []doctorCheck           `json:"checks" yaml:"checks"`
+	Cluster   string                  `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Preflight []preflight.CheckResult `json:"preflight,omitempty" yaml:"preflight,omitempty"`
+	OK        bool                    `json:"ok" yaml:"ok"`
+}
 
-	// Check 5: OCI connectivity (if verbose)
-	if doctorVerbose {
-		results = append(results, checkOCIConnectivity())
+func runDoctor(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(doctorOutput, false)
+	if err != nil {
+		return err
+	}
+	if format.Structured() {
+		if doctorAutoFix {
+			return fmt.Errorf("--auto-fix cannot be combined with --output %s", format)
+		}
+		return runDoctorReport(cmd.Context(), format)
 	}
 
-	// Check 6: Clusters configuration
-	results = append(results, checkClustersConfig())
+	fmt.Println("Running tunatap diagnostics...")
+	fmt.Println()
+
+	results := doctorChecks()
 
 	// Print basic results
 	fmt.Println("Basic Diagnostics:")
@@ -113,10 +126,16 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	// Run cluster-specific preflight checks if requested
 	if doctorCluster != "" || doctorPreflight {
 		fmt.Println()
-		preflightResults, preflightErr := runPreflightChecks(cmd.Context(), doctorCluster, doctorPreflight)
+		cluster, preflightResults, preflightErr := runPreflightChecks(cmd.Context(), doctorCluster, doctorPreflight)
 		if preflightErr != nil {
 			return preflightErr
 		}
+		if doctorCluster == "" {
+			fmt.Printf("No cluster specified, using first cluster: %s\n", cluster.ClusterName)
+		}
+		fmt.Printf("Preflight Checks for '%s':\n", cluster.ClusterName)
+		fmt.Println("---------------------------")
+		preflight.PrintResults(preflightResults, doctorVerbose)
 		if preflight.HasErrors(preflightResults) {
 			hasErrors = true
 		}
@@ -133,7 +152,7 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	if hasErrors && !doctorAutoFix {
 		fmt.Println("\nSome checks failed. Please review the errors above.")
 		fmt.Println("Run 'tunatap doctor --auto-fix' to automatically fix safe issues.")
-		return fmt.Errorf("diagnostics found issues")
+		return exitcode.Wrap(exitcode.PreflightFailed, fmt.Errorf("diagnostics found issues"))
 	}
 
 	if !doctorAutoFix {
@@ -142,6 +161,64 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runDoctorReport runs the doctor checks and writes them as a doctorReport.
+func runDoctorReport(ctx context.Context, format output.Format) error {
+	report := doctorReport{OK: true}
+	for _, r := range doctorChecks() {
+		report.Checks = append(report.Checks, doctorCheck{Name: r.name, Status: r.status, Message: r.message})
+		if r.status == "error" {
+			report.OK = false
+		}
+	}
+
+	if doctorCluster != "" || doctorPreflight {
+		cluster, results, err := runPreflightChecks(ctx, doctorCluster, doctorPreflight)
+		if err != nil {
+			return err
+		}
+		report.Cluster = cluster.ClusterName
+		report.Preflight = results
+		if preflight.HasErrors(results) {
+			report.OK = false
+		}
+	}
+
+	if err := output.Write(os.Stdout, format, report); err != nil {
+		return err
+	}
+	if !report.OK {
+		return exitcode.Wrap(exitcode.PreflightFailed, fmt.Errorf("diagnostics found issues"))
+	}
+	return nil
+}
+
+// doctorChecks runs the basic diagnostics.
+func doctorChecks() []checkResult {
+	results := []checkResult{}
+
+	// Check 1: Configuration file
+	results = append(results, checkConfigFile())
+
+	// Check 2: OCI configuration
+	results = append(results, checkOCIConfig())
+
+	// Check 3: SSH keys
+	results = append(results, checkSSHKeys())
+
+	// Check 4: OCI CLI
+	results = append(results, checkOCICLI())
+
+	// Check 5: OCI connectivity (if verbose)
+	if doctorVerbose {
+		results = append(results, checkOCIConnectivity())
+	}
+
+	// Check 6: Clusters configuration
+	results = append(results, checkClustersConfig())
+
+	return results
+}
+
 // runAutoFix runs the auto-fix process.
 func runAutoFix(dryRun bool) error {
 	fmt.Println("Auto-Fix:")
@@ -194,12 +271,14 @@ func runAutoFix(dryRun bool) error {
 	return nil
 }
 
-// runPreflightChecks runs OCI-aware preflight checks for a specific cluster.
-func runPreflightChecks(ctx context.Context, clusterName string, fullPreflight bool) ([]preflight.CheckResult, error) {
+// runPreflightChecks runs OCI-aware preflight checks for a specific cluster,
+// or the first configured cluster if clusterName is empty. It returns the
+// cluster checked.
+func runPreflightChecks(ctx context.Context, clusterName string, fullPreflight bool) (*config.Cluster, []preflight.CheckResult, error) {
 	// Load config
 	cfg, err := config.ReadConfig(GetConfigFile())
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
 	// Find cluster
@@ -207,18 +286,17 @@ func runPreflightChecks(ctx context.Context, clusterName string, fullPreflight b
 	if clusterName != "" {
 		cluster = config.FindClusterByName(cfg, clusterName)
 		if cluster == nil {
-			return nil, fmt.Errorf("cluster '%s' not found in config", clusterName)
+			return nil, nil, fmt.Errorf("cluster '%s' not found in config", clusterName)
 		}
 	} else if len(cfg.Clusters) > 0 {
 		cluster = cfg.Clusters[0]
-		fmt.Printf("No cluster specified, using first cluster: %s\n", cluster.ClusterName)
 	} else {
-		return nil, fmt.Errorf("no clusters configured")
+		return nil, nil, fmt.Errorf("no clusters configured")
 	}
 
 	// Create OCI client
 	var ociClient *client.OCIClient
-	ociClient, err = createOCIClient(cfg, cluster.Region)
+	ociClient, err = createOCIClient(cfg, cluster)
 	if err != nil {
 		log.Warn().Err(err).Msg("Could not create OCI client for preflight checks")
 	}
@@ -235,9 +313,6 @@ func runPreflightChecks(ctx context.Context, clusterName string, fullPreflight b
 	// Create checker and run
 	checker := preflight.NewChecker(opts)
 
-	fmt.Printf("Preflight Checks for '%s':\n", cluster.ClusterName)
-	fmt.Println("---------------------------")
-
 	var results []preflight.CheckResult
 	if fullPreflight {
 		results = checker.RunAll(ctx)
@@ -245,9 +320,7 @@ func runPreflightChecks(ctx context.Context, clusterName string, fullPreflight b
 		results = checker.RunForCluster(ctx)
 	}
 
-	preflight.PrintResults(results, doctorVerbose)
-
-	return results, nil
+	return cluster, results, nil
 }
 
 func checkConfigFile() checkResult {
@@ -347,7 +420,7 @@ func checkOCICLI() checkResult {
 
 	// Check OCI CLI version
 	cmd := exec.Command("oci", "--version")
-	output, err := cmd.Output()
+	out, err := cmd.Output()
 	if err != nil {
 		return checkResult{
 			name:    "OCI CLI",
@@ -359,7 +432,7 @@ func checkOCICLI() checkResult {
 	return checkResult{
 		name:    "OCI CLI",
 		status:  "ok",
-		message: fmt.Sprintf("Installed (%s)", string(output)[:len(output)-1]),
+		message: fmt.Sprintf("Installed (%s)", string(out)[:len(out)-1]),
 	}
 }
 
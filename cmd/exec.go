@@ -8,8 +8,8 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
-	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/scotttball/tunatap/internal/bastion"
@@ -18,7 +18,6 @@ import (
 	"github.com/scotttball/tunatap/internal/config"
 	"github.com/scotttball/tunatap/internal/discovery"
 	"github.com/scotttball/tunatap/internal/kubeconfig"
-	"github.com/scotttball/tunatap/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +29,12 @@ var (
 	execOCIProfile   string
 	execRegionHint   string
 	execNoCache      bool
+	execPortStrategy string
+	execDryRun       bool
+	execAll          bool
+	execTags         []string
+	execGroup        string
+	execParallel     int
 )
 
 var execCmd = &cobra.Command{
@@ -41,10 +46,22 @@ The tunnel is established, a temporary kubeconfig is generated pointing to
 localhost:<port>, and the command is executed. When the command exits,
 the tunnel is torn down and the temporary kubeconfig is cleaned up.
 
+With --dry-run, the bastion session that would be used, the equivalent ssh
+command and the command to run are printed without creating anything in OCI
+or running the command.
+
+Several clusters can be named before --, or selected with --all (every
+configured cluster), --group (every configured cluster of a group) or --tag. A tunnel is opened to each and the command is
+run once per cluster, with every line of its output prefixed by the cluster
+name. tunatap exits with the highest exit code of the runs.
+
 Examples:
   tunatap exec my-cluster -- kubectl get nodes
   tunatap exec my-cluster -- helm list -A
-  tunatap exec -c prod -- k9s`,
+  tunatap exec -c prod -- k9s
+  tunatap exec prod-east prod-west -- kubectl get nodes
+  tunatap exec --tag env=prod -- kubectl version
+  tunatap exec --group payments -- kubectl get pods -n payments`,
 	RunE:               runExec,
 	Args:               cobra.MinimumNArgs(1),
 	DisableFlagParsing: false,
@@ -55,44 +72,48 @@ func init() {
 
 	execCmd.Flags().StringVarP(&execClusterName, "cluster", "c", "", "cluster name to connect to")
 	execCmd.Flags().StringVarP(&execEndpointName, "endpoint", "e", "", "endpoint name (e.g., 'private', 'public')")
-	execCmd.Flags().StringVarP(&execBastionName, "bastion", "b", "", "bastion name to use")
-	execCmd.Flags().BoolVar(&execNoOCIAuth, "no-oci-auth", false, "disable OCI exec-auth in kubeconfig (use insecure mode)")
+	execCmd.Flags().StringVarP(&execBastionName, "bastion", "b", "", "bastion name or OCID to use")
+	execCmd.Flags().BoolVar(&execNoOCIAuth, "no-oci-auth", false, "disable OCI exec-auth in kubeconfig")
 	execCmd.Flags().StringVar(&execOCIProfile, "oci-profile", "", "OCI config profile for exec-auth (overrides config)")
 	execCmd.Flags().StringVarP(&execRegionHint, "region", "r", "", "region hint for cluster discovery (optional)")
 	execCmd.Flags().BoolVar(&execNoCache, "no-cache", false, "skip cache and force fresh discovery")
+	execCmd.Flags().StringVar(&execPortStrategy, "port-strategy", "", "what to do when the cluster's local_port is busy: increment, fail or takeover")
+	execCmd.Flags().BoolVar(&execDryRun, "dry-run", false, "print the session, ssh command and command that would be run without creating anything in OCI")
+	execCmd.Flags().BoolVar(&execAll, "all", false, "run the command against every configured cluster")
+	execCmd.Flags().StringArrayVar(&execTags, "tag", nil, "run the command against every cluster with this tag, key=value or namespace.key=value (repeatable; all must match)")
+	execCmd.Flags().StringVar(&execGroup, "group", "", "run the command against every configured cluster in this group")
+	execCmd.Flags().IntVar(&execParallel, "parallel", 0, "with several clusters, how many to run at once (default: all)")
 }
 
 func runExec(cmd *cobra.Command, args []string) error {
-	// Parse args to find cluster name and command
-	clusterArg := ""
-	commandArgs := args
-
-	// Check if first arg is cluster name (before --)
-	if len(args) > 0 && args[0] != "--" && execClusterName == "" {
-		clusterArg = args[0]
-		commandArgs = args[1:]
-	}
-
-	// Remove leading "--" if present
-	if len(commandArgs) > 0 && commandArgs[0] == "--" {
-		commandArgs = commandArgs[1:]
+	tags, err := parseTagSelectors(execTags)
+	if err != nil {
+		return err
 	}
+	selectedElsewhere := execClusterName != "" || execAll || execGroup != "" || len(tags) > 0
+	clusterArgs, commandArgs := splitExecArgs(args, cmd.ArgsLenAtDash(), selectedElsewhere)
 
 	if len(commandArgs) == 0 {
 		return fmt.Errorf("no command specified")
 	}
+	if len(clusterArgs) > 1 || execAll || execGroup != "" || len(tags) > 0 {
+		return runExecMulti(cmd, clusterArgs, tags, commandArgs)
+	}
+	clusterArg := ""
+	if len(clusterArgs) == 1 {
+		clusterArg = clusterArgs[0]
+	}
 
-	// Try to load configuration (non-fatal if missing for zero-touch mode)
-	cfg, cfgErr := config.ReadConfig(GetConfigFile())
-	if cfgErr != nil {
-		// Use default config for zero-touch mode
-		log.Debug().Msg("No config file found, using zero-touch mode")
-		cfg = config.DefaultConfig()
-	} else {
-		// Configure globals from config
-		if err := config.ConfigureGlobals(cfg); err != nil {
-			return fmt.Errorf("failed to configure globals: %w", err)
-		}
+	cfg, cfgLoaded, err := readConfig(GetConfigFile())
+	if err != nil {
+		return err
+	}
+
+	if err := configureHostKeys(cfg, true); err != nil {
+		return err
+	}
+	if err := configureCredentials(cmd.Context(), cfg); err != nil {
+		return err
 	}
 
 	// Determine cluster name
@@ -103,10 +124,9 @@ func runExec(cmd *cobra.Command, args []string) error {
 
 	var selectedCluster *config.Cluster
 	var ociClient *client.OCIClient
-	var err error
 
 	// Try to find cluster in config first (if we have a config)
-	if clusterToUse != "" && cfgErr == nil && !cfg.SkipDiscovery {
+	if clusterToUse != "" && cfgLoaded && !cfg.SkipDiscovery {
 		selectedCluster = config.FindClusterByName(cfg, clusterToUse)
 	}
 
@@ -114,6 +134,11 @@ func runExec(cmd *cobra.Command, args []string) error {
 	if selectedCluster == nil && clusterToUse != "" {
 		log.Info().Msgf("Cluster '%s' not found in config, attempting discovery...", clusterToUse)
 
+		hints, err := cluster.NewDiscoveryHints(cfg, execRegionHint)
+		if err != nil {
+			return err
+		}
+
 		// Create OCI client with auto-detection for discovery
 		ociClient, err = createOCIClientForDiscovery(cfg)
 		if err != nil {
@@ -123,15 +148,15 @@ func runExec(cmd *cobra.Command, args []string) error {
 		// Initialize cache
 		var cache *discovery.Cache
 		if !execNoCache {
-			ttl := time.Duration(cfg.GetCacheTTLHours()) * time.Hour
-			cache, _ = discovery.NewCache(utils.DefaultTunatapDir(), ttl)
+			cache = cluster.NewDiscoveryCache(cfg)
 		}
 
 		// Perform discovery
-		discoverer := discovery.NewDiscoverer(ociClient, cache)
-		hints := &discovery.DiscoveryHints{Region: execRegionHint}
-
+		discoverer := cluster.NewDiscoverer(ociClient, cache)
 		discovered, err := discoverer.DiscoverClusterWithHints(cmd.Context(), clusterToUse, hints)
+		if err != nil {
+			discovered, err = cluster.ChooseDiscoveredCluster(cmd.Context(), discoverer, hints, err)
+		}
 		if err != nil {
 			if errors.Is(err, discovery.ErrMultipleClustersFound) {
 				return err
@@ -151,8 +176,8 @@ func runExec(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to resolve cluster config: %w", err)
 		}
 
-		// Set region on OCI client
-		ociClient.SetRegion(discovered.Region)
+		// Use the OCI client for the cluster's region
+		ociClient = ociClient.WithRegion(discovered.Region)
 	} else if selectedCluster == nil {
 		// Interactive selection from config (or error if no clusters)
 		selectedCluster, err = selectCluster(cfg, clusterToUse)
@@ -176,17 +201,37 @@ func runExec(cmd *cobra.Command, args []string) error {
 
 	// Create OCI client if not already created (for config-based flow)
 	if ociClient == nil {
-		ociClient, err = createOCIClient(cfg, selectedCluster.Region)
+		ociClient, err = createOCIClient(cfg, selectedCluster)
 		if err != nil {
 			return fmt.Errorf("failed to create OCI client: %w", err)
 		}
 	}
 
+	// The generated kubeconfig needs a TCP port on localhost, so ignore any
+	// local_socket or bind_address
+	selectedCluster.LocalSocket = nil
+	selectedCluster.BindAddress = nil
+	if execPortStrategy != "" {
+		selectedCluster.PortStrategy = &execPortStrategy
+	}
+
 	// Validate cluster with auto port allocation
 	if err := cluster.ValidateAndUpdateCluster(cmd.Context(), ociClient, selectedCluster, true, 0); err != nil {
 		return fmt.Errorf("failed to validate cluster: %w", err)
 	}
 
+	if execDryRun {
+		plan, err := bastion.PlanTunnel(cmd.Context(), ociClient, cfg, selectedCluster, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		printTunnelPlan(selectedCluster, endpoint, plan)
+		fmt.Printf("Would run with a temporary kubeconfig for localhost:%d:\n  %s\n", *selectedCluster.LocalPort, strings.Join(commandArgs, " "))
+		return nil
+	}
+	releasePort := claimLocalPort(selectedCluster, selectedCluster.ClusterName, "")
+	defer releasePort()
+
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(cmd.Context())
 	defer cancel()
@@ -219,7 +264,7 @@ func runExec(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create temporary kubeconfig
-	kubeconfigPath, err := createTempKubeconfig(cfg, selectedCluster, actualPort, execNoOCIAuth, execOCIProfile)
+	kubeconfigPath, err := createTempKubeconfig(ctx, cfg, selectedCluster, actualPort, execNoOCIAuth, execOCIProfile)
 	if err != nil {
 		cancel()
 		return fmt.Errorf("failed to create kubeconfig: %w", err)
@@ -244,6 +289,7 @@ func runExec(cmd *cobra.Command, args []string) error {
 
 	// Wait for tunnel to close
 	<-tunnelErr
+	releasePort()
 
 	if cmdErr != nil {
 		if exitErr, ok := cmdErr.(*exec.ExitError); ok {
@@ -255,32 +301,28 @@ func runExec(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// createTempKubeconfig creates a temporary kubeconfig file for the cluster.
-// If the cluster has an OCID and OCI auth is not disabled, it uses OCI exec-auth
-// so kubectl can get short-lived tokens automatically via the OCI CLI.
-func createTempKubeconfig(cfg *config.Config, cluster *config.Cluster, port int, noOCIAuth bool, profileOverride string) (string, error) {
-	var kubecfg *kubeconfig.Kubeconfig
-
-	// Determine OCI profile to use
-	profile := profileOverride
-	if profile == "" {
-		profile = cfg.OCIProfile
+// splitExecArgs splits the arguments of exec into cluster names and the
+// command. dash is the number of arguments before --, or -1 without one.
+// Without --, the first argument is the cluster unless the clusters are
+// selected by flags.
+func splitExecArgs(args []string, dash int, selectedElsewhere bool) (clusters, command []string) {
+	switch {
+	case dash >= 0:
+		return args[:dash], args[dash:]
+	case selectedElsewhere || len(args) == 0:
+		return nil, args
+	default:
+		return args[:1], args[1:]
 	}
+}
 
-	// Use OCI exec-auth if cluster has OCID and OCI auth is not disabled
-	if cluster.Ocid != nil && *cluster.Ocid != "" && !noOCIAuth {
-		log.Debug().Msg("Using OCI exec-auth for kubeconfig (kubectl will get tokens via OCI CLI)")
-		kubecfg = kubeconfig.NewOCIKubeconfigForTunnel(
-			cluster.ClusterName,
-			*cluster.Ocid,
-			cluster.Region,
-			port,
-			profile,
-		)
-	} else {
-		// Fall back to simple insecure kubeconfig
-		log.Debug().Msg("Using insecure kubeconfig (no OCI exec-auth)")
-		kubecfg = kubeconfig.NewInsecureKubeconfig(cluster.ClusterName, port)
+// createTempKubeconfig creates a temporary kubeconfig file for the cluster.
+// If the cluster has an OCID and OCI auth is not disabled, it uses OCI exec-auth
+// so kubectl can get short-lived tokens automatically from 'tunatap token'.
+func createTempKubeconfig(ctx context.Context, cfg *config.Config, cluster *config.Cluster, port int, noOCIAuth bool, profileOverride string) (string, error) {
+	kubecfg, err := buildKubeconfig(ctx, cfg, cluster, port, noOCIAuth, profileOverride)
+	if err != nil {
+		return "", err
 	}
 
 	// Create temp file
@@ -293,3 +335,72 @@ func createTempKubeconfig(cfg *config.Config, cluster *config.Cluster, port int,
 
 	return kubeconfigPath, nil
 }
+
+// buildKubeconfig generates a kubeconfig pointing at a tunnel on localhost:port,
+// following the kubeconfig settings of the cluster.
+func buildKubeconfig(ctx context.Context, cfg *config.Config, cluster *config.Cluster, port int, noOCIAuth bool, profileOverride string) (*kubeconfig.Kubeconfig, error) {
+	prefs := cluster.Kubeconfig
+	if prefs == nil {
+		prefs = &config.ClusterKubeconfig{}
+	}
+	opts := kubeconfig.TunnelOptions{
+		ClusterName: cluster.ClusterName,
+		Region:      cluster.Region,
+		Port:        port,
+		ContextName: prefs.ContextName,
+		Namespace:   prefs.Namespace,
+	}
+
+	// Use OCI exec-auth if cluster has OCID and OCI auth is not disabled
+	if cluster.Ocid != nil && *cluster.Ocid != "" && !noOCIAuth {
+		log.Debug().Msg("Using OCI exec-auth for kubeconfig (kubectl will get tokens from tunatap token)")
+		opts.ClusterID = *cluster.Ocid
+		opts.Profile = profileOverride
+		if opts.Profile == "" {
+			opts.Profile = prefs.OCIProfile
+		}
+		if opts.Profile == "" {
+			opts.Profile = cfg.GetOCIProfile(cluster)
+		}
+		opts.AuthType = cfg.GetOCIAuthType(cluster)
+	} else {
+		log.Debug().Msg("Using kubeconfig without OCI exec-auth")
+	}
+
+	if cluster.ShouldEmbedCA() {
+		caData, serverName, err := fetchClusterCA(ctx, cfg, cluster)
+		if err != nil {
+			return nil, fmt.Errorf("%w\n\nSet kubeconfig.embed_ca: false on the cluster to skip TLS verification instead", err)
+		}
+		opts.CAData, opts.TLSServerName = caData, serverName
+	} else {
+		log.Debug().Msg("Skipping TLS verification in kubeconfig (kubeconfig.embed_ca: false)")
+	}
+
+	return kubeconfig.NewTunnelKubeconfig(opts), nil
+}
+
+// fetchClusterCA returns the certificate authority of the cluster's API
+// server, and the host name its certificate is issued for.
+func fetchClusterCA(ctx context.Context, cfg *config.Config, cluster *config.Cluster) (caData, serverName string, err error) {
+	if cluster.Ocid == nil || *cluster.Ocid == "" {
+		return "", "", fmt.Errorf("cluster '%s' has no OCID to fetch its certificate authority with", cluster.ClusterName)
+	}
+	ociClient, err := createOCIClient(cfg, cluster)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create OCI client: %w", err)
+	}
+	data, err := ociClient.GetClusterKubeconfig(ctx, *cluster.Ocid)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch the certificate authority of cluster '%s': %w", cluster.ClusterName, err)
+	}
+	kubecfg, err := kubeconfig.Parse(data)
+	if err != nil {
+		return "", "", err
+	}
+	caData, serverName, err = kubecfg.ClusterCA()
+	if err != nil {
+		return "", "", fmt.Errorf("cluster '%s': %w", cluster.ClusterName, err)
+	}
+	return caData, serverName, nil
+}
@@ -0,0 +1,373 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/spf13/cobra"
+)
+
+// execResult is the outcome of running the command against one cluster.
+type execResult struct {
+	exitCode int
+	err      error
+}
+
+// runExecMulti runs the command once against each selected cluster, each
+// through its own tunnel and kubeconfig, and exits with the highest exit
+// code of the runs.
+func runExecMulti(cmd *cobra.Command, names []string, tags []discovery.TagSelector, commandArgs []string) error {
+	if err := checkMultiExecFlags(names, tags); err != nil {
+		return err
+	}
+
+	code, err := execMulti(cmd, names, tags, commandArgs)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// checkMultiExecFlags rejects flags and selections that conflict when the
+// command runs against several clusters.
+func checkMultiExecFlags(names []string, tags []discovery.TagSelector) error {
+	if execClusterName != "" {
+		return fmt.Errorf("--cluster cannot be used with several clusters")
+	}
+	if execAll && len(tags) > 0 {
+		return fmt.Errorf("--all cannot be used with --tag")
+	}
+	if execAll && len(names) > 0 {
+		return fmt.Errorf("--all cannot be used with cluster names")
+	}
+	if len(tags) > 0 && len(names) > 0 {
+		return fmt.Errorf("--tag cannot be used with cluster names")
+	}
+	if execGroup != "" && (execAll || len(tags) > 0 || len(names) > 0) {
+		return fmt.Errorf("--group cannot be used with --all, --tag or cluster names")
+	}
+	if execParallel < 0 {
+		return fmt.Errorf("--parallel must not be negative")
+	}
+	return nil
+}
+
+// execMulti resolves every selected cluster, giving each a distinct local
+// port, then runs the command against them, at most --parallel at a time.
+// It returns the highest exit code, counting a cluster that could not be
+// reached as 1.
+func execMulti(cmd *cobra.Command, names []string, tags []discovery.TagSelector, commandArgs []string) (int, error) {
+	cfg, cfgLoaded, err := loadConnectConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	if execAll {
+		if !cfgLoaded || len(cfg.Clusters) == 0 {
+			return 0, fmt.Errorf("--all requires clusters in config")
+		}
+		for _, c := range cfg.Clusters {
+			names = append(names, c.ClusterName)
+		}
+	}
+	if execGroup != "" {
+		if names, err = groupClusterNames(cfg, execGroup); err != nil {
+			return 0, err
+		}
+	}
+
+	selected, err := resolveExecClusters(cmd.Context(), cfg, cfgLoaded, names, tags)
+	if err != nil {
+		return 0, err
+	}
+
+	usedPorts := make(map[int]string)
+	targets := make([]*multiTunnel, 0, len(selected))
+	for _, s := range selected {
+		t, err := prepareExecTarget(cmd.Context(), cfg, s.cluster, s.ociClient, usedPorts)
+		if err != nil {
+			return 0, fmt.Errorf("cluster '%s': %w", s.cluster.ClusterName, err)
+		}
+		targets = append(targets, t)
+	}
+
+	if execDryRun {
+		for _, t := range targets {
+			plan, err := bastion.PlanTunnel(cmd.Context(), t.ociClient, cfg, t.cluster, t.endpoint, nil)
+			if err != nil {
+				return 0, fmt.Errorf("cluster '%s': %w", t.cluster.ClusterName, err)
+			}
+			printTunnelPlan(t.cluster, t.endpoint, plan)
+			fmt.Printf("Would run with a temporary kubeconfig for localhost:%d:\n  %s\n", *t.cluster.LocalPort, strings.Join(commandArgs, " "))
+		}
+		return 0, nil
+	}
+	for _, t := range targets {
+		defer claimLocalPort(t.cluster, t.cluster.ClusterName, "")()
+	}
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		select {
+		case <-sigChan:
+			log.Info().Msg("Received shutdown signal, stopping commands...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	parallel := execParallel
+	if parallel == 0 {
+		parallel = len(targets)
+	}
+	sem := make(chan struct{}, parallel)
+
+	// Lines from different clusters interleave, but never within a line
+	var outMu sync.Mutex
+	results := make([]execResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			prefix := fmt.Sprintf("[%s] ", t.cluster.ClusterName)
+			stdout := newPrefixWriter(os.Stdout, &outMu, prefix)
+			stderr := newPrefixWriter(os.Stderr, &outMu, prefix)
+			code, err := runExecTarget(ctx, cfg, t, commandArgs, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+			results[i] = execResult{exitCode: code, err: err}
+		}()
+	}
+	wg.Wait()
+
+	printExecSummary(os.Stderr, targets, results)
+	return aggregateExitCode(results), nil
+}
+
+// execCluster is a cluster selected for a multi-cluster exec, with the OCI
+// client discovery created for it, if any.
+type execCluster struct {
+	cluster   *config.Cluster
+	ociClient *client.OCIClient
+}
+
+// resolveExecClusters finds the named clusters, or every cluster carrying
+// tags when there are tags.
+func resolveExecClusters(ctx context.Context, cfg *config.Config, cfgLoaded bool, names []string, tags []discovery.TagSelector) ([]execCluster, error) {
+	if len(tags) > 0 {
+		clusters, err := cluster.ResolveAllTagged(ctx, cfg, tags, execRegionHint, execNoCache)
+		if err != nil {
+			return nil, err
+		}
+		selected := make([]execCluster, len(clusters))
+		for i, c := range clusters {
+			selected[i] = execCluster{cluster: c}
+		}
+		return selected, nil
+	}
+
+	selected := make([]execCluster, 0, len(names))
+	for _, name := range names {
+		c, ociClient, err := resolveCluster(ctx, cfg, cfgLoaded, name, execRegionHint, execNoCache)
+		if err != nil {
+			return nil, fmt.Errorf("cluster '%s': %w", name, err)
+		}
+		selected = append(selected, execCluster{cluster: c, ociClient: ociClient})
+	}
+	return selected, nil
+}
+
+// prepareExecTarget applies the exec flags to a selected cluster, validates
+// it and moves it off any local port already given to another cluster.
+func prepareExecTarget(ctx context.Context, cfg *config.Config, c *config.Cluster, ociClient *client.OCIClient, usedPorts map[int]string) (*multiTunnel, error) {
+	if execBastionName != "" {
+		c.Bastion = &execBastionName
+	}
+
+	// The generated kubeconfig needs a TCP port on localhost, so ignore any
+	// local_socket or bind_address
+	c.LocalSocket = nil
+	c.BindAddress = nil
+	if execPortStrategy != "" {
+		c.PortStrategy = &execPortStrategy
+	}
+
+	endpoint := config.GetClusterEndpoint(c, execEndpointName)
+	if endpoint == nil {
+		return nil, fmt.Errorf("no endpoints configured for cluster '%s'", c.ClusterName)
+	}
+
+	if ociClient == nil {
+		var err error
+		ociClient, err = createOCIClient(cfg, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	if err := cluster.ValidateAndUpdateCluster(ctx, ociClient, c, true, 0); err != nil {
+		return nil, fmt.Errorf("failed to validate cluster: %w", err)
+	}
+	if err := reserveLocalPort(c, usedPorts); err != nil {
+		return nil, err
+	}
+
+	return &multiTunnel{cluster: c, endpoint: endpoint, ociClient: ociClient}, nil
+}
+
+// runExecTarget opens the tunnel to one cluster, runs the command with a
+// kubeconfig for it and closes the tunnel. It returns the command's exit
+// code, or an error if the command could not be run.
+func runExecTarget(ctx context.Context, cfg *config.Config, t *multiTunnel, commandArgs []string, stdout, stderr io.Writer) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tunnelErr := make(chan error, 1)
+	tunnelReady := make(chan int, 1)
+	go func() {
+		tunnelErr <- bastion.TunnelThroughBastionWithCallback(ctx, t.ociClient, cfg, t.cluster, t.endpoint, func(port int) {
+			select {
+			case tunnelReady <- port:
+			default:
+			}
+		})
+	}()
+
+	var port int
+	select {
+	case port = <-tunnelReady:
+		log.Info().Msgf("Tunnel to %s ready on port %d", t.cluster.ClusterName, port)
+	case err := <-tunnelErr:
+		return 0, fmt.Errorf("tunnel failed to start: %w", err)
+	case <-ctx.Done():
+		<-tunnelErr
+		return 0, fmt.Errorf("interrupted")
+	}
+	defer func() {
+		cancel()
+		<-tunnelErr
+	}()
+
+	kubeconfigPath, err := createTempKubeconfig(ctx, cfg, t.cluster, port, execNoOCIAuth, execOCIProfile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create kubeconfig: %w", err)
+	}
+	defer os.Remove(kubeconfigPath)
+
+	execCommand := exec.CommandContext(ctx, commandArgs[0], commandArgs[1:]...)
+	execCommand.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath))
+	execCommand.Stdout = stdout
+	execCommand.Stderr = stderr
+
+	if err := execCommand.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+	return 0, nil
+}
+
+// aggregateExitCode returns the highest exit code of results, counting a
+// run that failed before the command exited as 1.
+func aggregateExitCode(results []execResult) int {
+	code := 0
+	for _, r := range results {
+		c := r.exitCode
+		if r.err != nil && c == 0 {
+			c = 1
+		}
+		if c > code {
+			code = c
+		}
+	}
+	return code
+}
+
+// printExecSummary prints the exit code, or error, of each cluster's run.
+func printExecSummary(w io.Writer, targets []*multiTunnel, results []execResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tEXIT")
+	for i, t := range targets {
+		status := fmt.Sprintf("%d", results[i].exitCode)
+		if results[i].err != nil {
+			status = "error: " + results[i].err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", t.cluster.ClusterName, status)
+	}
+	tw.Flush()
+}
+
+// prefixWriter writes each complete line written to it to w with a prefix,
+// holding mu for the write so lines of several writers do not mix.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+// newPrefixWriter returns a prefixWriter writing to w.
+func newPrefixWriter(w io.Writer, mu *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{w: w, mu: mu, prefix: prefix}
+}
+
+// Write buffers p and writes out the lines it completes.
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return len(data), err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes out a final line with no newline.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) == 0 {
+		return
+	}
+	_ = p.writeLine(append(p.buf, '\n'))
+	p.buf = nil
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
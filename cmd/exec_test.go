@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSplitExecArgs(t *testing.T) {
+	tests := []struct {
+		name              string
+		args              []string
+		dash              int
+		selectedElsewhere bool
+		wantClusters      []string
+		wantCommand       []string
+	}{
+		{"cluster then command", []string{"prod", "kubectl", "get", "nodes"}, 1, false, []string{"prod"}, []string{"kubectl", "get", "nodes"}},
+		{"no dash", []string{"prod", "kubectl"}, -1, false, []string{"prod"}, []string{"kubectl"}},
+		{"several clusters", []string{"a", "b", "kubectl"}, 2, false, []string{"a", "b"}, []string{"kubectl"}},
+		{"selected by flag", []string{"kubectl", "get", "nodes"}, -1, true, nil, []string{"kubectl", "get", "nodes"}},
+		{"flag and dash", []string{"k9s"}, 0, true, []string{}, []string{"k9s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters, command := splitExecArgs(tt.args, tt.dash, tt.selectedElsewhere)
+			if len(clusters) != len(tt.wantClusters) || (len(clusters) > 0 && !reflect.DeepEqual(clusters, tt.wantClusters)) {
+				t.Errorf("clusters = %v, want %v", clusters, tt.wantClusters)
+			}
+			if !reflect.DeepEqual(command, tt.wantCommand) {
+				t.Errorf("command = %v, want %v", command, tt.wantCommand)
+			}
+		})
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := newPrefixWriter(&out, &mu, "[prod] ")
+
+	w.Write([]byte("NAME   STATUS\nnode-1 Ready\nnode-2"))
+	w.Write([]byte(" Ready\npartial"))
+	w.Flush()
+
+	want := "[prod] NAME   STATUS\n[prod] node-1 Ready\n[prod] node-2 Ready\n[prod] partial\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestAggregateExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []execResult
+		want    int
+	}{
+		{"all succeeded", []execResult{{}, {}}, 0},
+		{"highest code", []execResult{{exitCode: 1}, {exitCode: 3}, {}}, 3},
+		{"tunnel failure", []execResult{{}, {err: errors.New("tunnel failed")}}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := aggregateExitCode(tt.results); got != tt.want {
+			t.Errorf("%s: aggregateExitCode() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/spf13/cobra"
+)
+
+// ErrorFormatEnv sets the default of --error-format.
+const ErrorFormatEnv = "TUNATAP_ERROR_FORMAT"
+
+// errorFormat is how the error ending a run is printed: text or json.
+var errorFormat string
+
+// exitCode returns the exit code for the error a command returned. OCI
+// refusing a request as unauthenticated or unauthorized and the user
+// cancelling are recognised wherever they happen; otherwise the code the
+// error was marked with is used, then the discovery errors it wraps.
+func exitCode(err error) exitcode.Code {
+	if err == nil {
+		return exitcode.OK
+	}
+	if errors.Is(err, context.Canceled) {
+		return exitcode.Cancelled
+	}
+
+	var serviceErr common.ServiceError
+	if errors.As(err, &serviceErr) {
+		switch serviceErr.GetHTTPStatusCode() {
+		case 401, 403:
+			return exitcode.Auth
+		}
+	}
+	if errors.Is(err, discovery.ErrClusterAccessDenied) || errors.Is(err, discovery.ErrBastionAccessDenied) ||
+		errors.Is(err, discovery.ErrCompartmentAccessDenied) {
+		return exitcode.Auth
+	}
+
+	if code, ok := exitcode.Of(err); ok {
+		return code
+	}
+	switch {
+	case errors.Is(err, discovery.ErrClusterNotFound), errors.Is(err, discovery.ErrCompartmentNotFound):
+		return exitcode.NotFound
+	case errors.Is(err, discovery.ErrNoBastionFound), errors.Is(err, discovery.ErrBastionNotFound):
+		return exitcode.BastionUnavailable
+	}
+	return exitcode.Failure
+}
+
+// errorReport is the error printed with --error-format json.
+type errorReport struct {
+	Error    string `json:"error"`
+	Category string `json:"category"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// printError writes the error ending a run to w in the --error-format.
+func printError(w io.Writer, err error, code exitcode.Code) {
+	if errorFormat == "json" {
+		data, jsonErr := json.Marshal(errorReport{Error: err.Error(), Category: code.Category(), ExitCode: int(code)})
+		if jsonErr == nil {
+			fmt.Fprintln(w, string(data))
+			return
+		}
+	}
+	fmt.Fprintln(w, err)
+}
+
+// checkErrorFormat validates --error-format. With json, cobra's own
+// "Error:" line of root is silenced so that stderr ends with the JSON report
+// alone.
+func checkErrorFormat(root *cobra.Command) error {
+	switch errorFormat {
+	case "text":
+	case "json":
+		root.SilenceErrors = true
+		root.SilenceUsage = true
+	default:
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("invalid --error-format %q: must be text or json", errorFormat))
+	}
+	return nil
+}
+
+// flagError marks flag parsing errors as usage errors.
+func flagError(cmd *cobra.Command, err error) error {
+	return exitcode.Wrap(exitcode.Usage, err)
+}
+
+// defaultErrorFormat returns the default of --error-format.
+func defaultErrorFormat() string {
+	if v := os.Getenv(ErrorFormatEnv); v != "" {
+		return v
+	}
+	return "text"
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/exitcode"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want exitcode.Code
+	}{
+		{"nil", nil, exitcode.OK},
+		{"plain", errors.New("boom"), exitcode.Failure},
+		{"cancelled", fmt.Errorf("tunnel: %w", context.Canceled), exitcode.Cancelled},
+		{"marked", exitcode.Wrap(exitcode.PreflightFailed, errors.New("preflight checks failed")), exitcode.PreflightFailed},
+		{"not found", fmt.Errorf("discovery failed: %w", &discovery.ClusterNotFoundError{Name: "prod"}), exitcode.NotFound},
+		{"no bastion", fmt.Errorf("resolve: %w", discovery.ErrNoBastionFound), exitcode.BastionUnavailable},
+		{"access denied", exitcode.Wrap(exitcode.NotFound, discovery.ErrClusterAccessDenied), exitcode.Auth},
+		{"bastion access denied", exitcode.Wrap(exitcode.BastionUnavailable, discovery.ErrBastionAccessDenied), exitcode.Auth},
+		{"compartment not found", fmt.Errorf("discover: %w", discovery.ErrCompartmentNotFound), exitcode.NotFound},
+		{"innermost mark", exitcode.Wrap(exitcode.TunnelFailed, exitcode.Wrap(exitcode.BastionUnavailable, errors.New("session"))), exitcode.BastionUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintErrorJSON(t *testing.T) {
+	old := errorFormat
+	defer func() { errorFormat = old }()
+	errorFormat = "json"
+
+	var buf bytes.Buffer
+	printError(&buf, errors.New("cluster 'prod' not found"), exitcode.NotFound)
+
+	var report errorReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("printError() wrote invalid JSON %q: %v", buf.String(), err)
+	}
+	if report.Category != "not_found" || report.ExitCode != 4 || report.Error != "cluster 'prod' not found" {
+		t.Errorf("printError() = %+v", report)
+	}
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	forwardLocalPort    int
+	forwardBastionName  string
+	forwardRegionHint   string
+	forwardNoCache      bool
+	forwardBind         string
+	forwardPortStrategy string
+	forwardDryRun       bool
+)
+
+var forwardCmd = &cobra.Command{
+	Use:   "forward <cluster> <host:port>",
+	Short: "Forward a local port to any host in a cluster's VCN",
+	Long: `Forward a local port to a private host and port, such as a Redis instance
+or an internal load balancer, through the bastion of a cluster.
+
+The cluster only provides the bastion; the target needs no config entry. It
+is given as an IP address or a DNS name, which is resolved on the bastion
+side, and must be reachable from the bastion's subnet. The local port is
+chosen automatically unless --port is given.
+
+Examples:
+  tunatap forward prod-cluster 10.0.20.15:6379
+  tunatap forward prod-cluster internal-lb.example.oraclevcn.com:443 --port 8443`,
+	Args: cobra.ExactArgs(2),
+	RunE: runForward,
+}
+
+func init() {
+	rootCmd.AddCommand(forwardCmd)
+
+	forwardCmd.Flags().IntVarP(&forwardLocalPort, "port", "p", 0, "local port to listen on (default: automatic)")
+	forwardCmd.Flags().StringVarP(&forwardBastionName, "bastion", "b", "", "bastion name or OCID to use")
+	forwardCmd.Flags().StringVarP(&forwardRegionHint, "region", "r", "", "region hint for cluster discovery (optional)")
+	forwardCmd.Flags().BoolVar(&forwardNoCache, "no-cache", false, "skip cache and force fresh discovery")
+	forwardCmd.Flags().StringVar(&forwardBind, "bind", "", "local IPv4 address to listen on, e.g. 0.0.0.0 (default localhost)")
+	forwardCmd.Flags().StringVar(&forwardPortStrategy, "port-strategy", "", "what to do when --port is busy: increment, fail or takeover")
+	forwardCmd.Flags().BoolVar(&forwardDryRun, "dry-run", false, "print the session and ssh command that would be used without creating anything in OCI")
+}
+
+func runForward(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	target, err := parseForwardTarget(args[1])
+	if err != nil {
+		return err
+	}
+
+	cfg, cfgLoaded, err := loadConnectConfig()
+	if err != nil {
+		return err
+	}
+
+	selectedCluster, ociClient, err := resolveCluster(cmd.Context(), cfg, cfgLoaded, name, forwardRegionHint, forwardNoCache)
+	if err != nil {
+		return err
+	}
+
+	if forwardBastionName != "" {
+		selectedCluster.Bastion = &forwardBastionName
+	}
+
+	// The cluster's local port, socket and hooks belong to its API tunnel
+	selectedCluster.LocalPort = nil
+	selectedCluster.LocalSocket = nil
+	selectedCluster.Hooks = nil
+	if forwardBind != "" {
+		selectedCluster.BindAddress = &forwardBind
+	}
+	if forwardPortStrategy != "" {
+		selectedCluster.PortStrategy = &forwardPortStrategy
+	}
+
+	if ociClient == nil {
+		ociClient, err = createOCIClient(cfg, selectedCluster)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	if err := cluster.ValidateAndUpdateCluster(cmd.Context(), ociClient, selectedCluster, true, forwardLocalPort); err != nil {
+		return fmt.Errorf("failed to validate cluster: %w", err)
+	}
+
+	if forwardDryRun {
+		plan, err := bastion.PlanTunnel(cmd.Context(), ociClient, cfg, selectedCluster, target, nil)
+		if err != nil {
+			return err
+		}
+		printTunnelPlan(selectedCluster, target, plan)
+		return nil
+	}
+	defer claimLocalPort(selectedCluster, selectedCluster.ClusterName, "")()
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Info().Msg("Received shutdown signal, closing tunnel...")
+		cancel()
+	}()
+
+	auditLogger := newAuditLogger(ctx, cfg)
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
+
+	log.Info().Msgf("Forwarding to %s:%d through the bastion of %s", target.Host(), target.Port, selectedCluster.ClusterName)
+	var readyOnce sync.Once
+	opts := &bastion.TunnelOptions{
+		AuditLogger: auditLogger,
+		OnReady: func(int) {
+			readyOnce.Do(func() {
+				fmt.Printf("Forwarding %s -> %s:%d\n", multiTunnelLocal(selectedCluster), target.Host(), target.Port)
+				fmt.Println("Press Ctrl-C to close the tunnel.")
+			})
+		},
+	}
+	err = bastion.TunnelThroughBastionWithOptions(ctx, ociClient, cfg, selectedCluster, target, opts)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// parseForwardTarget parses the host:port of tunatap forward into an
+// endpoint, using the host as a DNS name unless it is an IP address.
+func parseForwardTarget(s string) (*config.ClusterEndpoint, error) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target %q, expected host:port: %w", s, err)
+	}
+	if host == "" {
+		return nil, fmt.Errorf("invalid target %q: missing host", s)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid target %q: port must be between 1 and 65535", s)
+	}
+
+	endpoint := &config.ClusterEndpoint{Name: "forward", Port: port}
+	if net.ParseIP(host) != nil {
+		endpoint.Ip = host
+	} else {
+		endpoint.Fqdn = host
+	}
+	return endpoint, nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import "testing"
+
+func TestParseForwardTarget(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantIP   string
+		wantFqdn string
+		wantPort int
+		wantErr  bool
+	}{
+		{in: "10.0.20.15:6379", wantIP: "10.0.20.15", wantPort: 6379},
+		{in: "internal-lb.example.oraclevcn.com:443", wantFqdn: "internal-lb.example.oraclevcn.com", wantPort: 443},
+		{in: "[fd00::5]:5432", wantIP: "fd00::5", wantPort: 5432},
+		{in: "10.0.20.15", wantErr: true},
+		{in: ":6379", wantErr: true},
+		{in: "redis:0", wantErr: true},
+		{in: "redis:http", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		ep, err := parseForwardTarget(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseForwardTarget(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if ep.Ip != tt.wantIP || ep.Fqdn != tt.wantFqdn || ep.Port != tt.wantPort {
+			t.Errorf("parseForwardTarget(%q) = %+v", tt.in, ep)
+		}
+	}
+}
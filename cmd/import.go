@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/importer"
+	"github.com/scotttball/tunatap/internal/kubeconfig"
+	"github.com/scotttball/tunatap/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Add clusters from OCI CLI output, Terraform state or a kubeconfig",
+	Long: `Add clusters to the config file from the output of other tools. The
+clusters to add are listed, and nothing is written until you confirm.
+
+Clusters already configured under the same name are skipped, or updated
+with --update. An update changes the region, OCIDs and private endpoint and
+keeps everything else, such as the local port and other endpoints.
+
+Pass - as the file to read standard input; --yes is then needed, since the
+confirmation cannot be read from it.`,
+}
+
+var importOCICLICmd = &cobra.Command{
+	Use:   "oci-cli <file>",
+	Short: "Import clusters from 'oci ce cluster list' or 'get' JSON output",
+	Long: `Import the clusters in the JSON output of 'oci ce cluster list' or
+'oci ce cluster get'. Deleted clusters are left out.
+
+Examples:
+  oci ce cluster list -c $COMPARTMENT_ID > clusters.json
+  tunatap import oci-cli clusters.json
+  oci ce cluster list -c $COMPARTMENT_ID | tunatap import oci-cli - --yes --bastion prod-bastion`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport(importer.FromOCICLI, nil),
+}
+
+var importTerraformCmd = &cobra.Command{
+	Use:   "terraform [state-file]",
+	Short: "Import the OKE clusters of a Terraform state file",
+	Long: `Import the oci_containerengine_cluster resources of a Terraform state
+file (default: terraform.tfstate). A cluster whose API endpoint subnet is
+the target subnet of an oci_bastion_bastion in the same state is set to use
+that bastion.
+
+Examples:
+  tunatap import terraform
+  terraform state pull | tunatap import terraform - --yes`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImport(importer.FromTerraformState, func() []string { return []string{"terraform.tfstate"} }),
+}
+
+var importKubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig [file]",
+	Short: "Import the OKE contexts of a kubeconfig",
+	Long: `Import the contexts of a kubeconfig that authenticate with
+'oci ce cluster generate-token', naming each cluster after its context.
+Without a file, the kubeconfig files kubectl reads are used ($KUBECONFIG or
+~/.kube/config).
+
+Examples:
+  tunatap import kubeconfig
+  kubectl config view --raw | tunatap import kubeconfig - --yes`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImport(importer.FromKubeconfig, kubeconfig.DefaultPaths),
+}
+
+var (
+	importYes     bool
+	importDryRun  bool
+	importUpdate  bool
+	importBastion string
+	importRegion  string
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importOCICLICmd)
+	importCmd.AddCommand(importTerraformCmd)
+	importCmd.AddCommand(importKubeconfigCmd)
+
+	f := importCmd.PersistentFlags()
+	f.BoolVarP(&importYes, "yes", "y", false, "write the clusters without asking")
+	f.BoolVar(&importDryRun, "dry-run", false, "only list the clusters that would be imported")
+	f.BoolVar(&importUpdate, "update", false, "update clusters that are already configured")
+	f.StringVar(&importBastion, "bastion", "", "bastion name to set on every imported cluster")
+	f.StringVarP(&importRegion, "region", "r", "", "region for clusters whose region cannot be worked out")
+}
+
+// runImport returns the RunE of an import command that converts its input
+// with parse. defaults, if set, returns the files read when none is given.
+func runImport(parse func([]byte) ([]*config.Cluster, error), defaults func() []string) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		files := args
+		defaulted := len(files) == 0 && defaults != nil
+		if defaulted {
+			files = defaults()
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no file given")
+		}
+
+		var clusters []*config.Cluster
+		for _, file := range files {
+			data, err := readImportFile(file)
+			if defaulted && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			parsed, err := parse(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			clusters = append(clusters, parsed...)
+		}
+		if len(clusters) == 0 {
+			fmt.Println("No clusters found to import.")
+			return nil
+		}
+
+		for _, c := range clusters {
+			if c.Region == "" {
+				c.Region = importRegion
+			}
+			if c.Region != "" {
+				c.Region = string(common.StringToRegion(c.Region))
+			}
+			if importBastion != "" {
+				c.Bastion = &importBastion
+			}
+		}
+
+		cfg, err := config.ReadConfigFile(GetConfigFile())
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		printImportPlan(cfg, clusters)
+		if importDryRun {
+			return nil
+		}
+
+		if !importYes {
+			if files[0] == "-" || !ui.CanPrompt() {
+				return ui.PromptError("import", "pass --yes to write the clusters")
+			}
+			fmt.Print("\nWrite these clusters to the config? [y/N]: ")
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			if answer != "y" && answer != "yes" {
+				fmt.Println("Nothing imported.")
+				return nil
+			}
+		}
+
+		added, replaced, skipped := importer.Merge(cfg, clusters, importUpdate)
+		if len(added)+len(replaced) == 0 {
+			fmt.Println("Nothing imported; all clusters are already configured (use --update to update them).")
+			return nil
+		}
+		if err := saveEditedConfig(cfg, true); err != nil {
+			return err
+		}
+
+		fmt.Printf("Imported %d cluster(s), updated %d, skipped %d\n", len(added), len(replaced), len(skipped))
+		return nil
+	}
+}
+
+// readImportFile reads an import file, or standard input for "-".
+func readImportFile(file string) ([]byte, error) {
+	if file == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return data, nil
+}
+
+// printImportPlan lists the clusters to import and what will happen to
+// each.
+func printImportPlan(cfg *config.Config, clusters []*config.Cluster) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREGION\tENDPOINT\tOCID\tACTION")
+	seen := make(map[string]bool)
+	for _, c := range clusters {
+		action := "add"
+		if seen[strings.ToLower(c.ClusterName)] {
+			action = "skip (duplicate)"
+		} else if config.FindClusterByName(cfg, c.ClusterName) != nil {
+			action = "skip (exists)"
+			if importUpdate {
+				action = "update"
+			}
+		}
+		seen[strings.ToLower(c.ClusterName)] = true
+
+		endpoint := "-"
+		if len(c.Endpoints) > 0 {
+			endpoint = fmt.Sprintf("%s:%d", c.Endpoints[0].Host(), c.Endpoints[0].Port)
+		} else if c.LocalPort != nil {
+			endpoint = fmt.Sprintf("localhost:%d", *c.LocalPort)
+		}
+		ocid := "-"
+		if c.Ocid != nil {
+			ocid = *c.Ocid
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.ClusterName, orDash(c.Region), endpoint, ocid, action)
+	}
+	w.Flush()
+}
@@ -2,12 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 
 	"github.com/rs/zerolog/log"
 	"github.com/scotttball/tunatap/internal/cluster"
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
 	"github.com/scotttball/tunatap/internal/kubeconfig"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +20,7 @@ var (
 	kubeconfigNoOCIAuth   bool
 	kubeconfigOCIProfile  string
 	kubeconfigMerge       bool
+	kubeconfigRemove      bool
 )
 
 var kubeconfigCmd = &cobra.Command{
@@ -27,8 +29,9 @@ var kubeconfigCmd = &cobra.Command{
 	Long: `Generate a kubeconfig file for accessing a cluster through tunatap.
 
 The generated kubeconfig uses OCI exec-auth by default, allowing kubectl to
-automatically obtain short-lived tokens via the OCI CLI. This is similar to
-the kubeconfig generated by 'oci ce cluster create-kubeconfig'.
+automatically obtain short-lived tokens from 'tunatap token', which signs
+them with the OCI SDK, so the OCI CLI is not needed. This is similar to the
+kubeconfig generated by 'oci ce cluster create-kubeconfig'.
 
 Examples:
   # Generate kubeconfig to stdout
@@ -40,8 +43,21 @@ Examples:
   # Merge into default kubeconfig
   tunatap kubeconfig my-cluster --merge
 
-  # Generate without OCI auth (insecure mode)
-  tunatap kubeconfig my-cluster --no-oci-auth`,
+  # Remove the cluster's context again, or that of every configured cluster
+  tunatap kubeconfig my-cluster --remove
+  tunatap kubeconfig --remove
+
+  # Generate without OCI exec-auth
+  tunatap kubeconfig my-cluster --no-oci-auth
+
+--merge writes to the kubeconfig kubectl reads: the file of $KUBECONFIG that
+already holds the context, or else its first file, or ~/.kube/config. The
+cluster, context and user of the same name are replaced, so merging again
+only updates them, and everything else in the file is kept.
+
+The context name, default namespace, OCI profile of exec-auth, whether the
+cluster's CA certificate is embedded and whether to merge by default are set
+per cluster under kubeconfig in the config.`,
 	RunE: runKubeconfig,
 	Args: cobra.MaximumNArgs(1),
 }
@@ -52,9 +68,10 @@ func init() {
 	kubeconfigCmd.Flags().StringVarP(&kubeconfigClusterName, "cluster", "c", "", "cluster name")
 	kubeconfigCmd.Flags().StringVarP(&kubeconfigOutputPath, "output", "o", "", "output file path (default: stdout)")
 	kubeconfigCmd.Flags().IntVarP(&kubeconfigPort, "port", "p", 6443, "local port for tunnel endpoint")
-	kubeconfigCmd.Flags().BoolVar(&kubeconfigNoOCIAuth, "no-oci-auth", false, "disable OCI exec-auth (use insecure mode)")
+	kubeconfigCmd.Flags().BoolVar(&kubeconfigNoOCIAuth, "no-oci-auth", false, "disable OCI exec-auth")
 	kubeconfigCmd.Flags().StringVar(&kubeconfigOCIProfile, "oci-profile", "", "OCI config profile for exec-auth")
-	kubeconfigCmd.Flags().BoolVar(&kubeconfigMerge, "merge", false, "merge into existing kubeconfig (~/.kube/config)")
+	kubeconfigCmd.Flags().BoolVar(&kubeconfigMerge, "merge", false, "merge into the default kubeconfig ($KUBECONFIG or ~/.kube/config)")
+	kubeconfigCmd.Flags().BoolVar(&kubeconfigRemove, "remove", false, "remove the cluster's context, or every configured cluster's, from the default kubeconfig")
 }
 
 func runKubeconfig(cmd *cobra.Command, args []string) error {
@@ -75,6 +92,13 @@ func runKubeconfig(cmd *cobra.Command, args []string) error {
 		clusterToUse = args[0]
 	}
 
+	if kubeconfigRemove {
+		if kubeconfigMerge || kubeconfigOutputPath != "" {
+			return exitcode.Wrap(exitcode.Usage, fmt.Errorf("--remove cannot be used with --merge or --output"))
+		}
+		return removeKubeconfigContexts(cfg, clusterToUse)
+	}
+
 	// Select cluster
 	selectedCluster, err := selectCluster(cfg, clusterToUse)
 	if err != nil {
@@ -82,7 +106,7 @@ func runKubeconfig(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create OCI client for cluster validation
-	ociClient, err := createOCIClient(cfg, selectedCluster.Region)
+	ociClient, err := createOCIClient(cfg, selectedCluster)
 	if err != nil {
 		return fmt.Errorf("failed to create OCI client: %w", err)
 	}
@@ -92,37 +116,31 @@ func runKubeconfig(cmd *cobra.Command, args []string) error {
 		log.Warn().Err(err).Msg("Cluster validation failed, some features may not work")
 	}
 
+	// kubectl only speaks TCP, so a socket-mode tunnel cannot back a kubeconfig
+	if selectedCluster.LocalSocket != nil && *selectedCluster.LocalSocket != "" {
+		return fmt.Errorf("cluster '%s' listens on unix socket %s; kubectl cannot connect to unix sockets, remove local_socket or use 'tunatap exec'",
+			selectedCluster.ClusterName, *selectedCluster.LocalSocket)
+	}
+
 	// Determine port
 	port := kubeconfigPort
 	if selectedCluster.LocalPort != nil && *selectedCluster.LocalPort > 0 {
 		port = *selectedCluster.LocalPort
 	}
 
-	// Determine OCI profile
-	profile := kubeconfigOCIProfile
-	if profile == "" {
-		profile = cfg.OCIProfile
-	}
-
 	// Generate kubeconfig
-	var kubecfg *kubeconfig.Kubeconfig
-	if selectedCluster.Ocid != nil && *selectedCluster.Ocid != "" && !kubeconfigNoOCIAuth {
-		log.Info().Msg("Generating kubeconfig with OCI exec-auth")
-		kubecfg = kubeconfig.NewOCIKubeconfigForTunnel(
-			selectedCluster.ClusterName,
-			*selectedCluster.Ocid,
-			selectedCluster.Region,
-			port,
-			profile,
-		)
-	} else {
-		log.Info().Msg("Generating kubeconfig without OCI auth (insecure mode)")
-		kubecfg = kubeconfig.NewInsecureKubeconfig(selectedCluster.ClusterName, port)
+	kubecfg, err := buildKubeconfig(cmd.Context(), cfg, selectedCluster, port, kubeconfigNoOCIAuth, kubeconfigOCIProfile)
+	if err != nil {
+		return err
 	}
 
 	// Handle output
-	if kubeconfigMerge {
-		return mergeKubeconfig(kubecfg)
+	merge := kubeconfigMerge
+	if !cmd.Flags().Changed("merge") && kubeconfigOutputPath == "" && selectedCluster.Kubeconfig != nil {
+		merge = selectedCluster.Kubeconfig.Merge
+	}
+	if merge {
+		return mergeKubeconfig(kubecfg, os.Stdout)
 	}
 
 	if kubeconfigOutputPath != "" {
@@ -144,39 +162,59 @@ func runKubeconfig(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// mergeKubeconfig merges the generated kubeconfig into ~/.kube/config
-func mergeKubeconfig(newKubecfg *kubeconfig.Kubeconfig) error {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+// mergeKubeconfig merges the generated kubeconfig into the default
+// kubeconfig and reports the new context on out.
+func mergeKubeconfig(newKubecfg *kubeconfig.Kubeconfig, out io.Writer) error {
+	kubeconfigPath := kubeconfig.MergePath(kubeconfig.DefaultPaths(), newKubecfg.CurrentContext)
+	if kubeconfigPath == "" {
+		return fmt.Errorf("failed to find the default kubeconfig: no home directory")
 	}
 
-	kubeconfigPath := filepath.Join(home, ".kube", "config")
-
-	// Load existing kubeconfig if it exists
-	var existing *kubeconfig.Kubeconfig
-	if _, err := os.Stat(kubeconfigPath); err == nil {
-		existing, err = kubeconfig.LoadFromFile(kubeconfigPath)
-		if err != nil {
-			return fmt.Errorf("failed to load existing kubeconfig: %w", err)
-		}
-	} else {
-		existing = kubeconfig.NewKubeconfig()
+	if err := kubeconfig.MergeFile(kubeconfigPath, newKubecfg); err != nil {
+		return err
 	}
 
-	// Merge
-	merged := kubeconfig.MergeKubeconfigs(existing, newKubecfg)
+	log.Info().Msgf("Merged kubeconfig into %s", kubeconfigPath)
+	fmt.Fprintf(out, "Context '%s' merged into %s\n", newKubecfg.CurrentContext, kubeconfigPath)
+	fmt.Fprintf(out, "Current context set to: %s\n", newKubecfg.CurrentContext)
+	return nil
+}
 
-	// Set current context to the new cluster
-	merged.SetCurrentContext(newKubecfg.CurrentContext)
+// removeKubeconfigContexts removes the context of the named cluster, or of
+// every configured cluster if name is empty, with its cluster and user,
+// from each file of the default kubeconfig.
+func removeKubeconfigContexts(cfg *config.Config, name string) error {
+	clusters := cfg.Clusters
+	if name != "" {
+		c := config.FindClusterByName(cfg, name)
+		if c == nil {
+			return exitcode.Wrap(exitcode.NotFound, fmt.Errorf("cluster '%s' not found in config", name))
+		}
+		clusters = []*config.Cluster{c}
+	}
 
-	// Write back
-	if err := merged.WriteToFile(kubeconfigPath); err != nil {
-		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	var names []string
+	for _, c := range clusters {
+		template := ""
+		if c.Kubeconfig != nil {
+			template = c.Kubeconfig.ContextName
+		}
+		names = append(names, kubeconfig.ContextName(template, c.ClusterName, c.Region))
 	}
 
-	log.Info().Msgf("Merged kubeconfig into %s", kubeconfigPath)
-	fmt.Printf("Context '%s' added to %s\n", newKubecfg.CurrentContext, kubeconfigPath)
-	fmt.Printf("Current context set to: %s\n", newKubecfg.CurrentContext)
+	removed := false
+	for _, path := range kubeconfig.DefaultPaths() {
+		ok, err := kubeconfig.RemoveFile(path, names)
+		if err != nil {
+			return err
+		}
+		if ok {
+			removed = true
+			fmt.Printf("Removed tunatap contexts from %s\n", path)
+		}
+	}
+	if !removed {
+		fmt.Println("No tunatap contexts to remove")
+	}
 	return nil
 }
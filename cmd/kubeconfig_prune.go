@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/scotttball/tunatap/internal/kubeconfig"
+	"github.com/scotttball/tunatap/internal/ports"
+	"github.com/spf13/cobra"
+)
+
+var kubeconfigPruneDryRun bool
+
+var kubeconfigPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove tunatap contexts whose tunnel is not running",
+	Long: `Remove the contexts tunatap wrote to the default kubeconfig ($KUBECONFIG or
+~/.kube/config) whose local port no running tunnel serves, along with their
+clusters and users.
+
+A tunatap context is one whose API server is on localhost and which is
+named tuna-..., or whose user gets tokens from 'tunatap token'. Its tunnel
+is running when a tunatap process, in the foreground or in the daemon,
+holds its port in the port registry. Contexts of clusters that are simply
+not connected right now are removed too; 'tunatap kubeconfig --merge' adds
+them back.
+
+Examples:
+  tunatap kubeconfig prune --dry-run
+  tunatap kubeconfig prune`,
+	Args: cobra.NoArgs,
+	RunE: runKubeconfigPrune,
+}
+
+func init() {
+	kubeconfigCmd.AddCommand(kubeconfigPruneCmd)
+	kubeconfigPruneCmd.Flags().BoolVar(&kubeconfigPruneDryRun, "dry-run", false, "list the stale contexts without removing them")
+}
+
+func runKubeconfigPrune(cmd *cobra.Command, args []string) error {
+	registry := ports.Default()
+	live := make(map[int]bool)
+	stale := 0
+
+	for _, path := range kubeconfig.DefaultPaths() {
+		k, err := kubeconfig.LoadFromFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		var contexts []string
+		for _, tc := range k.TunnelContexts() {
+			held, ok := live[tc.Port]
+			if !ok {
+				entry, err := registry.Lookup(tc.Port)
+				if err != nil {
+					return err
+				}
+				held = entry != nil
+				live[tc.Port] = held
+			}
+			if held {
+				continue
+			}
+			contexts = append(contexts, tc.Context)
+			fmt.Printf("%s: %s (port %d has no tunnel)\n", path, tc.Context, tc.Port)
+		}
+		stale += len(contexts)
+		if len(contexts) == 0 || kubeconfigPruneDryRun {
+			continue
+		}
+
+		if _, err := kubeconfig.RemoveFile(path, k.EntryNames(contexts)); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case stale == 0:
+		fmt.Println("No stale tunatap contexts")
+	case kubeconfigPruneDryRun:
+		fmt.Printf("\nStale contexts: %d; run without --dry-run to remove them\n", stale)
+	default:
+		fmt.Printf("\nRemoved stale contexts: %d\n", stale)
+	}
+	return nil
+}
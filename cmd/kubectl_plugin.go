@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// kubectlPluginName is the name kubectl looks for on the PATH to run
+// 'kubectl tuna'.
+const kubectlPluginName = "kubectl-tuna"
+
+var (
+	kubectlPluginDir   string
+	kubectlPluginCopy  bool
+	kubectlPluginForce bool
+)
+
+var installKubectlPluginCmd = &cobra.Command{
+	Use:   "install-kubectl-plugin",
+	Short: "Install tunatap as the kubectl plugin 'kubectl tuna'",
+	Long: `Install tunatap as a kubectl plugin, so that every tunatap command can be
+run as 'kubectl tuna', e.g. 'kubectl tuna connect prod'.
+
+The plugin is a symlink named kubectl-tuna to this binary, so it is upgraded
+with tunatap, in the directory of this binary unless --dir is given. The
+directory must be on the PATH for kubectl to find the plugin. On Windows, or
+with --copy, the binary is copied instead, and must be installed again after
+upgrading tunatap.
+
+Examples:
+  tunatap install-kubectl-plugin
+  tunatap install-kubectl-plugin --dir ~/.local/bin
+  tunatap install-kubectl-plugin --copy --force
+  kubectl tuna connect prod`,
+	Args: cobra.NoArgs,
+	RunE: runInstallKubectlPlugin,
+}
+
+func init() {
+	rootCmd.AddCommand(installKubectlPluginCmd)
+	installKubectlPluginCmd.Flags().StringVar(&kubectlPluginDir, "dir", "", "directory to install kubectl-tuna in (default: the directory of tunatap)")
+	installKubectlPluginCmd.Flags().BoolVar(&kubectlPluginCopy, "copy", false, "copy the binary instead of symlinking it")
+	installKubectlPluginCmd.Flags().BoolVar(&kubectlPluginForce, "force", false, "replace an existing kubectl-tuna")
+}
+
+// setKubectlPluginMode makes usage and help show 'kubectl tuna' when tunatap
+// is run by kubectl as its plugin, which it is when run as kubectl-tuna.
+func setKubectlPluginMode(arg0 string) {
+	if !isKubectlPlugin(arg0) {
+		return
+	}
+	if rootCmd.Annotations == nil {
+		rootCmd.Annotations = make(map[string]string)
+	}
+	rootCmd.Annotations[cobra.CommandDisplayNameAnnotation] = "kubectl tuna"
+}
+
+// isKubectlPlugin reports whether arg0, the name tunatap was run as, is
+// that of the kubectl plugin.
+func isKubectlPlugin(arg0 string) bool {
+	name := strings.TrimSuffix(filepath.Base(arg0), ".exe")
+	return name == kubectlPluginName
+}
+
+// kubectlPluginFile returns the file name of the plugin on goos.
+func kubectlPluginFile(goos string) string {
+	if goos == "windows" {
+		return kubectlPluginName + ".exe"
+	}
+	return kubectlPluginName
+}
+
+func runInstallKubectlPlugin(cmd *cobra.Command, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate tunatap executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	dir := kubectlPluginDir
+	if dir == "" {
+		dir = filepath.Dir(executable)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve plugin directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	// Symlinks need extra privileges on Windows
+	copyBinary := kubectlPluginCopy || runtime.GOOS == "windows"
+	target := filepath.Join(dir, kubectlPluginFile(runtime.GOOS))
+	installed, err := installKubectlPlugin(executable, target, copyBinary, kubectlPluginForce)
+	if err != nil {
+		return err
+	}
+
+	if installed {
+		fmt.Printf("Installed kubectl plugin: %s\n", target)
+	} else {
+		fmt.Printf("kubectl plugin already installed: %s\n", target)
+	}
+	if found, err := exec.LookPath(kubectlPluginName); err != nil || !sameFile(found, target) {
+		fmt.Printf("Warning: %s is not the first %s on the PATH; add %s to the PATH for kubectl to find it\n", target, kubectlPluginName, dir)
+	}
+	fmt.Println("Run 'kubectl tuna --help' to get started")
+	return nil
+}
+
+// installKubectlPlugin installs executable as the plugin target, as a
+// symlink or a copy. It reports false if target already is a symlink to
+// executable. Any other file at target is only replaced with force.
+func installKubectlPlugin(executable, target string, copyBinary, force bool) (bool, error) {
+	if info, err := os.Lstat(target); err == nil {
+		if !copyBinary && info.Mode()&os.ModeSymlink != 0 {
+			if link, err := os.Readlink(target); err == nil && link == executable {
+				return false, nil
+			}
+		}
+		if !force {
+			return false, fmt.Errorf("%s already exists; use --force to replace it", target)
+		}
+	}
+
+	// Install beside the target then rename over it, so that a failure
+	// leaves any existing plugin in place
+	tmp := target + ".tmp"
+	os.Remove(tmp)
+	if copyBinary {
+		if err := copyExecutable(executable, tmp); err != nil {
+			os.Remove(tmp)
+			return false, fmt.Errorf("failed to copy tunatap to %s: %w", target, err)
+		}
+	} else if err := os.Symlink(executable, tmp); err != nil {
+		return false, fmt.Errorf("failed to link %s to tunatap: %w", target, err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return false, fmt.Errorf("failed to install %s: %w", target, err)
+	}
+	return true, nil
+}
+
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// sameFile reports whether paths a and b are the same file, following
+// symlinks.
+func sameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
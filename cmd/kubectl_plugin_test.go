@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestIsKubectlPlugin(t *testing.T) {
+	tests := []struct {
+		arg0 string
+		want bool
+	}{
+		{"kubectl-tuna", true},
+		{"/usr/local/bin/kubectl-tuna", true},
+		{`C:\tools\kubectl-tuna.exe`, runtime.GOOS == "windows"},
+		{"kubectl-tuna.exe", true},
+		{"tunatap", false},
+		{"/usr/local/bin/kubectl-tunatap", false},
+	}
+	for _, tt := range tests {
+		if got := isKubectlPlugin(tt.arg0); got != tt.want {
+			t.Errorf("isKubectlPlugin(%q) = %v, want %v", tt.arg0, got, tt.want)
+		}
+	}
+
+	defer delete(rootCmd.Annotations, cobra.CommandDisplayNameAnnotation)
+	setKubectlPluginMode("/usr/local/bin/kubectl-tuna")
+	if got := statusCmd.CommandPath(); got != "kubectl tuna status" {
+		t.Errorf("CommandPath() as a kubectl plugin = %q, want %q", got, "kubectl tuna status")
+	}
+}
+
+func TestInstallKubectlPlugin(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "tunatap")
+	if err := os.WriteFile(executable, []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "bin", kubectlPluginName)
+	os.Mkdir(filepath.Dir(target), 0o755)
+
+	if runtime.GOOS != "windows" {
+		if installed, err := installKubectlPlugin(executable, target, false, false); err != nil || !installed {
+			t.Fatalf("installKubectlPlugin() = %v, %v", installed, err)
+		}
+		if link, err := os.Readlink(target); err != nil || link != executable {
+			t.Errorf("plugin links to %q, %v, want %q", link, err, executable)
+		}
+		if installed, err := installKubectlPlugin(executable, target, false, false); err != nil || installed {
+			t.Errorf("installing the same link again = %v, %v, want nothing to do", installed, err)
+		}
+	} else {
+		os.WriteFile(target, []byte("other"), 0o755)
+	}
+
+	if _, err := installKubectlPlugin(executable, target, true, false); err == nil {
+		t.Error("installKubectlPlugin() should not replace an existing plugin without force")
+	}
+	if installed, err := installKubectlPlugin(executable, target, true, true); err != nil || !installed {
+		t.Fatalf("installKubectlPlugin() with force = %v, %v", installed, err)
+	}
+	info, err := os.Lstat(target)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("plugin should be a copy, got %v, %v", info, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "binary" {
+		t.Errorf("copied plugin = %q", data)
+	}
+	if _, err := os.Stat(target + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file should not be left behind")
+	}
+}
@@ -3,26 +3,55 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/catalog"
 	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/output"
 	"github.com/scotttball/tunatap/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List resources",
-	Long:  `List configured clusters, bastions, and other resources.`,
+	Short: "List clusters and other resources",
+	Long: `List clusters, bastions, and other resources. On its own, list lists
+clusters, like 'list clusters'.`,
+	Args: cobra.NoArgs,
+	RunE: runListClusters,
 }
 
 var listClustersCmd = &cobra.Command{
 	Use:   "clusters",
-	Short: "List configured clusters",
-	RunE:  runListClusters,
+	Short: "List known clusters",
+	Long: `List the clusters of the config file, the cached shared catalogs and the
+discovery cache, with their region, compartment, endpoint and bastion, and
+whether a tunnel to them is up. With --live, every accessible compartment is
+also swept for clusters, as 'discover --all' does, refreshing the discovery
+cache.
+
+A cluster known from several places is listed once: the config file wins over
+catalogs, catalogs over the live sweep, and the sweep over the cache.
+
+Examples:
+  tunatap list
+  tunatap list clusters -o wide
+  tunatap list clusters --live --region us-ashburn-1
+  tunatap list clusters --group payments -o json`,
+	Args: cobra.NoArgs,
+	RunE: runListClusters,
 }
 
 var listBastionsCmd = &cobra.Command{
@@ -40,61 +69,383 @@ var listTenanciesCmd = &cobra.Command{
 var (
 	compartmentOcid string
 	region          string
+	listOutput      string
+	listGroup       string
+	listLive        bool
+	listRegion      string
+)
+
+// Where a listed cluster is known from, besides "catalog:<name>".
+const (
+	listSourceConfig = "config"
+	listSourceLive   = "oci"
+	listSourceCache  = "cache"
 )
 
+// clusterListItem is a cluster in the structured output of 'list clusters'.
+type clusterListItem struct {
+	Name        string         `json:"name" yaml:"name"`
+	Aliases     []string       `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Group       string         `json:"group,omitempty" yaml:"group,omitempty"`
+	Region      string         `json:"region" yaml:"region"`
+	Compartment string         `json:"compartment,omitempty" yaml:"compartment,omitempty"`
+	Endpoints   int            `json:"endpoints" yaml:"endpoints"`
+	Endpoint    string         `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Bastion     string         `json:"bastion,omitempty" yaml:"bastion,omitempty"`
+	BastionID   string         `json:"bastion_id,omitempty" yaml:"bastion_id,omitempty"`
+	OCID        string         `json:"ocid,omitempty" yaml:"ocid,omitempty"`
+	Source      string         `json:"source" yaml:"source"`
+	Tunnel      *clusterTunnel `json:"tunnel,omitempty" yaml:"tunnel,omitempty"`
+}
+
+// clusterTunnel is the tunnel that is up to a listed cluster.
+type clusterTunnel struct {
+	LocalPort int    `json:"local_port" yaml:"local_port"`
+	Mode      string `json:"mode" yaml:"mode"`
+	State     string `json:"state,omitempty" yaml:"state,omitempty"`
+	Uptime    string `json:"uptime" yaml:"uptime"`
+}
+
+// listedCluster is a cluster 'list clusters' shows, with where it is known
+// from.
+type listedCluster struct {
+	*config.Cluster
+	source string
+}
+
+// bastionListItem is a bastion in the structured output of 'list bastions'.
+type bastionListItem struct {
+	Name  string `json:"name" yaml:"name"`
+	State string `json:"state" yaml:"state"`
+	Type  string `json:"type,omitempty" yaml:"type,omitempty"`
+	ID    string `json:"id" yaml:"id"`
+}
+
+// tenancyListItem is a tenancy in the structured output of 'list tenancies'.
+type tenancyListItem struct {
+	Name string `json:"name" yaml:"name"`
+	OCID string `json:"ocid,omitempty" yaml:"ocid,omitempty"`
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.AddCommand(listClustersCmd)
 	listCmd.AddCommand(listBastionsCmd)
 	listCmd.AddCommand(listTenanciesCmd)
 
+	for _, c := range []*cobra.Command{listCmd, listClustersCmd} {
+		c.Flags().StringVar(&listGroup, "group", "", "only list the clusters in this group")
+		c.Flags().BoolVar(&listLive, "live", false, "also sweep OCI for clusters, refreshing the discovery cache")
+		c.Flags().StringVarP(&listRegion, "region", "r", "", "only sweep this region with --live")
+		addWideOutputFlag(c, &listOutput)
+	}
 	listBastionsCmd.Flags().StringVarP(&compartmentOcid, "compartment", "c", "", "compartment OCID")
 	listBastionsCmd.Flags().StringVarP(&region, "region", "r", "", "OCI region")
+
+	for _, c := range []*cobra.Command{listBastionsCmd, listTenanciesCmd} {
+		addOutputFlag(c, &listOutput)
+	}
 }
 
 func runListClusters(cmd *cobra.Command, args []string) error {
+	format, err := wideOutputFormat(listOutput)
+	if err != nil {
+		return err
+	}
+	if listRegion != "" && !listLive {
+		return fmt.Errorf("--region only applies with --live")
+	}
+
 	cfg, err := config.ReadConfig(GetConfigFile())
 	if err != nil {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
 
-	if len(cfg.Clusters) == 0 {
-		fmt.Println("No clusters configured.")
-		fmt.Println("Run 'tunatap setup' to add clusters.")
+	// Paths of discovered clusters' compartments are only shown if cached
+	cache, err := loadCache()
+	if err != nil {
+		log.Debug().Err(err).Msg("Not listing cached clusters")
+	}
+
+	var catalogs []*catalog.SharedCatalog
+	if len(cfg.CatalogSources) > 0 {
+		manager := catalog.NewCatalogManager(cfg.CatalogSources, getCatalogCacheDir())
+		manager.SetSecretResolver(secretResolver(cfg))
+		catalogs = manager.LoadCached()
+	}
+
+	var live []*config.Cluster
+	if listLive {
+		if live, err = sweepClusters(cmd.Context(), cfg, cache); err != nil {
+			return err
+		}
+	}
+
+	clusters := mergeListedClusters(cfg, catalogs, live, cache)
+	if listGroup != "" {
+		clusters = slices.DeleteFunc(clusters, func(c listedCluster) bool {
+			return c.Group == "" || !strings.EqualFold(c.Group, listGroup)
+		})
+	}
+
+	if len(clusters) == 0 && !format.Structured() {
+		if listGroup != "" {
+			fmt.Printf("No clusters in group '%s'.\n", listGroup)
+			return nil
+		}
+		fmt.Println("No clusters configured, cached or in a catalog.")
+		fmt.Println("Run 'tunatap setup' to add clusters, or 'tunatap list --live' to find them in OCI.")
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "NAME\tREGION\tENDPOINTS\tBASION")
+	tunnels, err := activeTunnels()
+	if err != nil {
+		log.Debug().Err(err).Msg("Not showing which clusters have a tunnel up")
+	}
+	items := make([]clusterListItem, 0, len(clusters))
+	for _, c := range clusters {
+		items = append(items, newClusterListItem(c, cache, tunnels))
+	}
+
+	if format.Structured() {
+		return output.Write(os.Stdout, format, items)
+	}
+	printClusterList(items, format == output.Wide)
+	return nil
+}
+
+// sweepClusters finds every cluster discovery can reach, in --region or
+// every subscribed region. The clusters found are cached, which also caches
+// the names of their compartments for their paths to be shown.
+func sweepClusters(ctx context.Context, cfg *config.Config, cache *discovery.Cache) ([]*config.Cluster, error) {
+	hints, err := cluster.NewDiscoveryHints(cfg, listRegion)
+	if err != nil {
+		return nil, err
+	}
+	ociClient, err := cluster.NewDiscoveryClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCI client: %w", err)
+	}
+	clusters, err := discovery.NewDiscoverer(ociClient, cache).DiscoverAll(ctx, hints)
+	if err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	return clusters, nil
+}
+
+// mergeListedClusters returns the clusters of the config, the catalogs, the
+// live sweep and the cache, in that order. A cluster whose name or OCID is
+// already listed is not listed again.
+func mergeListedClusters(cfg *config.Config, catalogs []*catalog.SharedCatalog, live []*config.Cluster, cache *discovery.Cache) []listedCluster {
+	var listed []listedCluster
+	names := make(map[string]bool)
+	ocids := make(map[string]bool)
+	add := func(c *config.Cluster, source string) {
+		name := strings.ToLower(c.ClusterName)
+		if names[name] || (c.Ocid != nil && ocids[*c.Ocid]) {
+			return
+		}
+		names[name] = true
+		if c.Ocid != nil && *c.Ocid != "" {
+			ocids[*c.Ocid] = true
+		}
+		listed = append(listed, listedCluster{Cluster: c, source: source})
+	}
 
 	for _, c := range cfg.Clusters {
-		endpointCount := len(c.Endpoints)
+		add(c, listSourceConfig)
+	}
+	for _, cat := range catalogs {
+		for _, c := range cat.Clusters {
+			add(c, "catalog:"+cat.Name)
+		}
+	}
+	for _, c := range live {
+		add(c, listSourceLive)
+	}
+	if cache != nil {
+		entries := cache.GetAllClusters()
+		for _, name := range sortedKeys(entries) {
+			// Choices made in one region and other kinds of resources are
+			// cached under names of their own
+			if strings.ContainsAny(name, "@:") {
+				continue
+			}
+			add(cachedCluster(name, entries[name], cache), listSourceCache)
+		}
+	}
+	return listed
+}
+
+// cachedCluster returns a cluster in the discovery cache as config.
+func cachedCluster(name string, entry *discovery.CacheEntry, cache *discovery.Cache) *config.Cluster {
+	c := &config.Cluster{ClusterName: name, Region: entry.Region}
+	if entry.OCID != "" {
+		c.Ocid = utils.StringPtr(entry.OCID)
+	}
+	if entry.CompartmentOCID != "" {
+		c.CompartmentOcid = utils.StringPtr(entry.CompartmentOCID)
+	}
+	if entry.EndpointIP != "" {
+		c.Endpoints = []*config.ClusterEndpoint{{Name: "private", Ip: entry.EndpointIP, Port: entry.EndpointPort}}
+	}
+	if b := cache.GetBastion(name); b != nil && b.OCID != "" {
+		c.BastionId = utils.StringPtr(b.OCID)
+	}
+	return c
+}
+
+// newClusterListItem returns the item listing c, with the newest of tunnels
+// to it.
+func newClusterListItem(c listedCluster, cache *discovery.Cache, tunnels []ActiveTunnel) clusterListItem {
+	item := clusterListItem{
+		Name:      c.ClusterName,
+		Aliases:   c.Aliases,
+		Group:     c.Group,
+		Region:    c.Region,
+		Endpoints: len(c.Endpoints),
+		Source:    c.source,
+	}
+	if compartment := clusterCompartment(c.Cluster, cache); compartment != "-" {
+		item.Compartment = compartment
+	}
+	if len(c.Endpoints) > 0 {
+		item.Endpoint = net.JoinHostPort(c.Endpoints[0].Ip, strconv.Itoa(c.Endpoints[0].Port))
+	}
+	if c.Bastion != nil {
+		item.Bastion = *c.Bastion
+	}
+	if c.BastionId != nil {
+		item.BastionID = *c.BastionId
+	}
+	if c.Ocid != nil {
+		item.OCID = *c.Ocid
+	}
+	// Tunnels are newest first
+	for _, t := range tunnels {
+		if c.MatchesName(t.ClusterName) {
+			item.Tunnel = &clusterTunnel{LocalPort: t.LocalPort, Mode: t.Mode, State: t.State, Uptime: t.UptimeStr}
+			break
+		}
+	}
+	return item
+}
+
+// printClusterList prints items as a table, with the columns that do not
+// fit most terminals if wide.
+func printClusterList(items []clusterListItem, wide bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "NAME\tGROUP\tREGION\tCOMPARTMENT\tENDPOINT\tBASTION\tTUNNEL"
+	if wide {
+		header += "\tSOURCE\tOCID"
+	}
+	fmt.Fprintln(w, header)
+
+	for _, item := range items {
 		bastionInfo := "-"
-		if c.Bastion != nil {
-			bastionInfo = *c.Bastion
-		} else if c.BastionId != nil {
+		if item.Bastion != "" {
+			bastionInfo = item.Bastion
+		} else if item.BastionID != "" {
 			// Truncate OCID for display
-			id := *c.BastionId
-			if len(id) > 20 {
-				bastionInfo = id[:20] + "..."
-			} else {
-				bastionInfo = id
+			bastionInfo = item.BastionID
+			if len(bastionInfo) > 20 && !wide {
+				bastionInfo = bastionInfo[:20] + "..."
 			}
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
-			c.ClusterName,
-			c.Region,
-			endpointCount,
+		row := []string{
+			listedName(item),
+			orDash(item.Group),
+			item.Region,
+			orDash(item.Compartment),
+			endpointInfo(item),
 			bastionInfo,
-		)
+			tunnelInfo(item.Tunnel),
+		}
+		if wide {
+			row = append(row, item.Source, orDash(item.OCID))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
 	}
 
 	w.Flush()
-	return nil
+}
+
+// listedName returns the name of a listed cluster followed by its aliases,
+// such as "payments-prod (pp, p1)".
+func listedName(item clusterListItem) string {
+	if len(item.Aliases) == 0 {
+		return item.Name
+	}
+	return fmt.Sprintf("%s (%s)", item.Name, strings.Join(item.Aliases, ", "))
+}
+
+// endpointInfo returns the ENDPOINT column of a listed cluster: its first
+// endpoint, and how many more it has.
+func endpointInfo(item clusterListItem) string {
+	switch {
+	case item.Endpoint == "":
+		return "-"
+	case item.Endpoints > 1:
+		return fmt.Sprintf("%s (+%d)", item.Endpoint, item.Endpoints-1)
+	default:
+		return item.Endpoint
+	}
+}
+
+// tunnelInfo returns the TUNNEL column of a listed cluster, such as
+// "up :6443".
+func tunnelInfo(t *clusterTunnel) string {
+	if t == nil {
+		return "-"
+	}
+	if t.State != "" && t.State != daemon.StateReady {
+		return fmt.Sprintf("%s :%d", t.State, t.LocalPort)
+	}
+	return fmt.Sprintf("up :%d", t.LocalPort)
+}
+
+// clusterCompartment returns the path of a configured cluster's
+// compartment, from its config or else the discovery cache, falling back to
+// its OCID.
+func clusterCompartment(c *config.Cluster, cache *discovery.Cache) string {
+	if c.Compartment != nil && *c.Compartment != "" {
+		if !utils.IsCompartmentOCID(*c.Compartment) {
+			return path.Join("root", *c.Compartment)
+		}
+		if cache != nil {
+			if p := cache.CompartmentPath(*c.Compartment); p != "" {
+				return p
+			}
+		}
+		return *c.Compartment
+	}
+
+	var ocid string
+	if c.CompartmentOcid != nil {
+		ocid = *c.CompartmentOcid
+	}
+	if cache != nil {
+		if entry := cache.GetCluster(c.ClusterName); entry != nil && entry.CompartmentPath != "" &&
+			(ocid == "" || entry.CompartmentOCID == ocid) {
+			return entry.CompartmentPath
+		}
+		if p := cache.CompartmentPath(ocid); p != "" {
+			return p
+		}
+	}
+	if ocid == "" {
+		return "-"
+	}
+	return ocid
 }
 
 func runListBastions(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(listOutput, false)
+	if err != nil {
+		return err
+	}
+
 	if compartmentOcid == "" {
 		return fmt.Errorf("--compartment flag is required")
 	}
@@ -109,7 +460,7 @@ func runListBastions(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create OCI client: %w", err)
 	}
 
-	ociClient.SetRegion(region)
+	ociClient = ociClient.WithRegion(region)
 
 	log.Info().Msgf("Listing bastions in compartment %s...", compartmentOcid)
 
@@ -119,6 +470,22 @@ func runListBastions(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to list bastions: %w", err)
 	}
 
+	if format.Structured() {
+		items := make([]bastionListItem, 0, len(bastions))
+		for _, b := range bastions {
+			item := bastionListItem{
+				Name:  *b.Name,
+				State: string(b.LifecycleState),
+				ID:    *b.Id,
+			}
+			if b.BastionType != nil {
+				item.Type = *b.BastionType
+			}
+			items = append(items, item)
+		}
+		return output.Write(os.Stdout, format, items)
+	}
+
 	if len(bastions) == 0 {
 		fmt.Println("No bastions found in the specified compartment.")
 		return nil
@@ -146,12 +513,31 @@ func runListBastions(cmd *cobra.Command, args []string) error {
 }
 
 func runListTenancies(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(listOutput, false)
+	if err != nil {
+		return err
+	}
+
 	cfg, err := config.ReadConfig(GetConfigFile())
 	if err != nil {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
 
-	if len(cfg.Tenancies) == 0 {
+	tenancies := cfg.AllTenancies()
+	if format.Structured() {
+		items := make([]tenancyListItem, 0, len(tenancies))
+		for name, ocid := range tenancies {
+			item := tenancyListItem{Name: name}
+			if ocid != nil {
+				item.OCID = *ocid
+			}
+			items = append(items, item)
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+		return output.Write(os.Stdout, format, items)
+	}
+
+	if len(tenancies) == 0 {
 		fmt.Println("No tenancies configured.")
 		fmt.Println("Run 'tunatap setup add-tenancy <name> <ocid>' to add tenancies.")
 		return nil
@@ -160,7 +546,7 @@ func runListTenancies(cmd *cobra.Command, args []string) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "NAME\tOCID")
 
-	for name, ocid := range cfg.Tenancies {
+	for name, ocid := range tenancies {
 		ocidStr := "-"
 		if ocid != nil {
 			ocidStr = *ocid
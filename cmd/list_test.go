@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/catalog"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestMergeListedClusters(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Clusters = []*config.Cluster{{ClusterName: "prod", Region: "us-ashburn-1"}}
+	catalogs := []*catalog.SharedCatalog{{Name: "team", Clusters: []*config.Cluster{
+		{ClusterName: "Prod", Region: "eu-frankfurt-1"},
+		{ClusterName: "staging", Region: "us-phoenix-1", Ocid: utils.StringPtr("ocid1.cluster.oc1.phx.s")},
+	}}}
+	live := []*config.Cluster{
+		{ClusterName: "staging-renamed", Ocid: utils.StringPtr("ocid1.cluster.oc1.phx.s")},
+		{ClusterName: "dev", Region: "us-ashburn-1", Ocid: utils.StringPtr("ocid1.cluster.oc1.iad.d")},
+	}
+
+	cache, err := discovery.NewCache(t.TempDir(), discovery.DefaultCacheTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, ocid := range map[string]string{
+		"dev":        "ocid1.cluster.oc1.iad.d",
+		"sandbox":    "ocid1.cluster.oc1.iad.x",
+		"sandbox@us": "ocid1.cluster.oc1.iad.x",
+		"adb:ledger": "ocid1.autonomousdatabase.oc1.iad.l",
+	} {
+		if err := cache.SetCluster(name, &discovery.CacheEntry{OCID: ocid, Region: "us-ashburn-1", EndpointIP: "10.0.0.9", EndpointPort: 6443}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.SetBastion("sandbox", &discovery.CacheEntry{OCID: "ocid1.bastion.oc1.iad.b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	listed := mergeListedClusters(cfg, catalogs, live, cache)
+	want := []struct{ name, source string }{
+		{"prod", "config"},
+		{"staging", "catalog:team"},
+		{"dev", "oci"},
+		{"sandbox", "cache"},
+	}
+	if len(listed) != len(want) {
+		t.Fatalf("mergeListedClusters() listed %d clusters, want %d", len(listed), len(want))
+	}
+	for i, w := range want {
+		if listed[i].ClusterName != w.name || listed[i].source != w.source {
+			t.Errorf("cluster %d = %s from %s, want %s from %s", i, listed[i].ClusterName, listed[i].source, w.name, w.source)
+		}
+	}
+
+	sandbox := listed[3]
+	if sandbox.BastionId == nil || *sandbox.BastionId != "ocid1.bastion.oc1.iad.b" || len(sandbox.Endpoints) != 1 {
+		t.Errorf("cached cluster = %+v, want its endpoint and bastion", sandbox.Cluster)
+	}
+}
+
+func TestNewClusterListItem(t *testing.T) {
+	c := listedCluster{
+		Cluster: &config.Cluster{
+			ClusterName: "prod",
+			Aliases:     []string{"p"},
+			Region:      "us-ashburn-1",
+			Compartment: utils.StringPtr("platform/oke"),
+			Endpoints: []*config.ClusterEndpoint{
+				{Name: "private", Ip: "10.0.0.5", Port: 6443},
+				{Name: "public", Ip: "203.0.113.5", Port: 6443},
+			},
+		},
+		source: listSourceConfig,
+	}
+	tunnels := []ActiveTunnel{
+		{ClusterName: "other", LocalPort: 7443, Mode: modeForeground},
+		{ClusterName: "p", LocalPort: 6443, Mode: modeDaemon, State: daemon.StateReady, UptimeStr: "5m0s"},
+		{ClusterName: "prod", LocalPort: 8443, Mode: modeForeground},
+	}
+
+	item := newClusterListItem(c, nil, tunnels)
+	if item.Compartment != "root/platform/oke" || item.Endpoint != "10.0.0.5:6443" || item.Source != "config" {
+		t.Errorf("newClusterListItem() = %+v", item)
+	}
+	if got := endpointInfo(item); got != "10.0.0.5:6443 (+1)" {
+		t.Errorf("endpointInfo() = %q", got)
+	}
+	if item.Tunnel == nil || item.Tunnel.LocalPort != 6443 || item.Tunnel.Mode != modeDaemon {
+		t.Fatalf("Tunnel = %+v, want the newest tunnel to the cluster or its aliases", item.Tunnel)
+	}
+	if got := tunnelInfo(item.Tunnel); got != "up :6443" {
+		t.Errorf("tunnelInfo() = %q", got)
+	}
+	if got := tunnelInfo(&clusterTunnel{LocalPort: 6443, State: "reconnecting"}); got != "reconnecting :6443" {
+		t.Errorf("tunnelInfo(reconnecting) = %q", got)
+	}
+
+	if item := newClusterListItem(c, nil, nil); item.Tunnel != nil || tunnelInfo(item.Tunnel) != "-" {
+		t.Errorf("a cluster without tunnels should show none, got %+v", item.Tunnel)
+	}
+}
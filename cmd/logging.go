@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/logfile"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// The console shows the log from its own level, info unless --debug is
+// given, while the log file, if any, gets everything from the level it is
+// configured with.
+var (
+	consoleLog = &zerolog.FilteredLevelWriter{
+		Writer: zerolog.LevelWriterAdapter{Writer: zerolog.ConsoleWriter{Out: os.Stderr}},
+		Level:  zerolog.InfoLevel,
+	}
+	fileLog *zerolog.FilteredLevelWriter
+)
+
+// setConsoleLog sends the console log to w.
+func setConsoleLog(w io.Writer) {
+	consoleLog.Writer = zerolog.LevelWriterAdapter{Writer: w}
+	applyLogging()
+}
+
+// setConsoleLevel shows level and above on the console. The log file keeps
+// its own level.
+func setConsoleLevel(level zerolog.Level) {
+	consoleLog.Level = level
+	applyLogging()
+}
+
+// logFilePath returns where the log file configured by cfg is written.
+func logFilePath(cfg *config.LogFile) string {
+	if cfg != nil && cfg.Path != "" {
+		return utils.ExpandPath(cfg.Path)
+	}
+	return filepath.Join(homePath, "logs", "tunatap.log")
+}
+
+// openLogFile starts writing the log to the log file set in the config
+// file, unless it is turned off there. A log file that cannot be opened is
+// not worth failing the command for.
+func openLogFile() {
+	cfg := config.ReadLogFileConfig(GetConfigFile())
+	if !cfg.IsEnabled() {
+		return
+	}
+	level, err := zerolog.ParseLevel(cfg.GetLevel())
+	if err != nil {
+		level = zerolog.DebugLevel
+	}
+
+	w, err := logfile.Open(logFilePath(cfg), int64(cfg.GetMaxSizeMB())<<20, cfg.GetMaxBackups())
+	if err != nil {
+		log.Debug().Err(err).Msg("Not writing the log file")
+		return
+	}
+	fileLog = &zerolog.FilteredLevelWriter{Writer: zerolog.LevelWriterAdapter{Writer: w}, Level: level}
+	applyLogging()
+}
+
+// applyLogging points the logger at the console and the log file, and sets
+// the global level to the lower of their levels so that neither misses
+// anything it shows.
+func applyLogging() {
+	level := consoleLog.Level
+	var w zerolog.LevelWriter = consoleLog
+	if fileLog != nil {
+		w = zerolog.MultiLevelWriter(consoleLog, fileLog)
+		level = min(level, fileLog.Level)
+	}
+	zerolog.SetGlobalLevel(level)
+	log.Logger = log.Output(w)
+}
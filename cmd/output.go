@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/scotttball/tunatap/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlag adds the --output flag choosing between the table and
+// structured output of a command.
+func addOutputFlag(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVarP(p, "output", "o", string(output.Table), "output format: table, json or yaml")
+}
+
+// outputFormat parses an --output value. legacyJSON is the older --json
+// flag of some commands, which means --output json.
+func outputFormat(value string, legacyJSON bool) (output.Format, error) {
+	if legacyJSON {
+		return output.JSON, nil
+	}
+	return output.ParseFormat(value)
+}
+
+// addTabularOutputFlag adds the --output flag of a command whose results
+// are rows, which can also be written as CSV.
+func addTabularOutputFlag(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVarP(p, "output", "o", string(output.Table), "output format: table, json, yaml or csv")
+}
+
+// tabularOutputFormat parses an --output value added by
+// addTabularOutputFlag.
+func tabularOutputFormat(value string, legacyJSON bool) (output.Format, error) {
+	if !legacyJSON && strings.EqualFold(value, string(output.CSV)) {
+		return output.CSV, nil
+	}
+	format, err := outputFormat(value, legacyJSON)
+	if err != nil {
+		return "", fmt.Errorf("unknown output format %q (expected table, json, yaml or csv)", value)
+	}
+	return format, nil
+}
+
+// addWideOutputFlag adds the --output flag of a command whose table has
+// more columns with -o wide.
+func addWideOutputFlag(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVarP(p, "output", "o", string(output.Table), "output format: table, wide, json or yaml")
+}
+
+// wideOutputFormat parses an --output value added by addWideOutputFlag.
+func wideOutputFormat(value string) (output.Format, error) {
+	if strings.EqualFold(value, string(output.Wide)) {
+		return output.Wide, nil
+	}
+	format, err := output.ParseFormat(value)
+	if err != nil {
+		return "", fmt.Errorf("unknown output format %q (expected table, wide, json or yaml)", value)
+	}
+	return format, nil
+}
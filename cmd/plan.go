@@ -0,0 +1,427 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/catalog"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/output"
+	"github.com/scotttball/tunatap/internal/ports"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	planBastion      string
+	planEndpoint     string
+	planPort         int
+	planRegion       string
+	planPortStrategy string
+	planOCIProfile   string
+	planNoCache      bool
+	planNoOCIAuth    bool
+	planOutput       string
+)
+
+// connectionPlan is how connect would reach a cluster, as printed by plan.
+type connectionPlan struct {
+	Cluster       string   `json:"cluster" yaml:"cluster"`
+	Source        string   `json:"source" yaml:"source"`
+	Region        string   `json:"region" yaml:"region"`
+	ClusterOCID   string   `json:"cluster_ocid,omitempty" yaml:"cluster_ocid,omitempty"`
+	Endpoint      string   `json:"endpoint" yaml:"endpoint"`
+	Bastion       string   `json:"bastion" yaml:"bastion"`
+	BastionSource string   `json:"bastion_source" yaml:"bastion_source"`
+	BastionType   string   `json:"bastion_type" yaml:"bastion_type"`
+	Fallbacks     []string `json:"fallback_bastions,omitempty" yaml:"fallback_bastions,omitempty"`
+	Auth          string   `json:"auth" yaml:"auth"`
+	KeySource     string   `json:"key_source" yaml:"key_source"`
+	LocalPort     string   `json:"local_port" yaml:"local_port"`
+	PortStrategy  string   `json:"port_strategy" yaml:"port_strategy"`
+	Kubeconfig    string   `json:"kubeconfig" yaml:"kubeconfig"`
+	Notes         []string `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan <cluster>",
+	Short: "Show how connect would reach a cluster, without connecting",
+	Long: `Print the plan connect would follow for a cluster: where the cluster's
+definition comes from (the config file or the discovery cache), which
+bastion would be used and why, the OCI auth method, the SSH key source, the
+local port and what happens if it is busy, and the kind of kubeconfig
+generated for it.
+
+Nothing is created and OCI is not called, so what only OCI knows, such as a
+cluster that has never been discovered, is described rather than resolved.
+For the bastion session and the equivalent ssh command, run
+'tunatap connect <cluster> --dry-run'.
+
+Examples:
+  tunatap plan prod-cluster
+  tunatap plan prod-cluster --bastion backup-bastion -p 7443
+  tunatap plan prod-cluster -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPlan,
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringVarP(&planBastion, "bastion", "b", "", "bastion name connect would be given")
+	planCmd.Flags().StringVarP(&planEndpoint, "endpoint", "e", "", "endpoint name connect would be given")
+	planCmd.Flags().IntVarP(&planPort, "port", "p", 0, "local port connect would be given")
+	planCmd.Flags().StringVarP(&planRegion, "region", "r", "", "region hint connect would be given")
+	planCmd.Flags().StringVar(&planPortStrategy, "port-strategy", "", "port strategy connect would be given: increment, fail or takeover")
+	planCmd.Flags().StringVar(&planOCIProfile, "oci-profile", "", "OCI config profile connect would be given")
+	planCmd.Flags().BoolVar(&planNoCache, "no-cache", false, "plan as if the discovery cache were skipped")
+	planCmd.Flags().BoolVar(&planNoOCIAuth, "no-oci-auth", false, "plan a kubeconfig without OCI exec-auth")
+	addOutputFlag(planCmd, &planOutput)
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(planOutput, false)
+	if err != nil {
+		return err
+	}
+	if planPortStrategy != "" {
+		if _, err := ports.ParseStrategy(planPortStrategy); err != nil {
+			return fmt.Errorf("invalid --port-strategy: %w", err)
+		}
+	}
+
+	cfg, cfgLoaded, err := readConfig(GetConfigFile())
+	if err != nil {
+		return err
+	}
+	if planOCIProfile != "" {
+		cfg.OverrideOCIProfile(planOCIProfile)
+	}
+
+	var cache *discovery.Cache
+	if !planNoCache {
+		cache = cluster.NewDiscoveryCache(cfg)
+	}
+	var catalogs []*catalog.SharedCatalog
+	if len(cfg.CatalogSources) > 0 {
+		manager := catalog.NewCatalogManager(cfg.CatalogSources, getCatalogCacheDir())
+		manager.SetSecretResolver(secretResolver(cfg))
+		catalogs = manager.LoadCached()
+	}
+
+	plan, err := newConnectionPlan(cfg, cfgLoaded, args[0], cache, catalogs)
+	if err != nil {
+		return err
+	}
+	if format.Structured() {
+		return output.Write(os.Stdout, format, plan)
+	}
+	printConnectionPlan(plan)
+	return nil
+}
+
+// newConnectionPlan works out how connect would reach the named cluster,
+// reading only the config, the discovery cache and the cached catalogs.
+func newConnectionPlan(cfg *config.Config, cfgLoaded bool, name string, cache *discovery.Cache, catalogs []*catalog.SharedCatalog) (*connectionPlan, error) {
+	p := &connectionPlan{Cluster: name}
+	inConfig := cfgLoaded && config.FindClusterByName(cfg, name) != nil
+
+	viaDiscovery := cluster.NeedsDiscovery(cfg, cfgLoaded, name)
+	var c *config.Cluster
+	switch {
+	case !viaDiscovery:
+		// Plan on a copy, as connect's flags would change the cluster
+		found := *config.FindClusterByName(cfg, name)
+		c = &found
+		p.Source = "config file " + GetConfigFile()
+	case utils.IsClusterOCID(name):
+		p.Source = "discovery, looking the cluster up in OCI by its OCID"
+	default:
+		c, p.Source = planCachedCluster(name, cache)
+	}
+	if inConfig && cfg.SkipDiscovery {
+		p.Notes = append(p.Notes, "skip_discovery is set, so connect looks the cluster up through discovery even though it is in the config file")
+	}
+	for _, cat := range catalogs {
+		for _, cc := range cat.Clusters {
+			if !cc.MatchesName(name) {
+				continue
+			}
+			if inConfig {
+				p.Notes = append(p.Notes, fmt.Sprintf("also defined in catalog '%s'; the config file entry is used", cat.Name))
+			} else {
+				p.Notes = append(p.Notes, fmt.Sprintf("defined in catalog '%s', which connect does not read; add it to the config file to use that definition", cat.Name))
+			}
+		}
+	}
+
+	resolved := c != nil
+	if !resolved {
+		c = &config.Cluster{ClusterName: name, Region: planRegion}
+		if utils.IsClusterOCID(name) {
+			c.Ocid = utils.StringPtr(name)
+			c.Region = utils.ExtractRegionFromOCID(name)
+		}
+	}
+	if planBastion != "" {
+		if c.BastionId != nil || viaDiscovery {
+			p.Notes = append(p.Notes, "--bastion is ignored because the cluster has a bastion OCID from config or discovery")
+		}
+		c.Bastion = &planBastion
+	}
+	if planPortStrategy != "" {
+		c.PortStrategy = &planPortStrategy
+	}
+
+	p.Region = c.Region
+	if p.Region == "" {
+		p.Region = "found by discovery, searching every subscribed region"
+	}
+	if c.Ocid != nil {
+		p.ClusterOCID = *c.Ocid
+	}
+
+	if ep := config.GetClusterEndpoint(c, planEndpoint); ep != nil {
+		p.Endpoint = fmt.Sprintf("%s:%d", ep.Host(), ep.Port)
+		if ep.Name != "" {
+			p.Endpoint = fmt.Sprintf("%s (%s)", p.Endpoint, ep.Name)
+		}
+		if planEndpoint != "" && !strings.EqualFold(ep.Name, planEndpoint) {
+			p.Notes = append(p.Notes, fmt.Sprintf("the cluster has no endpoint named '%s', so its first endpoint is used", planEndpoint))
+		}
+	} else if !resolved {
+		p.Endpoint = "the cluster's private API endpoint, found by discovery"
+	} else {
+		p.Endpoint = "none"
+		p.Notes = append(p.Notes, "the cluster has no endpoints, so connect would fail")
+	}
+
+	planBastionChoice(p, c, viaDiscovery)
+	p.Auth = planAuth(cfg, c, viaDiscovery)
+
+	keySource, err := bastion.KeySource(cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	p.KeySource = keySource
+
+	planLocalPort(p, c)
+
+	switch {
+	case planNoOCIAuth:
+		p.Kubeconfig = "without OCI exec-auth (--no-oci-auth)"
+	case c.Ocid != nil && *c.Ocid != "":
+		profile := cfg.GetOCIProfile(c)
+		if profile == "" {
+			profile = "DEFAULT"
+		}
+		p.Kubeconfig = fmt.Sprintf("OCI exec-auth, tokens from 'tunatap token' with profile %s", profile)
+	case !resolved:
+		p.Kubeconfig = "OCI exec-auth, once discovery finds the cluster OCID"
+	default:
+		p.Kubeconfig = "without OCI exec-auth, since the cluster has no OCID"
+	}
+	if c.ShouldEmbedCA() {
+		p.Kubeconfig += "; API server verified with the cluster's CA"
+	} else {
+		p.Kubeconfig += "; TLS verification skipped (kubeconfig.embed_ca: false)"
+	}
+	return p, nil
+}
+
+// planCachedCluster returns the cluster discovery would take from the
+// cache, or nil if discovery would have to search OCI, with a description
+// of where it comes from.
+func planCachedCluster(name string, cache *discovery.Cache) (*config.Cluster, string) {
+	if cache == nil {
+		return nil, "discovery, searching OCI (the discovery cache is skipped)"
+	}
+
+	hints := &discovery.DiscoveryHints{Region: planRegion}
+	key := discovery.ChoiceCacheKey(name, hints)
+	entry := cache.GetCluster(key)
+	if entry == nil {
+		key = discovery.ChoiceCacheKey(name, nil)
+		entry = cache.GetCluster(key)
+	}
+	if entry == nil {
+		if notFound := cache.GetNotFound(discovery.ChoiceCacheKey(name, nil)); notFound != nil {
+			return nil, fmt.Sprintf("none: discovery did not find the cluster at %s and will not search again for %s",
+				notFound.CachedAt.Format("2006-01-02 15:04"), cache.Remaining(notFound).Round(time.Second))
+		}
+		return nil, "discovery, searching OCI (the cluster is not in the discovery cache)"
+	}
+
+	c := &config.Cluster{
+		ClusterName:     name,
+		Region:          entry.Region,
+		Ocid:            utils.StringPtr(entry.OCID),
+		CompartmentOcid: utils.StringPtr(entry.CompartmentOCID),
+	}
+	if entry.EndpointIP != "" {
+		c.Endpoints = []*config.ClusterEndpoint{{Name: "private", Ip: entry.EndpointIP, Port: entry.EndpointPort}}
+	}
+	if b := cache.GetBastion(discovery.ChoiceCacheKey(name, nil)); b != nil {
+		c.BastionId = utils.StringPtr(b.OCID)
+		c.FallbackBastionIds = b.Fallbacks
+	}
+	return c, fmt.Sprintf("discovery cache %s (cached %s, expires in %s)",
+		cache.Path(), entry.CachedAt.Format("2006-01-02 15:04"), cache.Remaining(entry).Round(time.Second))
+}
+
+// planBastionChoice fills in the bastion of the plan and why it is chosen,
+// following cluster.ValidateAndUpdateCluster. viaDiscovery is set when the
+// cluster comes from discovery, which always picks the bastion itself.
+func planBastionChoice(p *connectionPlan, c *config.Cluster, viaDiscovery bool) {
+	compartment := "the cluster's compartment"
+	if c.CompartmentOcid != nil && *c.CompartmentOcid != "" {
+		compartment = "compartment " + *c.CompartmentOcid
+	} else if c.Compartment != nil && *c.Compartment != "" {
+		compartment = "compartment " + *c.Compartment
+	}
+
+	switch {
+	case c.BastionId != nil && viaDiscovery:
+		p.Bastion = *c.BastionId
+		p.BastionSource = "the bastion discovery cached for the cluster"
+	case c.BastionId != nil:
+		p.Bastion = *c.BastionId
+		p.BastionSource = "bastion_id in the config file"
+	case viaDiscovery:
+		p.Bastion = "chosen by discovery"
+		p.BastionSource = "the first active bastion in " + compartment + " reaching the cluster's subnet, then its VCN, that allows some client CIDR"
+	case c.Bastion != nil:
+		p.Bastion = *c.Bastion
+		p.BastionSource = fmt.Sprintf("matched by name among the bastions in %s, unless it has only one bastion, which is used whatever its name", compartment)
+		if planBastion != "" {
+			p.BastionSource = "--bastion, " + p.BastionSource
+		} else {
+			p.BastionSource = "bastion in the config file, " + p.BastionSource
+		}
+	default:
+		p.Bastion = "the only bastion in " + compartment
+		p.BastionSource = "no bastion is configured; with several bastions there, connect asks which to use"
+	}
+
+	switch {
+	case c.BastionType != nil && *c.BastionType != "":
+		p.BastionType = *c.BastionType
+	case c.BastionId != nil:
+		p.BastionType = "STANDARD"
+	default:
+		p.BastionType = "looked up when connecting"
+	}
+
+	p.Fallbacks = c.FallbackBastionIds
+	if len(p.Fallbacks) == 0 {
+		p.Fallbacks = c.FallbackBastions
+	}
+}
+
+// planAuth describes the OCI authentication connect would use for c.
+// Discovery always detects it; a configured cluster uses the oci_auth_type
+// of the cluster, its tenancy or the config, if set.
+func planAuth(cfg *config.Config, c *config.Cluster, viaDiscovery bool) string {
+	configPath := cfg.OCIConfigPath
+	if configPath == "" {
+		configPath = utils.DefaultOCIConfigPath()
+	}
+	profile := cfg.GetOCIProfile(c)
+	if profile == "" {
+		profile = "DEFAULT"
+	}
+
+	authType := client.AuthType(cfg.GetOCIAuthType(c))
+	how := "oci_auth_type"
+	if viaDiscovery || authType == "" || authType == client.AuthTypeAuto {
+		authType = client.DetectAuthType(configPath, profile)
+		how = "detected"
+	}
+	switch authType {
+	case client.AuthTypeInstancePrincipal, client.AuthTypeResourcePrincipal, client.AuthTypeOkeWorkloadIdentity:
+		return fmt.Sprintf("%s (%s)", authType, how)
+	default:
+		return fmt.Sprintf("%s (%s), profile %s of %s", authType, how, profile, configPath)
+	}
+}
+
+// planLocalPort fills in the local port of the plan, following
+// cluster.SetClusterLocalPort.
+func planLocalPort(p *connectionPlan, c *config.Cluster) {
+	strategy := ports.StrategyIncrement
+	p.PortStrategy = string(strategy) + " (default)"
+	if c.PortStrategy != nil && *c.PortStrategy != "" {
+		s, err := ports.ParseStrategy(*c.PortStrategy)
+		if err != nil {
+			p.PortStrategy = *c.PortStrategy
+			p.Notes = append(p.Notes, err.Error())
+		} else {
+			strategy = s
+			p.PortStrategy = string(s)
+		}
+	}
+
+	if c.LocalSocket != nil && *c.LocalSocket != "" {
+		p.LocalPort = "unix socket " + *c.LocalSocket
+		return
+	}
+
+	port := planPort
+	if port <= 0 && c.LocalPort != nil {
+		port = *c.LocalPort
+	}
+	if port <= 0 {
+		p.LocalPort = "any free port"
+		return
+	}
+
+	p.LocalPort = fmt.Sprintf("%d", port)
+	if c.BindAddress != nil && *c.BindAddress != "" {
+		p.LocalPort = fmt.Sprintf("%s:%d", *c.BindAddress, port)
+	}
+	if ports.IsAvailable(port) {
+		return
+	}
+	switch strategy {
+	case ports.StrategyFail:
+		p.Notes = append(p.Notes, fmt.Sprintf("local port %d is busy, so connect would fail", port))
+	case ports.StrategyTakeover:
+		p.Notes = append(p.Notes, fmt.Sprintf("local port %d is busy; connect would stop the tunatap tunnel holding it", port))
+	default:
+		p.Notes = append(p.Notes, fmt.Sprintf("local port %d is busy; connect would use the next free port", port))
+	}
+}
+
+// printConnectionPlan prints a plan as text.
+func printConnectionPlan(p *connectionPlan) {
+	fmt.Printf("Plan for cluster %s: nothing was created and OCI was not called.\n", p.Cluster)
+	fmt.Printf("  Source:      %s\n", p.Source)
+	fmt.Printf("  Region:      %s\n", p.Region)
+	if p.ClusterOCID != "" {
+		fmt.Printf("  Cluster:     %s\n", p.ClusterOCID)
+	}
+	fmt.Printf("  Endpoint:    %s\n", p.Endpoint)
+	fmt.Printf("  Bastion:     %s\n", p.Bastion)
+	fmt.Printf("               %s\n", p.BastionSource)
+	fmt.Printf("  Type:        %s\n", p.BastionType)
+	if len(p.Fallbacks) > 0 {
+		fmt.Printf("  Fallbacks:   %s\n", strings.Join(p.Fallbacks, ", "))
+	}
+	fmt.Printf("  Auth:        %s\n", p.Auth)
+	fmt.Printf("  Key:         %s\n", p.KeySource)
+	fmt.Printf("  Local port:  %s\n", p.LocalPort)
+	fmt.Printf("  If busy:     %s\n", p.PortStrategy)
+	fmt.Printf("  Kubeconfig:  %s\n", p.Kubeconfig)
+	if len(p.Notes) > 0 {
+		fmt.Println("Notes:")
+		for _, note := range p.Notes {
+			fmt.Printf("  - %s\n", note)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/catalog"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestNewConnectionPlanFromConfig(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	cfg := config.DefaultConfig()
+	cfg.Clusters = []*config.Cluster{{
+		ClusterName: "prod",
+		Region:      "us-ashburn-1",
+		Ocid:        utils.StringPtr("ocid1.cluster.oc1.iad.aaaa"),
+		Bastion:     utils.StringPtr("prod-bastion"),
+		Endpoints:   []*config.ClusterEndpoint{{Name: "private", Ip: "10.0.0.5", Port: 6443}},
+	}}
+	catalogs := []*catalog.SharedCatalog{{Name: "team", Clusters: []*config.Cluster{{ClusterName: "prod"}}}}
+
+	p, err := newConnectionPlan(cfg, true, "prod", nil, catalogs)
+	if err != nil {
+		t.Fatalf("newConnectionPlan() error = %v", err)
+	}
+	if !strings.HasPrefix(p.Source, "config file") {
+		t.Errorf("Source = %q, want the config file", p.Source)
+	}
+	if p.Bastion != "prod-bastion" || !strings.Contains(p.BastionSource, "bastion in the config file") {
+		t.Errorf("Bastion = %q (%s), want prod-bastion from config", p.Bastion, p.BastionSource)
+	}
+	if p.Endpoint != "10.0.0.5:6443 (private)" {
+		t.Errorf("Endpoint = %q", p.Endpoint)
+	}
+	if p.LocalPort != "any free port" || p.PortStrategy != "increment (default)" {
+		t.Errorf("LocalPort = %q, PortStrategy = %q", p.LocalPort, p.PortStrategy)
+	}
+	if !strings.HasPrefix(p.KeySource, "new ephemeral") {
+		t.Errorf("KeySource = %q, want an ephemeral key", p.KeySource)
+	}
+	if !strings.HasPrefix(p.Kubeconfig, "OCI exec-auth") {
+		t.Errorf("Kubeconfig = %q, want OCI exec-auth", p.Kubeconfig)
+	}
+	if len(p.Notes) != 1 || !strings.Contains(p.Notes[0], "catalog 'team'") {
+		t.Errorf("Notes = %v, want the catalog note", p.Notes)
+	}
+
+	// Planning must not change the configured cluster
+	if cfg.Clusters[0].LocalPort != nil || cfg.Clusters[0].PortStrategy != nil {
+		t.Errorf("newConnectionPlan() changed the config: %+v", cfg.Clusters[0])
+	}
+}
+
+func TestNewConnectionPlanFromCache(t *testing.T) {
+	cache, err := discovery.NewCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	if err := cache.SetCluster("dev", &discovery.CacheEntry{
+		OCID:            "ocid1.cluster.oc1.phx.bbbb",
+		Region:          "us-phoenix-1",
+		CompartmentOCID: "ocid1.compartment.oc1..cccc",
+		EndpointIP:      "10.1.0.5",
+		EndpointPort:    6443,
+	}); err != nil {
+		t.Fatalf("SetCluster() error = %v", err)
+	}
+	if err := cache.SetBastion("dev", &discovery.CacheEntry{OCID: "ocid1.bastion.oc1.phx.dddd"}); err != nil {
+		t.Fatalf("SetBastion() error = %v", err)
+	}
+
+	p, err := newConnectionPlan(config.DefaultConfig(), false, "dev", cache, nil)
+	if err != nil {
+		t.Fatalf("newConnectionPlan() error = %v", err)
+	}
+	if !strings.HasPrefix(p.Source, "discovery cache") || p.Region != "us-phoenix-1" {
+		t.Errorf("Source = %q, Region = %q, want the discovery cache", p.Source, p.Region)
+	}
+	if p.Bastion != "ocid1.bastion.oc1.phx.dddd" || !strings.Contains(p.BastionSource, "discovery cached") {
+		t.Errorf("Bastion = %q (%s), want the cached bastion", p.Bastion, p.BastionSource)
+	}
+
+	p, err = newConnectionPlan(config.DefaultConfig(), false, "unknown", cache, nil)
+	if err != nil {
+		t.Fatalf("newConnectionPlan() error = %v", err)
+	}
+	if !strings.Contains(p.Source, "searching OCI") || p.Bastion != "chosen by discovery" {
+		t.Errorf("Source = %q, Bastion = %q, want discovery", p.Source, p.Bastion)
+	}
+}
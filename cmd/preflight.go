@@ -1,21 +1,40 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/output"
 	"github.com/scotttball/tunatap/internal/preflight"
+	"github.com/scotttball/tunatap/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 var (
-	preflightCluster string
-	preflightVerbose bool
-	preflightTimeout int
+	preflightCluster  string
+	preflightVerbose  bool
+	preflightTimeout  int
+	preflightFixAllow bool
+	preflightOutput   string
 )
 
+// preflightReport is the structured output of preflight.
+type preflightReport struct {
+	Cluster  string                  `json:"cluster" yaml:"cluster"`
+	Checks   []preflight.CheckResult `json:"checks" yaml:"checks"`
+	Errors   int                     `json:"errors" yaml:"errors"`
+	Warnings int                     `json:"warnings" yaml:"warnings"`
+}
+
 var preflightCmd = &cobra.Command{
 	Use:   "preflight [cluster]",
 	Short: "Run preflight checks before connecting to a cluster",
@@ -29,6 +48,7 @@ Preflight checks include:
   - Cluster access permissions
   - SSH agent availability
   - Network connectivity to bastion endpoint
+  - Bastion client allowlist includes your public IP
 
 Examples:
   # Check a specific cluster
@@ -38,7 +58,13 @@ Examples:
   tunatap preflight my-cluster -v
 
   # Specify timeout for network checks
-  tunatap preflight my-cluster --timeout 15`,
+  tunatap preflight my-cluster --timeout 15
+
+  # Offer to add your public IP to the bastion's client allowlist
+  tunatap preflight my-cluster --fix-allowlist
+
+  # Results as JSON for other tools
+  tunatap preflight my-cluster -o json`,
 	RunE: runPreflight,
 	Args: cobra.MaximumNArgs(1),
 }
@@ -49,9 +75,19 @@ func init() {
 	preflightCmd.Flags().StringVarP(&preflightCluster, "cluster", "c", "", "cluster name to check")
 	preflightCmd.Flags().BoolVarP(&preflightVerbose, "verbose", "v", false, "show detailed output with suggestions")
 	preflightCmd.Flags().IntVar(&preflightTimeout, "timeout", 10, "timeout in seconds for network checks")
+	preflightCmd.Flags().BoolVar(&preflightFixAllow, "fix-allowlist", false, "offer to add your public IP to the bastion's client allowlist if it is missing")
+	addOutputFlag(preflightCmd, &preflightOutput)
 }
 
 func runPreflight(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(preflightOutput, false)
+	if err != nil {
+		return err
+	}
+	if format.Structured() && preflightFixAllow {
+		return fmt.Errorf("--fix-allowlist cannot be combined with --output %s", format)
+	}
+
 	// Determine cluster name
 	clusterName := preflightCluster
 	if clusterName == "" && len(args) > 0 {
@@ -75,10 +111,12 @@ func runPreflight(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	fmt.Printf("Running preflight checks for cluster '%s'...\n", selectedCluster.ClusterName)
+	if !format.Structured() {
+		fmt.Printf("Running preflight checks for cluster '%s'...\n", selectedCluster.ClusterName)
+	}
 
 	// Create OCI client
-	ociClient, err := createOCIClient(cfg, selectedCluster.Region)
+	ociClient, err := createOCIClient(cfg, selectedCluster)
 	if err != nil {
 		log.Warn().Err(err).Msg("Could not create OCI client - some checks will be skipped")
 	}
@@ -96,9 +134,6 @@ func runPreflight(cmd *cobra.Command, args []string) error {
 	checker := preflight.NewChecker(opts)
 	results := checker.RunAll(cmd.Context())
 
-	// Print results
-	preflight.PrintResults(results, preflightVerbose)
-
 	// Summary
 	errorCount := 0
 	warningCount := 0
@@ -111,6 +146,25 @@ func runPreflight(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if format.Structured() {
+		report := preflightReport{
+			Cluster:  selectedCluster.ClusterName,
+			Checks:   results,
+			Errors:   errorCount,
+			Warnings: warningCount,
+		}
+		if err := output.Write(os.Stdout, format, report); err != nil {
+			return err
+		}
+		if errorCount > 0 {
+			return exitcode.Wrap(exitcode.PreflightFailed, fmt.Errorf("preflight checks failed with %d error(s)", errorCount))
+		}
+		return nil
+	}
+
+	// Print results
+	preflight.PrintResults(results, preflightVerbose)
+
 	fmt.Println("Summary:")
 	fmt.Printf("  Total checks: %d\n", len(results))
 	if errorCount > 0 {
@@ -133,9 +187,54 @@ func runPreflight(cmd *cobra.Command, args []string) error {
 		fmt.Println("Run 'tunatap doctor --auto-fix' to attempt automatic fixes")
 	}
 
+	if preflightFixAllow {
+		fixed, err := fixBastionAllowlist(cmd.Context(), cfg, opts, results)
+		if err != nil {
+			return err
+		}
+		if fixed {
+			errorCount--
+		}
+	}
+
 	if errorCount > 0 {
-		return fmt.Errorf("preflight checks failed with %d error(s)", errorCount)
+		return exitcode.Wrap(exitcode.PreflightFailed, fmt.Errorf("preflight checks failed with %d error(s)", errorCount))
 	}
 
 	return nil
 }
+
+// fixBastionAllowlist adds this machine's public IP to the bastion's client
+// allowlist after confirmation, if the allowlist check failed. It reports
+// whether the allowlist was updated.
+func fixBastionAllowlist(ctx context.Context, cfg *config.Config, opts *preflight.CheckOptions, results []preflight.CheckResult) (bool, error) {
+	failed := slices.ContainsFunc(results, func(r preflight.CheckResult) bool {
+		return r.Name == preflight.AllowlistCheckName && r.Status == preflight.StatusError
+	})
+	if !failed {
+		return false, nil
+	}
+
+	ip, err := preflight.PublicIP(ctx, cfg.GetPublicIPURL())
+	if err != nil {
+		return false, err
+	}
+	cidr := preflight.HostCIDR(ip)
+
+	if !ui.CanPrompt() {
+		return false, ui.PromptError("--fix-allowlist", fmt.Sprintf("add %s to the client allowlist of bastion %s in the OCI console, or run interactively", cidr, *opts.Cluster.BastionId))
+	}
+
+	fmt.Printf("\nAdd %s to the client allowlist of bastion %s? [y/N]: ", cidr, *opts.Cluster.BastionId)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if answer != "y" && answer != "yes" {
+		return false, nil
+	}
+
+	if _, err := bastion.AllowClientCIDR(ctx, opts.OCIClient, *opts.Cluster.BastionId, cidr); err != nil {
+		return false, fmt.Errorf("failed to update bastion allowlist (this needs permission to update bastions): %w", err)
+	}
+	fmt.Printf("Added %s to the allowlist. The bastion applies the change within a few minutes.\n", cidr)
+	return true, nil
+}
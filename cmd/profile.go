@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// loadProfile returns the named profile from cfg, or nil if name is empty.
+func loadProfile(cfg *config.Config, name string) (*config.Profile, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	profile := config.FindProfileByName(cfg, name)
+	if profile == nil {
+		return nil, fmt.Errorf("profile '%s' not found in config", name)
+	}
+	if _, err := config.GetProfileForwards(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// applyProfileDefaults fills in connect options that were not given on the
+// command line from profile. A nil profile leaves them unchanged.
+func applyProfileDefaults(profile *config.Profile, clusterName, endpoint *string, socksPort *int, allEndpoints *bool) {
+	if profile == nil {
+		return
+	}
+
+	log.Info().Msgf("Using profile: %s", profile.Name)
+	if *clusterName == "" {
+		*clusterName = profile.Cluster
+	}
+	if *endpoint == "" {
+		*endpoint = profile.Endpoint
+	}
+	if *socksPort == 0 {
+		*socksPort = profile.SocksPort
+	}
+	*allEndpoints = *allEndpoints || profile.AllEndpoints
+}
+
+// withProfileForwards appends the profile's forwards to the additional
+// endpoints of a tunnel.
+func withProfileForwards(endpoints []*config.ClusterEndpoint, profile *config.Profile) ([]*config.ClusterEndpoint, error) {
+	if profile == nil {
+		return endpoints, nil
+	}
+
+	forwards, err := config.GetProfileForwards(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ep := range forwards {
+		protocol := "tcp"
+		if ep.IsUDP() {
+			protocol = "udp"
+		}
+		log.Info().Msgf("Profile forward %s: %s:%d/%s on local port %d", ep.Name, ep.Host(), ep.Port, protocol, *ep.LocalPort)
+	}
+	return append(endpoints, forwards...), nil
+}
+
+// profileKubeconfigHook returns a function to call each time the tunnel is
+// ready. It writes the kubeconfig of the profile, or of the config defaults
+// if the profile has none, for the tunnel's port, again only if a reconnect
+// moved the tunnel to another port.
+func profileKubeconfigHook(cfg *config.Config, c *config.Cluster, profile *config.Profile) func(port int) {
+	var settings *config.ProfileKubeconfig
+	what := "defaults"
+	switch {
+	case profile != nil && profile.Kubeconfig != nil:
+		settings = profile.Kubeconfig
+		what = "profile " + profile.Name
+	case cfg.Defaults != nil && cfg.Defaults.Kubeconfig != nil:
+		settings = cfg.Defaults.Kubeconfig
+	default:
+		return func(int) {}
+	}
+
+	var (
+		mu       sync.Mutex
+		lastPort int
+	)
+	return func(port int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if port == lastPort {
+			return
+		}
+
+		kubecfg, err := buildKubeconfig(context.Background(), cfg, c, port, settings.NoOCIAuth, "")
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to build kubeconfig for %s", what)
+			return
+		}
+		lastPort = port
+		if settings.Path != "" {
+			path := utils.ExpandPath(strings.ReplaceAll(settings.Path, "{cluster}", c.ClusterName))
+			if err := kubecfg.WriteToFile(path); err != nil {
+				log.Warn().Err(err).Msgf("Failed to write kubeconfig for %s", what)
+			} else {
+				log.Info().Msgf("Kubeconfig written to %s", path)
+			}
+		}
+		if settings.Merge {
+			// stdout may carry --events-json output
+			if err := mergeKubeconfig(kubecfg, os.Stderr); err != nil {
+				log.Warn().Err(err).Msgf("Failed to merge kubeconfig for %s", what)
+			}
+		}
+	}
+}
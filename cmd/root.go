@@ -4,10 +4,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 
+	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/kubeconfig"
+	"github.com/scotttball/tunatap/internal/output"
 	"github.com/scotttball/tunatap/internal/state"
+	"github.com/scotttball/tunatap/internal/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -19,6 +25,9 @@ var (
 	debug     bool
 	rawOutput bool
 	homePath  string
+
+	insecureHostKey bool
+	nonInteractive  bool
 )
 
 // rootCmd represents the base command
@@ -35,42 +44,68 @@ It simplifies connecting to OKE clusters and other private resources via bastion
 			if err != nil {
 				return fmt.Errorf("failed to open log file: %w", err)
 			}
-			log.Logger = log.Output(zerolog.New(logFile))
+			setConsoleLog(logFile)
 		} else {
-			log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+			setConsoleLog(zerolog.ConsoleWriter{Out: os.Stderr})
 		}
 
 		if debug {
-			zerolog.SetGlobalLevel(zerolog.DebugLevel)
+			setConsoleLevel(zerolog.DebugLevel)
 		} else {
-			zerolog.SetGlobalLevel(zerolog.InfoLevel)
+			setConsoleLevel(zerolog.InfoLevel)
+		}
+
+		ui.SetNonInteractive(nonInteractive)
+
+		if err := checkErrorFormat(cmd.Root()); err != nil {
+			return err
+		}
+		if err := selectConfigProfile(); err != nil {
+			return err
 		}
+		openLogFile()
 
 		// Initialize global state
 		globalState := state.GetInstance()
 		globalState.SetHomePath(homePath)
+		globalState.SetConfigDir(configDir())
+		kubeconfig.TokenCommand = tokenCommand()
+		kubeconfig.TokenConfigArgs = tokenConfigArgs()
+
+		startUpdateCheck(cmd)
 
 		return nil
 	},
 }
 
-// Execute runs the root command
+// Execute runs the root command and exits with the code for the class of
+// failure, if it fails.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	setKubectlPluginMode(os.Args[0])
+	err := rootCmd.Execute()
+	printUpdateNotice()
+	if err != nil {
+		code := exitCode(err)
+		printError(os.Stderr, err, code)
+		os.Exit(int(code))
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.tunatap/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&configProfile, "config-profile", "", "named config to use, with its own clusters and caches (also TUNATAP_PROFILE; see 'tunatap config use')")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug logging")
 	rootCmd.PersistentFlags().BoolVar(&rawOutput, "raw", false, "output raw logs to file instead of console")
+	rootCmd.PersistentFlags().BoolVar(&insecureHostKey, "insecure-host-key", false, "DANGEROUS: skip bastion host key verification")
+	rootCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "never prompt or show selectors; fail with an error instead (also TUNATAP_NON_INTERACTIVE=1)")
+	rootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", defaultErrorFormat(), "how a failure is printed on stderr: text or json (also TUNATAP_ERROR_FORMAT)")
+	rootCmd.SetFlagErrorFunc(flagError)
 }
 
 // SetVersionInfo sets the version information for the CLI
 func SetVersionInfo(v, c, d string) {
 	version = v
+	bastion.ClientVersion = v
 	commit = c
 	date = d
 }
@@ -85,20 +120,64 @@ func GetConfigFile() string {
 	if cfgFile != "" {
 		return cfgFile
 	}
-	return filepath.Join(homePath, "config.yaml")
+	return filepath.Join(configDir(), config.ConfigFileName)
+}
+
+// versionInfo is the build information printed by "tunatap version".
+type versionInfo struct {
+	Version       string `json:"version" yaml:"version"`
+	Commit        string `json:"commit" yaml:"commit"`
+	Date          string `json:"date" yaml:"date"`
+	GoVersion     string `json:"go_version" yaml:"go_version"`
+	OCISDKVersion string `json:"oci_sdk_version" yaml:"oci_sdk_version"`
+	OS            string `json:"os" yaml:"os"`
+	Arch          string `json:"arch" yaml:"arch"`
+}
+
+func currentVersionInfo() versionInfo {
+	return versionInfo{
+		Version:       version,
+		Commit:        commit,
+		Date:          date,
+		GoVersion:     runtime.Version(),
+		OCISDKVersion: common.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+	}
 }
 
+var (
+	versionJSON   bool
+	versionOutput string
+)
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("tunatap %s\n", version)
-		fmt.Printf("  commit: %s\n", commit)
-		fmt.Printf("  built:  %s\n", date)
+	Long: `Print the version and build information of tunatap. With --json, the
+Go and OCI SDK versions and the platform are included, for bug reports.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(versionOutput, versionJSON)
+		if err != nil {
+			return err
+		}
+		info := currentVersionInfo()
+		if format.Structured() {
+			return output.Write(os.Stdout, format, info)
+		}
+
+		fmt.Printf("tunatap %s\n", info.Version)
+		fmt.Printf("  commit: %s\n", info.Commit)
+		fmt.Printf("  built:  %s\n", info.Date)
+		fmt.Printf("  go:     %s (%s/%s)\n", info.GoVersion, info.OS, info.Arch)
+		fmt.Printf("  oci:    %s\n", info.OCISDKVersion)
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, "output as JSON")
+	addOutputFlag(versionCmd, &versionOutput)
 }
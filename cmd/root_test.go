@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"bytes"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
 	"github.com/spf13/cobra"
 )
 
@@ -60,6 +67,41 @@ func TestGetConfigFile(t *testing.T) {
 	}
 }
 
+func TestSelectConfigProfile(t *testing.T) {
+	origCfgFile, origHomePath, origProfile := cfgFile, homePath, configProfile
+	defer func() {
+		cfgFile, homePath, configProfile = origCfgFile, origHomePath, origProfile
+		activeConfigProfile, profileDir = "", ""
+	}()
+
+	homePath = t.TempDir()
+	cfgFile, configProfile = "", ""
+	t.Setenv(config.ConfigProfileEnv, "")
+	if err := config.CreateConfigProfile(homePath, "work", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := selectConfigProfile(); err != nil || GetConfigFile() != filepath.Join(homePath, "config.yaml") {
+		t.Errorf("selectConfigProfile() = %v, config %q; want the default config", err, GetConfigFile())
+	}
+
+	t.Setenv(config.ConfigProfileEnv, "work")
+	want := filepath.Join(homePath, "profiles", "work", "config.yaml")
+	if err := selectConfigProfile(); err != nil || GetConfigFile() != want {
+		t.Errorf("selectConfigProfile() with %s = %v, config %q; want %q", config.ConfigProfileEnv, err, GetConfigFile(), want)
+	}
+
+	configProfile = "missing"
+	if err := selectConfigProfile(); exitCode(err) != exitcode.NotFound {
+		t.Errorf("selectConfigProfile() of a missing profile = %v, want not found", err)
+	}
+
+	cfgFile = "/custom/config.yaml"
+	if err := selectConfigProfile(); exitCode(err) != exitcode.Usage {
+		t.Errorf("selectConfigProfile() with --config = %v, want a usage error", err)
+	}
+}
+
 func TestRootCommand(t *testing.T) {
 	// Test that root command exists and has expected properties
 	if rootCmd == nil {
@@ -125,3 +167,46 @@ func TestSubcommands(t *testing.T) {
 		}
 	}
 }
+
+func TestLogFile(t *testing.T) {
+	origCfgFile, origHomePath, origLogger := cfgFile, homePath, log.Logger
+	origConsole, origFile := *consoleLog, fileLog
+	defer func() {
+		cfgFile, homePath, log.Logger = origCfgFile, origHomePath, origLogger
+		*consoleLog, fileLog = origConsole, origFile
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}()
+
+	homePath = t.TempDir()
+	cfgFile = filepath.Join(homePath, "config.yaml")
+	var console bytes.Buffer
+	setConsoleLog(&console)
+	setConsoleLevel(zerolog.WarnLevel)
+	openLogFile()
+
+	log.Debug().Msg("connecting in detail")
+	log.Warn().Msg("session about to expire")
+
+	data, err := os.ReadFile(filepath.Join(homePath, "logs", "tunatap.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `{"level":"debug",`) || !strings.Contains(string(data), `"message":"connecting in detail"`) ||
+		!strings.Contains(string(data), `"message":"session about to expire"`) {
+		t.Errorf("log file = %s, want both messages as JSON", data)
+	}
+	if strings.Contains(console.String(), "connecting in detail") || !strings.Contains(console.String(), "session about to expire") {
+		t.Errorf("console log = %q, want only the warning", console.String())
+	}
+
+	// Turned off in the config, nothing but the console is logged to
+	fileLog.Writer.(zerolog.LevelWriterAdapter).Close()
+	fileLog = nil
+	if err := os.WriteFile(cfgFile, []byte("log_file:\n  enabled: false\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	openLogFile()
+	if fileLog != nil {
+		t.Error("log file turned off in the config is still written")
+	}
+}
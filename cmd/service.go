@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/service"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serviceHealthEndpoint string
+	serviceLogFile        string
+	serviceDryRun         bool
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Run tunatap as a service started at login",
+	Long: `Install tunatap as a per-user service: a systemd user unit on Linux or a
+launchd agent on macOS. The service starts at login and is restarted if it
+crashes.
+
+With clusters, the service keeps tunnels to them open as 'tunatap connect'
+would. Without, it runs the background daemon, so that tunnels started with
+'tunatap connect --detach' are served by it.`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install [cluster...]",
+	Short: "Install and start the service",
+	Long: `Install and start the service, replacing any installed before.
+
+The service uses the config file tunatap is run with now. Its output goes to
+~/.tunatap/service.log, or ~/.tunatap/daemon.log when it runs the daemon,
+unless --log-file is given.
+
+Examples:
+  tunatap service install prod-cluster staging-cluster --health-endpoint localhost:9090
+  tunatap service install
+  tunatap service install prod-cluster --dry-run`,
+	RunE: runServiceInstall,
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop and remove the service",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceUninstall,
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the service is installed and running",
+	Args:  cobra.NoArgs,
+	RunE:  runServiceStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(serviceCmd)
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(serviceUninstallCmd)
+	serviceCmd.AddCommand(serviceStatusCmd)
+
+	serviceInstallCmd.Flags().StringVar(&serviceHealthEndpoint, "health-endpoint", "", "address for the service's health server, e.g. localhost:9090 (default: health_endpoint from config)")
+	serviceInstallCmd.Flags().StringVar(&serviceLogFile, "log-file", "", "file the service's output is appended to")
+	serviceInstallCmd.Flags().BoolVar(&serviceDryRun, "dry-run", false, "print the unit or agent file without installing it")
+}
+
+func runServiceInstall(cmd *cobra.Command, args []string) error {
+	manager, err := service.Detect()
+	if err != nil {
+		return err
+	}
+
+	spec, err := serviceSpec(args)
+	if err != nil {
+		return err
+	}
+
+	if serviceDryRun {
+		data, err := manager.Render(spec)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Would install %s:\n\n", manager.Path())
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(spec.LogPath), 0o700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := manager.Install(spec); err != nil {
+		return fmt.Errorf("failed to install service: %w", err)
+	}
+
+	fmt.Printf("Installed %s service: %s\n", manager.Name(), manager.Path())
+	fmt.Printf("Logs: %s\n", spec.LogPath)
+	if serviceHealthEndpoint != "" {
+		fmt.Printf("Health: http://%s/health\n", serviceHealthEndpoint)
+	}
+	return nil
+}
+
+// serviceSpec builds the service running connect for clusters, or the
+// daemon without clusters, with the current config file and the install
+// flags.
+func serviceSpec(clusters []string) (*service.Spec, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate tunatap executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	configFile, err := filepath.Abs(GetConfigFile())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	var args []string
+	logPath := filepath.Join(utils.DefaultTunatapDir(), "service.log")
+	if len(clusters) > 0 {
+		args = append([]string{"connect"}, clusters...)
+	} else {
+		args = []string{"daemon", "run", "--background"}
+		logPath = daemon.DefaultLogPath()
+	}
+	args = append(args, "--config", configFile)
+	if serviceHealthEndpoint != "" {
+		args = append(args, "--health-endpoint", serviceHealthEndpoint)
+	}
+	if debug {
+		args = append(args, "--debug")
+	}
+
+	if serviceLogFile != "" {
+		if logPath, err = filepath.Abs(utils.ExpandPath(serviceLogFile)); err != nil {
+			return nil, fmt.Errorf("failed to resolve log file path: %w", err)
+		}
+	}
+
+	return &service.Spec{Executable: executable, Args: args, LogPath: logPath}, nil
+}
+
+func runServiceUninstall(cmd *cobra.Command, args []string) error {
+	manager, err := service.Detect()
+	if err != nil {
+		return err
+	}
+	if err := manager.Uninstall(); err != nil {
+		return fmt.Errorf("failed to uninstall service: %w", err)
+	}
+
+	fmt.Printf("Removed %s service: %s\n", manager.Name(), manager.Path())
+	return nil
+}
+
+func runServiceStatus(cmd *cobra.Command, args []string) error {
+	manager, err := service.Detect()
+	if err != nil {
+		return err
+	}
+	status, err := manager.Status()
+	if err != nil {
+		return err
+	}
+
+	if !status.Installed {
+		fmt.Printf("Not installed (%s)\n", manager.Path())
+		return nil
+	}
+	fmt.Printf("Installed: %s\n", manager.Path())
+	fmt.Printf("Status:    %s\n", status.Detail)
+	return nil
+}
@@ -0,0 +1,627 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	ocibastion "github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sessionsPruneDryRun    bool
+	sessionsPruneOlderThan time.Duration
+	sessionsListAll        bool
+	sessionsOlderThan      time.Duration
+	sessionsCreator        string
+	sessionsDeleteClusters []string
+	sessionsDeleteDryRun   bool
+	sessionsOutput         string
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manage bastion sessions",
+	Long:  `Commands for managing the bastion sessions tunatap creates.`,
+}
+
+var sessionsPruneCmd = &cobra.Command{
+	Use:   "prune [cluster...]",
+	Short: "Delete abandoned bastion sessions",
+	Long: `Delete the sessions tunatap created that are no longer useful, so that
+they do not count against the bastion's session quota.
+
+The bastions of every configured and cached cluster are checked, or only
+those of the given clusters. Only sessions whose display name starts with
+"tunatap-" are considered. Failed and expired sessions are always pruned;
+with --older-than, active sessions created at least that long ago are
+pruned too, which disconnects any tunnel still using them.
+
+Examples:
+  tunatap sessions prune --dry-run
+  tunatap sessions prune prod-cluster --older-than 2h`,
+	RunE: runSessionsPrune,
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list [cluster...]",
+	Short: "List bastion sessions and who created them",
+	Long: `List the sessions on the bastions of every configured and cached cluster,
+or only those of the given clusters.
+
+Sessions created by tunatap record the local user, host name and tunatap
+version in their display name, and these are shown in their own columns.
+Sessions created elsewhere show "-". Only active and creating sessions are
+listed unless --all is given.
+
+--creator matches the user recorded by tunatap, optionally with the host as
+user@host, so it never matches sessions created elsewhere.
+
+Examples:
+  tunatap sessions list
+  tunatap sessions list prod-cluster --all
+  tunatap sessions list --creator alice --older-than 4h`,
+	RunE: runSessionsList,
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <session-ocid>",
+	Short: "Show the details of a bastion session",
+	Long: `Show the state, target, owner and expiry of a session, and the ssh command
+OCI gives for it. The session is looked up on the bastions of the configured
+and cached clusters.
+
+Examples:
+  tunatap sessions show ocid1.bastionsession.oc1.iad.xxx`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionsShow,
+}
+
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete [session-ocid...]",
+	Short: "Delete bastion sessions",
+	Long: `Delete the given sessions, or the active sessions selected by --cluster,
+--older-than and --creator. Deleting a session disconnects any tunnel using
+it. Unlike prune, sessions created elsewhere are deleted too when they
+match.
+
+Examples:
+  tunatap sessions delete ocid1.bastionsession.oc1.iad.xxx
+  tunatap sessions delete --cluster prod-cluster --creator alice
+  tunatap sessions delete --older-than 8h --dry-run`,
+	RunE: runSessionsDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+	sessionsCmd.AddCommand(sessionsPruneCmd)
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsShowCmd)
+	sessionsCmd.AddCommand(sessionsDeleteCmd)
+
+	sessionsListCmd.Flags().BoolVar(&sessionsListAll, "all", false, "also list failed, deleted and deleting sessions")
+	sessionsListCmd.Flags().DurationVar(&sessionsOlderThan, "older-than", 0, "only list sessions older than this, e.g. 2h")
+	sessionsListCmd.Flags().StringVar(&sessionsCreator, "creator", "", "only list sessions created by this user, as user or user@host")
+	addOutputFlag(sessionsListCmd, &sessionsOutput)
+
+	addOutputFlag(sessionsShowCmd, &sessionsOutput)
+
+	sessionsDeleteCmd.Flags().StringSliceVarP(&sessionsDeleteClusters, "cluster", "c", nil, "only delete sessions on the bastions of these clusters")
+	sessionsDeleteCmd.Flags().DurationVar(&sessionsOlderThan, "older-than", 0, "only delete sessions older than this, e.g. 2h")
+	sessionsDeleteCmd.Flags().StringVar(&sessionsCreator, "creator", "", "only delete sessions created by this user, as user or user@host")
+	sessionsDeleteCmd.Flags().BoolVar(&sessionsDeleteDryRun, "dry-run", false, "list the sessions that would be deleted without deleting them")
+
+	sessionsPruneCmd.Flags().BoolVar(&sessionsPruneDryRun, "dry-run", false, "list the sessions that would be deleted without deleting them")
+	sessionsPruneCmd.Flags().DurationVar(&sessionsPruneOlderThan, "older-than", 0, "also prune active sessions older than this, e.g. 2h")
+}
+
+// sessionBastion is a bastion whose sessions are pruned.
+type sessionBastion struct {
+	Cluster string
+	Region  string
+	ID      string
+}
+
+func runSessionsPrune(cmd *cobra.Command, args []string) error {
+	cfg := loadBastionConfig()
+
+	clients := make(map[string]*client.OCIClient)
+	bastions := configuredBastions(cmd.Context(), cfg, clients, args)
+	if len(bastions) == 0 {
+		fmt.Println("No bastions found. Connect to a cluster first, or configure bastion_id for it.")
+		return nil
+	}
+
+	opts := &bastion.PruneOptions{OlderThan: sessionsPruneOlderThan, DryRun: sessionsPruneDryRun}
+	total, failed := 0, 0
+	for _, b := range bastions {
+		ociClient, err := regionClient(cfg, clients, b.Cluster, b.Region)
+		if err != nil {
+			return err
+		}
+
+		pruned, err := bastion.PruneSessions(cmd.Context(), ociClient, b.ID, opts)
+		if err != nil {
+			warnSessionsError(err, b)
+			continue
+		}
+
+		for _, p := range pruned {
+			name := ""
+			if p.Session.DisplayName != nil {
+				name = *p.Session.DisplayName
+			}
+			switch {
+			case sessionsPruneDryRun:
+				fmt.Printf("Would delete %s session %s (%s) on %s\n", p.Reason, *p.Session.Id, name, b.Cluster)
+			case p.Err != nil:
+				fmt.Printf("Failed to delete %s session %s (%s) on %s: %v\n", p.Reason, *p.Session.Id, name, b.Cluster, p.Err)
+				failed++
+			default:
+				fmt.Printf("Deleted %s session %s (%s) on %s\n", p.Reason, *p.Session.Id, name, b.Cluster)
+			}
+		}
+		total += len(pruned)
+	}
+
+	if total == 0 {
+		fmt.Println("No sessions to prune.")
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d sessions", failed, total)
+	}
+	return nil
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(sessionsOutput, false)
+	if err != nil {
+		return err
+	}
+
+	cfg := loadBastionConfig()
+
+	clients := make(map[string]*client.OCIClient)
+	bastions := configuredBastions(cmd.Context(), cfg, clients, args)
+	if len(bastions) == 0 && !format.Structured() {
+		fmt.Println("No bastions found. Connect to a cluster first, or configure bastion_id for it.")
+		return nil
+	}
+
+	filter := &bastion.SessionFilter{All: sessionsListAll, OlderThan: sessionsOlderThan, Creator: sessionsCreator}
+	now := time.Now()
+	infos := []sessionInfo{}
+	for _, b := range bastions {
+		ociClient, err := regionClient(cfg, clients, b.Cluster, b.Region)
+		if err != nil {
+			return err
+		}
+
+		sessions, err := ociClient.ListSessions(cmd.Context(), b.ID)
+		if err != nil {
+			warnSessionsError(err, b)
+			continue
+		}
+
+		for _, s := range sessions {
+			if !filter.Match(s, now) {
+				continue
+			}
+			infos = append(infos, newSessionInfo(b.Cluster, b.ID, s.DisplayName, s.Id, s.LifecycleState, s.TimeCreated))
+		}
+	}
+
+	if format.Structured() {
+		return output.Write(os.Stdout, format, infos)
+	}
+
+	if len(infos) == 0 {
+		fmt.Println("No sessions found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tNAME\tSTATE\tUSER\tHOST\tVERSION\tCREATED\tOCID")
+	for _, info := range infos {
+		fmt.Fprintln(w, sessionListRow(info))
+	}
+	return w.Flush()
+}
+
+func runSessionsShow(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(sessionsOutput, false)
+	if err != nil {
+		return err
+	}
+
+	cfg := loadBastionConfig()
+
+	clients := make(map[string]*client.OCIClient)
+	b, err := findSessionBastion(cmd.Context(), cfg, clients, args[0])
+	if err != nil {
+		return err
+	}
+	ociClient, err := regionClient(cfg, clients, b.Cluster, b.Region)
+	if err != nil {
+		return err
+	}
+
+	session, err := ociClient.GetSession(cmd.Context(), b.ID, args[0])
+	if err != nil {
+		return client.WrapOCIError(err, "get bastion session")
+	}
+
+	details := newSessionDetails(b.Cluster, session)
+	if format.Structured() {
+		return output.Write(os.Stdout, format, details)
+	}
+	printSession(details)
+	return nil
+}
+
+func runSessionsDelete(cmd *cobra.Command, args []string) error {
+	filter := &bastion.SessionFilter{OlderThan: sessionsOlderThan, Creator: sessionsCreator}
+	if len(args) > 0 && (len(sessionsDeleteClusters) > 0 || !filter.IsZero()) {
+		return fmt.Errorf("session OCIDs cannot be used with --cluster, --older-than or --creator")
+	}
+	if len(args) == 0 && len(sessionsDeleteClusters) == 0 && filter.IsZero() {
+		return fmt.Errorf("give session OCIDs, or select sessions with --cluster, --older-than or --creator")
+	}
+
+	cfg := loadBastionConfig()
+	clients := make(map[string]*client.OCIClient)
+
+	// Each target is a session to delete and the bastion it is on
+	type target struct {
+		bastion sessionBastion
+		id      string
+		name    string
+	}
+	var targets []target
+	if len(args) > 0 {
+		for _, id := range args {
+			b, err := findSessionBastion(cmd.Context(), cfg, clients, id)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, target{bastion: *b, id: id})
+		}
+	} else {
+		bastions := configuredBastions(cmd.Context(), cfg, clients, sessionsDeleteClusters)
+		if len(bastions) == 0 {
+			fmt.Println("No bastions found. Connect to a cluster first, or configure bastion_id for it.")
+			return nil
+		}
+		now := time.Now()
+		for _, b := range bastions {
+			ociClient, err := regionClient(cfg, clients, b.Cluster, b.Region)
+			if err != nil {
+				return err
+			}
+			sessions, err := ociClient.ListSessions(cmd.Context(), b.ID)
+			if err != nil {
+				warnSessionsError(err, b)
+				continue
+			}
+			for _, s := range sessions {
+				if s.Id == nil || !filter.Match(s, now) {
+					continue
+				}
+				name := ""
+				if s.DisplayName != nil {
+					name, _ = bastion.ParseSessionDisplayName(*s.DisplayName)
+				}
+				targets = append(targets, target{bastion: b, id: *s.Id, name: name})
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("No sessions to delete.")
+		return nil
+	}
+
+	failed := 0
+	for _, t := range targets {
+		label := t.id
+		if t.name != "" {
+			label = fmt.Sprintf("%s (%s)", t.id, t.name)
+		}
+		if sessionsDeleteDryRun {
+			fmt.Printf("Would delete session %s on %s\n", label, t.bastion.Cluster)
+			continue
+		}
+
+		ociClient, err := regionClient(cfg, clients, t.bastion.Cluster, t.bastion.Region)
+		if err != nil {
+			return err
+		}
+		if err := ociClient.DeleteSession(cmd.Context(), t.bastion.ID, t.id); err != nil {
+			fmt.Printf("Failed to delete session %s on %s: %v\n", label, t.bastion.Cluster, client.WrapOCIError(err, "delete bastion session"))
+			failed++
+			continue
+		}
+		fmt.Printf("Deleted session %s on %s\n", label, t.bastion.Cluster)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d sessions", failed, len(targets))
+	}
+	return nil
+}
+
+// findSessionBastion returns the configured or cached bastion a session is
+// on.
+func findSessionBastion(ctx context.Context, cfg *config.Config, clients map[string]*client.OCIClient, sessionID string) (*sessionBastion, error) {
+	bastions := configuredBastions(ctx, cfg, clients, nil)
+	for _, b := range bastions {
+		ociClient, err := regionClient(cfg, clients, b.Cluster, b.Region)
+		if err != nil {
+			return nil, err
+		}
+		sessions, err := ociClient.ListSessions(ctx, b.ID)
+		if err != nil {
+			warnSessionsError(err, b)
+			continue
+		}
+		for _, s := range sessions {
+			if s.Id != nil && *s.Id == sessionID {
+				return &b, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("session %s not found on the bastions of %d configured and cached clusters", sessionID, len(bastions))
+}
+
+// warnSessionsError logs a failure to list the sessions of a bastion. An
+// authorization failure is classified so it names the policy to grant.
+func warnSessionsError(err error, b sessionBastion) {
+	ociErr := client.ClassifyOCIError(err, "list bastion sessions")
+	switch ociErr.Type {
+	case client.ErrorTypeNotAuthorized, client.ErrorTypeNotAuthorizedOrNotFound:
+		log.Warn().Msgf("Not allowed to list sessions on bastion for cluster '%s'; this needs: Allow group <group-name> to read bastion-session in compartment <compartment-name>", b.Cluster)
+	case client.ErrorTypeNotFound:
+		log.Warn().Msgf("Bastion for cluster '%s' no longer exists: %s", b.Cluster, b.ID)
+	default:
+		log.Warn().Err(err).Msgf("Failed to list sessions on bastion for cluster '%s'", b.Cluster)
+	}
+}
+
+// sessionInfo is a session in the structured output of sessions list, with
+// the owner tunatap records in the display name decoded.
+type sessionInfo struct {
+	Cluster   string     `json:"cluster" yaml:"cluster"`
+	Name      string     `json:"name" yaml:"name"`
+	State     string     `json:"state" yaml:"state"`
+	User      string     `json:"user,omitempty" yaml:"user,omitempty"`
+	Host      string     `json:"host,omitempty" yaml:"host,omitempty"`
+	Version   string     `json:"version,omitempty" yaml:"version,omitempty"`
+	Created   *time.Time `json:"created,omitempty" yaml:"created,omitempty"`
+	ID        string     `json:"id" yaml:"id"`
+	BastionID string     `json:"bastion_id" yaml:"bastion_id"`
+}
+
+// sessionDetails is the structured output of sessions show.
+type sessionDetails struct {
+	sessionInfo `yaml:",inline"`
+	Details     string     `json:"details,omitempty" yaml:"details,omitempty"`
+	Type        string     `json:"type,omitempty" yaml:"type,omitempty"`
+	Target      string     `json:"target,omitempty" yaml:"target,omitempty"`
+	OSUser      string     `json:"os_user,omitempty" yaml:"os_user,omitempty"`
+	Expires     *time.Time `json:"expires,omitempty" yaml:"expires,omitempty"`
+	SSHCommand  string     `json:"ssh_command,omitempty" yaml:"ssh_command,omitempty"`
+}
+
+// newSessionInfo describes a session on the bastion bastionID of a cluster.
+func newSessionInfo(clusterName, bastionID string, displayName, id *string, state ocibastion.SessionLifecycleStateEnum, created *common.SDKTime) sessionInfo {
+	info := sessionInfo{
+		Cluster:   clusterName,
+		State:     string(state),
+		BastionID: bastionID,
+	}
+	if displayName != nil {
+		var owner bastion.SessionOwner
+		info.Name, owner = bastion.ParseSessionDisplayName(*displayName)
+		info.User, info.Host, info.Version = owner.User, owner.Host, owner.Version
+	}
+	if id != nil {
+		info.ID = *id
+	}
+	if created != nil {
+		t := created.Time
+		info.Created = &t
+	}
+	return info
+}
+
+// newSessionDetails describes a session for sessions show.
+func newSessionDetails(clusterName string, s *ocibastion.Session) sessionDetails {
+	value := func(p *string) string {
+		if p == nil {
+			return ""
+		}
+		return *p
+	}
+
+	d := sessionDetails{
+		sessionInfo: newSessionInfo(clusterName, value(s.BastionId), s.DisplayName, s.Id, s.LifecycleState, s.TimeCreated),
+		Details:     value(s.LifecycleDetails),
+		SSHCommand:  s.SshMetadata["command"],
+	}
+
+	switch target := s.TargetResourceDetails.(type) {
+	case ocibastion.PortForwardingSessionTargetResourceDetails:
+		host := value(target.TargetResourcePrivateIpAddress)
+		if target.TargetResourceFqdn != nil && *target.TargetResourceFqdn != "" {
+			host = *target.TargetResourceFqdn
+		}
+		port := 0
+		if target.TargetResourcePort != nil {
+			port = *target.TargetResourcePort
+		}
+		d.Type = "port forwarding"
+		d.Target = fmt.Sprintf("%s:%d", host, port)
+	case ocibastion.ManagedSshSessionTargetResourceDetails:
+		d.Type = "managed SSH"
+		d.Target = fmt.Sprintf("%s (%s)", value(target.TargetResourceDisplayName), value(target.TargetResourceId))
+		d.OSUser = value(target.TargetResourceOperatingSystemUserName)
+	case ocibastion.DynamicPortForwardingSessionTargetResourceDetails:
+		d.Type = "dynamic port forwarding"
+	}
+
+	if s.TimeCreated != nil && s.SessionTtlInSeconds != nil {
+		expires := s.TimeCreated.Add(time.Duration(*s.SessionTtlInSeconds) * time.Second)
+		d.Expires = &expires
+	}
+	return d
+}
+
+// printSession prints the details of a session for sessions show.
+func printSession(d sessionDetails) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "OCID:\t%s\n", orDash(d.ID))
+	fmt.Fprintf(w, "Name:\t%s\n", orDash(d.Name))
+	fmt.Fprintf(w, "Cluster:\t%s\n", d.Cluster)
+	fmt.Fprintf(w, "Bastion:\t%s\n", orDash(d.BastionID))
+	fmt.Fprintf(w, "State:\t%s\n", d.State)
+	if d.Details != "" {
+		fmt.Fprintf(w, "Details:\t%s\n", d.Details)
+	}
+	if d.Type != "" {
+		fmt.Fprintf(w, "Type:\t%s\n", d.Type)
+	}
+	if d.Target != "" {
+		fmt.Fprintf(w, "Target:\t%s\n", d.Target)
+	}
+	if d.OSUser != "" || d.Type == "managed SSH" {
+		fmt.Fprintf(w, "OS user:\t%s\n", orDash(d.OSUser))
+	}
+	fmt.Fprintf(w, "User:\t%s\n", orDash(d.User))
+	fmt.Fprintf(w, "Host:\t%s\n", orDash(d.Host))
+	fmt.Fprintf(w, "Version:\t%s\n", orDash(d.Version))
+	if d.Created != nil {
+		fmt.Fprintf(w, "Created:\t%s\n", d.Created.Local().Format(time.DateTime))
+	}
+	if d.Expires != nil {
+		fmt.Fprintf(w, "Expires:\t%s\n", d.Expires.Local().Format(time.DateTime))
+	}
+	if d.SSHCommand != "" {
+		fmt.Fprintf(w, "SSH command:\t%s\n", d.SSHCommand)
+	}
+	_ = w.Flush()
+}
+
+// sessionListRow formats a session for the sessions list table.
+func sessionListRow(info sessionInfo) string {
+	created := ""
+	if info.Created != nil {
+		created = info.Created.Local().Format(time.DateTime)
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+		info.Cluster, orDash(info.Name), info.State, orDash(info.User), orDash(info.Host), orDash(info.Version), orDash(created), info.ID)
+}
+
+// orDash returns v, or "-" if it is empty.
+func orDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
+
+// configuredBastions returns the bastions of the configured clusters and
+// of the clusters in the discovery cache, limited to names if given. Each
+// bastion appears once.
+func configuredBastions(ctx context.Context, cfg *config.Config, clients map[string]*client.OCIClient, names []string) []sessionBastion {
+	wanted := func(name string) bool {
+		return len(names) == 0 || slices.Contains(names, name)
+	}
+
+	var bastions []sessionBastion
+	seen := make(map[string]bool)
+	add := func(clusterName, region, id string) {
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		bastions = append(bastions, sessionBastion{Cluster: clusterName, Region: region, ID: id})
+	}
+
+	for _, c := range cfg.Clusters {
+		if !wanted(c.ClusterName) {
+			continue
+		}
+
+		if c.BastionId == nil && c.CompartmentOcid != nil {
+			ociClient, err := regionClient(cfg, clients, c.ClusterName, c.Region)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Skipping cluster '%s'", c.ClusterName)
+				continue
+			}
+			id, err := cluster.GetClusterBastion(ctx, ociClient, c)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Skipping cluster '%s'", c.ClusterName)
+				continue
+			}
+			c.BastionId = id
+		}
+		if c.BastionId != nil {
+			add(c.ClusterName, c.Region, *c.BastionId)
+		}
+		for _, id := range c.FallbackBastionIds {
+			add(c.ClusterName, c.Region, id)
+		}
+	}
+
+	cache, err := discovery.OpenCache(cfg)
+	if err != nil {
+		return bastions
+	}
+	cached := cache.GetAllBastions()
+	clusterNames := make([]string, 0, len(cached))
+	for name := range cached {
+		clusterNames = append(clusterNames, name)
+	}
+	sort.Strings(clusterNames)
+	for _, name := range clusterNames {
+		if !wanted(name) {
+			continue
+		}
+		entry := cached[name]
+		add(name, entry.Region, entry.OCID)
+		for _, id := range entry.Fallbacks {
+			add(name, entry.Region, id)
+		}
+	}
+
+	return bastions
+}
+
+// regionClient returns an OCI client for region with the credentials of the
+// named cluster, creating it on first use. Clusters not in the config use
+// the top-level credentials.
+func regionClient(cfg *config.Config, clients map[string]*client.OCIClient, clusterName, region string) (*client.OCIClient, error) {
+	configured := config.FindClusterByName(cfg, clusterName)
+	key := fmt.Sprintf("%s/%s/%s", cfg.GetOCIAuthType(configured), cfg.GetOCIProfile(configured), region)
+	if c, ok := clients[key]; ok {
+		return c, nil
+	}
+	c, err := cluster.NewClusterOCIClient(cfg, configured, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCI client: %w", err)
+	}
+	clients[key] = c
+	return c, nil
+}
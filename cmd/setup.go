@@ -9,6 +9,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/ui"
 	"github.com/scotttball/tunatap/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -28,6 +29,9 @@ func init() {
 }
 
 func runSetup(cmd *cobra.Command, args []string) error {
+	if !ui.CanPrompt() {
+		return ui.PromptError("the setup wizard", "use 'tunatap setup init' to write a default config, then edit "+GetConfigFile())
+	}
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println("Welcome to tunatap setup!")
@@ -36,7 +40,7 @@ func runSetup(cmd *cobra.Command, args []string) error {
 
 	// Load existing config or create new one
 	cfgPath := GetConfigFile()
-	cfg, err := config.ReadConfig(cfgPath)
+	cfg, err := config.ReadConfigFile(cfgPath)
 	if err != nil {
 		log.Warn().Err(err).Msg("Could not read existing config, creating new one")
 		cfg = config.DefaultConfig()
@@ -281,8 +285,9 @@ var setupShowCmd = &cobra.Command{
 			}
 		}
 
-		fmt.Printf("\nTenancies: %d\n", len(cfg.Tenancies))
-		for name := range cfg.Tenancies {
+		tenancies := cfg.AllTenancies()
+		fmt.Printf("\nTenancies: %d\n", len(tenancies))
+		for name := range tenancies {
 			fmt.Printf("  - %s\n", name)
 		}
 
@@ -295,11 +300,15 @@ var setupAddClusterCmd = &cobra.Command{
 	Use:   "add-cluster",
 	Short: "Add a cluster to configuration",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.ReadConfig(GetConfigFile())
+		cfg, err := config.ReadConfigFile(GetConfigFile())
 		if err != nil {
 			return fmt.Errorf("failed to read config: %w", err)
 		}
 
+		if !ui.CanPrompt() {
+			return ui.PromptError("add-cluster", "add the cluster to the clusters list in "+GetConfigFile())
+		}
+
 		reader := bufio.NewReader(os.Stdin)
 		cluster, err := promptForCluster(reader)
 		if err != nil {
@@ -326,17 +335,18 @@ var setupAddTenancyCmd = &cobra.Command{
 		name := args[0]
 		ocid := args[1]
 
-		cfg, err := config.ReadConfig(GetConfigFile())
+		cfg, err := config.ReadConfigFile(GetConfigFile())
 		if err != nil {
 			return fmt.Errorf("failed to read config: %w", err)
 		}
 
-		if cfg.Tenancies == nil {
-			cfg.Tenancies = make(map[string]*string)
+		delete(cfg.Tenancies, name)
+		if t := findTenancy(cfg, name); t != nil {
+			t.ID = ocid
+		} else {
+			cfg.TenancyList = append(cfg.TenancyList, &config.TenantInfo{Name: name, ID: ocid})
 		}
 
-		cfg.Tenancies[name] = utils.StringPtr(ocid)
-
 		if err := config.SaveConfig(GetConfigFile(), cfg); err != nil {
 			return fmt.Errorf("failed to save config: %w", err)
 		}
@@ -346,6 +356,16 @@ var setupAddTenancyCmd = &cobra.Command{
 	},
 }
 
+// findTenancy returns the tenancy_list entry named name, or nil.
+func findTenancy(cfg *config.Config, name string) *config.TenantInfo {
+	for _, t := range cfg.TenancyList {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
 func init() {
 	setupCmd.AddCommand(setupInitCmd)
 	setupCmd.AddCommand(setupShowCmd)
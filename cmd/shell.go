@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	shellEndpointName string
+	shellBastionName  string
+	shellNoOCIAuth    bool
+	shellOCIProfile   string
+	shellRegionHint   string
+	shellNoCache      bool
+	shellPortStrategy string
+	shellProgram      string
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell [cluster]",
+	Short: "Start a shell with a tunnel and kubeconfig for a cluster",
+	Long: `Open a tunnel to the cluster and start your shell with KUBECONFIG pointing
+at it, for interactive work that outlives a single exec.
+
+Besides KUBECONFIG, the shell gets TUNATAP_CLUSTER and TUNATAP_PORT, which a
+prompt can show. The tunnel and the temporary kubeconfig are removed when the
+shell exits. The shell is $SHELL (%COMSPEC% on Windows) unless --shell is
+given.
+
+Examples:
+  tunatap shell my-cluster
+  tunatap shell my-cluster --shell zsh
+
+A prompt showing the cluster, for bash:
+  PS1='${TUNATAP_CLUSTER:+($TUNATAP_CLUSTER) }\u@\h:\w\$ '`,
+	RunE: runShell,
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+
+	shellCmd.Flags().StringVarP(&shellEndpointName, "endpoint", "e", "", "endpoint name (e.g., 'private', 'public')")
+	shellCmd.Flags().StringVarP(&shellBastionName, "bastion", "b", "", "bastion name or OCID to use")
+	shellCmd.Flags().BoolVar(&shellNoOCIAuth, "no-oci-auth", false, "disable OCI exec-auth in kubeconfig")
+	shellCmd.Flags().StringVar(&shellOCIProfile, "oci-profile", "", "OCI config profile for exec-auth (overrides config)")
+	shellCmd.Flags().StringVarP(&shellRegionHint, "region", "r", "", "region hint for cluster discovery (optional)")
+	shellCmd.Flags().BoolVar(&shellNoCache, "no-cache", false, "skip cache and force fresh discovery")
+	shellCmd.Flags().StringVar(&shellPortStrategy, "port-strategy", "", "what to do when the cluster's local_port is busy: increment, fail or takeover")
+	shellCmd.Flags().StringVar(&shellProgram, "shell", "", "shell to start (default: $SHELL)")
+}
+
+func runShell(cmd *cobra.Command, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if current := os.Getenv("TUNATAP_CLUSTER"); current != "" {
+		log.Warn().Msgf("Already in a tunatap shell for %s; starting a nested one", current)
+	}
+
+	code, err := runShellSession(cmd.Context(), name)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// runShellSession opens the tunnel, runs the shell until it exits and
+// closes the tunnel. It returns the shell's exit code.
+func runShellSession(ctx context.Context, name string) (int, error) {
+	cfg, cfgLoaded, err := readConfig(GetConfigFile())
+	if err != nil {
+		return 0, err
+	}
+
+	if err := configureHostKeys(cfg, true); err != nil {
+		return 0, err
+	}
+	if err := configureCredentials(ctx, cfg); err != nil {
+		return 0, err
+	}
+
+	selectedCluster, ociClient, err := resolveCluster(ctx, cfg, cfgLoaded, name, shellRegionHint, shellNoCache)
+	if err != nil {
+		return 0, err
+	}
+
+	if shellBastionName != "" {
+		selectedCluster.Bastion = &shellBastionName
+	}
+
+	endpoint := config.GetClusterEndpoint(selectedCluster, shellEndpointName)
+	if endpoint == nil {
+		return 0, fmt.Errorf("no endpoints configured for cluster '%s'", selectedCluster.ClusterName)
+	}
+
+	if ociClient == nil {
+		ociClient, err = createOCIClient(cfg, selectedCluster)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	// The generated kubeconfig needs a TCP port on localhost, so ignore any
+	// local_socket or bind_address
+	selectedCluster.LocalSocket = nil
+	selectedCluster.BindAddress = nil
+	if shellPortStrategy != "" {
+		selectedCluster.PortStrategy = &shellPortStrategy
+	}
+
+	if err := cluster.ValidateAndUpdateCluster(ctx, ociClient, selectedCluster, true, 0); err != nil {
+		return 0, fmt.Errorf("failed to validate cluster: %w", err)
+	}
+	defer claimLocalPort(selectedCluster, selectedCluster.ClusterName, "")()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Ctrl-C aborts connecting, but once the shell runs it belongs to the
+	// shell, which sees it too
+	shellStarted := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		for {
+			select {
+			case sig := <-sigChan:
+				select {
+				case <-shellStarted:
+					if sig == syscall.SIGINT {
+						continue
+					}
+				default:
+				}
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	tunnelErr := make(chan error, 1)
+	tunnelReady := make(chan int, 1)
+	go func() {
+		tunnelErr <- bastion.TunnelThroughBastionWithCallback(ctx, ociClient, cfg, selectedCluster, endpoint, func(port int) {
+			select {
+			case tunnelReady <- port:
+			default:
+			}
+		})
+	}()
+
+	var port int
+	select {
+	case port = <-tunnelReady:
+		log.Info().Msgf("Tunnel ready on port %d", port)
+	case err := <-tunnelErr:
+		return 0, fmt.Errorf("tunnel failed to start: %w", err)
+	case <-ctx.Done():
+		<-tunnelErr
+		return 0, fmt.Errorf("interrupted")
+	}
+	defer func() {
+		cancel()
+		<-tunnelErr
+	}()
+
+	kubeconfigPath, err := createTempKubeconfig(ctx, cfg, selectedCluster, port, shellNoOCIAuth, shellOCIProfile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create kubeconfig: %w", err)
+	}
+	defer os.Remove(kubeconfigPath)
+
+	program := shellProgram
+	if program == "" {
+		program = defaultShell()
+	}
+
+	shell := exec.CommandContext(ctx, program)
+	shell.Env = append(os.Environ(),
+		fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath),
+		fmt.Sprintf("TUNATAP_CLUSTER=%s", selectedCluster.ClusterName),
+		fmt.Sprintf("TUNATAP_PORT=%d", port),
+	)
+	shell.Stdin = os.Stdin
+	shell.Stdout = os.Stdout
+	shell.Stderr = os.Stderr
+
+	fmt.Fprintf(os.Stderr, "Tunnel to %s on localhost:%d. Exit the shell to close it.\n", selectedCluster.ClusterName, port)
+	close(shellStarted)
+	err = shell.Run()
+	fmt.Fprintf(os.Stderr, "Closing tunnel to %s.\n", selectedCluster.ClusterName)
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, fmt.Errorf("failed to start shell %s: %w", program, err)
+	}
+	return 0, nil
+}
+
+// defaultShell returns the user's shell from the environment.
+func defaultShell() string {
+	if utils.IsWindows() {
+		if comspec := os.Getenv("COMSPEC"); comspec != "" {
+			return comspec
+		}
+		return "cmd.exe"
+	}
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	return "/bin/sh"
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+var (
+	sshClusterName string
+	sshBastionName string
+	sshUser        string
+	sshPort        int
+	sshCompartment string
+	sshRegionHint  string
+	sshNoCache     bool
+)
+
+var sshCmd = &cobra.Command{
+	Use:   "ssh <instance> [-- command [args...]]",
+	Short: "Open a shell on a private compute instance",
+	Long: `Log in to a private compute instance through a managed SSH session on a
+cluster's bastion.
+
+The instance is given by OCID or by display name, which is looked up in the
+cluster's compartment (or --compartment). The instance needs the Bastion
+plugin of the Oracle Cloud Agent enabled. Without a command an interactive
+shell is started.
+
+Examples:
+  tunatap ssh my-instance -c prod-cluster
+  tunatap ssh ocid1.instance.oc1.iad.xxx -c prod-cluster --user ubuntu
+  tunatap ssh my-instance -c prod-cluster -- uptime`,
+	RunE: runSSH,
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(sshCmd)
+
+	sshCmd.Flags().StringVarP(&sshClusterName, "cluster", "c", "", "cluster whose bastion to use")
+	sshCmd.Flags().StringVarP(&sshBastionName, "bastion", "b", "", "bastion name or OCID to use")
+	sshCmd.Flags().StringVarP(&sshUser, "user", "u", "opc", "OS user to log in as")
+	sshCmd.Flags().IntVar(&sshPort, "port", 22, "SSH port on the instance")
+	sshCmd.Flags().StringVar(&sshCompartment, "compartment", "", "compartment OCID to look up the instance name in (default: the cluster's)")
+	sshCmd.Flags().StringVarP(&sshRegionHint, "region", "r", "", "region hint for cluster discovery (optional)")
+	sshCmd.Flags().BoolVar(&sshNoCache, "no-cache", false, "skip cache and force fresh discovery")
+}
+
+func runSSH(cmd *cobra.Command, args []string) error {
+	instanceRef := args[0]
+	command := args[1:]
+	if len(command) > 0 && command[0] == "--" {
+		command = command[1:]
+	}
+
+	cfg, cfgLoaded, err := readConfig(GetConfigFile())
+	if err != nil {
+		return err
+	}
+
+	if err := configureHostKeys(cfg, true); err != nil {
+		return err
+	}
+	if err := configureCredentials(cmd.Context(), cfg); err != nil {
+		return err
+	}
+
+	selectedCluster, ociClient, err := resolveCluster(cmd.Context(), cfg, cfgLoaded, sshClusterName, sshRegionHint, sshNoCache)
+	if err != nil {
+		return err
+	}
+	if sshBastionName != "" {
+		selectedCluster.Bastion = &sshBastionName
+	}
+
+	if ociClient == nil {
+		ociClient, err = createOCIClient(cfg, selectedCluster)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	// Only the bastion is used, so nothing listens on the local port
+	if err := cluster.ValidateAndUpdateCluster(cmd.Context(), ociClient, selectedCluster, true, 0); err != nil {
+		return fmt.Errorf("failed to validate cluster: %w", err)
+	}
+
+	compartmentID := sshCompartment
+	if compartmentID == "" && selectedCluster.CompartmentOcid != nil {
+		compartmentID = *selectedCluster.CompartmentOcid
+	}
+	instanceID, err := resolveInstanceID(cmd.Context(), ociClient, compartmentID, instanceRef)
+	if err != nil {
+		return err
+	}
+
+	target := &bastion.ManagedSSHTarget{InstanceID: instanceID, User: sshUser, Port: sshPort}
+	sshClient, _, err := bastion.DialManagedSSH(cmd.Context(), ociClient, cfg, selectedCluster, target)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+
+	err = runRemoteSession(sshClient, command)
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		sshClient.Close()
+		os.Exit(exitErr.ExitStatus())
+	}
+	return err
+}
+
+// resolveInstanceID returns ref if it is an instance OCID, or the OCID of
+// the running instance named ref in compartmentID.
+func resolveInstanceID(ctx context.Context, ociClient client.OCIClientInterface, compartmentID, ref string) (string, error) {
+	if utils.IsInstanceOCID(ref) {
+		return ref, nil
+	}
+	if compartmentID == "" {
+		return "", fmt.Errorf("cannot look up instance '%s' without a compartment; use --compartment or an instance OCID", ref)
+	}
+
+	instances, err := ociClient.ListInstancesByName(ctx, compartmentID, ref)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(instances) {
+	case 0:
+		return "", fmt.Errorf("no running instance named '%s' in compartment %s", ref, compartmentID)
+	case 1:
+		return *instances[0].Id, nil
+	default:
+		ids := make([]string, len(instances))
+		for i, instance := range instances {
+			ids[i] = *instance.Id
+		}
+		return "", fmt.Errorf("multiple running instances named '%s'; use an OCID instead:\n  %s", ref, strings.Join(ids, "\n  "))
+	}
+}
+
+// runRemoteSession runs command on sshClient, or an interactive shell if
+// command is empty, connected to this process's stdio.
+func runRemoteSession(sshClient *ssh.Client, command []string) error {
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+		if err := session.RequestPty(termType, height, width, ssh.TerminalModes{}); err != nil {
+			return fmt.Errorf("failed to request terminal: %w", err)
+		}
+
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("failed to put terminal in raw mode: %w", err)
+		}
+		defer term.Restore(fd, oldState)
+
+		stop := watchWindowSize(session, fd)
+		defer stop()
+	}
+
+	if len(command) == 0 {
+		if err := session.Shell(); err != nil {
+			return fmt.Errorf("failed to start shell: %w", err)
+		}
+		return session.Wait()
+	}
+	return session.Run(strings.Join(command, " "))
+}
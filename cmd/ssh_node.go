@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/koki-develop/go-fzf"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/ui"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	sshNodeBastionName string
+	sshNodeUser        string
+	sshNodePort        int
+	sshNodeRegionHint  string
+	sshNodeNoCache     bool
+	sshNodePortForward bool
+)
+
+var sshNodeCmd = &cobra.Command{
+	Use:   "ssh-node <cluster> [node] [-- command [args...]]",
+	Short: "Open a shell on a cluster's worker node",
+	Long: `Log in to a worker node of a cluster's node pools through the cluster's
+bastion, for example to debug the kubelet.
+
+The node is given by name, private IP or instance OCID. Without a node, the
+nodes are listed to choose from. By default a managed SSH session is used,
+which needs the Bastion plugin of the Oracle Cloud Agent on the node; with
+--port-forward a port forwarding session to the node's SSH port is used
+instead, and the node must accept your SSH key.
+
+Examples:
+  tunatap ssh-node prod-cluster
+  tunatap ssh-node prod-cluster 10.0.10.5
+  tunatap ssh-node prod-cluster 10.0.10.5 --port-forward
+  tunatap ssh-node prod-cluster 10.0.10.5 -- sudo journalctl -u kubelet -n 100`,
+	RunE: runSSHNode,
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(sshNodeCmd)
+
+	sshNodeCmd.Flags().StringVarP(&sshNodeBastionName, "bastion", "b", "", "bastion name or OCID to use")
+	sshNodeCmd.Flags().StringVarP(&sshNodeUser, "user", "u", "opc", "OS user to log in as")
+	sshNodeCmd.Flags().IntVar(&sshNodePort, "port", 22, "SSH port on the node")
+	sshNodeCmd.Flags().StringVarP(&sshNodeRegionHint, "region", "r", "", "region hint for cluster discovery (optional)")
+	sshNodeCmd.Flags().BoolVar(&sshNodeNoCache, "no-cache", false, "skip cache and force fresh discovery")
+	sshNodeCmd.Flags().BoolVar(&sshNodePortForward, "port-forward", false, "use a port forwarding session instead of managed SSH")
+}
+
+func runSSHNode(cmd *cobra.Command, args []string) error {
+	clusterName := args[0]
+	nodeRef := ""
+	rest := args[1:]
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		if dash > 2 {
+			return fmt.Errorf("unexpected arguments before --: %s", strings.Join(args[2:dash], " "))
+		}
+		if dash == 2 {
+			nodeRef = args[1]
+		}
+		rest = args[dash:]
+	} else if len(rest) > 0 {
+		nodeRef, rest = rest[0], rest[1:]
+	}
+	command := rest
+
+	cfg, cfgLoaded, err := readConfig(GetConfigFile())
+	if err != nil {
+		return err
+	}
+
+	if err := configureHostKeys(cfg, true); err != nil {
+		return err
+	}
+	if err := configureCredentials(cmd.Context(), cfg); err != nil {
+		return err
+	}
+
+	selectedCluster, ociClient, err := resolveCluster(cmd.Context(), cfg, cfgLoaded, clusterName, sshNodeRegionHint, sshNodeNoCache)
+	if err != nil {
+		return err
+	}
+	if sshNodeBastionName != "" {
+		selectedCluster.Bastion = &sshNodeBastionName
+	}
+
+	if ociClient == nil {
+		ociClient, err = createOCIClient(cfg, selectedCluster)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	// Only the bastion is used, so nothing listens on the local port
+	if err := cluster.ValidateAndUpdateCluster(cmd.Context(), ociClient, selectedCluster, true, 0); err != nil {
+		return fmt.Errorf("failed to validate cluster: %w", err)
+	}
+	if selectedCluster.CompartmentOcid == nil || selectedCluster.Ocid == nil {
+		return fmt.Errorf("cluster %s has no OCID or compartment to list node pools in", selectedCluster.ClusterName)
+	}
+
+	nodes, err := discovery.NewDiscoverer(ociClient, nil).DiscoverNodes(cmd.Context(), *selectedCluster.CompartmentOcid, *selectedCluster.Ocid)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("cluster %s has no active worker nodes", selectedCluster.ClusterName)
+	}
+
+	node, err := selectNode(nodes, nodeRef)
+	if err != nil {
+		return err
+	}
+
+	var sshClient *ssh.Client
+	if sshNodePortForward {
+		sshClient, _, err = bastion.DialForwardedSSH(cmd.Context(), ociClient, cfg, selectedCluster, node.PrivateIP, sshNodePort, sshNodeUser)
+	} else {
+		target := &bastion.ManagedSSHTarget{InstanceID: node.InstanceID, User: sshNodeUser, Port: sshNodePort}
+		sshClient, _, err = bastion.DialManagedSSH(cmd.Context(), ociClient, cfg, selectedCluster, target)
+	}
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+
+	err = runRemoteSession(sshClient, command)
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		sshClient.Close()
+		os.Exit(exitErr.ExitStatus())
+	}
+	return err
+}
+
+// selectNode returns the node ref names, or lets the user pick one when ref
+// is empty. Without a terminal to pick on, the nodes are listed in the error.
+func selectNode(nodes []*discovery.DiscoveredNode, ref string) (*discovery.DiscoveredNode, error) {
+	if ref != "" {
+		node, err := discovery.FindNode(nodes, ref)
+		if err != nil {
+			return nil, fmt.Errorf("%w; nodes:\n%s", err, formatNodes(nodes))
+		}
+		return node, nil
+	}
+
+	if !ui.CanPrompt() {
+		return nil, ui.PromptError("choosing a node", "name one of these nodes:\n"+formatNodes(nodes))
+	}
+
+	f, err := fzf.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create selector: %w", err)
+	}
+	idxs, err := f.Find(nodes, func(i int) string { return nodeChoice(nodes[i]) })
+	if err != nil || len(idxs) == 0 {
+		return nil, exitcode.Wrap(exitcode.Cancelled, fmt.Errorf("no node selected"))
+	}
+	return nodes[idxs[0]], nil
+}
+
+// nodeChoice describes a node on one line.
+func nodeChoice(n *discovery.DiscoveredNode) string {
+	return fmt.Sprintf("%s (%s, pool: %s, %s)", n.Name, n.PrivateIP, n.NodePool, n.State)
+}
+
+func formatNodes(nodes []*discovery.DiscoveredNode) string {
+	lines := make([]string, len(nodes))
+	for i, n := range nodes {
+		lines[i] = "  - " + nodeChoice(n)
+	}
+	return strings.Join(lines, "\n")
+}
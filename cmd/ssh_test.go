@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+func TestResolveInstanceID(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	compartment := "ocid1.compartment.oc1..test"
+	for _, inst := range []struct{ id, name string }{
+		{"ocid1.instance.oc1.iad.web", "web"},
+		{"ocid1.instance.oc1.iad.db1", "db"},
+		{"ocid1.instance.oc1.iad.db2", "db"},
+	} {
+		mock.AddInstance(compartment, core.Instance{Id: &inst.id, DisplayName: &inst.name})
+	}
+	ctx := context.Background()
+
+	id, err := resolveInstanceID(ctx, mock, compartment, "web")
+	if err != nil {
+		t.Fatalf("resolveInstanceID() error = %v", err)
+	}
+	if id != "ocid1.instance.oc1.iad.web" {
+		t.Errorf("resolveInstanceID() = %q, want %q", id, "ocid1.instance.oc1.iad.web")
+	}
+
+	ocid := "ocid1.instance.oc1.iad.other"
+	if id, err := resolveInstanceID(ctx, mock, "", ocid); err != nil || id != ocid {
+		t.Errorf("resolveInstanceID() = %q, %v, want the OCID unchanged", id, err)
+	}
+
+	if _, err := resolveInstanceID(ctx, mock, compartment, "db"); err == nil {
+		t.Error("resolveInstanceID() should error when the name is ambiguous")
+	}
+	if _, err := resolveInstanceID(ctx, mock, compartment, "missing"); err == nil {
+		t.Error("resolveInstanceID() should error when no instance has the name")
+	}
+	if _, err := resolveInstanceID(ctx, mock, "", "web"); err == nil {
+		t.Error("resolveInstanceID() should error for a name without a compartment")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package cmd
+
+import "golang.org/x/crypto/ssh"
+
+// watchWindowSize is a no-op on Windows, which has no resize signal.
+func watchWindowSize(_ *ssh.Session, _ int) func() {
+	return func() {}
+}
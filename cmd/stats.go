@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/health"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsJSON           bool
+	statsHealthEndpoint string
+)
+
+// statsFetchTimeout bounds how long fetching stats from a health server may take.
+const statsFetchTimeout = 3 * time.Second
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show per-tunnel bandwidth and connection statistics",
+	Long: `Display traffic and connection statistics for running tunnels.
+
+Statistics include bytes transferred in each direction, active and total
+forwarded connections, connection durations, and SSH connection pool
+utilization. Use them to tell whether slowness comes from the tunnel
+(saturated pool, long-lived connections) or from the cluster itself.
+
+Tunnels owned by the background daemon are always included. Foreground
+tunnels are included when they run with a health endpoint, either from
+'health_endpoint' in config or --health-endpoint.`,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "output as JSON")
+	statsCmd.Flags().StringVar(&statsHealthEndpoint, "health-endpoint", "", "health server address of a foreground tunnel (default: from config)")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	var tunnels []*health.TunnelStatus
+
+	managed, err := daemon.NewClient(daemon.DefaultSocketPath()).Stats()
+	if err != nil {
+		log.Debug().Err(err).Msg("Daemon stats unavailable")
+	}
+	tunnels = append(tunnels, managed...)
+
+	endpoint := statsHealthEndpoint
+	if endpoint == "" {
+		if cfg, err := config.ReadConfig(GetConfigFile()); err == nil {
+			endpoint = cfg.HealthEndpoint
+		}
+	}
+	if endpoint != "" {
+		foreground, err := fetchHealthStats(endpoint)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Health endpoint %s unavailable", endpoint)
+		}
+		tunnels = append(tunnels, foreground...)
+	}
+
+	sort.Slice(tunnels, func(i, j int) bool {
+		if tunnels[i].Cluster != tunnels[j].Cluster {
+			return tunnels[i].Cluster < tunnels[j].Cluster
+		}
+		return tunnels[i].LocalPort < tunnels[j].LocalPort
+	})
+
+	if statsJSON {
+		if tunnels == nil {
+			tunnels = []*health.TunnelStatus{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tunnels)
+	}
+
+	if len(tunnels) == 0 {
+		fmt.Println("No running tunnels with statistics")
+		fmt.Println("Start one with 'tunatap connect --detach' or set health_endpoint in config")
+		return nil
+	}
+
+	return outputStatsTable(tunnels)
+}
+
+// fetchHealthStats reads tunnel statistics from a tunatap health server.
+func fetchHealthStats(addr string) ([]*health.TunnelStatus, error) {
+	url := addr
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+
+	httpClient := &http.Client{Timeout: statsFetchTimeout}
+	resp, err := httpClient.Get(strings.TrimSuffix(url, "/") + "/health")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query health server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// An unhealthy tunnel still reports its statistics with a 503
+	var status health.HealthStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode health response: %w", err)
+	}
+	return status.Tunnels, nil
+}
+
+func outputStatsTable(tunnels []*health.TunnelStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "CLUSTER\tLOCAL PORT\tHEALTHY\tCONNS\tTOTAL\tIN\tOUT\tAVG CONN\tMAX CONN\tPOOL")
+	for _, t := range tunnels {
+		conns, total, in, out, avg, longest := "-", "-", "-", "-", "-", "-"
+		if t.Traffic != nil {
+			conns = fmt.Sprintf("%d", t.Traffic.ActiveConnections)
+			total = fmt.Sprintf("%d", t.Traffic.TotalConnections)
+			in = utils.FormatBytes(t.Traffic.BytesIn)
+			out = utils.FormatBytes(t.Traffic.BytesOut)
+			avg = formatDuration(t.Traffic.AvgConnectionDuration)
+			longest = formatDuration(t.Traffic.MaxConnectionDuration)
+		}
+
+		pool := "-"
+		if t.Pool != nil {
+			pool = fmt.Sprintf("%d uses / %d conns", t.Pool.ActiveUses, t.Pool.Size)
+		}
+
+		healthy := "yes"
+		if !t.Healthy {
+			healthy = "no"
+		}
+
+		fmt.Fprintf(w, "%s\t:%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.Cluster, t.LocalPort, healthy, conns, total, in, out, avg, longest, pool)
+	}
+
+	fmt.Fprintln(w)
+	return w.Flush()
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/health"
+)
+
+func TestFetchHealthStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		// Unhealthy tunnels are still reported
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(&health.HealthStatus{
+			Tunnels: []*health.TunnelStatus{
+				{Cluster: "prod", LocalPort: 6443, Traffic: &health.TrafficStatus{BytesIn: 2048}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	tunnels, err := fetchHealthStats(server.URL)
+	if err != nil {
+		t.Fatalf("fetchHealthStats() error = %v", err)
+	}
+	if len(tunnels) != 1 {
+		t.Fatalf("fetchHealthStats() returned %d tunnels, want 1", len(tunnels))
+	}
+	if tunnels[0].Traffic == nil || tunnels[0].Traffic.BytesIn != 2048 {
+		t.Errorf("Traffic = %+v, want BytesIn 2048", tunnels[0].Traffic)
+	}
+}
+
+func TestStatsCommandFlags(t *testing.T) {
+	if statsCmd.Flags().Lookup("json") == nil {
+		t.Error("--json flag not found")
+	}
+	if statsCmd.Flags().Lookup("health-endpoint") == nil {
+		t.Error("--health-endpoint flag not found")
+	}
+}
@@ -1,163 +1,281 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"slices"
 	"sort"
+	"strconv"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
-	"github.com/scotttball/tunatap/internal/audit"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/health"
+	"github.com/scotttball/tunatap/internal/output"
 	"github.com/spf13/cobra"
 )
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show active tunnel status",
-	Long: `Display information about currently active SSH tunnels.
+	Long: `Display the tunnels running in every tunatap process: their local ports,
+bastion sessions, uptime, when their session was last refreshed, and health.
 
-This command parses audit logs to find tunnels that have connected
-but not yet disconnected, showing their current status and uptime.`,
+Each tunatap process running tunnels publishes their status from its health
+registry to a file under ~/.tunatap/status every few seconds. Tunnels owned by
+the background daemon ('connect --detach') also show the daemon's state of
+them, such as reconnecting.
+
+Examples:
+  tunatap status
+  tunatap status -v
+  tunatap status --json
+  tunatap status --watch`,
+	Args: cobra.NoArgs,
 	RunE: runStatus,
 }
 
 var (
-	statusJSON    bool
-	statusVerbose bool
+	statusJSON          bool
+	statusOutput        string
+	statusVerbose       bool
+	statusWatch         bool
+	statusWatchInterval time.Duration
 )
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
 	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "output as JSON")
+	addOutputFlag(statusCmd, &statusOutput)
 	statusCmd.Flags().BoolVarP(&statusVerbose, "verbose", "v", false, "show additional details")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "refresh the status until interrupted")
+	statusCmd.Flags().DurationVar(&statusWatchInterval, "interval", 2*time.Second, "how often --watch refreshes the status")
 }
 
 // ActiveTunnel represents an active tunnel connection.
 type ActiveTunnel struct {
-	SessionID   string        `json:"session_id"`
-	ClusterName string        `json:"cluster_name"`
-	Region      string        `json:"region,omitempty"`
-	LocalPort   int           `json:"local_port"`
-	RemoteHost  string        `json:"remote_host"`
-	RemotePort  int           `json:"remote_port"`
-	BastionID   string        `json:"bastion_id,omitempty"`
-	StartTime   time.Time     `json:"start_time"`
-	Uptime      time.Duration `json:"uptime_ns"`
-	UptimeStr   string        `json:"uptime"`
+	SessionID        string        `json:"session_id" yaml:"session_id"`
+	ClusterName      string        `json:"cluster_name" yaml:"cluster_name"`
+	Region           string        `json:"region,omitempty" yaml:"region,omitempty"`
+	LocalPort        int           `json:"local_port" yaml:"local_port"`
+	RemoteHost       string        `json:"remote_host" yaml:"remote_host"`
+	RemotePort       int           `json:"remote_port" yaml:"remote_port"`
+	BastionID        string        `json:"bastion_id,omitempty" yaml:"bastion_id,omitempty"`
+	BastionSessionID string        `json:"bastion_session_id,omitempty" yaml:"bastion_session_id,omitempty"`
+	PID              int           `json:"pid,omitempty" yaml:"pid,omitempty"`
+	StartTime        time.Time     `json:"start_time" yaml:"start_time"`
+	Uptime           time.Duration `json:"uptime_ns" yaml:"-"`
+	UptimeStr        string        `json:"uptime" yaml:"uptime"`
+	LastRefresh      *time.Time    `json:"last_refresh,omitempty" yaml:"last_refresh,omitempty"`
+	Healthy          *bool         `json:"healthy,omitempty" yaml:"healthy,omitempty"`
+	LastError        string        `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	Reconnects       int64         `json:"reconnects" yaml:"reconnects"`
+	Mode             string        `json:"mode" yaml:"mode"`
+	State            string        `json:"state,omitempty" yaml:"state,omitempty"`
 }
 
+const (
+	modeForeground = "foreground"
+	modeDaemon     = "daemon"
+)
+
 func runStatus(cmd *cobra.Command, args []string) error {
-	logDir := audit.DefaultLogDir()
+	format, err := outputFormat(statusOutput, statusJSON)
+	if err != nil {
+		return err
+	}
+	if !statusWatch {
+		return printStatus(format)
+	}
+	if statusWatchInterval < 500*time.Millisecond {
+		return fmt.Errorf("--interval must be at least 500ms")
+	}
 
-	// Query recent events to find active tunnels
-	// Look at events from the last 24 hours
-	since := time.Now().Add(-24 * time.Hour)
-	q := audit.Query{
-		StartTime: &since,
-		Limit:     1000, // Reasonable limit
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(statusWatchInterval)
+	defer ticker.Stop()
+	for {
+		if !format.Structured() {
+			// Clear the screen and move to its top left
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Every %s: tunatap status\t%s\n\n", statusWatchInterval, time.Now().Format(time.TimeOnly))
+		}
+		if err := printStatus(format); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
 	}
+}
 
-	events, err := audit.QueryLogs(logDir, q)
+// printStatus prints the active tunnels once, in format.
+func printStatus(format output.Format) error {
+	tunnels, err := activeTunnels()
 	if err != nil {
-		return fmt.Errorf("failed to query audit logs: %w", err)
+		return err
 	}
 
-	// Find active tunnels (connects without matching disconnects)
-	activeTunnels := findActiveTunnels(events)
+	if format.Structured() {
+		return output.Write(os.Stdout, format, tunnels)
+	}
 
-	if len(activeTunnels) == 0 {
-		if statusJSON {
-			fmt.Println("[]")
-		} else {
-			fmt.Println("No active tunnels")
-		}
+	if len(tunnels) == 0 {
+		fmt.Println("No active tunnels")
 		return nil
 	}
 
-	if statusJSON {
-		return outputJSON(activeTunnels)
+	return outputTable(tunnels)
+}
+
+// activeTunnels returns the tunnels that are up: those the tunatap
+// processes running them publish, with the daemon's state of its own.
+func activeTunnels() ([]ActiveTunnel, error) {
+	published, err := health.ReadPublished(health.DefaultStatusDir())
+	if err != nil {
+		return nil, err
+	}
+	return mergeDaemonTunnels(publishedTunnels(published, time.Now()), queryDaemonTunnels()), nil
+}
+
+// publishedTunnels returns the tunnels of the published statuses, newest
+// first.
+func publishedTunnels(published []*health.ProcessStatus, now time.Time) []ActiveTunnel {
+	tunnels := []ActiveTunnel{}
+	for _, p := range published {
+		for _, t := range p.Tunnels {
+			healthy := t.Healthy
+			uptime := now.Sub(t.StartTime)
+			tunnels = append(tunnels, ActiveTunnel{
+				SessionID:        t.ID,
+				ClusterName:      t.Cluster,
+				Region:           t.Region,
+				LocalPort:        t.LocalPort,
+				RemoteHost:       t.RemoteHost,
+				RemotePort:       t.RemotePort,
+				BastionSessionID: t.SessionID,
+				PID:              p.PID,
+				StartTime:        t.StartTime,
+				Uptime:           uptime,
+				UptimeStr:        formatDuration(uptime),
+				LastRefresh:      t.LastRefresh,
+				Healthy:          &healthy,
+				LastError:        t.LastError,
+				Reconnects:       t.Reconnects,
+				Mode:             modeForeground,
+			})
+		}
 	}
 
-	return outputTable(activeTunnels)
+	// Sort by start time (newest first)
+	sort.Slice(tunnels, func(i, j int) bool {
+		return tunnels[i].StartTime.After(tunnels[j].StartTime)
+	})
+
+	return tunnels
 }
 
-// findActiveTunnels finds tunnels that have connected but not disconnected.
-func findActiveTunnels(events []audit.AuditEvent) []ActiveTunnel {
-	// Track connect events by session ID
-	connects := make(map[string]*audit.AuditEvent)
+// queryDaemonTunnels returns the tunnels managed by the daemon, or nil if
+// the daemon is not running.
+func queryDaemonTunnels() []*daemon.TunnelInfo {
+	tunnels, err := daemon.NewClient(daemon.DefaultSocketPath()).Status()
+	if err != nil {
+		log.Debug().Err(err).Msg("Daemon status unavailable")
+		return nil
+	}
+	return tunnels
+}
 
-	// Process events in order
-	for i := range events {
-		e := &events[i]
-		switch e.EventType {
-		case audit.EventTypeConnect:
-			connects[e.SessionID] = e
-		case audit.EventTypeDisconnect, audit.EventTypeError:
-			// Remove from active if we see a disconnect or error
-			delete(connects, e.SessionID)
-		}
+// mergeDaemonTunnels adds the daemon's state to the tunnels it manages,
+// which the daemon publishes like any other process, and adds those it has
+// not published yet, such as while they connect.
+func mergeDaemonTunnels(tunnels []ActiveTunnel, managed []*daemon.TunnelInfo) []ActiveTunnel {
+	if len(managed) == 0 {
+		return tunnels
 	}
 
-	// Convert remaining connects to active tunnels
-	tunnels := make([]ActiveTunnel, 0, len(connects))
+	byKey := make(map[string]int, len(tunnels))
+	for i, t := range tunnels {
+		byKey[fmt.Sprintf("%s:%d", t.ClusterName, t.LocalPort)] = i
+	}
 	now := time.Now()
+	merged := slices.Clone(tunnels)
 
-	for _, e := range connects {
-		uptime := now.Sub(e.Timestamp)
-		tunnels = append(tunnels, ActiveTunnel{
-			SessionID:   e.SessionID,
-			ClusterName: e.ClusterName,
-			Region:      e.Region,
-			LocalPort:   e.LocalPort,
-			RemoteHost:  e.RemoteHost,
-			RemotePort:  e.RemotePort,
-			BastionID:   e.BastionID,
-			StartTime:   e.Timestamp,
+	for _, m := range managed {
+		if i, ok := byKey[fmt.Sprintf("%s:%d", m.Cluster, m.LocalPort)]; ok {
+			merged[i].Mode = modeDaemon
+			merged[i].State = m.State
+			continue
+		}
+		uptime := now.Sub(m.StartTime)
+		merged = append(merged, ActiveTunnel{
+			ClusterName: m.Cluster,
+			LocalPort:   m.LocalPort,
+			RemoteHost:  m.RemoteHost,
+			RemotePort:  m.RemotePort,
+			StartTime:   m.StartTime,
 			Uptime:      uptime,
 			UptimeStr:   formatDuration(uptime),
+			LastError:   m.LastError,
+			Mode:        modeDaemon,
+			State:       m.State,
 		})
 	}
 
-	// Sort by start time (newest first)
-	sort.Slice(tunnels, func(i, j int) bool {
-		return tunnels[i].StartTime.After(tunnels[j].StartTime)
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].StartTime.After(merged[j].StartTime)
 	})
 
-	return tunnels
+	return merged
 }
 
-func outputJSON(tunnels []ActiveTunnel) error {
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(tunnels)
+// displayMode returns the MODE column value for a tunnel.
+func displayMode(t ActiveTunnel) string {
+	if t.State != "" && t.State != daemon.StateReady {
+		return fmt.Sprintf("%s (%s)", t.Mode, t.State)
+	}
+	return t.Mode
 }
 
 func outputTable(tunnels []ActiveTunnel) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
+	now := time.Now()
 	if statusVerbose {
-		fmt.Fprintln(w, "CLUSTER\tLOCAL PORT\tREMOTE\tUPTIME\tSESSION ID\tSTARTED")
+		fmt.Fprintln(w, "CLUSTER\tLOCAL PORT\tREMOTE\tUPTIME\tHEALTH\tREFRESHED\tMODE\tPID\tSESSION ID\tSTARTED")
 		for _, t := range tunnels {
-			fmt.Fprintf(w, "%s\t:%d\t%s:%d\t%s\t%s\t%s\n",
+			fmt.Fprintf(w, "%s\t:%d\t%s:%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 				t.ClusterName,
 				t.LocalPort,
 				t.RemoteHost,
 				t.RemotePort,
 				t.UptimeStr,
-				truncateSessionID(t.SessionID),
+				displayHealth(t),
+				displayRefresh(t, now),
+				displayMode(t),
+				displayPID(t),
+				orDash(truncateSessionID(t.BastionSessionID)),
 				t.StartTime.Local().Format("15:04:05"),
 			)
 		}
 	} else {
-		fmt.Fprintln(w, "CLUSTER\tLOCAL PORT\tREMOTE\tUPTIME")
+		fmt.Fprintln(w, "CLUSTER\tLOCAL PORT\tREMOTE\tUPTIME\tHEALTH\tREFRESHED\tMODE")
 		for _, t := range tunnels {
-			fmt.Fprintf(w, "%s\t:%d\t%s:%d\t%s\n",
+			fmt.Fprintf(w, "%s\t:%d\t%s:%d\t%s\t%s\t%s\t%s\n",
 				t.ClusterName,
 				t.LocalPort,
 				t.RemoteHost,
 				t.RemotePort,
 				t.UptimeStr,
+				displayHealth(t),
+				displayRefresh(t, now),
+				displayMode(t),
 			)
 		}
 	}
@@ -169,6 +287,37 @@ func outputTable(tunnels []ActiveTunnel) error {
 	return nil
 }
 
+// displayHealth returns the HEALTH column value for a tunnel.
+func displayHealth(t ActiveTunnel) string {
+	switch {
+	case t.Healthy == nil:
+		return "-"
+	case *t.Healthy:
+		return "healthy"
+	case t.Reconnects > 0:
+		return fmt.Sprintf("unhealthy (%d reconnects)", t.Reconnects)
+	default:
+		return "unhealthy"
+	}
+}
+
+// displayRefresh returns the REFRESHED column value for a tunnel: how long
+// ago its bastion session was last checked.
+func displayRefresh(t ActiveTunnel, now time.Time) string {
+	if t.LastRefresh == nil {
+		return "-"
+	}
+	return formatDuration(now.Sub(*t.LastRefresh)) + " ago"
+}
+
+// displayPID returns the PID column value for a tunnel.
+func displayPID(t ActiveTunnel) string {
+	if t.PID == 0 {
+		return "-"
+	}
+	return strconv.Itoa(t.PID)
+}
+
 // formatDuration formats a duration in a human-readable way.
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
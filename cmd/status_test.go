@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/health"
+)
+
+func TestStatusTunnels(t *testing.T) {
+	now := time.Now()
+	refreshed := now.Add(-30 * time.Second)
+	published := []*health.ProcessStatus{
+		{PID: 100, Tunnels: []*health.TunnelStatus{
+			{ID: "a", Cluster: "prod", LocalPort: 6443, Healthy: true, StartTime: now.Add(-time.Hour), SessionID: "ocid1.bastionsession.oc1..a", LastRefresh: &refreshed},
+		}},
+		{PID: 200, Tunnels: []*health.TunnelStatus{
+			{ID: "b", Cluster: "staging", LocalPort: 7443, Healthy: false, Reconnects: 2, StartTime: now.Add(-time.Minute)},
+		}},
+	}
+
+	tunnels := publishedTunnels(published, now)
+	if len(tunnels) != 2 || tunnels[0].ClusterName != "staging" || tunnels[1].PID != 100 {
+		t.Fatalf("publishedTunnels() = %+v, want both tunnels newest first", tunnels)
+	}
+	if got := displayHealth(tunnels[0]); got != "unhealthy (2 reconnects)" {
+		t.Errorf("displayHealth() = %q", got)
+	}
+	if got := displayRefresh(tunnels[1], now); got != "30s ago" {
+		t.Errorf("displayRefresh() = %q", got)
+	}
+
+	merged := mergeDaemonTunnels(tunnels, []*daemon.TunnelInfo{
+		{Cluster: "prod", LocalPort: 6443, State: daemon.StateReady, StartTime: now.Add(-time.Hour)},
+		{Cluster: "dev", LocalPort: 8443, State: "connecting", StartTime: now},
+	})
+	if len(merged) != 3 {
+		t.Fatalf("mergeDaemonTunnels() = %+v, want 3 tunnels", merged)
+	}
+	if merged[0].ClusterName != "dev" || merged[0].Healthy != nil || displayHealth(merged[0]) != "-" {
+		t.Errorf("daemon tunnel not published yet = %+v", merged[0])
+	}
+	if prod := merged[2]; prod.Mode != modeDaemon || prod.State != daemon.StateReady || prod.PID != 100 || prod.BastionSessionID == "" {
+		t.Errorf("published daemon tunnel = %+v, want the daemon's state with its health", prod)
+	}
+	if tunnels[1].Mode != modeForeground {
+		t.Error("mergeDaemonTunnels() should not change the tunnels it is given")
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var stopAll bool
+
+var stopCmd = &cobra.Command{
+	Use:   "stop [cluster]",
+	Short: "Stop a background tunnel",
+	Long: `Stop a tunnel that was started with 'tunatap connect --detach'.
+
+Examples:
+  tunatap stop my-cluster   # Stop the tunnel to my-cluster
+  tunatap stop --all        # Stop every background tunnel`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStop,
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+	stopCmd.Flags().BoolVar(&stopAll, "all", false, "stop all background tunnels")
+}
+
+func runStop(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !stopAll {
+		return fmt.Errorf("specify a cluster name or use --all")
+	}
+
+	client := daemon.NewClient(daemon.DefaultSocketPath())
+
+	if !stopAll {
+		if err := client.Stop(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Stopped tunnel to %s\n", args[0])
+		return nil
+	}
+
+	tunnels, err := client.Status()
+	if err != nil {
+		if errors.Is(err, daemon.ErrNotRunning) {
+			fmt.Println("No background tunnels")
+			return nil
+		}
+		return err
+	}
+
+	if len(tunnels) == 0 {
+		fmt.Println("No background tunnels")
+		return nil
+	}
+
+	for _, t := range tunnels {
+		if err := client.Stop(t.Cluster); err != nil {
+			fmt.Printf("Failed to stop %s: %v\n", t.Cluster, err)
+			continue
+		}
+		fmt.Printf("Stopped tunnel to %s\n", t.Cluster)
+	}
+
+	return nil
+}
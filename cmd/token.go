@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/kubeconfig"
+	"github.com/scotttball/tunatap/internal/tokencache"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenClusterID  string
+	tokenRegion     string
+	tokenOCIProfile string
+	tokenAuthType   string
+	tokenFlush      bool
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Print an ExecCredential with a token for an OKE cluster",
+	Long: `Print a client.authentication.k8s.io ExecCredential holding a short-lived
+token for the Kubernetes API server of an OKE cluster, as
+'oci ce cluster generate-token' does, without needing the OCI CLI.
+
+Kubeconfigs generated by tunatap run this command for kubectl; it is not
+meant to be run by hand. The token is signed with the OCI credentials of
+--profile, or of the cluster in the config with the OCID --cluster-id.
+
+Tokens are cached in ~/.tunatap/tokens until shortly before they expire,
+so kubectl commands in a row reuse one. --flush removes the cached tokens,
+such as after switching OCI credentials.
+
+Examples:
+  tunatap token --cluster-id ocid1.cluster.oc1.iad.aaaa --region us-ashburn-1
+  tunatap token --flush`,
+	Args:          cobra.NoArgs,
+	Hidden:        true,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runToken,
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+
+	f := tokenCmd.Flags()
+	f.StringVar(&tokenClusterID, "cluster-id", "", "OCID of the cluster")
+	f.StringVar(&tokenRegion, "region", "", "region of the cluster (default: that of the cluster OCID)")
+	f.StringVar(&tokenOCIProfile, "profile", "", "OCI config profile to sign the token with")
+	f.StringVar(&tokenAuthType, "auth-type", "", "OCI authentication type (default: that of the cluster, or auto-detected)")
+	f.BoolVar(&tokenFlush, "flush", false, "remove the cached tokens instead")
+}
+
+func runToken(cmd *cobra.Command, args []string) error {
+	// kubectl shows what is logged to stderr, so only warnings are
+	if !debug {
+		setConsoleLevel(zerolog.WarnLevel)
+	}
+
+	cfg, _, err := readConfig(GetConfigFile())
+	if err != nil {
+		return err
+	}
+	cache := tokencache.New(filepath.Join(homePath, tokencache.DirName), cfg.CacheEncryption)
+
+	if tokenFlush {
+		n, err := cache.Flush()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed cached tokens: %d\n", n)
+		return nil
+	}
+	if tokenClusterID == "" {
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("--cluster-id is required"))
+	}
+
+	region := tokenRegion
+	if region == "" {
+		region = utils.ExtractRegionFromOCID(tokenClusterID)
+	}
+	c := &config.Cluster{Ocid: &tokenClusterID, Region: region}
+	for _, configured := range cfg.Clusters {
+		if configured.Ocid != nil && *configured.Ocid == tokenClusterID {
+			copied := *configured
+			c = &copied
+			break
+		}
+	}
+	if tokenOCIProfile != "" {
+		c.OCIProfile = &tokenOCIProfile
+	}
+	if tokenAuthType != "" {
+		c.OCIAuthType = &tokenAuthType
+	}
+
+	key := tokencache.Key{
+		ClusterID: tokenClusterID,
+		Region:    region,
+		Profile:   cfg.GetOCIProfile(c),
+		AuthType:  cfg.GetOCIAuthType(c),
+	}
+	cached, ok := cache.Get(key)
+	if !ok {
+		ociClient, err := cluster.NewClusterOCIClient(cfg, c, region)
+		if err != nil {
+			return err
+		}
+		token, expires, err := ociClient.GenerateClusterToken(cmd.Context(), tokenClusterID, region)
+		if err != nil {
+			return exitcode.Wrap(exitcode.Auth, err)
+		}
+		cached = &tokencache.Token{Token: token, ExpiresAt: expires}
+		if err := cache.Put(key, *cached); err != nil {
+			log.Debug().Err(err).Msg("Failed to cache token")
+		}
+	}
+
+	cred := kubeconfig.NewExecCredential(cached.Token, cached.ExpiresAt, os.Getenv("KUBERNETES_EXEC_INFO"))
+	enc := json.NewEncoder(os.Stdout)
+	if err := enc.Encode(cred); err != nil {
+		return fmt.Errorf("failed to write credential: %w", err)
+	}
+	return nil
+}
+
+// tokenConfigArgs returns the flags that make the token command of
+// generated kubeconfigs read the config in use: --config and
+// --config-profile, when they are not the defaults.
+func tokenConfigArgs() []string {
+	var args []string
+	if cfgFile != "" {
+		// kubectl runs the command from any directory
+		path, err := filepath.Abs(utils.ExpandPath(cfgFile))
+		if err != nil {
+			path = cfgFile
+		}
+		args = append(args, "--config", path)
+	}
+	if activeConfigProfile != "" {
+		args = append(args, "--config-profile", activeConfigProfile)
+	}
+	return args
+}
+
+// tokenCommand returns the command generated kubeconfigs run for tokens:
+// "tunatap" if that is this binary on the PATH, or else the path of this
+// binary.
+func tokenCommand() string {
+	self, err := os.Executable()
+	if err != nil {
+		return "tunatap"
+	}
+	selfInfo, err := os.Stat(self)
+	if err != nil {
+		return "tunatap"
+	}
+	if found, err := exec.LookPath("tunatap"); err == nil {
+		if foundInfo, err := os.Stat(found); err == nil && os.SameFile(selfInfo, foundInfo) {
+			return "tunatap"
+		}
+	}
+	return self
+}
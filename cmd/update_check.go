@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/ui"
+	"github.com/scotttball/tunatap/internal/update"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// updateNoticeWait is how long Execute waits for a running update check
+// after the command has finished.
+const updateNoticeWait = time.Second
+
+// updateResult receives the state of the update check started for this
+// run, or is nil if none was started.
+var updateResult chan *update.State
+
+// startUpdateCheck looks up the latest release in the background, unless
+// this is a dev build, the run is not interactive, the command is a
+// completion helper, or the check is turned off with update_check: false
+// or TUNATAP_UPDATE_CHECK=false.
+func startUpdateCheck(cmd *cobra.Command) {
+	if !update.IsRelease(version) || ui.NonInteractive() || os.Getenv("CI") != "" {
+		return
+	}
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		return
+	}
+	if cmd.Name() == "completion" || cmd.Name() == "token" || strings.HasPrefix(cmd.Name(), "__") {
+		return
+	}
+	if !updateCheckEnabled() {
+		return
+	}
+
+	updateResult = make(chan *update.State, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), update.DefaultTimeout)
+		defer cancel()
+		state, err := update.NewChecker(homePath).Check(ctx)
+		if err != nil {
+			log.Debug().Err(err).Msg("Update check failed")
+		}
+		updateResult <- state
+	}()
+}
+
+// updateCheckEnabled reports whether the config, with its environment
+// overrides, allows the update check. The config is read quietly, since
+// the command itself reports a missing or invalid one.
+func updateCheckEnabled() bool {
+	cfg := config.DefaultConfig()
+	if _, err := os.Stat(GetConfigFile()); err == nil {
+		if cfg, err = config.ReadConfigFile(GetConfigFile()); err != nil {
+			return false
+		}
+	}
+	if _, err := config.ApplyEnv(cfg); err != nil {
+		return false
+	}
+	return cfg.IsUpdateCheckEnabled()
+}
+
+// printUpdateNotice prints a notice to stderr if the update check found a
+// newer release, waiting briefly for a check still running.
+func printUpdateNotice() {
+	if updateResult == nil {
+		return
+	}
+	select {
+	case state := <-updateResult:
+		if notice := update.Notice(version, state); notice != "" {
+			fmt.Fprintf(os.Stderr, "\n%s\n", notice)
+		}
+	case <-time.After(updateNoticeWait):
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval time.Duration
+	watchRegion   string
+	watchNoCache  bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <cluster>",
+	Short: "Watch a cluster's private endpoint and bastion for changes",
+	Long: `Periodically re-fetch a cluster's private endpoint, its lifecycle state and
+the state of its bastion, printing a line whenever one of them changes, until
+interrupted.
+
+A cluster upgrade or endpoint migration can move the private endpoint to a
+new IP, which breaks tunnels still forwarding to the old one. Running tunnels
+can watch for this themselves with endpoint_watch_interval_seconds, or
+'connect --watch-endpoint', and reconnect to the new endpoint with
+endpoint_change_action: reconnect.
+
+Examples:
+  tunatap watch prod-cluster
+  tunatap watch prod-cluster --interval 30s`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", time.Minute, "how often to check the cluster")
+	watchCmd.Flags().StringVarP(&watchRegion, "region", "r", "", "region hint for cluster discovery (optional)")
+	watchCmd.Flags().BoolVar(&watchNoCache, "no-cache", false, "skip cache and force fresh discovery")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	if watchInterval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s")
+	}
+
+	cfg, cfgLoaded, err := loadConnectConfig()
+	if err != nil {
+		return err
+	}
+
+	c, ociClient, err := cluster.Resolve(cmd.Context(), cfg, cfgLoaded, args[0], watchRegion, watchNoCache)
+	if err != nil {
+		return err
+	}
+	if ociClient == nil {
+		if ociClient, err = createOCIClient(cfg, c); err != nil {
+			return fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	if err := cluster.SetClusterTenancy(cmd.Context(), ociClient, c); err != nil {
+		return err
+	}
+	if err := cluster.SetClusterOcid(cmd.Context(), ociClient, c); err != nil {
+		return err
+	}
+	if c.BastionId == nil && c.CompartmentOcid != nil {
+		// The bastion's state is shown if it can be found
+		if c.BastionId, err = cluster.GetClusterBastion(cmd.Context(), ociClient, c); err != nil {
+			log.Debug().Err(err).Msg("Not watching the bastion")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Watching cluster %s every %s (Ctrl-C to stop)\n", c.ClusterName, watchInterval)
+	bastion.WatchEndpoint(ctx, ociClient, c, watchInterval, printEndpointState)
+	return nil
+}
+
+// printEndpointState prints the state of a watched cluster the first time,
+// and what changed after that.
+func printEndpointState(previous, current *bastion.EndpointState) {
+	now := time.Now().Format(time.RFC3339)
+	if previous == nil {
+		bastionState := current.BastionState
+		if bastionState == "" {
+			bastionState = "unknown"
+		}
+		endpoint := current.Endpoint()
+		if endpoint == "" {
+			endpoint = "none"
+		}
+		fmt.Printf("%s  endpoint %s, cluster %s, bastion %s\n", now, endpoint, current.ClusterState, bastionState)
+		return
+	}
+	fmt.Printf("%s  %s\n", now, strings.Join(current.Changes(previous), "; "))
+}
@@ -1,6 +1,7 @@
 package audit
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/pkg/utils"
 )
 
 // EventType represents the type of audit event.
@@ -20,25 +22,37 @@ const (
 	EventTypeError      EventType = "error"
 	EventTypeRefresh    EventType = "session_refresh"
 	EventTypeExec       EventType = "exec"
+	// EventTypeCheckpoint is a signed checkpoint of the hash chain of the
+	// log, written by Loggers with EnableHashChain.
+	EventTypeCheckpoint EventType = "checkpoint"
 )
 
 // AuditEvent represents a single audit log entry.
 type AuditEvent struct {
-	Timestamp   time.Time         `json:"timestamp"`
-	EventType   EventType         `json:"event_type"`
-	SessionID   string            `json:"session_id,omitempty"`
-	ClusterName string            `json:"cluster_name,omitempty"`
-	Region      string            `json:"region,omitempty"`
-	LocalPort   int               `json:"local_port,omitempty"`
-	RemoteHost  string            `json:"remote_host,omitempty"`
-	RemotePort  int               `json:"remote_port,omitempty"`
-	BastionID   string            `json:"bastion_id,omitempty"`
-	Duration    *time.Duration    `json:"duration_ns,omitempty"`
-	Error       string            `json:"error,omitempty"`
-	Command     string            `json:"command,omitempty"`
-	ExitCode    *int              `json:"exit_code,omitempty"`
-	User        string            `json:"user,omitempty"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
+	Timestamp       time.Time         `json:"timestamp" yaml:"timestamp"`
+	EventType       EventType         `json:"event_type" yaml:"event_type"`
+	SessionID       string            `json:"session_id,omitempty" yaml:"session_id,omitempty"`
+	ClusterName     string            `json:"cluster_name,omitempty" yaml:"cluster_name,omitempty"`
+	Region          string            `json:"region,omitempty" yaml:"region,omitempty"`
+	LocalPort       int               `json:"local_port,omitempty" yaml:"local_port,omitempty"`
+	RemoteHost      string            `json:"remote_host,omitempty" yaml:"remote_host,omitempty"`
+	RemotePort      int               `json:"remote_port,omitempty" yaml:"remote_port,omitempty"`
+	BastionID       string            `json:"bastion_id,omitempty" yaml:"bastion_id,omitempty"`
+	Duration        *time.Duration    `json:"duration_ns,omitempty" yaml:"-"`
+	BytesIn         int64             `json:"bytes_in,omitempty" yaml:"bytes_in,omitempty"`
+	BytesOut        int64             `json:"bytes_out,omitempty" yaml:"bytes_out,omitempty"`
+	PeakConnections int64             `json:"peak_connections,omitempty" yaml:"peak_connections,omitempty"`
+	Error           string            `json:"error,omitempty" yaml:"error,omitempty"`
+	Command         string            `json:"command,omitempty" yaml:"command,omitempty"`
+	ExitCode        *int              `json:"exit_code,omitempty" yaml:"exit_code,omitempty"`
+	User            string            `json:"user,omitempty" yaml:"user,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	// PrevHash is the hash of the record before this one in the log, when
+	// the log is hash-chained. Checkpoints carry the Signature of PrevHash
+	// and the KeyID of the key it was signed with.
+	PrevHash  string `json:"prev_hash,omitempty" yaml:"prev_hash,omitempty"`
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty"`
+	KeyID     string `json:"key_id,omitempty" yaml:"key_id,omitempty"`
 }
 
 // Session tracks an active tunnel session for audit purposes.
@@ -52,15 +66,55 @@ type Session struct {
 	BastionID   string
 	StartTime   time.Time
 	metadata    map[string]string
+
+	trafficMu sync.Mutex
+	traffic   Traffic
+}
+
+// Traffic is what the tunnels of a session carried.
+type Traffic struct {
+	// BytesIn is the number of bytes received from the remote endpoint.
+	BytesIn int64
+	// BytesOut is the number of bytes sent to the remote endpoint.
+	BytesOut int64
+	// PeakConnections is the most connections forwarded at once.
+	PeakConnections int64
+}
+
+// AddTraffic adds what one of the session's tunnels carried, to be recorded
+// in the event that ends the session. A session that reconnects adds that
+// of each of its tunnels.
+func (s *Session) AddTraffic(t Traffic) {
+	s.trafficMu.Lock()
+	defer s.trafficMu.Unlock()
+	s.traffic.BytesIn += t.BytesIn
+	s.traffic.BytesOut += t.BytesOut
+	s.traffic.PeakConnections = max(s.traffic.PeakConnections, t.PeakConnections)
+}
+
+// Traffic returns what the session's tunnels have carried so far.
+func (s *Session) Traffic() Traffic {
+	s.trafficMu.Lock()
+	defer s.trafficMu.Unlock()
+	return s.traffic
 }
 
-// Logger handles audit logging to a local file.
+// Logger handles audit logging to a local file, and ships the events to
+// any sinks added with AddSink.
 type Logger struct {
+	logDir    string
 	logPath   string
 	mu        sync.Mutex
 	file      *os.File
+	shippers  []*Shipper
 	sessions  map[string]*Session
 	sessionMu sync.RWMutex
+
+	// Set by EnableHashChain
+	lockFile       *os.File
+	key            ed25519.PrivateKey
+	lastCheckpoint time.Time
+	unsigned       int
 }
 
 // NewLogger creates a new audit logger.
@@ -71,7 +125,7 @@ func NewLogger(logDir string) (*Logger, error) {
 	}
 
 	// Create log file path with date
-	logPath := filepath.Join(logDir, fmt.Sprintf("audit-%s.jsonl", time.Now().Format("2006-01-02")))
+	logPath := logFilePath(logDir, time.Now())
 
 	// Open log file in append mode
 	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
@@ -80,17 +134,40 @@ func NewLogger(logDir string) (*Logger, error) {
 	}
 
 	return &Logger{
+		logDir:   logDir,
 		logPath:  logPath,
 		file:     file,
 		sessions: make(map[string]*Session),
 	}, nil
 }
 
-// Close closes the audit logger.
+// AddSink ships every event logged from now on to sink as well.
+func (l *Logger) AddSink(sink Sink, opts ShipperOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.shippers = append(l.shippers, NewShipper(sink, opts))
+}
+
+// Close ships the events still buffered for sinks and closes the audit
+// logger.
 func (l *Logger) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	for _, s := range l.shippers {
+		if err := s.Close(); err != nil {
+			log.Debug().Err(err).Str("sink", s.sink.Name()).Msg("Failed to close audit sink")
+		}
+	}
+	l.shippers = nil
+
+	if l.key != nil {
+		if err := l.checkpoint(); err != nil {
+			log.Warn().Err(err).Msg("Failed to sign audit log checkpoint")
+		}
+		l.lockFile.Close()
+	}
+
 	if l.file != nil {
 		return l.file.Close()
 	}
@@ -112,19 +189,22 @@ func (l *Logger) Log(event *AuditEvent) error {
 		event.User = os.Getenv("USER")
 	}
 
-	// Marshal to JSON
-	data, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
+	// Ship a copy, as the caller may reuse the event
+	for _, s := range l.shippers {
+		shipped := *event
+		s.Enqueue(&shipped)
 	}
 
-	// Write to file with newline
-	if _, err := l.file.Write(append(data, '\n')); err != nil {
-		return fmt.Errorf("failed to write event: %w", err)
+	if err := l.write(event); err != nil {
+		return err
 	}
 
-	// Sync to ensure durability
-	return l.file.Sync()
+	if l.key != nil && time.Since(l.lastCheckpoint) >= CheckpointInterval {
+		if err := l.checkpoint(); err != nil {
+			log.Warn().Err(err).Msg("Failed to sign audit log checkpoint")
+		}
+	}
+	return nil
 }
 
 // StartSession starts tracking a new session.
@@ -167,6 +247,7 @@ func (l *Logger) EndSession(sessionID string, errorMsg string) error {
 	}
 
 	duration := time.Since(session.StartTime)
+	traffic := session.Traffic()
 
 	eventType := EventTypeDisconnect
 	if errorMsg != "" {
@@ -174,17 +255,20 @@ func (l *Logger) EndSession(sessionID string, errorMsg string) error {
 	}
 
 	return l.Log(&AuditEvent{
-		EventType:   eventType,
-		SessionID:   sessionID,
-		ClusterName: session.ClusterName,
-		Region:      session.Region,
-		LocalPort:   session.LocalPort,
-		RemoteHost:  session.RemoteHost,
-		RemotePort:  session.RemotePort,
-		BastionID:   session.BastionID,
-		Duration:    &duration,
-		Error:       errorMsg,
-		Metadata:    session.metadata,
+		EventType:       eventType,
+		SessionID:       sessionID,
+		ClusterName:     session.ClusterName,
+		Region:          session.Region,
+		LocalPort:       session.LocalPort,
+		RemoteHost:      session.RemoteHost,
+		RemotePort:      session.RemotePort,
+		BastionID:       session.BastionID,
+		Duration:        &duration,
+		BytesIn:         traffic.BytesIn,
+		BytesOut:        traffic.BytesOut,
+		PeakConnections: traffic.PeakConnections,
+		Error:           errorMsg,
+		Metadata:        session.metadata,
 	})
 }
 
@@ -279,10 +363,9 @@ type Query struct {
 // QueryLogs queries audit logs based on criteria.
 func QueryLogs(logDir string, q Query) ([]AuditEvent, error) {
 	// Find all log files
-	pattern := filepath.Join(logDir, "audit-*.jsonl")
-	files, err := filepath.Glob(pattern)
+	files, err := logFiles(logDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find log files: %w", err)
+		return nil, err
 	}
 
 	events := make([]AuditEvent, 0)
@@ -334,6 +417,10 @@ func readLogFile(path string, q Query) ([]AuditEvent, error) {
 		if q.EventType != "" && event.EventType != q.EventType {
 			continue
 		}
+		// Checkpoints are of the log rather than of tunnels
+		if q.EventType == "" && event.EventType == EventTypeCheckpoint {
+			continue
+		}
 		if q.SessionID != "" && event.SessionID != q.SessionID {
 			continue
 		}
@@ -357,11 +444,18 @@ func FormatEvent(e *AuditEvent) string {
 		if e.Duration != nil {
 			duration = e.Duration.Round(time.Second).String()
 		}
+		if traffic := formatTraffic(e); traffic != "" {
+			duration += ", " + traffic
+		}
 		return fmt.Sprintf("[%s] DISCONNECT %s (duration: %s, session: %s)",
 			ts, e.ClusterName, duration, e.SessionID)
 	case EventTypeError:
-		return fmt.Sprintf("[%s] ERROR    %s: %s (session: %s)",
-			ts, e.ClusterName, e.Error, e.SessionID)
+		session := "session: " + e.SessionID
+		if traffic := formatTraffic(e); traffic != "" {
+			session = traffic + ", " + session
+		}
+		return fmt.Sprintf("[%s] ERROR    %s: %s (%s)",
+			ts, e.ClusterName, e.Error, session)
 	case EventTypeRefresh:
 		return fmt.Sprintf("[%s] REFRESH  %s (session: %s)",
 			ts, e.ClusterName, e.SessionID)
@@ -377,10 +471,22 @@ func FormatEvent(e *AuditEvent) string {
 	}
 }
 
+// formatTraffic describes the traffic of a session's closing event, or
+// returns "" if it carried none.
+func formatTraffic(e *AuditEvent) string {
+	if e.BytesIn == 0 && e.BytesOut == 0 {
+		return ""
+	}
+	return fmt.Sprintf("in: %s, out: %s, peak connections: %d",
+		utils.FormatBytes(e.BytesIn), utils.FormatBytes(e.BytesOut), e.PeakConnections)
+}
+
 // Summary represents aggregated audit statistics.
 type Summary struct {
 	TotalConnections int
 	TotalDuration    time.Duration
+	BytesIn          int64
+	BytesOut         int64
 	ClusterStats     map[string]ClusterStat
 	ErrorCount       int
 }
@@ -389,6 +495,9 @@ type Summary struct {
 type ClusterStat struct {
 	ConnectionCount int
 	TotalDuration   time.Duration
+	BytesIn         int64
+	BytesOut        int64
+	PeakConnections int64
 	ErrorCount      int
 	LastAccess      time.Time
 }
@@ -410,21 +519,43 @@ func GetSummary(events []AuditEvent) *Summary {
 			}
 			summary.ClusterStats[events[i].ClusterName] = stat
 
-		case EventTypeDisconnect:
+		case EventTypeDisconnect, EventTypeError:
+			// A session that ended in an error was connected until then
+			stat := summary.ClusterStats[events[i].ClusterName]
+			if events[i].EventType == EventTypeError {
+				summary.ErrorCount++
+				stat.ErrorCount++
+			}
 			if events[i].Duration != nil {
 				summary.TotalDuration += *events[i].Duration
-				stat := summary.ClusterStats[events[i].ClusterName]
 				stat.TotalDuration += *events[i].Duration
-				summary.ClusterStats[events[i].ClusterName] = stat
 			}
-
-		case EventTypeError:
-			summary.ErrorCount++
-			stat := summary.ClusterStats[events[i].ClusterName]
-			stat.ErrorCount++
+			summary.BytesIn += events[i].BytesIn
+			summary.BytesOut += events[i].BytesOut
+			stat.BytesIn += events[i].BytesIn
+			stat.BytesOut += events[i].BytesOut
+			stat.PeakConnections = max(stat.PeakConnections, events[i].PeakConnections)
 			summary.ClusterStats[events[i].ClusterName] = stat
 		}
 	}
 
 	return summary
 }
+
+// ErrorRate is the share of connections that ended in an error, from 0 to 1.
+func (s *Summary) ErrorRate() float64 {
+	return errorRate(s.ErrorCount, s.TotalConnections)
+}
+
+// ErrorRate is the share of connections to the cluster that ended in an
+// error, from 0 to 1.
+func (s ClusterStat) ErrorRate() float64 {
+	return errorRate(s.ErrorCount, s.ConnectionCount)
+}
+
+func errorRate(errors, connections int) float64 {
+	if connections == 0 {
+		return 0
+	}
+	return min(float64(errors)/float64(connections), 1)
+}
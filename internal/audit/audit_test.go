@@ -2,6 +2,7 @@ package audit
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -137,6 +138,34 @@ func TestEndSessionWithError(t *testing.T) {
 	}
 }
 
+func TestEndSessionRecordsTraffic(t *testing.T) {
+	tempDir := t.TempDir()
+
+	logger, err := NewLogger(tempDir)
+	if err != nil {
+		t.Fatalf("NewLogger error: %v", err)
+	}
+	defer logger.Close()
+
+	session := &Session{ClusterName: "test-cluster"}
+	logger.StartSession(session)
+	// A session that reconnected adds the traffic of each tunnel
+	session.AddTraffic(Traffic{BytesIn: 1000, BytesOut: 200, PeakConnections: 4})
+	session.AddTraffic(Traffic{BytesIn: 24, BytesOut: 56, PeakConnections: 2})
+	logger.EndSession(session.ID, "")
+
+	events, _ := QueryLogs(tempDir, Query{EventType: EventTypeDisconnect})
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 disconnect event, got %d", len(events))
+	}
+	if e := events[0]; e.BytesIn != 1024 || e.BytesOut != 256 || e.PeakConnections != 4 {
+		t.Errorf("traffic = %d in, %d out, %d peak; want 1024, 256, 4", e.BytesIn, e.BytesOut, e.PeakConnections)
+	}
+	if formatted := FormatEvent(&events[0]); !strings.Contains(formatted, "in: 1.0KiB, out: 256B, peak connections: 4") {
+		t.Errorf("FormatEvent() = %q, want the traffic", formatted)
+	}
+}
+
 func TestQueryLogsWithFilters(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -277,6 +306,38 @@ func TestGetSummary(t *testing.T) {
 	if summary.ClusterStats["cluster-b"].ConnectionCount != 1 {
 		t.Errorf("cluster-b connections = %d, want 1", summary.ClusterStats["cluster-b"].ConnectionCount)
 	}
+
+	if summary.BytesIn != 0 {
+		t.Errorf("BytesIn = %d without traffic, want 0", summary.BytesIn)
+	}
+
+	if rate := summary.ClusterStats["cluster-a"].ErrorRate(); rate != 0.5 {
+		t.Errorf("cluster-a error rate = %v, want 0.5", rate)
+	}
+	if rate := summary.ErrorRate(); rate != 1.0/3 {
+		t.Errorf("error rate = %v, want 1/3", rate)
+	}
+}
+
+func TestGetSummaryErrorAndTraffic(t *testing.T) {
+	duration := 5 * time.Minute
+	summary := GetSummary([]AuditEvent{
+		{EventType: EventTypeConnect, ClusterName: "prod"},
+		{EventType: EventTypeError, ClusterName: "prod", Error: "bastion session expired", Duration: &duration, BytesIn: 300, BytesOut: 30, PeakConnections: 5},
+		{EventType: EventTypeConnect, ClusterName: "prod"},
+		{EventType: EventTypeDisconnect, ClusterName: "prod", BytesIn: 700, BytesOut: 70, PeakConnections: 2},
+	})
+
+	if summary.TotalDuration != duration || summary.ClusterStats["prod"].TotalDuration != duration {
+		t.Errorf("durations = %v and %v, want the time connected before the error", summary.TotalDuration, summary.ClusterStats["prod"].TotalDuration)
+	}
+	prod := summary.ClusterStats["prod"]
+	if summary.BytesIn != 1000 || summary.BytesOut != 100 || prod.BytesIn != 1000 || prod.PeakConnections != 5 {
+		t.Errorf("traffic = %d in, %d out, prod %+v", summary.BytesIn, summary.BytesOut, prod)
+	}
+	if (&Summary{}).ErrorRate() != 0 {
+		t.Error("error rate without connections should be 0")
+	}
 }
 
 func TestDefaultLogDir(t *testing.T) {
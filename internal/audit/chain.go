@@ -0,0 +1,306 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/flock"
+)
+
+// CheckpointInterval is how often a Logger with a hash chain signs a
+// checkpoint while it writes events. It also signs one when it is closed.
+const CheckpointInterval = time.Hour
+
+// lockFileName is the file in the log directory that Loggers with a hash
+// chain lock while they write, so that the records of all processes form
+// one chain.
+const lockFileName = ".lock"
+
+// checkpointContext is signed before the hash of the record a checkpoint
+// follows, so that its signature cannot be taken for one of anything else.
+const checkpointContext = "tunatap audit checkpoint\n"
+
+// EnableHashChain makes the logger chain each record it writes to the one
+// before it, in this or an earlier log file, by its hash, and sign a
+// checkpoint of the chain with key every CheckpointInterval and when it is
+// closed. VerifyLogs then detects records that were changed, removed or
+// inserted.
+func (l *Logger) EnableHashChain(key ed25519.PrivateKey) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := os.OpenFile(filepath.Join(l.logDir, lockFileName), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit lock file: %w", err)
+	}
+	l.lockFile = lock
+	l.key = key
+	l.lastCheckpoint = time.Now()
+	return nil
+}
+
+// checkpoint signs a checkpoint of the chain if any records were written
+// since the last.
+func (l *Logger) checkpoint() error {
+	if l.unsigned == 0 {
+		return nil
+	}
+	err := l.write(&AuditEvent{
+		Timestamp: time.Now().UTC(),
+		EventType: EventTypeCheckpoint,
+		KeyID:     KeyID(l.key.Public().(ed25519.PublicKey)),
+	})
+	if err != nil {
+		return err
+	}
+	l.unsigned = 0
+	l.lastCheckpoint = time.Now()
+	return nil
+}
+
+// write appends event to the log file of today. With a hash chain it holds
+// the lock of the log directory meanwhile and chains event to the last
+// record written, signing it if it is a checkpoint.
+func (l *Logger) write(event *AuditEvent) error {
+	if l.key != nil {
+		if err := flock.Lock(l.lockFile); err != nil {
+			return fmt.Errorf("failed to lock audit log: %w", err)
+		}
+		defer flock.Unlock(l.lockFile)
+	}
+
+	if err := l.rotate(); err != nil {
+		return err
+	}
+
+	if l.key != nil {
+		prev, err := lastRecordHash(l.logDir)
+		if err != nil {
+			return fmt.Errorf("failed to read last audit record: %w", err)
+		}
+		event.PrevHash = prev
+		if event.EventType == EventTypeCheckpoint {
+			event.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(l.key, checkpointMessage(prev)))
+		} else {
+			l.unsigned++
+		}
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+
+	// Sync to ensure durability
+	return l.file.Sync()
+}
+
+// rotate moves the logger to the log file of today, when it has been
+// running since an earlier day.
+func (l *Logger) rotate() error {
+	path := logFilePath(l.logDir, time.Now())
+	if path == l.logPath {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	l.file.Close()
+	l.file = file
+	l.logPath = path
+	return nil
+}
+
+// logFilePath returns the path of the log file for the day of t.
+func logFilePath(logDir string, t time.Time) string {
+	return filepath.Join(logDir, fmt.Sprintf("audit-%s.jsonl", t.Format("2006-01-02")))
+}
+
+// logFiles returns the log files in logDir, oldest first.
+func logFiles(logDir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(logDir, "audit-*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find log files: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// lastRecordHash returns the hash of the last record in the log files of
+// logDir, or "" if there is none.
+func lastRecordHash(logDir string) (string, error) {
+	files, err := logFiles(logDir)
+	if err != nil {
+		return "", err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		line, err := lastLine(files[i])
+		if err != nil {
+			return "", err
+		}
+		if line != nil {
+			return recordHash(line), nil
+		}
+	}
+	return "", nil
+}
+
+// lastLine returns the last line of the file at path, without its newline,
+// or nil if the file is empty.
+func lastLine(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read back from the end until a whole line is in the tail
+	for chunk := int64(64 << 10); ; chunk *= 2 {
+		offset := max(info.Size()-chunk, 0)
+		tail := make([]byte, info.Size()-offset)
+		if _, err := f.ReadAt(tail, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		tail = bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(tail, '\n'); i >= 0 {
+			return tail[i+1:], nil
+		}
+		if offset == 0 {
+			if len(tail) == 0 {
+				return nil, nil
+			}
+			return tail, nil
+		}
+	}
+}
+
+// recordHash returns the hash a record is chained to the next by.
+func recordHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+func checkpointMessage(prevHash string) []byte {
+	return []byte(checkpointContext + prevHash)
+}
+
+// Verification is the result of VerifyLogs.
+type Verification struct {
+	// Records is the number of records read.
+	Records int `json:"records" yaml:"records"`
+	// Chained is the number of those chained to the record before them.
+	Chained int `json:"chained" yaml:"chained"`
+	// Checkpoints is the number of checkpoints with a valid signature.
+	Checkpoints int `json:"checkpoints" yaml:"checkpoints"`
+	// LastCheckpoint is when the last valid checkpoint was signed.
+	LastCheckpoint *time.Time `json:"last_checkpoint,omitempty" yaml:"last_checkpoint,omitempty"`
+	// Unsigned is the number of chained records after the last valid
+	// checkpoint, which could be removed without a trace.
+	Unsigned int `json:"unsigned" yaml:"unsigned"`
+	// Problems describes each record that breaks the chain, each with its
+	// file and line.
+	Problems []string `json:"problems" yaml:"problems"`
+}
+
+// OK reports whether the logs verified without problems.
+func (v *Verification) OK() bool {
+	return len(v.Problems) == 0
+}
+
+// VerifyLogs checks the hash chain of the log files in logDir and the
+// checkpoint signatures against key. The chain starts at the first record
+// of the oldest file, so removing whole files from the start of the logs,
+// as when archiving them, is not a problem; removing records after the last
+// checkpoint cannot be detected.
+func VerifyLogs(logDir string, key ed25519.PublicKey) (*Verification, error) {
+	files, err := logFiles(logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Verification{Problems: []string{}}
+	keyID := KeyID(key)
+	var prevLine []byte
+	chained := false
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+		for lineNo := 1; scanner.Scan(); lineNo++ {
+			line := scanner.Bytes()
+			where := fmt.Sprintf("%s:%d", filepath.Base(file), lineNo)
+			v.Records++
+
+			var event AuditEvent
+			if err := json.Unmarshal(line, &event); err != nil {
+				v.Problems = append(v.Problems, fmt.Sprintf("%s: not an audit record", where))
+			} else {
+				switch {
+				case event.PrevHash != "":
+					if prevLine != nil && event.PrevHash != recordHash(prevLine) {
+						v.Problems = append(v.Problems, fmt.Sprintf("%s: does not follow the record before it; records were changed, removed or inserted", where))
+					}
+					chained = true
+					v.Chained++
+				case chained:
+					v.Problems = append(v.Problems, fmt.Sprintf("%s: not chained to the record before it", where))
+				}
+
+				if event.EventType == EventTypeCheckpoint {
+					if problem := verifyCheckpoint(&event, key, keyID); problem != "" {
+						v.Problems = append(v.Problems, fmt.Sprintf("%s: %s", where, problem))
+					} else {
+						v.Checkpoints++
+						ts := event.Timestamp
+						v.LastCheckpoint = &ts
+						v.Unsigned = 0
+					}
+				} else if event.PrevHash != "" {
+					v.Unsigned++
+				}
+			}
+			prevLine = append(prevLine[:0], line...)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+	}
+	return v, nil
+}
+
+// verifyCheckpoint describes what is wrong with the signature of
+// checkpoint, or returns "".
+func verifyCheckpoint(checkpoint *AuditEvent, key ed25519.PublicKey, keyID string) string {
+	if checkpoint.KeyID != keyID {
+		return fmt.Sprintf("checkpoint signed with key %s, not %s", checkpoint.KeyID, keyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil || !ed25519.Verify(key, checkpointMessage(checkpoint.PrevHash), sig) {
+		return "checkpoint signature is not valid"
+	}
+	return ""
+}
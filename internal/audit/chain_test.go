@@ -0,0 +1,248 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeChained logs the connect and disconnect of a session for each of
+// clusters, each with its own Logger as if from its own process.
+func writeChained(t *testing.T, dir string, key ed25519.PrivateKey, clusters ...string) {
+	t.Helper()
+	for _, cluster := range clusters {
+		logger, err := NewLogger(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.EnableHashChain(key); err != nil {
+			t.Fatal(err)
+		}
+		session := &Session{ClusterName: cluster}
+		if err := logger.StartSession(session); err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.EndSession(session.ID, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	key, err := LoadOrCreateKey(filepath.Join(t.TempDir(), KeyFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// logLines returns the lines of the only log file in dir.
+func logLines(t *testing.T, dir string) (string, []string) {
+	t.Helper()
+	files, _ := logFiles(dir)
+	if len(files) != 1 {
+		t.Fatalf("found %d log files, want 1", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files[0], strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestHashChainVerifies(t *testing.T) {
+	dir := t.TempDir()
+	key := testKey(t)
+
+	// Records written before the chain was enabled are not checked
+	plain, err := NewLogger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Log(&AuditEvent{EventType: EventTypeConnect, ClusterName: "old"})
+	plain.Close()
+
+	writeChained(t, dir, key, "prod", "staging")
+
+	v, err := VerifyLogs(dir, key.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.OK() {
+		t.Fatalf("VerifyLogs() problems = %v", v.Problems)
+	}
+	if v.Records != 7 || v.Chained != 6 || v.Checkpoints != 2 || v.Unsigned != 0 || v.LastCheckpoint == nil {
+		t.Errorf("VerifyLogs() = %+v, want 7 records, 6 chained, 2 checkpoints", v)
+	}
+
+	events, _ := QueryLogs(dir, Query{})
+	if len(events) != 5 {
+		t.Errorf("QueryLogs() returned %d events, want 5 without checkpoints", len(events))
+	}
+	if checkpoints, _ := QueryLogs(dir, Query{EventType: EventTypeCheckpoint}); len(checkpoints) != 2 {
+		t.Errorf("QueryLogs(checkpoint) returned %d events, want 2", len(checkpoints))
+	}
+}
+
+func TestHashChainDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+		want   string
+	}{
+		{
+			name: "changed",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"cluster_name":"prod"`, `"cluster_name":"dev"`, 1)
+				return lines
+			},
+			want: ":3: does not follow the record before it",
+		},
+		{
+			name: "removed",
+			tamper: func(lines []string) []string {
+				return append(lines[:1:1], lines[2:]...)
+			},
+			want: ":2: does not follow the record before it",
+		},
+		{
+			name: "inserted unchained",
+			tamper: func(lines []string) []string {
+				return append(lines[:4:4], append([]string{`{"event_type":"connect","cluster_name":"prod"}`}, lines[4:]...)...)
+			},
+			want: ":5: not chained to the record before it",
+		},
+		{
+			name: "forged checkpoint",
+			tamper: func(lines []string) []string {
+				lines[2] = strings.Replace(lines[2], `"signature":"`, `"signature":"AA`, 1)
+				return lines
+			},
+			want: ":3: checkpoint signature is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			key := testKey(t)
+			writeChained(t, dir, key, "prod", "staging")
+
+			file, lines := logLines(t, dir)
+			lines = tt.tamper(lines)
+			if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := VerifyLogs(dir, key.Public().(ed25519.PublicKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.OK() || !strings.Contains(strings.Join(v.Problems, "\n"), tt.want) {
+				t.Errorf("VerifyLogs() problems = %v, want %q", v.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashChainUnsignedAndOtherKey(t *testing.T) {
+	dir := t.TempDir()
+	key := testKey(t)
+	writeChained(t, dir, key, "prod")
+
+	// A logger that has not closed yet has not signed its records
+	logger, err := NewLogger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	if err := logger.EnableHashChain(key); err != nil {
+		t.Fatal(err)
+	}
+	logger.Log(&AuditEvent{EventType: EventTypeConnect, ClusterName: "prod"})
+
+	v, err := VerifyLogs(dir, key.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.OK() || v.Unsigned != 1 {
+		t.Errorf("VerifyLogs() = %+v, want 1 unsigned record and no problems", v)
+	}
+
+	other := testKey(t)
+	v, err = VerifyLogs(dir, other.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.OK() || !strings.Contains(v.Problems[0], "checkpoint signed with key "+KeyID(key.Public().(ed25519.PublicKey))) {
+		t.Errorf("VerifyLogs() with another key problems = %v", v.Problems)
+	}
+}
+
+func TestLastLine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit-2024-01-01.jsonl")
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if line, err := lastLine(path); err != nil || line != nil {
+		t.Errorf("lastLine(empty) = %q, %v", line, err)
+	}
+
+	// Lines longer than the first chunk read back
+	long := bytes.Repeat([]byte("x"), 100<<10)
+	data := append(append([]byte("first\n"), long...), '\n')
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if line, err := lastLine(path); err != nil || !bytes.Equal(line, long) {
+		t.Errorf("lastLine() = %d bytes, %v; want the %d byte last line", len(line), err, len(long))
+	}
+
+	if hash, err := lastRecordHash(dir); err != nil || hash != recordHash(long) {
+		t.Errorf("lastRecordHash() = %q, %v", hash, err)
+	}
+	if hash, err := lastRecordHash(t.TempDir()); err != nil || hash != "" {
+		t.Errorf("lastRecordHash() of no logs = %q, %v", hash, err)
+	}
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "home", KeyFileName)
+
+	key, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("key file mode = %v, want it readable by its owner only", info.Mode())
+	}
+
+	again, err := LoadOrCreateKey(path)
+	if err != nil || !key.Equal(again) {
+		t.Errorf("LoadOrCreateKey() did not load the key it created: %v", err)
+	}
+
+	for _, p := range []string{path + ".pub", path} {
+		pub, err := LoadPublicKey(p)
+		if err != nil || !pub.Equal(key.Public()) {
+			t.Errorf("LoadPublicKey(%s) = %v, want the public key of the signing key", filepath.Base(p), err)
+		}
+	}
+	if _, err := LoadPublicKey(filepath.Join(t.TempDir(), "missing.pub")); err == nil {
+		t.Error("LoadPublicKey() of a missing file should fail")
+	}
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+const (
+	// ociLogSource and ociLogType are the source and type of the entries
+	// put in OCI Logging.
+	ociLogSource = "tunatap"
+	ociLogType   = "com.tunatap.audit"
+)
+
+// LogPutter puts entries in an OCI Logging custom log, as
+// client.OCIClient does.
+type LogPutter interface {
+	PutLogEntries(ctx context.Context, logID, source, logType string, entries []client.LogEntry) error
+}
+
+// OCILoggingSink puts events in an OCI Logging custom log, as JSON, one
+// entry per event.
+type OCILoggingSink struct {
+	logID  string
+	putter LogPutter
+	host   string
+}
+
+// NewOCILoggingSink returns a sink putting events in the log logID through
+// putter.
+func NewOCILoggingSink(logID string, putter LogPutter) *OCILoggingSink {
+	host, _ := os.Hostname()
+	return &OCILoggingSink{logID: logID, putter: putter, host: host}
+}
+
+// Name names the log.
+func (s *OCILoggingSink) Name() string {
+	return "OCI Logging " + s.logID
+}
+
+// Send puts events in the log. Entry IDs are derived from the host,
+// session and time of each event, so a batch sent again has the same IDs.
+func (s *OCILoggingSink) Send(ctx context.Context, events []*AuditEvent) error {
+	entries := make([]client.LogEntry, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		entries = append(entries, client.LogEntry{
+			ID:   fmt.Sprintf("%s-%s-%d-%s", s.host, event.SessionID, event.Timestamp.UnixNano(), event.EventType),
+			Time: event.Timestamp,
+			Data: string(data),
+		})
+	}
+	return s.putter.PutLogEntries(ctx, s.logID, ociLogSource, ociLogType, entries)
+}
+
+// Close does nothing; the OCI client is shared.
+func (s *OCILoggingSink) Close() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Sink is a destination audit events are shipped to besides the local log
+// file, such as a webhook or a central log service.
+type Sink interface {
+	// Name describes the sink in log messages.
+	Name() string
+	// Send delivers a batch of events. An error means the whole batch may
+	// be sent again.
+	Send(ctx context.Context, events []*AuditEvent) error
+	// Close releases the sink once no more events will be sent.
+	Close() error
+}
+
+const (
+	// DefaultBufferSize is how many events a Shipper holds by default.
+	DefaultBufferSize = 1000
+	// DefaultMaxRetries is how many times a Shipper retries a batch by
+	// default.
+	DefaultMaxRetries = 5
+
+	// maxBatch is the largest number of events sent at once.
+	maxBatch = 100
+	// sendTimeout bounds a single Send.
+	sendTimeout = 10 * time.Second
+	// closeTimeout bounds how long Close waits for buffered events.
+	closeTimeout = 5 * time.Second
+)
+
+// ShipperOptions control the buffering and retries of a Shipper. A zero
+// BufferSize or RetryDelay takes the default.
+type ShipperOptions struct {
+	// BufferSize is how many events are held while the sink cannot be
+	// reached. Beyond it the oldest events are dropped.
+	BufferSize int
+	// MaxRetries is how many times a failed batch is retried, with
+	// exponential backoff, before it is dropped. Zero means never.
+	MaxRetries int
+	// RetryDelay is the delay before the first retry. Default: 1s.
+	RetryDelay time.Duration
+}
+
+// Shipper sends events to a sink in the background, in batches, so that
+// logging never waits on the network. Batches that fail are retried with
+// exponential backoff.
+type Shipper struct {
+	sink Sink
+	opts ShipperOptions
+
+	mu      sync.Mutex
+	pending []*AuditEvent
+	dropped int
+	closed  bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewShipper starts shipping events enqueued with Enqueue to sink.
+func NewShipper(sink Sink, opts ShipperOptions) *Shipper {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	s := &Shipper{
+		sink: sink,
+		opts: opts,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Enqueue adds event to the events to ship. It never blocks: when the
+// buffer is full the oldest event is dropped.
+func (s *Shipper) Enqueue(event *AuditEvent) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	if len(s.pending) >= s.opts.BufferSize {
+		s.pending = s.pending[1:]
+		s.dropped++
+	}
+	s.pending = append(s.pending, event)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close ships the buffered events, waiting up to a few seconds, and closes
+// the sink.
+func (s *Shipper) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	select {
+	case <-s.done:
+	case <-time.After(closeTimeout):
+		log.Warn().Str("sink", s.sink.Name()).Msg("Gave up shipping buffered audit events")
+	}
+	return s.sink.Close()
+}
+
+func (s *Shipper) run() {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.wake:
+		case <-s.stop:
+			// Ship what is left, without retrying, as the process is exiting
+			for s.sendNext(0) {
+			}
+			return
+		}
+		for s.sendNext(s.opts.MaxRetries) {
+		}
+	}
+}
+
+// sendNext sends the next batch of pending events, retrying up to retries
+// times, and reports whether there may be more to send.
+func (s *Shipper) sendNext(retries int) bool {
+	s.mu.Lock()
+	if s.dropped > 0 {
+		log.Warn().Str("sink", s.sink.Name()).Int("dropped", s.dropped).Msg("Audit event buffer full, dropped the oldest events")
+		s.dropped = 0
+	}
+	n := min(len(s.pending), maxBatch)
+	batch := s.pending[:n:n]
+	s.pending = s.pending[n:]
+	s.mu.Unlock()
+	if n == 0 {
+		return false
+	}
+
+	delay := s.opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := s.sink.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			break
+		}
+		if attempt >= retries {
+			log.Warn().Err(err).Str("sink", s.sink.Name()).Int("events", n).Msg("Failed to ship audit events, dropping them")
+			break
+		}
+		log.Debug().Err(err).Str("sink", s.sink.Name()).Msgf("Failed to ship audit events, retrying in %s", delay)
+		select {
+		case <-time.After(delay):
+		case <-s.stop:
+			// Closing: try once more without waiting
+			retries = attempt + 1
+		}
+		delay *= 2
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending) > 0
+}
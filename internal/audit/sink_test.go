@@ -0,0 +1,268 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// fakeSink records the batches sent to it, failing the first failures
+// sends.
+type fakeSink struct {
+	mu       sync.Mutex
+	batches  [][]*AuditEvent
+	failures int
+	closed   bool
+}
+
+func (f *fakeSink) Name() string { return "fake" }
+
+func (f *fakeSink) Send(ctx context.Context, events []*AuditEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("unreachable")
+	}
+	f.batches = append(f.batches, events)
+	return nil
+}
+
+func (f *fakeSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeSink) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sessions []string
+	for _, b := range f.batches {
+		for _, e := range b {
+			sessions = append(sessions, e.SessionID)
+		}
+	}
+	return sessions
+}
+
+// waitFor waits up to a few seconds for cond to hold.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShipperRetries(t *testing.T) {
+	sink := &fakeSink{failures: 2}
+	s := NewShipper(sink, ShipperOptions{MaxRetries: 3, RetryDelay: time.Millisecond})
+	s.Enqueue(&AuditEvent{SessionID: "a"})
+	s.Enqueue(&AuditEvent{SessionID: "b"})
+	waitFor(t, func() bool { return len(sink.sent()) == 2 })
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(sink.sent(), ","); got != "a,b" {
+		t.Errorf("sent %q, want a,b after retries", got)
+	}
+	if !sink.closed {
+		t.Error("Close() did not close the sink")
+	}
+	s.Enqueue(&AuditEvent{SessionID: "c"})
+	if got := len(sink.sent()); got != 2 {
+		t.Errorf("events enqueued after Close() were sent")
+	}
+}
+
+func TestShipperDropsAfterRetries(t *testing.T) {
+	sink := &fakeSink{failures: 1}
+	s := NewShipper(sink, ShipperOptions{RetryDelay: time.Millisecond})
+	s.Enqueue(&AuditEvent{SessionID: "lost"})
+	waitFor(t, func() bool {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		return sink.failures == 0
+	})
+	s.Enqueue(&AuditEvent{SessionID: "kept"})
+	s.Close()
+
+	if got := strings.Join(sink.sent(), ","); got != "kept" {
+		t.Errorf("sent %q, want only the event after the failed batch", got)
+	}
+}
+
+func TestShipperBufferFull(t *testing.T) {
+	sink := &fakeSink{}
+	s := &Shipper{sink: sink, opts: ShipperOptions{BufferSize: 2}, wake: make(chan struct{}, 1)}
+	for _, id := range []string{"a", "b", "c"} {
+		s.Enqueue(&AuditEvent{SessionID: id})
+	}
+	if len(s.pending) != 2 || s.pending[0].SessionID != "b" || s.dropped != 1 {
+		t.Errorf("pending %d events from %s, dropped %d; want the 2 newest", len(s.pending), s.pending[0].SessionID, s.dropped)
+	}
+}
+
+func TestLoggerShipsEvents(t *testing.T) {
+	logger, err := NewLogger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &fakeSink{}
+	logger.AddSink(sink, ShipperOptions{})
+	if err := logger.StartSession(&Session{ID: "s1", ClusterName: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.EndSession("s1", ""); err != nil {
+		t.Fatal(err)
+	}
+	logger.Close()
+
+	if got := strings.Join(sink.sent(), ","); got != "s1,s1" {
+		t.Errorf("shipped sessions %q, want the connect and disconnect of s1", got)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got []AuditEvent
+	var auth string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL+"/hook?token=x", map[string]string{"Authorization": "Bearer abc"})
+	if strings.Contains(sink.Name(), "token") {
+		t.Errorf("Name() = %q shows the query", sink.Name())
+	}
+	events := []*AuditEvent{{EventType: EventTypeConnect, ClusterName: "prod"}}
+	if err := sink.Send(context.Background(), events); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ClusterName != "prod" || auth != "Bearer abc" {
+		t.Errorf("webhook got %+v with Authorization %q", got, auth)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := sink.Send(context.Background(), events); err == nil {
+		t.Error("Send() to a failing webhook succeeded")
+	}
+}
+
+func TestOCILoggingSink(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	sink := NewOCILoggingSink("ocid1.log.oc1.iad.aaaa", mock)
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := []*AuditEvent{{Timestamp: ts, EventType: EventTypeConnect, SessionID: "s1", ClusterName: "prod"}}
+	if err := sink.Send(context.Background(), events); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	entries := mock.LogEntries["ocid1.log.oc1.iad.aaaa"]
+	if len(entries) != 1 || !entries[0].Time.Equal(ts) || !strings.Contains(entries[0].Data, `"cluster_name":"prod"`) {
+		t.Fatalf("log entries = %+v", entries)
+	}
+	if err := sink.Send(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	if again := mock.LogEntries["ocid1.log.oc1.iad.aaaa"][1]; again.ID != entries[0].ID {
+		t.Errorf("entry sent again has ID %q, want %q", again.ID, entries[0].ID)
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no syslog on Windows")
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), "tunatap-test")
+	if err != nil {
+		t.Fatalf("NewSyslogSink() error = %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Send(context.Background(), []*AuditEvent{{EventType: EventTypeConnect, ClusterName: "prod"}}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := string(buf[:n]); !strings.Contains(msg, "tunatap-test") || !strings.Contains(msg, `"cluster_name":"prod"`) {
+		t.Errorf("syslog message = %q", msg)
+	}
+}
+
+func TestAddSinks(t *testing.T) {
+	logger, err := NewLogger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	mock := client.NewMockOCIClient()
+	var regions []string
+	deps := SinkDeps{
+		Resolve: func(ctx context.Context, value string) (string, error) {
+			if value == "vault:bad" {
+				return "", errors.New("no such secret")
+			}
+			return strings.TrimPrefix(value, "keychain:"), nil
+		},
+		LogClient: func(region string) (LogPutter, error) {
+			regions = append(regions, region)
+			return mock, nil
+		},
+	}
+	err = logger.AddSinks(context.Background(), []*config.AuditSink{
+		{Type: "webhook", URL: "https://audit.example.com", Headers: map[string]string{"Authorization": "keychain:token"}},
+		{Type: "oci_logging", LogID: "ocid1.log.oc1.phx.aaaa"},
+		{Type: "webhook", URL: "https://audit.example.com", Headers: map[string]string{"Authorization": "vault:bad"}},
+		{Type: "oci_logging", LogID: "ocid1.log.oc1.phx.bbbb", Region: "us-ashburn-1", MaxRetries: utils.IntPtr(0)},
+	}, deps)
+	if err == nil || !strings.Contains(err.Error(), "audit_sinks[2]") {
+		t.Errorf("AddSinks() error = %v, want that of the sink with a bad secret", err)
+	}
+	if len(logger.shippers) != 3 {
+		t.Fatalf("AddSinks() added %d sinks, want 3", len(logger.shippers))
+	}
+	if webhook := logger.shippers[0].sink.(*WebhookSink); webhook.headers["Authorization"] != "token" {
+		t.Errorf("webhook header = %q, want the resolved secret", webhook.headers["Authorization"])
+	}
+	if logger.shippers[0].opts.MaxRetries != DefaultMaxRetries || logger.shippers[2].opts.MaxRetries != 0 {
+		t.Error("AddSinks() did not apply max_retries")
+	}
+	if len(regions) != 2 || regions[1] != "us-ashburn-1" || regions[0] == "" {
+		t.Errorf("log clients created for regions %v", regions)
+	}
+}
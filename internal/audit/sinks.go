@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// SinkDeps are what AddSinks needs to create the sinks of a config.
+type SinkDeps struct {
+	// Resolve resolves a secret reference, such as a webhook header kept
+	// in Vault, or returns a plain value as it is.
+	Resolve func(ctx context.Context, value string) (string, error)
+	// LogClient returns the client to put entries in OCI Logging logs of
+	// region with.
+	LogClient func(region string) (LogPutter, error)
+}
+
+// AddSinks adds the sinks configured in sinks to l. Sinks that cannot be
+// created are left out, and their errors returned together, so that one
+// unreachable sink does not stop the others.
+func (l *Logger) AddSinks(ctx context.Context, sinks []*config.AuditSink, deps SinkDeps) error {
+	var errs []error
+	for i, sc := range sinks {
+		sink, err := newSink(ctx, sc, deps)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("audit_sinks[%d] (%s): %w", i, sc.Type, err))
+			continue
+		}
+		opts := ShipperOptions{MaxRetries: DefaultMaxRetries}
+		if sc.BufferSize != nil {
+			opts.BufferSize = *sc.BufferSize
+		}
+		if sc.MaxRetries != nil {
+			opts.MaxRetries = *sc.MaxRetries
+		}
+		l.AddSink(sink, opts)
+	}
+	return errors.Join(errs...)
+}
+
+// newSink creates the sink sc configures.
+func newSink(ctx context.Context, sc *config.AuditSink, deps SinkDeps) (Sink, error) {
+	switch sc.Type {
+	case "webhook":
+		headers := make(map[string]string, len(sc.Headers))
+		for name, value := range sc.Headers {
+			if deps.Resolve != nil {
+				resolved, err := deps.Resolve(ctx, value)
+				if err != nil {
+					return nil, fmt.Errorf("header %s: %w", name, err)
+				}
+				value = resolved
+			}
+			headers[name] = value
+		}
+		return NewWebhookSink(sc.URL, headers), nil
+
+	case "oci_logging":
+		if deps.LogClient == nil {
+			return nil, errors.New("no OCI client to put logs with")
+		}
+		region := sc.Region
+		if region == "" {
+			region = utils.ExtractRegionFromOCID(sc.LogID)
+		}
+		putter, err := deps.LogClient(region)
+		if err != nil {
+			return nil, err
+		}
+		return NewOCILoggingSink(sc.LogID, putter), nil
+
+	case "syslog":
+		tag := sc.Tag
+		if tag == "" {
+			tag = "tunatap"
+		}
+		return NewSyslogSink(sc.Network, sc.Address, tag)
+	}
+	return nil, fmt.Errorf("unknown audit sink type '%s'", sc.Type)
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogSink writes each event as a JSON message to syslog, at info
+// priority for connects and disconnects and warning for errors.
+type SyslogSink struct {
+	name   string
+	writer *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog server at address over network, or
+// to the local syslog daemon if network is empty, tagging messages with
+// tag.
+func NewSyslogSink(network, address, tag string) (*SyslogSink, error) {
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	name := "syslog"
+	if address != "" {
+		name = "syslog " + address
+	}
+	return &SyslogSink{name: name, writer: writer}, nil
+}
+
+// Name describes the syslog server.
+func (s *SyslogSink) Name() string {
+	return s.name
+}
+
+// Send writes events. The writer reconnects by itself if the connection
+// was lost.
+func (s *SyslogSink) Send(ctx context.Context, events []*AuditEvent) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		if event.EventType == EventTypeError {
+			err = s.writer.Warning(string(data))
+		} else {
+			err = s.writer.Info(string(data))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write to syslog: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to syslog.
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
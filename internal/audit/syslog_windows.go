@@ -0,0 +1,31 @@
+//go:build windows
+
+package audit
+
+import (
+	"context"
+	"errors"
+)
+
+// SyslogSink is not available on Windows, which has no syslog.
+type SyslogSink struct{}
+
+// NewSyslogSink fails on Windows, which has no syslog.
+func NewSyslogSink(network, address, tag string) (*SyslogSink, error) {
+	return nil, errors.New("syslog audit sinks are not supported on Windows")
+}
+
+// Name describes the sink.
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+// Send is never called, since no SyslogSink can be created.
+func (s *SyslogSink) Send(ctx context.Context, events []*AuditEvent) error {
+	return errors.New("syslog audit sinks are not supported on Windows")
+}
+
+// Close does nothing.
+func (s *SyslogSink) Close() error {
+	return nil
+}
@@ -0,0 +1,247 @@
+package autofix
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/secrets"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	FixTypeConfigPermissions FixType = "config_permissions"
+	FixTypeAuditLogging      FixType = "audit_logging"
+)
+
+// Severity is how risky the setting of an audit finding is.
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"
+	SeverityMedium Severity = "medium"
+	SeverityLow    Severity = "low"
+)
+
+// Finding is a risky setting found by Audit.
+type Finding struct {
+	// Check names the kind of risk, such as "config_permissions".
+	Check    string   `json:"check" yaml:"check"`
+	Severity Severity `json:"severity" yaml:"severity"`
+	Message  string   `json:"message" yaml:"message"`
+	// Suggestion is how to fix the setting by hand.
+	Suggestion string `json:"suggestion" yaml:"suggestion"`
+	// Fix fixes the setting; nil if it must be fixed by hand.
+	Fix *Fix `json:"-" yaml:"-"`
+}
+
+// Fixable reports whether the finding can be fixed by ApplySafe.
+func (fd *Finding) Fixable() bool {
+	return fd.Fix != nil && fd.Fix.Safe
+}
+
+// Audit checks cfg, read from the fixer's config path, for risky settings.
+// The fixes of its findings replace those of Diagnose, so that ApplySafe
+// fixes what Audit found.
+func (f *Fixer) Audit(cfg *config.Config) []*Finding {
+	var findings []*Finding
+	add := func(fd *Finding) {
+		findings = append(findings, fd)
+	}
+
+	f.fixes = make([]*Fix, 0)
+	f.auditConfigPermissions(add)
+	auditSSHKeys(cfg, add)
+	auditSecrets(cfg, add)
+	auditKubeconfigs(cfg, add)
+	if !cfg.IsAuditLoggingEnabled() {
+		add(&Finding{
+			Check:      "audit_logging",
+			Severity:   SeverityLow,
+			Message:    "audit logging is disabled, so tunnels leave no record of who connected where",
+			Suggestion: "remove audit_logging: false from the config",
+			Fix: &Fix{
+				Type:        FixTypeAuditLogging,
+				Description: "Enable audit logging",
+				Safe:        true,
+				Details:     f.configPath,
+			},
+		})
+	}
+
+	for _, fd := range findings {
+		if fd.Fix != nil {
+			f.fixes = append(f.fixes, fd.Fix)
+		}
+	}
+	return findings
+}
+
+// HasSeverity reports whether any of findings is at least as severe as
+// min.
+func HasSeverity(findings []*Finding, min Severity) bool {
+	for _, fd := range findings {
+		if severityRank(fd.Severity) >= severityRank(min) {
+			return true
+		}
+	}
+	return false
+}
+
+func severityRank(s Severity) int {
+	switch s {
+	case SeverityHigh:
+		return 3
+	case SeverityMedium:
+		return 2
+	case SeverityLow:
+		return 1
+	}
+	return 0
+}
+
+// auditConfigPermissions flags a config file other users can read. Windows
+// has no mode bits to check.
+func (f *Fixer) auditConfigPermissions(add func(*Finding)) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(f.configPath)
+	if err != nil {
+		return
+	}
+	mode := info.Mode().Perm()
+	if mode&0o077 == 0 {
+		return
+	}
+
+	severity := SeverityMedium
+	who := "other users"
+	if mode&0o004 != 0 {
+		severity = SeverityHigh
+		who = "every user"
+	}
+	add(&Finding{
+		Check:      "config_permissions",
+		Severity:   severity,
+		Message:    fmt.Sprintf("%s has mode %04o and can be read by %s", f.configPath, mode, who),
+		Suggestion: fmt.Sprintf("chmod 600 %s", f.configPath),
+		Fix: &Fix{
+			Type:        FixTypeConfigPermissions,
+			Description: fmt.Sprintf("Restrict %s to its owner", f.configPath),
+			Safe:        true,
+			Details:     f.configPath,
+		},
+	})
+}
+
+// auditSSHKeys flags SSH private keys of cfg that are not encrypted with a
+// passphrase. Keys that cannot be read are left to doctor.
+func auditSSHKeys(cfg *config.Config, add func(*Finding)) {
+	var keys []string
+	if cfg.SshPrivateKeyFile != "" && !cfg.UseEphemeralKeys {
+		keys = append(keys, cfg.SshPrivateKeyFile)
+	}
+	for _, c := range cfg.Clusters {
+		for _, hop := range c.Hops {
+			if hop.SshPrivateKeyFile != "" {
+				keys = append(keys, hop.SshPrivateKeyFile)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		path := utils.ExpandPath(key)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// Encrypted keys fail to parse without their passphrase
+		if _, err := ssh.ParseRawPrivateKey(data); err != nil {
+			continue
+		}
+		add(&Finding{
+			Check:      "ssh_key_passphrase",
+			Severity:   SeverityMedium,
+			Message:    fmt.Sprintf("SSH private key %s has no passphrase", key),
+			Suggestion: fmt.Sprintf("encrypt it with 'ssh-keygen -p -f %s' and set ssh_private_key_passphrase to a keychain: reference, or use use_ephemeral_keys: true", key),
+		})
+	}
+}
+
+// auditSecrets flags secrets kept in plain text in cfg rather than as
+// secret references.
+func auditSecrets(cfg *config.Config, add func(*Finding)) {
+	for _, s := range []struct{ key, value string }{
+		{"ssh_private_key_passphrase", cfg.SshPrivateKeyPassphrase},
+		{"ssh_socks_proxy_password", cfg.SshSocksProxyPassword},
+	} {
+		if s.value == "" || secrets.IsReference(s.value) {
+			continue
+		}
+		add(&Finding{
+			Check:      "plaintext_secret",
+			Severity:   SeverityMedium,
+			Message:    fmt.Sprintf("%s is kept in plain text in the config", s.key),
+			Suggestion: fmt.Sprintf("store it with 'tunatap config set-secret keychain:tunatap/%s' and set %s to that reference", s.key, s.key),
+		})
+	}
+}
+
+// auditKubeconfigs flags clusters whose kubeconfigs skip TLS verification
+// of the API server, or that cannot fetch its CA as neither the cluster's
+// OCID nor a compartment and tenant to look it up with are set.
+func auditKubeconfigs(cfg *config.Config, add func(*Finding)) {
+	for _, c := range cfg.Clusters {
+		if !c.ShouldEmbedCA() {
+			add(&Finding{
+				Check:      "kubeconfig_insecure",
+				Severity:   SeverityMedium,
+				Message:    fmt.Sprintf("kubeconfigs of cluster '%s' skip TLS verification of the API server (kubeconfig.embed_ca: false)", c.ClusterName),
+				Suggestion: fmt.Sprintf("remove kubeconfig.embed_ca: false from cluster '%s'", c.ClusterName),
+			})
+			continue
+		}
+		hasOCID := c.Ocid != nil && *c.Ocid != ""
+		canLookUp := (c.Compartment != nil || c.CompartmentOcid != nil) && c.Tenant != nil
+		if !hasOCID && !canLookUp {
+			add(&Finding{
+				Check:      "kubeconfig_ca",
+				Severity:   SeverityHigh,
+				Message:    fmt.Sprintf("cluster '%s' has no OCID, nor a compartment and tenant to look it up with, so the CA data of its kubeconfigs cannot be fetched", c.ClusterName),
+				Suggestion: fmt.Sprintf("set the ocid of cluster '%s'", c.ClusterName),
+			})
+		}
+	}
+}
+
+// fixConfigPermissions makes the config file readable by its owner only.
+func (f *Fixer) fixConfigPermissions() error {
+	if err := os.Chmod(f.configPath, 0o600); err != nil {
+		return fmt.Errorf("failed to change mode of %s: %w", f.configPath, err)
+	}
+	log.Info().Str("path", f.configPath).Msg("Restricted config file permissions")
+	return nil
+}
+
+// fixAuditLogging removes audit_logging: false from the config file.
+func (f *Fixer) fixAuditLogging() error {
+	cfg, err := config.ReadConfigFile(f.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg.AuditLogging = nil
+	if err := config.SaveConfig(f.configPath, cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	log.Info().Str("path", f.configPath).Msg("Enabled audit logging")
+	return nil
+}
@@ -0,0 +1,166 @@
+package autofix
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"golang.org/x/crypto/ssh"
+)
+
+// writeKey writes an ed25519 private key to path, encrypted if passphrase
+// is not empty.
+func writeKey(t *testing.T, path, passphrase string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func findingChecks(findings []*Finding) map[string]*Finding {
+	checks := make(map[string]*Finding)
+	for _, fd := range findings {
+		checks[fd.Check] = fd
+	}
+	return checks
+}
+
+func TestAudit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not checked on Windows")
+	}
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	plainKey := filepath.Join(dir, "plain")
+	writeKey(t, plainKey, "")
+
+	cfg := &config.Config{
+		SshPrivateKeyFile:       plainKey,
+		SshPrivateKeyPassphrase: "hunter2",
+		AuditLogging:            utils.BoolPtr(false),
+		Clusters: []*config.Cluster{
+			{ClusterName: "prod"},
+		},
+	}
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(configPath, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixer := NewFixer(configPath, false)
+	checks := findingChecks(fixer.Audit(cfg))
+	for check, severity := range map[string]Severity{
+		"config_permissions": SeverityHigh,
+		"ssh_key_passphrase": SeverityMedium,
+		"plaintext_secret":   SeverityMedium,
+		"kubeconfig_ca":      SeverityHigh,
+		"audit_logging":      SeverityLow,
+	} {
+		fd := checks[check]
+		if fd == nil {
+			t.Errorf("Audit() found no %s", check)
+			continue
+		}
+		if fd.Severity != severity {
+			t.Errorf("%s severity = %s, want %s", check, fd.Severity, severity)
+		}
+		if fd.Suggestion == "" {
+			t.Errorf("%s has no suggestion", check)
+		}
+	}
+	if _, ok := checks["kubeconfig_insecure"]; ok {
+		t.Error("Audit() found kubeconfig_insecure for a cluster that embeds the CA")
+	}
+
+	results := fixer.ApplySafe()
+	if len(results) != 2 {
+		t.Fatalf("ApplySafe() applied %d fixes, want 2", len(results))
+	}
+	for _, r := range results {
+		if !r.Applied {
+			t.Errorf("%s: %v", r.Fix.Description, r.Error)
+		}
+	}
+	if info, err := os.Stat(configPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("config mode after fix = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+	fixed, err := config.ReadConfigFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fixed.IsAuditLoggingEnabled() {
+		t.Error("audit logging still disabled after fix")
+	}
+	checks = findingChecks(fixer.Audit(fixed))
+	for _, check := range []string{"config_permissions", "audit_logging"} {
+		if _, ok := checks[check]; ok {
+			t.Errorf("Audit() after fixes still found %s", check)
+		}
+	}
+}
+
+func TestAuditClean(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	encryptedKey := filepath.Join(dir, "encrypted")
+	writeKey(t, encryptedKey, "s3cret")
+
+	cfg := &config.Config{
+		SshPrivateKeyFile:       encryptedKey,
+		SshPrivateKeyPassphrase: "keychain:tunatap/ssh-passphrase",
+		Clusters: []*config.Cluster{{
+			ClusterName: "prod",
+			Ocid:        utils.StringPtr("ocid1.cluster.oc1.iad.aaaa"),
+		}},
+	}
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := NewFixer(configPath, false).Audit(cfg)
+	if len(findings) != 0 {
+		for _, fd := range findings {
+			t.Errorf("Audit() found %s: %s", fd.Check, fd.Message)
+		}
+	}
+	if HasSeverity(findings, SeverityLow) {
+		t.Error("HasSeverity() = true without findings")
+	}
+}
+
+func TestAuditInsecureKubeconfig(t *testing.T) {
+	cfg := &config.Config{Clusters: []*config.Cluster{{
+		ClusterName: "dev",
+		Ocid:        utils.StringPtr("ocid1.cluster.oc1.iad.aaaa"),
+		Kubeconfig:  &config.ClusterKubeconfig{EmbedCA: utils.BoolPtr(false)},
+	}}}
+	findings := NewFixer(filepath.Join(t.TempDir(), "config.yaml"), false).Audit(cfg)
+	fd := findingChecks(findings)["kubeconfig_insecure"]
+	if fd == nil || fd.Severity != SeverityMedium || fd.Fixable() {
+		t.Fatalf("kubeconfig_insecure finding = %+v", fd)
+	}
+	if HasSeverity(findings, SeverityHigh) || !HasSeverity(findings, SeverityLow) {
+		t.Error("HasSeverity() does not rank a medium finding as medium")
+	}
+}
@@ -135,6 +135,12 @@ func (f *Fixer) ApplyFix(fix *Fix) FixResult {
 	case FixTypeTunaConfig:
 		err = f.fixTunaConfig()
 		message = "Created default tunatap config"
+	case FixTypeConfigPermissions:
+		err = f.fixConfigPermissions()
+		message = "Restricted config file to its owner"
+	case FixTypeAuditLogging:
+		err = f.fixAuditLogging()
+		message = "Enabled audit logging"
 	default:
 		err = fmt.Errorf("unknown fix type: %s", fix.Type)
 	}
@@ -2,18 +2,26 @@ package bastion
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"os"
-	"os/exec"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/scotttball/tunatap/internal/audit"
 	"github.com/scotttball/tunatap/internal/client"
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/events"
+	"github.com/scotttball/tunatap/internal/exitcode"
 	"github.com/scotttball/tunatap/internal/health"
+	"github.com/scotttball/tunatap/internal/hooks"
+	"github.com/scotttball/tunatap/internal/pool"
+	"github.com/scotttball/tunatap/internal/tracing"
 	"github.com/scotttball/tunatap/internal/tunnel"
 	"github.com/scotttball/tunatap/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -23,19 +31,80 @@ type TunnelOptions struct {
 	AuditLogger *audit.Logger
 	// OnReady is called when the tunnel is ready with the actual port
 	OnReady ReadyCallback
+	// SocksPort, if non-zero, exposes a local SOCKS5 proxy on this port that
+	// routes arbitrary destinations through the bastion SSH connection
+	SocksPort int
+	// AdditionalEndpoints are forwarded alongside the primary endpoint over
+	// the same bastion session, each on its own configured local port
+	AdditionalEndpoints []*config.ClusterEndpoint
+	// MaxBandwidth, if non-zero, overrides the cluster's max_bandwidth
+	// (bytes per second across the whole tunnel)
+	MaxBandwidth int64
+	// Events receives lifecycle events; nil disables them.
+	Events *events.Writer
+	// EndpointWatchInterval, if non-zero, overrides the cluster endpoint
+	// watch interval from config
+	EndpointWatchInterval time.Duration
+	// EndpointChangeAction, if set, overrides the endpoint_change_action
+	// from config
+	EndpointChangeAction EndpointChangeAction
 }
 
-// bastionBackoffConfig returns the backoff configuration for bastion retries.
-func bastionBackoffConfig() *utils.BackoffConfig {
+// bastionBackoffConfig returns the backoff configuration for bastion
+// retries from a retry policy.
+func bastionBackoffConfig(policy *config.RetryPolicy) (*utils.BackoffConfig, error) {
+	initial := policy.GetInitialIntervalSeconds()
+	maxInterval := policy.GetMaxIntervalSeconds()
+	if initial < 1 {
+		return nil, fmt.Errorf("invalid retry initial_interval_seconds %d: must be at least 1", initial)
+	}
+	if maxInterval < initial {
+		return nil, fmt.Errorf("invalid retry max_interval_seconds %d: must be at least initial_interval_seconds (%d)", maxInterval, initial)
+	}
+	if policy.GetMultiplier() < 1 {
+		return nil, fmt.Errorf("invalid retry multiplier %g: must be at least 1", policy.GetMultiplier())
+	}
+	if policy.GetMaxAttempts() < 0 {
+		return nil, fmt.Errorf("invalid retry max_attempts %d: must not be negative", policy.GetMaxAttempts())
+	}
+
 	return &utils.BackoffConfig{
-		InitialInterval: 5 * time.Second,
-		MaxInterval:     2 * time.Minute,
-		Multiplier:      1.5,
+		InitialInterval: time.Duration(initial) * time.Second,
+		MaxInterval:     time.Duration(maxInterval) * time.Second,
+		Multiplier:      policy.GetMultiplier(),
 		JitterFactor:    0.3,
-		MaxAttempts:     15,
-	}
+		MaxAttempts:     policy.GetMaxAttempts(),
+	}, nil
+}
+
+// permanentError marks a tunnel error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// permanent marks err as an error that retrying cannot fix.
+func permanent(err error) error {
+	return &permanentError{err: err}
 }
 
+// isPermanent reports whether retrying after err is pointless, because the
+// configuration is invalid or OCI refused the request for good.
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p) || client.IsPermanentError(err)
+}
+
+// statsReportInterval is how often tunnel statistics are published to the health registry.
+const statsReportInterval = 5 * time.Second
+
 // ReadyCallback is called when the tunnel is ready with the actual port.
 type ReadyCallback func(port int)
 
@@ -55,12 +124,17 @@ func TunnelThroughBastionWithOptions(ctx context.Context, ociClient *client.OCIC
 		opts = &TunnelOptions{}
 	}
 
-	backoff := utils.NewBackoff(bastionBackoffConfig())
+	// Fail fast on settings that would otherwise fail every retry
+	settings, err := validateTunnel(cfg, cluster, endpoint, opts)
+	if err != nil {
+		return err
+	}
 
-	// Default bastion type to STANDARD if not set
-	bastionType := "STANDARD"
-	if cluster.BastionType != nil {
-		bastionType = *cluster.BastionType
+	backoffConfig := settings.backoff
+	backoff := utils.NewBackoff(backoffConfig)
+	bastionType := clusterBastionType(cluster)
+	if !isLoopbackAddress(settings.bindAddress) {
+		log.Warn().Msgf("Tunnel will listen on %s and accept connections from other hosts; anyone who can reach it can use the tunnel", settings.bindAddress)
 	}
 
 	// Generate a session ID for audit/health tracking
@@ -76,7 +150,7 @@ func TunnelThroughBastionWithOptions(ctx context.Context, ociClient *client.OCIC
 		ClusterName: cluster.ClusterName,
 		Region:      cluster.Region,
 		LocalPort:   *cluster.LocalPort,
-		RemoteHost:  endpoint.Ip,
+		RemoteHost:  endpoint.Host(),
 		RemotePort:  endpoint.Port,
 		BastionID:   bastionID,
 	}
@@ -88,11 +162,17 @@ func TunnelThroughBastionWithOptions(ctx context.Context, ociClient *client.OCIC
 		Cluster:    cluster.ClusterName,
 		Region:     cluster.Region,
 		LocalPort:  *cluster.LocalPort,
-		RemoteHost: endpoint.Ip,
+		RemoteHost: endpoint.Host(),
 		RemotePort: endpoint.Port,
 		Healthy:    false, // Will be set to true once tunnel is ready
 	}
+	if cluster.LocalSocket != nil {
+		tunnelStatus.LocalSocket = *cluster.LocalSocket
+	}
 	healthRegistry.Register(tunnelStatus)
+	publishCtx, stopPublishing := context.WithCancel(ctx)
+	defer stopPublishing()
+	healthRegistry.Publish(publishCtx, health.DefaultStatusDir())
 
 	// Track whether tunnel was ever healthy (for audit logging)
 	var tunnelWasHealthy bool
@@ -113,14 +193,32 @@ func TunnelThroughBastionWithOptions(ctx context.Context, ociClient *client.OCIC
 		}
 	}()
 
+	follower := startEndpointWatch(ctx, ociClient, cfg, cluster, endpoint, settings.endpointChangeAction, opts)
+
 	for {
 		log.Debug().Msgf("Connection attempt %d", backoff.Attempt()+1)
 
+		attemptCtx, endAttempt := follower.attempt(ctx)
+		attemptCtx, span := startAttemptSpan(attemptCtx, cluster, backoff.Attempt()+1, tunnelWasHealthy)
 		var err error
 		if bastionType == "INTERNAL" {
-			err = handleInternalBastionWithOptions(ctx, cluster, endpoint, sessionID, opts, healthRegistry, auditSession, &tunnelWasHealthy)
+			err = handleInternalBastionWithOptions(attemptCtx, cfg, cluster, endpoint, settings, sessionID, opts, healthRegistry, auditSession, &tunnelWasHealthy)
 		} else {
-			err = handleStandardBastionWithOptions(ctx, ociClient, cfg, cluster, endpoint, sessionID, opts, healthRegistry, auditSession, &tunnelWasHealthy)
+			err = handleStandardBastionWithOptions(attemptCtx, ociClient, cfg, cluster, endpoint, settings, sessionID, opts, healthRegistry, auditSession, &tunnelWasHealthy)
+		}
+		// Already ended if the tunnel got ready
+		tracing.End(span, err)
+		endAttempt()
+
+		// The endpoint moved: tunnel to the new one without waiting
+		if moved := follower.takeMove(); moved != nil && ctx.Err() == nil {
+			log.Warn().Msgf("Reconnecting cluster '%s' to its new endpoint %s", cluster.ClusterName, moved.Endpoint())
+			endpoint.Ip, endpoint.Port = moved.EndpointIP, moved.EndpointPort
+			auditSession.RemoteHost, auditSession.RemotePort = moved.EndpointIP, moved.EndpointPort
+			healthRegistry.UpdateRemote(sessionID, moved.EndpointIP, moved.EndpointPort)
+			healthRegistry.RecordReconnect(sessionID)
+			backoff.Reset()
+			continue
 		}
 
 		if err == nil {
@@ -143,17 +241,29 @@ func TunnelThroughBastionWithOptions(ctx context.Context, ociClient *client.OCIC
 		default:
 		}
 
+		if isPermanent(err) {
+			log.Error().Msg("Not retrying, the error will not go away on its own")
+			return exitcode.Wrap(exitcode.TunnelFailed, err)
+		}
+
 		// Get next backoff duration
 		duration, shouldRetry := backoff.Next()
 		if !shouldRetry {
 			lastError = fmt.Errorf("max retry attempts (%d) exceeded: %w", backoff.Attempt(), err)
-			return lastError
+			return exitcode.Wrap(exitcode.TunnelFailed, lastError)
 		}
 
 		log.Info().Msgf("Retrying in %s (attempt %d/%d)",
 			duration.Round(time.Millisecond),
 			backoff.Attempt(),
-			bastionBackoffConfig().MaxAttempts)
+			backoffConfig.MaxAttempts)
+		opts.Events.Emit(events.Event{
+			Type:      events.Reconnecting,
+			Cluster:   cluster.ClusterName,
+			Attempt:   backoff.Attempt(),
+			RetryInMs: duration.Milliseconds(),
+			Error:     err.Error(),
+		})
 
 		// Sleep with context awareness
 		select {
@@ -162,75 +272,274 @@ func TunnelThroughBastionWithOptions(ctx context.Context, ociClient *client.OCIC
 			return ctx.Err()
 		case <-time.After(duration):
 		}
+
+		healthRegistry.RecordReconnect(sessionID)
 	}
 }
 
-// handleInternalBastionWithOptions handles tunneling through an internal bastion with full options.
-func handleInternalBastionWithOptions(ctx context.Context, cluster *config.Cluster, endpoint *config.ClusterEndpoint, sessionID string, opts *TunnelOptions, healthRegistry *health.Registry, auditSession *audit.Session, tunnelWasHealthy *bool) error {
-	log.Info().Msg("Using internal bastion service")
+// startAttemptSpan starts the span of a connection attempt, which ends once
+// the tunnel is ready. Attempts after the tunnel was up start a trace of
+// their own, as the connect that started it is long over.
+func startAttemptSpan(ctx context.Context, cluster *config.Cluster, attempt int, reconnect bool) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{tracing.ClusterKey.String(cluster.ClusterName), tracing.AttemptKey.Int(attempt)}
+	if reconnect {
+		return tracing.StartRoot(ctx, "tunnel.reconnect", attrs...)
+	}
+	return tracing.Start(ctx, "tunnel.connect", attrs...)
+}
+
+// tunnelSettings are the settings of a tunnel parsed by validateTunnel.
+type tunnelSettings struct {
+	bindAddress          string
+	idleAction           tunnel.IdleAction
+	endpointChangeAction EndpointChangeAction
+	maxBandwidth         int64
+	maxConnBandwidth     int64
+	backoff              *utils.BackoffConfig
+}
+
+// validateTunnel checks the settings of a tunnel to endpoint that would
+// otherwise fail every connection attempt, and returns them parsed.
+func validateTunnel(cfg *config.Config, cluster *config.Cluster, endpoint *config.ClusterEndpoint, opts *TunnelOptions) (*tunnelSettings, error) {
+	var settings tunnelSettings
+	var err error
+	if settings.idleAction, err = tunnel.ParseIdleAction(cfg.IdleAction); err != nil {
+		return nil, err
+	}
+	if settings.endpointChangeAction, err = ParseEndpointChangeAction(cfg.EndpointChangeAction); err != nil {
+		return nil, err
+	}
+	if settings.maxBandwidth, settings.maxConnBandwidth, err = bandwidthLimits(cluster, opts); err != nil {
+		return nil, err
+	}
+	if err := validateSessionTTL(cfg, cluster); err != nil {
+		return nil, err
+	}
+	if endpoint.Host() == "" {
+		return nil, fmt.Errorf("endpoint '%s' of cluster '%s' has no ip or fqdn configured", endpoint.Name, cluster.ClusterName)
+	}
+	if _, err := keyRotator(cfg); err != nil {
+		return nil, err
+	}
+	if settings.backoff, err = bastionBackoffConfig(cfg.GetRetryPolicy(cluster)); err != nil {
+		return nil, err
+	}
+	if err := validateHops(cluster, clusterBastionType(cluster)); err != nil {
+		return nil, err
+	}
+	if clusterBastionType(cluster) == "INTERNAL" && cluster.JumpBoxIP == nil {
+		return nil, fmt.Errorf("jumpbox_ip setting is required for internal bastion service")
+	}
+	if settings.bindAddress, err = clusterBindAddress(cluster); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// clusterBastionType returns the type of the cluster's bastion, STANDARD
+// if it is not known.
+func clusterBastionType(cluster *config.Cluster) string {
+	if cluster.BastionType != nil {
+		return *cluster.BastionType
+	}
+	return "STANDARD"
+}
 
-	if cluster.JumpBoxIP == nil {
-		return fmt.Errorf("jumpbox_ip setting is required for internal bastion service")
+// sessionTarget returns the endpoint a bastion session for a tunnel to
+// endpoint targets. With hops that is the first hop, not the endpoint.
+func sessionTarget(cluster *config.Cluster, endpoint *config.ClusterEndpoint) *config.ClusterEndpoint {
+	if len(cluster.Hops) == 0 {
+		return endpoint
 	}
+	first := cluster.Hops[0]
+	return &config.ClusterEndpoint{Name: endpoint.Name, Ip: first.Host, Port: first.GetPort()}
+}
 
-	bastionLB := fmt.Sprintf("ztb-internal.bastion.%s.oci.oracleiaas.com", cluster.Region)
+// internalBastionHost returns the load balancer of the internal bastion
+// service in region.
+func internalBastionHost(region string) string {
+	return fmt.Sprintf("ztb-internal.bastion.%s.oci.oracleiaas.com", region)
+}
+
+// ClusterBastionHost returns the bastion service host name for cluster's
+// standard bastion: the configured bastion_host if there is one, or else the
+// host derived from the bastion's realm and the cluster's region.
+func ClusterBastionHost(cfg *config.Config, cluster *config.Cluster) string {
+	if host := cfg.GetBastionHost(cluster); host != "" {
+		return host
+	}
+	return GetBastionHost(*cluster.BastionId, cluster.Region)
+}
 
+// internalTunnelCommand returns the ssh command equivalent to a tunnel
+// through an internal bastion.
+func internalTunnelCommand(cluster *config.Cluster, endpoint *config.ClusterEndpoint, bindAddress string, opts *TunnelOptions) string {
 	sshCmd := GetInternalTunnelCommand(
 		*cluster.LocalPort,
 		endpoint.Port,
-		endpoint.Ip,
+		endpoint.Host(),
 		*cluster.BastionId,
 		*cluster.JumpBoxIP,
 		cluster.Region,
 		*cluster.CompartmentOcid,
-		bastionLB,
+		internalBastionHost(cluster.Region),
 	)
+	return addTunnelForwards(sshCmd, cluster, bindAddress, opts)
+}
 
-	log.Info().Msgf("Creating ssh tunnel. The equivalent ssh command is:\n%s\nYou can now use kubectl in another terminal", sshCmd)
+// standardTunnelCommand returns the ssh command equivalent to a tunnel
+// through the standard bastion session bastionSessionID.
+func standardTunnelCommand(cfg *config.Config, cluster *config.Cluster, endpoint *config.ClusterEndpoint, bastionSessionID, bindAddress string, opts *TunnelOptions) string {
+	var sshCmd string
+	if len(cluster.Hops) > 0 {
+		hops := make([]string, len(cluster.Hops))
+		for i, hop := range cluster.Hops {
+			hops[i] = fmt.Sprintf("%s@%s", hop.GetUser(), FormatRemoteAddress(hop.Host, hop.GetPort()))
+		}
+		sshCmd = GetHopTunnelCommand(
+			cfg.SshPrivateKeyFile,
+			*cluster.LocalPort,
+			endpoint.Port,
+			endpoint.Host(),
+			bastionSessionID,
+			ClusterBastionHost(cfg, cluster),
+			hops,
+		)
+	} else {
+		sshCmd = GetTunnelCommand(
+			cfg.SshPrivateKeyFile,
+			*cluster.LocalPort,
+			endpoint.Port,
+			endpoint.Host(),
+			bastionSessionID,
+			ClusterBastionHost(cfg, cluster),
+			cfg.SshSocksProxy,
+		)
+	}
+	return addTunnelForwards(sshCmd, cluster, bindAddress, opts)
+}
 
-	// Mark tunnel as healthy, start audit session, and call ready callback
-	healthRegistry.UpdateHealth(sessionID, true, "")
-	*tunnelWasHealthy = true
-	if opts.AuditLogger != nil {
-		if err := opts.AuditLogger.StartSession(auditSession); err != nil {
-			log.Warn().Err(err).Msg("Failed to start audit session")
+// addTunnelForwards adds the cluster's local socket and bind address and
+// the options' SOCKS proxy and additional endpoints to an ssh command.
+func addTunnelForwards(sshCmd string, cluster *config.Cluster, bindAddress string, opts *TunnelOptions) string {
+	if cluster.LocalSocket != nil {
+		sshCmd = UseLocalSocket(sshCmd, *cluster.LocalPort, *cluster.LocalSocket)
+	}
+	sshCmd = AddDynamicForward(sshCmd, opts.SocksPort)
+	for _, ep := range opts.AdditionalEndpoints {
+		// OpenSSH has no UDP forwarding, so UDP endpoints have no equivalent
+		if !ep.IsUDP() {
+			sshCmd = AddLocalForward(sshCmd, *ep.LocalPort, ep.Port, ep.Host())
 		}
 	}
-	if opts.OnReady != nil {
-		opts.OnReady(*cluster.LocalPort)
+	return UseBindAddress(sshCmd, bindAddress)
+}
+
+// internalJumpBoxUser is the user logged in to on an internal bastion's jump box.
+const internalJumpBoxUser = "opc"
+
+// handleInternalBastionWithOptions handles tunneling through an internal bastion with full options.
+// The bastion load balancer proxies to the jump box, which forwards to the
+// endpoint, like the ssh ProxyCommand shown in the log.
+func handleInternalBastionWithOptions(ctx context.Context, cfg *config.Config, cluster *config.Cluster, endpoint *config.ClusterEndpoint, settings *tunnelSettings, auditSessionID string, opts *TunnelOptions, healthRegistry *health.Registry, auditSession *audit.Session, tunnelWasHealthy *bool) error {
+	log.Info().Msg("Using internal bastion service")
+
+	bindAddress := settings.bindAddress
+
+	bastionLB := internalBastionHost(cluster.Region)
+	sshCmd := internalTunnelCommand(cluster, endpoint, bindAddress, opts)
+
+	log.Info().Msgf("Creating ssh tunnel. The equivalent ssh command is:\n%s\nYou can now use kubectl in another terminal", sshCmd)
+
+	bastionConfig, err := tunnel.CreateSSHClientConfigWithAgent(*cluster.BastionId, cfg.SshPrivateKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to create SSH config for bastion: %w", err)
+	}
+	jumpBoxConfig, err := tunnel.CreateSSHClientConfigWithAgent(internalJumpBoxUser, cfg.SshPrivateKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to create SSH config for jump box: %w", err)
 	}
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", sshCmd)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	tun := tunnel.NewSSHTunnel(
+		FormatBindAddress(bindAddress, *cluster.LocalPort),
+		FormatRemoteAddress(bastionLB, 22),
+		bastionConfig,
+		FormatRemoteAddress(endpoint.Host(), endpoint.Port),
+		cfg.GetPoolSize(),
+		cfg.GetWarmupCount(),
+		cfg.GetMaxConcurrent(),
+		cfg.SshSocksProxy,
+	)
+	tun.AddHop(FormatRemoteAddress(*cluster.JumpBoxIP, 22), jumpBoxConfig)
+	configureTunnel(tun, cfg, cluster, settings, opts)
 
-	return cmd.Run()
+	hookEnv := newHookEnv(cluster, endpoint, bindAddress, "")
+	return runTunnel(ctx, tun, cluster, bindAddress, opts, healthRegistry, auditSessionID, auditSession, tunnelWasHealthy, hookEnv)
 }
 
 // handleStandardBastionWithOptions handles tunneling through a standard bastion service with full options.
-func handleStandardBastionWithOptions(ctx context.Context, ociClient *client.OCIClient, cfg *config.Config, cluster *config.Cluster, endpoint *config.ClusterEndpoint, auditSessionID string, opts *TunnelOptions, healthRegistry *health.Registry, auditSession *audit.Session, tunnelWasHealthy *bool) error {
+func handleStandardBastionWithOptions(ctx context.Context, ociClient *client.OCIClient, cfg *config.Config, cluster *config.Cluster, endpoint *config.ClusterEndpoint, settings *tunnelSettings, auditSessionID string, opts *TunnelOptions, healthRegistry *health.Registry, auditSession *audit.Session, tunnelWasHealthy *bool) error {
 	var bastionSessionID string
 	var sshConfig ssh.ClientConfig
+	bindAddress := settings.bindAddress
+
+	sessionEndpoint := sessionTarget(cluster, endpoint)
 
 	log.Info().Msg("Getting bastion session...")
-	err := UpdateBastionConnection(ctx, &bastionSessionID, &sshConfig, ociClient, cfg, cluster, endpoint)
+	sessionCtx, span := tracing.Start(ctx, "bastion.session")
+	err := getFailoverSession(sessionCtx, &bastionSessionID, &sshConfig, ociClient, cfg, cluster, sessionEndpoint)
+	if err == nil {
+		span.SetAttributes(tracing.BastionKey.String(*cluster.BastionId), tracing.SessionKey.String(bastionSessionID))
+	}
+	tracing.End(span, err)
 	if err != nil {
-		return fmt.Errorf("failed to get session from Bastion: %w", err)
+		return exitcode.Wrap(exitcode.BastionUnavailable, fmt.Errorf("failed to get session from Bastion: %w", err))
 	}
+	// Failover may have switched bastions
+	auditSession.BastionID = *cluster.BastionId
 
 	log.Info().Msgf("Using session: %s", bastionSessionID)
+	healthRegistry.SetSession(auditSessionID, bastionSessionID)
+	opts.Events.Emit(events.Event{Type: events.SessionCreated, Cluster: cluster.ClusterName, SessionID: bastionSessionID})
 
-	sshCmd := GetTunnelCommand(
-		cfg.SshPrivateKeyFile,
-		*cluster.LocalPort,
-		endpoint.Port,
-		endpoint.Ip,
-		bastionSessionID,
-		cluster.Region,
+	sshCmd := standardTunnelCommand(cfg, cluster, endpoint, bastionSessionID, bindAddress, opts)
+
+	log.Info().Msgf("Creating ssh tunnel. The equivalent ssh command is:\n%s\nYou can now use kubectl in another terminal", sshCmd)
+
+	// Establish SSH tunnel
+	bastionAddr := ClusterBastionHost(cfg, cluster) + ":22"
+	localAddr := FormatBindAddress(bindAddress, *cluster.LocalPort)
+	remoteTunnel := fmt.Sprintf("localhost:%d", endpoint.Port)
+	if len(cluster.Hops) > 0 {
+		// The last hop reaches the endpoint directly
+		remoteTunnel = FormatRemoteAddress(endpoint.Host(), endpoint.Port)
+	}
+
+	tun := tunnel.NewSSHTunnel(
+		localAddr,
+		bastionAddr,
+		&sshConfig,
+		remoteTunnel,
+		cfg.GetPoolSize(),
+		cfg.GetWarmupCount(),
+		cfg.GetMaxConcurrent(),
 		cfg.SshSocksProxy,
 	)
 
-	log.Info().Msgf("Creating ssh tunnel. The equivalent ssh command is:\n%s\nYou can now use kubectl in another terminal", sshCmd)
+	for _, hop := range cluster.Hops {
+		keyFile := hop.SshPrivateKeyFile
+		if keyFile == "" {
+			keyFile = cfg.SshPrivateKeyFile
+		}
+		hopConfig, err := tunnel.CreateSSHClientConfigWithAgent(hop.GetUser(), keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to create SSH config for hop %s: %w", hop.Host, err)
+		}
+		tun.AddHop(FormatRemoteAddress(hop.Host, hop.GetPort()), hopConfig)
+	}
+
+	configureTunnel(tun, cfg, cluster, settings, opts)
+	hookEnv := newHookEnv(cluster, endpoint, bindAddress, bastionSessionID)
 
 	// Start periodic session refresh
 	ticker := time.NewTicker(30 * time.Second)
@@ -243,9 +552,26 @@ func handleStandardBastionWithOptions(ctx context.Context, ociClient *client.OCI
 				return
 			case <-ticker.C:
 				log.Debug().Msg("Periodic update check of bastion session...")
-				if err := UpdateBastionConnection(ctx, &bastionSessionID, &sshConfig, ociClient, cfg, cluster, endpoint); err != nil {
+				previousSessionID := bastionSessionID
+				if err := UpdateBastionConnection(ctx, &bastionSessionID, &sshConfig, ociClient, cfg, cluster, sessionEndpoint); err != nil {
 					log.Error().Err(err).Msg("Failed to update bastion connection")
-				} else if opts.AuditLogger != nil {
+					if ctx.Err() == nil {
+						opts.Events.Emit(events.Event{Type: events.RefreshFailed, Cluster: cluster.ClusterName, Error: err.Error()})
+					}
+					continue
+				}
+				healthRegistry.SetSession(auditSessionID, bastionSessionID)
+				if bastionSessionID != previousSessionID {
+					healthRegistry.RecordSessionRefresh(auditSessionID)
+					opts.Events.Emit(events.Event{Type: events.Refresh, Cluster: cluster.ClusterName, SessionID: bastionSessionID})
+					go handOffSession(ctx, ociClient, *cluster.BastionId, previousSessionID, tun)
+
+					refreshEnv := hookEnv
+					refreshEnv.BastionID = *cluster.BastionId
+					refreshEnv.SessionID = bastionSessionID
+					runHook(cluster, hooks.Refresh, refreshEnv)
+				}
+				if opts.AuditLogger != nil {
 					// Log session refresh event (ignore errors as this is non-critical)
 					_ = opts.AuditLogger.LogSessionRefresh(auditSessionID, bastionSessionID)
 				}
@@ -253,28 +579,74 @@ func handleStandardBastionWithOptions(ctx context.Context, ociClient *client.OCI
 		}
 	}()
 
-	// Establish SSH tunnel
-	bastionAddr := GetBastionHostAddress(*cluster.BastionId, cluster.Region)
-	localAddr := fmt.Sprintf("localhost:%d", *cluster.LocalPort)
-	remoteTunnel := fmt.Sprintf("localhost:%d", endpoint.Port)
+	return runTunnel(ctx, tun, cluster, bindAddress, opts, healthRegistry, auditSessionID, auditSession, tunnelWasHealthy, hookEnv)
+}
 
-	tun := tunnel.NewSSHTunnel(
-		localAddr,
-		bastionAddr,
-		&sshConfig,
-		remoteTunnel,
-		cfg.GetPoolSize(),
-		cfg.GetWarmupCount(),
-		cfg.GetMaxConcurrent(),
-		cfg.SshSocksProxy,
-	)
+// configureTunnel applies the cluster's and the options' listener, pool,
+// bandwidth, keepalive, idle and forwarding settings to tun.
+func configureTunnel(tun *tunnel.SSHTunnel, cfg *config.Config, cluster *config.Cluster, settings *tunnelSettings, opts *TunnelOptions) {
+	if cluster.LocalSocket != nil && *cluster.LocalSocket != "" {
+		tun.ListenOnSocket(*cluster.LocalSocket)
+	}
+
+	if autoscale := cfg.SshConnectionPoolAutoscale; autoscale != nil {
+		tun.Autoscale = &pool.AutoscaleConfig{
+			MinSize:           autoscale.GetMin(),
+			MaxSize:           autoscale.GetMax(),
+			ScaleUpCooldown:   time.Duration(autoscale.GetScaleUpCooldownSeconds()) * time.Second,
+			ScaleDownCooldown: time.Duration(autoscale.GetScaleDownCooldownSeconds()) * time.Second,
+		}
+	}
+
+	if settings.maxBandwidth > 0 || settings.maxConnBandwidth > 0 {
+		tun.SetBandwidthLimits(settings.maxBandwidth, settings.maxConnBandwidth)
+	}
+
+	tun.SetKeepalive(time.Duration(cfg.GetKeepaliveInterval())*time.Second, cfg.GetKeepaliveMax())
+
+	if minutes := cfg.GetIdleTimeoutMinutes(); minutes > 0 {
+		tun.SetIdleTimeout(time.Duration(minutes)*time.Minute, settings.idleAction)
+	}
+
+	if opts.SocksPort != 0 {
+		tun.EnableDynamicForwarding(fmt.Sprintf("localhost:%d", opts.SocksPort))
+	}
+
+	for _, ep := range opts.AdditionalEndpoints {
+		if ep.IsUDP() {
+			tun.AddUDPForward(FormatBindAddress(settings.bindAddress, *ep.LocalPort), FormatRemoteAddress(ep.Host(), ep.Port))
+			continue
+		}
+		tun.AddForward(FormatBindAddress(settings.bindAddress, *ep.LocalPort), FormatRemoteAddress(ep.Host(), ep.Port))
+	}
+}
+
+// runTunnel starts tun and blocks until it fails, idles out or ctx is
+// cancelled. Once the tunnel is ready it is marked healthy, the audit session
+// starts and the ready callback and event fire; when it ends, its traffic is
+// added to the audit session.
+func runTunnel(ctx context.Context, tun *tunnel.SSHTunnel, cluster *config.Cluster, bindAddress string, opts *TunnelOptions, healthRegistry *health.Registry, auditSessionID string, auditSession *audit.Session, tunnelWasHealthy *bool, hookEnv hooks.Env) (err error) {
+	runHook(cluster, hooks.Connect, hookEnv)
+	defer func() {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			hookEnv.Error = err.Error()
+		}
+		runHook(cluster, hooks.Disconnect, hookEnv)
+	}()
 
 	// Start tunnel asynchronously and wait for it to be ready
+	_, dialSpan := tracing.Start(ctx, "ssh.dial")
 	errCh := tun.StartAsync()
 
 	// Wait for tunnel to be ready
 	select {
 	case <-tun.Ready:
+		dialSpan.End()
+		// The attempt's span covers getting the tunnel ready, not its lifetime
+		attemptSpan := trace.SpanFromContext(ctx)
+		attemptSpan.AddEvent("tunnel ready")
+		attemptSpan.End()
+
 		// Tunnel is ready - mark healthy, start audit session, call callback
 		healthRegistry.UpdateHealth(auditSessionID, true, "")
 		*tunnelWasHealthy = true
@@ -283,19 +655,44 @@ func handleStandardBastionWithOptions(ctx context.Context, ociClient *client.OCI
 				log.Warn().Err(err).Msg("Failed to start audit session")
 			}
 		}
+		// Count what the tunnel carried towards the audit session once it ends
+		defer func() {
+			stats := tun.Stats()
+			auditSession.AddTraffic(audit.Traffic{
+				BytesIn:         stats.BytesIn,
+				BytesOut:        stats.BytesOut,
+				PeakConnections: stats.PeakConnections,
+			})
+		}()
+		opts.Events.Emit(readyEvent(cluster, bindAddress, tun.GetActualLocalPort()))
+		if hookEnv.LocalSocket == "" {
+			hookEnv.LocalPort = tun.GetActualLocalPort()
+		}
+		runHook(cluster, hooks.Ready, hookEnv)
 		if opts.OnReady != nil {
 			opts.OnReady(tun.GetActualLocalPort())
 		}
 	case err := <-errCh:
+		tracing.End(dialSpan, err)
 		return err
 	case <-ctx.Done():
+		tracing.End(dialSpan, ctx.Err())
 		tun.Close()
 		return ctx.Err()
 	}
 
+	// Publish traffic and pool statistics while the tunnel runs
+	statsCtx, statsCancel := context.WithCancel(ctx)
+	defer statsCancel()
+	go reportTunnelStats(statsCtx, tun, healthRegistry, auditSessionID)
+
 	// Wait for tunnel to complete or context cancellation
 	select {
 	case err := <-errCh:
+		if errors.Is(err, tunnel.ErrIdleTimeout) {
+			log.Info().Msg("Tunnel closed after idle timeout")
+			return nil
+		}
 		return err
 	case <-ctx.Done():
 		tun.Close()
@@ -303,6 +700,191 @@ func handleStandardBastionWithOptions(ctx context.Context, ociClient *client.OCI
 	}
 }
 
+// sessionDrainCheckInterval is how often a session hand-off checks whether
+// the old session's connections have finished.
+const sessionDrainCheckInterval = 10 * time.Second
+
+// handOffSession moves tun onto SSH connections for the current bastion
+// session, then deletes the old session once the forwarded connections still
+// using it have ended. Anything still open when the old session expires is
+// cut off by the bastion.
+func handOffSession(ctx context.Context, ociClient *client.OCIClient, bastionID, oldSessionID string, tun *tunnel.SSHTunnel) {
+	draining, err := tun.RotateConnections()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to connect with the new bastion session; staying on the old one")
+		return
+	}
+	if draining > 0 {
+		log.Info().Msgf("Moved to new bastion session; waiting for %d SSH connections to finish on session %s", draining, oldSessionID)
+	}
+
+	ticker := time.NewTicker(sessionDrainCheckInterval)
+	defer ticker.Stop()
+	for tun.DrainingConnections() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	if err := ociClient.DeleteSession(ctx, bastionID, oldSessionID); err != nil {
+		// The session may already have expired
+		log.Debug().Err(err).Msgf("Failed to delete old bastion session %s", oldSessionID)
+		return
+	}
+	log.Info().Msgf("Deleted old bastion session %s", oldSessionID)
+}
+
+// newHookEnv describes a tunnel to endpoint over the bastion session
+// sessionID to hooks. The session is empty for internal bastions.
+func newHookEnv(cluster *config.Cluster, endpoint *config.ClusterEndpoint, bindAddress, sessionID string) hooks.Env {
+	env := hooks.Env{
+		Cluster:    cluster.ClusterName,
+		Region:     cluster.Region,
+		SessionID:  sessionID,
+		RemoteHost: endpoint.Host(),
+		RemotePort: endpoint.Port,
+	}
+	if cluster.BastionId != nil {
+		env.BastionID = *cluster.BastionId
+	}
+	if cluster.LocalSocket != nil && *cluster.LocalSocket != "" {
+		env.LocalSocket = *cluster.LocalSocket
+	} else {
+		env.LocalAddress = bindAddress
+		env.LocalPort = *cluster.LocalPort
+	}
+	return env
+}
+
+// runHook runs the cluster's hook for event, if it has one.
+func runHook(cluster *config.Cluster, event hooks.Event, env hooks.Env) {
+	if cluster.Hooks == nil {
+		return
+	}
+
+	var command string
+	switch event {
+	case hooks.Connect:
+		command = cluster.Hooks.OnConnect
+	case hooks.Ready:
+		command = cluster.Hooks.OnReady
+	case hooks.Refresh:
+		command = cluster.Hooks.OnRefresh
+	case hooks.Disconnect:
+		command = cluster.Hooks.OnDisconnect
+	}
+	hooks.Run(command, event, env)
+}
+
+// readyEvent describes where a ready tunnel listens.
+func readyEvent(cluster *config.Cluster, bindAddress string, port int) events.Event {
+	e := events.Event{Type: events.TunnelReady, Cluster: cluster.ClusterName}
+	if cluster.LocalSocket != nil && *cluster.LocalSocket != "" {
+		e.Socket = *cluster.LocalSocket
+		return e
+	}
+	e.Address = bindAddress
+	e.Port = port
+	return e
+}
+
+// clusterBindAddress returns the address the cluster's local listeners bind
+// to. Only IPv4 addresses and "localhost" are accepted.
+func clusterBindAddress(cluster *config.Cluster) (string, error) {
+	if cluster.BindAddress == nil || *cluster.BindAddress == "" {
+		return "localhost", nil
+	}
+
+	addr := *cluster.BindAddress
+	if addr == "localhost" {
+		return addr, nil
+	}
+	if ip := net.ParseIP(addr); ip == nil || ip.To4() == nil {
+		return "", fmt.Errorf("invalid bind_address %q for cluster '%s': must be an IPv4 address or localhost", addr, cluster.ClusterName)
+	}
+	return addr, nil
+}
+
+// isLoopbackAddress reports whether a bind address only accepts local connections.
+func isLoopbackAddress(addr string) bool {
+	if addr == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateHops checks the cluster's jump host chain. Hops are only supported
+// with standard bastions; internal bastions use jumpbox_ip instead.
+func validateHops(cluster *config.Cluster, bastionType string) error {
+	if len(cluster.Hops) == 0 {
+		return nil
+	}
+	if bastionType == "INTERNAL" {
+		return fmt.Errorf("hops are not supported with internal bastions for cluster '%s'", cluster.ClusterName)
+	}
+	for i, hop := range cluster.Hops {
+		if hop.Host == "" {
+			return fmt.Errorf("hop %d of cluster '%s' has no host configured", i+1, cluster.ClusterName)
+		}
+	}
+	return nil
+}
+
+// bandwidthLimits returns the tunnel-wide and per-connection bandwidth caps in
+// bytes per second from the cluster config, with opts.MaxBandwidth taking
+// precedence over the cluster's max_bandwidth.
+func bandwidthLimits(cluster *config.Cluster, opts *TunnelOptions) (total, perConnection int64, err error) {
+	if cluster.MaxBandwidth != nil {
+		if total, err = utils.ParseBandwidth(*cluster.MaxBandwidth); err != nil {
+			return 0, 0, fmt.Errorf("invalid max_bandwidth for cluster '%s': %w", cluster.ClusterName, err)
+		}
+	}
+	if opts.MaxBandwidth > 0 {
+		total = opts.MaxBandwidth
+	}
+
+	if cluster.MaxConnectionBandwidth != nil {
+		if perConnection, err = utils.ParseBandwidth(*cluster.MaxConnectionBandwidth); err != nil {
+			return 0, 0, fmt.Errorf("invalid max_connection_bandwidth for cluster '%s': %w", cluster.ClusterName, err)
+		}
+	}
+
+	return total, perConnection, nil
+}
+
+// reportTunnelStats periodically publishes the tunnel's pool and traffic
+// statistics to the health registry until ctx is cancelled.
+func reportTunnelStats(ctx context.Context, tun *tunnel.SSHTunnel, healthRegistry *health.Registry, id string) {
+	ticker := time.NewTicker(statsReportInterval)
+	defer ticker.Stop()
+
+	for {
+		stats := tun.Stats()
+		healthRegistry.UpdatePoolStatus(id, &health.PoolStatus{
+			Size:       stats.PoolSize,
+			ActiveUses: stats.PoolActiveUses,
+			Available:  stats.PoolCapacity - stats.PoolActiveUses,
+		})
+		healthRegistry.UpdateTraffic(id, &health.TrafficStatus{
+			BytesIn:               stats.BytesIn,
+			BytesOut:              stats.BytesOut,
+			ActiveConnections:     stats.ActiveConnections,
+			TotalConnections:      stats.TotalConnections,
+			AvgConnectionDuration: stats.AvgConnectionDuration,
+			MaxConnectionDuration: stats.MaxConnectionDuration,
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // StartTunnel is a convenience function to start a tunnel to a cluster.
 func StartTunnel(ctx context.Context, configPath, clusterName string, localPort int) error {
 	cfg, err := config.ReadConfig(configPath)
@@ -326,7 +908,7 @@ func StartTunnel(ctx context.Context, configPath, clusterName string, localPort
 	}
 
 	// Create OCI client
-	ociClient, err := createOCIClient(cfg, cluster.Region)
+	ociClient, err := createOCIClient(cfg, cluster)
 	if err != nil {
 		return fmt.Errorf("failed to create OCI client: %w", err)
 	}
@@ -339,12 +921,13 @@ func StartTunnel(ctx context.Context, configPath, clusterName string, localPort
 	return TunnelThroughBastion(ctx, ociClient, cfg, cluster, endpoint)
 }
 
-// createOCIClient creates an OCI client for the given region.
-func createOCIClient(cfg *config.Config, region string) (*client.OCIClient, error) {
+// createOCIClient creates an OCI client for the region of cluster, with its
+// credentials.
+func createOCIClient(cfg *config.Config, cluster *config.Cluster) (*client.OCIClient, error) {
 	// Determine auth type
 	authType := client.AuthTypeAuto
-	if cfg.OCIAuthType != "" {
-		authType = client.AuthType(cfg.OCIAuthType)
+	if t := cfg.GetOCIAuthType(cluster); t != "" {
+		authType = client.AuthType(t)
 	}
 
 	// Determine config path and profile
@@ -353,7 +936,7 @@ func createOCIClient(cfg *config.Config, region string) (*client.OCIClient, erro
 		configPath = utils.DefaultOCIConfigPath()
 	}
 
-	profile := cfg.OCIProfile
+	profile := cfg.GetOCIProfile(cluster)
 	if profile == "" {
 		profile = "DEFAULT"
 	}
@@ -364,6 +947,6 @@ func createOCIClient(cfg *config.Config, region string) (*client.OCIClient, erro
 		return nil, err
 	}
 
-	ociClient.SetRegion(region)
-	return ociClient, nil
+	ociClient.SetRetryPolicy(client.RetryPolicyFromConfig(cfg.OCIRetry))
+	return ociClient.WithRegion(cluster.Region), nil
 }
@@ -0,0 +1,185 @@
+package bastion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/tunnel"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestBandwidthLimits(t *testing.T) {
+	cluster := &config.Cluster{
+		ClusterName:            "prod",
+		MaxBandwidth:           utils.StringPtr("10M"),
+		MaxConnectionBandwidth: utils.StringPtr("512K"),
+	}
+
+	total, perConnection, err := bandwidthLimits(cluster, &TunnelOptions{})
+	if err != nil {
+		t.Fatalf("bandwidthLimits() error = %v", err)
+	}
+	if total != 10<<20 || perConnection != 512<<10 {
+		t.Errorf("bandwidthLimits() = %d, %d, want %d, %d", total, perConnection, 10<<20, 512<<10)
+	}
+
+	// The --max-bandwidth flag overrides the cluster's tunnel-wide cap
+	total, _, _ = bandwidthLimits(cluster, &TunnelOptions{MaxBandwidth: 1 << 20})
+	if total != 1<<20 {
+		t.Errorf("bandwidthLimits() total = %d, want %d", total, 1<<20)
+	}
+
+	cluster.MaxConnectionBandwidth = utils.StringPtr("lots")
+	if _, _, err := bandwidthLimits(cluster, &TunnelOptions{}); err == nil {
+		t.Error("bandwidthLimits() should reject an invalid max_connection_bandwidth")
+	}
+}
+
+func TestValidateTunnel(t *testing.T) {
+	cfg := &config.Config{IdleAction: "shrink", EndpointChangeAction: "reconnect"}
+	cluster := &config.Cluster{
+		ClusterName:  "prod",
+		BindAddress:  utils.StringPtr("0.0.0.0"),
+		MaxBandwidth: utils.StringPtr("1M"),
+	}
+	endpoint := &config.ClusterEndpoint{Name: "private", Ip: "10.0.0.1", Port: 6443}
+
+	settings, err := validateTunnel(cfg, cluster, endpoint, &TunnelOptions{})
+	if err != nil {
+		t.Fatalf("validateTunnel() error = %v", err)
+	}
+	if settings.bindAddress != "0.0.0.0" || settings.idleAction != tunnel.IdleActionShrink ||
+		settings.endpointChangeAction != EndpointChangeReconnect || settings.maxBandwidth != 1<<20 || settings.backoff == nil {
+		t.Errorf("validateTunnel() = %+v, want the parsed settings", settings)
+	}
+
+	cfg.IdleAction = "hibernate"
+	if _, err := validateTunnel(cfg, cluster, endpoint, &TunnelOptions{}); err == nil {
+		t.Error("validateTunnel() should reject an unknown idle_action")
+	}
+}
+
+func TestValidateHops(t *testing.T) {
+	cluster := &config.Cluster{
+		ClusterName: "restricted",
+		Hops:        []*config.Hop{{Host: "10.0.1.5"}},
+	}
+
+	if err := validateHops(cluster, "STANDARD"); err != nil {
+		t.Errorf("validateHops() error = %v", err)
+	}
+	if err := validateHops(cluster, "INTERNAL"); err == nil {
+		t.Error("validateHops() should reject hops with an internal bastion")
+	}
+
+	cluster.Hops = append(cluster.Hops, &config.Hop{User: "ops"})
+	if err := validateHops(cluster, "STANDARD"); err == nil {
+		t.Error("validateHops() should reject a hop without a host")
+	}
+}
+
+func TestClusterBindAddress(t *testing.T) {
+	tests := []struct {
+		bind    *string
+		want    string
+		wantErr bool
+	}{
+		{nil, "localhost", false},
+		{utils.StringPtr(""), "localhost", false},
+		{utils.StringPtr("localhost"), "localhost", false},
+		{utils.StringPtr("0.0.0.0"), "0.0.0.0", false},
+		{utils.StringPtr("10.1.2.3"), "10.1.2.3", false},
+		{utils.StringPtr("::"), "", true},
+		{utils.StringPtr("eth0"), "", true},
+	}
+
+	for _, tt := range tests {
+		cluster := &config.Cluster{ClusterName: "ci", BindAddress: tt.bind}
+		got, err := clusterBindAddress(cluster)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("clusterBindAddress(%v) error = %v, wantErr %v", tt.bind, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("clusterBindAddress(%v) = %q, want %q", tt.bind, got, tt.want)
+		}
+	}
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"localhost": true,
+		"127.0.0.1": true,
+		"0.0.0.0":   false,
+		"10.1.2.3":  false,
+	} {
+		if got := isLoopbackAddress(addr); got != want {
+			t.Errorf("isLoopbackAddress(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestBastionBackoffConfig(t *testing.T) {
+	got, err := bastionBackoffConfig(&config.RetryPolicy{})
+	if err != nil {
+		t.Fatalf("bastionBackoffConfig() error = %v", err)
+	}
+	if got.InitialInterval != 5*time.Second || got.MaxInterval != 2*time.Minute || got.Multiplier != 1.5 || got.MaxAttempts != 15 {
+		t.Errorf("bastionBackoffConfig() = %+v, want the defaults", got)
+	}
+
+	initial, attempts, multiplier := 10, 0, 2.0
+	got, err = bastionBackoffConfig(&config.RetryPolicy{InitialIntervalSeconds: &initial, MaxAttempts: &attempts, Multiplier: &multiplier})
+	if err != nil {
+		t.Fatalf("bastionBackoffConfig() error = %v", err)
+	}
+	if got.InitialInterval != 10*time.Second || got.MaxAttempts != 0 || got.Multiplier != 2 {
+		t.Errorf("bastionBackoffConfig() = %+v, want the configured settings", got)
+	}
+
+	zero, negative, shrinking, longInitial := 0, -1, 0.5, 300
+	invalid := []*config.RetryPolicy{
+		{InitialIntervalSeconds: &zero},
+		{MaxAttempts: &negative},
+		{Multiplier: &shrinking},
+		{InitialIntervalSeconds: &longInitial},
+	}
+	for _, policy := range invalid {
+		if _, err := bastionBackoffConfig(policy); err == nil {
+			t.Errorf("bastionBackoffConfig(%+v) error = nil, want an error", policy)
+		}
+	}
+}
+
+// statusError is an OCI service error with an HTTP status.
+type statusError int
+
+func (e statusError) GetHTTPStatusCode() int  { return int(e) }
+func (e statusError) GetMessage() string      { return http.StatusText(int(e)) }
+func (e statusError) GetCode() string         { return "" }
+func (e statusError) GetOpcRequestID() string { return "" }
+func (e statusError) Error() string           { return http.StatusText(int(e)) }
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection reset"), false},
+		{fmt.Errorf("failed to create session: %w", statusError(http.StatusForbidden)), true},
+		{fmt.Errorf("failed to create session: %w", statusError(http.StatusNotFound)), true},
+		{fmt.Errorf("failed to create session: %w", statusError(http.StatusTooManyRequests)), false},
+		{fmt.Errorf("failed to create session: %w", statusError(http.StatusServiceUnavailable)), false},
+		{fmt.Errorf("failed to get session: %w", permanent(errors.New("bad config"))), true},
+	}
+
+	for _, tt := range tests {
+		if got := isPermanent(tt.err); got != tt.want {
+			t.Errorf("isPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
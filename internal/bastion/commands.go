@@ -2,6 +2,7 @@ package bastion
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -28,13 +29,9 @@ func getDomainFromRealm(realm string) string {
 	return "oraclegovcloud"
 }
 
-// GetTunnelCommand generates the SSH command for connecting through a bastion.
-func GetTunnelCommand(privateKeyFile string, localPort, remotePort int, remoteIP, sessionID, region, socksProxy string) string {
-	realm := extractRealmFromOCID(sessionID)
-	domain := getDomainFromRealm(realm)
-
-	bastionHost := fmt.Sprintf("host.bastion.%s.oci.%s.com", region, domain)
-
+// GetTunnelCommand generates the SSH command for connecting through a bastion
+// at bastionHost.
+func GetTunnelCommand(privateKeyFile string, localPort, remotePort int, remoteIP, sessionID, bastionHost, socksProxy string) string {
 	cmd := fmt.Sprintf("ssh -i %s -o StrictHostKeyChecking=accept-new -o ProxyUseFdpass=no",
 		privateKeyFile)
 
@@ -48,6 +45,24 @@ func GetTunnelCommand(privateKeyFile string, localPort, remotePort int, remoteIP
 	return cmd
 }
 
+// GetHopTunnelCommand generates the SSH command for connecting through a
+// bastion and then a chain of jump hosts, each given as user@host:port. The
+// last hop is the SSH destination, so the forward is made from there.
+func GetHopTunnelCommand(privateKeyFile string, localPort, remotePort int, remoteIP, sessionID, bastionHost string, hops []string) string {
+	jumps := []string{fmt.Sprintf("%s@%s:22", sessionID, bastionHost)}
+	jumps = append(jumps, hops[:len(hops)-1]...)
+
+	return fmt.Sprintf("ssh -i %s -o StrictHostKeyChecking=accept-new -J %s -N -L %d:%s:%d ssh://%s",
+		privateKeyFile, strings.Join(jumps, ","), localPort, remoteIP, remotePort, hops[len(hops)-1])
+}
+
+// GetManagedSSHCommand generates the SSH command for logging in to an
+// instance through a managed SSH session. target is user@host:port.
+func GetManagedSSHCommand(privateKeyFile, sessionID, bastionHost, target string) string {
+	return fmt.Sprintf("ssh -i %s -o StrictHostKeyChecking=accept-new -J %s@%s:22 ssh://%s",
+		privateKeyFile, sessionID, bastionHost, target)
+}
+
 // GetInternalTunnelCommand generates the SSH command for internal bastion type.
 func GetInternalTunnelCommand(localPort, remotePort int, remoteIP, bastionID, jumpBoxIP, region, compartmentID, bastionLB string) string {
 	cmd := fmt.Sprintf("ssh -o StrictHostKeyChecking=accept-new -o ProxyUseFdpass=no "+
@@ -61,11 +76,55 @@ func GetInternalTunnelCommand(localPort, remotePort int, remoteIP, bastionID, ju
 	return cmd
 }
 
+// AddDynamicForward adds a local SOCKS5 listener (-D) to an SSH command.
+func AddDynamicForward(sshCmd string, socksPort int) string {
+	if socksPort <= 0 || !strings.HasPrefix(sshCmd, "ssh ") {
+		return sshCmd
+	}
+	return fmt.Sprintf("ssh -D localhost:%d %s", socksPort, strings.TrimPrefix(sshCmd, "ssh "))
+}
+
+// AddLocalForward adds an extra local port forward (-L) to an SSH command.
+func AddLocalForward(sshCmd string, localPort, remotePort int, remoteIP string) string {
+	if !strings.HasPrefix(sshCmd, "ssh ") {
+		return sshCmd
+	}
+	return fmt.Sprintf("ssh -L %d:%s:%d %s", localPort, remoteIP, remotePort, strings.TrimPrefix(sshCmd, "ssh "))
+}
+
+// UseLocalSocket rewrites the primary local port forward of an SSH command to
+// listen on a Unix domain socket at socketPath instead of localPort.
+func UseLocalSocket(sshCmd string, localPort int, socketPath string) string {
+	if socketPath == "" || !strings.HasPrefix(sshCmd, "ssh ") {
+		return sshCmd
+	}
+	sshCmd = strings.Replace(sshCmd, fmt.Sprintf("-L %d:", localPort), fmt.Sprintf("-L %s:", socketPath), 1)
+	return fmt.Sprintf("ssh -o StreamLocalBindUnlink=yes %s", strings.TrimPrefix(sshCmd, "ssh "))
+}
+
 // FormatLocalAddress formats a local address for tunnel binding.
 func FormatLocalAddress(port int) string {
 	return fmt.Sprintf("localhost:%d", port)
 }
 
+// FormatBindAddress formats a local address on bindAddress for tunnel binding.
+func FormatBindAddress(bindAddress string, port int) string {
+	return fmt.Sprintf("%s:%d", bindAddress, port)
+}
+
+// localPortForward matches the start of a port-based local forward (-L).
+var localPortForward = regexp.MustCompile(` -L (\d)`)
+
+// UseBindAddress rewrites every port-based local forward (-L) of an SSH
+// command to listen on bindAddress instead of localhost. Socket forwards are
+// left alone.
+func UseBindAddress(sshCmd, bindAddress string) string {
+	if bindAddress == "" || bindAddress == "localhost" || !strings.HasPrefix(sshCmd, "ssh ") {
+		return sshCmd
+	}
+	return localPortForward.ReplaceAllString(sshCmd, fmt.Sprintf(" -L %s:$1", bindAddress))
+}
+
 // FormatRemoteAddress formats a remote address for tunnel destination.
 func FormatRemoteAddress(ip string, port int) string {
 	return fmt.Sprintf("%s:%d", ip, port)
@@ -87,10 +146,15 @@ func GetBastionDomain(bastionID string) string {
 	return getDomainFromRealm(realm)
 }
 
+// GetBastionHost returns the bastion service host name for a bastion in
+// region, with the domain derived from the bastion's realm.
+func GetBastionHost(bastionID, region string) string {
+	return fmt.Sprintf("host.bastion.%s.oci.%s.com", region, GetBastionDomain(bastionID))
+}
+
 // GetBastionHostAddress returns the full bastion host address.
 func GetBastionHostAddress(bastionID, region string) string {
-	domain := GetBastionDomain(bastionID)
-	return fmt.Sprintf("host.bastion.%s.oci.%s.com:22", region, domain)
+	return GetBastionHost(bastionID, region) + ":22"
 }
 
 // ParsePort safely parses a port string to int.
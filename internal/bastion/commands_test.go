@@ -3,6 +3,9 @@ package bastion
 import (
 	"strings"
 	"testing"
+
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
 )
 
 func TestGetTunnelCommand(t *testing.T) {
@@ -12,7 +15,7 @@ func TestGetTunnelCommand(t *testing.T) {
 		6443,
 		"10.0.0.1",
 		"ocid1.bastionsession.oc1.iad.test",
-		"us-ashburn-1",
+		"host.bastion.us-ashburn-1.oci.oraclecloud.com",
 		"",
 	)
 
@@ -42,6 +45,43 @@ func TestGetTunnelCommand(t *testing.T) {
 	}
 }
 
+func TestGetHopTunnelCommand(t *testing.T) {
+	cmd := GetHopTunnelCommand(
+		"~/.ssh/id_rsa",
+		6443,
+		6443,
+		"10.0.0.1",
+		"ocid1.bastionsession.oc1.iad.test",
+		"host.bastion.us-ashburn-1.oci.oraclecloud.com",
+		[]string{"opc@10.0.1.5:22", "ops@10.0.2.5:2222"},
+	)
+
+	wantJump := "-J ocid1.bastionsession.oc1.iad.test@host.bastion.us-ashburn-1.oci.oraclecloud.com:22,opc@10.0.1.5:22 "
+	if !strings.Contains(cmd, wantJump) {
+		t.Errorf("Command = %q, should contain %q", cmd, wantJump)
+	}
+	if !strings.Contains(cmd, "-L 6443:10.0.0.1:6443") {
+		t.Errorf("Command = %q, should forward to the endpoint from the last hop", cmd)
+	}
+	if !strings.HasSuffix(cmd, "ssh://ops@10.0.2.5:2222") {
+		t.Errorf("Command = %q, should end with the last hop as destination", cmd)
+	}
+}
+
+func TestGetManagedSSHCommand(t *testing.T) {
+	cmd := GetManagedSSHCommand(
+		"~/.ssh/id_rsa",
+		"ocid1.bastionsession.oc1.iad.test",
+		"host.bastion.us-ashburn-1.oci.oraclecloud.com",
+		"opc@10.0.1.5:22",
+	)
+
+	want := "ssh -i ~/.ssh/id_rsa -o StrictHostKeyChecking=accept-new -J ocid1.bastionsession.oc1.iad.test@host.bastion.us-ashburn-1.oci.oraclecloud.com:22 ssh://opc@10.0.1.5:22"
+	if cmd != want {
+		t.Errorf("GetManagedSSHCommand() = %q, want %q", cmd, want)
+	}
+}
+
 func TestGetTunnelCommandWithSocksProxy(t *testing.T) {
 	cmd := GetTunnelCommand(
 		"~/.ssh/id_rsa",
@@ -49,7 +89,7 @@ func TestGetTunnelCommandWithSocksProxy(t *testing.T) {
 		6443,
 		"10.0.0.1",
 		"ocid1.bastionsession.oc1.iad.test",
-		"us-ashburn-1",
+		"host.bastion.us-ashburn-1.oci.oraclecloud.com",
 		"localhost:1080",
 	)
 
@@ -62,20 +102,43 @@ func TestGetTunnelCommandWithSocksProxy(t *testing.T) {
 	}
 }
 
-func TestGetTunnelCommandGovCloud(t *testing.T) {
-	// Use a session ID that indicates gov cloud (contains "2" in the realm)
-	cmd := GetTunnelCommand(
-		"~/.ssh/id_rsa",
-		6443,
-		6443,
-		"10.0.0.1",
-		"ocid1.bastionsession.oc2.iad.test", // oc2 = gov cloud
-		"us-gov-ashburn-1",
-		"",
-	)
+func TestClusterBastionHost(t *testing.T) {
+	bastionID := "ocid1.bastion.oc8.nrt.test"
+	tenant := "dedicated"
+	cfg := &config.Config{
+		TenancyList: []*config.TenantInfo{
+			{Name: tenant, BastionHost: "bastion.{region}.oci.example-dedicated.com"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		cluster *config.Cluster
+		want    string
+	}{
+		{
+			"derived from realm",
+			&config.Cluster{Region: "ap-tokyo-1", BastionId: &bastionID},
+			"host.bastion.ap-tokyo-1.oci.oraclegovcloud.com",
+		},
+		{
+			"tenancy override",
+			&config.Cluster{Region: "ap-tokyo-1", BastionId: &bastionID, Tenant: &tenant},
+			"bastion.ap-tokyo-1.oci.example-dedicated.com",
+		},
+		{
+			"cluster override",
+			&config.Cluster{Region: "ap-tokyo-1", BastionId: &bastionID, Tenant: &tenant, BastionHost: utils.StringPtr("bastion.internal.example")},
+			"bastion.internal.example",
+		},
+	}
 
-	if !strings.Contains(cmd, "oraclegovcloud.com") {
-		t.Error("Gov cloud session should use oraclegovcloud.com domain")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClusterBastionHost(cfg, tt.cluster); got != tt.want {
+				t.Errorf("ClusterBastionHost() = %q, want %q", got, tt.want)
+			}
+		})
 	}
 }
 
@@ -108,6 +171,48 @@ func TestGetInternalTunnelCommand(t *testing.T) {
 	}
 }
 
+func TestAddDynamicForward(t *testing.T) {
+	base := "ssh -i key -N -L 6443:10.0.0.1:6443 user@host"
+
+	cmd := AddDynamicForward(base, 1080)
+	if !strings.HasPrefix(cmd, "ssh -D localhost:1080 ") {
+		t.Errorf("AddDynamicForward() = %q, want -D option after ssh", cmd)
+	}
+	if !strings.HasSuffix(cmd, "-N -L 6443:10.0.0.1:6443 user@host") {
+		t.Errorf("AddDynamicForward() = %q, should keep original options", cmd)
+	}
+
+	if got := AddDynamicForward(base, 0); got != base {
+		t.Errorf("AddDynamicForward() with port 0 = %q, want unchanged", got)
+	}
+}
+
+func TestAddLocalForward(t *testing.T) {
+	base := "ssh -i key -N -L 6443:10.0.0.1:6443 user@host"
+
+	cmd := AddLocalForward(base, 15432, 5432, "10.0.0.3")
+	if !strings.HasPrefix(cmd, "ssh -L 15432:10.0.0.3:5432 ") {
+		t.Errorf("AddLocalForward() = %q, want -L option after ssh", cmd)
+	}
+	if !strings.HasSuffix(cmd, "-N -L 6443:10.0.0.1:6443 user@host") {
+		t.Errorf("AddLocalForward() = %q, should keep original options", cmd)
+	}
+}
+
+func TestUseLocalSocket(t *testing.T) {
+	base := "ssh -i key -N -L 6443:10.0.0.1:6443 user@host"
+
+	cmd := UseLocalSocket(base, 6443, "/tmp/prod.sock")
+	want := "ssh -o StreamLocalBindUnlink=yes -i key -N -L /tmp/prod.sock:10.0.0.1:6443 user@host"
+	if cmd != want {
+		t.Errorf("UseLocalSocket() = %q, want %q", cmd, want)
+	}
+
+	if got := UseLocalSocket(base, 6443, ""); got != base {
+		t.Errorf("UseLocalSocket() with empty path = %q, want unchanged", got)
+	}
+}
+
 func TestFormatLocalAddress(t *testing.T) {
 	addr := FormatLocalAddress(6443)
 
@@ -116,6 +221,31 @@ func TestFormatLocalAddress(t *testing.T) {
 	}
 }
 
+func TestUseBindAddress(t *testing.T) {
+	base := "ssh -L 15432:10.0.0.3:5432 -i key -N -L 6443:10.0.0.1:6443 user@host"
+
+	cmd := UseBindAddress(base, "0.0.0.0")
+	want := "ssh -L 0.0.0.0:15432:10.0.0.3:5432 -i key -N -L 0.0.0.0:6443:10.0.0.1:6443 user@host"
+	if cmd != want {
+		t.Errorf("UseBindAddress() = %q, want %q", cmd, want)
+	}
+
+	if got := UseBindAddress(base, "localhost"); got != base {
+		t.Errorf("UseBindAddress() with localhost = %q, want unchanged", got)
+	}
+
+	socket := "ssh -N -L /tmp/prod.sock:10.0.0.1:6443 user@host"
+	if got := UseBindAddress(socket, "0.0.0.0"); got != socket {
+		t.Errorf("UseBindAddress() with socket forward = %q, want unchanged", got)
+	}
+}
+
+func TestFormatBindAddress(t *testing.T) {
+	if addr := FormatBindAddress("0.0.0.0", 6443); addr != "0.0.0.0:6443" {
+		t.Errorf("FormatBindAddress() = %q, want %q", addr, "0.0.0.0:6443")
+	}
+}
+
 func TestFormatRemoteAddress(t *testing.T) {
 	addr := FormatRemoteAddress("10.0.0.1", 6443)
 
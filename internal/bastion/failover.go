@@ -0,0 +1,161 @@
+package bastion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"golang.org/x/crypto/ssh"
+)
+
+// bastionFailoverDelay is how long a bastion gets to produce a session before
+// the next candidate is tried alongside it. Session creation normally takes
+// well under this, so a healthy primary rarely costs a second session.
+const bastionFailoverDelay = 20 * time.Second
+
+// bastionAttempt is the outcome of getting a session from one bastion.
+type bastionAttempt struct {
+	bastionID string
+	sessionID string
+	sshConfig *ssh.ClientConfig
+	err       error
+}
+
+// bastionCandidates returns the cluster's bastion followed by its fallbacks,
+// with the bastion that last won a failover first.
+func bastionCandidates(cluster *config.Cluster, preferred string) []string {
+	var candidates []string
+	if cluster.BastionId != nil {
+		candidates = append(candidates, *cluster.BastionId)
+	}
+	for _, id := range cluster.FallbackBastionIds {
+		if !slices.Contains(candidates, id) {
+			candidates = append(candidates, id)
+		}
+	}
+
+	if i := slices.Index(candidates, preferred); i > 0 {
+		candidates = append([]string{preferred}, slices.Delete(candidates, i, i+1)...)
+	}
+	return candidates
+}
+
+// raceBastions gets a session from the first candidate that can produce one.
+// Candidates are started in order: each as soon as the previous one fails,
+// or after delay if it is still working. Sessions from attempts that lose the
+// race are left to expire, since they may be shared with other tunnels.
+func raceBastions(ctx context.Context, candidates []string, delay time.Duration, try func(ctx context.Context, bastionID string) bastionAttempt) (bastionAttempt, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so that attempts still running after we return never block
+	results := make(chan bastionAttempt, len(candidates))
+	next, running := 0, 0
+	start := func() {
+		id := candidates[next]
+		next++
+		running++
+		go func() { results <- try(ctx, id) }()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var errs []error
+	start()
+	for {
+		select {
+		case result := <-results:
+			running--
+			if result.err == nil {
+				return result, nil
+			}
+
+			log.Warn().Err(result.err).Msgf("Bastion %s could not create a session", result.bastionID)
+			errs = append(errs, fmt.Errorf("bastion %s: %w", result.bastionID, result.err))
+			if next < len(candidates) {
+				log.Info().Msgf("Failing over to bastion %s", candidates[next])
+				start()
+				timer.Reset(delay)
+			} else if running == 0 {
+				return bastionAttempt{}, errors.Join(errs...)
+			}
+
+		case <-timer.C:
+			if next < len(candidates) {
+				log.Info().Msgf("No session after %s, also trying bastion %s", delay, candidates[next])
+				start()
+				timer.Reset(delay)
+			}
+
+		case <-ctx.Done():
+			return bastionAttempt{}, ctx.Err()
+		}
+	}
+}
+
+// getFailoverSession gets a session for endpoint from the cluster's bastion
+// or, failing that, one of its fallbacks. The winning bastion becomes the
+// cluster's bastion and is recorded in the discovery cache.
+func getFailoverSession(ctx context.Context, sessionID *string, sshConfig *ssh.ClientConfig, ociClient *client.OCIClient, cfg *config.Config, cluster *config.Cluster, endpoint *config.ClusterEndpoint) error {
+	if len(cluster.FallbackBastionIds) == 0 {
+		return UpdateBastionConnection(ctx, sessionID, sshConfig, ociClient, cfg, cluster, endpoint)
+	}
+
+	cache := bastionCache(cfg)
+	preferred := ""
+	if cache != nil {
+		if entry := cache.GetBastion(cluster.ClusterName); entry != nil {
+			preferred = entry.OCID
+		}
+	}
+	candidates := bastionCandidates(cluster, preferred)
+
+	winner, err := raceBastions(ctx, candidates, bastionFailoverDelay, func(ctx context.Context, bastionID string) bastionAttempt {
+		attempt := bastionAttempt{bastionID: bastionID, sshConfig: &ssh.ClientConfig{}}
+		candidate := *cluster
+		candidate.BastionId = &bastionID
+		attempt.err = UpdateBastionConnection(ctx, &attempt.sessionID, attempt.sshConfig, ociClient, cfg, &candidate, endpoint)
+		return attempt
+	})
+	if err != nil {
+		return err
+	}
+
+	if cluster.BastionId == nil || *cluster.BastionId != winner.bastionID {
+		log.Info().Msgf("Using bastion %s", winner.bastionID)
+	}
+	cluster.BastionId = &winner.bastionID
+	*sessionID = winner.sessionID
+	*sshConfig = *winner.sshConfig
+
+	if cache != nil && winner.bastionID != preferred {
+		entry := &discovery.CacheEntry{
+			OCID:      winner.bastionID,
+			Region:    cluster.Region,
+			Fallbacks: slices.DeleteFunc(slices.Clone(candidates), func(id string) bool { return id == winner.bastionID }),
+		}
+		if cluster.CompartmentOcid != nil {
+			entry.CompartmentOCID = *cluster.CompartmentOcid
+		}
+		if err := cache.SetBastion(cluster.ClusterName, entry); err != nil {
+			log.Warn().Err(err).Msg("Failed to cache winning bastion")
+		}
+	}
+	return nil
+}
+
+// bastionCache opens the discovery cache, or returns nil if it is unavailable.
+func bastionCache(cfg *config.Config) *discovery.Cache {
+	cache, err := discovery.OpenCache(cfg)
+	if err != nil {
+		return nil
+	}
+	return cache
+}
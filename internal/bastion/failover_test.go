@@ -0,0 +1,93 @@
+package bastion
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestBastionCandidates(t *testing.T) {
+	cluster := &config.Cluster{
+		BastionId:          utils.StringPtr("primary"),
+		FallbackBastionIds: []string{"dr", "primary", "spare"},
+	}
+
+	if got, want := bastionCandidates(cluster, ""), []string{"primary", "dr", "spare"}; !slices.Equal(got, want) {
+		t.Errorf("bastionCandidates() = %v, want %v", got, want)
+	}
+	if got, want := bastionCandidates(cluster, "spare"), []string{"spare", "primary", "dr"}; !slices.Equal(got, want) {
+		t.Errorf("bastionCandidates(spare) = %v, want %v", got, want)
+	}
+	if got, want := bastionCandidates(cluster, "unknown"), []string{"primary", "dr", "spare"}; !slices.Equal(got, want) {
+		t.Errorf("bastionCandidates(unknown) = %v, want %v", got, want)
+	}
+}
+
+func TestRaceBastionsFailsOverOnError(t *testing.T) {
+	var mu sync.Mutex
+	var tried []string
+
+	winner, err := raceBastions(context.Background(), []string{"dead", "dr"}, time.Hour, func(ctx context.Context, id string) bastionAttempt {
+		mu.Lock()
+		tried = append(tried, id)
+		mu.Unlock()
+		if id == "dead" {
+			return bastionAttempt{bastionID: id, err: errors.New("service unavailable")}
+		}
+		return bastionAttempt{bastionID: id, sessionID: "session-" + id}
+	})
+	if err != nil {
+		t.Fatalf("raceBastions() error = %v", err)
+	}
+	if winner.bastionID != "dr" || winner.sessionID != "session-dr" {
+		t.Errorf("raceBastions() = %+v, want the dr bastion", winner)
+	}
+	if !slices.Equal(tried, []string{"dead", "dr"}) {
+		t.Errorf("tried %v, want [dead dr]", tried)
+	}
+}
+
+func TestRaceBastionsStartsNextAfterDelay(t *testing.T) {
+	winner, err := raceBastions(context.Background(), []string{"slow", "fast"}, 10*time.Millisecond, func(ctx context.Context, id string) bastionAttempt {
+		if id == "slow" {
+			// Hangs like a bastion that never activates the session
+			<-ctx.Done()
+			return bastionAttempt{bastionID: id, err: ctx.Err()}
+		}
+		return bastionAttempt{bastionID: id}
+	})
+	if err != nil {
+		t.Fatalf("raceBastions() error = %v", err)
+	}
+	if winner.bastionID != "fast" {
+		t.Errorf("raceBastions() winner = %q, want %q", winner.bastionID, "fast")
+	}
+}
+
+func TestRaceBastionsAllFail(t *testing.T) {
+	_, err := raceBastions(context.Background(), []string{"a", "b"}, time.Hour, func(ctx context.Context, id string) bastionAttempt {
+		return bastionAttempt{bastionID: id, err: errors.New("down")}
+	})
+	if err == nil {
+		t.Fatal("raceBastions() should fail when every bastion fails")
+	}
+}
+
+func TestRaceBastionsContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := raceBastions(ctx, []string{"a"}, time.Hour, func(ctx context.Context, id string) bastionAttempt {
+		<-ctx.Done()
+		return bastionAttempt{bastionID: id, err: ctx.Err()}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("raceBastions() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,55 @@
+package bastion
+
+import (
+	"strings"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+)
+
+// SessionFilter selects bastion sessions by state, age and creator.
+type SessionFilter struct {
+	// All also selects failed, deleting and deleted sessions. Otherwise
+	// only active and creating sessions are selected.
+	All bool
+	// OlderThan, if non-zero, selects only sessions created at least this
+	// long ago.
+	OlderThan time.Duration
+	// Creator, if set, selects only sessions tunatap created for this user,
+	// given as "user" or "user@host".
+	Creator string
+}
+
+// IsZero reports whether the filter selects every active session.
+func (f *SessionFilter) IsZero() bool {
+	return f.OlderThan == 0 && f.Creator == ""
+}
+
+// Match reports whether the filter selects session at now.
+func (f *SessionFilter) Match(session bastion.SessionSummary, now time.Time) bool {
+	if !f.All && session.LifecycleState != bastion.SessionLifecycleStateActive && session.LifecycleState != bastion.SessionLifecycleStateCreating {
+		return false
+	}
+
+	if f.OlderThan > 0 {
+		if session.TimeCreated == nil || now.Sub(session.TimeCreated.Time) < f.OlderThan {
+			return false
+		}
+	}
+
+	if f.Creator != "" {
+		displayName := ""
+		if session.DisplayName != nil {
+			displayName = *session.DisplayName
+		}
+		_, owner := ParseSessionDisplayName(displayName)
+		user, host, hasHost := strings.Cut(f.Creator, "@")
+		if owner.User == "" || !strings.EqualFold(owner.User, user) {
+			return false
+		}
+		if hasHost && !strings.EqualFold(owner.Host, host) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package bastion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestSessionFilterMatch(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	session := func(name string, state bastion.SessionLifecycleStateEnum, age time.Duration) bastion.SessionSummary {
+		return bastion.SessionSummary{
+			DisplayName:    utils.StringPtr(name),
+			LifecycleState: state,
+			TimeCreated:    &common.SDKTime{Time: now.Add(-age)},
+		}
+	}
+	alice := session("tunatap-10.0.0.1-6443~user=alice~host=laptop~version=1.2.0", bastion.SessionLifecycleStateActive, 2*time.Hour)
+	manual := session("manual", bastion.SessionLifecycleStateActive, 2*time.Hour)
+	failed := session("tunatap-ssh-opc~user=alice~host=laptop", bastion.SessionLifecycleStateFailed, time.Hour)
+
+	tests := []struct {
+		name    string
+		filter  SessionFilter
+		session bastion.SessionSummary
+		want    bool
+	}{
+		{"active", SessionFilter{}, alice, true},
+		{"failed left out", SessionFilter{}, failed, false},
+		{"failed with all", SessionFilter{All: true}, failed, true},
+		{"old enough", SessionFilter{OlderThan: time.Hour}, alice, true},
+		{"too young", SessionFilter{OlderThan: 3 * time.Hour}, alice, false},
+		{"creator", SessionFilter{Creator: "Alice"}, alice, true},
+		{"creator and host", SessionFilter{Creator: "alice@laptop"}, alice, true},
+		{"other host", SessionFilter{Creator: "alice@desktop"}, alice, false},
+		{"other creator", SessionFilter{Creator: "bob"}, alice, false},
+		{"creator of a session created elsewhere", SessionFilter{Creator: "alice"}, manual, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.session, now); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
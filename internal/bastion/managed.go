@@ -0,0 +1,153 @@
+package bastion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/tunnel"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultManagedSSHPort is the SSH port managed SSH sessions connect to.
+const defaultManagedSSHPort = 22
+
+// ManagedSSHTarget is a compute instance reached through a MANAGED_SSH
+// session. The instance needs the Bastion plugin of the Oracle Cloud Agent.
+type ManagedSSHTarget struct {
+	// InstanceID is the instance OCID.
+	InstanceID string
+	// User is the OS user to log in as; the session installs its key for it.
+	User string
+	// Port is the instance's SSH port. Default: 22.
+	Port int
+}
+
+// GetPort returns the target's SSH port, defaulting to 22.
+func (t *ManagedSSHTarget) GetPort() int {
+	if t.Port == 0 {
+		return defaultManagedSSHPort
+	}
+	return t.Port
+}
+
+// DialManagedSSH opens an SSH connection to target through a managed SSH
+// session on the cluster's bastion. It returns the connected client and the
+// bastion session ID.
+func DialManagedSSH(ctx context.Context, ociClient *client.OCIClient, cfg *config.Config, cluster *config.Cluster, target *ManagedSSHTarget) (*ssh.Client, string, error) {
+	if cluster.BastionType != nil && *cluster.BastionType != "STANDARD" {
+		return nil, "", fmt.Errorf("managed SSH sessions require a standard bastion, but bastion type is %s", *cluster.BastionType)
+	}
+
+	manager := NewSessionManager(ociClient, cfg)
+	session, err := manager.GetOrCreateManagedSSHSession(ctx, cluster, target)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get or create session: %w", err)
+	}
+
+	targetAddr, err := managedSessionAddress(session, target)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The session authorizes the same key on the bastion and the instance
+	bastionConfig, err := managedSSHClientConfig(manager, cfg, *session.Id)
+	if err != nil {
+		return nil, "", err
+	}
+	targetConfig, err := managedSSHClientConfig(manager, cfg, target.User)
+	if err != nil {
+		return nil, "", err
+	}
+
+	log.Info().Msgf("Connecting to %s@%s. The equivalent ssh command is:\n%s",
+		target.User, targetAddr, GetManagedSSHCommand(cfg.SshPrivateKeyFile, *session.Id, ClusterBastionHost(cfg, cluster), target.User+"@"+targetAddr))
+	sshClient, err := tunnel.Dial(
+		ClusterBastionHost(cfg, cluster)+":22",
+		bastionConfig,
+		cfg.SshSocksProxy,
+		&tunnel.Hop{Server: tunnel.NewEndpoint(targetAddr), Config: targetConfig},
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to instance: %w", err)
+	}
+	return sshClient, *session.Id, nil
+}
+
+// DialForwardedSSH opens an SSH connection to user@host:port through a port
+// forwarding session on the cluster's bastion, for instances without the
+// Bastion plugin. Unlike DialManagedSSH, the instance must already accept
+// the configured SSH key or an agent key for user. It returns the connected
+// client and the bastion session ID.
+func DialForwardedSSH(ctx context.Context, ociClient *client.OCIClient, cfg *config.Config, cluster *config.Cluster, host string, port int, user string) (*ssh.Client, string, error) {
+	if cluster.BastionType != nil && *cluster.BastionType != "STANDARD" {
+		return nil, "", fmt.Errorf("port forwarding sessions require a standard bastion, but bastion type is %s", *cluster.BastionType)
+	}
+
+	manager := NewSessionManager(ociClient, cfg)
+	session, err := manager.GetOrCreateSession(ctx, cluster, &config.ClusterEndpoint{Ip: host, Port: port})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get or create session: %w", err)
+	}
+
+	bastionConfig, err := managedSSHClientConfig(manager, cfg, *session.Id)
+	if err != nil {
+		return nil, "", err
+	}
+	// The session's key only opens the bastion; the instance needs the user's
+	// own key
+	targetConfig, err := tunnel.CreateSSHClientConfigWithAgent(user, cfg.SshPrivateKeyFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create SSH config: %w", err)
+	}
+
+	targetAddr := FormatRemoteAddress(host, port)
+	log.Info().Msgf("Connecting to %s@%s. The equivalent ssh command is:\n%s",
+		user, targetAddr, GetManagedSSHCommand(cfg.SshPrivateKeyFile, *session.Id, ClusterBastionHost(cfg, cluster), user+"@"+targetAddr))
+	sshClient, err := tunnel.Dial(
+		ClusterBastionHost(cfg, cluster)+":22",
+		bastionConfig,
+		cfg.SshSocksProxy,
+		&tunnel.Hop{Server: tunnel.NewEndpoint(targetAddr), Config: targetConfig},
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to instance: %w", err)
+	}
+	return sshClient, *session.Id, nil
+}
+
+// managedSessionAddress returns the instance address a managed SSH session
+// connects to.
+func managedSessionAddress(session *bastion.Session, target *ManagedSSHTarget) (string, error) {
+	details, ok := session.TargetResourceDetails.(bastion.ManagedSshSessionTargetResourceDetails)
+	if !ok || details.TargetResourcePrivateIpAddress == nil || *details.TargetResourcePrivateIpAddress == "" {
+		return "", fmt.Errorf("session %s has no target instance address", *session.Id)
+	}
+
+	port := target.GetPort()
+	if details.TargetResourcePort != nil {
+		port = *details.TargetResourcePort
+	}
+	return FormatRemoteAddress(*details.TargetResourcePrivateIpAddress, port), nil
+}
+
+// managedSSHClientConfig creates an SSH config for user that authenticates
+// with the session's key.
+func managedSSHClientConfig(manager *SessionManager, cfg *config.Config, user string) (*ssh.ClientConfig, error) {
+	var (
+		sshConfig *ssh.ClientConfig
+		err       error
+	)
+	if signer := manager.GetEphemeralSigner(); signer != nil {
+		sshConfig, err = tunnel.CreateSSHClientConfigWithSigner(user, signer)
+	} else {
+		sshConfig, err = tunnel.CreateSSHClientConfigWithAgent(user, cfg.SshPrivateKeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH config: %w", err)
+	}
+	return sshConfig, nil
+}
@@ -0,0 +1,78 @@
+package bastion
+
+import (
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+)
+
+func TestManagedSSHTargetGetPort(t *testing.T) {
+	if got := (&ManagedSSHTarget{}).GetPort(); got != 22 {
+		t.Errorf("GetPort() = %d, want 22", got)
+	}
+	if got := (&ManagedSSHTarget{Port: 2222}).GetPort(); got != 2222 {
+		t.Errorf("GetPort() = %d, want 2222", got)
+	}
+}
+
+func TestManagedSessionMatchesTarget(t *testing.T) {
+	instanceID := "ocid1.instance.oc1.iad.test"
+	user := "opc"
+	port := 22
+	session := bastion.SessionSummary{
+		TargetResourceDetails: bastion.ManagedSshSessionTargetResourceDetails{
+			TargetResourceId:                      &instanceID,
+			TargetResourceOperatingSystemUserName: &user,
+			TargetResourcePort:                    &port,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		target *ManagedSSHTarget
+		want   bool
+	}{
+		{"same target", &ManagedSSHTarget{InstanceID: instanceID, User: "opc"}, true},
+		{"other user", &ManagedSSHTarget{InstanceID: instanceID, User: "ubuntu"}, false},
+		{"other port", &ManagedSSHTarget{InstanceID: instanceID, User: "opc", Port: 2222}, false},
+		{"other instance", &ManagedSSHTarget{InstanceID: "ocid1.instance.oc1.iad.other", User: "opc"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := managedSessionMatchesTarget(session, tt.target); got != tt.want {
+				t.Errorf("managedSessionMatchesTarget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	ip := "10.0.0.5"
+	forwarding := bastion.SessionSummary{
+		TargetResourceDetails: bastion.PortForwardingSessionTargetResourceDetails{TargetResourcePrivateIpAddress: &ip},
+	}
+	if managedSessionMatchesTarget(forwarding, &ManagedSSHTarget{InstanceID: instanceID, User: "opc"}) {
+		t.Error("managedSessionMatchesTarget() should not match a port forwarding session")
+	}
+}
+
+func TestManagedSessionAddress(t *testing.T) {
+	sessionID := "ocid1.bastionsession.oc1.iad.test"
+	ip := "10.0.0.5"
+	session := &bastion.Session{
+		Id:                    &sessionID,
+		TargetResourceDetails: bastion.ManagedSshSessionTargetResourceDetails{TargetResourcePrivateIpAddress: &ip},
+	}
+
+	addr, err := managedSessionAddress(session, &ManagedSSHTarget{Port: 2222})
+	if err != nil {
+		t.Fatalf("managedSessionAddress() error = %v", err)
+	}
+	if addr != "10.0.0.5:2222" {
+		t.Errorf("managedSessionAddress() = %q, want %q", addr, "10.0.0.5:2222")
+	}
+
+	session.TargetResourceDetails = bastion.ManagedSshSessionTargetResourceDetails{}
+	if _, err := managedSessionAddress(session, &ManagedSSHTarget{}); err == nil {
+		t.Error("managedSessionAddress() should error without a target address")
+	}
+}
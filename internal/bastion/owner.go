@@ -0,0 +1,85 @@
+package bastion
+
+import (
+	"os"
+	"strings"
+)
+
+// ClientVersion is the tunatap version recorded on the sessions it creates.
+var ClientVersion = "dev"
+
+// ownerSeparator starts each field of the owner suffix of a session display
+// name. Bastion sessions cannot carry tags, so the owner is kept in the name.
+const ownerSeparator = "~"
+
+// SessionOwner identifies who created a bastion session.
+type SessionOwner struct {
+	User    string
+	Host    string
+	Version string
+}
+
+// localSessionOwner returns the owner of the sessions this process creates.
+func localSessionOwner() SessionOwner {
+	host, _ := os.Hostname()
+	// The domain adds length without helping to recognise the machine
+	if i := strings.Index(host, "."); i > 0 {
+		host = host[:i]
+	}
+	return SessionOwner{User: localUserName(), Host: host, Version: ClientVersion}
+}
+
+// suffix returns the owner fields to append to a display name, such as
+// "~user=alice~host=laptop~version=1.2.0". Unset fields are left out.
+func (o SessionOwner) suffix() string {
+	var b strings.Builder
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		value = strings.NewReplacer(ownerSeparator, "-", "=", "-").Replace(value)
+		b.WriteString(ownerSeparator + key + "=" + value)
+	}
+	add("user", o.User)
+	add("host", o.Host)
+	add("version", o.Version)
+	return b.String()
+}
+
+// withOwner appends owner's fields to a session display name, shortening
+// the name so the whole fits in maxDisplayNameLength.
+func withOwner(name string, owner SessionOwner) string {
+	suffix := owner.suffix()
+	if len(suffix) > maxDisplayNameLength/2 {
+		suffix = suffix[:maxDisplayNameLength/2]
+	}
+	if len(name)+len(suffix) > maxDisplayNameLength {
+		name = name[:maxDisplayNameLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// ParseSessionDisplayName splits a session display name into the name
+// rendered from the template and the owner recorded by withOwner. Names
+// without an owner suffix, such as those of sessions created elsewhere,
+// return a zero owner.
+func ParseSessionDisplayName(displayName string) (string, SessionOwner) {
+	name, fields, found := strings.Cut(displayName, ownerSeparator)
+	if !found {
+		return displayName, SessionOwner{}
+	}
+
+	var owner SessionOwner
+	for _, field := range strings.Split(fields, ownerSeparator) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "user":
+			owner.User = value
+		case "host":
+			owner.Host = value
+		case "version":
+			owner.Version = value
+		}
+	}
+	return name, owner
+}
@@ -0,0 +1,37 @@
+package bastion
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSessionDisplayName(t *testing.T) {
+	owner := SessionOwner{User: "alice", Host: "laptop", Version: "1.2.0"}
+	displayName := withOwner("tunatap-10.0.0.1-6443", owner)
+	if displayName != "tunatap-10.0.0.1-6443~user=alice~host=laptop~version=1.2.0" {
+		t.Errorf("withOwner() = %q", displayName)
+	}
+
+	name, got := ParseSessionDisplayName(displayName)
+	if name != "tunatap-10.0.0.1-6443" || got != owner {
+		t.Errorf("ParseSessionDisplayName() = %q, %+v, want %q, %+v", name, got, "tunatap-10.0.0.1-6443", owner)
+	}
+
+	name, got = ParseSessionDisplayName("console-session")
+	if name != "console-session" || got != (SessionOwner{}) {
+		t.Errorf("ParseSessionDisplayName() = %q, %+v, want no owner", name, got)
+	}
+}
+
+func TestWithOwner(t *testing.T) {
+	// Separators in values would break decoding
+	got := withOwner("tunatap-x", SessionOwner{User: "a~b", Host: "h=1"})
+	if got != "tunatap-x~user=a-b~host=h-1" {
+		t.Errorf("withOwner() = %q, want separators replaced", got)
+	}
+
+	long := withOwner("tunatap-"+strings.Repeat("x", 300), SessionOwner{User: "alice", Version: "1.2.0"})
+	if len(long) != maxDisplayNameLength || !strings.HasSuffix(long, "~user=alice~version=1.2.0") {
+		t.Errorf("withOwner() = %q (%d), want the name shortened to keep the owner", long, len(long))
+	}
+}
@@ -0,0 +1,161 @@
+package bastion
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/tunnel"
+)
+
+// TunnelPlan describes the bastion session and ssh command a tunnel would
+// use, as worked out by PlanTunnel.
+type TunnelPlan struct {
+	BastionID   string
+	BastionName string
+	BastionType string
+	// FallbackBastionIDs are tried if the bastion fails.
+	FallbackBastionIDs []string
+	// Target is the host:port the session targets, the first hop with hops.
+	Target string
+	// ExistingSessionID is the active session that would be reused, if any.
+	// Otherwise a new session named DisplayName would be created.
+	ExistingSessionID string
+	DisplayName       string
+	TTL               time.Duration
+	// KeySource describes the SSH key the session would carry.
+	KeySource string
+	// SSHCommand is the equivalent ssh command. A new session's OCID is
+	// not known yet, so a placeholder stands in for it.
+	SSHCommand string
+}
+
+// PlanTunnel works out what TunnelThroughBastionWithOptions would do for
+// endpoint, using only read calls: no session is created and no key pair is
+// generated. The cluster must have been validated.
+func PlanTunnel(ctx context.Context, ociClient client.OCIClientInterface, cfg *config.Config, cluster *config.Cluster, endpoint *config.ClusterEndpoint, opts *TunnelOptions) (*TunnelPlan, error) {
+	if opts == nil {
+		opts = &TunnelOptions{}
+	}
+	settings, err := validateTunnel(cfg, cluster, endpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.BastionId == nil {
+		return nil, fmt.Errorf("bastion ID not set for cluster")
+	}
+
+	bindAddress := settings.bindAddress
+	plan := &TunnelPlan{
+		BastionID:          *cluster.BastionId,
+		BastionType:        clusterBastionType(cluster),
+		FallbackBastionIDs: cluster.FallbackBastionIds,
+	}
+
+	b, err := ociClient.GetBastion(ctx, plan.BastionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bastion: %w", err)
+	}
+	if b.Name != nil {
+		plan.BastionName = *b.Name
+	}
+
+	if plan.BastionType == "INTERNAL" {
+		if cluster.CompartmentOcid == nil {
+			return nil, fmt.Errorf("compartment OCID not set")
+		}
+		// Internal bastions have no sessions; the tunnel logs in to the jump box
+		plan.Target = FormatRemoteAddress(*cluster.JumpBoxIP, 22)
+		plan.KeySource, _ = KeySource(cfg, cluster)
+		plan.SSHCommand = internalTunnelCommand(cluster, endpoint, bindAddress, opts)
+		return plan, nil
+	}
+
+	target := sessionTarget(cluster, endpoint)
+	plan.Target = FormatRemoteAddress(target.Host(), target.Port)
+
+	ttl, err := fitSessionTTL(cfg.GetSessionTTLMinutes(cluster)*60, b, cfg.HasSessionTTL(cluster))
+	if err != nil {
+		return nil, err
+	}
+	plan.TTL = time.Duration(ttl) * time.Second
+
+	if plan.KeySource, err = KeySource(cfg, cluster); err != nil {
+		return nil, err
+	}
+	m := NewSessionManager(nil, cfg)
+
+	sessionID, err := planReusableSession(ctx, ociClient, m, plan.BastionID, target)
+	if err != nil {
+		return nil, err
+	}
+	if sessionID != "" {
+		plan.ExistingSessionID = sessionID
+	} else {
+		plan.DisplayName = withOwner(sessionDisplayName(cfg.GetSessionNameTemplate(cluster), cluster.ClusterName, fmt.Sprintf("%s-%d", target.Host(), target.Port)), localSessionOwner())
+		sessionID = fmt.Sprintf("ocid1.bastionsession.%s..<new-session>", extractRealmFromOCID(plan.BastionID))
+	}
+	plan.SSHCommand = standardTunnelCommand(cfg, cluster, endpoint, sessionID, bindAddress, opts)
+	return plan, nil
+}
+
+// planReusableSession returns the active session on bastionID that
+// GetOrCreateSession would reuse for target, or "" if it would create one.
+// Ephemeral keys only live in the process that generated them, so a new
+// process never reuses a session with them.
+func planReusableSession(ctx context.Context, ociClient client.OCIClientInterface, m *SessionManager, bastionID string, target *config.ClusterEndpoint) (string, error) {
+	if m.useEphemeralKeys {
+		return "", nil
+	}
+
+	sessions, err := ociClient.ListSessions(ctx, bastionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, session := range sessions {
+		if session.LifecycleState != bastion.SessionLifecycleStateActive || !m.sessionMatchesTarget(session, target) {
+			continue
+		}
+		fullSession, err := ociClient.GetSession(ctx, bastionID, *session.Id)
+		if err != nil {
+			continue
+		}
+		if m.sessionHasTimeRemaining(fullSession) {
+			return *session.Id, nil
+		}
+	}
+	return "", nil
+}
+
+// KeySource describes the SSH key a tunnel to cluster would authenticate
+// with: a new ephemeral key, the SSH agent or a key file. Nothing is
+// generated or read.
+func KeySource(cfg *config.Config, cluster *config.Cluster) (string, error) {
+	// Internal bastions log in to the jump box with the user's own key
+	if clusterBastionType(cluster) == "INTERNAL" || !NewSessionManager(nil, cfg).useEphemeralKeys {
+		return planKeyFileSource(cfg), nil
+	}
+	rotator, err := keyRotator(cfg)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("new ephemeral %s key, generated in memory", rotator.Algorithm()), nil
+}
+
+// planKeyFileSource describes the key a session would carry when ephemeral
+// keys are not used, which is the SSH agent's first key if it has any.
+func planKeyFileSource(cfg *config.Config) string {
+	if tunnel.SSHAgentAvailable() {
+		if signers, err := tunnel.GetSSHAgentSigners(); err == nil && len(signers) > 0 {
+			return "SSH agent"
+		}
+	}
+	keyPath := cfg.SshPrivateKeyFile
+	if keyPath == "" {
+		keyPath = "~/.ssh/id_rsa"
+	}
+	return fmt.Sprintf("key file %s", keyPath)
+}
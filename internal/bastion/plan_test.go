@@ -0,0 +1,118 @@
+package bastion
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestPlanTunnelMakesNoWrites(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	bastionID := "ocid1.bastion.oc1..test"
+	maxTTL := 3 * 3600
+	mock.AddBastion(&bastion.Bastion{Id: &bastionID, Name: utils.StringPtr("prod-bastion"), MaxSessionTtlInSeconds: &maxTTL})
+
+	cfg := config.DefaultConfig()
+	localPort := 6443
+	cluster := &config.Cluster{ClusterName: "prod", Region: "us-ashburn-1", BastionId: &bastionID, LocalPort: &localPort}
+	endpoint := &config.ClusterEndpoint{Name: "private", Ip: "10.0.1.100", Port: 6443}
+
+	plan, err := PlanTunnel(context.Background(), mock, cfg, cluster, endpoint, nil)
+	if err != nil {
+		t.Fatalf("PlanTunnel() error = %v", err)
+	}
+	if plan.BastionName != "prod-bastion" || plan.Target != "10.0.1.100:6443" {
+		t.Errorf("PlanTunnel() = bastion %q target %q, want prod-bastion 10.0.1.100:6443", plan.BastionName, plan.Target)
+	}
+	if plan.ExistingSessionID != "" || !strings.HasPrefix(plan.DisplayName, SessionDisplayNamePrefix) {
+		t.Errorf("PlanTunnel() = session %q name %q, want a new tunatap session", plan.ExistingSessionID, plan.DisplayName)
+	}
+	if plan.TTL != 3*time.Hour {
+		t.Errorf("PlanTunnel() TTL = %s, want 3h", plan.TTL)
+	}
+	if !strings.Contains(plan.KeySource, "ephemeral") {
+		t.Errorf("PlanTunnel() KeySource = %q, want an ephemeral key", plan.KeySource)
+	}
+	if !strings.Contains(plan.SSHCommand, "-L 6443:10.0.1.100:6443") || !strings.Contains(plan.SSHCommand, "host.bastion.us-ashburn-1.oci.oraclecloud.com") {
+		t.Errorf("PlanTunnel() SSHCommand = %q", plan.SSHCommand)
+	}
+
+	for _, call := range mock.GetCalls() {
+		switch call.Method {
+		case "GetBastion", "ListSessions", "GetSession":
+		default:
+			t.Errorf("PlanTunnel() called %s", call.Method)
+		}
+	}
+}
+
+func TestPlanTunnelReusesSession(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	bastionID := "ocid1.bastion.oc1..test"
+	mock.AddBastion(&bastion.Bastion{Id: &bastionID})
+	sessionID := "ocid1.bastionsession.oc1..active"
+	mock.Sessions[sessionID] = &bastion.Session{
+		Id:             &sessionID,
+		BastionId:      &bastionID,
+		LifecycleState: bastion.SessionLifecycleStateActive,
+		TargetResourceDetails: bastion.PortForwardingSessionTargetResourceDetails{
+			TargetResourcePrivateIpAddress: common.String("10.0.1.100"),
+			TargetResourcePort:             common.Int(6443),
+		},
+		TimeCreated:         &common.SDKTime{Time: time.Now()},
+		SessionTtlInSeconds: common.Int(3 * 3600),
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.SshPrivateKeyFile = "~/.ssh/id_ed25519"
+	localPort := 6443
+	cluster := &config.Cluster{ClusterName: "prod", Region: "us-ashburn-1", BastionId: &bastionID, LocalPort: &localPort}
+	endpoint := &config.ClusterEndpoint{Ip: "10.0.1.100", Port: 6443}
+
+	plan, err := PlanTunnel(context.Background(), mock, cfg, cluster, endpoint, nil)
+	if err != nil {
+		t.Fatalf("PlanTunnel() error = %v", err)
+	}
+	if plan.ExistingSessionID != sessionID {
+		t.Errorf("PlanTunnel() ExistingSessionID = %q, want %q", plan.ExistingSessionID, sessionID)
+	}
+	if !strings.Contains(plan.SSHCommand, sessionID+"@") {
+		t.Errorf("PlanTunnel() SSHCommand = %q, want the existing session", plan.SSHCommand)
+	}
+}
+
+func TestKeySource(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	internal := "INTERNAL"
+
+	tests := []struct {
+		name    string
+		keyFile string
+		cluster *config.Cluster
+		want    string
+	}{
+		{"ephemeral by default", "", &config.Cluster{}, "new ephemeral ed25519 key"},
+		{"key file", "~/.ssh/id_ed25519", &config.Cluster{}, "key file ~/.ssh/id_ed25519"},
+		{"internal bastion", "", &config.Cluster{BastionType: &internal}, "key file ~/.ssh/id_rsa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.SshPrivateKeyFile = tt.keyFile
+			got, err := KeySource(cfg, tt.cluster)
+			if err != nil {
+				t.Fatalf("KeySource() error = %v", err)
+			}
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("KeySource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package bastion
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+)
+
+// Freeform tags on bastions created by tunatap.
+const (
+	// CreatedByTag marks a bastion as created by tunatap; only such
+	// bastions are ever deleted by it.
+	CreatedByTag = "tunatap-created"
+	// ClusterTag records the cluster a bastion was created for.
+	ClusterTag = "tunatap-cluster"
+	// ExpiresTag holds the RFC 3339 time after which the bastion may be
+	// deleted.
+	ExpiresTag = "tunatap-expires"
+)
+
+const (
+	// bastionCreateTimeout bounds how long CreateBastion waits for the new
+	// bastion to become active.
+	bastionCreateTimeout = 15 * time.Minute
+
+	// bastionPollInterval is how often a new bastion's state is checked.
+	bastionPollInterval = 10 * time.Second
+)
+
+// CreateOptions describes a standard bastion to create for a cluster.
+type CreateOptions struct {
+	ClusterName   string
+	CompartmentID string
+	// SubnetID is the subnet the bastion reaches, normally the cluster's
+	// API endpoint subnet.
+	SubnetID string
+	// AllowCIDRs are the client CIDR blocks allowed to connect.
+	AllowCIDRs []string
+	// TTL, if non-zero, tags the bastion to expire after this long.
+	TTL time.Duration
+}
+
+// ValidateAllowCIDRs checks that at least one valid CIDR block is allowed.
+// Allowing every address is permitted but logged.
+func ValidateAllowCIDRs(cidrs []string) error {
+	if len(cidrs) == 0 {
+		return fmt.Errorf("no client CIDR blocks to allow; set bastion_allow_cidrs or use --allow-cidr (e.g. your public IP with /32)")
+	}
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid client CIDR block %q: %w", cidr, err)
+		}
+		if ones, _ := ipNet.Mask.Size(); ones == 0 {
+			log.Warn().Msgf("Bastion will accept connections from any address (%s)", cidr)
+		}
+	}
+	return nil
+}
+
+// nonAlphanumeric matches the characters not allowed in bastion names.
+var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// Name returns the name of the bastion tunatap creates for a cluster.
+// Bastion names may only contain letters and digits.
+func Name(clusterName string) string {
+	name := "tunatap" + nonAlphanumeric.ReplaceAllString(clusterName, "")
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// CreateBastion creates a standard bastion for a cluster and waits for it
+// to become active.
+func CreateBastion(ctx context.Context, ociClient client.OCIClientInterface, opts *CreateOptions) (*bastion.Bastion, error) {
+	if err := ValidateAllowCIDRs(opts.AllowCIDRs); err != nil {
+		return nil, err
+	}
+	if opts.SubnetID == "" {
+		return nil, fmt.Errorf("no subnet to create the bastion in")
+	}
+
+	tags := map[string]string{
+		CreatedByTag: "true",
+		ClusterTag:   opts.ClusterName,
+	}
+	if opts.TTL > 0 {
+		tags[ExpiresTag] = time.Now().Add(opts.TTL).UTC().Format(time.RFC3339)
+	}
+
+	name := Name(opts.ClusterName)
+	bastionType := "STANDARD"
+	maxSessionTTL := config.MaxSessionTTLMinutes * 60
+	details := bastion.CreateBastionDetails{
+		BastionType:              &bastionType,
+		CompartmentId:            &opts.CompartmentID,
+		TargetSubnetId:           &opts.SubnetID,
+		Name:                     &name,
+		ClientCidrBlockAllowList: opts.AllowCIDRs,
+		MaxSessionTtlInSeconds:   &maxSessionTTL,
+		FreeformTags:             tags,
+	}
+
+	log.Info().Msgf("Creating bastion %s in subnet %s", name, opts.SubnetID)
+	created, err := ociClient.CreateBastion(ctx, details)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msgf("Bastion created: %s, waiting for active state (this takes a few minutes)...", *created.Id)
+	ctx, cancel := context.WithTimeout(ctx, bastionCreateTimeout)
+	defer cancel()
+	return waitForBastionActive(ctx, ociClient, *created.Id)
+}
+
+// waitForBastionActive polls a bastion until it is active.
+func waitForBastionActive(ctx context.Context, ociClient client.OCIClientInterface, bastionID string) (*bastion.Bastion, error) {
+	ticker := time.NewTicker(bastionPollInterval)
+	defer ticker.Stop()
+
+	for {
+		b, err := ociClient.GetBastion(ctx, bastionID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch b.LifecycleState {
+		case bastion.BastionLifecycleStateActive:
+			return b, nil
+		case bastion.BastionLifecycleStateFailed, bastion.BastionLifecycleStateDeleted:
+			return nil, fmt.Errorf("bastion %s entered state %s", bastionID, b.LifecycleState)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for bastion %s to become active: %w", bastionID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// CreatedBastions lists the active bastions in a compartment that tunatap
+// created for clusterName, or for any cluster if clusterName is empty.
+func CreatedBastions(ctx context.Context, ociClient client.OCIClientInterface, compartmentID, clusterName string) ([]bastion.BastionSummary, error) {
+	bastions, err := ociClient.ListBastions(ctx, compartmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []bastion.BastionSummary
+	for _, b := range bastions {
+		if b.LifecycleState != bastion.BastionLifecycleStateActive || b.FreeformTags[CreatedByTag] != "true" {
+			continue
+		}
+		if clusterName != "" && b.FreeformTags[ClusterTag] != clusterName {
+			continue
+		}
+		created = append(created, b)
+	}
+	return created, nil
+}
+
+// IsExpired reports whether a bastion's expiry tag has passed at now.
+// Bastions without a valid expiry tag never expire.
+func IsExpired(tags map[string]string, now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339, tags[ExpiresTag])
+	if err != nil {
+		return false
+	}
+	return now.After(expires)
+}
+
+// DeleteBastion deletes a bastion that tunatap created. Other bastions are
+// refused.
+func DeleteBastion(ctx context.Context, ociClient client.OCIClientInterface, bastionID string) error {
+	b, err := ociClient.GetBastion(ctx, bastionID)
+	if err != nil {
+		return err
+	}
+	if b.FreeformTags[CreatedByTag] != "true" {
+		return fmt.Errorf("bastion %s was not created by tunatap; refusing to delete it", bastionID)
+	}
+
+	log.Info().Msgf("Deleting bastion %s", bastionID)
+	return ociClient.DeleteBastion(ctx, bastionID)
+}
+
+// AllowClientCIDR adds cidr to a bastion's client CIDR allowlist, unless it
+// is already there. It reports whether the allowlist was changed.
+func AllowClientCIDR(ctx context.Context, ociClient client.OCIClientInterface, bastionID, cidr string) (bool, error) {
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return false, fmt.Errorf("invalid client CIDR block %q: %w", cidr, err)
+	}
+
+	b, err := ociClient.GetBastion(ctx, bastionID)
+	if err != nil {
+		return false, err
+	}
+	if slices.Contains(b.ClientCidrBlockAllowList, cidr) {
+		return false, nil
+	}
+
+	allowList := append(slices.Clone(b.ClientCidrBlockAllowList), cidr)
+	log.Info().Msgf("Adding %s to the client allowlist of bastion %s", cidr, bastionID)
+	if err := ociClient.UpdateBastion(ctx, bastionID, bastion.UpdateBastionDetails{ClientCidrBlockAllowList: allowList}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,146 @@
+package bastion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestValidateAllowCIDRs(t *testing.T) {
+	tests := []struct {
+		cidrs   []string
+		wantErr bool
+	}{
+		{nil, true},
+		{[]string{"203.0.113.7/32"}, false},
+		{[]string{"10.0.0.0/8", "0.0.0.0/0"}, false},
+		{[]string{"203.0.113.7"}, true},
+		{[]string{"10.0.0.0/8", "bogus"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateAllowCIDRs(tt.cidrs); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateAllowCIDRs(%v) error = %v, wantErr %v", tt.cidrs, err, tt.wantErr)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	if got, want := Name("prod-cluster_1"), "tunatapprodcluster1"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		tags map[string]string
+		want bool
+	}{
+		{nil, false},
+		{map[string]string{ExpiresTag: "not-a-time"}, false},
+		{map[string]string{ExpiresTag: "2026-01-02T11:00:00Z"}, true},
+		{map[string]string{ExpiresTag: "2026-01-02T13:00:00Z"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsExpired(tt.tags, now); got != tt.want {
+			t.Errorf("IsExpired(%v) = %v, want %v", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestCreateAndDeleteBastion(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddBastion(&bastion.Bastion{
+		Id:   utils.StringPtr("ocid1.bastion.oc1..existing"),
+		Name: utils.StringPtr("existing"),
+	})
+
+	created, err := CreateBastion(context.Background(), mock, &CreateOptions{
+		ClusterName:   "prod",
+		CompartmentID: "ocid1.compartment.oc1..prod",
+		SubnetID:      "ocid1.subnet.oc1..api",
+		AllowCIDRs:    []string{"203.0.113.7/32"},
+		TTL:           time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("CreateBastion() error = %v", err)
+	}
+	if *created.BastionType != "STANDARD" || *created.TargetSubnetId != "ocid1.subnet.oc1..api" {
+		t.Errorf("CreateBastion() = type %s subnet %s, want STANDARD in the API subnet", *created.BastionType, *created.TargetSubnetId)
+	}
+	if created.FreeformTags[CreatedByTag] != "true" || created.FreeformTags[ClusterTag] != "prod" {
+		t.Errorf("CreateBastion() tags = %v, want created-by and cluster tags", created.FreeformTags)
+	}
+	if IsExpired(created.FreeformTags, time.Now()) || !IsExpired(created.FreeformTags, time.Now().Add(2*time.Hour)) {
+		t.Errorf("CreateBastion() expiry tag = %q, want about an hour from now", created.FreeformTags[ExpiresTag])
+	}
+
+	bastions, err := CreatedBastions(context.Background(), mock, "ocid1.compartment.oc1..prod", "prod")
+	if err != nil {
+		t.Fatalf("CreatedBastions() error = %v", err)
+	}
+	if len(bastions) != 1 || *bastions[0].Id != *created.Id {
+		t.Fatalf("CreatedBastions() = %d bastions, want only the created one", len(bastions))
+	}
+
+	if err := DeleteBastion(context.Background(), mock, "ocid1.bastion.oc1..existing"); err == nil {
+		t.Error("DeleteBastion() deleted a bastion tunatap did not create")
+	}
+	if err := DeleteBastion(context.Background(), mock, *created.Id); err != nil {
+		t.Fatalf("DeleteBastion() error = %v", err)
+	}
+	if _, err := mock.GetBastion(context.Background(), *created.Id); err == nil {
+		t.Error("DeleteBastion() left the bastion in place")
+	}
+}
+
+func TestCreateBastionRequiresCIDRs(t *testing.T) {
+	mock := client.NewMockOCIClient()
+
+	_, err := CreateBastion(context.Background(), mock, &CreateOptions{
+		ClusterName:   "prod",
+		CompartmentID: "ocid1.compartment.oc1..prod",
+		SubnetID:      "ocid1.subnet.oc1..api",
+	})
+	if err == nil {
+		t.Fatal("CreateBastion() error = nil, want an error without allowed CIDRs")
+	}
+	for _, call := range mock.GetCalls() {
+		if call.Method == "CreateBastion" {
+			t.Error("CreateBastion() called the API without allowed CIDRs")
+		}
+	}
+}
+
+func TestAllowClientCIDR(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	bastionID := "ocid1.bastion.oc1..test"
+	mock.AddBastion(&bastion.Bastion{Id: &bastionID, ClientCidrBlockAllowList: []string{"10.0.0.0/8"}})
+
+	changed, err := AllowClientCIDR(context.Background(), mock, bastionID, "203.0.113.7/32")
+	if err != nil {
+		t.Fatalf("AllowClientCIDR() error = %v", err)
+	}
+	if !changed {
+		t.Error("AllowClientCIDR() = false, want true for a new CIDR")
+	}
+
+	b, _ := mock.GetBastion(context.Background(), bastionID)
+	if len(b.ClientCidrBlockAllowList) != 2 || b.ClientCidrBlockAllowList[1] != "203.0.113.7/32" {
+		t.Errorf("allowlist = %v, want the CIDR appended", b.ClientCidrBlockAllowList)
+	}
+
+	if changed, err := AllowClientCIDR(context.Background(), mock, bastionID, "203.0.113.7/32"); err != nil || changed {
+		t.Errorf("AllowClientCIDR() again = %v, %v, want false, nil", changed, err)
+	}
+	if _, err := AllowClientCIDR(context.Background(), mock, bastionID, "bogus"); err == nil {
+		t.Error("AllowClientCIDR() error = nil, want an error for an invalid CIDR")
+	}
+}
@@ -0,0 +1,89 @@
+package bastion
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+// SessionDisplayNamePrefix starts the display name of every session tunatap
+// creates. Only sessions with this prefix are pruned.
+const SessionDisplayNamePrefix = "tunatap-"
+
+// PruneOptions selects the sessions PruneSessions deletes.
+type PruneOptions struct {
+	// OlderThan, if non-zero, also prunes active sessions created at least
+	// this long ago. Otherwise only failed and expired sessions are pruned.
+	OlderThan time.Duration
+	// DryRun reports the sessions that would be pruned without deleting them.
+	DryRun bool
+}
+
+// PrunedSession is a session selected by PruneSessions.
+type PrunedSession struct {
+	Session bastion.SessionSummary
+	// Reason says why the session was selected: failed, expired or stale.
+	Reason string
+	// Err is set if deleting the session failed.
+	Err error
+}
+
+// pruneReason returns why a session should be pruned at now, or "" to keep
+// it.
+func pruneReason(session bastion.SessionSummary, olderThan time.Duration, now time.Time) string {
+	if session.DisplayName == nil || !strings.HasPrefix(*session.DisplayName, SessionDisplayNamePrefix) {
+		return ""
+	}
+
+	switch session.LifecycleState {
+	case bastion.SessionLifecycleStateFailed:
+		return "failed"
+	case bastion.SessionLifecycleStateActive, bastion.SessionLifecycleStateCreating:
+	default:
+		return ""
+	}
+
+	if session.TimeCreated == nil {
+		return ""
+	}
+	created := session.TimeCreated.Time
+	if session.SessionTtlInSeconds != nil && !now.Before(created.Add(time.Duration(*session.SessionTtlInSeconds)*time.Second)) {
+		return "expired"
+	}
+	if olderThan > 0 && now.Sub(created) >= olderThan {
+		return "stale"
+	}
+	return ""
+}
+
+// PruneSessions deletes the failed, expired and (with opts.OlderThan) stale
+// sessions tunatap created on a bastion. It returns the selected sessions;
+// a failed deletion is recorded on its session rather than stopping the
+// prune.
+func PruneSessions(ctx context.Context, ociClient client.OCIClientInterface, bastionID string, opts *PruneOptions) ([]PrunedSession, error) {
+	sessions, err := ociClient.ListSessions(ctx, bastionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var pruned []PrunedSession
+	for _, session := range sessions {
+		reason := pruneReason(session, opts.OlderThan, now)
+		if reason == "" || session.Id == nil {
+			continue
+		}
+
+		p := PrunedSession{Session: session, Reason: reason}
+		if !opts.DryRun {
+			log.Debug().Msgf("Deleting %s session %s", reason, *session.Id)
+			p.Err = ociClient.DeleteSession(ctx, bastionID, *session.Id)
+		}
+		pruned = append(pruned, p)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,83 @@
+package bastion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestPruneReason(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	session := func(name string, state bastion.SessionLifecycleStateEnum, age time.Duration) bastion.SessionSummary {
+		ttl := 3 * 3600
+		return bastion.SessionSummary{
+			DisplayName:         utils.StringPtr(name),
+			LifecycleState:      state,
+			TimeCreated:         &common.SDKTime{Time: now.Add(-age)},
+			SessionTtlInSeconds: &ttl,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		session   bastion.SessionSummary
+		olderThan time.Duration
+		want      string
+	}{
+		{"other tool", session("manual", bastion.SessionLifecycleStateFailed, time.Hour), 0, ""},
+		{"failed", session("tunatap-10.0.0.1-6443", bastion.SessionLifecycleStateFailed, time.Hour), 0, "failed"},
+		{"deleted", session("tunatap-10.0.0.1-6443", bastion.SessionLifecycleStateDeleted, 5*time.Hour), 0, ""},
+		{"expired", session("tunatap-10.0.0.1-6443", bastion.SessionLifecycleStateActive, 4*time.Hour), 0, "expired"},
+		{"active", session("tunatap-10.0.0.1-6443", bastion.SessionLifecycleStateActive, 2*time.Hour), 0, ""},
+		{"stale", session("tunatap-ssh-opc", bastion.SessionLifecycleStateActive, 2*time.Hour), time.Hour, "stale"},
+		{"young", session("tunatap-ssh-opc", bastion.SessionLifecycleStateActive, 30*time.Minute), time.Hour, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pruneReason(tt.session, tt.olderThan, now); got != tt.want {
+				t.Errorf("pruneReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneSessions(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	bastionID := "ocid1.bastion.oc1..test"
+	failed := "ocid1.session.oc1..failed"
+	mine := "ocid1.session.oc1..mine"
+	other := "ocid1.session.oc1..other"
+	mock.Sessions[failed] = &bastion.Session{Id: &failed, BastionId: &bastionID, DisplayName: utils.StringPtr("tunatap-ssh-opc"), LifecycleState: bastion.SessionLifecycleStateFailed}
+	mock.Sessions[mine] = &bastion.Session{Id: &mine, BastionId: &bastionID, DisplayName: utils.StringPtr("tunatap-10.0.0.1-6443"), LifecycleState: bastion.SessionLifecycleStateActive}
+	mock.Sessions[other] = &bastion.Session{Id: &other, BastionId: &bastionID, DisplayName: utils.StringPtr("manual"), LifecycleState: bastion.SessionLifecycleStateFailed}
+
+	pruned, err := PruneSessions(context.Background(), mock, bastionID, &PruneOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("PruneSessions() error = %v", err)
+	}
+	if len(pruned) != 1 || *pruned[0].Session.Id != failed {
+		t.Fatalf("PruneSessions(dry run) selected %d sessions, want only the failed tunatap session", len(pruned))
+	}
+	if _, ok := mock.Sessions[failed]; !ok {
+		t.Fatal("PruneSessions(dry run) deleted a session")
+	}
+
+	if _, err := PruneSessions(context.Background(), mock, bastionID, &PruneOptions{}); err != nil {
+		t.Fatalf("PruneSessions() error = %v", err)
+	}
+	if _, ok := mock.Sessions[failed]; ok {
+		t.Error("PruneSessions() kept the failed session")
+	}
+	if _, ok := mock.Sessions[mine]; !ok {
+		t.Error("PruneSessions() deleted an active session")
+	}
+	if _, ok := mock.Sessions[other]; !ok {
+		t.Error("PruneSessions() deleted a session tunatap did not create")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/user"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,17 +14,56 @@ import (
 	"github.com/scotttball/tunatap/internal/client"
 	"github.com/scotttball/tunatap/internal/config"
 	"github.com/scotttball/tunatap/internal/sshkeys"
+	"github.com/scotttball/tunatap/internal/tracing"
 	"github.com/scotttball/tunatap/internal/tunnel"
 	"golang.org/x/crypto/ssh"
 )
 
 const (
-	sessionMaxTTLHours     = 3
 	sessionCheckBuffer     = 10 * time.Minute
 	sessionRefreshBuffer   = 5 * time.Minute  // Start refresh 5 minutes before expiration
 	sessionRefreshInterval = 30 * time.Second // How often to check for refresh
+
+	// maxDisplayNameLength is the longest session display name OCI accepts.
+	maxDisplayNameLength = 255
+
+	// sessionWaitReportInterval is how often waiting on a session that stays
+	// in one state is reported.
+	sessionWaitReportInterval = 30 * time.Second
 )
 
+// keyRotators holds one ephemeral key rotator per algorithm and rotation
+// interval, shared by all session managers so that keys can outlive the
+// manager that generated them.
+var (
+	keyRotatorsMu sync.Mutex
+	keyRotators   = make(map[string]*sshkeys.Rotator)
+)
+
+// keyRotator returns the shared ephemeral key rotator for cfg's key policy.
+func keyRotator(cfg *config.Config) (*sshkeys.Rotator, error) {
+	algorithm, err := sshkeys.ParseKeyAlgorithm(cfg.EphemeralKeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	hours := cfg.GetEphemeralKeyRotationHours()
+	if hours < 0 {
+		return nil, fmt.Errorf("invalid ephemeral_key_rotation_hours %d: must not be negative", hours)
+	}
+	interval := time.Duration(hours) * time.Hour
+
+	keyRotatorsMu.Lock()
+	defer keyRotatorsMu.Unlock()
+
+	key := fmt.Sprintf("%s/%s", algorithm, interval)
+	rotator, ok := keyRotators[key]
+	if !ok {
+		rotator = sshkeys.NewRotator(algorithm, interval)
+		keyRotators[key] = rotator
+	}
+	return rotator, nil
+}
+
 // SessionManager manages bastion sessions.
 type SessionManager struct {
 	ociClient *client.OCIClient
@@ -179,7 +220,7 @@ func (m *SessionManager) refreshSession(ctx context.Context, cluster *config.Clu
 	m.mu.Lock()
 	m.currentSession = session
 	if session.TimeCreated != nil {
-		m.sessionExpiration = session.TimeCreated.Time.Add(time.Duration(sessionMaxTTLHours) * time.Hour)
+		m.sessionExpiration = sessionExpiry(session)
 	}
 	m.mu.Unlock()
 
@@ -199,11 +240,10 @@ func (m *SessionManager) GetOrCreateSession(ctx context.Context, cluster *config
 	}
 
 	// Find an active session for our target
-	targetIP := endpoint.Ip
 	for _, session := range sessions {
 		if session.LifecycleState == bastion.SessionLifecycleStateActive {
 			// Check if this session targets our endpoint
-			if m.sessionMatchesTarget(session, targetIP, endpoint.Port) {
+			if m.sessionMatchesTarget(session, endpoint) {
 				log.Info().Msgf("Found existing active session: %s", *session.Id)
 
 				// Get full session details
@@ -213,6 +253,12 @@ func (m *SessionManager) GetOrCreateSession(ctx context.Context, cluster *config
 					continue
 				}
 
+				// An ephemeral key is only reused while the rotation policy allows
+				if m.useEphemeralKeys && !m.adoptSessionKey(fullSession) {
+					log.Debug().Msgf("Session %s does not carry a reusable ephemeral key", *session.Id)
+					continue
+				}
+
 				// Check if session has enough time remaining
 				if m.sessionHasTimeRemaining(fullSession) {
 					// Track this session
@@ -235,6 +281,72 @@ func (m *SessionManager) GetOrCreateSession(ctx context.Context, cluster *config
 	return session, nil
 }
 
+// GetOrCreateManagedSSHSession gets an active managed SSH session for target
+// or creates a new one.
+func (m *SessionManager) GetOrCreateManagedSSHSession(ctx context.Context, cluster *config.Cluster, target *ManagedSSHTarget) (*bastion.Session, error) {
+	if cluster.BastionId == nil {
+		return nil, fmt.Errorf("bastion ID not set for cluster")
+	}
+
+	// An existing session can only be used with the key it was created with
+	if m.canReuseSessions() {
+		sessions, err := m.ociClient.ListSessions(ctx, *cluster.BastionId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+
+		for _, session := range sessions {
+			if session.LifecycleState != bastion.SessionLifecycleStateActive || !managedSessionMatchesTarget(session, target) {
+				continue
+			}
+
+			fullSession, err := m.ociClient.GetSession(ctx, *cluster.BastionId, *session.Id)
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to get session details, will create new")
+				continue
+			}
+			if m.useEphemeralKeys && !m.adoptSessionKey(fullSession) {
+				continue
+			}
+			if m.sessionHasTimeRemaining(fullSession) {
+				log.Info().Msgf("Found existing active session: %s", *session.Id)
+				m.trackSession(fullSession)
+				return fullSession, nil
+			}
+		}
+	}
+
+	log.Info().Msgf("Creating new managed SSH session for %s@%s", target.User, target.InstanceID)
+
+	user := target.User
+	instanceID := target.InstanceID
+	port := target.GetPort()
+	session, err := m.createAndWait(ctx, cluster, bastion.CreateManagedSshSessionTargetResourceDetails{
+		TargetResourceId:                      &instanceID,
+		TargetResourceOperatingSystemUserName: &user,
+		TargetResourcePort:                    &port,
+	}, fmt.Sprintf("ssh-%s", user))
+	if err != nil {
+		return nil, err
+	}
+
+	m.trackSession(session)
+	return session, nil
+}
+
+// managedSessionMatchesTarget checks if a session is a managed SSH session
+// for target.
+func managedSessionMatchesTarget(session bastion.SessionSummary, target *ManagedSSHTarget) bool {
+	details, ok := session.TargetResourceDetails.(bastion.ManagedSshSessionTargetResourceDetails)
+	if !ok {
+		return false
+	}
+
+	return details.TargetResourceId != nil && *details.TargetResourceId == target.InstanceID &&
+		details.TargetResourceOperatingSystemUserName != nil && *details.TargetResourceOperatingSystemUserName == target.User &&
+		(details.TargetResourcePort == nil || *details.TargetResourcePort == target.GetPort())
+}
+
 // trackSession updates the session tracking information.
 func (m *SessionManager) trackSession(session *bastion.Session) {
 	m.mu.Lock()
@@ -242,27 +354,29 @@ func (m *SessionManager) trackSession(session *bastion.Session) {
 
 	m.currentSession = session
 	if session.TimeCreated != nil {
-		m.sessionExpiration = session.TimeCreated.Time.Add(time.Duration(sessionMaxTTLHours) * time.Hour)
+		m.sessionExpiration = sessionExpiry(session)
 		log.Debug().Msgf("Session expires at: %s (in %s)",
 			m.sessionExpiration.Format(time.RFC3339),
 			time.Until(m.sessionExpiration).Round(time.Minute))
 	}
 }
 
-// sessionMatchesTarget checks if a session targets the given IP and port.
-func (m *SessionManager) sessionMatchesTarget(session bastion.SessionSummary, targetIP string, targetPort int) bool {
+// sessionMatchesTarget checks if a session targets the endpoint's FQDN, or
+// its IP if it has none, and port.
+func (m *SessionManager) sessionMatchesTarget(session bastion.SessionSummary, endpoint *config.ClusterEndpoint) bool {
 	if session.TargetResourceDetails == nil {
 		return false
 	}
 
 	switch details := session.TargetResourceDetails.(type) {
 	case bastion.PortForwardingSessionTargetResourceDetails:
-		if details.TargetResourcePrivateIpAddress != nil &&
-			*details.TargetResourcePrivateIpAddress == targetIP &&
-			details.TargetResourcePort != nil &&
-			*details.TargetResourcePort == targetPort {
-			return true
+		if details.TargetResourcePort == nil || *details.TargetResourcePort != endpoint.Port {
+			return false
 		}
+		if endpoint.Fqdn != "" {
+			return details.TargetResourceFqdn != nil && strings.EqualFold(*details.TargetResourceFqdn, endpoint.Fqdn)
+		}
+		return details.TargetResourcePrivateIpAddress != nil && *details.TargetResourcePrivateIpAddress == endpoint.Ip
 	}
 
 	return false
@@ -274,70 +388,268 @@ func (m *SessionManager) sessionHasTimeRemaining(session *bastion.Session) bool
 		return false
 	}
 
-	expirationTime := session.TimeCreated.Time.Add(time.Duration(sessionMaxTTLHours) * time.Hour)
-	remainingTime := time.Until(expirationTime)
+	remainingTime := time.Until(sessionExpiry(session))
 
 	return remainingTime > sessionCheckBuffer
 }
 
-// createSession creates a new bastion session.
-func (m *SessionManager) createSession(ctx context.Context, cluster *config.Cluster, endpoint *config.ClusterEndpoint) (*bastion.Session, error) {
-	log.Info().Msgf("Creating new bastion session for %s:%d", endpoint.Ip, endpoint.Port)
+// sessionExpiry returns when a session expires. Sessions without a TTL are
+// assumed to have the longest one OCI allows.
+func sessionExpiry(session *bastion.Session) time.Time {
+	ttl := time.Duration(config.MaxSessionTTLMinutes) * time.Minute
+	if session.SessionTtlInSeconds != nil {
+		ttl = time.Duration(*session.SessionTtlInSeconds) * time.Second
+	}
+	return session.TimeCreated.Time.Add(ttl)
+}
+
+// validateSessionTTL checks the cluster's session lifetime against the
+// bounds OCI allows.
+func validateSessionTTL(cfg *config.Config, cluster *config.Cluster) error {
+	minutes := cfg.GetSessionTTLMinutes(cluster)
+	if minutes < config.MinSessionTTLMinutes || minutes > config.MaxSessionTTLMinutes {
+		return fmt.Errorf("invalid session_ttl_minutes %d for cluster '%s': must be between %d and %d",
+			minutes, cluster.ClusterName, config.MinSessionTTLMinutes, config.MaxSessionTTLMinutes)
+	}
+	return nil
+}
 
-	var publicKey string
-	var err error
+// fitSessionTTL checks a session lifetime in seconds against the bastion's
+// maximum. A configured lifetime that is too long is an error, while the
+// default is lowered to the maximum.
+func fitSessionTTL(ttl int, b *bastion.Bastion, configured bool) (int, error) {
+	if b.MaxSessionTtlInSeconds == nil || ttl <= *b.MaxSessionTtlInSeconds {
+		return ttl, nil
+	}
 
-	// Use ephemeral keys if configured
-	if m.useEphemeralKeys {
-		log.Info().Msg("Using ephemeral SSH keys (in-memory, never written to disk)")
-		keyPair, keyErr := sshkeys.GenerateEphemeralKeyPair()
-		if keyErr != nil {
-			return nil, fmt.Errorf("failed to generate ephemeral keys: %w", keyErr)
+	maxTTL := *b.MaxSessionTtlInSeconds
+	if configured {
+		bastionID := ""
+		if b.Id != nil {
+			bastionID = *b.Id
 		}
-		publicKey = keyPair.PublicKeyString()
+		return 0, permanent(fmt.Errorf("session_ttl_minutes %d exceeds the %d minute session limit of bastion %s", ttl/60, maxTTL/60, bastionID))
+	}
+	log.Debug().Msgf("Bastion allows sessions of at most %d minutes, using that", maxTTL/60)
+	return maxTTL, nil
+}
 
-		// Store the key pair for use in SSH connections
-		m.mu.Lock()
-		m.ephemeralKeyPair = keyPair
-		m.mu.Unlock()
-	} else {
-		// Use traditional key file
-		publicKey, err = m.getPublicKey()
-		if err != nil {
-			return nil, fmt.Errorf("failed to read public key: %w", err)
+// sessionDisplayName renders a session display name template for a
+// cluster's session to target. Names always start with
+// SessionDisplayNamePrefix, which marks the sessions tunatap may prune.
+func sessionDisplayName(template, clusterName, target string) string {
+	name := strings.NewReplacer(
+		"{cluster}", clusterName,
+		"{target}", target,
+		"{user}", localUserName(),
+	).Replace(template)
+
+	if !strings.HasPrefix(name, SessionDisplayNamePrefix) {
+		name = SessionDisplayNamePrefix + name
+	}
+	if len(name) > maxDisplayNameLength {
+		name = name[:maxDisplayNameLength]
+	}
+	return name
+}
+
+// localUserName returns the name of the user running tunatap.
+func localUserName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		// Windows user names include the domain
+		if i := strings.LastIndex(u.Username, "\\"); i >= 0 {
+			return u.Username[i+1:]
 		}
+		return u.Username
 	}
+	return os.Getenv("USER")
+}
 
-	sessionTTL := sessionMaxTTLHours * 3600 // Convert to seconds
+// createSession creates a new bastion session.
+func (m *SessionManager) createSession(ctx context.Context, cluster *config.Cluster, endpoint *config.ClusterEndpoint) (*bastion.Session, error) {
+	log.Info().Msgf("Creating new bastion session for %s:%d", endpoint.Host(), endpoint.Port)
 
-	targetIP := endpoint.Ip
 	targetPort := endpoint.Port
+	target := bastion.CreatePortForwardingSessionTargetResourceDetails{
+		TargetResourcePort: &targetPort,
+	}
+	// The bastion resolves an FQDN target itself
+	if endpoint.Fqdn != "" {
+		fqdn := endpoint.Fqdn
+		target.TargetResourceFqdn = &fqdn
+	} else {
+		targetIP := endpoint.Ip
+		target.TargetResourcePrivateIpAddress = &targetIP
+	}
+
+	return m.createAndWait(ctx, cluster, target, fmt.Sprintf("%s-%d", endpoint.Host(), endpoint.Port))
+}
+
+// createAndWait creates a session with the given target on the cluster's
+// bastion and waits for it to become active. targetName fills {target} in
+// the display name template.
+func (m *SessionManager) createAndWait(ctx context.Context, cluster *config.Cluster, target bastion.CreateSessionTargetResourceDetails, targetName string) (*bastion.Session, error) {
+	sessionTTL, err := m.sessionTTL(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := m.sessionPublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := withOwner(sessionDisplayName(m.config.GetSessionNameTemplate(cluster), cluster.ClusterName, targetName), localSessionOwner())
 
 	sessionDetails := bastion.CreateSessionDetails{
-		BastionId: cluster.BastionId,
-		TargetResourceDetails: bastion.CreatePortForwardingSessionTargetResourceDetails{
-			TargetResourcePrivateIpAddress: &targetIP,
-			TargetResourcePort:             &targetPort,
-		},
+		BastionId:             cluster.BastionId,
+		TargetResourceDetails: target,
 		KeyDetails: &bastion.PublicKeyDetails{
 			PublicKeyContent: &publicKey,
 		},
-		DisplayName:         stringPtr(fmt.Sprintf("tunatap-%s-%d", endpoint.Ip, endpoint.Port)),
+		DisplayName:         &displayName,
 		SessionTtlInSeconds: &sessionTTL,
 	}
 
-	session, err := m.ociClient.CreateSession(ctx, *cluster.BastionId, sessionDetails)
+	createCtx, span := tracing.Start(ctx, "bastion.create_session", tracing.BastionKey.String(*cluster.BastionId))
+	session, err := m.ociClient.CreateSession(createCtx, *cluster.BastionId, sessionDetails)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+		err = fmt.Errorf("failed to create session: %w", err)
+		tracing.End(span, err)
+		return nil, err
 	}
+	span.SetAttributes(tracing.SessionKey.String(*session.Id))
+	tracing.End(span, nil)
 
 	log.Info().Msgf("Session created: %s, waiting for active state...", *session.Id)
 
 	// Wait for session to become active
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	timeout := time.Duration(m.config.GetSessionWaitTimeoutSeconds()) * time.Second
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
+	waitCtx, span = tracing.Start(waitCtx, "bastion.wait_for_active", tracing.SessionKey.String(*session.Id))
+
+	progress := &sessionWaitProgress{sessionID: *session.Id}
+	active, err := m.ociClient.WaitForSessionActive(waitCtx, *cluster.BastionId, *session.Id, &client.SessionWaitOptions{
+		PollInterval: time.Duration(m.config.GetSessionWaitPollSeconds()) * time.Second,
+		OnPoll:       progress.update,
+	})
+	if err != nil && ctx.Err() == nil && waitCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("session %s was still %s after %s; raise session_wait_timeout_seconds if the bastion is slow",
+			*session.Id, progress.state, timeout)
+		tracing.End(span, err)
+		return nil, err
+	}
+	tracing.End(span, err)
+	return active, err
+}
+
+// sessionWaitProgress reports the state of a session being waited on: every
+// state change, and a reminder while the state stays the same.
+type sessionWaitProgress struct {
+	sessionID  string
+	state      bastion.SessionLifecycleStateEnum
+	lastReport time.Duration
+}
+
+// update logs the session's state after a poll, if it is worth reporting.
+func (p *sessionWaitProgress) update(state bastion.SessionLifecycleStateEnum, elapsed time.Duration) {
+	if msg := p.report(state, elapsed); msg != "" {
+		log.Info().Msg(msg)
+	}
+}
 
-	return m.ociClient.WaitForSessionActive(ctx, *cluster.BastionId, *session.Id)
+// report records the state seen after elapsed and returns the message to
+// show for it, or "" if nothing changed since the last report.
+func (p *sessionWaitProgress) report(state bastion.SessionLifecycleStateEnum, elapsed time.Duration) string {
+	if state != p.state {
+		p.state = state
+		p.lastReport = elapsed
+		return fmt.Sprintf("Session %s is %s (%s elapsed)", p.sessionID, state, elapsed.Round(time.Second))
+	}
+	if elapsed-p.lastReport >= sessionWaitReportInterval {
+		p.lastReport = elapsed
+		return fmt.Sprintf("Still waiting for session %s, %s for %s", p.sessionID, state, elapsed.Round(time.Second))
+	}
+	return ""
+}
+
+// sessionTTL returns the lifetime in seconds of a new session on the
+// cluster's bastion.
+func (m *SessionManager) sessionTTL(ctx context.Context, cluster *config.Cluster) (int, error) {
+	ttl := m.config.GetSessionTTLMinutes(cluster) * 60
+
+	b, err := m.ociClient.GetBastion(ctx, *cluster.BastionId)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to get bastion session limit, using configured TTL")
+		return ttl, nil
+	}
+	return fitSessionTTL(ttl, b, m.config.HasSessionTTL(cluster))
+}
+
+// sessionPublicKey returns the public key for a new session. With ephemeral
+// keys the key pair comes from the rotation policy, which generates a new
+// one unless keys are reused, and is kept for SSH authentication.
+func (m *SessionManager) sessionPublicKey() (string, error) {
+	if !m.useEphemeralKeys {
+		publicKey, err := m.getPublicKey()
+		if err != nil {
+			return "", fmt.Errorf("failed to read public key: %w", err)
+		}
+		return publicKey, nil
+	}
+
+	rotator, err := keyRotator(m.config)
+	if err != nil {
+		return "", err
+	}
+
+	keyPair, err := rotator.KeyPair()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ephemeral keys: %w", err)
+	}
+	log.Info().Msgf("Using ephemeral %s SSH key (in-memory, never written to disk)", keyPair.Algorithm())
+
+	// Store the key pair for use in SSH connections
+	m.mu.Lock()
+	m.ephemeralKeyPair = keyPair
+	m.mu.Unlock()
+
+	return keyPair.PublicKeyString(), nil
+}
+
+// canReuseSessions reports whether existing sessions may be used. With
+// ephemeral keys that requires a rotation policy that reuses keys.
+func (m *SessionManager) canReuseSessions() bool {
+	if !m.useEphemeralKeys {
+		return true
+	}
+	rotator, err := keyRotator(m.config)
+	return err == nil && rotator.Interval() > 0
+}
+
+// adoptSessionKey reports whether an existing session can be used with
+// ephemeral keys, which is only the case when it carries the current key
+// and the rotation policy allows reusing it. The key is then kept for SSH
+// authentication.
+func (m *SessionManager) adoptSessionKey(session *bastion.Session) bool {
+	if session.KeyDetails == nil || session.KeyDetails.PublicKeyContent == nil {
+		return false
+	}
+
+	rotator, err := keyRotator(m.config)
+	if err != nil {
+		return false
+	}
+	keyPair := rotator.Match(*session.KeyDetails.PublicKeyContent)
+	if keyPair == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	m.ephemeralKeyPair = keyPair
+	m.mu.Unlock()
+	return true
 }
 
 // getPublicKey reads the public key from SSH agent or the configured private key file.
@@ -1,8 +1,12 @@
 package bastion
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/scotttball/tunatap/internal/config"
 )
 
@@ -32,9 +36,41 @@ func TestSessionManagerWithNilClient(t *testing.T) {
 }
 
 func TestSessionMatchesTarget(t *testing.T) {
-	// This would require mocking the bastion.SessionSummary
-	// For now, just verify the function signature exists
-	t.Log("sessionMatchesTarget test - requires OCI SDK mocking")
+	m := NewSessionManager(nil, config.DefaultConfig())
+	ipSession := bastion.SessionSummary{
+		TargetResourceDetails: bastion.PortForwardingSessionTargetResourceDetails{
+			TargetResourcePrivateIpAddress: common.String("10.0.1.100"),
+			TargetResourcePort:             common.Int(6443),
+		},
+	}
+	fqdnSession := bastion.SessionSummary{
+		TargetResourceDetails: bastion.PortForwardingSessionTargetResourceDetails{
+			TargetResourceFqdn:             common.String("db.adb.us-ashburn-1.oraclecloud.com"),
+			TargetResourcePrivateIpAddress: common.String("10.0.2.7"),
+			TargetResourcePort:             common.Int(1522),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		session  bastion.SessionSummary
+		endpoint *config.ClusterEndpoint
+		want     bool
+	}{
+		{"same ip", ipSession, &config.ClusterEndpoint{Ip: "10.0.1.100", Port: 6443}, true},
+		{"other ip", ipSession, &config.ClusterEndpoint{Ip: "10.0.1.101", Port: 6443}, false},
+		{"other port", ipSession, &config.ClusterEndpoint{Ip: "10.0.1.100", Port: 443}, false},
+		{"same fqdn", fqdnSession, &config.ClusterEndpoint{Fqdn: "DB.adb.us-ashburn-1.oraclecloud.com", Port: 1522}, true},
+		{"fqdn against ip session", ipSession, &config.ClusterEndpoint{Fqdn: "db.example.com", Ip: "10.0.1.100", Port: 6443}, false},
+		{"ip against fqdn session", fqdnSession, &config.ClusterEndpoint{Ip: "10.0.2.7", Port: 1522}, true},
+		{"no details", bastion.SessionSummary{}, &config.ClusterEndpoint{Ip: "10.0.1.100", Port: 6443}, false},
+	}
+
+	for _, tt := range tests {
+		if got := m.sessionMatchesTarget(tt.session, tt.endpoint); got != tt.want {
+			t.Errorf("sessionMatchesTarget(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
 }
 
 func TestGetPublicKeyNonExistent(t *testing.T) {
@@ -62,3 +98,122 @@ func TestStringPtr(t *testing.T) {
 		t.Errorf("stringPtr(%q) = %q, want %q", s, *ptr, s)
 	}
 }
+
+func TestSessionExpiry(t *testing.T) {
+	created := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	session := &bastion.Session{TimeCreated: &common.SDKTime{Time: created}}
+
+	if got, want := sessionExpiry(session), created.Add(3*time.Hour); !got.Equal(want) {
+		t.Errorf("sessionExpiry() = %s, want %s without a TTL", got, want)
+	}
+
+	ttl := 1800
+	session.SessionTtlInSeconds = &ttl
+	if got, want := sessionExpiry(session), created.Add(30*time.Minute); !got.Equal(want) {
+		t.Errorf("sessionExpiry() = %s, want %s", got, want)
+	}
+}
+
+func TestValidateSessionTTL(t *testing.T) {
+	tests := []struct {
+		minutes int
+		wantErr bool
+	}{
+		{29, true},
+		{30, false},
+		{180, false},
+		{181, true},
+	}
+
+	for _, tt := range tests {
+		minutes := tt.minutes
+		cluster := &config.Cluster{ClusterName: "prod", SessionTTLMinutes: &minutes}
+		if err := validateSessionTTL(config.DefaultConfig(), cluster); (err != nil) != tt.wantErr {
+			t.Errorf("validateSessionTTL(%d) error = %v, wantErr %v", tt.minutes, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFitSessionTTL(t *testing.T) {
+	maxTTL := 3600
+	b := &bastion.Bastion{Id: stringPtr("ocid1.bastion.oc1..test"), MaxSessionTtlInSeconds: &maxTTL}
+
+	if got, err := fitSessionTTL(1800, b, true); err != nil || got != 1800 {
+		t.Errorf("fitSessionTTL(1800) = %d, %v, want 1800, nil", got, err)
+	}
+	if got, err := fitSessionTTL(10800, b, false); err != nil || got != 3600 {
+		t.Errorf("fitSessionTTL(10800) default = %d, %v, want 3600, nil", got, err)
+	}
+	if _, err := fitSessionTTL(10800, b, true); err == nil {
+		t.Error("fitSessionTTL(10800) configured error = nil, want an error above the bastion limit")
+	}
+	if got, err := fitSessionTTL(10800, &bastion.Bastion{}, true); err != nil || got != 10800 {
+		t.Errorf("fitSessionTTL() without a limit = %d, %v, want 10800, nil", got, err)
+	}
+}
+
+func TestSessionDisplayName(t *testing.T) {
+	user := localUserName()
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{target}", "tunatap-10.0.0.1-6443"},
+		{"{cluster}-{target}", "tunatap-prod-10.0.0.1-6443"},
+		{"tunatap-{user}-{target}", "tunatap-" + user + "-10.0.0.1-6443"},
+		{"audit-{user}", "tunatap-audit-" + user},
+	}
+
+	for _, tt := range tests {
+		if got := sessionDisplayName(tt.template, "prod", "10.0.0.1-6443"); got != tt.want {
+			t.Errorf("sessionDisplayName(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	if got := sessionDisplayName(strings.Repeat("x", 300), "prod", "t"); len(got) != maxDisplayNameLength {
+		t.Errorf("sessionDisplayName() length = %d, want %d", len(got), maxDisplayNameLength)
+	}
+}
+
+func TestKeyRotator(t *testing.T) {
+	hours := 4
+	cfg := &config.Config{EphemeralKeyAlgorithm: "rsa-4096", EphemeralKeyRotationHours: &hours}
+
+	rotator, err := keyRotator(cfg)
+	if err != nil {
+		t.Fatalf("keyRotator() error = %v", err)
+	}
+	if rotator.Algorithm() != "rsa-4096" || rotator.Interval() != 4*time.Hour {
+		t.Errorf("keyRotator() = %s every %s, want rsa-4096 every 4h", rotator.Algorithm(), rotator.Interval())
+	}
+	if again, _ := keyRotator(&config.Config{EphemeralKeyAlgorithm: "rsa-4096", EphemeralKeyRotationHours: &hours}); again != rotator {
+		t.Error("keyRotator() returned a different rotator for the same policy")
+	}
+
+	if _, err := keyRotator(&config.Config{EphemeralKeyAlgorithm: "dsa"}); err == nil {
+		t.Error("keyRotator() error = nil, want an error for an unknown algorithm")
+	}
+}
+
+func TestSessionWaitProgress(t *testing.T) {
+	p := &sessionWaitProgress{sessionID: "ocid1.bastionsession.oc1..test"}
+
+	steps := []struct {
+		state      bastion.SessionLifecycleStateEnum
+		elapsed    time.Duration
+		wantReport bool
+	}{
+		{bastion.SessionLifecycleStateCreating, 0, true},
+		{bastion.SessionLifecycleStateCreating, 3 * time.Second, false},
+		{bastion.SessionLifecycleStateCreating, 30 * time.Second, true},
+		{bastion.SessionLifecycleStateCreating, 33 * time.Second, false},
+		{bastion.SessionLifecycleStateActive, 36 * time.Second, true},
+	}
+
+	for _, step := range steps {
+		if got := p.report(step.state, step.elapsed); (got != "") != step.wantReport {
+			t.Errorf("report(%s, %s) = %q, want report %v", step.state, step.elapsed, got, step.wantReport)
+		}
+	}
+}
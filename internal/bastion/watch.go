@@ -0,0 +1,267 @@
+package bastion
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/events"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// EndpointChangeAction is what a watched tunnel does when its cluster's
+// private endpoint moves.
+type EndpointChangeAction string
+
+const (
+	// EndpointChangeWarn logs a warning and keeps the tunnel as it is.
+	EndpointChangeWarn EndpointChangeAction = "warn"
+
+	// EndpointChangeReconnect tunnels to the new endpoint straight away,
+	// over a new bastion session.
+	EndpointChangeReconnect EndpointChangeAction = "reconnect"
+)
+
+// ParseEndpointChangeAction parses an endpoint change action name. An empty
+// name means warn.
+func ParseEndpointChangeAction(s string) (EndpointChangeAction, error) {
+	switch EndpointChangeAction(s) {
+	case "", EndpointChangeWarn:
+		return EndpointChangeWarn, nil
+	case EndpointChangeReconnect:
+		return EndpointChangeReconnect, nil
+	default:
+		return "", fmt.Errorf("unknown endpoint change action %q (expected %q or %q)", s, EndpointChangeWarn, EndpointChangeReconnect)
+	}
+}
+
+// defaultPrivateEndpointPort is the Kubernetes API port of OKE private
+// endpoints given without one.
+const defaultPrivateEndpointPort = 6443
+
+// EndpointState is what OCI reports about a cluster's private endpoint and
+// its bastion.
+type EndpointState struct {
+	EndpointIP   string
+	EndpointPort int
+	ClusterState string
+	// BastionState is empty for clusters without a bastion OCID, such as
+	// those behind internal bastions.
+	BastionState string
+}
+
+// Endpoint returns the private endpoint as host:port, or "" if the cluster
+// has none.
+func (s *EndpointState) Endpoint() string {
+	if s.EndpointIP == "" {
+		return ""
+	}
+	return FormatRemoteAddress(s.EndpointIP, s.EndpointPort)
+}
+
+// Changes describes how s differs from previous, one sentence each.
+func (s *EndpointState) Changes(previous *EndpointState) []string {
+	var changes []string
+	if s.Endpoint() != previous.Endpoint() {
+		changes = append(changes, fmt.Sprintf("private endpoint moved from %s to %s", orNone(previous.Endpoint()), orNone(s.Endpoint())))
+	}
+	if s.ClusterState != previous.ClusterState {
+		changes = append(changes, fmt.Sprintf("cluster is now %s (was %s)", s.ClusterState, orNone(previous.ClusterState)))
+	}
+	if s.BastionState != previous.BastionState {
+		changes = append(changes, fmt.Sprintf("bastion is now %s (was %s)", orNone(s.BastionState), orNone(previous.BastionState)))
+	}
+	return changes
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// FetchEndpointState gets the current private endpoint and lifecycle state
+// of an OKE cluster, and the state of its bastion. The OCI client must be
+// set to the cluster's region.
+func FetchEndpointState(ctx context.Context, ociClient client.OCIClientInterface, cluster *config.Cluster) (*EndpointState, error) {
+	if cluster.Ocid == nil || !utils.IsClusterOCID(*cluster.Ocid) {
+		return nil, fmt.Errorf("cluster '%s' has no OKE cluster OCID to watch", cluster.ClusterName)
+	}
+
+	c, err := ociClient.GetCluster(ctx, *cluster.Ocid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	state := &EndpointState{ClusterState: string(c.LifecycleState)}
+	if c.Endpoints != nil && c.Endpoints.PrivateEndpoint != nil {
+		state.EndpointIP, state.EndpointPort = splitPrivateEndpoint(*c.Endpoints.PrivateEndpoint)
+	}
+
+	if cluster.BastionId != nil && clusterBastionType(cluster) != "INTERNAL" {
+		b, err := ociClient.GetBastion(ctx, *cluster.BastionId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bastion: %w", err)
+		}
+		state.BastionState = string(b.LifecycleState)
+	}
+	return state, nil
+}
+
+// splitPrivateEndpoint splits an OKE private endpoint such as
+// "10.0.1.100:6443" into its IP and port.
+func splitPrivateEndpoint(endpoint string) (string, int) {
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint, defaultPrivateEndpointPort
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, defaultPrivateEndpointPort
+	}
+	return host, port
+}
+
+// WatchEndpoint fetches the cluster's endpoint state every interval until
+// ctx is cancelled, calling onChange with the previous and current state
+// whenever they differ. The first successful fetch is the baseline and is
+// returned through onChange with a nil previous state. Failed fetches are
+// logged and retried at the next interval.
+func WatchEndpoint(ctx context.Context, ociClient client.OCIClientInterface, cluster *config.Cluster, interval time.Duration, onChange func(previous, current *EndpointState)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previous *EndpointState
+	for {
+		current, err := FetchEndpointState(ctx, ociClient, cluster)
+		switch {
+		case err != nil:
+			if ctx.Err() == nil {
+				log.Warn().Err(err).Msgf("Failed to check the endpoint of cluster '%s'", cluster.ClusterName)
+			}
+		case previous == nil || len(current.Changes(previous)) > 0:
+			onChange(previous, current)
+			previous = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// endpointFollower restarts the running tunnel attempt when the watched
+// cluster's private endpoint moves, so that the next attempt tunnels to the
+// new endpoint. A nil follower never restarts anything.
+type endpointFollower struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	moved  *EndpointState
+}
+
+// attempt returns the context for a tunnel attempt, cancelled by a move,
+// or already cancelled if a move has not been taken yet.
+func (f *endpointFollower) attempt(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f == nil {
+		return ctx, func() {}
+	}
+	attemptCtx, cancel := context.WithCancel(ctx)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancel = cancel
+	if f.moved != nil {
+		cancel()
+	}
+	return attemptCtx, cancel
+}
+
+// move records that the endpoint moved to state and ends the current
+// attempt.
+func (f *endpointFollower) move(state *EndpointState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.moved = state
+	if f.cancel != nil {
+		f.cancel()
+	}
+}
+
+// takeMove returns the endpoint state moved to since the last call, if any.
+func (f *endpointFollower) takeMove() *EndpointState {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	moved := f.moved
+	f.moved = nil
+	return moved
+}
+
+// startEndpointWatch watches the cluster's private endpoint and bastion while
+// a tunnel to endpoint runs, if enabled by opts or config, and warns about
+// changes. With the reconnect action it returns a follower that ends the
+// running attempt when the endpoint the tunnel forwards to moves.
+func startEndpointWatch(ctx context.Context, ociClient client.OCIClientInterface, cfg *config.Config, cluster *config.Cluster, endpoint *config.ClusterEndpoint, configured EndpointChangeAction, opts *TunnelOptions) *endpointFollower {
+	interval := opts.EndpointWatchInterval
+	if interval <= 0 {
+		interval = time.Duration(cfg.GetEndpointWatchIntervalSeconds()) * time.Second
+	}
+	if interval <= 0 {
+		return nil
+	}
+	if cluster.Ocid == nil || !utils.IsClusterOCID(*cluster.Ocid) {
+		log.Debug().Msgf("Not watching the endpoint of '%s', which is not an OKE cluster", cluster.ClusterName)
+		return nil
+	}
+
+	action := opts.EndpointChangeAction
+	if action == "" {
+		action = configured
+	}
+	var follower *endpointFollower
+	if action == EndpointChangeReconnect {
+		follower = &endpointFollower{}
+	}
+
+	// The endpoint is updated between attempts, so the watcher keeps its
+	// own copy of the IP the tunnel forwards to
+	var target string
+	if endpoint.Fqdn == "" {
+		target = endpoint.Ip
+	}
+
+	log.Info().Msgf("Watching the endpoint of cluster '%s' every %s", cluster.ClusterName, interval)
+	go WatchEndpoint(ctx, ociClient, cluster, interval, func(previous, current *EndpointState) {
+		if previous == nil {
+			log.Debug().Msgf("Cluster '%s' endpoint %s, cluster %s, bastion %s",
+				cluster.ClusterName, orNone(current.Endpoint()), current.ClusterState, orNone(current.BastionState))
+			return
+		}
+		for _, change := range current.Changes(previous) {
+			log.Warn().Msgf("Cluster '%s': %s", cluster.ClusterName, change)
+		}
+		if current.Endpoint() == previous.Endpoint() || current.EndpointIP == "" || previous.EndpointIP != target {
+			return
+		}
+
+		opts.Events.Emit(events.Event{Type: events.EndpointChanged, Cluster: cluster.ClusterName, Endpoint: current.Endpoint()})
+		if follower == nil {
+			log.Warn().Msgf("The tunnel still forwards to %s; reconnect, or set endpoint_change_action to %q, to use the new endpoint",
+				previous.Endpoint(), EndpointChangeReconnect)
+			return
+		}
+		target = current.EndpointIP
+		follower.move(current)
+	})
+	return follower
+}
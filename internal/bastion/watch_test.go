@@ -0,0 +1,155 @@
+package bastion
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	ocibastion "github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+func TestParseEndpointChangeAction(t *testing.T) {
+	for in, want := range map[string]EndpointChangeAction{"": EndpointChangeWarn, "warn": EndpointChangeWarn, "reconnect": EndpointChangeReconnect} {
+		if got, err := ParseEndpointChangeAction(in); err != nil || got != want {
+			t.Errorf("ParseEndpointChangeAction(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseEndpointChangeAction("restart"); err == nil {
+		t.Error("ParseEndpointChangeAction(restart) should fail")
+	}
+}
+
+func TestEndpointStateChanges(t *testing.T) {
+	previous := &EndpointState{EndpointIP: "10.0.1.10", EndpointPort: 6443, ClusterState: "ACTIVE", BastionState: "ACTIVE"}
+
+	if changes := (&EndpointState{EndpointIP: "10.0.1.10", EndpointPort: 6443, ClusterState: "ACTIVE", BastionState: "ACTIVE"}).Changes(previous); len(changes) != 0 {
+		t.Errorf("Changes() = %v, want none", changes)
+	}
+
+	current := &EndpointState{EndpointIP: "10.0.1.20", EndpointPort: 6443, ClusterState: "UPDATING", BastionState: "DELETED"}
+	want := []string{
+		"private endpoint moved from 10.0.1.10:6443 to 10.0.1.20:6443",
+		"cluster is now UPDATING (was ACTIVE)",
+		"bastion is now DELETED (was ACTIVE)",
+	}
+	changes := current.Changes(previous)
+	if len(changes) != len(want) {
+		t.Fatalf("Changes() = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Changes()[%d] = %q, want %q", i, changes[i], want[i])
+		}
+	}
+}
+
+// newWatchTestClient returns a mock with an active cluster whose private
+// endpoint is at ip, and an active bastion.
+func newWatchTestClient(ip string) (*client.MockOCIClient, *config.Cluster) {
+	mock := client.NewMockOCIClient()
+	clusterID, bastionID := "ocid1.cluster.oc1.iad.watch", "ocid1.bastion.oc1.iad.watch"
+	setWatchTestEndpoint(mock, clusterID, ip)
+	mock.AddBastion(&ocibastion.Bastion{Id: &bastionID, LifecycleState: ocibastion.BastionLifecycleStateActive})
+
+	return mock, &config.Cluster{ClusterName: "prod", Ocid: &clusterID, BastionId: &bastionID}
+}
+
+func setWatchTestEndpoint(mock *client.MockOCIClient, clusterID, ip string) {
+	mock.AddCluster(&containerengine.Cluster{
+		Id:             &clusterID,
+		LifecycleState: containerengine.ClusterLifecycleStateActive,
+		Endpoints:      &containerengine.ClusterEndpoints{PrivateEndpoint: utils.StringPtr(ip + ":6443")},
+	})
+}
+
+func TestFetchEndpointState(t *testing.T) {
+	mock, cluster := newWatchTestClient("10.0.1.10")
+
+	state, err := FetchEndpointState(context.Background(), mock, cluster)
+	if err != nil {
+		t.Fatalf("FetchEndpointState() error = %v", err)
+	}
+	want := EndpointState{EndpointIP: "10.0.1.10", EndpointPort: 6443, ClusterState: "ACTIVE", BastionState: "ACTIVE"}
+	if *state != want {
+		t.Errorf("FetchEndpointState() = %+v, want %+v", *state, want)
+	}
+
+	if _, err := FetchEndpointState(context.Background(), mock, &config.Cluster{ClusterName: "db", Ocid: utils.StringPtr("ocid1.autonomousdatabase.oc1.iad.a")}); err == nil {
+		t.Error("FetchEndpointState() should fail for a resource that is not an OKE cluster")
+	}
+}
+
+func TestWatchEndpoint(t *testing.T) {
+	mock, cluster := newWatchTestClient("10.0.1.10")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var seen []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		WatchEndpoint(ctx, mock, cluster, 5*time.Millisecond, func(previous, current *EndpointState) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, current.Endpoint())
+			if previous == nil {
+				// The endpoint moves after the baseline
+				setWatchTestEndpoint(mock, *cluster.Ocid, "10.0.1.20")
+				return
+			}
+			cancel()
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchEndpoint() did not see the endpoint move")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 || seen[0] != "10.0.1.10:6443" || seen[1] != "10.0.1.20:6443" {
+		t.Errorf("onChange saw %v, want the old and new endpoint", seen)
+	}
+}
+
+func TestEndpointFollower(t *testing.T) {
+	var nilFollower *endpointFollower
+	ctx, cancel := nilFollower.attempt(context.Background())
+	cancel()
+	if ctx.Err() != nil || nilFollower.takeMove() != nil {
+		t.Error("a nil follower should never end an attempt")
+	}
+
+	f := &endpointFollower{}
+	ctx, cancel = f.attempt(context.Background())
+	defer cancel()
+	moved := &EndpointState{EndpointIP: "10.0.1.20", EndpointPort: 6443}
+	f.move(moved)
+	if ctx.Err() == nil {
+		t.Error("a move should end the running attempt")
+	}
+
+	// A move not yet taken ends the next attempt straight away
+	f.move(moved)
+	next, cancelNext := f.attempt(context.Background())
+	defer cancelNext()
+	if next.Err() == nil {
+		t.Error("an attempt should end at once while a move is pending")
+	}
+
+	if got := f.takeMove(); got != moved {
+		t.Errorf("takeMove() = %+v, want %+v", got, moved)
+	}
+	if f.takeMove() != nil {
+		t.Error("takeMove() should return a move once")
+	}
+}
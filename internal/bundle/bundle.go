@@ -0,0 +1,122 @@
+// Package bundle writes diagnostic bundles: gzipped tarballs of text files
+// with secrets redacted, for attaching to support tickets.
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Redacted replaces every secret removed from a bundle.
+const Redacted = "[REDACTED]"
+
+// secretWords are the key names whose values are redacted.
+const secretWords = `password|passwd|passphrase|pass_phrase|secret|token|fingerprint|api_key|apikey|credential|private_key`
+
+var redactions = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	// PEM private keys, whole
+	{regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`), Redacted},
+	// JSON "key": "value"
+	{regexp.MustCompile(`(?i)("[\w.-]*(?:` + secretWords + `)[\w.-]*"\s*:\s*)"(?:[^"\\]|\\.)*"`), `$1"` + Redacted + `"`},
+	// YAML, INI and environment key: value and key=value at the start of a line
+	{regexp.MustCompile(`(?im)^(\s*(?:-\s*)?[\w.-]*(?:` + secretWords + `)[\w.-]*\s*[:=][ \t]*)\S.*$`), `${1}` + Redacted},
+	// Authorization headers
+	{regexp.MustCompile(`(?i)(authorization:\s*\w+\s+)\S+`), `${1}` + Redacted},
+	// Credentials in URLs
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^@\s/]+@`), `${1}` + Redacted + `@`},
+}
+
+// Redact returns data with private keys, credentials in URLs and the values
+// of keys naming secrets replaced by Redacted.
+func Redact(data []byte) []byte {
+	for _, r := range redactions {
+		data = r.pattern.ReplaceAll(data, []byte(r.replace))
+	}
+	return data
+}
+
+// Writer writes a bundle, redacting every file added to it.
+type Writer struct {
+	gz  *gzip.Writer
+	tar *tar.Writer
+	dir string
+	now time.Time
+}
+
+// NewWriter returns a Writer writing a bundle to w, with its files under
+// dir in the archive.
+func NewWriter(w io.Writer, dir string) *Writer {
+	gz := gzip.NewWriter(w)
+	return &Writer{gz: gz, tar: tar.NewWriter(gz), dir: dir, now: time.Now()}
+}
+
+// Add adds a file to the bundle after redacting it.
+func (w *Writer) Add(name string, data []byte) error {
+	data = Redact(data)
+	hdr := &tar.Header{
+		Name:    w.dir + "/" + name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: w.now,
+	}
+	if err := w.tar.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := w.tar.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close finishes the bundle. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// TailFile returns the last n lines of the file at path.
+func TailFile(path string, n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Keep a ring of the last n lines
+	lines := make([][]byte, 0, n)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		if len(lines) == n {
+			lines = append(lines[1:], line)
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var b bytes.Buffer
+	for _, line := range lines {
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,175 @@
+// Package cachecrypt encrypts cache files at rest with a key kept in the OS
+// keychain.
+//
+// Discovery and catalog caches hold OCIDs, private endpoint IPs and the
+// compartment structure of a tenancy. With cache_encryption set they are
+// written sealed with AES-256-GCM under a random key created on first use
+// and stored in the OS keychain (see package keychain). Encrypted files are
+// recognised by their header and read back whatever the setting, so turning
+// it off rewrites them as plain text on the next save.
+package cachecrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/scotttball/tunatap/internal/keychain"
+)
+
+const (
+	// keychainService and keychainAccount name the cache key in the keychain.
+	keychainService = "tunatap"
+	keychainAccount = "cache-key"
+
+	keySize = 32
+)
+
+// header starts every encrypted file, followed by the GCM nonce and the
+// sealed content.
+var header = []byte("TUNATAP-ENC1\n")
+
+// ErrKeyNotFound is returned by a Keychain that holds no cache key.
+var ErrKeyNotFound = keychain.ErrNotFound
+
+// Keychain stores the cache key.
+type Keychain = keychain.Keychain
+
+var (
+	mu    sync.Mutex
+	store = keychain.System()
+	key   []byte
+)
+
+// SetKeychain replaces the keychain the cache key is kept in, or restores
+// the OS keychain if k is nil, and forgets the key already read. It is meant
+// for tests.
+func SetKeychain(k Keychain) {
+	mu.Lock()
+	defer mu.Unlock()
+	if k == nil {
+		k = keychain.System()
+	}
+	store = k
+	key = nil
+}
+
+// cacheKey returns the cache key, reading it from the keychain the first
+// time, and creating it there if create is set and there is none.
+func cacheKey(create bool) ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+
+	secret, err := store.Get(keychainService, keychainAccount)
+	switch {
+	case errors.Is(err, ErrKeyNotFound) && create:
+		k := make([]byte, keySize)
+		if _, err := rand.Read(k); err != nil {
+			return nil, fmt.Errorf("failed to generate cache key: %w", err)
+		}
+		if err := store.Set(keychainService, keychainAccount, base64.StdEncoding.EncodeToString(k)); err != nil {
+			return nil, fmt.Errorf("failed to store cache key in keychain: %w", err)
+		}
+		key = k
+		return key, nil
+	case err != nil:
+		return nil, err
+	}
+
+	k, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil || len(k) != keySize {
+		return nil, fmt.Errorf("cache key in keychain is not a %d-byte base64 key", keySize)
+	}
+	key = k
+	return key, nil
+}
+
+// IsEncrypted reports whether data was written encrypted.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, header)
+}
+
+// Encrypt seals data with the cache key, creating the key if needed.
+func Encrypt(data []byte) ([]byte, error) {
+	k, err := cacheKey(true)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := append(append([]byte{}, header...), nonce...)
+	return gcm.Seal(out, nonce, data, header), nil
+}
+
+// Decrypt opens data sealed by Encrypt. Data that is not encrypted is
+// returned as it is.
+func Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	k, err := cacheKey(false)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := data[len(header):]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted cache is truncated")
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt cache, which may have been written with another key: %w", err)
+	}
+	return plain, nil
+}
+
+func newGCM(k []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadFile reads a cache file, decrypting it if it was written encrypted.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data)
+}
+
+// WriteFile writes a cache file, encrypted if encrypt is set. It never falls
+// back to writing plain text when encryption fails.
+func WriteFile(path string, data []byte, perm os.FileMode, encrypt bool) error {
+	if encrypt {
+		sealed, err := Encrypt(data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+		data = sealed
+	}
+	return os.WriteFile(path, data, perm)
+}
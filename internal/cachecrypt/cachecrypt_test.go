@@ -0,0 +1,123 @@
+package cachecrypt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// memoryKeychain is a keychain held in memory.
+type memoryKeychain map[string]string
+
+func (k memoryKeychain) Get(service, account string) (string, error) {
+	secret, ok := k[service+"/"+account]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return secret, nil
+}
+
+func (k memoryKeychain) Set(service, account, secret string) error {
+	k[service+"/"+account] = secret
+	return nil
+}
+
+func useMemoryKeychain(t *testing.T) memoryKeychain {
+	t.Helper()
+	k := memoryKeychain{}
+	SetKeychain(k)
+	t.Cleanup(func() { SetKeychain(nil) })
+	return k
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k := useMemoryKeychain(t)
+	plain := []byte(`{"clusters":{"prod":{"endpoint_ip":"10.0.1.10"}}}`)
+
+	sealed, err := Encrypt(plain)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !IsEncrypted(sealed) || bytes.Contains(sealed, []byte("10.0.1.10")) {
+		t.Fatalf("Encrypt() = %q, want sealed content", sealed)
+	}
+	if len(k) != 1 {
+		t.Errorf("keychain = %v, want the key created on first use", k)
+	}
+
+	got, err := Decrypt(sealed)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Decrypt() = %q, %v; want %q", got, err, plain)
+	}
+
+	// Plain text passes through
+	if got, err := Decrypt(plain); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Decrypt(plain) = %q, %v; want it unchanged", got, err)
+	}
+
+	// Tampering is detected
+	sealed[len(sealed)-1] ^= 0xff
+	if _, err := Decrypt(sealed); err == nil {
+		t.Error("Decrypt() should fail for tampered content")
+	}
+}
+
+func TestDecryptWithoutKey(t *testing.T) {
+	useMemoryKeychain(t)
+	sealed, err := Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	// Another machine, or a keychain that lost the key
+	useMemoryKeychain(t)
+	if _, err := Decrypt(sealed); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Decrypt() error = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestWriteFileReadFile(t *testing.T) {
+	useMemoryKeychain(t)
+	path := filepath.Join(t.TempDir(), "cache.json")
+	plain := []byte(`{"ocid":"ocid1.cluster.oc1..a"}`)
+
+	if err := WriteFile(path, plain, 0600, true); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	onDisk, _ := os.ReadFile(path)
+	if !IsEncrypted(onDisk) {
+		t.Errorf("file = %q, want it encrypted", onDisk)
+	}
+	if got, err := ReadFile(path); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("ReadFile() = %q, %v; want %q", got, err, plain)
+	}
+
+	if err := WriteFile(path, plain, 0600, false); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if got, err := ReadFile(path); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("ReadFile() = %q, %v; want %q", got, err, plain)
+	}
+}
+
+type failingKeychain struct{}
+
+func (failingKeychain) Get(service, account string) (string, error) { return "", ErrKeyNotFound }
+func (failingKeychain) Set(service, account, secret string) error {
+	return errors.New("keychain locked")
+}
+
+func TestWriteFileKeychainUnavailable(t *testing.T) {
+	SetKeychain(failingKeychain{})
+	t.Cleanup(func() { SetKeychain(nil) })
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+	if err := WriteFile(path, []byte("secret"), 0600, true); err == nil {
+		t.Fatal("WriteFile() should fail when the key cannot be stored")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("WriteFile() should not fall back to plain text")
+	}
+}
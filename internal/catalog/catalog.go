@@ -12,8 +12,10 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/cachecrypt"
 	"github.com/scotttball/tunatap/internal/client"
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/secrets"
 	"gopkg.in/yaml.v3"
 )
 
@@ -41,8 +43,10 @@ type CatalogManager struct {
 	sources    []*config.CatalogSource
 	cacheDir   string
 	cacheTTL   time.Duration
+	encrypt    bool
 	ociClient  *client.OCIClient
 	httpClient *http.Client
+	secrets    *secrets.Resolver
 }
 
 // NewCatalogManager creates a new catalog manager.
@@ -62,11 +66,23 @@ func (m *CatalogManager) SetOCIClient(client *client.OCIClient) {
 	m.ociClient = client
 }
 
+// SetSecretResolver sets the resolver of secret references in source
+// auth tokens. Without one, tokens are used as they are.
+func (m *CatalogManager) SetSecretResolver(resolver *secrets.Resolver) {
+	m.secrets = resolver
+}
+
 // SetCacheTTL sets the cache time-to-live.
 func (m *CatalogManager) SetCacheTTL(ttl time.Duration) {
 	m.cacheTTL = ttl
 }
 
+// SetEncrypted sets whether cached catalogs are written encrypted with the
+// key in the OS keychain. Encrypted caches are read either way.
+func (m *CatalogManager) SetEncrypted(encrypt bool) {
+	m.encrypt = encrypt
+}
+
 // FetchAll fetches all enabled catalog sources.
 func (m *CatalogManager) FetchAll(ctx context.Context) ([]*SharedCatalog, error) {
 	catalogs := make([]*SharedCatalog, 0)
@@ -90,6 +106,21 @@ func (m *CatalogManager) FetchAll(ctx context.Context) ([]*SharedCatalog, error)
 	return catalogs, nil
 }
 
+// LoadCached returns the catalogs of the enabled sources that are in the
+// cache and not expired, without fetching any.
+func (m *CatalogManager) LoadCached() []*SharedCatalog {
+	var catalogs []*SharedCatalog
+	for _, source := range m.sources {
+		if !source.Enabled {
+			continue
+		}
+		if catalog, err := m.loadFromCache(source); err == nil {
+			catalogs = append(catalogs, catalog)
+		}
+	}
+	return catalogs
+}
+
 // FetchSource fetches a single catalog source.
 func (m *CatalogManager) FetchSource(ctx context.Context, source *config.CatalogSource) (*SharedCatalog, error) {
 	// Check cache first
@@ -107,7 +138,7 @@ func (m *CatalogManager) FetchSource(ctx context.Context, source *config.Catalog
 
 	switch sourceType {
 	case "https", "http":
-		data, err = m.fetchHTTPS(ctx, source.URL)
+		data, err = m.fetchHTTPS(ctx, source)
 	case "oci":
 		data, err = m.fetchOCI(ctx, source)
 	case "file":
@@ -160,14 +191,26 @@ func (m *CatalogManager) detectSourceType(source *config.CatalogSource) string {
 	}
 }
 
-// fetchHTTPS fetches a catalog from an HTTPS URL.
-func (m *CatalogManager) fetchHTTPS(ctx context.Context, urlStr string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+// fetchHTTPS fetches a catalog from an HTTPS URL, with the source's auth
+// token if it has one.
+func (m *CatalogManager) fetchHTTPS(ctx context.Context, source *config.CatalogSource) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", source.URL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Accept", "application/yaml, application/x-yaml, text/yaml, text/plain")
+	if source.AuthToken != "" {
+		token := source.AuthToken
+		if m.secrets != nil {
+			if token, err = m.secrets.Resolve(ctx, token); err != nil {
+				return nil, fmt.Errorf("auth_token: %w", err)
+			}
+		} else if secrets.IsReference(token) {
+			return nil, fmt.Errorf("auth_token: cannot resolve %s here", token)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
@@ -190,9 +233,7 @@ func (m *CatalogManager) fetchOCI(ctx context.Context, source *config.CatalogSou
 
 	// Get namespace
 	namespace := ""
-	if source.OCIRegion != "" {
-		m.ociClient.SetRegion(source.OCIRegion)
-	}
+	ociClient := m.ociClient.WithRegion(source.OCIRegion)
 
 	// Parse OCI URL if provided
 	bucket := source.OCIBucket
@@ -212,7 +253,7 @@ func (m *CatalogManager) fetchOCI(ctx context.Context, source *config.CatalogSou
 		return nil, fmt.Errorf("OCI bucket and object are required")
 	}
 
-	return m.ociClient.GetObject(ctx, namespace, bucket, object)
+	return ociClient.GetObject(ctx, namespace, bucket, object)
 }
 
 // fetchFile fetches a catalog from a local file.
@@ -246,7 +287,7 @@ func (m *CatalogManager) loadFromCache(source *config.CatalogSource) (*SharedCat
 		return nil, fmt.Errorf("cache expired")
 	}
 
-	data, err := os.ReadFile(cachePath)
+	data, err := cachecrypt.ReadFile(cachePath)
 	if err != nil {
 		return nil, err
 	}
@@ -272,7 +313,7 @@ func (m *CatalogManager) saveToCache(source *config.CatalogSource, data []byte)
 	}
 
 	cachePath := m.cachePath(source)
-	return os.WriteFile(cachePath, data, 0o600)
+	return cachecrypt.WriteFile(cachePath, data, 0o600, m.encrypt)
 }
 
 // cachePath returns the cache file path for a source.
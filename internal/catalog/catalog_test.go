@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/secrets"
 )
 
 func TestNewCatalogManager(t *testing.T) {
@@ -46,7 +47,7 @@ clusters:
 	manager := NewCatalogManager(nil, "")
 
 	ctx := context.Background()
-	data, err := manager.fetchHTTPS(ctx, server.URL)
+	data, err := manager.fetchHTTPS(ctx, &config.CatalogSource{URL: server.URL})
 	if err != nil {
 		t.Fatalf("fetchHTTPS error: %v", err)
 	}
@@ -56,6 +57,43 @@ clusters:
 	}
 }
 
+func TestFetchHTTPSAuthToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("name: team\n"))
+	}))
+	defer server.Close()
+
+	manager := NewCatalogManager(nil, "")
+	resolver := secrets.NewResolver(nil)
+	resolver.Keychain = tokenKeychain{}
+	manager.SetSecretResolver(resolver)
+	ctx := context.Background()
+
+	for _, token := range []string{"t0ken", "keychain:tunatap/catalog-token"} {
+		if _, err := manager.fetchHTTPS(ctx, &config.CatalogSource{URL: server.URL, AuthToken: token}); err != nil {
+			t.Errorf("fetchHTTPS() with auth_token %q error = %v", token, err)
+		}
+	}
+	if _, err := manager.fetchHTTPS(ctx, &config.CatalogSource{URL: server.URL}); err == nil {
+		t.Error("fetchHTTPS() without the token = nil error, want unauthorized")
+	}
+}
+
+// tokenKeychain holds the catalog token.
+type tokenKeychain struct{}
+
+func (tokenKeychain) Get(service, account string) (string, error) {
+	return "t0ken", nil
+}
+
+func (tokenKeychain) Set(service, account, secret string) error {
+	return nil
+}
+
 func TestFetchFile(t *testing.T) {
 	// Create temp file
 	tempDir := t.TempDir()
@@ -173,6 +211,26 @@ clusters: []
 	}
 }
 
+func TestLoadCached(t *testing.T) {
+	tempDir := t.TempDir()
+	cached := &config.CatalogSource{Name: "cached", Enabled: true}
+	disabled := &config.CatalogSource{Name: "disabled"}
+	missing := &config.CatalogSource{Name: "missing", Enabled: true}
+	manager := NewCatalogManager([]*config.CatalogSource{cached, disabled, missing}, tempDir)
+
+	for _, source := range []*config.CatalogSource{cached, disabled} {
+		data := []byte("version: \"1.0\"\nname: \"" + source.Name + "\"\nclusters: []\n")
+		if err := manager.saveToCache(source, data); err != nil {
+			t.Fatalf("saveToCache error: %v", err)
+		}
+	}
+
+	catalogs := manager.LoadCached()
+	if len(catalogs) != 1 || catalogs[0].Name != "cached" {
+		t.Errorf("LoadCached() = %+v, want only the cached enabled catalog", catalogs)
+	}
+}
+
 func TestCacheExpiry(t *testing.T) {
 	tempDir := t.TempDir()
 	manager := NewCatalogManager(nil, tempDir)
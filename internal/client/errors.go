@@ -274,6 +274,29 @@ func IsNotFoundError(err error) bool {
 	return ociErr.Type == ErrorTypeNotFound
 }
 
+// IsPreconditionFailedError returns true if the OCI service refused a
+// conditional request, such as a PutObject with an ETag, because the
+// resource changed (412).
+func IsPreconditionFailedError(err error) bool {
+	var serviceErr common.ServiceError
+	return errors.As(err, &serviceErr) && serviceErr.GetHTTPStatusCode() == 412
+}
+
+// IsPermanentError returns true if the OCI service rejected a request in a
+// way that retrying cannot fix: as invalid (400), unauthenticated (401),
+// unauthorized (403) or for a missing resource (404).
+func IsPermanentError(err error) bool {
+	var serviceErr common.ServiceError
+	if !errors.As(err, &serviceErr) {
+		return false
+	}
+	switch serviceErr.GetHTTPStatusCode() {
+	case 400, 401, 403, 404:
+		return true
+	}
+	return false
+}
+
 // WrapOCIError wraps an OCI error with classification and context.
 // Use this to provide better error messages to users.
 func WrapOCIError(err error, operation string) error {
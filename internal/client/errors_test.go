@@ -2,6 +2,7 @@ package client
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -226,6 +227,37 @@ func TestIsNotFoundError(t *testing.T) {
 	}
 }
 
+func TestIsPermanentError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&mockServiceError{statusCode: http.StatusBadRequest}, true},
+		{&mockServiceError{statusCode: http.StatusUnauthorized}, true},
+		{fmt.Errorf("failed to create session: %w", &mockServiceError{statusCode: http.StatusForbidden}), true},
+		{&mockServiceError{statusCode: http.StatusNotFound, code: "NotAuthorizedOrNotFound"}, true},
+		{&mockServiceError{statusCode: http.StatusConflict}, false},
+		{&mockServiceError{statusCode: http.StatusTooManyRequests}, false},
+		{&mockServiceError{statusCode: http.StatusInternalServerError}, false},
+		{errors.New("403 forbidden"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPermanentError(tt.err); got != tt.want {
+			t.Errorf("IsPermanentError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsPreconditionFailedError(t *testing.T) {
+	if !IsPreconditionFailedError(fmt.Errorf("failed to put object: %w", &mockServiceError{statusCode: http.StatusPreconditionFailed})) {
+		t.Error("IsPreconditionFailedError(412) = false")
+	}
+	if IsPreconditionFailedError(&mockServiceError{statusCode: http.StatusConflict}) {
+		t.Error("IsPreconditionFailedError(409) = true")
+	}
+}
+
 func TestOCIError_Error(t *testing.T) {
 	ociErr := &OCIError{
 		Type:       ErrorTypeNotAuthorized,
@@ -5,7 +5,12 @@ import (
 
 	"github.com/oracle/oci-go-sdk/v65/bastion"
 	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/oracle/oci-go-sdk/v65/database"
 	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/mysql"
+	"github.com/oracle/oci-go-sdk/v65/psql"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
 )
 
 // OCIClientInterface defines the interface for OCI operations.
@@ -14,36 +19,72 @@ import (
 //go:generate mockgen -destination=mock_client.go -package=client github.com/scotttball/tunatap/internal/client OCIClientInterface
 type OCIClientInterface interface {
 	// Region management
-	SetRegion(region string)
+	RegionClient(region string) OCIClientInterface
+	GetConfiguredRegion() string
 	GetAuthType() AuthType
 
+	// Instrumentation
+	SetCallObserver(observer CallObserver)
+
 	// Object Storage operations
 	GetNamespace(ctx context.Context, tenancyOcid string) (string, error)
 	GetObject(ctx context.Context, namespace, bucket, object string) ([]byte, error)
 
 	// Identity operations
 	GetCompartmentIDByPath(ctx context.Context, tenancyOcid, path string) (*string, error)
+	GetCompartment(ctx context.Context, compartmentID string) (*identity.Compartment, error)
 
 	// Container Engine operations
 	FetchClusterID(ctx context.Context, compartmentID, clusterName string) (*string, error)
 	GetCluster(ctx context.Context, clusterID string) (*containerengine.Cluster, error)
+	GetClusterKubeconfig(ctx context.Context, clusterID string) ([]byte, error)
+	ListNodePools(ctx context.Context, compartmentID, clusterID string) ([]containerengine.NodePoolSummary, error)
+	GetNodePool(ctx context.Context, nodePoolID string) (*containerengine.NodePool, error)
+
+	// Compute operations
+	ListInstancesByName(ctx context.Context, compartmentID, name string) ([]core.Instance, error)
+	ListInstances(ctx context.Context, compartmentID string) ([]core.Instance, error)
+	GetPrimaryVnic(ctx context.Context, compartmentID, instanceID string) (*core.Vnic, error)
+
+	// Network operations
+	GetPrivateIP(ctx context.Context, privateIPID string) (*core.PrivateIp, error)
+
+	// Database operations
+	ListAutonomousDatabases(ctx context.Context, compartmentID string) ([]database.AutonomousDatabaseSummary, error)
+	GetAutonomousDatabase(ctx context.Context, databaseID string) (*database.AutonomousDatabase, error)
+	ListDbSystems(ctx context.Context, compartmentID string) ([]database.DbSystemSummary, error)
+	GetDbSystem(ctx context.Context, dbSystemID string) (*database.DbSystem, error)
+	ListMySQLDbSystems(ctx context.Context, compartmentID string) ([]mysql.DbSystemSummary, error)
+	GetMySQLDbSystem(ctx context.Context, dbSystemID string) (*mysql.DbSystem, error)
+	ListPostgresDbSystems(ctx context.Context, compartmentID string) ([]psql.DbSystemSummary, error)
+	GetPostgresDbSystem(ctx context.Context, dbSystemID string) (*psql.DbSystem, error)
+
+	// Vault operations
+	GetSecretContent(ctx context.Context, secretID string) (string, error)
+
+	// Logging operations
+	PutLogEntries(ctx context.Context, logID, source, logType string, entries []LogEntry) error
 
 	// Bastion operations
 	ListBastions(ctx context.Context, compartmentID string) ([]bastion.BastionSummary, error)
 	GetBastion(ctx context.Context, bastionID string) (*bastion.Bastion, error)
+	CreateBastion(ctx context.Context, details bastion.CreateBastionDetails) (*bastion.Bastion, error)
+	UpdateBastion(ctx context.Context, bastionID string, details bastion.UpdateBastionDetails) error
+	DeleteBastion(ctx context.Context, bastionID string) error
 
 	// Session operations
 	CreateSession(ctx context.Context, bastionID string, sessionDetails bastion.CreateSessionDetails) (*bastion.Session, error)
 	GetSession(ctx context.Context, bastionID, sessionID string) (*bastion.Session, error)
 	ListSessions(ctx context.Context, bastionID string) ([]bastion.SessionSummary, error)
 	DeleteSession(ctx context.Context, bastionID, sessionID string) error
-	WaitForSessionActive(ctx context.Context, bastionID, sessionID string) (*bastion.Session, error)
+	WaitForSessionActive(ctx context.Context, bastionID, sessionID string, opts *SessionWaitOptions) (*bastion.Session, error)
 
 	// Discovery operations (zero-touch support)
 	GetTenancyOCID() (string, error)
 	ListCompartments(ctx context.Context, parentID string) ([]identity.Compartment, error)
-	ListClustersInCompartment(ctx context.Context, compartmentID string) ([]containerengine.ClusterSummary, error)
+	ListClustersInCompartment(ctx context.Context, compartmentID string, states []containerengine.ClusterLifecycleStateEnum) ([]containerengine.ClusterSummary, error)
 	GetSubscribedRegions(ctx context.Context, tenancyID string) ([]identity.RegionSubscription, error)
+	SearchResources(ctx context.Context, query string) ([]resourcesearch.ResourceSummary, error)
 }
 
 // Ensure OCIClient implements OCIClientInterface
@@ -8,7 +8,12 @@ import (
 
 	"github.com/oracle/oci-go-sdk/v65/bastion"
 	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/oracle/oci-go-sdk/v65/database"
 	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/mysql"
+	"github.com/oracle/oci-go-sdk/v65/psql"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
 )
 
 // MockOCIClient is a mock implementation of OCIClientInterface for testing.
@@ -16,20 +21,33 @@ type MockOCIClient struct {
 	mu sync.RWMutex
 
 	// Configuration
-	Region      string
-	AuthType    AuthType
-	TenancyOCID string
+	Region           string
+	ConfiguredRegion string
+	AuthType         AuthType
+	TenancyOCID      string
 
 	// Mock data stores
-	Compartments          map[string]string                           // path -> OCID
-	CompartmentsByID      map[string][]identity.Compartment           // parent OCID -> child compartments
-	Clusters              map[string]*containerengine.Cluster         // OCID -> Cluster
-	ClustersByCompartment map[string][]containerengine.ClusterSummary // compartment OCID -> clusters
-	Bastions              map[string]*bastion.Bastion                 // OCID -> Bastion
-	Sessions              map[string]*bastion.Session                 // OCID -> Session
-	Objects               map[string][]byte                           // "namespace/bucket/object" -> content
-	Namespace             string
-	SubscribedRegions     []identity.RegionSubscription
+	Compartments           map[string]string                           // path -> OCID
+	CompartmentsByID       map[string][]identity.Compartment           // parent OCID -> child compartments
+	Clusters               map[string]*containerengine.Cluster         // OCID -> Cluster
+	ClustersByCompartment  map[string][]containerengine.ClusterSummary // compartment OCID -> clusters
+	NodePools              map[string]*containerengine.NodePool        // OCID -> NodePool
+	Bastions               map[string]*bastion.Bastion                 // OCID -> Bastion
+	InstancesByCompartment map[string][]core.Instance                  // compartment OCID -> instances
+	PrimaryVnics           map[string]*core.Vnic                       // instance OCID -> primary VNIC
+	PrivateIPs             map[string]*core.PrivateIp                  // OCID -> private IP
+	AutonomousDatabases    map[string]*database.AutonomousDatabase     // OCID -> Autonomous Database
+	DbSystems              map[string]*database.DbSystem               // OCID -> DB system
+	MySQLDbSystems         map[string]*mysql.DbSystem                  // OCID -> MySQL DB system
+	PostgresDbSystems      map[string]*psql.DbSystem                   // OCID -> PostgreSQL DB system
+	Secrets                map[string]string                           // OCID -> decoded secret content
+	LogEntries             map[string][]LogEntry                       // log OCID -> entries put
+	Kubeconfigs            map[string][]byte                           // cluster OCID -> kubeconfig OCI generates
+	Sessions               map[string]*bastion.Session                 // OCID -> Session
+	Objects                map[string][]byte                           // "namespace/bucket/object" -> content
+	Namespace              string
+	SubscribedRegions      []identity.RegionSubscription
+	SearchResults          map[string][]resourcesearch.ResourceSummary // region -> resources found by any query
 
 	// Behavior configuration
 	CreateSessionDelay time.Duration
@@ -46,6 +64,8 @@ type MockOCIClient struct {
 
 	// Call tracking for assertions
 	Calls []MockCall
+
+	observer CallObserver
 }
 
 // MockCall records a method call for test assertions.
@@ -58,18 +78,30 @@ type MockCall struct {
 // NewMockOCIClient creates a new mock client with default configuration.
 func NewMockOCIClient() *MockOCIClient {
 	return &MockOCIClient{
-		AuthType:              AuthTypeConfigFile,
-		TenancyOCID:           "ocid1.tenancy.oc1..mock",
-		Compartments:          make(map[string]string),
-		CompartmentsByID:      make(map[string][]identity.Compartment),
-		Clusters:              make(map[string]*containerengine.Cluster),
-		ClustersByCompartment: make(map[string][]containerengine.ClusterSummary),
-		Bastions:              make(map[string]*bastion.Bastion),
-		Sessions:              make(map[string]*bastion.Session),
-		Objects:               make(map[string][]byte),
-		Namespace:             "test-namespace",
-		SubscribedRegions:     []identity.RegionSubscription{},
-		Calls:                 make([]MockCall, 0),
+		AuthType:               AuthTypeConfigFile,
+		TenancyOCID:            "ocid1.tenancy.oc1..mock",
+		Compartments:           make(map[string]string),
+		CompartmentsByID:       make(map[string][]identity.Compartment),
+		Clusters:               make(map[string]*containerengine.Cluster),
+		ClustersByCompartment:  make(map[string][]containerengine.ClusterSummary),
+		NodePools:              make(map[string]*containerengine.NodePool),
+		Bastions:               make(map[string]*bastion.Bastion),
+		InstancesByCompartment: make(map[string][]core.Instance),
+		PrimaryVnics:           make(map[string]*core.Vnic),
+		PrivateIPs:             make(map[string]*core.PrivateIp),
+		AutonomousDatabases:    make(map[string]*database.AutonomousDatabase),
+		DbSystems:              make(map[string]*database.DbSystem),
+		MySQLDbSystems:         make(map[string]*mysql.DbSystem),
+		PostgresDbSystems:      make(map[string]*psql.DbSystem),
+		Secrets:                make(map[string]string),
+		LogEntries:             make(map[string][]LogEntry),
+		Kubeconfigs:            make(map[string][]byte),
+		Sessions:               make(map[string]*bastion.Session),
+		Objects:                make(map[string][]byte),
+		Namespace:              "test-namespace",
+		SubscribedRegions:      []identity.RegionSubscription{},
+		SearchResults:          make(map[string][]resourcesearch.ResourceSummary),
+		Calls:                  make([]MockCall, 0),
 	}
 }
 
@@ -83,6 +115,26 @@ func (m *MockOCIClient) recordCall(method string, args ...interface{}) {
 	})
 }
 
+// observeCall reports a call to the observer, if one is set. Mock calls
+// take no time and are reported as successful.
+func (m *MockOCIClient) observeCall(ctx context.Context, method string) {
+	m.mu.RLock()
+	observer := m.observer
+	m.mu.RUnlock()
+	if observer != nil {
+		observer(ctx, APICall{Operation: method, StatusCode: 200})
+	}
+}
+
+// SetCallObserver sets the observer told about every call made with a
+// context.
+func (m *MockOCIClient) SetCallObserver(observer CallObserver) {
+	m.recordCall("SetCallObserver")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observer = observer
+}
+
 // GetCalls returns all recorded calls (thread-safe).
 func (m *MockOCIClient) GetCalls() []MockCall {
 	m.mu.RLock()
@@ -99,12 +151,31 @@ func (m *MockOCIClient) ResetCalls() {
 	m.Calls = make([]MockCall, 0)
 }
 
-// SetRegion sets the region for the mock client.
-func (m *MockOCIClient) SetRegion(region string) {
-	m.recordCall("SetRegion", region)
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.Region = region
+// RegionClient returns a mock client for region sharing the mock data and
+// recorded calls of m.
+func (m *MockOCIClient) RegionClient(region string) OCIClientInterface {
+	m.recordCall("RegionClient", region)
+	return &mockRegionClient{MockOCIClient: m, region: region}
+}
+
+// mockRegionClient is a MockOCIClient for another region than its own.
+type mockRegionClient struct {
+	*MockOCIClient
+	region string
+}
+
+// SearchResources returns the mock search results for the region of the
+// client, whatever the query.
+func (r *mockRegionClient) SearchResources(ctx context.Context, query string) ([]resourcesearch.ResourceSummary, error) {
+	return r.searchResources(ctx, query, r.region)
+}
+
+// GetConfiguredRegion returns the mock configured region.
+func (m *MockOCIClient) GetConfiguredRegion() string {
+	m.recordCall("GetConfiguredRegion")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ConfiguredRegion
 }
 
 // GetAuthType returns the authentication type.
@@ -118,6 +189,7 @@ func (m *MockOCIClient) GetAuthType() AuthType {
 // GetNamespace returns the mock namespace.
 func (m *MockOCIClient) GetNamespace(ctx context.Context, tenancyOcid string) (string, error) {
 	m.recordCall("GetNamespace", tenancyOcid)
+	m.observeCall(ctx, "GetNamespace")
 	if m.ShouldFailAuth {
 		return "", fmt.Errorf("mock auth failure")
 	}
@@ -129,6 +201,7 @@ func (m *MockOCIClient) GetNamespace(ctx context.Context, tenancyOcid string) (s
 // GetObject retrieves a mock object.
 func (m *MockOCIClient) GetObject(ctx context.Context, namespace, bucket, object string) ([]byte, error) {
 	m.recordCall("GetObject", namespace, bucket, object)
+	m.observeCall(ctx, "GetObject")
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -142,6 +215,7 @@ func (m *MockOCIClient) GetObject(ctx context.Context, namespace, bucket, object
 // GetCompartmentIDByPath returns a mock compartment OCID.
 func (m *MockOCIClient) GetCompartmentIDByPath(ctx context.Context, tenancyOcid, path string) (*string, error) {
 	m.recordCall("GetCompartmentIDByPath", tenancyOcid, path)
+	m.observeCall(ctx, "GetCompartmentIDByPath")
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -151,9 +225,34 @@ func (m *MockOCIClient) GetCompartmentIDByPath(ctx context.Context, tenancyOcid,
 	return nil, fmt.Errorf("compartment not found: %s", path)
 }
 
+// GetCompartment returns a compartment added with AddCompartmentByID, with
+// its parent set.
+func (m *MockOCIClient) GetCompartment(ctx context.Context, compartmentID string) (*identity.Compartment, error) {
+	m.recordCall("GetCompartment", compartmentID)
+	m.observeCall(ctx, "GetCompartment")
+	if m.CompartmentError != nil {
+		return nil, m.CompartmentError
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for parentID, children := range m.CompartmentsByID {
+		for _, c := range children {
+			if c.Id != nil && *c.Id == compartmentID {
+				if c.CompartmentId == nil {
+					c.CompartmentId = &parentID
+				}
+				return &c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("compartment not found: %s", compartmentID)
+}
+
 // FetchClusterID finds a cluster OCID by name.
 func (m *MockOCIClient) FetchClusterID(ctx context.Context, compartmentID, clusterName string) (*string, error) {
 	m.recordCall("FetchClusterID", compartmentID, clusterName)
+	m.observeCall(ctx, "FetchClusterID")
 	if m.ShouldFailCluster {
 		return nil, fmt.Errorf("mock cluster lookup failure")
 	}
@@ -161,9 +260,15 @@ func (m *MockOCIClient) FetchClusterID(ctx context.Context, compartmentID, clust
 	defer m.mu.RUnlock()
 
 	for ocid, cluster := range m.Clusters {
-		if cluster.Name != nil && *cluster.Name == clusterName {
-			return &ocid, nil
+		if cluster.Name == nil || *cluster.Name != clusterName {
+			continue
 		}
+		switch cluster.LifecycleState {
+		case "", containerengine.ClusterLifecycleStateActive, containerengine.ClusterLifecycleStateUpdating:
+		default:
+			continue
+		}
+		return &ocid, nil
 	}
 	return nil, fmt.Errorf("cluster not found: %s", clusterName)
 }
@@ -171,6 +276,7 @@ func (m *MockOCIClient) FetchClusterID(ctx context.Context, compartmentID, clust
 // GetCluster retrieves cluster details.
 func (m *MockOCIClient) GetCluster(ctx context.Context, clusterID string) (*containerengine.Cluster, error) {
 	m.recordCall("GetCluster", clusterID)
+	m.observeCall(ctx, "GetCluster")
 	if m.ClusterError != nil {
 		return nil, m.ClusterError
 	}
@@ -186,9 +292,74 @@ func (m *MockOCIClient) GetCluster(ctx context.Context, clusterID string) (*cont
 	return nil, fmt.Errorf("cluster not found: %s", clusterID)
 }
 
+// GetClusterKubeconfig returns the mock kubeconfig of a cluster.
+func (m *MockOCIClient) GetClusterKubeconfig(ctx context.Context, clusterID string) ([]byte, error) {
+	m.recordCall("GetClusterKubeconfig", clusterID)
+	m.observeCall(ctx, "CreateKubeconfig")
+	if m.ClusterError != nil {
+		return nil, m.ClusterError
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if data, ok := m.Kubeconfigs[clusterID]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("cluster not found: %s", clusterID)
+}
+
+// ListNodePools returns the mock node pools of a cluster.
+func (m *MockOCIClient) ListNodePools(ctx context.Context, compartmentID, clusterID string) ([]containerengine.NodePoolSummary, error) {
+	m.recordCall("ListNodePools", compartmentID, clusterID)
+	m.observeCall(ctx, "ListNodePools")
+	if m.ShouldFailCluster {
+		return nil, fmt.Errorf("mock node pool listing failure")
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var nodePools []containerengine.NodePoolSummary
+	for _, np := range m.NodePools {
+		if np.ClusterId != nil && *np.ClusterId == clusterID {
+			nodePools = append(nodePools, containerengine.NodePoolSummary{Id: np.Id, Name: np.Name, ClusterId: np.ClusterId})
+		}
+	}
+	return nodePools, nil
+}
+
+// GetNodePool returns a mock node pool.
+func (m *MockOCIClient) GetNodePool(ctx context.Context, nodePoolID string) (*containerengine.NodePool, error) {
+	m.recordCall("GetNodePool", nodePoolID)
+	m.observeCall(ctx, "GetNodePool")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if np, ok := m.NodePools[nodePoolID]; ok {
+		return np, nil
+	}
+	return nil, fmt.Errorf("node pool not found: %s", nodePoolID)
+}
+
+// ListInstancesByName lists mock instances with the given display name.
+func (m *MockOCIClient) ListInstancesByName(ctx context.Context, compartmentID, name string) ([]core.Instance, error) {
+	m.recordCall("ListInstancesByName", compartmentID, name)
+	m.observeCall(ctx, "ListInstancesByName")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var instances []core.Instance
+	for _, instance := range m.InstancesByCompartment[compartmentID] {
+		if instance.DisplayName != nil && *instance.DisplayName == name {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
 // ListBastions lists mock bastions.
 func (m *MockOCIClient) ListBastions(ctx context.Context, compartmentID string) ([]bastion.BastionSummary, error) {
 	m.recordCall("ListBastions", compartmentID)
+	m.observeCall(ctx, "ListBastions")
 	if m.BastionError != nil {
 		return nil, m.BastionError
 	}
@@ -202,6 +373,9 @@ func (m *MockOCIClient) ListBastions(ctx context.Context, compartmentID string)
 			Name:           b.Name,
 			BastionType:    b.BastionType,
 			LifecycleState: bastion.BastionLifecycleStateActive,
+			TargetVcnId:    b.TargetVcnId,
+			TargetSubnetId: b.TargetSubnetId,
+			FreeformTags:   b.FreeformTags,
 		})
 	}
 	return summaries, nil
@@ -210,6 +384,10 @@ func (m *MockOCIClient) ListBastions(ctx context.Context, compartmentID string)
 // GetBastion retrieves bastion details.
 func (m *MockOCIClient) GetBastion(ctx context.Context, bastionID string) (*bastion.Bastion, error) {
 	m.recordCall("GetBastion", bastionID)
+	m.observeCall(ctx, "GetBastion")
+	if m.BastionError != nil {
+		return nil, m.BastionError
+	}
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -219,9 +397,75 @@ func (m *MockOCIClient) GetBastion(ctx context.Context, bastionID string) (*bast
 	return nil, fmt.Errorf("bastion not found: %s", bastionID)
 }
 
+// CreateBastion creates a mock bastion, which is active immediately.
+func (m *MockOCIClient) CreateBastion(ctx context.Context, details bastion.CreateBastionDetails) (*bastion.Bastion, error) {
+	m.recordCall("CreateBastion", details)
+	m.observeCall(ctx, "CreateBastion")
+	if m.BastionError != nil {
+		return nil, m.BastionError
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bastionID := fmt.Sprintf("ocid1.bastion.oc1..mock%d", len(m.Bastions)+1)
+	b := &bastion.Bastion{
+		Id:                       &bastionID,
+		Name:                     details.Name,
+		BastionType:              details.BastionType,
+		CompartmentId:            details.CompartmentId,
+		TargetSubnetId:           details.TargetSubnetId,
+		ClientCidrBlockAllowList: details.ClientCidrBlockAllowList,
+		MaxSessionTtlInSeconds:   details.MaxSessionTtlInSeconds,
+		FreeformTags:             details.FreeformTags,
+		LifecycleState:           bastion.BastionLifecycleStateActive,
+	}
+	m.Bastions[bastionID] = b
+	return b, nil
+}
+
+// UpdateBastion updates a mock bastion's allowlist and tags.
+func (m *MockOCIClient) UpdateBastion(ctx context.Context, bastionID string, details bastion.UpdateBastionDetails) error {
+	m.recordCall("UpdateBastion", bastionID, details)
+	m.observeCall(ctx, "UpdateBastion")
+	if m.BastionError != nil {
+		return m.BastionError
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.Bastions[bastionID]
+	if !ok {
+		return fmt.Errorf("bastion not found: %s", bastionID)
+	}
+	if details.ClientCidrBlockAllowList != nil {
+		b.ClientCidrBlockAllowList = details.ClientCidrBlockAllowList
+	}
+	if details.FreeformTags != nil {
+		b.FreeformTags = details.FreeformTags
+	}
+	return nil
+}
+
+// DeleteBastion deletes a mock bastion.
+func (m *MockOCIClient) DeleteBastion(ctx context.Context, bastionID string) error {
+	m.recordCall("DeleteBastion", bastionID)
+	m.observeCall(ctx, "DeleteBastion")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.Bastions[bastionID]; !ok {
+		return fmt.Errorf("bastion not found: %s", bastionID)
+	}
+	delete(m.Bastions, bastionID)
+	return nil
+}
+
 // CreateSession creates a mock session.
 func (m *MockOCIClient) CreateSession(ctx context.Context, bastionID string, sessionDetails bastion.CreateSessionDetails) (*bastion.Session, error) {
 	m.recordCall("CreateSession", bastionID, sessionDetails)
+	m.observeCall(ctx, "CreateSession")
 	if m.ShouldFailSession {
 		return nil, fmt.Errorf("mock session creation failure")
 	}
@@ -248,6 +492,7 @@ func (m *MockOCIClient) CreateSession(ctx context.Context, bastionID string, ses
 // GetSession retrieves session details.
 func (m *MockOCIClient) GetSession(ctx context.Context, bastionID, sessionID string) (*bastion.Session, error) {
 	m.recordCall("GetSession", bastionID, sessionID)
+	m.observeCall(ctx, "GetSession")
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -260,6 +505,7 @@ func (m *MockOCIClient) GetSession(ctx context.Context, bastionID, sessionID str
 // ListSessions lists mock sessions.
 func (m *MockOCIClient) ListSessions(ctx context.Context, bastionID string) ([]bastion.SessionSummary, error) {
 	m.recordCall("ListSessions", bastionID)
+	m.observeCall(ctx, "ListSessions")
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -267,10 +513,13 @@ func (m *MockOCIClient) ListSessions(ctx context.Context, bastionID string) ([]b
 	for _, s := range m.Sessions {
 		if s.BastionId != nil && *s.BastionId == bastionID {
 			summaries = append(summaries, bastion.SessionSummary{
-				Id:             s.Id,
-				BastionId:      s.BastionId,
-				DisplayName:    s.DisplayName,
-				LifecycleState: s.LifecycleState,
+				Id:                    s.Id,
+				BastionId:             s.BastionId,
+				DisplayName:           s.DisplayName,
+				LifecycleState:        s.LifecycleState,
+				TargetResourceDetails: s.TargetResourceDetails,
+				TimeCreated:           s.TimeCreated,
+				SessionTtlInSeconds:   s.SessionTtlInSeconds,
 			})
 		}
 	}
@@ -280,6 +529,7 @@ func (m *MockOCIClient) ListSessions(ctx context.Context, bastionID string) ([]b
 // DeleteSession deletes a mock session.
 func (m *MockOCIClient) DeleteSession(ctx context.Context, bastionID, sessionID string) error {
 	m.recordCall("DeleteSession", bastionID, sessionID)
+	m.observeCall(ctx, "DeleteSession")
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -291,8 +541,14 @@ func (m *MockOCIClient) DeleteSession(ctx context.Context, bastionID, sessionID
 }
 
 // WaitForSessionActive waits for a session to become active.
-func (m *MockOCIClient) WaitForSessionActive(ctx context.Context, bastionID, sessionID string) (*bastion.Session, error) {
+func (m *MockOCIClient) WaitForSessionActive(ctx context.Context, bastionID, sessionID string, opts *SessionWaitOptions) (*bastion.Session, error) {
 	m.recordCall("WaitForSessionActive", bastionID, sessionID)
+	m.observeCall(ctx, "WaitForSessionActive")
+
+	start := time.Now()
+	if opts != nil && opts.OnPoll != nil {
+		opts.OnPoll(bastion.SessionLifecycleStateCreating, 0)
+	}
 
 	if m.SessionActiveDelay > 0 {
 		select {
@@ -308,6 +564,9 @@ func (m *MockOCIClient) WaitForSessionActive(ctx context.Context, bastionID, ses
 	if session, ok := m.Sessions[sessionID]; ok {
 		// Transition to active
 		session.LifecycleState = bastion.SessionLifecycleStateActive
+		if opts != nil && opts.OnPoll != nil {
+			opts.OnPoll(session.LifecycleState, time.Since(start))
+		}
 		return session, nil
 	}
 	return nil, fmt.Errorf("session not found: %s", sessionID)
@@ -340,6 +599,211 @@ func (m *MockOCIClient) AddBastion(b *bastion.Bastion) {
 	}
 }
 
+// AddInstance adds a compute instance to a compartment for tests.
+func (m *MockOCIClient) AddInstance(compartmentID string, instance core.Instance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.InstancesByCompartment[compartmentID] = append(m.InstancesByCompartment[compartmentID], instance)
+}
+
+// ListInstances lists the mock instances of a compartment.
+func (m *MockOCIClient) ListInstances(ctx context.Context, compartmentID string) ([]core.Instance, error) {
+	m.recordCall("ListInstances", compartmentID)
+	m.observeCall(ctx, "ListInstances")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.InstancesByCompartment[compartmentID], nil
+}
+
+// GetPrimaryVnic returns the mock primary VNIC of an instance.
+func (m *MockOCIClient) GetPrimaryVnic(ctx context.Context, compartmentID, instanceID string) (*core.Vnic, error) {
+	m.recordCall("GetPrimaryVnic", compartmentID, instanceID)
+	m.observeCall(ctx, "GetPrimaryVnic")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if vnic, ok := m.PrimaryVnics[instanceID]; ok {
+		return vnic, nil
+	}
+	return nil, fmt.Errorf("instance %s has no attached primary VNIC", instanceID)
+}
+
+// GetPrivateIP returns a mock private IP.
+func (m *MockOCIClient) GetPrivateIP(ctx context.Context, privateIPID string) (*core.PrivateIp, error) {
+	m.recordCall("GetPrivateIP", privateIPID)
+	m.observeCall(ctx, "GetPrivateIP")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if ip, ok := m.PrivateIPs[privateIPID]; ok {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("private IP not found: %s", privateIPID)
+}
+
+// ListAutonomousDatabases lists the mock Autonomous Databases of a
+// compartment.
+func (m *MockOCIClient) ListAutonomousDatabases(ctx context.Context, compartmentID string) ([]database.AutonomousDatabaseSummary, error) {
+	m.recordCall("ListAutonomousDatabases", compartmentID)
+	m.observeCall(ctx, "ListAutonomousDatabases")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var databases []database.AutonomousDatabaseSummary
+	for _, db := range m.AutonomousDatabases {
+		if db.CompartmentId != nil && *db.CompartmentId == compartmentID {
+			databases = append(databases, database.AutonomousDatabaseSummary{
+				Id:                db.Id,
+				CompartmentId:     db.CompartmentId,
+				DisplayName:       db.DisplayName,
+				SubnetId:          db.SubnetId,
+				PrivateEndpointIp: db.PrivateEndpointIp,
+			})
+		}
+	}
+	return databases, nil
+}
+
+// GetAutonomousDatabase returns a mock Autonomous Database.
+func (m *MockOCIClient) GetAutonomousDatabase(ctx context.Context, databaseID string) (*database.AutonomousDatabase, error) {
+	m.recordCall("GetAutonomousDatabase", databaseID)
+	m.observeCall(ctx, "GetAutonomousDatabase")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if db, ok := m.AutonomousDatabases[databaseID]; ok {
+		return db, nil
+	}
+	return nil, fmt.Errorf("autonomous database not found: %s", databaseID)
+}
+
+// ListDbSystems lists the mock DB systems of a compartment.
+func (m *MockOCIClient) ListDbSystems(ctx context.Context, compartmentID string) ([]database.DbSystemSummary, error) {
+	m.recordCall("ListDbSystems", compartmentID)
+	m.observeCall(ctx, "ListDbSystems")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var systems []database.DbSystemSummary
+	for _, s := range m.DbSystems {
+		if s.CompartmentId != nil && *s.CompartmentId == compartmentID {
+			systems = append(systems, database.DbSystemSummary{
+				Id:            s.Id,
+				CompartmentId: s.CompartmentId,
+				DisplayName:   s.DisplayName,
+				SubnetId:      s.SubnetId,
+			})
+		}
+	}
+	return systems, nil
+}
+
+// GetDbSystem returns a mock DB system.
+func (m *MockOCIClient) GetDbSystem(ctx context.Context, dbSystemID string) (*database.DbSystem, error) {
+	m.recordCall("GetDbSystem", dbSystemID)
+	m.observeCall(ctx, "GetDbSystem")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if s, ok := m.DbSystems[dbSystemID]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("DB system not found: %s", dbSystemID)
+}
+
+// ListMySQLDbSystems lists the mock MySQL DB systems of a compartment.
+func (m *MockOCIClient) ListMySQLDbSystems(ctx context.Context, compartmentID string) ([]mysql.DbSystemSummary, error) {
+	m.recordCall("ListMySQLDbSystems", compartmentID)
+	m.observeCall(ctx, "ListMySQLDbSystems")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var systems []mysql.DbSystemSummary
+	for _, s := range m.MySQLDbSystems {
+		if s.CompartmentId != nil && *s.CompartmentId == compartmentID {
+			systems = append(systems, mysql.DbSystemSummary{
+				Id:            s.Id,
+				CompartmentId: s.CompartmentId,
+				DisplayName:   s.DisplayName,
+			})
+		}
+	}
+	return systems, nil
+}
+
+// GetMySQLDbSystem returns a mock MySQL DB system.
+func (m *MockOCIClient) GetMySQLDbSystem(ctx context.Context, dbSystemID string) (*mysql.DbSystem, error) {
+	m.recordCall("GetMySQLDbSystem", dbSystemID)
+	m.observeCall(ctx, "GetMySQLDbSystem")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if s, ok := m.MySQLDbSystems[dbSystemID]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("MySQL DB system not found: %s", dbSystemID)
+}
+
+// ListPostgresDbSystems lists the mock PostgreSQL DB systems of a
+// compartment.
+func (m *MockOCIClient) ListPostgresDbSystems(ctx context.Context, compartmentID string) ([]psql.DbSystemSummary, error) {
+	m.recordCall("ListPostgresDbSystems", compartmentID)
+	m.observeCall(ctx, "ListPostgresDbSystems")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var systems []psql.DbSystemSummary
+	for _, s := range m.PostgresDbSystems {
+		if s.CompartmentId != nil && *s.CompartmentId == compartmentID {
+			systems = append(systems, psql.DbSystemSummary{
+				Id:            s.Id,
+				CompartmentId: s.CompartmentId,
+				DisplayName:   s.DisplayName,
+			})
+		}
+	}
+	return systems, nil
+}
+
+// GetPostgresDbSystem returns a mock PostgreSQL DB system.
+func (m *MockOCIClient) GetPostgresDbSystem(ctx context.Context, dbSystemID string) (*psql.DbSystem, error) {
+	m.recordCall("GetPostgresDbSystem", dbSystemID)
+	m.observeCall(ctx, "GetPostgresDbSystem")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if s, ok := m.PostgresDbSystems[dbSystemID]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("PostgreSQL DB system not found: %s", dbSystemID)
+}
+
+// GetSecretContent returns the content of a mock Vault secret.
+func (m *MockOCIClient) GetSecretContent(ctx context.Context, secretID string) (string, error) {
+	m.recordCall("GetSecretContent", secretID)
+	m.observeCall(ctx, "GetSecretBundle")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if content, ok := m.Secrets[secretID]; ok {
+		return content, nil
+	}
+	return "", fmt.Errorf("secret not found: %s", secretID)
+}
+
+// PutLogEntries records entries as put in the mock log logID.
+func (m *MockOCIClient) PutLogEntries(ctx context.Context, logID, source, logType string, entries []LogEntry) error {
+	m.recordCall("PutLogEntries", logID)
+	m.observeCall(ctx, "PutLogs")
+	if m.ShouldFailAuth {
+		return fmt.Errorf("mock auth failure")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.LogEntries[logID] = append(m.LogEntries[logID], entries...)
+	return nil
+}
+
 // AddObject adds an object for tests.
 func (m *MockOCIClient) AddObject(namespace, bucket, object string, content []byte) {
 	m.mu.Lock()
@@ -364,6 +828,7 @@ func (m *MockOCIClient) GetTenancyOCID() (string, error) {
 // ListCompartments returns mock compartments for a parent.
 func (m *MockOCIClient) ListCompartments(ctx context.Context, parentID string) ([]identity.Compartment, error) {
 	m.recordCall("ListCompartments", parentID)
+	m.observeCall(ctx, "ListCompartments")
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -373,9 +838,11 @@ func (m *MockOCIClient) ListCompartments(ctx context.Context, parentID string) (
 	return []identity.Compartment{}, nil
 }
 
-// ListClustersInCompartment returns mock clusters in a compartment.
-func (m *MockOCIClient) ListClustersInCompartment(ctx context.Context, compartmentID string) ([]containerengine.ClusterSummary, error) {
-	m.recordCall("ListClustersInCompartment", compartmentID)
+// ListClustersInCompartment returns mock clusters in a compartment. States
+// are recorded but not applied, so callers' own filtering can be tested.
+func (m *MockOCIClient) ListClustersInCompartment(ctx context.Context, compartmentID string, states []containerengine.ClusterLifecycleStateEnum) ([]containerengine.ClusterSummary, error) {
+	m.recordCall("ListClustersInCompartment", compartmentID, states)
+	m.observeCall(ctx, "ListClustersInCompartment")
 	if m.ShouldFailCluster {
 		return nil, fmt.Errorf("mock cluster listing failure")
 	}
@@ -391,6 +858,7 @@ func (m *MockOCIClient) ListClustersInCompartment(ctx context.Context, compartme
 // GetSubscribedRegions returns mock subscribed regions.
 func (m *MockOCIClient) GetSubscribedRegions(ctx context.Context, tenancyID string) ([]identity.RegionSubscription, error) {
 	m.recordCall("GetSubscribedRegions", tenancyID)
+	m.observeCall(ctx, "GetSubscribedRegions")
 	if m.RegionError != nil {
 		return nil, m.RegionError
 	}
@@ -399,6 +867,26 @@ func (m *MockOCIClient) GetSubscribedRegions(ctx context.Context, tenancyID stri
 	return m.SubscribedRegions, nil
 }
 
+// SearchResources returns the mock search results for Region, whatever the
+// query.
+func (m *MockOCIClient) SearchResources(ctx context.Context, query string) ([]resourcesearch.ResourceSummary, error) {
+	m.mu.RLock()
+	region := m.Region
+	m.mu.RUnlock()
+	return m.searchResources(ctx, query, region)
+}
+
+func (m *MockOCIClient) searchResources(ctx context.Context, query, region string) ([]resourcesearch.ResourceSummary, error) {
+	m.recordCall("SearchResources", query)
+	m.observeCall(ctx, "SearchResources")
+	if m.ShouldFailCluster {
+		return nil, fmt.Errorf("mock resource search failure")
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.SearchResults[region], nil
+}
+
 // Helper methods for discovery test setup
 
 // AddCompartmentByID adds a compartment to a parent for tests.
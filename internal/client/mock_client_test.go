@@ -7,21 +7,26 @@ import (
 
 	"github.com/oracle/oci-go-sdk/v65/bastion"
 	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
 )
 
 func TestMockOCIClient_BasicOperations(t *testing.T) {
 	mock := NewMockOCIClient()
 
 	// Test region
-	mock.SetRegion("us-ashburn-1")
-	if mock.Region != "us-ashburn-1" {
-		t.Errorf("Expected region us-ashburn-1, got %s", mock.Region)
+	mock.SearchResults["us-ashburn-1"] = []resourcesearch.ResourceSummary{{}}
+	regional := mock.RegionClient("us-ashburn-1")
+	if results, _ := regional.SearchResources(context.Background(), "query all resources"); len(results) != 1 {
+		t.Errorf("Expected the search results of us-ashburn-1, got %v", results)
+	}
+	if results, _ := mock.SearchResources(context.Background(), "query all resources"); len(results) != 0 {
+		t.Errorf("Expected the mock itself to stay in its own region, got %v", results)
 	}
 
 	// Verify call was recorded
 	calls := mock.GetCalls()
-	if len(calls) != 1 || calls[0].Method != "SetRegion" {
-		t.Errorf("Expected SetRegion call to be recorded, got %v", calls)
+	if len(calls) != 3 || calls[0].Method != "RegionClient" {
+		t.Errorf("Expected RegionClient call to be recorded, got %v", calls)
 	}
 }
 
@@ -142,7 +147,7 @@ func TestMockOCIClient_SessionLifecycle(t *testing.T) {
 	sessionID := *session.Id
 
 	// Wait for active
-	activeSession, err := mock.WaitForSessionActive(ctx, bastionID, sessionID)
+	activeSession, err := mock.WaitForSessionActive(ctx, bastionID, sessionID, nil)
 	if err != nil {
 		t.Fatalf("Failed to wait for session: %v", err)
 	}
@@ -192,7 +197,7 @@ func TestMockOCIClient_SessionWithDelay(t *testing.T) {
 		t.Fatalf("Failed to create session: %v", err)
 	}
 
-	_, err = mock.WaitForSessionActive(ctx, bastionID, *session.Id)
+	_, err = mock.WaitForSessionActive(ctx, bastionID, *session.Id, nil)
 	if err != nil {
 		t.Fatalf("Failed to wait for session: %v", err)
 	}
@@ -257,7 +262,7 @@ func TestMockOCIClient_CallTracking(t *testing.T) {
 	mock := NewMockOCIClient()
 	ctx := context.Background()
 
-	mock.SetRegion("us-ashburn-1")
+	mock.RegionClient("us-ashburn-1")
 	mock.GetAuthType()
 	mock.GetNamespace(ctx, "test-tenancy")
 
@@ -266,7 +271,7 @@ func TestMockOCIClient_CallTracking(t *testing.T) {
 		t.Errorf("Expected 3 calls recorded, got %d", len(calls))
 	}
 
-	expectedMethods := []string{"SetRegion", "GetAuthType", "GetNamespace"}
+	expectedMethods := []string{"RegionClient", "GetAuthType", "GetNamespace"}
 	for i, expected := range expectedMethods {
 		if calls[i].Method != expected {
 			t.Errorf("Call %d: expected %s, got %s", i, expected, calls[i].Method)
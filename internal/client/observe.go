@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+)
+
+// APICall describes one request an OCI client made.
+type APICall struct {
+	// Operation names the request by method and path, such as
+	// "GET /clusters/{id}", with OCIDs left out.
+	Operation  string
+	Duration   time.Duration
+	StatusCode int
+	Err        error
+}
+
+// RateLimited reports whether OCI turned the call away for making too many
+// requests.
+func (c APICall) RateLimited() bool {
+	return c.StatusCode == http.StatusTooManyRequests
+}
+
+// Failed reports whether the call failed for a reason other than a rate
+// limit.
+func (c APICall) Failed() bool {
+	return !c.RateLimited() && (c.Err != nil || c.StatusCode >= http.StatusBadRequest)
+}
+
+// CallObserver is told about every API call a client makes, with the
+// context the call was made with. It may be called concurrently.
+type CallObserver func(ctx context.Context, call APICall)
+
+// SetCallObserver sets the observer told about every API call, including
+// retries, of the client and its clients for other regions, or removes it
+// if nil.
+func (c *OCIClient) SetCallObserver(observer CallObserver) {
+	c.regions.observerMu.Lock()
+	defer c.regions.observerMu.Unlock()
+	c.regions.observer = observer
+}
+
+func (c *OCIClient) callObserver() CallObserver {
+	c.regions.observerMu.RLock()
+	defer c.regions.observerMu.RUnlock()
+	return c.regions.observer
+}
+
+// observed wraps the HTTP dispatcher of an SDK client so that its requests
+// are reported to the client's observer.
+func (c *OCIClient) observed(next common.HTTPRequestDispatcher) common.HTTPRequestDispatcher {
+	return &observedDispatcher{next: next, client: c}
+}
+
+type observedDispatcher struct {
+	next   common.HTTPRequestDispatcher
+	client *OCIClient
+}
+
+func (d *observedDispatcher) Do(req *http.Request) (*http.Response, error) {
+	observer := d.client.callObserver()
+	if observer == nil {
+		return d.next.Do(req)
+	}
+
+	start := time.Now()
+	resp, err := d.next.Do(req)
+	call := APICall{Operation: requestOperation(req), Duration: time.Since(start), Err: err}
+	if resp != nil {
+		call.StatusCode = resp.StatusCode
+	}
+	observer(req.Context(), call)
+	return resp, err
+}
+
+// requestOperation names a request by its method and path, leaving out the
+// API version and replacing OCIDs with "{id}", so that calls to the same
+// operation share a name.
+func requestOperation(req *http.Request) string {
+	var segments []string
+	for _, s := range strings.Split(strings.Trim(req.URL.Path, "/"), "/") {
+		switch {
+		case s == "" || isAPIVersion(s):
+			continue
+		case strings.HasPrefix(s, "ocid1."):
+			s = "{id}"
+		}
+		segments = append(segments, s)
+	}
+	return req.Method + " /" + strings.Join(segments, "/")
+}
+
+// isAPIVersion reports whether a path segment is an OCI API version, such as
+// "20180222".
+func isAPIVersion(s string) bool {
+	if len(s) != 8 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestRequestOperation(t *testing.T) {
+	tests := []struct {
+		method, url, want string
+	}{
+		{"GET", "https://containerengine.us-ashburn-1.oci.oraclecloud.com/20180222/clusters?compartmentId=ocid1.compartment.oc1..a", "GET /clusters"},
+		{"GET", "https://containerengine.us-ashburn-1.oci.oraclecloud.com/20180222/clusters/ocid1.cluster.oc1.iad.a", "GET /clusters/{id}"},
+		{"POST", "https://query.us-ashburn-1.oci.oraclecloud.com/20180409/resources", "POST /resources"},
+		{"GET", "https://identity.us-ashburn-1.oci.oraclecloud.com/20160918/tenancies/ocid1.tenancy.oc1..a/regionSubscriptions", "GET /tenancies/{id}/regionSubscriptions"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := requestOperation(req); got != tt.want {
+			t.Errorf("requestOperation(%s %s) = %q, want %q", tt.method, tt.url, got, tt.want)
+		}
+	}
+}
+
+type fakeDispatcher struct{ status int }
+
+func (d fakeDispatcher) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: d.status, Request: req}, nil
+}
+
+type observeTestKey struct{}
+
+func TestObservedDispatcher(t *testing.T) {
+	c := &OCIClient{regions: newRegionClients()}
+	dispatcher := c.observed(fakeDispatcher{status: http.StatusTooManyRequests})
+
+	req, _ := http.NewRequest("GET", "https://identity.us-ashburn-1.oci.oraclecloud.com/20160918/compartments", nil)
+	if _, err := dispatcher.Do(req); err != nil {
+		t.Fatalf("Do() without an observer error = %v", err)
+	}
+
+	var calls []APICall
+	c.SetCallObserver(func(ctx context.Context, call APICall) {
+		if ctx.Value(observeTestKey{}) != "scope" {
+			t.Error("the observer should get the request's context")
+		}
+		calls = append(calls, call)
+	})
+	req = req.WithContext(context.WithValue(context.Background(), observeTestKey{}, "scope"))
+	if _, err := dispatcher.Do(req); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if len(calls) != 1 || calls[0].Operation != "GET /compartments" || !calls[0].RateLimited() || calls[0].Failed() {
+		t.Errorf("observed %+v, want one rate-limited GET /compartments", calls)
+	}
+}
@@ -1,20 +1,31 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/oracle/oci-go-sdk/v65/bastion"
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/common/auth"
 	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/oracle/oci-go-sdk/v65/database"
 	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/loggingingestion"
+	"github.com/oracle/oci-go-sdk/v65/mysql"
 	"github.com/oracle/oci-go-sdk/v65/objectstorage"
+	"github.com/oracle/oci-go-sdk/v65/psql"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+	"github.com/oracle/oci-go-sdk/v65/secrets"
 	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/health"
 )
 
 // AuthType represents the type of OCI authentication to use.
@@ -31,21 +42,42 @@ const (
 	AuthTypeSecurityToken AuthType = "security_token"
 	// AuthTypeResourcePrincipal uses resource principal authentication (for OCI functions, etc.).
 	AuthTypeResourcePrincipal AuthType = "resource_principal"
+	// AuthTypeOkeWorkloadIdentity uses OKE workload identity authentication (for pods in OKE clusters).
+	AuthTypeOkeWorkloadIdentity AuthType = "oke_workload_identity"
 )
 
-// OCIClient wraps multiple OCI SDK clients.
+// serviceAccountTokenPath is where Kubernetes mounts the token of the pod's
+// service account, which OKE workload identity exchanges for OCI credentials.
+var serviceAccountTokenPath = auth.KubernetesServiceAccountTokenPath
+
+// OCIClient wraps multiple OCI SDK clients, all for one region. WithRegion
+// returns the client for another region.
 type OCIClient struct {
 	configProvider      common.ConfigurationProvider
+	region              string
 	identityClient      identity.IdentityClient
 	bastionClient       bastion.BastionClient
 	containerClient     containerengine.ContainerEngineClient
+	computeClient       core.ComputeClient
 	objectStorageClient objectstorage.ObjectStorageClient
+	searchClient        resourcesearch.ResourceSearchClient
+	databaseClient      database.DatabaseClient
+	networkClient       core.VirtualNetworkClient
+	mysqlClient         mysql.DbSystemClient
+	postgresClient      psql.PostgresqlClient
+	secretsClient       secrets.SecretsClient
+	loggingClient       loggingingestion.LoggingClient
+
+	breaker *breaker
+	regions *regionClients
 }
 
 // NewOCIClient creates a new OCI client with the given config provider.
 func NewOCIClient(configProvider *common.ConfigurationProvider) (*OCIClient, error) {
 	client := &OCIClient{
 		configProvider: *configProvider,
+		breaker:        newBreaker(0, 0),
+		regions:        newRegionClients(),
 	}
 
 	var err error
@@ -70,9 +102,66 @@ func NewOCIClient(configProvider *common.ConfigurationProvider) (*OCIClient, err
 		return nil, fmt.Errorf("failed to create object storage client: %w", err)
 	}
 
+	client.computeClient, err = core.NewComputeClientWithConfigurationProvider(*configProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compute client: %w", err)
+	}
+
+	client.searchClient, err = resourcesearch.NewResourceSearchClientWithConfigurationProvider(*configProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource search client: %w", err)
+	}
+
+	client.databaseClient, err = database.NewDatabaseClientWithConfigurationProvider(*configProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database client: %w", err)
+	}
+
+	client.networkClient, err = core.NewVirtualNetworkClientWithConfigurationProvider(*configProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create virtual network client: %w", err)
+	}
+
+	client.mysqlClient, err = mysql.NewDbSystemClientWithConfigurationProvider(*configProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MySQL client: %w", err)
+	}
+
+	client.postgresClient, err = psql.NewPostgresqlClientWithConfigurationProvider(*configProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PostgreSQL client: %w", err)
+	}
+
+	client.secretsClient, err = secrets.NewSecretsClientWithConfigurationProvider(*configProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secrets client: %w", err)
+	}
+
+	client.loggingClient, err = loggingingestion.NewLoggingClientWithConfigurationProvider(*configProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logging ingestion client: %w", err)
+	}
+
+	for _, base := range client.baseClients() {
+		base.HTTPClient = client.guarded(client.observed(base.HTTPClient))
+	}
+	client.region = client.GetConfiguredRegion()
+	client.regions.clients[client.region] = client
+	client.SetRetryPolicy(DefaultRetryPolicy())
+
 	return client, nil
 }
 
+// baseClients returns the base clients of the SDK clients c wraps.
+func (c *OCIClient) baseClients() []*common.BaseClient {
+	return []*common.BaseClient{
+		&c.identityClient.BaseClient, &c.bastionClient.BaseClient, &c.containerClient.BaseClient,
+		&c.objectStorageClient.BaseClient, &c.computeClient.BaseClient, &c.searchClient.BaseClient,
+		&c.databaseClient.BaseClient, &c.networkClient.BaseClient, &c.mysqlClient.BaseClient,
+		&c.postgresClient.BaseClient, &c.secretsClient.BaseClient, &c.loggingClient.BaseClient,
+	}
+}
+
 // NewOCIClientWithProfile creates a new OCI client using a specific profile.
 func NewOCIClientWithProfile(configPath, profile string) (*OCIClient, error) {
 	configProvider := common.CustomProfileConfigProvider(configPath, profile)
@@ -94,6 +183,9 @@ func NewOCIClientWithAuthType(authType AuthType, configPath, profile string) (*O
 	case AuthTypeResourcePrincipal:
 		return NewOCIClientWithResourcePrincipal()
 
+	case AuthTypeOkeWorkloadIdentity:
+		return NewOCIClientWithOkeWorkloadIdentity()
+
 	case AuthTypeAuto:
 		return NewOCIClientAuto(configPath, profile)
 
@@ -130,6 +222,21 @@ func NewOCIClientWithResourcePrincipal() (*OCIClient, error) {
 	return NewOCIClient(&provider)
 }
 
+// NewOCIClientWithOkeWorkloadIdentity creates a new OCI client using OKE workload identity authentication.
+// This is used when running in a pod of an OKE enhanced cluster, such as a CI runner, whose service
+// account is granted access by IAM policies, so that no API key needs to be mounted.
+func NewOCIClientWithOkeWorkloadIdentity() (*OCIClient, error) {
+	log.Debug().Msg("Using OKE workload identity authentication")
+
+	configProvider, err := auth.OkeWorkloadIdentityConfigurationProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OKE workload identity provider: %w", err)
+	}
+
+	var provider common.ConfigurationProvider = configProvider
+	return NewOCIClient(&provider)
+}
+
 // NewOCIClientWithSecurityToken creates a new OCI client using security token authentication.
 // This uses tokens generated by `oci session authenticate` for SSO/SAML flows.
 func NewOCIClientWithSecurityToken(configPath, profile string) (*OCIClient, error) {
@@ -153,9 +260,10 @@ func NewOCIClientWithSecurityToken(configPath, profile string) (*OCIClient, erro
 // NewOCIClientAuto creates a new OCI client by auto-detecting the best authentication method.
 // The detection order is:
 // 1. Security token (if session token file exists and is valid)
-// 2. Instance principal (if running on OCI and metadata service is available)
+// 2. OKE workload identity (if running in an OKE pod set up for it)
 // 3. Resource principal (if OCI_RESOURCE_PRINCIPAL_VERSION env var is set)
-// 4. Config file (fallback to standard config file authentication)
+// 4. Instance principal (if running on OCI and metadata service is available)
+// 5. Config file (fallback to standard config file authentication)
 func NewOCIClientAuto(configPath, profile string) (*OCIClient, error) {
 	log.Debug().Msg("Auto-detecting OCI authentication method")
 
@@ -169,6 +277,16 @@ func NewOCIClientAuto(configPath, profile string) (*OCIClient, error) {
 		log.Debug().Err(err).Msg("Security token auth failed, trying next method")
 	}
 
+	// Check for OKE workload identity (pods of OKE clusters)
+	if isRunningOnOKE() {
+		log.Info().Msg("Using OKE workload identity authentication")
+		client, err := NewOCIClientWithOkeWorkloadIdentity()
+		if err == nil {
+			return client, nil
+		}
+		log.Debug().Err(err).Msg("OKE workload identity auth failed, trying next method")
+	}
+
 	// Check for resource principal (OCI Functions, etc.)
 	if os.Getenv("OCI_RESOURCE_PRINCIPAL_VERSION") != "" {
 		log.Info().Msg("Using resource principal authentication")
@@ -194,6 +312,23 @@ func NewOCIClientAuto(configPath, profile string) (*OCIClient, error) {
 	return NewOCIClientWithProfile(configPath, profile)
 }
 
+// DetectAuthType returns the authentication method NewOCIClientAuto would
+// try first for configPath and profile, without creating a client.
+func DetectAuthType(configPath, profile string) AuthType {
+	switch {
+	case hasValidSecurityToken(configPath, profile):
+		return AuthTypeSecurityToken
+	case isRunningOnOKE():
+		return AuthTypeOkeWorkloadIdentity
+	case os.Getenv("OCI_RESOURCE_PRINCIPAL_VERSION") != "":
+		return AuthTypeResourcePrincipal
+	case isRunningOnOCI():
+		return AuthTypeInstancePrincipal
+	default:
+		return AuthTypeConfigFile
+	}
+}
+
 // hasValidSecurityToken checks if a valid security token exists.
 func hasValidSecurityToken(configPath, profile string) bool {
 	if configPath == "" {
@@ -232,6 +367,21 @@ func isRunningOnOCI() bool {
 	return false
 }
 
+// isRunningOnOKE checks if the current environment is a pod of an OKE cluster set up for
+// workload identity: the pod has a service account token, and the resource principal version
+// and region are set without the session token that other resource principals come with.
+func isRunningOnOKE() bool {
+	if os.Getenv("OCI_CLI_AUTH") == string(AuthTypeOkeWorkloadIdentity) {
+		return true
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" || os.Getenv("OCI_RESOURCE_PRINCIPAL_VERSION") == "" ||
+		os.Getenv("OCI_RESOURCE_PRINCIPAL_RPST") != "" {
+		return false
+	}
+	_, err := os.Stat(serviceAccountTokenPath)
+	return err == nil
+}
+
 // GetAuthType returns the authentication type being used by this client.
 func (c *OCIClient) GetAuthType() AuthType {
 	// Try to determine auth type from the config provider
@@ -243,12 +393,14 @@ func (c *OCIClient) GetAuthType() AuthType {
 	return AuthTypeConfigFile
 }
 
-// SetRegion sets the region for all clients.
-func (c *OCIClient) SetRegion(region string) {
-	c.identityClient.SetRegion(region)
-	c.bastionClient.SetRegion(region)
-	c.containerClient.SetRegion(region)
-	c.objectStorageClient.SetRegion(region)
+// GetConfiguredRegion returns the region of the OCI config profile or
+// principal the client was created with, or "" if it has none.
+func (c *OCIClient) GetConfiguredRegion() string {
+	region, err := c.configProvider.Region()
+	if err != nil {
+		return ""
+	}
+	return region
 }
 
 // GetNamespace returns the Object Storage namespace for a tenancy.
@@ -259,6 +411,7 @@ func (c *OCIClient) GetNamespace(ctx context.Context, tenancyOcid string) (strin
 
 	response, err := c.objectStorageClient.GetNamespace(ctx, request)
 	if err != nil {
+		recordAPIError("GetNamespace")
 		return "", fmt.Errorf("failed to get namespace: %w", err)
 	}
 
@@ -275,6 +428,7 @@ func (c *OCIClient) GetObject(ctx context.Context, namespace, bucket, object str
 
 	response, err := c.objectStorageClient.GetObject(ctx, request)
 	if err != nil {
+		recordAPIError("GetObject")
 		return nil, fmt.Errorf("failed to get object: %w", err)
 	}
 	defer response.Content.Close()
@@ -287,6 +441,68 @@ func (c *OCIClient) GetObject(ctx context.Context, namespace, bucket, object str
 	return data, nil
 }
 
+// GetObjectWithETag retrieves an object from Object Storage with its ETag,
+// for a later conditional PutObject.
+func (c *OCIClient) GetObjectWithETag(ctx context.Context, namespace, bucket, object string) ([]byte, string, error) {
+	request := objectstorage.GetObjectRequest{
+		NamespaceName: &namespace,
+		BucketName:    &bucket,
+		ObjectName:    &object,
+	}
+
+	response, err := c.objectStorageClient.GetObject(ctx, request)
+	if err != nil {
+		recordAPIError("GetObject")
+		return nil, "", fmt.Errorf("failed to get object: %w", err)
+	}
+	defer response.Content.Close()
+
+	data, err := io.ReadAll(response.Content)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read object content: %w", err)
+	}
+
+	etag := ""
+	if response.ETag != nil {
+		etag = *response.ETag
+	}
+	return data, etag, nil
+}
+
+// PutObject uploads data to Object Storage and returns the new ETag. The
+// upload only replaces the object while its ETag is still etag; with an
+// empty etag it only creates an object that does not exist yet. Otherwise
+// it fails with a 412 error, see IsPreconditionFailedError.
+func (c *OCIClient) PutObject(ctx context.Context, namespace, bucket, object string, data []byte, etag string) (string, error) {
+	length := int64(len(data))
+	contentType := "application/yaml"
+	request := objectstorage.PutObjectRequest{
+		NamespaceName: &namespace,
+		BucketName:    &bucket,
+		ObjectName:    &object,
+		ContentLength: &length,
+		ContentType:   &contentType,
+		PutObjectBody: io.NopCloser(bytes.NewReader(data)),
+	}
+	if etag != "" {
+		request.IfMatch = &etag
+	} else {
+		none := "*"
+		request.IfNoneMatch = &none
+	}
+
+	response, err := c.objectStorageClient.PutObject(ctx, request)
+	if err != nil {
+		recordAPIError("PutObject")
+		return "", fmt.Errorf("failed to put object: %w", err)
+	}
+
+	if response.ETag == nil {
+		return "", nil
+	}
+	return *response.ETag, nil
+}
+
 // GetCompartmentIDByPath finds a compartment by path (e.g., "parent/child/grandchild").
 func (c *OCIClient) GetCompartmentIDByPath(ctx context.Context, tenancyOcid, path string) (*string, error) {
 	parts := strings.Split(path, "/")
@@ -303,6 +519,21 @@ func (c *OCIClient) GetCompartmentIDByPath(ctx context.Context, tenancyOcid, pat
 	return &currentCompartmentID, nil
 }
 
+// GetCompartment gets a compartment, or the tenancy given its OCID.
+func (c *OCIClient) GetCompartment(ctx context.Context, compartmentID string) (*identity.Compartment, error) {
+	request := identity.GetCompartmentRequest{
+		CompartmentId: &compartmentID,
+	}
+
+	response, err := c.identityClient.GetCompartment(ctx, request)
+	if err != nil {
+		recordAPIError("GetCompartment")
+		return nil, fmt.Errorf("failed to get compartment: %w", err)
+	}
+
+	return &response.Compartment, nil
+}
+
 // findCompartmentByName finds a compartment by name within a parent compartment.
 func (c *OCIClient) findCompartmentByName(ctx context.Context, parentID, name string) (*string, error) {
 	request := identity.ListCompartmentsRequest{
@@ -312,6 +543,7 @@ func (c *OCIClient) findCompartmentByName(ctx context.Context, parentID, name st
 
 	response, err := c.identityClient.ListCompartments(ctx, request)
 	if err != nil {
+		recordAPIError("ListCompartments")
 		return nil, fmt.Errorf("failed to list compartments: %w", err)
 	}
 
@@ -322,22 +554,34 @@ func (c *OCIClient) findCompartmentByName(ctx context.Context, parentID, name st
 	return response.Items[0].Id, nil
 }
 
-// FetchClusterID finds a cluster OCID by name in a compartment.
+// FetchClusterID finds the OCID of the active or updating cluster with a
+// name in a compartment. Deleted and failed clusters keep their names, so
+// they are skipped.
 func (c *OCIClient) FetchClusterID(ctx context.Context, compartmentID, clusterName string) (*string, error) {
 	request := containerengine.ListClustersRequest{
 		CompartmentId: &compartmentID,
 		Name:          &clusterName,
+		LifecycleState: []containerengine.ClusterLifecycleStateEnum{
+			containerengine.ClusterLifecycleStateActive,
+			containerengine.ClusterLifecycleStateUpdating,
+		},
 	}
 
-	response, err := c.containerClient.ListClusters(ctx, request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list clusters: %w", err)
-	}
-
-	for _, cluster := range response.Items {
-		if *cluster.Name == clusterName {
-			return cluster.Id, nil
+	for {
+		response, err := c.containerClient.ListClusters(ctx, request)
+		if err != nil {
+			recordAPIError("ListClusters")
+			return nil, fmt.Errorf("failed to list clusters: %w", err)
 		}
+		for _, cluster := range response.Items {
+			if cluster.Name != nil && *cluster.Name == clusterName {
+				return cluster.Id, nil
+			}
+		}
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
 	}
 
 	return nil, fmt.Errorf("cluster '%s' not found in compartment %s", clusterName, compartmentID)
@@ -351,12 +595,82 @@ func (c *OCIClient) GetCluster(ctx context.Context, clusterID string) (*containe
 
 	response, err := c.containerClient.GetCluster(ctx, request)
 	if err != nil {
+		recordAPIError("GetCluster")
 		return nil, fmt.Errorf("failed to get cluster: %w", err)
 	}
 
 	return &response.Cluster, nil
 }
 
+// GetClusterKubeconfig returns the kubeconfig OCI generates for the private
+// endpoint of a cluster, which holds the certificate authority of its API
+// server.
+func (c *OCIClient) GetClusterKubeconfig(ctx context.Context, clusterID string) ([]byte, error) {
+	tokenVersion := "2.0.0"
+	request := containerengine.CreateKubeconfigRequest{
+		ClusterId: &clusterID,
+		CreateClusterKubeconfigContentDetails: containerengine.CreateClusterKubeconfigContentDetails{
+			TokenVersion: &tokenVersion,
+			Endpoint:     containerengine.CreateClusterKubeconfigContentDetailsEndpointPrivateEndpoint,
+		},
+	}
+
+	response, err := c.containerClient.CreateKubeconfig(ctx, request)
+	if err != nil {
+		recordAPIError("CreateKubeconfig")
+		return nil, fmt.Errorf("failed to get cluster kubeconfig: %w", err)
+	}
+	defer response.Content.Close()
+
+	data, err := io.ReadAll(response.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster kubeconfig: %w", err)
+	}
+	return data, nil
+}
+
+// ListNodePools lists the active and updating node pools of a cluster.
+func (c *OCIClient) ListNodePools(ctx context.Context, compartmentID, clusterID string) ([]containerengine.NodePoolSummary, error) {
+	request := containerengine.ListNodePoolsRequest{
+		CompartmentId: &compartmentID,
+		ClusterId:     &clusterID,
+		LifecycleState: []containerengine.NodePoolLifecycleStateEnum{
+			containerengine.NodePoolLifecycleStateActive,
+			containerengine.NodePoolLifecycleStateUpdating,
+		},
+	}
+
+	var nodePools []containerengine.NodePoolSummary
+	for {
+		response, err := c.containerClient.ListNodePools(ctx, request)
+		if err != nil {
+			recordAPIError("ListNodePools")
+			return nil, fmt.Errorf("failed to list node pools: %w", err)
+		}
+		nodePools = append(nodePools, response.Items...)
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return nodePools, nil
+}
+
+// GetNodePool gets a node pool, including its nodes.
+func (c *OCIClient) GetNodePool(ctx context.Context, nodePoolID string) (*containerengine.NodePool, error) {
+	request := containerengine.GetNodePoolRequest{
+		NodePoolId: &nodePoolID,
+	}
+
+	response, err := c.containerClient.GetNodePool(ctx, request)
+	if err != nil {
+		recordAPIError("GetNodePool")
+		return nil, fmt.Errorf("failed to get node pool: %w", err)
+	}
+
+	return &response.NodePool, nil
+}
+
 // ListBastions lists all bastions in a compartment.
 func (c *OCIClient) ListBastions(ctx context.Context, compartmentID string) ([]bastion.BastionSummary, error) {
 	request := bastion.ListBastionsRequest{
@@ -365,6 +679,7 @@ func (c *OCIClient) ListBastions(ctx context.Context, compartmentID string) ([]b
 
 	response, err := c.bastionClient.ListBastions(ctx, request)
 	if err != nil {
+		recordAPIError("ListBastions")
 		return nil, fmt.Errorf("failed to list bastions: %w", err)
 	}
 
@@ -379,12 +694,62 @@ func (c *OCIClient) GetBastion(ctx context.Context, bastionID string) (*bastion.
 
 	response, err := c.bastionClient.GetBastion(ctx, request)
 	if err != nil {
+		recordAPIError("GetBastion")
 		return nil, fmt.Errorf("failed to get bastion: %w", err)
 	}
 
 	return &response.Bastion, nil
 }
 
+// CreateBastion creates a bastion. The bastion is returned while it is still
+// being created.
+func (c *OCIClient) CreateBastion(ctx context.Context, details bastion.CreateBastionDetails) (*bastion.Bastion, error) {
+	request := bastion.CreateBastionRequest{
+		CreateBastionDetails: details,
+	}
+
+	response, err := c.bastionClient.CreateBastion(ctx, request)
+	if err != nil {
+		recordAPIError("CreateBastion")
+		return nil, fmt.Errorf("failed to create bastion: %w", err)
+	}
+
+	return &response.Bastion, nil
+}
+
+// UpdateBastion updates a bastion's settings. The update is applied
+// asynchronously by the bastion service.
+func (c *OCIClient) UpdateBastion(ctx context.Context, bastionID string, details bastion.UpdateBastionDetails) error {
+	request := bastion.UpdateBastionRequest{
+		BastionId:            &bastionID,
+		UpdateBastionDetails: details,
+	}
+
+	_, err := c.bastionClient.UpdateBastion(ctx, request)
+	if err != nil {
+		recordAPIError("UpdateBastion")
+		return fmt.Errorf("failed to update bastion: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBastion deletes a bastion.
+func (c *OCIClient) DeleteBastion(ctx context.Context, bastionID string) error {
+	request := bastion.DeleteBastionRequest{
+		BastionId: &bastionID,
+	}
+
+	_, err := c.bastionClient.DeleteBastion(ctx, request)
+	if err != nil {
+		recordAPIError("DeleteBastion")
+		return fmt.Errorf("failed to delete bastion: %w", err)
+	}
+
+	log.Debug().Msgf("Deleted bastion: %s", bastionID)
+	return nil
+}
+
 // CreateSession creates a new bastion session.
 func (c *OCIClient) CreateSession(ctx context.Context, bastionID string, sessionDetails bastion.CreateSessionDetails) (*bastion.Session, error) {
 	request := bastion.CreateSessionRequest{
@@ -393,6 +758,7 @@ func (c *OCIClient) CreateSession(ctx context.Context, bastionID string, session
 
 	response, err := c.bastionClient.CreateSession(ctx, request)
 	if err != nil {
+		recordAPIError("CreateSession")
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
@@ -407,6 +773,7 @@ func (c *OCIClient) GetSession(ctx context.Context, bastionID, sessionID string)
 
 	response, err := c.bastionClient.GetSession(ctx, request)
 	if err != nil {
+		recordAPIError("GetSession")
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
@@ -421,6 +788,7 @@ func (c *OCIClient) ListSessions(ctx context.Context, bastionID string) ([]basti
 
 	response, err := c.bastionClient.ListSessions(ctx, request)
 	if err != nil {
+		recordAPIError("ListSessions")
 		return nil, fmt.Errorf("failed to list sessions: %w", err)
 	}
 
@@ -435,6 +803,7 @@ func (c *OCIClient) DeleteSession(ctx context.Context, bastionID, sessionID stri
 
 	_, err := c.bastionClient.DeleteSession(ctx, request)
 	if err != nil {
+		recordAPIError("DeleteSession")
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 
@@ -442,26 +811,55 @@ func (c *OCIClient) DeleteSession(ctx context.Context, bastionID, sessionID stri
 	return nil
 }
 
-// WaitForSessionActive waits for a session to become active.
-func (c *OCIClient) WaitForSessionActive(ctx context.Context, bastionID, sessionID string) (*bastion.Session, error) {
+// DefaultSessionPollInterval is how often WaitForSessionActive checks the
+// session state when no interval is given.
+const DefaultSessionPollInterval = 3 * time.Second
+
+// SessionWaitOptions control how WaitForSessionActive polls.
+type SessionWaitOptions struct {
+	// PollInterval is the time between session state checks.
+	// Default: DefaultSessionPollInterval.
+	PollInterval time.Duration
+
+	// OnPoll, if set, is called with the session state after every check
+	// and the time spent waiting so far.
+	OnPoll func(state bastion.SessionLifecycleStateEnum, elapsed time.Duration)
+}
+
+// WaitForSessionActive waits for a session to become active, polling its
+// state until ctx is done. opts may be nil.
+func (c *OCIClient) WaitForSessionActive(ctx context.Context, bastionID, sessionID string, opts *SessionWaitOptions) (*bastion.Session, error) {
+	interval := DefaultSessionPollInterval
+	if opts != nil && opts.PollInterval > 0 {
+		interval = opts.PollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
 	for {
+		session, err := c.GetSession(ctx, bastionID, sessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts != nil && opts.OnPoll != nil {
+			opts.OnPoll(session.LifecycleState, time.Since(start))
+		}
+
+		switch session.LifecycleState {
+		case bastion.SessionLifecycleStateActive:
+			return session, nil
+		case bastion.SessionLifecycleStateDeleted, bastion.SessionLifecycleStateFailed:
+			return nil, fmt.Errorf("session entered %s state", session.LifecycleState)
+		}
+
+		log.Debug().Msgf("Session state: %s, waiting...", session.LifecycleState)
+
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
-		default:
-			session, err := c.GetSession(ctx, bastionID, sessionID)
-			if err != nil {
-				return nil, err
-			}
-
-			switch session.LifecycleState {
-			case bastion.SessionLifecycleStateActive:
-				return session, nil
-			case bastion.SessionLifecycleStateDeleted, bastion.SessionLifecycleStateFailed:
-				return nil, fmt.Errorf("session entered %s state", session.LifecycleState)
-			}
-
-			log.Debug().Msgf("Session state: %s, waiting...", session.LifecycleState)
+		case <-ticker.C:
 		}
 	}
 }
@@ -484,6 +882,7 @@ func (c *OCIClient) ListCompartments(ctx context.Context, parentID string) ([]id
 	for {
 		response, err := c.identityClient.ListCompartments(ctx, request)
 		if err != nil {
+			recordAPIError("ListCompartments")
 			return nil, fmt.Errorf("failed to list compartments: %w", err)
 		}
 		allCompartments = append(allCompartments, response.Items...)
@@ -495,20 +894,19 @@ func (c *OCIClient) ListCompartments(ctx context.Context, parentID string) ([]id
 	return allCompartments, nil
 }
 
-// ListClustersInCompartment lists all OKE clusters in a compartment.
-func (c *OCIClient) ListClustersInCompartment(ctx context.Context, compartmentID string) ([]containerengine.ClusterSummary, error) {
+// ListClustersInCompartment lists the OKE clusters in a compartment that
+// are in one of states, or in any state if states is empty.
+func (c *OCIClient) ListClustersInCompartment(ctx context.Context, compartmentID string, states []containerengine.ClusterLifecycleStateEnum) ([]containerengine.ClusterSummary, error) {
 	request := containerengine.ListClustersRequest{
-		CompartmentId: &compartmentID,
-		LifecycleState: []containerengine.ClusterLifecycleStateEnum{
-			containerengine.ClusterLifecycleStateActive,
-			containerengine.ClusterLifecycleStateUpdating,
-		},
+		CompartmentId:  &compartmentID,
+		LifecycleState: states,
 	}
 
 	var allClusters []containerengine.ClusterSummary
 	for {
 		response, err := c.containerClient.ListClusters(ctx, request)
 		if err != nil {
+			recordAPIError("ListClusters")
 			return nil, fmt.Errorf("failed to list clusters: %w", err)
 		}
 		allClusters = append(allClusters, response.Items...)
@@ -520,6 +918,318 @@ func (c *OCIClient) ListClustersInCompartment(ctx context.Context, compartmentID
 	return allClusters, nil
 }
 
+// SearchResources runs a structured Resource Search query in the current
+// region, such as "query cluster resources where displayName = 'prod'".
+func (c *OCIClient) SearchResources(ctx context.Context, query string) ([]resourcesearch.ResourceSummary, error) {
+	request := resourcesearch.SearchResourcesRequest{
+		SearchDetails: resourcesearch.StructuredSearchDetails{
+			Query: &query,
+		},
+	}
+
+	var resources []resourcesearch.ResourceSummary
+	for {
+		response, err := c.searchClient.SearchResources(ctx, request)
+		if err != nil {
+			recordAPIError("SearchResources")
+			return nil, fmt.Errorf("failed to search resources: %w", err)
+		}
+		resources = append(resources, response.Items...)
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return resources, nil
+}
+
+// ListInstancesByName lists the running compute instances in a compartment
+// with the given display name.
+func (c *OCIClient) ListInstancesByName(ctx context.Context, compartmentID, name string) ([]core.Instance, error) {
+	request := core.ListInstancesRequest{
+		CompartmentId:  &compartmentID,
+		DisplayName:    &name,
+		LifecycleState: core.InstanceLifecycleStateRunning,
+	}
+
+	var allInstances []core.Instance
+	for {
+		response, err := c.computeClient.ListInstances(ctx, request)
+		if err != nil {
+			recordAPIError("ListInstances")
+			return nil, fmt.Errorf("failed to list instances: %w", err)
+		}
+		allInstances = append(allInstances, response.Items...)
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return allInstances, nil
+}
+
+// ListInstances lists the running compute instances in a compartment.
+func (c *OCIClient) ListInstances(ctx context.Context, compartmentID string) ([]core.Instance, error) {
+	request := core.ListInstancesRequest{
+		CompartmentId:  &compartmentID,
+		LifecycleState: core.InstanceLifecycleStateRunning,
+	}
+
+	var allInstances []core.Instance
+	for {
+		response, err := c.computeClient.ListInstances(ctx, request)
+		if err != nil {
+			recordAPIError("ListInstances")
+			return nil, fmt.Errorf("failed to list instances: %w", err)
+		}
+		allInstances = append(allInstances, response.Items...)
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return allInstances, nil
+}
+
+// GetPrimaryVnic returns the primary VNIC of an instance in compartmentID.
+func (c *OCIClient) GetPrimaryVnic(ctx context.Context, compartmentID, instanceID string) (*core.Vnic, error) {
+	request := core.ListVnicAttachmentsRequest{
+		CompartmentId: &compartmentID,
+		InstanceId:    &instanceID,
+	}
+
+	for {
+		response, err := c.computeClient.ListVnicAttachments(ctx, request)
+		if err != nil {
+			recordAPIError("ListVnicAttachments")
+			return nil, fmt.Errorf("failed to list VNIC attachments: %w", err)
+		}
+		for _, attachment := range response.Items {
+			if attachment.VnicId == nil || attachment.LifecycleState != core.VnicAttachmentLifecycleStateAttached {
+				continue
+			}
+			vnic, err := c.networkClient.GetVnic(ctx, core.GetVnicRequest{VnicId: attachment.VnicId})
+			if err != nil {
+				recordAPIError("GetVnic")
+				return nil, fmt.Errorf("failed to get VNIC: %w", err)
+			}
+			if vnic.IsPrimary != nil && *vnic.IsPrimary {
+				return &vnic.Vnic, nil
+			}
+		}
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return nil, fmt.Errorf("instance %s has no attached primary VNIC", instanceID)
+}
+
+// GetPrivateIP returns a private IP by its OCID.
+func (c *OCIClient) GetPrivateIP(ctx context.Context, privateIPID string) (*core.PrivateIp, error) {
+	response, err := c.networkClient.GetPrivateIp(ctx, core.GetPrivateIpRequest{PrivateIpId: &privateIPID})
+	if err != nil {
+		recordAPIError("GetPrivateIp")
+		return nil, fmt.Errorf("failed to get private IP: %w", err)
+	}
+	return &response.PrivateIp, nil
+}
+
+// ListAutonomousDatabases lists the available Autonomous Databases in a
+// compartment.
+func (c *OCIClient) ListAutonomousDatabases(ctx context.Context, compartmentID string) ([]database.AutonomousDatabaseSummary, error) {
+	request := database.ListAutonomousDatabasesRequest{
+		CompartmentId:  &compartmentID,
+		LifecycleState: database.AutonomousDatabaseSummaryLifecycleStateAvailable,
+	}
+
+	var allDatabases []database.AutonomousDatabaseSummary
+	for {
+		response, err := c.databaseClient.ListAutonomousDatabases(ctx, request)
+		if err != nil {
+			recordAPIError("ListAutonomousDatabases")
+			return nil, fmt.Errorf("failed to list autonomous databases: %w", err)
+		}
+		allDatabases = append(allDatabases, response.Items...)
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return allDatabases, nil
+}
+
+// GetAutonomousDatabase returns an Autonomous Database by its OCID.
+func (c *OCIClient) GetAutonomousDatabase(ctx context.Context, databaseID string) (*database.AutonomousDatabase, error) {
+	response, err := c.databaseClient.GetAutonomousDatabase(ctx, database.GetAutonomousDatabaseRequest{AutonomousDatabaseId: &databaseID})
+	if err != nil {
+		recordAPIError("GetAutonomousDatabase")
+		return nil, fmt.Errorf("failed to get autonomous database: %w", err)
+	}
+	return &response.AutonomousDatabase, nil
+}
+
+// ListDbSystems lists the available DB systems in a compartment.
+func (c *OCIClient) ListDbSystems(ctx context.Context, compartmentID string) ([]database.DbSystemSummary, error) {
+	request := database.ListDbSystemsRequest{
+		CompartmentId:  &compartmentID,
+		LifecycleState: database.DbSystemSummaryLifecycleStateAvailable,
+	}
+
+	var allSystems []database.DbSystemSummary
+	for {
+		response, err := c.databaseClient.ListDbSystems(ctx, request)
+		if err != nil {
+			recordAPIError("ListDbSystems")
+			return nil, fmt.Errorf("failed to list DB systems: %w", err)
+		}
+		allSystems = append(allSystems, response.Items...)
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return allSystems, nil
+}
+
+// GetDbSystem returns a DB system by its OCID.
+func (c *OCIClient) GetDbSystem(ctx context.Context, dbSystemID string) (*database.DbSystem, error) {
+	response, err := c.databaseClient.GetDbSystem(ctx, database.GetDbSystemRequest{DbSystemId: &dbSystemID})
+	if err != nil {
+		recordAPIError("GetDbSystem")
+		return nil, fmt.Errorf("failed to get DB system: %w", err)
+	}
+	return &response.DbSystem, nil
+}
+
+// ListMySQLDbSystems lists the active MySQL HeatWave DB systems in a
+// compartment.
+func (c *OCIClient) ListMySQLDbSystems(ctx context.Context, compartmentID string) ([]mysql.DbSystemSummary, error) {
+	request := mysql.ListDbSystemsRequest{
+		CompartmentId:  &compartmentID,
+		LifecycleState: mysql.DbSystemLifecycleStateActive,
+	}
+
+	var allSystems []mysql.DbSystemSummary
+	for {
+		response, err := c.mysqlClient.ListDbSystems(ctx, request)
+		if err != nil {
+			recordAPIError("ListMySQLDbSystems")
+			return nil, fmt.Errorf("failed to list MySQL DB systems: %w", err)
+		}
+		allSystems = append(allSystems, response.Items...)
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return allSystems, nil
+}
+
+// GetMySQLDbSystem returns a MySQL HeatWave DB system by its OCID.
+func (c *OCIClient) GetMySQLDbSystem(ctx context.Context, dbSystemID string) (*mysql.DbSystem, error) {
+	response, err := c.mysqlClient.GetDbSystem(ctx, mysql.GetDbSystemRequest{DbSystemId: &dbSystemID})
+	if err != nil {
+		recordAPIError("GetMySQLDbSystem")
+		return nil, fmt.Errorf("failed to get MySQL DB system: %w", err)
+	}
+	return &response.DbSystem, nil
+}
+
+// ListPostgresDbSystems lists the active OCI Database with PostgreSQL DB
+// systems in a compartment.
+func (c *OCIClient) ListPostgresDbSystems(ctx context.Context, compartmentID string) ([]psql.DbSystemSummary, error) {
+	request := psql.ListDbSystemsRequest{
+		CompartmentId:  &compartmentID,
+		LifecycleState: psql.DbSystemLifecycleStateActive,
+	}
+
+	var allSystems []psql.DbSystemSummary
+	for {
+		response, err := c.postgresClient.ListDbSystems(ctx, request)
+		if err != nil {
+			recordAPIError("ListPostgresDbSystems")
+			return nil, fmt.Errorf("failed to list PostgreSQL DB systems: %w", err)
+		}
+		allSystems = append(allSystems, response.Items...)
+		if response.OpcNextPage == nil {
+			break
+		}
+		request.Page = response.OpcNextPage
+	}
+	return allSystems, nil
+}
+
+// GetPostgresDbSystem returns an OCI Database with PostgreSQL DB system by
+// its OCID.
+func (c *OCIClient) GetPostgresDbSystem(ctx context.Context, dbSystemID string) (*psql.DbSystem, error) {
+	response, err := c.postgresClient.GetDbSystem(ctx, psql.GetDbSystemRequest{DbSystemId: &dbSystemID})
+	if err != nil {
+		recordAPIError("GetPostgresDbSystem")
+		return nil, fmt.Errorf("failed to get PostgreSQL DB system: %w", err)
+	}
+	return &response.DbSystem, nil
+}
+
+// GetSecretContent returns the decoded content of the current version of a
+// Vault secret.
+func (c *OCIClient) GetSecretContent(ctx context.Context, secretID string) (string, error) {
+	response, err := c.secretsClient.GetSecretBundle(ctx, secrets.GetSecretBundleRequest{SecretId: &secretID})
+	if err != nil {
+		recordAPIError("GetSecretBundle")
+		return "", fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	content, ok := response.SecretBundleContent.(secrets.Base64SecretBundleContentDetails)
+	if !ok || content.Content == nil {
+		return "", fmt.Errorf("secret %s has no base64 content", secretID)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(*content.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+	return string(decoded), nil
+}
+
+// LogEntry is an entry put in an OCI Logging custom log.
+type LogEntry struct {
+	// ID uniquely identifies the entry, so that retries are not logged
+	// twice.
+	ID   string
+	Time time.Time
+	Data string
+}
+
+// PutLogEntries puts entries in the custom log logID, as one batch of the
+// given source and type.
+func (c *OCIClient) PutLogEntries(ctx context.Context, logID, source, logType string, entries []LogEntry) error {
+	batch := loggingingestion.LogEntryBatch{
+		Source:              &source,
+		Type:                &logType,
+		Defaultlogentrytime: &common.SDKTime{Time: time.Now()},
+	}
+	for _, e := range entries {
+		batch.Entries = append(batch.Entries, loggingingestion.LogEntry{
+			Id:   common.String(e.ID),
+			Time: &common.SDKTime{Time: e.Time},
+			Data: common.String(e.Data),
+		})
+	}
+
+	_, err := c.loggingClient.PutLogs(ctx, loggingingestion.PutLogsRequest{
+		LogId: &logID,
+		PutLogsDetails: loggingingestion.PutLogsDetails{
+			Specversion:     common.String("1.0"),
+			LogEntryBatches: []loggingingestion.LogEntryBatch{batch},
+		},
+	})
+	if err != nil {
+		recordAPIError("PutLogs")
+		return fmt.Errorf("failed to put log entries: %w", err)
+	}
+	return nil
+}
+
 // GetSubscribedRegions returns the list of regions the tenancy is subscribed to.
 func (c *OCIClient) GetSubscribedRegions(ctx context.Context, tenancyID string) ([]identity.RegionSubscription, error) {
 	request := identity.ListRegionSubscriptionsRequest{
@@ -527,7 +1237,13 @@ func (c *OCIClient) GetSubscribedRegions(ctx context.Context, tenancyID string)
 	}
 	response, err := c.identityClient.ListRegionSubscriptions(ctx, request)
 	if err != nil {
+		recordAPIError("ListRegionSubscriptions")
 		return nil, fmt.Errorf("failed to list region subscriptions: %w", err)
 	}
 	return response.Items, nil
 }
+
+// recordAPIError counts a failed OCI API call for metrics reporting.
+func recordAPIError(operation string) {
+	health.GetRegistry().RecordOCIError(operation)
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectAuthTypeOkeWorkloadIdentity(t *testing.T) {
+	dir := t.TempDir()
+	origTokenPath := serviceAccountTokenPath
+	defer func() { serviceAccountTokenPath = origTokenPath }()
+	serviceAccountTokenPath = filepath.Join(dir, "token")
+	if err := os.WriteFile(serviceAccountTokenPath, []byte("eyJhbGciOi"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ociConfig := filepath.Join(dir, "oci-config")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want AuthType
+	}{
+		{"pod with workload identity", map[string]string{
+			"KUBERNETES_SERVICE_HOST":        "10.96.0.1",
+			"OCI_RESOURCE_PRINCIPAL_VERSION": "2.2",
+			"OCI_RESOURCE_PRINCIPAL_REGION":  "us-ashburn-1",
+		}, AuthTypeOkeWorkloadIdentity},
+		{"OCI_CLI_AUTH", map[string]string{"OCI_CLI_AUTH": "oke_workload_identity"}, AuthTypeOkeWorkloadIdentity},
+		{"pod with a resource principal session token", map[string]string{
+			"KUBERNETES_SERVICE_HOST":        "10.96.0.1",
+			"OCI_RESOURCE_PRINCIPAL_VERSION": "2.2",
+			"OCI_RESOURCE_PRINCIPAL_RPST":    "/var/run/rpst",
+		}, AuthTypeResourcePrincipal},
+		{"pod without workload identity", map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"}, AuthTypeConfigFile},
+		{"outside Kubernetes", map[string]string{"OCI_RESOURCE_PRINCIPAL_VERSION": "2.2"}, AuthTypeResourcePrincipal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"KUBERNETES_SERVICE_HOST", "OCI_RESOURCE_PRINCIPAL_VERSION", "OCI_RESOURCE_PRINCIPAL_RPST", "OCI_RESOURCE_PRINCIPAL_REGION", "OCI_CLI_AUTH"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if got := DetectAuthType(ociConfig, "DEFAULT"); got != tt.want {
+				t.Errorf("DetectAuthType() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_VERSION", "2.2")
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_RPST", "")
+	serviceAccountTokenPath = filepath.Join(dir, "missing")
+	if isRunningOnOKE() {
+		t.Error("isRunningOnOKE() without a service account token should be false")
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import "sync"
+
+// regionClients is what a client shares with the clients for other regions
+// it hands out: the clients themselves, made once per region, and the retry
+// policy and observer they all use.
+type regionClients struct {
+	mu      sync.Mutex
+	clients map[string]*OCIClient
+	policy  RetryPolicy
+
+	observerMu sync.RWMutex
+	observer   CallObserver
+}
+
+func newRegionClients() *regionClients {
+	return &regionClients{clients: make(map[string]*OCIClient)}
+}
+
+// WithRegion returns a client making its calls to region, made the first
+// time it is asked for and shared from then on. The client itself is
+// returned for its own region or "". Unlike setting the region of a single
+// client, this is safe while other goroutines use clients for other
+// regions.
+//
+// The clients share their retry policy and observer; each region has its
+// own circuit breaker, so that a region failing does not turn calls to the
+// others away.
+func (c *OCIClient) WithRegion(region string) *OCIClient {
+	if region == "" || region == c.region {
+		return c
+	}
+
+	c.regions.mu.Lock()
+	defer c.regions.mu.Unlock()
+	if rc, ok := c.regions.clients[region]; ok {
+		return rc
+	}
+	rc := c.copyForRegion(region)
+	rc.applyRetryPolicy(c.regions.policy)
+	c.regions.clients[region] = rc
+	return rc
+}
+
+// RegionClient is WithRegion for callers holding an OCIClientInterface.
+func (c *OCIClient) RegionClient(region string) OCIClientInterface {
+	return c.WithRegion(region)
+}
+
+// copyForRegion returns a client with copies of the SDK clients of c, sent
+// to region and guarded by a breaker of their own.
+func (c *OCIClient) copyForRegion(region string) *OCIClient {
+	rc := &OCIClient{
+		configProvider:      c.configProvider,
+		region:              region,
+		identityClient:      c.identityClient,
+		bastionClient:       c.bastionClient,
+		containerClient:     c.containerClient,
+		computeClient:       c.computeClient,
+		objectStorageClient: c.objectStorageClient,
+		searchClient:        c.searchClient,
+		databaseClient:      c.databaseClient,
+		networkClient:       c.networkClient,
+		mysqlClient:         c.mysqlClient,
+		postgresClient:      c.postgresClient,
+		secretsClient:       c.secretsClient,
+		loggingClient:       c.loggingClient,
+		breaker:             newBreaker(0, 0),
+		regions:             c.regions,
+	}
+	for _, base := range rc.baseClients() {
+		if guarded, ok := base.HTTPClient.(*guardedDispatcher); ok {
+			base.HTTPClient = rc.guarded(guarded.next)
+		}
+	}
+
+	rc.identityClient.SetRegion(region)
+	rc.bastionClient.SetRegion(region)
+	rc.containerClient.SetRegion(region)
+	rc.objectStorageClient.SetRegion(region)
+	rc.computeClient.SetRegion(region)
+	rc.searchClient.SetRegion(region)
+	rc.databaseClient.SetRegion(region)
+	rc.networkClient.SetRegion(region)
+	rc.mysqlClient.SetRegion(region)
+	rc.postgresClient.SetRegion(region)
+	rc.secretsClient.SetRegion(region)
+	rc.loggingClient.SetRegion(region)
+	return rc
+}
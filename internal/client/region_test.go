@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithRegion(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	if c.WithRegion("") != c || c.WithRegion("us-ashburn-1") != c {
+		t.Error("WithRegion() of the client's own region or \"\" should return the client")
+	}
+
+	var wg sync.WaitGroup
+	clients := make([]*OCIClient, 8)
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clients[i] = c.WithRegion("eu-frankfurt-1")
+		}()
+	}
+	wg.Wait()
+	for _, rc := range clients[1:] {
+		if rc != clients[0] {
+			t.Fatal("WithRegion() should make one client per region")
+		}
+	}
+
+	frankfurt := clients[0]
+	if !strings.Contains(frankfurt.containerClient.Host, "eu-frankfurt-1") || !strings.Contains(frankfurt.bastionClient.Host, "eu-frankfurt-1") {
+		t.Errorf("client for eu-frankfurt-1 calls %s and %s", frankfurt.containerClient.Host, frankfurt.bastionClient.Host)
+	}
+	if c.objectStorageClient.Host != server.URL {
+		t.Errorf("WithRegion() changed the host of the client itself to %s", c.objectStorageClient.Host)
+	}
+	if frankfurt.WithRegion("us-ashburn-1") != c {
+		t.Error("clients for other regions should share the cache")
+	}
+}
+
+func TestWithRegionSharesPolicyAndObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"code":"InternalError","message":"oops"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	phoenix := c.WithRegion("us-phoenix-1")
+	phoenix.objectStorageClient.Host = server.URL
+
+	var mu sync.Mutex
+	var calls int
+	phoenix.SetCallObserver(func(context.Context, APICall) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+	})
+	c.SetRetryPolicy(RetryPolicy{
+		MaxAttempts:      2,
+		InitialInterval:  time.Millisecond,
+		MaxInterval:      time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	})
+
+	// Both attempts fail, opening the circuit of the client for us-phoenix-1
+	phoenix.GetNamespace(context.Background(), "ocid1.tenancy.oc1..t")
+	if calls != 2 {
+		t.Errorf("observer saw %d calls, want the 2 attempts", calls)
+	}
+	if _, err := phoenix.GetNamespace(context.Background(), "ocid1.tenancy.oc1..t"); err == nil || !strings.Contains(err.Error(), "keeps failing") {
+		t.Errorf("GetNamespace() in us-phoenix-1 error = %v, want the circuit open", err)
+	}
+
+	// The circuit of the client for us-ashburn-1 is its own
+	if _, err := c.GetNamespace(context.Background(), "ocid1.tenancy.oc1..t"); err == nil || strings.Contains(err.Error(), "keeps failing") {
+		t.Errorf("GetNamespace() in us-ashburn-1 error = %v, want the server's error", err)
+	}
+	if calls != 4 {
+		t.Errorf("observer saw %d calls, want the clients of both regions observed", calls)
+	}
+}
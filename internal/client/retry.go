@@ -0,0 +1,248 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// RetryPolicy configures how every call of an OCIClient is retried when OCI
+// throttles it (429) or fails it (5xx, or no response at all), and when an
+// operation failing over and over is given a rest.
+type RetryPolicy struct {
+	// MaxAttempts is how many times a call is made before its error is
+	// returned; 1 turns retrying off.
+	MaxAttempts int
+
+	// InitialInterval is the wait before the first retry. It doubles with
+	// each retry, up to MaxInterval. A Retry-After sent by OCI is waited
+	// instead, up to MaxInterval as well.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+
+	// BreakerThreshold is how many requests of one operation in a row
+	// must fail before further calls of it fail right away, for
+	// BreakerCooldown; 0 turns circuit breaking off.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy of clients that have not been
+// given one.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicyFromConfig(nil)
+}
+
+// RetryPolicyFromConfig returns the retry policy set by the oci_retry
+// settings r, which may be nil for the defaults.
+func RetryPolicyFromConfig(r *config.OCIRetry) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:      r.GetMaxAttempts(),
+		InitialInterval:  time.Duration(r.GetInitialIntervalSeconds()) * time.Second,
+		MaxInterval:      time.Duration(r.GetMaxIntervalSeconds()) * time.Second,
+		BreakerThreshold: r.GetCircuitBreakerThreshold(),
+		BreakerCooldown:  time.Duration(r.GetCircuitBreakerCooldownSeconds()) * time.Second,
+	}
+}
+
+// CircuitOpenError is returned for calls of an operation that failed too
+// many times in a row, without making the call, until its cooldown ends.
+type CircuitOpenError struct {
+	Operation string
+	Until     time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s keeps failing; not calling it again until %s", e.Operation, e.Until.Format(time.TimeOnly))
+}
+
+// SetRetryPolicy makes the client, and its clients for other regions,
+// retry their calls, and break the circuit of failing operations, as policy
+// says.
+func (c *OCIClient) SetRetryPolicy(policy RetryPolicy) {
+	c.regions.mu.Lock()
+	defer c.regions.mu.Unlock()
+	c.regions.policy = policy
+	for _, rc := range c.regions.clients {
+		rc.applyRetryPolicy(policy)
+	}
+}
+
+func (c *OCIClient) applyRetryPolicy(policy RetryPolicy) {
+	sdkPolicy := sdkRetryPolicy(policy)
+	for _, base := range c.baseClients() {
+		base.Configuration.RetryPolicy = &sdkPolicy
+	}
+	c.breaker.configure(policy.BreakerThreshold, policy.BreakerCooldown)
+}
+
+// sdkRetryPolicy returns the SDK retry policy carrying out policy. The SDK
+// rewinds request bodies and signs each attempt anew, and adds a retry
+// token to create requests so that a retried create does not create twice.
+func sdkRetryPolicy(policy RetryPolicy) common.RetryPolicy {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := &utils.BackoffConfig{
+		InitialInterval: policy.InitialInterval,
+		MaxInterval:     policy.MaxInterval,
+		Multiplier:      2,
+		JitterFactor:    0.2,
+	}
+	// NewRetryPolicy, unlike NewRetryPolicyWithOptions, does not swap the
+	// policy for the SDK's default one outside an eventual consistency window
+	return common.NewRetryPolicy(uint(attempts), shouldRetry, func(r common.OCIOperationResponse) time.Duration {
+		if wait, ok := retryAfter(r); ok {
+			return min(wait, policy.MaxInterval)
+		}
+		return backoff.CalculateBackoff(int(r.AttemptNumber) - 1)
+	})
+}
+
+// shouldRetry reports whether the outcome of a call is worth retrying: a
+// rate limit, a server error other than 501, or a network error. Calls
+// turned away by an open circuit are not.
+func shouldRetry(r common.OCIOperationResponse) bool {
+	if r.Error == nil {
+		return false
+	}
+	var open *CircuitOpenError
+	if errors.As(r.Error, &open) {
+		return false
+	}
+	var serviceErr common.ServiceError
+	if errors.As(r.Error, &serviceErr) {
+		return retryableStatus(serviceErr.GetHTTPStatusCode())
+	}
+	return common.IsNetworkError(r.Error)
+}
+
+// retryableStatus reports whether a response with status may succeed if
+// the request is made again.
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests ||
+		(status >= http.StatusInternalServerError && status != http.StatusNotImplemented)
+}
+
+// retryAfter returns how long the Retry-After header of the response asks
+// to wait, if it has one, in seconds or as a date.
+func retryAfter(r common.OCIOperationResponse) (time.Duration, bool) {
+	if r.Response == nil {
+		return 0, false
+	}
+	resp := r.Response.HTTPResponse()
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// breaker keeps the circuit of each operation of a client: closed while its
+// requests succeed, open for a cooldown once threshold of them in a row
+// have failed. The first request after the cooldown is let through, and
+// opens the circuit again at once if it fails too.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+	now       func() time.Time
+}
+
+// circuit is the state of one operation's circuit.
+type circuit struct {
+	failures  int
+	openUntil time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+		now:       time.Now,
+	}
+}
+
+func (b *breaker) configure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = threshold
+	b.cooldown = cooldown
+}
+
+// allow returns a CircuitOpenError if the circuit of operation is open.
+func (b *breaker) allow(operation string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 {
+		return nil
+	}
+	if c := b.circuits[operation]; c != nil && b.now().Before(c.openUntil) {
+		return &CircuitOpenError{Operation: operation, Until: c.openUntil}
+	}
+	return nil
+}
+
+// record counts the outcome of a request of operation.
+func (b *breaker) record(operation string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 {
+		return
+	}
+	if !failed {
+		delete(b.circuits, operation)
+		return
+	}
+	c := b.circuits[operation]
+	if c == nil {
+		c = &circuit{}
+		b.circuits[operation] = c
+	}
+	c.failures++
+	if c.failures >= b.threshold {
+		c.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// guarded wraps the HTTP dispatcher of an SDK client so that requests of
+// operations whose circuit is open are not made.
+func (c *OCIClient) guarded(next common.HTTPRequestDispatcher) common.HTTPRequestDispatcher {
+	return &guardedDispatcher{next: next, breaker: c.breaker}
+}
+
+type guardedDispatcher struct {
+	next    common.HTTPRequestDispatcher
+	breaker *breaker
+}
+
+func (d *guardedDispatcher) Do(req *http.Request) (*http.Response, error) {
+	operation := requestOperation(req)
+	if err := d.breaker.allow(operation); err != nil {
+		return nil, err
+	}
+	resp, err := d.next.Do(req)
+	if req.Context().Err() == nil {
+		d.breaker.record(operation, err != nil || retryableStatus(resp.StatusCode))
+	}
+	return resp, err
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/objectstorage"
+)
+
+// newTestClient returns a client with a throwaway API key whose object
+// storage calls go to server.
+func newTestClient(t *testing.T, server *httptest.Server) *OCIClient {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	provider := common.NewRawConfigurationProvider(
+		"ocid1.tenancy.oc1..t", "ocid1.user.oc1..u", "us-ashburn-1", "aa:bb", string(keyPEM), nil)
+
+	c, err := NewOCIClient(&provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.objectStorageClient.Host = server.URL
+	return c
+}
+
+func TestRetryPolicyRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"code":"TooManyRequests","message":"slow down"}`))
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"code":"ServiceUnavailable","message":"try later"}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`"acme"`))
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond, MaxInterval: 10 * time.Millisecond})
+
+	namespace, err := c.GetNamespace(context.Background(), "ocid1.tenancy.oc1..t")
+	if err != nil || namespace != "acme" {
+		t.Fatalf("GetNamespace() = %q, %v; want it to succeed on the third attempt", namespace, err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+}
+
+func TestRetryPolicyDoesNotRetryPermanentErrors(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"NotAuthorizedOrNotFound","message":"not found"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond})
+
+	if _, err := c.GetNamespace(context.Background(), "ocid1.tenancy.oc1..t"); err == nil {
+		t.Fatal("GetNamespace() should fail")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("server got %d requests, want 1", n)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"code":"InternalError","message":"oops"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.SetRetryPolicy(RetryPolicy{
+		MaxAttempts:      2,
+		InitialInterval:  time.Millisecond,
+		MaxInterval:      time.Millisecond,
+		BreakerThreshold: 3,
+		BreakerCooldown:  time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		c.GetNamespace(context.Background(), "ocid1.tenancy.oc1..t")
+	}
+	// The third failed request opened the circuit during the second call
+	if n := requests.Load(); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+	_, err := c.GetNamespace(context.Background(), "ocid1.tenancy.oc1..t")
+	var open *CircuitOpenError
+	if !errors.As(err, &open) || open.Operation != "GET /n" {
+		t.Errorf("GetNamespace() with the circuit open error = %v, want a CircuitOpenError for GET /n", err)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newBreaker(2, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	b.record("GET /compartments", true)
+	b.record("GET /compartments", false)
+	b.record("GET /compartments", true)
+	if err := b.allow("GET /compartments"); err != nil {
+		t.Fatalf("a success should reset the failure count, got %v", err)
+	}
+	b.record("GET /compartments", true)
+	if err := b.allow("GET /compartments"); err == nil || !strings.Contains(err.Error(), "GET /compartments keeps failing") {
+		t.Errorf("allow() after 2 failures in a row = %v, want the circuit open", err)
+	}
+	if err := b.allow("GET /clusters"); err != nil {
+		t.Errorf("other operations should not be affected, got %v", err)
+	}
+
+	now = now.Add(31 * time.Second)
+	if err := b.allow("GET /compartments"); err != nil {
+		t.Errorf("allow() after the cooldown = %v, want one more try", err)
+	}
+	b.record("GET /compartments", true)
+	if err := b.allow("GET /compartments"); err == nil {
+		t.Error("a failure after the cooldown should open the circuit again")
+	}
+
+	b.configure(0, 0)
+	if err := b.allow("GET /compartments"); err != nil {
+		t.Errorf("allow() with circuit breaking off = %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	response := func(value string) common.OCIOperationResponse {
+		header := http.Header{}
+		if value != "" {
+			header.Set("Retry-After", value)
+		}
+		return common.OCIOperationResponse{Response: objectstorage.GetNamespaceResponse{RawResponse: &http.Response{Header: header}}}
+	}
+
+	if wait, ok := retryAfter(response("7")); !ok || wait != 7*time.Second {
+		t.Errorf("retryAfter(7) = %v, %v", wait, ok)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if wait, ok := retryAfter(response(date)); !ok || wait <= 50*time.Second || wait > time.Minute {
+		t.Errorf("retryAfter(%s) = %v, %v; want about a minute", date, wait, ok)
+	}
+	for _, value := range []string{"", "soon"} {
+		if _, ok := retryAfter(response(value)); ok {
+			t.Errorf("retryAfter(%q) should have no wait", value)
+		}
+	}
+	if _, ok := retryAfter(common.OCIOperationResponse{Error: errors.New("connection reset")}); ok {
+		t.Error("retryAfter() without a response should have no wait")
+	}
+}
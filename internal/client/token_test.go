@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+)
+
+func TestGenerateClusterToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	c := &OCIClient{configProvider: common.NewRawConfigurationProvider(
+		"ocid1.tenancy.oc1..t", "ocid1.user.oc1..u", "us-phoenix-1", "aa:bb", string(keyPEM), nil)}
+
+	token, expires, err := c.GenerateClusterToken(context.Background(), "ocid1.cluster.oc1.iad.aaaa", "us-ashburn-1")
+	if err != nil {
+		t.Fatalf("GenerateClusterToken() error = %v", err)
+	}
+	if d := time.Until(expires); d <= 0 || d > ClusterTokenLifetime {
+		t.Errorf("token expires in %v, want within %v", d, ClusterTokenLifetime)
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatalf("token is not base64url: %v", err)
+	}
+	tokenURL, err := url.Parse(string(decoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tokenURL.Scheme + "://" + tokenURL.Host + tokenURL.Path; got != "https://containerengine.us-ashburn-1.oraclecloud.com/cluster_request/ocid1.cluster.oc1.iad.aaaa" {
+		t.Errorf("token URL = %s", got)
+	}
+	auth := tokenURL.Query().Get("authorization")
+	if !strings.Contains(auth, `keyId="ocid1.tenancy.oc1..t/ocid1.user.oc1..u/aa:bb"`) || !strings.Contains(auth, `headers="date (request-target) host"`) {
+		t.Errorf("authorization = %q", auth)
+	}
+	if tokenURL.Query().Get("date") == "" {
+		t.Error("token has no date")
+	}
+
+	// Without a region the profile's is used
+	token, _, err = c.GenerateClusterToken(context.Background(), "ocid1.cluster.oc1.phx.bbbb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := base64.URLEncoding.DecodeString(token); !strings.Contains(string(decoded), "containerengine.us-phoenix-1.") {
+		t.Errorf("token without a region = %s, want the profile's region", decoded)
+	}
+}
@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/koki-develop/go-fzf"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/ui"
+)
+
+// ChooseDiscoveredCluster recovers from a discovery error by letting the
+// user pick a cluster on the terminal: one of the matches when several
+// clusters have the name or tags, or one of the similar names when none
+// does. The chosen cluster is completed with discoverer; a choice among
+// several clusters with a name is cached for the name and hints, so it is
+// not asked again. Without a terminal, when running non-interactively or when
+// there is nothing to choose from, err is returned unchanged.
+func ChooseDiscoveredCluster(ctx context.Context, discoverer *discovery.Discoverer, hints *discovery.DiscoveryHints, err error) (*discovery.DiscoveredCluster, error) {
+	if !ui.CanPrompt() {
+		return nil, err
+	}
+
+	var (
+		multiple *discovery.MultipleClustersError
+		notFound *discovery.ClusterNotFoundError
+	)
+	switch {
+	case errors.As(err, &multiple) && multiple.Tagged:
+		fmt.Fprintf(os.Stderr, "Several clusters are tagged %s. Choose one:\n", multiple.Name)
+		chosen, pickErr := pickDiscoveredCluster(multiple.Matches)
+		if pickErr != nil {
+			return nil, err
+		}
+		return discoverer.CompleteCluster(ctx, chosen, chosen.CacheKey())
+
+	case errors.As(err, &multiple):
+		fmt.Fprintf(os.Stderr, "Several clusters are named '%s'. Choose one:\n", multiple.Name)
+		chosen, pickErr := pickDiscoveredCluster(multiple.Matches)
+		if pickErr != nil {
+			return nil, err
+		}
+		return discoverer.CompleteCluster(ctx, chosen, discovery.ChoiceCacheKey(multiple.Name, hints))
+
+	case errors.As(err, &notFound) && len(notFound.Suggestions) > 0:
+		fmt.Fprintf(os.Stderr, "Cluster '%s' not found. Choose a similar cluster:\n", notFound.Name)
+		chosen, pickErr := pickDiscoveredCluster(notFound.Suggestions)
+		if pickErr != nil {
+			return nil, err
+		}
+		return discoverer.CompleteCluster(ctx, chosen, chosen.CacheKey())
+	}
+	return nil, err
+}
+
+// pickDiscoveredCluster asks the user to pick one of clusters, which are
+// shown with their region and compartment.
+func pickDiscoveredCluster(clusters []*discovery.DiscoveredCluster) (*discovery.DiscoveredCluster, error) {
+	f, err := fzf.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create selector: %w", err)
+	}
+
+	choices := discovery.GetMultipleClusterChoices(clusters)
+	idxs, err := f.Find(choices, func(i int) string { return choices[i] })
+	if err != nil || len(idxs) == 0 {
+		return nil, exitcode.Wrap(exitcode.Cancelled, fmt.Errorf("no cluster selected"))
+	}
+	return clusters[idxs[0]], nil
+}
@@ -9,16 +9,23 @@ import (
 
 	"github.com/koki-develop/go-fzf"
 	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/rs/zerolog/log"
 	"github.com/scotttball/tunatap/internal/client"
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/ports"
 	"github.com/scotttball/tunatap/internal/state"
+	"github.com/scotttball/tunatap/internal/ui"
 	"github.com/scotttball/tunatap/pkg/utils"
 )
 
 // ValidateAndUpdateCluster validates and populates cluster configuration.
 func ValidateAndUpdateCluster(ctx context.Context, ociClient *client.OCIClient, cluster *config.Cluster, useBastion bool, localPort int) error {
-	SetClusterLocalPort(cluster, localPort)
+	if err := SetClusterLocalPort(cluster, localPort); err != nil {
+		return err
+	}
 
 	if err := SetClusterTenancy(ctx, ociClient, cluster); err != nil {
 		return err
@@ -30,17 +37,23 @@ func ValidateAndUpdateCluster(ctx context.Context, ociClient *client.OCIClient,
 
 	SetClusterURL(cluster)
 
+	// A bastion given by OCID replaces the one discovery found
+	if useBastion && overridesBastionID(cluster) {
+		cluster.BastionId = nil
+		cluster.BastionType = nil
+	}
+
 	if useBastion && cluster.BastionId == nil {
 		bastionID, err := GetClusterBastion(ctx, ociClient, cluster)
 		if err != nil {
-			return err
+			return exitcode.Wrap(exitcode.BastionUnavailable, err)
 		}
 		cluster.BastionId = bastionID
 
 		if cluster.BastionId != nil {
 			b, err := ociClient.GetBastion(ctx, *cluster.BastionId)
 			if err != nil {
-				return fmt.Errorf("failed to fetch bastion: %w", err)
+				return exitcode.Wrap(exitcode.BastionUnavailable, fmt.Errorf("failed to fetch bastion: %w", err))
 			}
 
 			log.Info().Msgf("Bastion type: %s", *b.BastionType)
@@ -48,6 +61,60 @@ func ValidateAndUpdateCluster(ctx context.Context, ociClient *client.OCIClient,
 		}
 	}
 
+	if useBastion && len(cluster.FallbackBastions) > 0 {
+		if err := SetClusterFallbackBastions(ctx, ociClient, cluster); err != nil {
+			return exitcode.Wrap(exitcode.BastionUnavailable, err)
+		}
+	}
+
+	return nil
+}
+
+// SetClusterFallbackBastions resolves the cluster's fallback_bastions to
+// OCIDs. Bastions that are not standard bastions are skipped, since only
+// standard bastions can fail over.
+func SetClusterFallbackBastions(ctx context.Context, ociClient *client.OCIClient, cluster *config.Cluster) error {
+	var bastions []bastion.BastionSummary
+
+	for _, ref := range cluster.FallbackBastions {
+		id := ref
+		if !utils.IsBastionOCID(ref) {
+			if bastions == nil {
+				if cluster.CompartmentOcid == nil {
+					return fmt.Errorf("compartment OCID not set")
+				}
+				var err error
+				bastions, err = ociClient.ListBastions(ctx, *cluster.CompartmentOcid)
+				if err != nil {
+					return fmt.Errorf("failed to list bastions: %w", err)
+				}
+			}
+			idx := slices.IndexFunc(bastions, func(b bastion.BastionSummary) bool {
+				return b.Name != nil && strings.EqualFold(*b.Name, ref)
+			})
+			if idx == -1 {
+				return fmt.Errorf("fallback bastion '%s' not found", ref)
+			}
+			id = *bastions[idx].Id
+		}
+
+		if (cluster.BastionId != nil && id == *cluster.BastionId) || slices.Contains(cluster.FallbackBastionIds, id) {
+			continue
+		}
+
+		b, err := ociClient.GetBastion(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to fetch fallback bastion '%s': %w", ref, err)
+		}
+		if b.BastionType != nil && *b.BastionType != "STANDARD" {
+			log.Warn().Msgf("Ignoring fallback bastion '%s': only standard bastions can fail over", ref)
+			continue
+		}
+
+		log.Info().Msgf("Fallback bastion '%s' with OCID: %s", ref, id)
+		cluster.FallbackBastionIds = append(cluster.FallbackBastionIds, id)
+	}
+
 	return nil
 }
 
@@ -72,7 +139,17 @@ func FindAvailablePort(startPort int) (int, error) {
 
 // SetClusterLocalPort sets or finds an available local port for the cluster.
 // If localPort is 0 or negative, it will find any available port (ephemeral allocation).
-func SetClusterLocalPort(cluster *config.Cluster, localPort int) {
+// A busy port is handled according to the cluster's port_strategy.
+func SetClusterLocalPort(cluster *config.Cluster, localPort int) error {
+	strategyName := ""
+	if cluster.PortStrategy != nil {
+		strategyName = *cluster.PortStrategy
+	}
+	strategy, err := ports.ParseStrategy(strategyName)
+	if err != nil {
+		return err
+	}
+
 	// Use cluster config port if command-line port not specified (default 6443)
 	if localPort <= 0 && cluster.LocalPort != nil && *cluster.LocalPort > 0 {
 		localPort = *cluster.LocalPort
@@ -86,16 +163,41 @@ func SetClusterLocalPort(cluster *config.Cluster, localPort int) {
 		}
 		cluster.LocalPort = &port
 		log.Info().Msgf("Using ephemeral port: %d", port)
-		return
+		return nil
 	}
 
-	// Find available port starting from the specified port
-	port, err := FindAvailablePort(localPort)
+	port, err := resolveBusyPort(localPort, strategy, ports.Default())
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to find available port")
+		return err
 	}
 
 	cluster.LocalPort = &port
+	return nil
+}
+
+// resolveBusyPort returns the port to use in place of port according to
+// strategy.
+func resolveBusyPort(port int, strategy ports.Strategy, registry *ports.Registry) (int, error) {
+	if ports.IsAvailable(port) {
+		return port, nil
+	}
+
+	switch strategy {
+	case ports.StrategyFail:
+		if entry, _ := registry.Lookup(port); entry != nil {
+			return 0, fmt.Errorf("local port %d is in use by tunatap process %d (cluster %s); "+
+				"use --port-strategy takeover to replace it", port, entry.PID, entry.Cluster)
+		}
+		return 0, fmt.Errorf("local port %d is in use", port)
+	case ports.StrategyTakeover:
+		if err := registry.Takeover(port); err != nil {
+			return 0, err
+		}
+		return port, nil
+	default:
+		// Find available port starting from the specified port
+		return FindAvailablePort(port)
+	}
 }
 
 // FindEphemeralPort finds any available TCP port on localhost.
@@ -132,7 +234,9 @@ func SetClusterOcid(ctx context.Context, ociClient *client.OCIClient, cluster *c
 			return fmt.Errorf("compartment and tenant must be set for the cluster if the cluster id is not set")
 		}
 
-		if cluster.CompartmentOcid == nil {
+		if cluster.CompartmentOcid == nil && utils.IsCompartmentOCID(*cluster.Compartment) {
+			cluster.CompartmentOcid = utils.StringPtr(*cluster.Compartment)
+		} else if cluster.CompartmentOcid == nil {
 			compartmentID, err := ociClient.GetCompartmentIDByPath(ctx, *cluster.TenantOcid, *cluster.Compartment)
 			if err != nil {
 				return fmt.Errorf("failed to get compartment id: %w", err)
@@ -166,8 +270,14 @@ func SetClusterURL(cluster *config.Cluster) {
 	}
 }
 
-// GetClusterBastion finds and returns a bastion ID for the cluster.
+// GetClusterBastion finds and returns a bastion ID for the cluster. A
+// bastion given by OCID is looked up directly; one given by name is looked
+// for in the cluster's compartment.
 func GetClusterBastion(ctx context.Context, ociClient *client.OCIClient, cluster *config.Cluster) (*string, error) {
+	if cluster.Bastion != nil && utils.IsBastionOCID(*cluster.Bastion) {
+		return assignBastionByOCID(ctx, ociClient, cluster)
+	}
+
 	if cluster.CompartmentOcid == nil {
 		return nil, fmt.Errorf("compartment OCID not set")
 	}
@@ -207,8 +317,41 @@ func assignBastionByName(cluster *config.Cluster, bastions []bastion.BastionSumm
 	return nil, fmt.Errorf("bastion '%s' not found", *cluster.Bastion)
 }
 
+// overridesBastionID reports whether the cluster's bastion is given by an
+// OCID other than its bastion ID.
+func overridesBastionID(cluster *config.Cluster) bool {
+	return cluster.Bastion != nil && utils.IsBastionOCID(*cluster.Bastion) &&
+		(cluster.BastionId == nil || *cluster.BastionId != *cluster.Bastion)
+}
+
+// assignBastionByOCID looks up the bastion the cluster's bastion OCID names,
+// which must be in the cluster's region.
+func assignBastionByOCID(ctx context.Context, ociClient *client.OCIClient, cluster *config.Cluster) (*string, error) {
+	b, err := discovery.NewDiscoverer(ociClient, nil).DiscoverBastionByOCID(ctx, *cluster.Bastion)
+	if err != nil {
+		return nil, err
+	}
+
+	bastionRegion := utils.ExtractRegionFromOCID(b.OCID)
+	if common.StringToRegion(bastionRegion) != common.StringToRegion(cluster.Region) {
+		return nil, fmt.Errorf("bastion '%s' is in region %s, but cluster '%s' is in region %s",
+			b.Name, bastionRegion, cluster.ClusterName, cluster.Region)
+	}
+
+	log.Info().Msgf("Found bastion '%s' with OCID: %s", b.Name, b.OCID)
+	return &b.OCID, nil
+}
+
 // allowUserToSelectBastion prompts the user to select a bastion interactively.
 func allowUserToSelectBastion(_ *config.Cluster, bastions []bastion.BastionSummary) (*string, error) {
+	if !ui.CanPrompt() {
+		names := make([]string, len(bastions))
+		for i, b := range bastions {
+			names[i] = *b.Name
+		}
+		return nil, ui.PromptError("choosing a bastion", "pass --bastion or set bastion for the cluster in config to one of: "+strings.Join(names, ", "))
+	}
+
 	f, err := fzf.New()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize fuzzy finder: %w", err)
@@ -216,7 +359,7 @@ func allowUserToSelectBastion(_ *config.Cluster, bastions []bastion.BastionSumma
 
 	idxs, err := f.Find(bastions, func(i int) string { return *bastions[i].Name })
 	if err != nil || len(idxs) == 0 {
-		return nil, fmt.Errorf("no bastion selected")
+		return nil, exitcode.Wrap(exitcode.Cancelled, fmt.Errorf("no bastion selected"))
 	}
 
 	return bastions[idxs[0]].Id, nil
@@ -263,6 +406,9 @@ func GetClusterInfo(cluster *config.Cluster) map[string]interface{} {
 				"ip":   ep.Ip,
 				"port": ep.Port,
 			}
+			if ep.Fqdn != "" {
+				endpoints[i]["fqdn"] = ep.Fqdn
+			}
 		}
 		info["endpoints"] = endpoints
 	}
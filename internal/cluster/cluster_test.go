@@ -2,9 +2,11 @@ package cluster
 
 import (
 	"context"
+	"net"
 	"testing"
 
 	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/ports"
 	"github.com/scotttball/tunatap/pkg/utils"
 )
 
@@ -290,6 +292,29 @@ func TestAssignBastionByName(t *testing.T) {
 	t.Log("assignBastionByName test - requires OCI SDK mocking")
 }
 
+func TestOverridesBastionID(t *testing.T) {
+	discovered := "ocid1.bastion.oc1.iad.discovered"
+	given := "ocid1.bastion.oc1.iad.given"
+	tests := []struct {
+		name    string
+		cluster *config.Cluster
+		want    bool
+	}{
+		{"no bastion", &config.Cluster{BastionId: &discovered}, false},
+		{"by name", &config.Cluster{Bastion: utils.StringPtr("jump"), BastionId: &discovered}, false},
+		{"same OCID", &config.Cluster{Bastion: &discovered, BastionId: &discovered}, false},
+		{"other OCID", &config.Cluster{Bastion: &given, BastionId: &discovered}, true},
+		{"OCID without ID", &config.Cluster{Bastion: &given}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overridesBastionID(tt.cluster); got != tt.want {
+				t.Errorf("overridesBastionID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateAndUpdateClusterMinimal(t *testing.T) {
 	// This test requires an OCI client, so we can't fully test it
 	// Just verify it handles nil inputs gracefully
@@ -320,3 +345,57 @@ func TestUtilsIntegration(t *testing.T) {
 		t.Error("utils.StringPtr integration failed")
 	}
 }
+
+func TestResolveBusyPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	busy := ln.Addr().(*net.TCPAddr).Port
+	registry := ports.NewRegistry(t.TempDir())
+
+	port, err := resolveBusyPort(busy, ports.StrategyIncrement, registry)
+	if err != nil {
+		t.Fatalf("resolveBusyPort(increment) error = %v", err)
+	}
+	if port <= busy {
+		t.Errorf("resolveBusyPort(increment) = %d, want a port above %d", port, busy)
+	}
+
+	if _, err := resolveBusyPort(busy, ports.StrategyFail, registry); err == nil {
+		t.Error("resolveBusyPort(fail) should error for a busy port")
+	}
+
+	if _, err := resolveBusyPort(busy, ports.StrategyTakeover, registry); err == nil {
+		t.Error("resolveBusyPort(takeover) should error for a port tunatap does not hold")
+	}
+}
+
+func TestSetClusterLocalPortInvalidStrategy(t *testing.T) {
+	cluster := &config.Cluster{PortStrategy: utils.StringPtr("kill")}
+	if err := SetClusterLocalPort(cluster, 6443); err == nil {
+		t.Error("SetClusterLocalPort() should reject an unknown port strategy")
+	}
+}
+
+func TestNeedsDiscovery(t *testing.T) {
+	cfg := &config.Config{
+		Clusters: []*config.Cluster{{ClusterName: "prod"}},
+	}
+
+	if NeedsDiscovery(cfg, true, "PROD") {
+		t.Error("NeedsDiscovery() = true for a configured cluster, want false")
+	}
+	if !NeedsDiscovery(cfg, true, "staging") {
+		t.Error("NeedsDiscovery() = false for an unknown cluster, want true")
+	}
+	if !NeedsDiscovery(cfg, false, "prod") {
+		t.Error("NeedsDiscovery() = false without a config file, want true")
+	}
+
+	cfg.SkipDiscovery = true
+	if !NeedsDiscovery(cfg, true, "prod") {
+		t.Error("NeedsDiscovery() = false with skip_discovery, want true")
+	}
+}
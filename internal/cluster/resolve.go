@@ -0,0 +1,371 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/discovery"
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"github.com/scotttball/tunatap/internal/kubeconfig"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// Resolve finds the named cluster in config or, failing that, through
+// discovery. The OCI client is only returned when discovery created one.
+func Resolve(ctx context.Context, cfg *config.Config, cfgLoaded bool, name, region string, skipCache bool) (*config.Cluster, *client.OCIClient, error) {
+	return ResolveResource(ctx, cfg, cfgLoaded, name, region, skipCache, discovery.ResourceCluster)
+}
+
+// ResolveResource is Resolve for a private resource of the given type, such
+// as a database. Only clusters are looked up in config; other resources are
+// always discovered, and returned as a cluster whose endpoint is theirs.
+func ResolveResource(ctx context.Context, cfg *config.Config, cfgLoaded bool, name, region string, skipCache bool, resourceType discovery.ResourceType) (*config.Cluster, *client.OCIClient, error) {
+	if name == "" {
+		return nil, nil, fmt.Errorf("cluster name is required")
+	}
+
+	// Try to find cluster in config first (if we have a config)
+	if resourceType == discovery.ResourceCluster && !NeedsDiscovery(cfg, cfgLoaded, name) {
+		return config.FindClusterByName(cfg, name), nil, nil
+	}
+
+	return discover(ctx, cfg, name, region, skipCache, resourceType)
+}
+
+// NewDiscoveryHints returns the hints for discovering a cluster in region,
+// or in any region if it is empty, with the discovery settings of cfg.
+func NewDiscoveryHints(cfg *config.Config, region string) (*discovery.DiscoveryHints, error) {
+	method, err := discovery.ParseMethod(cfg.DiscoveryMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	hints := &discovery.DiscoveryHints{Region: region, Method: method}
+	filter := &discovery.CompartmentFilter{
+		Include:  cfg.DiscoveryIncludeCompartments,
+		Exclude:  cfg.DiscoveryExcludeCompartments,
+		MaxDepth: cfg.GetDiscoveryMaxDepth(),
+	}
+	if !filter.IsZero() {
+		hints.Compartments = filter
+	}
+	return hints, nil
+}
+
+// NeedsDiscovery reports whether Resolve will look the named cluster up in
+// OCI rather than in config.
+func NeedsDiscovery(cfg *config.Config, cfgLoaded bool, name string) bool {
+	if !cfgLoaded || cfg.SkipDiscovery {
+		return true
+	}
+	return config.FindClusterByName(cfg, name) == nil
+}
+
+// discover locates a cluster and its bastion in OCI by name or OCID.
+func discover(ctx context.Context, cfg *config.Config, name, region string, skipCache bool, resourceType discovery.ResourceType) (*config.Cluster, *client.OCIClient, error) {
+	hints, err := NewDiscoveryHints(cfg, region)
+	if err != nil {
+		return nil, nil, err
+	}
+	hints.Type = resourceType
+
+	ociClient, discoverer, err := openDiscovery(ctx, cfg, skipCache)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var discovered *discovery.DiscoveredCluster
+
+	// Check if the input is an OCID - use direct lookup if so
+	if resourceType == discovery.ResourceCluster && discovery.IsClusterOCID(name) {
+		log.Info().Msgf("Detected cluster OCID, performing direct lookup...")
+		discovered, err = discoverer.DiscoverClusterByOCID(ctx, name)
+		if err != nil {
+			// Error messages from DiscoverClusterByOCID are already well-formatted
+			return nil, nil, err
+		}
+	} else {
+		if resourceType == discovery.ResourceCluster {
+			log.Info().Msgf("Cluster '%s' not found in config, attempting discovery...", name)
+		}
+
+		// Perform name-based discovery
+		discovered, err = discoverer.DiscoverClusterWithHints(ctx, name, hints)
+		if err != nil {
+			discovered, err = ChooseDiscoveredCluster(ctx, discoverer, hints, err)
+		}
+		if err != nil {
+			// Check if multiple clusters found - offer interactive selection
+			if errors.Is(err, discovery.ErrMultipleClustersFound) {
+				return nil, nil, err // The error message already contains all matches
+			}
+
+			// Provide better error messages for common failures
+			var notFound *discovery.ClusterNotFoundError
+			if errors.As(err, &notFound) && len(notFound.Suggestions) > 0 {
+				return nil, nil, exitcode.Wrap(exitcode.NotFound, fmt.Errorf("cluster '%s' not found. Similar clusters:\n%s\n\n"+
+					"Use the full name, or run on a terminal to choose one", name, discovery.FormatSuggestions(notFound.Suggestions)))
+			}
+			if errors.Is(err, discovery.ErrClusterNotFound) && resourceType != discovery.ResourceCluster {
+				return nil, nil, exitcode.Wrap(exitcode.NotFound, fmt.Errorf("%s '%s' not found\n\n"+
+					"If it exists, you may need to:\n"+
+					"  - Check that you have IAM policies to list it\n"+
+					"  - Specify the region with --region if searching is slow", resourceType, name))
+			}
+			if errors.Is(err, discovery.ErrClusterNotFound) {
+				return nil, nil, exitcode.Wrap(exitcode.NotFound, fmt.Errorf("cluster '%s' not found\n\n"+
+					"To find available clusters, try:\n"+
+					"  tunatap list\n\n"+
+					"If the cluster exists, you may need to:\n"+
+					"  - Check that you have IAM policies to list clusters\n"+
+					"  - Specify the region with --region if searching is slow\n"+
+					"  - Use the cluster OCID directly instead of the name", name))
+			}
+
+			if errors.Is(err, discovery.ErrClusterAccessDenied) {
+				return nil, nil, err // Already has good suggestion
+			}
+
+			// Check for auth errors
+			ociErr := client.ClassifyOCIError(err, "cluster discovery")
+			if ociErr.Type == client.ErrorTypeNotAuthenticated {
+				return nil, nil, exitcode.Wrap(exitcode.Auth, fmt.Errorf("authentication failed during discovery\n\n%s", ociErr.Suggestion))
+			}
+
+			return nil, nil, fmt.Errorf("discovery failed: %w", err)
+		}
+	}
+
+	return resolveDiscovered(ctx, discoverer, ociClient, discovered)
+}
+
+// ResolveTagged finds the cluster, or resource of the given type, carrying
+// every one of tags through discovery, letting the user choose on the
+// terminal when several do.
+func ResolveTagged(ctx context.Context, cfg *config.Config, tags []discovery.TagSelector, region string, skipCache bool, resourceType discovery.ResourceType) (*config.Cluster, *client.OCIClient, error) {
+	hints, err := NewDiscoveryHints(cfg, region)
+	if err != nil {
+		return nil, nil, err
+	}
+	hints.Type = resourceType
+
+	ociClient, discoverer, err := openDiscovery(ctx, cfg, skipCache)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	discovered, err := discoverer.DiscoverClusterByTags(ctx, tags, hints)
+	if err != nil {
+		discovered, err = ChooseDiscoveredCluster(ctx, discoverer, hints, err)
+	}
+	if err != nil {
+		if errors.Is(err, discovery.ErrMultipleClustersFound) {
+			return nil, nil, err
+		}
+		if errors.Is(err, discovery.ErrClusterNotFound) {
+			return nil, nil, exitcode.Wrap(exitcode.NotFound, fmt.Errorf("no %s tagged %s found\n\n"+
+				"Tags are matched with OCI Resource Search, which may lag tag changes by a few minutes.\n"+
+				"If the resource exists, you may need to:\n"+
+				"  - Check the tag keys and values; values are case-sensitive\n"+
+				"  - Check that you have IAM policies to inspect it", resourceType, discovery.FormatTags(tags)))
+		}
+		ociErr := client.ClassifyOCIError(err, "cluster discovery")
+		if ociErr.Type == client.ErrorTypeNotAuthenticated {
+			return nil, nil, exitcode.Wrap(exitcode.Auth, fmt.Errorf("authentication failed during discovery\n\n%s", ociErr.Suggestion))
+		}
+		return nil, nil, fmt.Errorf("discovery failed: %w", err)
+	}
+
+	return resolveDiscovered(ctx, discoverer, ociClient, discovered)
+}
+
+// ResolveAllTagged finds every cluster carrying all of tags through
+// discovery, with its bastion. Clusters in different regions cannot share
+// one OCI client, so none is returned; callers create one per cluster.
+func ResolveAllTagged(ctx context.Context, cfg *config.Config, tags []discovery.TagSelector, region string, skipCache bool) ([]*config.Cluster, error) {
+	hints, err := NewDiscoveryHints(cfg, region)
+	if err != nil {
+		return nil, err
+	}
+
+	ociClient, discoverer, err := openDiscovery(ctx, cfg, skipCache)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered, err := discoverer.DiscoverClustersByTags(ctx, tags, hints)
+	if err != nil {
+		if errors.Is(err, discovery.ErrClusterNotFound) {
+			return nil, exitcode.Wrap(exitcode.NotFound, fmt.Errorf("no cluster tagged %s found", discovery.FormatTags(tags)))
+		}
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+
+	clusters := make([]*config.Cluster, 0, len(discovered))
+	for _, d := range discovered {
+		c, _, err := resolveDiscovered(ctx, discoverer, ociClient, d)
+		if err != nil {
+			return nil, fmt.Errorf("cluster '%s': %w", d.Name, err)
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters, nil
+}
+
+// openDiscovery creates an OCI client for discovery and a discoverer using
+// it and, unless skipCache is set, the discovery cache. The discoverer
+// records into the profile carried by ctx, if any.
+func openDiscovery(ctx context.Context, cfg *config.Config, skipCache bool) (*client.OCIClient, *discovery.Discoverer, error) {
+	// Create OCI client with auto-detection for discovery
+	ociClient, err := NewDiscoveryClient(cfg)
+	if err != nil {
+		ociErr := client.ClassifyOCIError(err, "create OCI client")
+		if ociErr.Suggestion != "" {
+			return nil, nil, exitcode.Wrap(exitcode.Auth, fmt.Errorf("failed to create OCI client: %s\n\n%s", ociErr.Message, ociErr.Suggestion))
+		}
+		return nil, nil, exitcode.Wrap(exitcode.Auth, fmt.Errorf("failed to create OCI client: %w", err))
+	}
+
+	// Initialize cache
+	var cache *discovery.Cache
+	if !skipCache {
+		cache = NewDiscoveryCache(cfg)
+	}
+
+	discoverer := NewDiscoverer(ociClient, cache)
+	if profile := discovery.ProfileFromContext(ctx); profile != nil {
+		discoverer.SetProfile(profile)
+	}
+	return ociClient, discoverer, nil
+}
+
+// NewDiscoverer returns a discoverer using ociClient and cache, which may be
+// nil, that searches first the regions the user's kubeconfig and OCI CLI
+// config point at.
+func NewDiscoverer(ociClient client.OCIClientInterface, cache *discovery.Cache) *discovery.Discoverer {
+	discoverer := discovery.NewDiscoverer(ociClient, cache)
+	discoverer.SetKubeconfigPaths(kubeconfig.DefaultPaths())
+	return discoverer
+}
+
+// resolveDiscovered finds the bastion of a discovered cluster and returns
+// both as a config cluster, with the OCI client for the cluster's region.
+func resolveDiscovered(ctx context.Context, discoverer *discovery.Discoverer, ociClient *client.OCIClient, discovered *discovery.DiscoveredCluster) (*config.Cluster, *client.OCIClient, error) {
+	// Discover bastion
+	bastionInfo, err := discoverer.DiscoverBastion(ctx, discovered)
+	if err != nil {
+		if errors.Is(err, discovery.ErrNoBastionFound) {
+			return nil, nil, &NoBastionError{Cluster: discovered}
+		}
+
+		ociErr := client.ClassifyOCIError(err, "bastion discovery")
+		if ociErr.Suggestion != "" {
+			return nil, nil, exitcode.Wrap(exitcode.BastionUnavailable, fmt.Errorf("failed to discover bastion: %s\n\n%s", ociErr.Message, ociErr.Suggestion))
+		}
+		return nil, nil, exitcode.Wrap(exitcode.BastionUnavailable, fmt.Errorf("failed to discover bastion: %w", err))
+	}
+
+	// Convert to config.Cluster
+	selectedCluster, err := discoverer.ResolveToConfig(discovered, bastionInfo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve cluster config: %w", err)
+	}
+
+	return selectedCluster, ociClient.WithRegion(discovered.Region), nil
+}
+
+// NoBastionError is returned by Resolve when a discovered cluster has no
+// usable bastion. It wraps discovery.ErrNoBastionFound.
+type NoBastionError struct {
+	// Cluster is the discovered cluster, from which a bastion can be created.
+	Cluster *discovery.DiscoveredCluster
+}
+
+func (e *NoBastionError) Error() string {
+	return fmt.Sprintf("no bastion found for cluster '%s'\n\n"+
+		"A bastion is required to connect to private OKE clusters.\n"+
+		"Please ensure:\n"+
+		"  1. A bastion exists in the cluster's compartment\n"+
+		"  2. The bastion is in ACTIVE state\n"+
+		"  3. You have IAM policies to read bastions\n\n"+
+		"To create a bastion, run:\n"+
+		"  tunatap bastion create %s --allow-cidr <your-ip>/32\n"+
+		"or pass --create-bastion to connect, or visit the OCI Console:\n"+
+		"  https://cloud.oracle.com/bastion", e.Cluster.Name, e.Cluster.Name)
+}
+
+func (e *NoBastionError) Unwrap() error {
+	return discovery.ErrNoBastionFound
+}
+
+// NewDiscoveryCache opens the discovery cache with the TTLs and encryption
+// setting of cfg. It returns nil if the cache cannot be opened.
+func NewDiscoveryCache(cfg *config.Config) *discovery.Cache {
+	cache, err := discovery.OpenCache(cfg)
+	if err != nil {
+		return nil
+	}
+	return cache
+}
+
+// NewDiscoveryClient creates an OCI client for discovery operations.
+// Uses auto-detection of authentication without requiring config values.
+func NewDiscoveryClient(cfg *config.Config) (*client.OCIClient, error) {
+	configPath := cfg.OCIConfigPath
+	if configPath == "" {
+		configPath = utils.DefaultOCIConfigPath()
+	}
+
+	profile := cfg.OCIProfile
+	if profile == "" {
+		profile = "DEFAULT"
+	}
+
+	ociClient, err := client.NewOCIClientAuto(configPath, profile)
+	if err != nil {
+		return nil, err
+	}
+	ociClient.SetRetryPolicy(client.RetryPolicyFromConfig(cfg.OCIRetry))
+	return ociClient, nil
+}
+
+// NewOCIClient creates an OCI client for region using the auth type, config
+// file and profile from cfg.
+func NewOCIClient(cfg *config.Config, region string) (*client.OCIClient, error) {
+	return NewClusterOCIClient(cfg, nil, region)
+}
+
+// NewClusterOCIClient creates an OCI client for region with the credentials
+// configured for c: its oci_auth_type and oci_profile, else those of its
+// tenancy, else those of cfg. c may be nil for the latter.
+func NewClusterOCIClient(cfg *config.Config, c *config.Cluster, region string) (*client.OCIClient, error) {
+	// Determine auth type
+	authType := client.AuthTypeAuto
+	if t := cfg.GetOCIAuthType(c); t != "" {
+		authType = client.AuthType(t)
+	}
+
+	// Determine config path and profile
+	configPath := cfg.OCIConfigPath
+	if configPath == "" {
+		configPath = utils.DefaultOCIConfigPath()
+	}
+
+	profile := cfg.GetOCIProfile(c)
+	if profile == "" {
+		profile = "DEFAULT"
+	}
+
+	// Create client with appropriate auth type
+	ociClient, err := client.NewOCIClientWithAuthType(authType, configPath, profile)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Auth, err)
+	}
+
+	ociClient.SetRetryPolicy(client.RetryPolicyFromConfig(cfg.OCIRetry))
+	return ociClient.WithRegion(region), nil
+}
@@ -1,7 +1,13 @@
 package config
 
+import "strings"
+
 // Config represents the main application configuration.
 type Config struct {
+	// Version is the version of the config file layout. Files of older
+	// versions are migrated when they are loaded.
+	Version int `yaml:"version,omitempty"`
+
 	// Tenancies maps tenancy names to their OCIDs (legacy format).
 	Tenancies map[string]*string `yaml:"tenancies,omitempty"`
 
@@ -11,6 +17,18 @@ type Config struct {
 	// Clusters is a list of cluster configurations.
 	Clusters []*Cluster `yaml:"clusters,omitempty"`
 
+	// Defaults are settings every cluster inherits unless it sets its own.
+	Defaults *ClusterDefaults `yaml:"defaults,omitempty"`
+
+	// Profiles are named sets of forwards and kubeconfig settings that can be
+	// applied to a tunnel with --profile.
+	Profiles []*Profile `yaml:"profiles,omitempty"`
+
+	// Includes are glob patterns of files, relative to the directory of the
+	// config file, whose clusters, profiles and tenancies are added to it.
+	// The files in clusters.d beside the config file are always included.
+	Includes []string `yaml:"includes,omitempty"`
+
 	// CatalogSources is a list of remote catalog sources for team config sharing.
 	CatalogSources []*CatalogSource `yaml:"catalog_sources,omitempty"`
 
@@ -20,9 +38,19 @@ type Config struct {
 	// SshPrivateKeyFile is the path to the SSH private key for bastion connections.
 	SshPrivateKeyFile string `yaml:"ssh_private_key_file,omitempty"`
 
+	// SshPrivateKeyPassphrase decrypts SshPrivateKeyFile when it is
+	// encrypted. Like the other secrets, it can be a vault: or keychain:
+	// reference resolved when it is used.
+	SshPrivateKeyPassphrase string `yaml:"ssh_private_key_passphrase,omitempty"`
+
 	// SshSocksProxy is an optional SOCKS proxy address for SSH connections.
 	SshSocksProxy string `yaml:"ssh_socks_proxy,omitempty"`
 
+	// SshSocksProxyUser and SshSocksProxyPassword authenticate to the SOCKS
+	// proxy. The password can be a secret reference.
+	SshSocksProxyUser     string `yaml:"ssh_socks_proxy_user,omitempty"`
+	SshSocksProxyPassword string `yaml:"ssh_socks_proxy_password,omitempty"`
+
 	// SshConnectionPoolSize is the maximum number of SSH connections in the pool.
 	SshConnectionPoolSize *int `yaml:"ssh_connection_pool_size,omitempty"`
 
@@ -32,8 +60,26 @@ type Config struct {
 	// SshConnectionMaxConcurrentUse is the max concurrent uses per SSH connection.
 	SshConnectionMaxConcurrentUse *int `yaml:"ssh_connection_max_concurrent_use,omitempty"`
 
+	// SshConnectionPoolAutoscale lets the pool grow and shrink with demand.
+	// When set, its max replaces SshConnectionPoolSize.
+	SshConnectionPoolAutoscale *PoolAutoscale `yaml:"ssh_connection_pool_autoscale,omitempty"`
+
+	// SshKeepaliveInterval is the interval in seconds between keepalives sent
+	// on pooled SSH connections. 0 disables keepalives. Default: 30.
+	SshKeepaliveInterval *int `yaml:"ssh_keepalive_interval,omitempty"`
+
+	// SshKeepaliveMax is the number of unanswered keepalives after which a
+	// pooled SSH connection is closed. Default: 3.
+	SshKeepaliveMax *int `yaml:"ssh_keepalive_max,omitempty"`
+
+	// SshHostKeyPolicy decides how unknown bastion host keys are handled:
+	// "prompt" (default) asks on the terminal, "accept-new" records them
+	// without asking, "strict" rejects them. Changed keys are always rejected.
+	SshHostKeyPolicy string `yaml:"ssh_host_key_policy,omitempty"`
+
 	// OCIAuthType specifies the OCI authentication type.
-	// Options: "auto", "config", "instance_principal", "security_token", "resource_principal"
+	// Options: "auto", "config", "instance_principal", "security_token", "resource_principal",
+	// "oke_workload_identity"
 	OCIAuthType string `yaml:"oci_auth_type,omitempty"`
 
 	// OCIConfigPath is the path to the OCI config file.
@@ -42,32 +88,194 @@ type Config struct {
 	// OCIProfile is the profile to use from the OCI config file.
 	OCIProfile string `yaml:"oci_profile,omitempty"`
 
+	// IdleTimeoutMinutes shuts down or shrinks a tunnel after this many
+	// minutes without traffic. Default: 0 (disabled).
+	IdleTimeoutMinutes *int `yaml:"idle_timeout_minutes,omitempty"`
+
+	// IdleAction is what happens to an idle tunnel: "shutdown" (default) or
+	// "shrink" to drop pooled SSH connections until the next request.
+	IdleAction string `yaml:"idle_action,omitempty"`
+
+	// EndpointWatchIntervalSeconds is how often a running tunnel re-fetches
+	// its cluster's private endpoint and bastion state. Default: 0
+	// (disabled).
+	EndpointWatchIntervalSeconds *int `yaml:"endpoint_watch_interval_seconds,omitempty"`
+
+	// EndpointChangeAction is what a watched tunnel does when its cluster's
+	// private endpoint moves: "warn" (default) or "reconnect" to tunnel to
+	// the new endpoint.
+	EndpointChangeAction string `yaml:"endpoint_change_action,omitempty"`
+
 	// Zero-Touch settings
 
 	// UseEphemeralKeys enables ephemeral in-memory SSH keys (never written to disk).
 	// Default: true when SshPrivateKeyFile is not set.
 	UseEphemeralKeys bool `yaml:"use_ephemeral_keys,omitempty"`
 
+	// EphemeralKeyAlgorithm is the algorithm of ephemeral keys: "ed25519"
+	// (default) or "rsa-4096".
+	EphemeralKeyAlgorithm string `yaml:"ephemeral_key_algorithm,omitempty"`
+
+	// EphemeralKeyRotationHours reuses an ephemeral key for new sessions
+	// until it is this many hours old. Default: 0 (a new key for every
+	// session).
+	EphemeralKeyRotationHours *int `yaml:"ephemeral_key_rotation_hours,omitempty"`
+
 	// CacheTTLHours is the cache TTL in hours for discovered cluster mappings.
 	// Default: 24 hours.
 	CacheTTLHours *int `yaml:"cache_ttl_hours,omitempty"`
 
+	// NegativeCacheTTLMinutes is how long discovery remembers that a cluster
+	// name was not found. Default: 10 minutes; 0 disables it.
+	NegativeCacheTTLMinutes *int `yaml:"negative_cache_ttl_minutes,omitempty"`
+
+	// CacheEncryption encrypts the discovery, catalog and token caches with
+	// a key kept in the OS keychain.
+	CacheEncryption bool `yaml:"cache_encryption,omitempty"`
+
 	// SkipDiscovery disables auto-discovery of clusters not in config.
 	SkipDiscovery bool `yaml:"skip_discovery,omitempty"`
 
+	// SaveDiscoveredClusters is whether clusters found by discovery when
+	// connecting are added to the config file: "never" (default), "prompt"
+	// to ask on a terminal, or "always".
+	SaveDiscoveredClusters string `yaml:"save_discovered_clusters,omitempty"`
+
 	// DiscoveryRegions specifies which regions to search during discovery.
 	// If empty, all subscribed regions are searched.
 	DiscoveryRegions []string `yaml:"discovery_regions,omitempty"`
 
+	// DiscoveryMethod is how clusters are found by name: "compartments"
+	// (default) lists each compartment's clusters, "search" uses OCI
+	// Resource Search with one query per region.
+	DiscoveryMethod string `yaml:"discovery_method,omitempty"`
+
+	// DiscoveryIncludeCompartments limits the compartment walk of discovery
+	// to these compartments and their descendants, given by OCID, name or
+	// path such as "root/prod". Wildcards are allowed.
+	DiscoveryIncludeCompartments []string `yaml:"discovery_include_compartments,omitempty"`
+
+	// DiscoveryExcludeCompartments are compartments, and their descendants,
+	// that discovery never lists, given like DiscoveryIncludeCompartments.
+	DiscoveryExcludeCompartments []string `yaml:"discovery_exclude_compartments,omitempty"`
+
+	// DiscoveryMaxDepth is how deep below the tenancy discovery walks the
+	// compartment tree. Default: 0 (no limit).
+	DiscoveryMaxDepth *int `yaml:"discovery_max_depth,omitempty"`
+
+	// BastionAllowCidrs are the client CIDR blocks allowed to connect to
+	// bastions that tunatap creates.
+	BastionAllowCidrs []string `yaml:"bastion_allow_cidrs,omitempty"`
+
+	// BastionTTLHours marks bastions that tunatap creates to expire after
+	// this many hours. Default: 0 (never).
+	BastionTTLHours *int `yaml:"bastion_ttl_hours,omitempty"`
+
+	// PublicIPURL is an HTTP endpoint that returns the caller's public IP
+	// address as plain text, used to check bastion client allowlists.
+	// Default: https://checkip.amazonaws.com
+	PublicIPURL string `yaml:"public_ip_url,omitempty"`
+
+	// SessionTTLMinutes is the lifetime of the bastion sessions tunatap
+	// creates, between 30 and 180 minutes. Default: 180.
+	SessionTTLMinutes *int `yaml:"session_ttl_minutes,omitempty"`
+
+	// SessionNameTemplate is the display name of the bastion sessions
+	// tunatap creates. {cluster}, {target} and {user} are replaced by the
+	// cluster name, the session target and the local user name. Default:
+	// "{target}".
+	SessionNameTemplate string `yaml:"session_name_template,omitempty"`
+
+	// SessionWaitTimeoutSeconds is how long to wait for a new bastion
+	// session to become active. Default: 300.
+	SessionWaitTimeoutSeconds *int `yaml:"session_wait_timeout_seconds,omitempty"`
+
+	// SessionWaitPollSeconds is how often a new session's state is checked
+	// while waiting for it. Default: 3.
+	SessionWaitPollSeconds *int `yaml:"session_wait_poll_seconds,omitempty"`
+
+	// Retry configures how a failed tunnel is retried.
+	Retry *RetryPolicy `yaml:"retry,omitempty"`
+
 	// Monitoring settings
 
 	// HealthEndpoint is the address for the health HTTP server (e.g., "localhost:9090").
 	// If set, enables health/metrics endpoints.
 	HealthEndpoint string `yaml:"health_endpoint,omitempty"`
 
+	// DesktopNotifications shows OS notifications when a tunnel run by the
+	// daemon drops, reconnects, stops with an error or fails to refresh its
+	// bastion session.
+	DesktopNotifications bool `yaml:"desktop_notifications,omitempty"`
+
 	// AuditLogging enables audit logging of tunnel connect/disconnect events.
 	// Default: true
 	AuditLogging *bool `yaml:"audit_logging,omitempty"`
+
+	// AuditHashChain chains each record of the local audit log to the one
+	// before it by its hash and signs checkpoints of the chain with a local
+	// key, so that 'audit verify' detects changed or removed records.
+	AuditHashChain bool `yaml:"audit_hash_chain,omitempty"`
+
+	// AuditSinks are where audit events are also sent, besides the local
+	// audit log, such as a webhook or an OCI Logging log.
+	AuditSinks []*AuditSink `yaml:"audit_sinks,omitempty"`
+
+	// Tracing exports OpenTelemetry traces of connecting over OTLP, showing
+	// where the time of a connect goes.
+	Tracing *Tracing `yaml:"tracing,omitempty"`
+
+	// OCIRetry configures how calls to the OCI API are retried when they
+	// are throttled or fail on the service's side.
+	OCIRetry *OCIRetry `yaml:"oci_retry,omitempty"`
+
+	// LogFile configures the log file every command writes its detailed
+	// log to, whatever the console shows.
+	LogFile *LogFile `yaml:"log_file,omitempty"`
+
+	// UpdateCheck enables the daily check for a newer tunatap release.
+	// Default: true
+	UpdateCheck *bool `yaml:"update_check,omitempty"`
+
+	// StrictConfig makes problems found in the config file, such as unknown
+	// keys or invalid OCIDs, fail loading it rather than only being logged.
+	StrictConfig bool `yaml:"strict_config,omitempty"`
+}
+
+// PoolAutoscale configures dynamic sizing of the SSH connection pool.
+type PoolAutoscale struct {
+	// Min is the number of connections kept open when idle. Default: 1.
+	Min *int `yaml:"min,omitempty"`
+
+	// Max is the most connections the pool may open. Default: 20.
+	Max *int `yaml:"max,omitempty"`
+
+	// ScaleUpCooldownSeconds is the minimum time between connections added
+	// ahead of demand. Default: 5.
+	ScaleUpCooldownSeconds *int `yaml:"scale_up_cooldown_seconds,omitempty"`
+
+	// ScaleDownCooldownSeconds is how long a connection must be idle before
+	// it is closed. Default: 120.
+	ScaleDownCooldownSeconds *int `yaml:"scale_down_cooldown_seconds,omitempty"`
+}
+
+// RetryPolicy configures the exponential backoff between attempts to
+// re-establish a failed tunnel. Errors that retrying cannot fix, such as
+// missing permissions, end the tunnel without retrying.
+type RetryPolicy struct {
+	// InitialIntervalSeconds is the wait before the first retry. Default: 5.
+	InitialIntervalSeconds *int `yaml:"initial_interval_seconds,omitempty"`
+
+	// MaxIntervalSeconds caps the wait between retries. Default: 120.
+	MaxIntervalSeconds *int `yaml:"max_interval_seconds,omitempty"`
+
+	// Multiplier is the factor the wait grows by after each retry.
+	// Default: 1.5.
+	Multiplier *float64 `yaml:"multiplier,omitempty"`
+
+	// MaxAttempts is the number of retries before giving up, 0 for
+	// unlimited. Default: 15.
+	MaxAttempts *int `yaml:"max_attempts,omitempty"`
 }
 
 // TenantInfo represents a tenancy configuration.
@@ -80,6 +288,18 @@ type TenantInfo struct {
 
 	// Namespace is the Object Storage namespace.
 	Namespace string `yaml:"namespace,omitempty"`
+
+	// BastionHost overrides the bastion service host name for clusters in
+	// this tenancy. "{region}" is replaced with the cluster's region.
+	BastionHost string `yaml:"bastion_host,omitempty"`
+
+	// OCIProfile overrides the top-level oci_profile for clusters in this
+	// tenancy.
+	OCIProfile string `yaml:"oci_profile,omitempty"`
+
+	// OCIAuthType overrides the top-level oci_auth_type for clusters in this
+	// tenancy.
+	OCIAuthType string `yaml:"oci_auth_type,omitempty"`
 }
 
 // CatalogSource represents a source for shared cluster catalogs.
@@ -107,6 +327,10 @@ type CatalogSource struct {
 
 	// Priority determines merge order (higher wins).
 	Priority int `yaml:"priority,omitempty"`
+
+	// AuthToken is sent as a bearer token when fetching an https source.
+	// It can be a secret reference.
+	AuthToken string `yaml:"auth_token,omitempty"`
 }
 
 // RemoteConfig specifies the OCI Object Storage location for remote configuration.
@@ -115,6 +339,113 @@ type RemoteConfig struct {
 	TenancyOcid string `yaml:"tenancy_ocid"`
 	Bucket      string `yaml:"bucket"`
 	Object      string `yaml:"object"`
+
+	// Keys are the top-level config keys 'tunatap config sync' shares with
+	// the remote config. Default: clusters, tenancies, tenancy_list and
+	// profiles.
+	Keys []string `yaml:"keys,omitempty"`
+}
+
+// AuditSink is a destination audit events are shipped to, in batches,
+// retrying while it cannot be reached.
+type AuditSink struct {
+	// Type is webhook, oci_logging or syslog.
+	Type string `yaml:"type"`
+
+	// URL is the HTTPS endpoint a webhook POSTs events to, as a JSON array.
+	URL string `yaml:"url,omitempty"`
+
+	// Headers are sent with each webhook request. Values may be secret
+	// references, such as an Authorization header kept in Vault.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// LogID is the OCID of the OCI Logging custom log to put events in.
+	LogID string `yaml:"log_id,omitempty"`
+
+	// Region of the log. Default: that of LogID.
+	Region string `yaml:"region,omitempty"`
+
+	// Network and Address of a remote syslog server, such as udp and
+	// logs.example.com:514. Default: the local syslog daemon.
+	Network string `yaml:"network,omitempty"`
+	Address string `yaml:"address,omitempty"`
+
+	// Tag of syslog messages. Default: tunatap.
+	Tag string `yaml:"tag,omitempty"`
+
+	// BufferSize is how many events are held while the sink cannot be
+	// reached before the oldest are dropped. Default: 1000.
+	BufferSize *int `yaml:"buffer_size,omitempty"`
+
+	// MaxRetries is how many times a batch is retried before it is
+	// dropped. Default: 5.
+	MaxRetries *int `yaml:"max_retries,omitempty"`
+}
+
+// Tracing configures the OTLP export of connect traces. The standard
+// OTEL_EXPORTER_OTLP_* environment variables configure it as well.
+type Tracing struct {
+	// Endpoint is the OTLP/HTTP URL of a collector, such as
+	// https://otel.example.com:4318. /v1/traces is added when it has no
+	// path.
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// Headers are sent with each export request. Values may be secret
+	// references, such as an API key kept in Vault.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// SampleRatio is the fraction of connects traced, between 0 and 1.
+	// Default: 1.
+	SampleRatio *float64 `yaml:"sample_ratio,omitempty"`
+}
+
+// OCIRetry configures the retries of OCI API calls that were rate limited
+// (429), failed with a server error (5xx) or got no response, and the
+// circuit breaking of API operations that keep failing.
+type OCIRetry struct {
+	// MaxAttempts is how many times a call is made before its error is
+	// returned, 1 for no retries. Default: 5.
+	MaxAttempts *int `yaml:"max_attempts,omitempty"`
+
+	// InitialIntervalSeconds is the wait before the first retry, doubling
+	// with each retry. Default: 1.
+	InitialIntervalSeconds *int `yaml:"initial_interval_seconds,omitempty"`
+
+	// MaxIntervalSeconds caps the wait between retries, including a wait
+	// asked for with Retry-After. Default: 30.
+	MaxIntervalSeconds *int `yaml:"max_interval_seconds,omitempty"`
+
+	// CircuitBreakerThreshold is how many requests of one API operation
+	// in a row must fail before further calls of it fail at once, 0 for
+	// never. Default: 10.
+	CircuitBreakerThreshold *int `yaml:"circuit_breaker_threshold,omitempty"`
+
+	// CircuitBreakerCooldownSeconds is how long calls of such an operation
+	// fail before it is tried again. Default: 30.
+	CircuitBreakerCooldownSeconds *int `yaml:"circuit_breaker_cooldown_seconds,omitempty"`
+}
+
+// LogFile configures the log file tunatap writes JSON logs to, at debug
+// level by default, so that the log of a failure can be looked at after the
+// fact. The file is rotated by size.
+type LogFile struct {
+	// Enabled turns the log file on or off. Default: true.
+	Enabled *bool `yaml:"enabled,omitempty"`
+
+	// Path is where the log file is written. Default:
+	// ~/.tunatap/logs/tunatap.log.
+	Path string `yaml:"path,omitempty"`
+
+	// Level is the lowest level written: trace, debug, info, warn or
+	// error. Default: debug.
+	Level string `yaml:"level,omitempty"`
+
+	// MaxSizeMB is the size in megabytes the file is rotated at.
+	// Default: 10.
+	MaxSizeMB *int `yaml:"max_size_mb,omitempty"`
+
+	// MaxBackups is how many rotated files are kept. Default: 5.
+	MaxBackups *int `yaml:"max_backups,omitempty"`
 }
 
 // Cluster represents a Kubernetes cluster configuration.
@@ -122,6 +453,14 @@ type Cluster struct {
 	// ClusterName is the display name of the cluster.
 	ClusterName string `yaml:"cluster_name"`
 
+	// Aliases are other names the cluster can be given by wherever a
+	// cluster name is accepted, such as a short name for a generated one.
+	Aliases []string `yaml:"aliases,omitempty"`
+
+	// Group puts the cluster in a group of clusters that commands such as
+	// exec and connect can be run against with --group.
+	Group string `yaml:"group,omitempty"`
+
 	// Region is the OCI region where the cluster is located.
 	Region string `yaml:"region"`
 
@@ -149,17 +488,197 @@ type Cluster struct {
 	// Bastion is the bastion name (for lookup).
 	Bastion *string `yaml:"bastion,omitempty"`
 
+	// FallbackBastions are bastion names or OCIDs, in the cluster's region,
+	// tried when the primary bastion cannot create a session. Standard
+	// bastions only.
+	FallbackBastions []string `yaml:"fallback_bastions,omitempty"`
+
+	// FallbackBastionIds are the resolved fallback bastion OCIDs.
+	FallbackBastionIds []string `yaml:"fallback_bastion_ids,omitempty"`
+
+	// BastionHost overrides the bastion service host name, for realms and
+	// dedicated regions whose DNS does not follow the usual pattern.
+	// "{region}" is replaced with the cluster's region.
+	BastionHost *string `yaml:"bastion_host,omitempty"`
+
+	// OCIProfile overrides the oci_profile of the cluster's tenancy and the
+	// top-level one, for clusters reached with other credentials.
+	OCIProfile *string `yaml:"oci_profile,omitempty"`
+
+	// OCIAuthType overrides the oci_auth_type of the cluster's tenancy and
+	// the top-level one.
+	OCIAuthType *string `yaml:"oci_auth_type,omitempty"`
+
 	// JumpBoxIP is the jump box IP for internal bastions.
 	JumpBoxIP *string `yaml:"jumpbox_ip,omitempty"`
 
 	// LocalPort is the local port for the tunnel.
 	LocalPort *int `yaml:"local_port,omitempty"`
 
+	// LocalSocket is a Unix domain socket path to listen on instead of LocalPort.
+	LocalSocket *string `yaml:"local_socket,omitempty"`
+
+	// BindAddress is the local IPv4 address the tunnel listens on.
+	// Default: localhost. Use 0.0.0.0 to accept connections from other hosts.
+	BindAddress *string `yaml:"bind_address,omitempty"`
+
+	// PortStrategy is what happens when LocalPort is in use: "increment"
+	// (default) tries the next port, "fail" errors, and "takeover" stops the
+	// tunatap process holding it.
+	PortStrategy *string `yaml:"port_strategy,omitempty"`
+
+	// MaxBandwidth caps the tunnel's total throughput (e.g. "10M" bytes/s).
+	MaxBandwidth *string `yaml:"max_bandwidth,omitempty"`
+
+	// MaxConnectionBandwidth caps each forwarded connection (e.g. "2M" bytes/s).
+	MaxConnectionBandwidth *string `yaml:"max_connection_bandwidth,omitempty"`
+
+	// SessionTTLMinutes overrides session_ttl_minutes for this cluster.
+	SessionTTLMinutes *int `yaml:"session_ttl_minutes,omitempty"`
+
+	// SessionNameTemplate overrides session_name_template for this cluster.
+	SessionNameTemplate *string `yaml:"session_name_template,omitempty"`
+
+	// Retry overrides settings of the top-level retry for this cluster.
+	Retry *RetryPolicy `yaml:"retry,omitempty"`
+
+	// Hooks are commands run at points in the cluster's tunnel lifecycle.
+	Hooks *Hooks `yaml:"hooks,omitempty"`
+
+	// Kubeconfig controls the kubeconfigs generated for the cluster.
+	Kubeconfig *ClusterKubeconfig `yaml:"kubeconfig,omitempty"`
+
 	// URL is the OCI console URL for the cluster.
 	URL *string `yaml:"url,omitempty"`
 
 	// Endpoints contains the cluster API endpoints.
 	Endpoints []*ClusterEndpoint `yaml:"endpoints,omitempty"`
+
+	// Hops are SSH jump hosts chained after a standard bastion, in order.
+	// The bastion session targets the first hop and endpoints are reached
+	// from the last one.
+	Hops []*Hop `yaml:"hops,omitempty"`
+}
+
+// ClusterKubeconfig controls the kubeconfigs generated for a cluster by
+// exec, shell, kubeconfig and the kubeconfig settings of profiles.
+type ClusterKubeconfig struct {
+	// Namespace is the default namespace of the context.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// ContextName is the name of the context, with "{cluster}" and
+	// "{region}" replaced. Default: tuna-{cluster}.
+	ContextName string `yaml:"context_name,omitempty"`
+
+	// EmbedCA verifies the API server with the cluster's certificate
+	// authority, fetched from OCI. False skips TLS verification instead.
+	// Default: true.
+	EmbedCA *bool `yaml:"embed_ca,omitempty"`
+
+	// Merge makes 'tunatap kubeconfig' merge into ~/.kube/config when no
+	// output file is given.
+	Merge bool `yaml:"merge,omitempty"`
+
+	// OCIProfile is the OCI config profile of exec-auth. Default: the
+	// cluster's oci_profile.
+	OCIProfile string `yaml:"oci_profile,omitempty"`
+}
+
+// ClusterDefaults are settings the clusters of the config inherit unless
+// they set their own, like the defaults of a shared catalog.
+type ClusterDefaults struct {
+	// Region is the region of clusters that have none.
+	Region string `yaml:"region,omitempty"`
+
+	// BastionType is the bastion_type of clusters that have none.
+	BastionType string `yaml:"bastion_type,omitempty"`
+
+	// LocalPortRange is a range of ports such as "6443-6499". Clusters
+	// without a local_port are each given the next port of the range that
+	// no other cluster uses, in the order of the config.
+	LocalPortRange string `yaml:"local_port_range,omitempty"`
+
+	// Endpoint names the endpoint connected to when none is given, for
+	// clusters that have an endpoint of that name.
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// Kubeconfig is written once a tunnel is ready, unless a profile
+	// applied to it has kubeconfig settings of its own.
+	Kubeconfig *ProfileKubeconfig `yaml:"kubeconfig,omitempty"`
+}
+
+// Hooks are shell commands run at points in a tunnel's lifecycle, with
+// TUNATAP_* environment variables describing the tunnel and its session.
+type Hooks struct {
+	// OnConnect runs once a bastion session is obtained, before the tunnel
+	// starts listening.
+	OnConnect string `yaml:"on_connect,omitempty"`
+
+	// OnReady runs each time the tunnel starts accepting connections.
+	OnReady string `yaml:"on_ready,omitempty"`
+
+	// OnRefresh runs when the bastion session is replaced by a new one.
+	OnRefresh string `yaml:"on_refresh,omitempty"`
+
+	// OnDisconnect runs when a tunnel that ran OnConnect stops.
+	OnDisconnect string `yaml:"on_disconnect,omitempty"`
+}
+
+// Hop is an SSH jump host reached through the bastion or the previous hop.
+type Hop struct {
+	// Host is the hop's address as seen from the previous hop.
+	Host string `yaml:"host"`
+
+	// Port is the hop's SSH port. Default: 22.
+	Port *int `yaml:"port,omitempty"`
+
+	// User is the SSH user on the hop. Default: opc.
+	User string `yaml:"user,omitempty"`
+
+	// SshPrivateKeyFile overrides the top-level ssh_private_key_file for
+	// this hop.
+	SshPrivateKeyFile string `yaml:"ssh_private_key_file,omitempty"`
+}
+
+// Profile bundles the settings for a common workflow so they can be applied
+// to a tunnel with a single --profile flag. Command-line flags take
+// precedence over profile settings.
+type Profile struct {
+	// Name is the profile name passed to --profile.
+	Name string `yaml:"name"`
+
+	// Cluster is the cluster to connect to when none is given.
+	Cluster string `yaml:"cluster,omitempty"`
+
+	// Endpoint selects the primary endpoint by name.
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// AllEndpoints also forwards every endpoint configured on the cluster.
+	AllEndpoints bool `yaml:"all_endpoints,omitempty"`
+
+	// SocksPort exposes a SOCKS5 proxy on this local port.
+	SocksPort int `yaml:"socks_port,omitempty"`
+
+	// Forwards are extra destinations forwarded alongside the primary
+	// endpoint, such as node SSH or Prometheus. Each needs a local_port.
+	Forwards []*ClusterEndpoint `yaml:"forwards,omitempty"`
+
+	// Kubeconfig is written once the tunnel is ready. Optional.
+	Kubeconfig *ProfileKubeconfig `yaml:"kubeconfig,omitempty"`
+}
+
+// ProfileKubeconfig controls the kubeconfig written for a profile's tunnel,
+// or for every tunnel when set in the defaults.
+type ProfileKubeconfig struct {
+	// Merge adds a context for the tunnel to ~/.kube/config and selects it.
+	Merge bool `yaml:"merge,omitempty"`
+
+	// Path writes a standalone kubeconfig to this file. "{cluster}" is
+	// replaced with the cluster's name.
+	Path string `yaml:"path,omitempty"`
+
+	// NoOCIAuth generates the kubeconfig without OCI exec-auth.
+	NoOCIAuth bool `yaml:"no_oci_auth,omitempty"`
 }
 
 // ClusterEndpoint represents a cluster API endpoint.
@@ -170,8 +689,22 @@ type ClusterEndpoint struct {
 	// Ip is the endpoint IP address.
 	Ip string `yaml:"ip"`
 
+	// Fqdn is the endpoint's DNS name, used instead of Ip for targets
+	// without a stable address such as database private endpoints. It is
+	// resolved on the bastion side.
+	Fqdn string `yaml:"fqdn,omitempty"`
+
 	// Port is the endpoint port.
 	Port int `yaml:"port"`
+
+	// LocalPort is the local port to forward this endpoint on when several
+	// endpoints are forwarded at once. The primary endpoint uses the
+	// cluster's local_port instead.
+	LocalPort *int `yaml:"local_port,omitempty"`
+
+	// Protocol is "tcp" (default) or "udp". UDP endpoints can only be
+	// forwarded as additional endpoints, and only to DNS servers on DNSPort.
+	Protocol string `yaml:"protocol,omitempty"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -181,6 +714,7 @@ func DefaultConfig() *Config {
 	maxConcurrent := 10
 
 	return &Config{
+		Version:                       CurrentVersion,
 		Tenancies:                     make(map[string]*string),
 		Clusters:                      []*Cluster{},
 		SshConnectionPoolSize:         &poolSize,
@@ -189,6 +723,22 @@ func DefaultConfig() *Config {
 	}
 }
 
+// AllTenancies returns the OCIDs of the tenancies of both Tenancies and
+// TenancyList by name, those of TenancyList winning.
+func (c *Config) AllTenancies() map[string]*string {
+	tenancies := make(map[string]*string, len(c.Tenancies)+len(c.TenancyList))
+	for name, ocid := range c.Tenancies {
+		tenancies[name] = ocid
+	}
+	for _, t := range c.TenancyList {
+		if t != nil && t.Name != "" {
+			id := t.ID
+			tenancies[t.Name] = &id
+		}
+	}
+	return tenancies
+}
+
 // GetPoolSize returns the connection pool size with default fallback.
 func (c *Config) GetPoolSize() int {
 	if c.SshConnectionPoolSize != nil {
@@ -213,6 +763,217 @@ func (c *Config) GetMaxConcurrent() int {
 	return 10
 }
 
+// GetMin returns the minimum pool size with default fallback.
+func (a *PoolAutoscale) GetMin() int {
+	if a.Min != nil {
+		return *a.Min
+	}
+	return 1
+}
+
+// GetMax returns the maximum pool size with default fallback.
+func (a *PoolAutoscale) GetMax() int {
+	if a.Max != nil {
+		return *a.Max
+	}
+	return 20
+}
+
+// GetScaleUpCooldownSeconds returns the scale-up cooldown with default fallback.
+func (a *PoolAutoscale) GetScaleUpCooldownSeconds() int {
+	if a.ScaleUpCooldownSeconds != nil {
+		return *a.ScaleUpCooldownSeconds
+	}
+	return 5
+}
+
+// GetScaleDownCooldownSeconds returns the scale-down cooldown with default fallback.
+func (a *PoolAutoscale) GetScaleDownCooldownSeconds() int {
+	if a.ScaleDownCooldownSeconds != nil {
+		return *a.ScaleDownCooldownSeconds
+	}
+	return 120
+}
+
+// GetInitialIntervalSeconds returns the first retry wait with default fallback.
+func (p *RetryPolicy) GetInitialIntervalSeconds() int {
+	if p.InitialIntervalSeconds != nil {
+		return *p.InitialIntervalSeconds
+	}
+	return 5
+}
+
+// GetMaxIntervalSeconds returns the longest retry wait with default fallback.
+func (p *RetryPolicy) GetMaxIntervalSeconds() int {
+	if p.MaxIntervalSeconds != nil {
+		return *p.MaxIntervalSeconds
+	}
+	return 120
+}
+
+// GetMultiplier returns the retry wait growth factor with default fallback.
+func (p *RetryPolicy) GetMultiplier() float64 {
+	if p.Multiplier != nil {
+		return *p.Multiplier
+	}
+	return 1.5
+}
+
+// GetMaxAttempts returns the number of retries with default fallback.
+func (p *RetryPolicy) GetMaxAttempts() int {
+	if p.MaxAttempts != nil {
+		return *p.MaxAttempts
+	}
+	return 15
+}
+
+// GetSampleRatio returns the fraction of connects traced with default fallback.
+func (t *Tracing) GetSampleRatio() float64 {
+	if t.SampleRatio != nil {
+		return *t.SampleRatio
+	}
+	return 1
+}
+
+// GetMaxAttempts returns how many times an OCI API call is made with default fallback.
+func (r *OCIRetry) GetMaxAttempts() int {
+	if r != nil && r.MaxAttempts != nil {
+		return *r.MaxAttempts
+	}
+	return 5
+}
+
+// GetInitialIntervalSeconds returns the wait before the first retry of an OCI API call with default fallback.
+func (r *OCIRetry) GetInitialIntervalSeconds() int {
+	if r != nil && r.InitialIntervalSeconds != nil {
+		return *r.InitialIntervalSeconds
+	}
+	return 1
+}
+
+// GetMaxIntervalSeconds returns the longest wait between retries of an OCI API call with default fallback.
+func (r *OCIRetry) GetMaxIntervalSeconds() int {
+	if r != nil && r.MaxIntervalSeconds != nil {
+		return *r.MaxIntervalSeconds
+	}
+	return 30
+}
+
+// GetCircuitBreakerThreshold returns how many failed requests open the circuit of an OCI API operation with default fallback.
+func (r *OCIRetry) GetCircuitBreakerThreshold() int {
+	if r != nil && r.CircuitBreakerThreshold != nil {
+		return *r.CircuitBreakerThreshold
+	}
+	return 10
+}
+
+// GetCircuitBreakerCooldownSeconds returns how long the circuit of an OCI API operation stays open with default fallback.
+func (r *OCIRetry) GetCircuitBreakerCooldownSeconds() int {
+	if r != nil && r.CircuitBreakerCooldownSeconds != nil {
+		return *r.CircuitBreakerCooldownSeconds
+	}
+	return 30
+}
+
+// IsEnabled returns whether the log file is written (default: true).
+func (l *LogFile) IsEnabled() bool {
+	if l != nil && l.Enabled != nil {
+		return *l.Enabled
+	}
+	return true
+}
+
+// GetLevel returns the lowest level written to the log file with default fallback.
+func (l *LogFile) GetLevel() string {
+	if l != nil && l.Level != "" {
+		return l.Level
+	}
+	return "debug"
+}
+
+// GetMaxSizeMB returns the size the log file is rotated at with default fallback.
+func (l *LogFile) GetMaxSizeMB() int {
+	if l != nil && l.MaxSizeMB != nil {
+		return *l.MaxSizeMB
+	}
+	return 10
+}
+
+// GetMaxBackups returns how many rotated log files are kept with default fallback.
+func (l *LogFile) GetMaxBackups() int {
+	if l != nil && l.MaxBackups != nil {
+		return *l.MaxBackups
+	}
+	return 5
+}
+
+// MergeRetryPolicy returns a policy with the settings of override, falling
+// back to those of base. Either may be nil.
+func MergeRetryPolicy(base, override *RetryPolicy) *RetryPolicy {
+	merged := &RetryPolicy{}
+	if base != nil {
+		*merged = *base
+	}
+	if override == nil {
+		return merged
+	}
+	if override.InitialIntervalSeconds != nil {
+		merged.InitialIntervalSeconds = override.InitialIntervalSeconds
+	}
+	if override.MaxIntervalSeconds != nil {
+		merged.MaxIntervalSeconds = override.MaxIntervalSeconds
+	}
+	if override.Multiplier != nil {
+		merged.Multiplier = override.Multiplier
+	}
+	if override.MaxAttempts != nil {
+		merged.MaxAttempts = override.MaxAttempts
+	}
+	return merged
+}
+
+// GetRetryPolicy returns the retry policy for a cluster: its own retry
+// settings, falling back to the top-level ones. cluster may be nil.
+func (c *Config) GetRetryPolicy(cluster *Cluster) *RetryPolicy {
+	if cluster == nil {
+		return MergeRetryPolicy(c.Retry, nil)
+	}
+	return MergeRetryPolicy(c.Retry, cluster.Retry)
+}
+
+// GetKeepaliveInterval returns the SSH keepalive interval in seconds with default fallback.
+func (c *Config) GetKeepaliveInterval() int {
+	if c.SshKeepaliveInterval != nil {
+		return *c.SshKeepaliveInterval
+	}
+	return 30
+}
+
+// GetKeepaliveMax returns the max unanswered SSH keepalives with default fallback.
+func (c *Config) GetKeepaliveMax() int {
+	if c.SshKeepaliveMax != nil {
+		return *c.SshKeepaliveMax
+	}
+	return 3
+}
+
+// GetIdleTimeoutMinutes returns the tunnel idle timeout in minutes (default: 0, disabled).
+func (c *Config) GetIdleTimeoutMinutes() int {
+	if c.IdleTimeoutMinutes != nil {
+		return *c.IdleTimeoutMinutes
+	}
+	return 0
+}
+
+// GetEndpointWatchIntervalSeconds returns how often running tunnels check
+// their cluster's endpoint in seconds (default: 0, disabled).
+func (c *Config) GetEndpointWatchIntervalSeconds() int {
+	if c.EndpointWatchIntervalSeconds != nil {
+		return *c.EndpointWatchIntervalSeconds
+	}
+	return 0
+}
+
 // GetCacheTTLHours returns the cache TTL in hours with default fallback.
 func (c *Config) GetCacheTTLHours() int {
 	if c.CacheTTLHours != nil {
@@ -221,6 +982,179 @@ func (c *Config) GetCacheTTLHours() int {
 	return 24 // Default 24 hours
 }
 
+// GetDiscoveryMaxDepth returns how deep discovery walks the compartment
+// tree (default: 0, no limit).
+func (c *Config) GetDiscoveryMaxDepth() int {
+	if c.DiscoveryMaxDepth != nil {
+		return *c.DiscoveryMaxDepth
+	}
+	return 0
+}
+
+// GetNegativeCacheTTLMinutes returns how long discovery remembers names it
+// did not find (default: 10 minutes).
+func (c *Config) GetNegativeCacheTTLMinutes() int {
+	if c.NegativeCacheTTLMinutes != nil {
+		return *c.NegativeCacheTTLMinutes
+	}
+	return 10
+}
+
+// GetEphemeralKeyRotationHours returns how many hours an ephemeral key is
+// reused for (default: 0, a new key for every session).
+func (c *Config) GetEphemeralKeyRotationHours() int {
+	if c.EphemeralKeyRotationHours != nil {
+		return *c.EphemeralKeyRotationHours
+	}
+	return 0
+}
+
+// GetBastionTTLHours returns the lifetime of bastions created by tunatap in
+// hours (default: 0, no expiry).
+func (c *Config) GetBastionTTLHours() int {
+	if c.BastionTTLHours != nil {
+		return *c.BastionTTLHours
+	}
+	return 0
+}
+
+// GetPublicIPURL returns the public IP lookup URL (default:
+// https://checkip.amazonaws.com).
+func (c *Config) GetPublicIPURL() string {
+	if c.PublicIPURL != "" {
+		return c.PublicIPURL
+	}
+	return "https://checkip.amazonaws.com"
+}
+
+// Bounds OCI places on bastion session lifetimes.
+const (
+	MinSessionTTLMinutes = 30
+	MaxSessionTTLMinutes = 180
+)
+
+// GetSessionTTLMinutes returns the bastion session lifetime in minutes for
+// cluster, which may be nil, with default fallback.
+func (c *Config) GetSessionTTLMinutes(cluster *Cluster) int {
+	if cluster != nil && cluster.SessionTTLMinutes != nil {
+		return *cluster.SessionTTLMinutes
+	}
+	if c.SessionTTLMinutes != nil {
+		return *c.SessionTTLMinutes
+	}
+	return MaxSessionTTLMinutes
+}
+
+// HasSessionTTL reports whether a session lifetime is configured for
+// cluster, which may be nil, rather than defaulted.
+func (c *Config) HasSessionTTL(cluster *Cluster) bool {
+	return (cluster != nil && cluster.SessionTTLMinutes != nil) || c.SessionTTLMinutes != nil
+}
+
+// GetSessionNameTemplate returns the bastion session display name template
+// for cluster, which may be nil, with default fallback.
+func (c *Config) GetSessionNameTemplate(cluster *Cluster) string {
+	if cluster != nil && cluster.SessionNameTemplate != nil && *cluster.SessionNameTemplate != "" {
+		return *cluster.SessionNameTemplate
+	}
+	if c.SessionNameTemplate != "" {
+		return c.SessionNameTemplate
+	}
+	return "{target}"
+}
+
+// GetBastionHost returns the bastion service host name configured for
+// cluster, from the cluster or else its tenancy in tenancy_list, with
+// "{region}" expanded. It returns "" when the host should be derived from
+// the bastion's realm.
+func (c *Config) GetBastionHost(cluster *Cluster) string {
+	host := ""
+	if cluster.BastionHost != nil {
+		host = *cluster.BastionHost
+	} else if cluster.Tenant != nil {
+		for _, t := range c.TenancyList {
+			if t.Name == *cluster.Tenant {
+				host = t.BastionHost
+				break
+			}
+		}
+	}
+	return strings.ReplaceAll(host, "{region}", cluster.Region)
+}
+
+// GetOCIProfile returns the OCI config profile for cluster: its own, else
+// that of its tenancy in tenancy_list, else the top-level oci_profile. It
+// returns "" when none is set, for the DEFAULT profile. cluster may be nil
+// for requests not made for one cluster.
+func (c *Config) GetOCIProfile(cluster *Cluster) string {
+	if cluster != nil && cluster.OCIProfile != nil && *cluster.OCIProfile != "" {
+		return *cluster.OCIProfile
+	}
+	if t := c.clusterTenancy(cluster); t != nil && t.OCIProfile != "" {
+		return t.OCIProfile
+	}
+	return c.OCIProfile
+}
+
+// GetOCIAuthType returns the OCI authentication type for cluster, looked up
+// like GetOCIProfile. It returns "" when none is set, for auto-detection.
+func (c *Config) GetOCIAuthType(cluster *Cluster) string {
+	if cluster != nil && cluster.OCIAuthType != nil && *cluster.OCIAuthType != "" {
+		return *cluster.OCIAuthType
+	}
+	if t := c.clusterTenancy(cluster); t != nil && t.OCIAuthType != "" {
+		return t.OCIAuthType
+	}
+	return c.OCIAuthType
+}
+
+// OverrideOCIProfile makes profile the OCI config profile of every cluster,
+// replacing those set per cluster and tenancy, as an --oci-profile flag does.
+func (c *Config) OverrideOCIProfile(profile string) {
+	c.OCIProfile = profile
+	for _, cluster := range c.Clusters {
+		cluster.OCIProfile = nil
+	}
+	for _, t := range c.TenancyList {
+		t.OCIProfile = ""
+	}
+}
+
+// clusterTenancy returns the tenancy_list entry of cluster, by its tenant
+// name or tenancy OCID, or nil if it has none.
+func (c *Config) clusterTenancy(cluster *Cluster) *TenantInfo {
+	if cluster == nil {
+		return nil
+	}
+	for _, t := range c.TenancyList {
+		if cluster.Tenant != nil && t.Name == *cluster.Tenant {
+			return t
+		}
+		if cluster.TenantOcid != nil && t.ID != "" && t.ID == *cluster.TenantOcid {
+			return t
+		}
+	}
+	return nil
+}
+
+// GetSessionWaitTimeoutSeconds returns how long to wait for a new session to
+// become active in seconds with default fallback.
+func (c *Config) GetSessionWaitTimeoutSeconds() int {
+	if c.SessionWaitTimeoutSeconds != nil && *c.SessionWaitTimeoutSeconds > 0 {
+		return *c.SessionWaitTimeoutSeconds
+	}
+	return 300
+}
+
+// GetSessionWaitPollSeconds returns the session state polling interval in
+// seconds with default fallback.
+func (c *Config) GetSessionWaitPollSeconds() int {
+	if c.SessionWaitPollSeconds != nil && *c.SessionWaitPollSeconds > 0 {
+		return *c.SessionWaitPollSeconds
+	}
+	return 3
+}
+
 // IsAuditLoggingEnabled returns whether audit logging is enabled (default: true).
 func (c *Config) IsAuditLoggingEnabled() bool {
 	if c.AuditLogging != nil {
@@ -228,3 +1162,37 @@ func (c *Config) IsAuditLoggingEnabled() bool {
 	}
 	return true // Enabled by default
 }
+
+// ShouldEmbedCA returns whether kubeconfigs of the cluster verify its API
+// server with its certificate authority (default: true).
+func (c *Cluster) ShouldEmbedCA() bool {
+	if c.Kubeconfig != nil && c.Kubeconfig.EmbedCA != nil {
+		return *c.Kubeconfig.EmbedCA
+	}
+	return true
+}
+
+// IsUpdateCheckEnabled returns whether the check for a newer release is
+// enabled (default: true).
+func (c *Config) IsUpdateCheckEnabled() bool {
+	if c.UpdateCheck != nil {
+		return *c.UpdateCheck
+	}
+	return true
+}
+
+// GetPort returns the hop's SSH port with default fallback.
+func (h *Hop) GetPort() int {
+	if h.Port != nil {
+		return *h.Port
+	}
+	return 22
+}
+
+// GetUser returns the hop's SSH user with default fallback.
+func (h *Hop) GetUser() string {
+	if h.User != "" {
+		return h.User
+	}
+	return "opc"
+}
@@ -73,6 +73,70 @@ func TestConfigGetters(t *testing.T) {
 	}
 }
 
+func TestKeepaliveGetters(t *testing.T) {
+	cfg := &Config{}
+
+	if cfg.GetKeepaliveInterval() != 30 {
+		t.Errorf("GetKeepaliveInterval() with nil = %d, want 30", cfg.GetKeepaliveInterval())
+	}
+	if cfg.GetKeepaliveMax() != 3 {
+		t.Errorf("GetKeepaliveMax() with nil = %d, want 3", cfg.GetKeepaliveMax())
+	}
+
+	// Zero is an explicit "disabled", not a missing value
+	interval := 0
+	maxMissed := 5
+	cfg.SshKeepaliveInterval = &interval
+	cfg.SshKeepaliveMax = &maxMissed
+
+	if cfg.GetKeepaliveInterval() != 0 {
+		t.Errorf("GetKeepaliveInterval() = %d, want 0", cfg.GetKeepaliveInterval())
+	}
+	if cfg.GetKeepaliveMax() != maxMissed {
+		t.Errorf("GetKeepaliveMax() = %d, want %d", cfg.GetKeepaliveMax(), maxMissed)
+	}
+}
+
+func TestHopGetters(t *testing.T) {
+	hop := &Hop{Host: "10.0.1.5"}
+
+	if hop.GetPort() != 22 {
+		t.Errorf("GetPort() with nil = %d, want 22", hop.GetPort())
+	}
+	if hop.GetUser() != "opc" {
+		t.Errorf("GetUser() with empty = %q, want %q", hop.GetUser(), "opc")
+	}
+
+	port := 2222
+	hop.Port = &port
+	hop.User = "ops"
+
+	if hop.GetPort() != port {
+		t.Errorf("GetPort() = %d, want %d", hop.GetPort(), port)
+	}
+	if hop.GetUser() != "ops" {
+		t.Errorf("GetUser() = %q, want %q", hop.GetUser(), "ops")
+	}
+}
+
+func TestShouldEmbedCA(t *testing.T) {
+	embed, skip := true, false
+	for _, tt := range []struct {
+		prefs *ClusterKubeconfig
+		want  bool
+	}{
+		{nil, true},
+		{&ClusterKubeconfig{Namespace: "payments"}, true},
+		{&ClusterKubeconfig{EmbedCA: &embed}, true},
+		{&ClusterKubeconfig{EmbedCA: &skip}, false},
+	} {
+		c := &Cluster{ClusterName: "prod", Kubeconfig: tt.prefs}
+		if got := c.ShouldEmbedCA(); got != tt.want {
+			t.Errorf("ShouldEmbedCA() with %+v = %v, want %v", tt.prefs, got, tt.want)
+		}
+	}
+}
+
 func TestClusterStruct(t *testing.T) {
 	cluster := &Cluster{
 		ClusterName: "test-cluster",
@@ -212,6 +276,29 @@ clusters:
 	}
 }
 
+func TestReadLogFileConfig(t *testing.T) {
+	dir := t.TempDir()
+	if l := ReadLogFileConfig(filepath.Join(dir, "missing.yaml")); l != nil || !l.IsEnabled() || l.GetLevel() != "debug" {
+		t.Errorf("ReadLogFileConfig() of a missing file = %+v, want nil with the defaults", l)
+	}
+
+	cfgPath := filepath.Join(dir, "config.yaml")
+	configContent := `
+clusters:
+  - cluster_name: test-cluster
+log_file:
+  level: info
+  max_backups: 0
+`
+	if err := os.WriteFile(cfgPath, []byte(configContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l := ReadLogFileConfig(cfgPath)
+	if l == nil || !l.IsEnabled() || l.GetLevel() != "info" || l.GetMaxBackups() != 0 || l.GetMaxSizeMB() != 10 {
+		t.Errorf("ReadLogFileConfig() = %+v", l)
+	}
+}
+
 func TestSaveConfig(t *testing.T) {
 	// Create temp directory
 	tmpDir, err := os.MkdirTemp("", "tunatap-test-*")
@@ -263,7 +350,7 @@ func TestFindClusterByName(t *testing.T) {
 		Clusters: []*Cluster{
 			{ClusterName: "cluster-1", Region: "us-ashburn-1"},
 			{ClusterName: "Cluster-2", Region: "eu-frankfurt-1"},
-			{ClusterName: "CLUSTER-3", Region: "ap-tokyo-1"},
+			{ClusterName: "CLUSTER-3", Region: "ap-tokyo-1", Aliases: []string{"c3", "cluster-1"}},
 		},
 	}
 
@@ -274,6 +361,7 @@ func TestFindClusterByName(t *testing.T) {
 		wantName  string
 	}{
 		{"exact match", "cluster-1", true, "cluster-1"},
+		{"alias", "C3", true, "CLUSTER-3"},
 		{"case insensitive", "CLUSTER-1", true, "cluster-1"},
 		{"mixed case", "cLuStEr-2", true, "Cluster-2"},
 		{"not found", "cluster-4", false, ""},
@@ -299,6 +387,25 @@ func TestFindClusterByName(t *testing.T) {
 	}
 }
 
+func TestFindClustersByGroup(t *testing.T) {
+	cfg := &Config{
+		Clusters: []*Cluster{
+			{ClusterName: "payments-prod", Group: "payments"},
+			{ClusterName: "orders-prod", Group: "orders"},
+			{ClusterName: "payments-dr", Group: "Payments"},
+			{ClusterName: "sandbox"},
+		},
+	}
+
+	got := FindClustersByGroup(cfg, "payments")
+	if len(got) != 2 || got[0].ClusterName != "payments-prod" || got[1].ClusterName != "payments-dr" {
+		t.Errorf("FindClustersByGroup(payments) = %v", got)
+	}
+	if got := FindClustersByGroup(cfg, ""); len(got) != 0 {
+		t.Errorf("FindClustersByGroup(\"\") = %v, want none", got)
+	}
+}
+
 func TestGetClusterEndpoint(t *testing.T) {
 	cluster := &Cluster{
 		ClusterName: "test",
@@ -352,6 +459,158 @@ func TestGetClusterEndpointNoEndpoints(t *testing.T) {
 	}
 }
 
+func TestGetAdditionalEndpoints(t *testing.T) {
+	sshPort := 2222
+	dbPort := 15432
+	cluster := &Cluster{
+		ClusterName: "test",
+		Endpoints: []*ClusterEndpoint{
+			{Name: "api", Ip: "10.0.0.1", Port: 6443},
+			{Name: "ssh", Ip: "10.0.0.2", Port: 22, LocalPort: &sshPort},
+			{Name: "db", Ip: "10.0.0.3", Port: 5432, LocalPort: &dbPort},
+		},
+	}
+
+	got, err := GetAdditionalEndpoints(cluster, cluster.Endpoints[0])
+	if err != nil {
+		t.Fatalf("GetAdditionalEndpoints() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetAdditionalEndpoints() returned %d endpoints, want 2", len(got))
+	}
+	if got[0].Name != "ssh" || got[1].Name != "db" {
+		t.Errorf("GetAdditionalEndpoints() = [%s %s], want [ssh db]", got[0].Name, got[1].Name)
+	}
+
+	// The primary endpoint does not need a local_port of its own
+	if _, err := GetAdditionalEndpoints(cluster, cluster.Endpoints[1]); err == nil {
+		t.Error("GetAdditionalEndpoints() should fail when an endpoint has no local_port")
+	}
+}
+
+func TestGetAdditionalEndpointsProtocol(t *testing.T) {
+	dnsPort := 5353
+	cluster := &Cluster{
+		ClusterName: "test",
+		Endpoints: []*ClusterEndpoint{
+			{Name: "api", Ip: "10.0.0.1", Port: 6443},
+			{Name: "dns", Ip: "169.254.169.254", Port: 53, LocalPort: &dnsPort, Protocol: "UDP"},
+		},
+	}
+
+	got, err := GetAdditionalEndpoints(cluster, cluster.Endpoints[0])
+	if err != nil {
+		t.Fatalf("GetAdditionalEndpoints() error = %v", err)
+	}
+	if len(got) != 1 || !got[0].IsUDP() {
+		t.Errorf("GetAdditionalEndpoints() = %v, want the UDP dns endpoint", got)
+	}
+
+	if _, err := GetAdditionalEndpoints(cluster, cluster.Endpoints[1]); err == nil {
+		t.Error("GetAdditionalEndpoints() should fail when the primary endpoint is UDP")
+	}
+
+	cluster.Endpoints[1].Port = 8125
+	if _, err := GetAdditionalEndpoints(cluster, cluster.Endpoints[0]); err == nil {
+		t.Error("GetAdditionalEndpoints() should reject UDP endpoints other than DNS")
+	}
+
+	cluster.Endpoints[1].Protocol = "sctp"
+	if _, err := GetAdditionalEndpoints(cluster, cluster.Endpoints[0]); err == nil {
+		t.Error("GetAdditionalEndpoints() should reject unknown protocols")
+	}
+}
+
+func TestFindProfileByName(t *testing.T) {
+	cfg := &Config{
+		Profiles: []*Profile{
+			{Name: "debug", Cluster: "prod"},
+			{Name: "metrics"},
+		},
+	}
+
+	if p := FindProfileByName(cfg, "DEBUG"); p == nil || p.Cluster != "prod" {
+		t.Errorf("FindProfileByName(DEBUG) = %v, want the debug profile", p)
+	}
+	if p := FindProfileByName(cfg, "missing"); p != nil {
+		t.Errorf("FindProfileByName(missing) = %v, want nil", p)
+	}
+}
+
+func TestGetProfileForwards(t *testing.T) {
+	sshPort := 2222
+	promPort := 9090
+	profile := &Profile{
+		Name: "debug",
+		Forwards: []*ClusterEndpoint{
+			{Name: "node-ssh", Ip: "10.0.10.5", Port: 22, LocalPort: &sshPort},
+			{Name: "prometheus", Ip: "10.0.20.7", Port: 9090, LocalPort: &promPort},
+		},
+	}
+
+	got, err := GetProfileForwards(profile)
+	if err != nil {
+		t.Fatalf("GetProfileForwards() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("GetProfileForwards() returned %d forwards, want 2", len(got))
+	}
+
+	profile.Forwards[1].LocalPort = nil
+	if _, err := GetProfileForwards(profile); err == nil {
+		t.Error("GetProfileForwards() should fail when a forward has no local_port")
+	}
+
+	profile.Forwards[1] = &ClusterEndpoint{Name: "prometheus", LocalPort: &promPort}
+	if _, err := GetProfileForwards(profile); err == nil {
+		t.Error("GetProfileForwards() should fail when a forward has no destination")
+	}
+}
+
+func TestClusterEndpointHost(t *testing.T) {
+	tests := []struct {
+		ep   ClusterEndpoint
+		want string
+	}{
+		{ClusterEndpoint{Ip: "10.0.1.100"}, "10.0.1.100"},
+		{ClusterEndpoint{Fqdn: "db.example.com"}, "db.example.com"},
+		{ClusterEndpoint{Ip: "10.0.1.100", Fqdn: "db.example.com"}, "db.example.com"},
+		{ClusterEndpoint{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.ep.Host(); got != tt.want {
+			t.Errorf("Host() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestGetRetryPolicy(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.GetRetryPolicy(nil); got.GetMaxAttempts() != 15 || got.GetInitialIntervalSeconds() != 5 {
+		t.Errorf("GetRetryPolicy() = %d attempts after %ds, want the defaults", got.GetMaxAttempts(), got.GetInitialIntervalSeconds())
+	}
+
+	globalAttempts, globalInitial := 5, 10
+	cfg.Retry = &RetryPolicy{MaxAttempts: &globalAttempts, InitialIntervalSeconds: &globalInitial}
+	clusterAttempts := 0
+	cluster := &Cluster{ClusterName: "prod", Retry: &RetryPolicy{MaxAttempts: &clusterAttempts}}
+
+	got := cfg.GetRetryPolicy(cluster)
+	if got.GetMaxAttempts() != 0 {
+		t.Errorf("GetMaxAttempts() = %d, want the cluster's 0", got.GetMaxAttempts())
+	}
+	if got.GetInitialIntervalSeconds() != 10 {
+		t.Errorf("GetInitialIntervalSeconds() = %d, want the top-level 10", got.GetInitialIntervalSeconds())
+	}
+	if got.GetMultiplier() != 1.5 {
+		t.Errorf("GetMultiplier() = %g, want the default 1.5", got.GetMultiplier())
+	}
+	if *cfg.Retry.MaxAttempts != 5 {
+		t.Error("GetRetryPolicy() modified the top-level policy")
+	}
+}
+
 func TestGetDefaultConfigPath(t *testing.T) {
 	path, err := GetDefaultConfigPath()
 	if err != nil {
@@ -367,3 +626,117 @@ func TestGetDefaultConfigPath(t *testing.T) {
 		t.Errorf("GetDefaultConfigPath() = %q, should be absolute path", path)
 	}
 }
+
+func TestGetSessionTTLMinutes(t *testing.T) {
+	cfg := &Config{}
+	cluster := &Cluster{ClusterName: "prod"}
+
+	if got := cfg.GetSessionTTLMinutes(cluster); got != MaxSessionTTLMinutes {
+		t.Errorf("GetSessionTTLMinutes() = %d, want %d", got, MaxSessionTTLMinutes)
+	}
+	if cfg.HasSessionTTL(cluster) {
+		t.Error("HasSessionTTL() = true, want false without a configured TTL")
+	}
+
+	global, perCluster := 60, 30
+	cfg.SessionTTLMinutes = &global
+	if got := cfg.GetSessionTTLMinutes(cluster); got != 60 {
+		t.Errorf("GetSessionTTLMinutes() = %d, want 60 from config", got)
+	}
+
+	cluster.SessionTTLMinutes = &perCluster
+	if got := cfg.GetSessionTTLMinutes(cluster); got != 30 {
+		t.Errorf("GetSessionTTLMinutes() = %d, want 30 from cluster", got)
+	}
+	if got := cfg.GetSessionTTLMinutes(nil); got != 60 {
+		t.Errorf("GetSessionTTLMinutes(nil) = %d, want 60", got)
+	}
+}
+
+func TestGetSessionNameTemplate(t *testing.T) {
+	cfg := &Config{}
+	cluster := &Cluster{ClusterName: "prod"}
+
+	if got := cfg.GetSessionNameTemplate(cluster); got != "{target}" {
+		t.Errorf("GetSessionNameTemplate() = %q, want %q", got, "{target}")
+	}
+
+	cfg.SessionNameTemplate = "{user}-{target}"
+	if got := cfg.GetSessionNameTemplate(cluster); got != "{user}-{target}" {
+		t.Errorf("GetSessionNameTemplate() = %q, want %q", got, "{user}-{target}")
+	}
+
+	template := "{cluster}-{target}"
+	cluster.SessionNameTemplate = &template
+	if got := cfg.GetSessionNameTemplate(cluster); got != template {
+		t.Errorf("GetSessionNameTemplate() = %q, want %q", got, template)
+	}
+}
+
+func TestGetSessionWaitSettings(t *testing.T) {
+	cfg := &Config{}
+	if got := cfg.GetSessionWaitTimeoutSeconds(); got != 300 {
+		t.Errorf("GetSessionWaitTimeoutSeconds() = %d, want 300", got)
+	}
+	if got := cfg.GetSessionWaitPollSeconds(); got != 3 {
+		t.Errorf("GetSessionWaitPollSeconds() = %d, want 3", got)
+	}
+
+	timeout, poll := 600, 10
+	cfg.SessionWaitTimeoutSeconds = &timeout
+	cfg.SessionWaitPollSeconds = &poll
+	if got := cfg.GetSessionWaitTimeoutSeconds(); got != 600 {
+		t.Errorf("GetSessionWaitTimeoutSeconds() = %d, want 600", got)
+	}
+	if got := cfg.GetSessionWaitPollSeconds(); got != 10 {
+		t.Errorf("GetSessionWaitPollSeconds() = %d, want 10", got)
+	}
+}
+
+func TestGetOCICredentials(t *testing.T) {
+	tenant, tenancyOCID := "acme", "ocid1.tenancy.oc1..acme"
+	cfg := &Config{
+		OCIProfile: "WORK",
+		TenancyList: []*TenantInfo{
+			{Name: "acme", ID: tenancyOCID, OCIProfile: "ACME", OCIAuthType: "security_token"},
+		},
+	}
+	plain := &Cluster{ClusterName: "dev"}
+	byName := &Cluster{ClusterName: "acme-prod", Tenant: &tenant}
+	byOCID := &Cluster{ClusterName: "acme-dev", TenantOcid: &tenancyOCID}
+
+	if got := cfg.GetOCIProfile(plain); got != "WORK" {
+		t.Errorf("GetOCIProfile() = %q, want WORK", got)
+	}
+	if got := cfg.GetOCIProfile(nil); got != "WORK" {
+		t.Errorf("GetOCIProfile(nil) = %q, want WORK", got)
+	}
+	if got := cfg.GetOCIAuthType(plain); got != "" {
+		t.Errorf("GetOCIAuthType() = %q, want auto", got)
+	}
+	for _, c := range []*Cluster{byName, byOCID} {
+		if got := cfg.GetOCIProfile(c); got != "ACME" {
+			t.Errorf("GetOCIProfile(%s) = %q, want the tenancy's ACME", c.ClusterName, got)
+		}
+		if got := cfg.GetOCIAuthType(c); got != "security_token" {
+			t.Errorf("GetOCIAuthType(%s) = %q, want the tenancy's security_token", c.ClusterName, got)
+		}
+	}
+
+	profile, authType := "ACME_PROD", "config"
+	byName.OCIProfile, byName.OCIAuthType = &profile, &authType
+	if got := cfg.GetOCIProfile(byName); got != "ACME_PROD" {
+		t.Errorf("GetOCIProfile() = %q, want the cluster's ACME_PROD", got)
+	}
+	if got := cfg.GetOCIAuthType(byName); got != "config" {
+		t.Errorf("GetOCIAuthType() = %q, want the cluster's config", got)
+	}
+
+	cfg.Clusters = []*Cluster{plain, byName, byOCID}
+	cfg.OverrideOCIProfile("FLAG")
+	for _, c := range cfg.Clusters {
+		if got := cfg.GetOCIProfile(c); got != "FLAG" {
+			t.Errorf("GetOCIProfile(%s) after OverrideOCIProfile() = %q, want FLAG", c.ClusterName, got)
+		}
+	}
+}
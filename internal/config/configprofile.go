@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Config profiles are named configs, each with its own config file and
+// caches, such as one for work and one for a customer. The default profile
+// is the config in the tunatap directory itself; the others live under its
+// profiles directory.
+
+// ConfigProfileEnv selects the config profile, like --config-profile.
+const ConfigProfileEnv = "TUNATAP_PROFILE"
+
+// DefaultConfigProfile is the name of the config in the tunatap directory.
+const DefaultConfigProfile = "default"
+
+// ConfigFileName is the name of the config file of a config profile.
+const ConfigFileName = "config.yaml"
+
+const (
+	configProfilesDir    = "profiles"
+	currentProfileFile   = "config-profile"
+	maxConfigProfileName = 64
+)
+
+var configProfileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateConfigProfileName checks that name can name a config profile:
+// letters, digits, '_', '.' and '-', starting with a letter or digit.
+func ValidateConfigProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("config profile name is empty")
+	}
+	if len(name) > maxConfigProfileName || !configProfileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid config profile name '%s': use letters, digits, '_', '.' and '-'", name)
+	}
+	return nil
+}
+
+// ConfigProfileDir returns the directory of the config profile name under
+// the tunatap directory home.
+func ConfigProfileDir(home, name string) string {
+	if name == "" || name == DefaultConfigProfile {
+		return home
+	}
+	return filepath.Join(home, configProfilesDir, name)
+}
+
+// ConfigProfileExists reports whether the config profile name was created.
+// The default profile always exists.
+func ConfigProfileExists(home, name string) bool {
+	if name == "" || name == DefaultConfigProfile {
+		return true
+	}
+	info, err := os.Stat(ConfigProfileDir(home, name))
+	return err == nil && info.IsDir()
+}
+
+// ListConfigProfiles returns the names of the config profiles under home,
+// the default first and the others sorted.
+func ListConfigProfiles(home string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(home, configProfilesDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list config profiles: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && ValidateConfigProfileName(entry.Name()) == nil && entry.Name() != DefaultConfigProfile {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultConfigProfile}, names...), nil
+}
+
+// CreateConfigProfile creates the directory of the config profile name and,
+// if data is not nil, its config file with data.
+func CreateConfigProfile(home, name string, data []byte) error {
+	if err := ValidateConfigProfileName(name); err != nil {
+		return err
+	}
+	if name == DefaultConfigProfile {
+		return fmt.Errorf("the %s config profile always exists", DefaultConfigProfile)
+	}
+	if ConfigProfileExists(home, name) {
+		return fmt.Errorf("config profile '%s' already exists", name)
+	}
+
+	dir := ConfigProfileDir(home, name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create config profile: %w", err)
+	}
+	if data != nil {
+		if err := os.WriteFile(filepath.Join(dir, ConfigFileName), data, 0o600); err != nil {
+			return fmt.Errorf("failed to write config of profile '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// CurrentConfigProfile returns the config profile set with
+// UseConfigProfile, or the default one if none was.
+func CurrentConfigProfile(home string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(home, currentProfileFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultConfigProfile, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the current config profile: %w", err)
+	}
+	name := strings.TrimSpace(string(data))
+	if name == "" {
+		return DefaultConfigProfile, nil
+	}
+	return name, nil
+}
+
+// UseConfigProfile makes name the config profile used when none is given
+// with --config-profile or TUNATAP_PROFILE.
+func UseConfigProfile(home, name string) error {
+	if err := ValidateConfigProfileName(name); err != nil {
+		return err
+	}
+	if !ConfigProfileExists(home, name) {
+		return fmt.Errorf("config profile '%s' does not exist", name)
+	}
+
+	path := filepath.Join(home, currentProfileFile)
+	if name == DefaultConfigProfile {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to reset the current config profile: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(home, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", home, err)
+	}
+	if err := os.WriteFile(path, []byte(name+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to set the current config profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateConfigProfileName(t *testing.T) {
+	for _, name := range []string{"work", "customer-x", "acme_2.prod"} {
+		if err := ValidateConfigProfileName(name); err != nil {
+			t.Errorf("ValidateConfigProfileName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../etc", "a/b", ".hidden", "-x", "has space"} {
+		if err := ValidateConfigProfileName(name); err == nil {
+			t.Errorf("ValidateConfigProfileName(%q) = nil, want an error", name)
+		}
+	}
+}
+
+func TestConfigProfiles(t *testing.T) {
+	home := t.TempDir()
+
+	if dir := ConfigProfileDir(home, DefaultConfigProfile); dir != home {
+		t.Errorf("ConfigProfileDir(default) = %q, want %q", dir, home)
+	}
+	if err := UseConfigProfile(home, "work"); err == nil {
+		t.Error("UseConfigProfile() of a missing profile = nil, want an error")
+	}
+
+	if err := CreateConfigProfile(home, "work", []byte("clusters: []\n")); err != nil {
+		t.Fatalf("CreateConfigProfile() error = %v", err)
+	}
+	if err := CreateConfigProfile(home, "personal", nil); err != nil {
+		t.Fatalf("CreateConfigProfile() error = %v", err)
+	}
+	if err := CreateConfigProfile(home, "work", nil); err == nil {
+		t.Error("CreateConfigProfile() of an existing profile = nil, want an error")
+	}
+	if _, err := os.Stat(filepath.Join(home, "profiles", "work", ConfigFileName)); err != nil {
+		t.Errorf("config of the created profile: %v", err)
+	}
+
+	names, err := ListConfigProfiles(home)
+	if err != nil {
+		t.Fatalf("ListConfigProfiles() error = %v", err)
+	}
+	if want := []string{"default", "personal", "work"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListConfigProfiles() = %v, want %v", names, want)
+	}
+
+	if name, _ := CurrentConfigProfile(home); name != DefaultConfigProfile {
+		t.Errorf("CurrentConfigProfile() = %q, want default", name)
+	}
+	if err := UseConfigProfile(home, "work"); err != nil {
+		t.Fatalf("UseConfigProfile() error = %v", err)
+	}
+	if name, _ := CurrentConfigProfile(home); name != "work" {
+		t.Errorf("CurrentConfigProfile() = %q, want work", name)
+	}
+	if err := UseConfigProfile(home, DefaultConfigProfile); err != nil {
+		t.Fatalf("UseConfigProfile(default) error = %v", err)
+	}
+	if name, _ := CurrentConfigProfile(home); name != DefaultConfigProfile {
+		t.Errorf("CurrentConfigProfile() after reset = %q, want default", name)
+	}
+}
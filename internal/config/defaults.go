@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParsePortRange parses a range of ports such as "6443-6499". A single
+// port is a range of one.
+func ParsePortRange(s string) (first, last int, err error) {
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		to = from
+	}
+	first, err1 := strconv.Atoi(strings.TrimSpace(from))
+	last, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil || first < 1 || last > 65535 || first > last {
+		return 0, 0, fmt.Errorf("port range '%s' must be two ports from 1 to 65535 such as 6443-6499", s)
+	}
+	return first, last, nil
+}
+
+// ApplyDefaults gives the clusters of config the settings of its defaults
+// that they do not set themselves. A cluster with an endpoint named as the
+// default one has it moved first, so that it is connected to when no
+// endpoint is given. It returns an error if the local port range is used
+// up.
+func ApplyDefaults(config *Config) error {
+	d := config.Defaults
+	if d == nil {
+		return nil
+	}
+
+	for _, c := range config.Clusters {
+		if c.Region == "" && d.Region != "" {
+			c.Region = d.Region
+		}
+		if c.BastionType == nil && d.BastionType != "" {
+			bastionType := d.BastionType
+			c.BastionType = &bastionType
+		}
+		if d.Endpoint != "" {
+			i := slices.IndexFunc(c.Endpoints, func(ep *ClusterEndpoint) bool {
+				return strings.EqualFold(ep.Name, d.Endpoint)
+			})
+			if i > 0 {
+				ep := c.Endpoints[i]
+				c.Endpoints = slices.Insert(slices.Delete(c.Endpoints, i, i+1), 0, ep)
+			}
+		}
+	}
+
+	if d.LocalPortRange == "" {
+		return nil
+	}
+	first, last, err := ParsePortRange(d.LocalPortRange)
+	if err != nil {
+		return fmt.Errorf("defaults local_port_range: %w", err)
+	}
+	used := make(map[int]bool)
+	for _, c := range config.Clusters {
+		if c.LocalPort != nil {
+			used[*c.LocalPort] = true
+		}
+	}
+	port := first
+	for _, c := range config.Clusters {
+		if c.LocalPort != nil || (c.LocalSocket != nil && *c.LocalSocket != "") {
+			continue
+		}
+		for port <= last && used[port] {
+			port++
+		}
+		if port > last {
+			return fmt.Errorf("defaults local_port_range %s has no port left for cluster '%s'", d.LocalPortRange, c.ClusterName)
+		}
+		localPort := port
+		c.LocalPort = &localPort
+		used[port] = true
+	}
+	return nil
+}
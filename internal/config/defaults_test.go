@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		in          string
+		first, last int
+		wantErr     bool
+	}{
+		{"6443-6499", 6443, 6499, false},
+		{"7000", 7000, 7000, false},
+		{" 8000 - 8010 ", 8000, 8010, false},
+		{"6499-6443", 0, 0, true},
+		{"0-10", 0, 0, true},
+		{"60000-70000", 0, 0, true},
+		{"low-high", 0, 0, true},
+	}
+	for _, tt := range tests {
+		first, last, err := ParsePortRange(tt.in)
+		if (err != nil) != tt.wantErr || first != tt.first || last != tt.last {
+			t.Errorf("ParsePortRange(%q) = %d, %d, %v", tt.in, first, last, err)
+		}
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	port, socket, bastionType := 6444, "/tmp/dev.sock", "INTERNAL"
+	cfg := &Config{
+		Defaults: &ClusterDefaults{
+			Region:         "us-ashburn-1",
+			BastionType:    "STANDARD",
+			LocalPortRange: "6443-6446",
+			Endpoint:       "private",
+		},
+		Clusters: []*Cluster{
+			{ClusterName: "a", Endpoints: []*ClusterEndpoint{{Name: "public"}, {Name: "Private"}}},
+			{ClusterName: "b", Region: "us-phoenix-1", BastionType: &bastionType, LocalPort: &port},
+			{ClusterName: "c"},
+			{ClusterName: "d", LocalSocket: &socket},
+		},
+	}
+	if err := ApplyDefaults(cfg); err != nil {
+		t.Fatalf("ApplyDefaults() error = %v", err)
+	}
+
+	a, b, c, d := cfg.Clusters[0], cfg.Clusters[1], cfg.Clusters[2], cfg.Clusters[3]
+	if a.Region != "us-ashburn-1" || a.BastionType == nil || *a.BastionType != "STANDARD" {
+		t.Errorf("cluster a = region %q, bastion_type %v; want the defaults", a.Region, a.BastionType)
+	}
+	if b.Region != "us-phoenix-1" || *b.BastionType != "INTERNAL" || *b.LocalPort != 6444 {
+		t.Errorf("cluster b lost its own settings: %+v", b)
+	}
+	// Ports are handed out in order, skipping those clusters use
+	if *a.LocalPort != 6443 || *c.LocalPort != 6445 {
+		t.Errorf("local ports = %d, %d; want 6443, 6445", *a.LocalPort, *c.LocalPort)
+	}
+	if d.LocalPort != nil {
+		t.Errorf("cluster with a local_socket got local_port %d", *d.LocalPort)
+	}
+	if ep := GetClusterEndpoint(a, ""); ep.Name != "Private" {
+		t.Errorf("default endpoint = %q, want the one named in the defaults", ep.Name)
+	}
+
+	cfg.Defaults.LocalPortRange = "6443"
+	cfg.Clusters = append(cfg.Clusters, &Cluster{ClusterName: "e"}, &Cluster{ClusterName: "f"})
+	if err := ApplyDefaults(cfg); err == nil {
+		t.Error("ApplyDefaults() with too few ports = nil error")
+	}
+}
+
+func TestReadConfigDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `version: 2
+defaults:
+  region: us-ashburn-1
+  local_port_range: 7000-7099
+clusters:
+  - cluster_name: prod
+  - cluster_name: dev
+    region: us-phoenix-1
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	prod, dev := cfg.Clusters[0], cfg.Clusters[1]
+	if prod.Region != "us-ashburn-1" || dev.Region != "us-phoenix-1" {
+		t.Errorf("regions = %q, %q", prod.Region, dev.Region)
+	}
+	if *prod.LocalPort != 7000 || *dev.LocalPort != 7001 {
+		t.Errorf("local ports = %d, %d", *prod.LocalPort, *dev.LocalPort)
+	}
+
+	// The file alone, as read to be saved, is left without them
+	file, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Clusters[0].Region != "" || file.Clusters[0].LocalPort != nil {
+		t.Errorf("ReadConfigFile() applied the defaults: %+v", file.Clusters[0])
+	}
+}
@@ -24,9 +24,7 @@ func ConfigureGlobals(config *Config) error {
 	globalState := state.GetInstance()
 
 	// Set tenancies in global state
-	if config.Tenancies != nil {
-		globalState.SetTenancies(config.Tenancies)
-	}
+	globalState.SetTenancies(config.AllTenancies())
 
 	log.Debug().Msg("Global state configured from config")
 	return nil
@@ -116,7 +114,7 @@ func LoadRemoteConfig(ctx context.Context, config *Config, ociClient interface {
 	remoteConfig := config.RemoteConfig
 
 	homePath := globalState.GetHomePath()
-	remoteConfigPath := filepath.Join(homePath, defaultOutputConfigFile)
+	remoteConfigPath := filepath.Join(globalState.GetConfigDir(), defaultOutputConfigFile)
 	configPath := filepath.Join(homePath, defaultTunaConfigFileName)
 	log.Info().Msgf("Using config file: %s", configPath)
 
@@ -146,6 +144,11 @@ func LoadRemoteConfig(ctx context.Context, config *Config, ociClient interface {
 		return remoteConfigPath, err
 	}
 
+	// A remote config of an older layout is migrated in memory only
+	if migrated, _, err := Migrate(resp, CurrentVersion); err == nil {
+		resp = migrated
+	}
+
 	remoteCfg := Config{}
 	err = yaml.Unmarshal(resp, &remoteCfg)
 	if err != nil {
@@ -155,21 +158,54 @@ func LoadRemoteConfig(ctx context.Context, config *Config, ociClient interface {
 
 	// Merge remote config into main config
 	config.Tenancies = remoteCfg.Tenancies
+	config.TenancyList = remoteCfg.TenancyList
 	config.Clusters = remoteCfg.Clusters
 
 	return remoteConfigPath, nil
 }
 
-// FindClusterByName finds a cluster by name in the config.
+// FindClusterByName finds a cluster by name in the config, or else by one
+// of its aliases.
 func FindClusterByName(config *Config, name string) *Cluster {
 	for _, cluster := range config.Clusters {
 		if strings.EqualFold(cluster.ClusterName, name) {
 			return cluster
 		}
 	}
+	for _, cluster := range config.Clusters {
+		if cluster.MatchesName(name) {
+			return cluster
+		}
+	}
 	return nil
 }
 
+// FindClustersByGroup returns the clusters of the config in group, in
+// order.
+func FindClustersByGroup(config *Config, group string) []*Cluster {
+	var clusters []*Cluster
+	for _, cluster := range config.Clusters {
+		if cluster.Group != "" && strings.EqualFold(cluster.Group, group) {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters
+}
+
+// MatchesName reports whether name is the cluster's name or one of its
+// aliases.
+func (c *Cluster) MatchesName(name string) bool {
+	if strings.EqualFold(c.ClusterName, name) {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetClusterEndpoint returns the first endpoint or a specific named endpoint.
 func GetClusterEndpoint(cluster *Cluster, name string) *ClusterEndpoint {
 	if len(cluster.Endpoints) == 0 {
@@ -188,3 +224,86 @@ func GetClusterEndpoint(cluster *Cluster, name string) *ClusterEndpoint {
 
 	return cluster.Endpoints[0]
 }
+
+// Host returns the endpoint's FQDN, or its IP if it has none.
+func (ep *ClusterEndpoint) Host() string {
+	if ep.Fqdn != "" {
+		return ep.Fqdn
+	}
+	return ep.Ip
+}
+
+// DNSPort is the port of the only UDP service an endpoint can forward to.
+// Datagrams are relayed over TCP in the framing of DNS over TCP, which other
+// UDP services do not accept.
+const DNSPort = 53
+
+// IsUDP reports whether the endpoint forwards UDP datagrams.
+func (ep *ClusterEndpoint) IsUDP() bool {
+	return strings.EqualFold(ep.Protocol, "udp")
+}
+
+// GetAdditionalEndpoints returns the endpoints other than primary that should
+// be forwarded alongside it. An endpoint without a local_port cannot be
+// forwarded, so an error naming it is returned instead.
+func GetAdditionalEndpoints(cluster *Cluster, primary *ClusterEndpoint) ([]*ClusterEndpoint, error) {
+	if primary != nil && primary.IsUDP() {
+		return nil, fmt.Errorf("endpoint '%s' of cluster '%s' is UDP and cannot be the primary endpoint", primary.Name, cluster.ClusterName)
+	}
+
+	var endpoints []*ClusterEndpoint
+	for _, ep := range cluster.Endpoints {
+		if ep == primary {
+			continue
+		}
+		if err := validateForward(ep, fmt.Sprintf("endpoint '%s' of cluster '%s'", ep.Name, cluster.ClusterName)); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, nil
+}
+
+// FindProfileByName looks up a profile by name (case-insensitive).
+func FindProfileByName(config *Config, name string) *Profile {
+	for _, p := range config.Profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetProfileForwards returns the profile's forwards, checking that each can
+// be forwarded.
+func GetProfileForwards(profile *Profile) ([]*ClusterEndpoint, error) {
+	for _, ep := range profile.Forwards {
+		if ep.Host() == "" || ep.Port == 0 {
+			return nil, fmt.Errorf("forward '%s' of profile '%s' needs an ip or fqdn and a port", ep.Name, profile.Name)
+		}
+		if err := validateForward(ep, fmt.Sprintf("forward '%s' of profile '%s'", ep.Name, profile.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return profile.Forwards, nil
+}
+
+// validateForward checks that an endpoint forwarded on its own local port
+// has one and uses a known protocol, UDP only to DNS. what names the
+// endpoint in errors.
+func validateForward(ep *ClusterEndpoint, what string) error {
+	if ep.LocalPort == nil {
+		return fmt.Errorf("%s has no local_port configured", what)
+	}
+	switch strings.ToLower(ep.Protocol) {
+	case "", "tcp":
+		return nil
+	case "udp":
+		if ep.Port != DNSPort {
+			return fmt.Errorf("%s is udp but not DNS on port %d, the only UDP service that can be forwarded", what, DNSPort)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s has unknown protocol '%s'", what, ep.Protocol)
+	}
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/scotttball/tunatap/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// IncludeDirName is the directory beside the config file whose .yaml and
+// .yml files are always included, so that each team or tool can keep its
+// clusters in a file of its own.
+const IncludeDirName = "clusters.d"
+
+// includeKeys are the keys an included file can set.
+var includeKeys = []string{"clusters", "profiles", "tenancy_list"}
+
+// LoadIncludes adds the clusters, profiles and tenancies of the files
+// included by config, read from path, to it: those matching its includes
+// patterns, in order, then those in clusters.d. It returns the files
+// included and their problems, each with the file it is in.
+//
+// The config file comes first: a cluster, profile or tenancy already
+// configured in it, or in a file included before, is ignored with a
+// problem.
+func LoadIncludes(config *Config, path string) ([]string, []error) {
+	files, _, problems := loadIncludes(config, path)
+	return files, problems
+}
+
+// ClusterIncludedFrom returns the file included by config, read from path,
+// that configures the cluster named name, or "" if none does or config
+// configures it itself. config is left as it is.
+func ClusterIncludedFrom(config *Config, path, name string) string {
+	included := &Config{
+		Includes: config.Includes,
+		Defaults: config.Defaults,
+		Clusters: slices.Clone(config.Clusters),
+	}
+	_, owners, _ := loadIncludes(included, path)
+	if owner := owners["cluster:"+strings.ToLower(name)]; owner != utils.ExpandPath(path) {
+		return owner
+	}
+	return ""
+}
+
+// loadIncludes is LoadIncludes, also returning the file configuring each
+// cluster, profile and tenancy as configuredNames does.
+func loadIncludes(config *Config, path string) ([]string, map[string]string, []error) {
+	path = utils.ExpandPath(path)
+	dir := filepath.Dir(path)
+	patterns := append(slices.Clone(config.Includes),
+		filepath.Join(IncludeDirName, "*.yaml"),
+		filepath.Join(IncludeDirName, "*.yml"))
+
+	var files []string
+	var problems []error
+	for _, pattern := range patterns {
+		pattern = utils.ExpandPath(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			// Reported by Validate
+			continue
+		}
+		if len(matches) == 0 && !hasMeta(pattern) {
+			problems = append(problems, fmt.Errorf("included file %s does not exist", pattern))
+		}
+		for _, match := range matches {
+			if match == path || slices.Contains(files, match) {
+				continue
+			}
+			if info, err := os.Stat(match); err != nil || info.IsDir() {
+				continue
+			}
+			files = append(files, match)
+		}
+	}
+
+	defaultRegion := ""
+	if config.Defaults != nil && regionPattern.MatchString(config.Defaults.Region) {
+		defaultRegion = config.Defaults.Region
+	}
+	owners := configuredNames(config, path)
+	for _, file := range files {
+		fragment, errs := readInclude(file, defaultRegion)
+		if fragment != nil {
+			errs = append(errs, mergeInclude(config, fragment, file, owners)...)
+		}
+		for _, err := range errs {
+			problems = append(problems, fmt.Errorf("%s: %w", file, err))
+		}
+	}
+	return files, owners, problems
+}
+
+// hasMeta reports whether pattern has any of the special characters of
+// filepath.Match.
+func hasMeta(pattern string) bool {
+	magic := `*?[\`
+	if runtime.GOOS == "windows" {
+		// Backslash is the path separator rather than an escape
+		magic = `*?[`
+	}
+	return strings.ContainsAny(pattern, magic)
+}
+
+// readInclude reads the included file at path, with its problems. Its
+// clusters are checked as if they inherited defaultRegion from the config.
+// It returns a nil config if the file cannot be read.
+func readInclude(path, defaultRegion string) (*Config, []error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []error{err}
+	}
+	fragment := &Config{}
+	if err := yaml.Unmarshal(data, fragment); err != nil {
+		if errs := CheckSchema(data); len(errs) > 0 {
+			return nil, errs
+		}
+		return nil, []error{fmt.Errorf("failed to parse: %w", err)}
+	}
+
+	var errs []error
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) == nil && len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		// Keys that are not options at all are reported by CheckSchema
+		fields := structFields(reflect.TypeOf(Config{}))
+		root := doc.Content[0]
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key := root.Content[i]
+			if _, ok := fields[key.Value]; ok && !slices.Contains(includeKeys, key.Value) {
+				errs = append(errs, &ValidationError{
+					Path:    key.Value,
+					Line:    key.Line,
+					Message: fmt.Sprintf("%s cannot be set in an included file, only %s and %s", key.Value, strings.Join(includeKeys[:len(includeKeys)-1], ", "), includeKeys[len(includeKeys)-1]),
+				})
+			}
+		}
+	}
+
+	check := &Config{Clusters: fragment.Clusters, Profiles: fragment.Profiles, TenancyList: fragment.TenancyList}
+	if defaultRegion != "" {
+		check.Defaults = &ClusterDefaults{Region: defaultRegion}
+	}
+	errs = append(errs, Check(check, data)...)
+	return fragment, errs
+}
+
+// configuredNames returns the file configuring each cluster, profile and
+// tenancy of config, read from path, by kind and lowercased name.
+func configuredNames(config *Config, path string) map[string]string {
+	owners := make(map[string]string)
+	for _, c := range config.Clusters {
+		owners["cluster:"+strings.ToLower(c.ClusterName)] = path
+	}
+	for _, p := range config.Profiles {
+		owners["profile:"+strings.ToLower(p.Name)] = path
+	}
+	for _, t := range config.TenancyList {
+		owners["tenancy:"+strings.ToLower(t.Name)] = path
+	}
+	return owners
+}
+
+// mergeInclude adds what fragment, included from path, configures to
+// config, except what owners shows is configured already.
+func mergeInclude(config, fragment *Config, path string, owners map[string]string) []error {
+	var errs []error
+	claim := func(kind, name string) bool {
+		if name == "" {
+			// Reported by Validate
+			return false
+		}
+		key := kind + ":" + strings.ToLower(name)
+		if owner, ok := owners[key]; ok {
+			if owner != path {
+				errs = append(errs, fmt.Errorf("%s '%s' is already configured in %s; ignoring this one", kind, name, owner))
+			}
+			return false
+		}
+		owners[key] = path
+		return true
+	}
+
+	for _, c := range fragment.Clusters {
+		if claim("cluster", c.ClusterName) {
+			config.Clusters = append(config.Clusters, c)
+		}
+	}
+	for _, p := range fragment.Profiles {
+		if claim("profile", p.Name) {
+			config.Profiles = append(config.Profiles, p)
+		}
+	}
+	for _, t := range fragment.TenancyList {
+		if claim("tenancy", t.Name) {
+			config.TenancyList = append(config.TenancyList, t)
+		}
+	}
+	return errs
+}
+
+// validateIncludes checks that the includes patterns of config are valid
+// patterns.
+func validateIncludes(config *Config, add func(string, string, ...any)) {
+	for i, pattern := range config.Includes {
+		if _, err := filepath.Match(pattern, ""); errors.Is(err, filepath.ErrBadPattern) {
+			add(fmt.Sprintf("includes[%d]", i), "includes pattern '%s' is not a valid pattern", pattern)
+		}
+	}
+}
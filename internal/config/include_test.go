@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("config.yaml", `version: 2
+includes:
+  - teams/*.yaml
+defaults:
+  region: us-ashburn-1
+clusters:
+  - cluster_name: prod
+`)
+	write("teams/b.yaml", `clusters:
+  - cluster_name: search
+`)
+	write("teams/a.yaml", `clusters:
+  - cluster_name: payments
+    region: us-phoenix-1
+  - cluster_name: Prod
+    region: eu-frankfurt-1
+profiles:
+  - name: payments
+`)
+	write(IncludeDirName+"/local.yml", `clusters:
+  - cluster_name: scratch
+  - cluster_name: search
+`)
+	write(IncludeDirName+"/notes.txt", "not included")
+
+	cfg, err := ReadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	var names []string
+	for _, c := range cfg.Clusters {
+		names = append(names, c.ClusterName+"@"+c.Region)
+	}
+	want := "prod@us-ashburn-1 payments@us-phoenix-1 search@us-ashburn-1 scratch@us-ashburn-1"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("clusters = %s, want %s", got, want)
+	}
+	if FindProfileByName(cfg, "payments") == nil {
+		t.Error("profile of an included file is missing")
+	}
+
+	// The file alone, as read to be saved, is left without them
+	file, err := ReadConfigFile(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Clusters) != 1 {
+		t.Errorf("ReadConfigFile() included files: %d clusters", len(file.Clusters))
+	}
+
+	for name, want := range map[string]string{
+		"scratch": filepath.Join(dir, IncludeDirName, "local.yml"),
+		"search":  filepath.Join(dir, "teams", "b.yaml"),
+		"prod":    "",
+		"missing": "",
+	} {
+		if got := ClusterIncludedFrom(file, filepath.Join(dir, "config.yaml"), name); got != want {
+			t.Errorf("ClusterIncludedFrom(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if len(file.Clusters) != 1 {
+		t.Errorf("ClusterIncludedFrom() changed the config: %d clusters", len(file.Clusters))
+	}
+}
+
+func TestLoadIncludesProblems(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.Mkdir(filepath.Join(dir, IncludeDirName), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	data := `clusters:
+  - cluster_name: dev
+    region: us-phoenix-1
+    local_port: 99999
+ssh_private_key_file: ~/.ssh/team
+`
+	if err := os.WriteFile(filepath.Join(dir, IncludeDirName, "team.yaml"), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		Includes: []string{"missing.yaml"},
+		Clusters: []*Cluster{{ClusterName: "dev", Region: "us-ashburn-1"}},
+	}
+	files, errs := LoadIncludes(cfg, path)
+	if len(files) != 1 {
+		t.Errorf("LoadIncludes() files = %v", files)
+	}
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	all := strings.Join(got, "\n")
+	for _, want := range []string{
+		"missing.yaml does not exist",
+		"team.yaml: line 4: cluster 'dev' local_port",
+		"team.yaml: line 5: ssh_private_key_file cannot be set in an included file",
+		"team.yaml: cluster 'dev' is already configured in " + path,
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("LoadIncludes() problems missing %q:\n%s", want, all)
+		}
+	}
+	if len(cfg.Clusters) != 1 || cfg.Clusters[0].Region != "us-ashburn-1" {
+		t.Errorf("cluster of the config file should win, got %+v", cfg.Clusters)
+	}
+
+	if errs := Validate(&Config{Includes: []string{"teams/[.yaml"}}); len(errs) != 1 {
+		t.Errorf("Validate() of a bad includes pattern = %v", errs)
+	}
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the version of the config file layout this tunatap
+// reads and writes. Files without a version key are version 1.
+const CurrentVersion = 2
+
+// BackupSuffix is added to the name of a config file for the copy of it
+// kept when it is migrated.
+const BackupSuffix = ".bak"
+
+const versionKey = "version"
+
+// migration upgrades a config document from version from to from+1.
+// apply reports whether it changed the document.
+type migration struct {
+	from        int
+	description string
+	apply       func(root *yaml.Node) bool
+}
+
+// migrations are the upgrades of the config file layout, in order.
+var migrations = []migration{
+	{
+		from:        1,
+		description: "tenancies moved into tenancy_list",
+		apply:       migrateTenanciesToList,
+	},
+}
+
+// ConfigVersion returns the layout version of the YAML config data.
+func ConfigVersion(data []byte) (int, error) {
+	doc, err := parseConfigDocument(data)
+	if err != nil {
+		return 0, err
+	}
+	if doc == nil {
+		return CurrentVersion, nil
+	}
+	return documentVersion(doc.Content[0])
+}
+
+// Migrate upgrades the YAML config data to version to, keeping its
+// comments, and returns it with a description of each step taken. Data
+// already at version to is returned unchanged, and data the steps do not
+// change only gets its version key set.
+func Migrate(data []byte, to int) ([]byte, []string, error) {
+	if to < 1 || to > CurrentVersion {
+		return nil, nil, fmt.Errorf("unknown config version %d: this tunatap knows versions 1 to %d", to, CurrentVersion)
+	}
+	doc, err := parseConfigDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if doc == nil {
+		return data, nil, nil
+	}
+	root := doc.Content[0]
+	from, err := documentVersion(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from > to {
+		return nil, nil, fmt.Errorf("config is at version %d and cannot be migrated back to version %d", from, to)
+	}
+	if from == to {
+		return data, nil, nil
+	}
+
+	var steps []string
+	changed := false
+	for _, m := range migrations {
+		if m.from >= from && m.from < to {
+			if m.apply(root) {
+				changed = true
+			}
+			steps = append(steps, fmt.Sprintf("version %d to %d: %s", m.from, m.from+1, m.description))
+		}
+	}
+
+	// Leave the file as it is otherwise, down to its line numbers
+	if !changed && mappingIndex(root, versionKey) < 0 {
+		out := bytes.TrimRight(data, "\n")
+		return append(out, fmt.Sprintf("\n%s: %d\n", versionKey, to)...), steps, nil
+	}
+	setVersion(root, to)
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return out, steps, nil
+}
+
+// MigrateFile migrates the config file at path to version to, first
+// copying it to path+BackupSuffix, and returns the steps taken. A file
+// already at version to is left alone.
+func MigrateFile(path string, to int) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	migrated, steps, err := Migrate(data, to)
+	if err != nil || len(steps) == 0 {
+		return nil, err
+	}
+	if err := os.WriteFile(path+BackupSuffix, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to back up config file: %w", err)
+	}
+	if err := os.WriteFile(path, migrated, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	return steps, nil
+}
+
+// parseConfigDocument parses YAML config data, returning nil for an empty
+// config.
+func parseConfigDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file must be a mapping of keys to values")
+	}
+	return &doc, nil
+}
+
+// documentVersion returns the version of the config mapping root.
+func documentVersion(root *yaml.Node) (int, error) {
+	i := mappingIndex(root, versionKey)
+	if i < 0 {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(root.Content[i+1].Value)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid config version %q", root.Content[i+1].Value)
+	}
+	return v, nil
+}
+
+// setVersion sets the version of the config mapping root, adding it as the
+// first key if missing.
+func setVersion(root *yaml.Node, version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	if i := mappingIndex(root, versionKey); i >= 0 {
+		root.Content[i+1] = value
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: versionKey}
+	root.Content = slices.Insert(root.Content, 0, key, value)
+}
+
+// mappingIndex returns the index of key in the mapping n, or -1.
+func mappingIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeKey removes the key at index i of the mapping n with its value,
+// handing its comment on to the next key.
+func removeKey(n *yaml.Node, i int) {
+	if comment := n.Content[i].HeadComment; comment != "" && i+2 < len(n.Content) {
+		next := n.Content[i+2]
+		next.HeadComment = strings.TrimSpace(comment + "\n" + next.HeadComment)
+	}
+	n.Content = slices.Delete(n.Content, i, i+2)
+}
+
+// migrateTenanciesToList moves the entries of the legacy tenancies map
+// into tenancy_list, where tenancies can have settings of their own. An
+// entry of tenancy_list wins over one of the same name in the map, as in
+// AllTenancies.
+func migrateTenanciesToList(root *yaml.Node) bool {
+	i := mappingIndex(root, "tenancies")
+	if i < 0 {
+		return false
+	}
+	key, tenancies := root.Content[i], root.Content[i+1]
+	if tenancies.Kind != yaml.MappingNode && tenancies.Tag != "!!null" {
+		return false
+	}
+
+	var list *yaml.Node
+	if j := mappingIndex(root, "tenancy_list"); j >= 0 {
+		if list = root.Content[j+1]; list.Kind != yaml.SequenceNode {
+			return false
+		}
+		removeKey(root, i)
+	} else {
+		list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		listKey := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "tenancy_list", HeadComment: key.HeadComment}
+		root.Content[i], root.Content[i+1] = listKey, list
+	}
+
+	names := make(map[string]bool)
+	for _, item := range list.Content {
+		if j := mappingIndex(item, "name"); j >= 0 {
+			names[item.Content[j+1].Value] = true
+		}
+	}
+	str := func(s string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	}
+	for j := 0; j+1 < len(tenancies.Content); j += 2 {
+		name, ocid := tenancies.Content[j], tenancies.Content[j+1]
+		if names[name.Value] {
+			continue
+		}
+		id := ocid.Value
+		if ocid.Tag == "!!null" {
+			id = ""
+		}
+		item := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: name.HeadComment}
+		item.Content = []*yaml.Node{str("name"), str(name.Value), str("id"), str(id)}
+		item.Content[3].LineComment = ocid.LineComment
+		list.Content = append(list.Content, item)
+	}
+	if len(list.Content) == 0 {
+		removeKey(root, mappingIndex(root, "tenancy_list"))
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const v1Config = `# team config
+tenancies:
+  acme: ocid1.tenancy.oc1..acme # main tenancy
+  labs: ocid1.tenancy.oc1..labs
+tenancy_list:
+  - name: labs
+    id: ocid1.tenancy.oc1..labs2
+    oci_profile: LABS
+clusters:
+  - cluster_name: prod
+    region: us-ashburn-1
+    tenant: acme
+`
+
+func TestMigrate(t *testing.T) {
+	data, steps, err := Migrate([]byte(v1Config), CurrentVersion)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if len(steps) != 1 {
+		t.Errorf("Migrate() steps = %v, want one", steps)
+	}
+
+	got := string(data)
+	for _, want := range []string{"# team config", "# main tenancy", "version: 2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("migrated config has no %q:\n%s", want, got)
+		}
+	}
+	if v, err := ConfigVersion(data); err != nil || v != CurrentVersion {
+		t.Errorf("ConfigVersion() = %d, %v; want %d", v, err, CurrentVersion)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Tenancies) != 0 {
+		t.Errorf("Tenancies = %v, want them moved", cfg.Tenancies)
+	}
+	if len(cfg.TenancyList) != 2 {
+		t.Fatalf("TenancyList has %d entries, want 2", len(cfg.TenancyList))
+	}
+	// The tenancy_list entry wins over the map entry of the same name
+	if labs := cfg.TenancyList[0]; labs.ID != "ocid1.tenancy.oc1..labs2" || labs.OCIProfile != "LABS" {
+		t.Errorf("labs tenancy = %+v", labs)
+	}
+	if acme := cfg.TenancyList[1]; acme.Name != "acme" || acme.ID != "ocid1.tenancy.oc1..acme" {
+		t.Errorf("acme tenancy = %+v", acme)
+	}
+
+	if again, steps, err := Migrate(data, CurrentVersion); err != nil || len(steps) != 0 || string(again) != got {
+		t.Errorf("Migrate() of a current config = %v, %v; want it unchanged", steps, err)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	if _, _, err := Migrate([]byte(v1Config), CurrentVersion+1); err == nil {
+		t.Error("Migrate() to an unknown version = nil, want an error")
+	}
+	if _, _, err := Migrate([]byte("version: 2\n"), 1); err == nil {
+		t.Error("Migrate() to an older version = nil, want an error")
+	}
+	if _, _, err := Migrate([]byte("version: two\n"), CurrentVersion); err == nil {
+		t.Error("Migrate() of an invalid version = nil, want an error")
+	}
+}
+
+func TestMigrateOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(v1Config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatalf("ReadConfigFile() error = %v", err)
+	}
+	if cfg.Version != CurrentVersion || len(cfg.TenancyList) != 2 {
+		t.Errorf("ReadConfigFile() = version %d with %d tenancies", cfg.Version, len(cfg.TenancyList))
+	}
+	if ocid := cfg.AllTenancies()["acme"]; ocid == nil || *ocid != "ocid1.tenancy.oc1..acme" {
+		t.Errorf("AllTenancies()[acme] = %v", ocid)
+	}
+
+	backup, err := os.ReadFile(path + BackupSuffix)
+	if err != nil || string(backup) != v1Config {
+		t.Errorf("backup = %q, %v; want the original config", backup, err)
+	}
+	data, _ := os.ReadFile(path)
+	if v, _ := ConfigVersion(data); v != CurrentVersion {
+		t.Errorf("config file version = %d after loading, want %d", v, CurrentVersion)
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("clusters: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	steps, err := MigrateFile(path, 2)
+	if err != nil || len(steps) != 1 {
+		t.Fatalf("MigrateFile() = %v, %v", steps, err)
+	}
+	// Nothing to move, so the file only gains its version
+	if data, _ := os.ReadFile(path); string(data) != "clusters: []\nversion: 2\n" {
+		t.Errorf("migrated config file = %q", data)
+	}
+	if steps, err := MigrateFile(path, 2); err != nil || len(steps) != 0 {
+		t.Errorf("MigrateFile() again = %v, %v; want nothing to do", steps, err)
+	}
+}
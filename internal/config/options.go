@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the environment variables that override options, such
+// as TUNATAP_HEALTH_ENDPOINT for health_endpoint.
+const EnvPrefix = "TUNATAP_"
+
+// OptionKeys returns the keys of every option, sorted. Options are the
+// scalar settings of a Config, such as "session_ttl_minutes" or
+// "retry.max_attempts"; clusters, tenancies, profiles and catalog sources
+// are not options, and neither is the version of the config layout.
+func OptionKeys() []string {
+	var keys []string
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := yamlName(f)
+			if name == "" || (prefix == "" && name == versionKey) {
+				continue
+			}
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer && ft.Elem().Kind() == reflect.Struct {
+				walk(ft.Elem(), prefix+name+".")
+				continue
+			}
+			if isOptionType(ft) {
+				keys = append(keys, prefix+name)
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	sort.Strings(keys)
+	return keys
+}
+
+// OptionEnvVar returns the environment variable overriding an option.
+func OptionEnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// GetOption returns the value of an option, or nil if it is not set.
+func GetOption(config *Config, key string) (any, error) {
+	v, err := optionValue(config, key, false)
+	if err != nil {
+		return nil, err
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return v.Elem().Interface(), nil
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil, nil
+		}
+	}
+	if v.IsZero() {
+		return nil, nil
+	}
+	return v.Interface(), nil
+}
+
+// SetOption parses value and sets the option to it. Lists are given
+// comma-separated.
+func SetOption(config *Config, key, value string) error {
+	v, err := optionValue(config, key, true)
+	if err != nil {
+		return err
+	}
+
+	t := v.Type()
+	if t.Kind() == reflect.Slice {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	}
+
+	elem := t
+	if t.Kind() == reflect.Pointer {
+		elem = t.Elem()
+	}
+	parsed := reflect.New(elem).Elem()
+	switch elem.Kind() {
+	case reflect.String:
+		parsed.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, not '%s'", key, value)
+		}
+		parsed.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number, not '%s'", key, value)
+		}
+		parsed.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, not '%s'", key, value)
+		}
+		parsed.SetFloat(f)
+	}
+
+	if t.Kind() == reflect.Pointer {
+		v.Set(parsed.Addr())
+	} else {
+		v.Set(parsed)
+	}
+	return nil
+}
+
+// UnsetOption clears an option so its default applies.
+func UnsetOption(config *Config, key string) error {
+	v, err := optionValue(config, key, false)
+	if err != nil {
+		return err
+	}
+	if v.IsValid() {
+		v.Set(reflect.Zero(v.Type()))
+	}
+	return nil
+}
+
+// ApplyEnv sets the options whose environment variable is set, and returns
+// the variables applied.
+func ApplyEnv(config *Config) ([]string, error) {
+	var applied []string
+	for _, key := range OptionKeys() {
+		name := OptionEnvVar(key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := SetOption(config, key, value); err != nil {
+			return applied, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		applied = append(applied, name)
+	}
+	return applied, nil
+}
+
+// optionValue returns the settable field of an option. Parent structs are
+// allocated when create is set; otherwise a missing parent returns the
+// zero Value.
+func optionValue(config *Config, key string, create bool) (reflect.Value, error) {
+	if err := checkOptionKey(key); err != nil {
+		return reflect.Value{}, err
+	}
+
+	v := reflect.ValueOf(config).Elem()
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		f, _ := fieldByYAMLName(v, part)
+		if f.IsNil() {
+			if !create {
+				return reflect.Value{}, nil
+			}
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		v = f.Elem()
+	}
+	f, _ := fieldByYAMLName(v, parts[len(parts)-1])
+	return f, nil
+}
+
+// checkOptionKey returns an error unless key is an option.
+func checkOptionKey(key string) error {
+	for _, k := range OptionKeys() {
+		if k == key {
+			return nil
+		}
+	}
+	top, _, _ := strings.Cut(key, ".")
+	if _, ok := fieldByYAMLName(reflect.ValueOf(Config{}), top); ok && !strings.Contains(key, ".") {
+		return fmt.Errorf("'%s' is not an option; edit it with the other config commands", key)
+	}
+	return fmt.Errorf("unknown option '%s'", key)
+}
+
+// fieldByYAMLName returns the field of struct v with the given YAML name.
+func fieldByYAMLName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if yamlName(t.Field(i)) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// yamlName returns the YAML key of a field, or "" if it has none.
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// isOptionType reports whether values of t can be parsed from a string.
+func isOptionType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		return t.Elem().Kind() == reflect.String
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestOptionKeys(t *testing.T) {
+	keys := OptionKeys()
+	for _, want := range []string{"session_ttl_minutes", "retry.max_attempts", "ssh_connection_pool_autoscale.max", "discovery_regions", "audit_logging"} {
+		if !slices.Contains(keys, want) {
+			t.Errorf("OptionKeys() is missing %s", want)
+		}
+	}
+	for _, notOption := range []string{"clusters", "tenancies", "profiles", "catalog_sources", "retry"} {
+		if slices.Contains(keys, notOption) {
+			t.Errorf("OptionKeys() contains %s", notOption)
+		}
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	cfg := &Config{}
+	tests := []struct {
+		key   string
+		value string
+		want  any
+	}{
+		{"health_endpoint", "localhost:9090", "localhost:9090"},
+		{"session_ttl_minutes", "120", 120},
+		{"audit_logging", "false", false},
+		{"cache_encryption", "true", true},
+		{"retry.multiplier", "2.5", 2.5},
+		{"ssh_connection_pool_autoscale.max", "8", 8},
+		{"discovery_regions", "us-ashburn-1, us-phoenix-1", []string{"us-ashburn-1", "us-phoenix-1"}},
+	}
+
+	for _, tt := range tests {
+		if err := SetOption(cfg, tt.key, tt.value); err != nil {
+			t.Fatalf("SetOption(%s) error = %v", tt.key, err)
+		}
+		got, err := GetOption(cfg, tt.key)
+		if err != nil {
+			t.Fatalf("GetOption(%s) error = %v", tt.key, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetOption(%s) = %#v, want %#v", tt.key, got, tt.want)
+		}
+	}
+
+	if cfg.IsAuditLoggingEnabled() {
+		t.Error("audit_logging=false should disable audit logging")
+	}
+}
+
+func TestSetOptionErrors(t *testing.T) {
+	cfg := &Config{}
+	for _, tt := range []struct{ key, value string }{
+		{"session_ttl_minutes", "two hours"},
+		{"cache_encryption", "maybe"},
+		{"no_such_option", "1"},
+		{"clusters", "prod"},
+		{"retry.no_such_option", "1"},
+	} {
+		if err := SetOption(cfg, tt.key, tt.value); err == nil {
+			t.Errorf("SetOption(%s, %s) should fail", tt.key, tt.value)
+		}
+	}
+}
+
+func TestGetOptionUnset(t *testing.T) {
+	cfg := &Config{}
+	for _, key := range []string{"health_endpoint", "session_ttl_minutes", "retry.max_attempts", "discovery_regions"} {
+		got, err := GetOption(cfg, key)
+		if err != nil || got != nil {
+			t.Errorf("GetOption(%s) = %v, %v; want nil, nil", key, got, err)
+		}
+	}
+	if cfg.Retry != nil {
+		t.Error("GetOption should not allocate parent structs")
+	}
+}
+
+func TestUnsetOption(t *testing.T) {
+	ttl := 60
+	cfg := &Config{SessionTTLMinutes: &ttl, HealthEndpoint: "localhost:9090"}
+	if err := UnsetOption(cfg, "session_ttl_minutes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnsetOption(cfg, "health_endpoint"); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnsetOption(cfg, "retry.max_attempts"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SessionTTLMinutes != nil || cfg.HealthEndpoint != "" {
+		t.Errorf("options not unset: %+v", cfg)
+	}
+}
+
+func TestOptionEnvVar(t *testing.T) {
+	if got := OptionEnvVar("retry.max_attempts"); got != "TUNATAP_RETRY_MAX_ATTEMPTS" {
+		t.Errorf("OptionEnvVar() = %s", got)
+	}
+}
+
+func TestReadConfigEnvOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("session_ttl_minutes: 60\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TUNATAP_SESSION_TTL_MINUTES", "90")
+
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	if cfg.GetSessionTTLMinutes(nil) != 90 {
+		t.Errorf("session TTL = %d, want the override 90", cfg.GetSessionTTLMinutes(nil))
+	}
+
+	cfg, err = ReadConfigFile(path)
+	if err != nil {
+		t.Fatalf("ReadConfigFile() error = %v", err)
+	}
+	if cfg.GetSessionTTLMinutes(nil) != 60 {
+		t.Errorf("ReadConfigFile() session TTL = %d, want 60", cfg.GetSessionTTLMinutes(nil))
+	}
+
+	t.Setenv("TUNATAP_SESSION_TTL_MINUTES", "soon")
+	if _, err := ReadConfig(path); err == nil {
+		t.Error("ReadConfig() should fail on an invalid override")
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,13 +11,86 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// ReadConfig loads configuration from a YAML file.
+// ReadConfig loads configuration from a YAML file, with the options set in
+// TUNATAP_* environment variables overriding it.
+//
+// The file is checked with Check, and each problem, such as a misspelled
+// key, logged as a warning; with strict_config set, they fail the read
+// instead. The clusters, profiles and tenancies of the files it includes,
+// and of those in clusters.d beside it, are added to it, and its clusters
+// then inherit the settings of its defaults.
 func ReadConfig(path string) (*Config, error) {
+	config, data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := ApplyEnv(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range applied {
+		log.Debug().Msgf("Config overridden by %s", name)
+	}
+
+	var errs []error
+	if len(data) > 0 {
+		errs = Check(config, data)
+	}
+	files, includeErrs := LoadIncludes(config, path)
+	for _, file := range files {
+		log.Debug().Msgf("Included config file %s", file)
+	}
+	if len(errs) > 0 || len(includeErrs) > 0 {
+		if config.StrictConfig {
+			return nil, fmt.Errorf("invalid config file %s (strict_config is set):\n%w", path, errors.Join(append(errs, includeErrs...)...))
+		}
+		for _, err := range errs {
+			log.Warn().Msgf("Config file %s: %v", path, err)
+		}
+		for _, err := range includeErrs {
+			log.Warn().Msgf("Included config file %v", err)
+		}
+	}
+
+	if err := ApplyDefaults(config); err != nil {
+		log.Warn().Msgf("Config file %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// ReadConfigFile loads configuration from a YAML file alone. Commands that
+// save the config read it with this so environment overrides and included
+// files are not written back.
+func ReadConfigFile(path string) (*Config, error) {
+	config, _, err := readConfigFile(path)
+	return config, err
+}
+
+// ReadLogFileConfig reads only the log_file settings of the config file at
+// path, without logging anything, so that logging can be set up before the
+// config is loaded. It returns nil if the file cannot be read or has none.
+func ReadLogFileConfig(path string) *LogFile {
+	data, err := os.ReadFile(utils.ExpandPath(path))
+	if err != nil {
+		return nil
+	}
+	var partial struct {
+		LogFile *LogFile `yaml:"log_file"`
+	}
+	if err := yaml.Unmarshal(data, &partial); err != nil {
+		return nil
+	}
+	return partial.LogFile
+}
+
+// readConfigFile is ReadConfigFile, also returning the file's contents.
+func readConfigFile(path string) (*Config, []byte, error) {
 	// Expand ~ to home directory
 	if len(path) > 0 && path[0] == '~' {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
+			return nil, nil, fmt.Errorf("failed to get home directory: %w", err)
 		}
 		path = filepath.Join(home, path[1:])
 	}
@@ -25,20 +99,22 @@ func ReadConfig(path string) (*Config, error) {
 	if err != nil {
 		if os.IsNotExist(err) {
 			log.Info().Msgf("Config file not found at %s, using defaults", path)
-			return DefaultConfig(), nil
+			return DefaultConfig(), nil, nil
 		}
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
 	// Handle empty config file
 	if len(data) == 0 {
 		log.Info().Msg("Config file is empty, using defaults")
-		return DefaultConfig(), nil
+		return DefaultConfig(), nil, nil
 	}
 
+	data = migrateOnLoad(path, data)
+
 	config := DefaultConfig()
 	if err := yaml.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	// Apply defaults for nil pointer fields
@@ -61,7 +137,37 @@ func ReadConfig(path string) (*Config, error) {
 		log.Debug().Msgf("Using default SSH key: %s", config.SshPrivateKeyFile)
 	}
 
-	return config, nil
+	return config, data, nil
+}
+
+// migrateOnLoad migrates config data of an older layout read from path to
+// CurrentVersion. The file is rewritten, keeping the original next to it;
+// if that fails, the migrated config is still used for this run.
+func migrateOnLoad(path string, data []byte) []byte {
+	version, err := ConfigVersion(data)
+	if err != nil || version >= CurrentVersion {
+		return data
+	}
+	migrated, steps, err := Migrate(data, CurrentVersion)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to migrate config file %s", path)
+		return data
+	}
+	for _, step := range steps {
+		log.Debug().Msgf("Config migration %s", step)
+	}
+
+	backup := path + BackupSuffix
+	if err := os.WriteFile(backup, data, 0o600); err != nil {
+		log.Warn().Err(err).Msgf("Config file %s is version %d; migrated it for this run only", path, version)
+		return migrated
+	}
+	if err := os.WriteFile(path, migrated, 0o600); err != nil {
+		log.Warn().Err(err).Msgf("Config file %s is version %d; migrated it for this run only", path, version)
+		return migrated
+	}
+	log.Info().Msgf("Migrated config file %s from version %d to %d; the original is in %s", path, version, CurrentVersion, backup)
+	return migrated
 }
 
 // SaveConfig writes configuration to a YAML file.
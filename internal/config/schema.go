@@ -0,0 +1,337 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CheckSchema checks the YAML of a config file against the Config type:
+// keys that are not options, such as misspelled ones, and values of the
+// wrong type. Unlike parsing, it reports every problem, each with its line.
+func CheckSchema(data []byte) []error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []error{err}
+	}
+	c := &schemaChecker{}
+	if len(doc.Content) > 0 {
+		c.check(doc.Content[0], reflect.TypeOf(Config{}), "")
+	}
+	return c.errs
+}
+
+// Check returns every problem of a config read from data: those found by
+// CheckSchema in the file and by Validate in cfg, with their lines, in the
+// order of the file.
+func Check(cfg *Config, data []byte) []error {
+	errs := append(CheckSchema(data), Validate(cfg)...)
+	Locate(data, errs)
+	// Problems without a line go last
+	order := func(err error) int {
+		if line := errorLine(err); line > 0 {
+			return line
+		}
+		return math.MaxInt
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return order(errs[i]) < order(errs[j])
+	})
+	return errs
+}
+
+// Locate sets the line of the ValidationErrors in errs to where their path
+// is in data, or the closest enclosing key that is there, such as the
+// cluster missing a field.
+func Locate(data []byte, errs []error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return
+	}
+	lines := make(map[string]int)
+	nodeLines(doc.Content[0], "", lines)
+
+	for _, err := range errs {
+		var v *ValidationError
+		if !errors.As(err, &v) || v.Line > 0 {
+			continue
+		}
+		for path := v.Path; path != ""; path = parentPath(path) {
+			if line, ok := lines[path]; ok {
+				v.Line = line
+				break
+			}
+		}
+	}
+}
+
+// errorLine is the line of err, or 0 if it has none.
+func errorLine(err error) int {
+	var v *ValidationError
+	if errors.As(err, &v) {
+		return v.Line
+	}
+	return 0
+}
+
+// nodeLines records the line of every key and sequence item under n.
+func nodeLines(n *yaml.Node, path string, lines map[string]int) {
+	n = resolveAlias(n)
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := joinPath(path, n.Content[i].Value)
+			lines[key] = n.Content[i].Line
+			nodeLines(n.Content[i+1], key, lines)
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			key := fmt.Sprintf("%s[%d]", path, i)
+			lines[key] = item.Line
+			nodeLines(item, key, lines)
+		}
+	}
+}
+
+// parentPath returns the path enclosing path: clusters[0] for
+// clusters[0].region and clusters for clusters[0].
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// schemaChecker collects the problems CheckSchema finds.
+type schemaChecker struct {
+	errs []error
+}
+
+func (c *schemaChecker) add(n *yaml.Node, path, format string, args ...any) {
+	c.errs = append(c.errs, &ValidationError{Path: path, Line: n.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+// check checks the node at path against t.
+func (c *schemaChecker) check(n *yaml.Node, t reflect.Type, path string) {
+	n = resolveAlias(n)
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	what := path
+	if what == "" {
+		what = "the config"
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			c.add(n, path, "%s must be a mapping of keys to values", what)
+			return
+		}
+		fields := structFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			keyPath := joinPath(path, key.Value)
+			f, ok := fields[key.Value]
+			if !ok {
+				c.add(key, keyPath, "unknown key '%s'%s", key.Value, suggestKey(key.Value, fields))
+				continue
+			}
+			c.check(value, f.Type, keyPath)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			c.add(n, path, "%s must be a mapping of keys to values", what)
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			c.check(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			c.add(n, path, "%s must be a list", what)
+			return
+		}
+		for i, item := range n.Content {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode {
+			c.add(n, path, "%s must be a string", what)
+		}
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" {
+			c.add(n, path, "%s must be true or false, not %s", what, describeNode(n))
+		}
+	case reflect.Int:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!int" {
+			c.add(n, path, "%s must be a whole number, not %s", what, describeNode(n))
+		}
+	case reflect.Float64:
+		if n.Kind != yaml.ScalarNode || (n.Tag != "!!int" && n.Tag != "!!float") {
+			c.add(n, path, "%s must be a number, not %s", what, describeNode(n))
+		}
+	}
+}
+
+// describeNode describes a value of the wrong type for an error.
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return "'" + n.Value + "'"
+}
+
+// structFields returns the fields of t by their YAML key.
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name := yamlName(f); name != "" && f.IsExported() {
+			fields[name] = f
+		}
+	}
+	return fields
+}
+
+// suggestKey returns a "did you mean" hint naming the known key closest to
+// key, or "" if none is close.
+func suggestKey(key string, fields map[string]reflect.StructField) string {
+	best, bestDist := "", 4
+	for name := range fields {
+		d := keyDistance(key, name)
+		if d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean '%s'?)", best)
+}
+
+// keyDistance returns the number of single-byte insertions, deletions and
+// substitutions that turn a into b.
+func keyDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// SchemaID is the $id of the JSON Schema of the config file.
+const SchemaID = "https://github.com/scotttball/tunatap/config.schema.json"
+
+// JSONSchema returns a JSON Schema of the config file, for editors that
+// complete and check YAML with one. It describes the same keys and types
+// as CheckSchema, and the option values, ports, OCIDs and regions Validate
+// accepts.
+func JSONSchema() map[string]any {
+	schema := typeSchema(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = "tunatap config"
+
+	props := schema["properties"].(map[string]any)
+	for key, choices := range optionChoices {
+		if p, ok := props[key].(map[string]any); ok {
+			p["enum"] = choices
+		}
+	}
+	return schema
+}
+
+// typeSchema returns the schema of values of t, found under key.
+func typeSchema(t reflect.Type, key string) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		props := make(map[string]any)
+		for name, f := range structFields(t) {
+			props[name] = typeSchema(f.Type, name)
+		}
+		s := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+		if t == reflect.TypeOf(Cluster{}) {
+			s["required"] = []string{"cluster_name", "region"}
+		}
+		return s
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), "")}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), key)}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int:
+		s := map[string]any{"type": "integer"}
+		switch key {
+		case "port", "local_port":
+			s["minimum"], s["maximum"] = 1, 65535
+		case "socks_port":
+			s["minimum"], s["maximum"] = 0, 65535
+		case "session_ttl_minutes":
+			s["minimum"], s["maximum"] = 30, 180
+		}
+		return s
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+
+	s := map[string]any{"type": "string"}
+	switch key {
+	case "region", "oci_region", "discovery_regions":
+		s["pattern"] = regionPattern.String()
+	case "ocid", "compartment_ocid", "tenant_ocid", "tenancy_ocid", "bastion_id", "fallback_bastion_ids", "id":
+		s["pattern"] = `^ocid1\.[a-z0-9]+\.[a-z0-9]+\.[a-z0-9-]*\..+$`
+	case "port_strategy":
+		s["enum"] = []string{"increment", "fail", "takeover"}
+	case "oci_auth_type":
+		s["enum"] = optionChoices["oci_auth_type"]
+	}
+	return s
+}
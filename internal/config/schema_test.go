@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const checkedConfig = `ssh_private_keyfile: ~/.ssh/id_ed25519
+ssh_connection_pool_size: lots
+clusters:
+  - cluster_name: prod
+    region: us-ashburn-1
+    ocid: ocid1.cluster.oc1.iad.aaaa
+    bastion_id: cluster-bastion
+    endpoints:
+      - name: private
+        ip: 10.0.0.5
+        port: 70000
+  - cluster_name: dev
+    region: Phoenix
+    hops:
+      - host: jump
+        prot: 22
+`
+
+func TestCheckSchema(t *testing.T) {
+	errs := CheckSchema([]byte(checkedConfig))
+	want := []string{
+		"line 1: unknown key 'ssh_private_keyfile' (did you mean 'ssh_private_key_file'?)",
+		"line 2: ssh_connection_pool_size must be a whole number, not 'lots'",
+		"line 16: unknown key 'prot' (did you mean 'port'?)",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("CheckSchema() = %v, want %d errors", errs, len(want))
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, err, want[i])
+		}
+	}
+
+	if errs := CheckSchema([]byte("clusters: [\n")); len(errs) != 1 {
+		t.Errorf("CheckSchema() of invalid YAML = %v, want the parse error", errs)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// Parse what does parse, as the config of a valid file would be
+	data := strings.Replace(checkedConfig, "lots", "5", 1)
+	cfg := &Config{}
+	if err := yaml.Unmarshal([]byte(data), cfg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	errs := Check(cfg, []byte(data))
+	want := []string{
+		"line 1: unknown key 'ssh_private_keyfile'",
+		"line 7: cluster 'prod' bastion_id: invalid OCID format: cluster-bastion",
+		"line 11: endpoint 'private' of cluster 'prod' port must be between 1 and 65535, not 70000",
+		"line 13: cluster 'dev' region 'Phoenix' is not an OCI region",
+		"line 16: unknown key 'prot'",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Check() = %v, want %d errors", errs, len(want))
+	}
+	for i, err := range errs {
+		if !strings.HasPrefix(err.Error(), want[i]) {
+			t.Errorf("error %d = %q, want %q", i, err, want[i])
+		}
+	}
+}
+
+func TestLocateEnclosingKey(t *testing.T) {
+	data := []byte("clusters:\n  - cluster_name: prod\n    local_port: 6443\n")
+	errs := Validate(&Config{Clusters: []*Cluster{{ClusterName: "prod"}}})
+	Locate(data, errs)
+	if len(errs) != 1 || errs[0].Error() != "line 2: cluster 'prod' has no region" {
+		t.Errorf("Locate() = %v, want the missing region at the cluster's line", errs)
+	}
+}
+
+func TestReadConfigStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("sesion_ttl_minutes: 60\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadConfig(path); err != nil {
+		t.Fatalf("ReadConfig() error = %v, want only a warning", err)
+	}
+
+	t.Setenv("TUNATAP_STRICT_CONFIG", "true")
+	_, err := ReadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "line 1: unknown key 'sesion_ttl_minutes'") {
+		t.Errorf("ReadConfig() with strict_config error = %v, want the unknown key", err)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema()
+	props := schema["properties"].(map[string]any)
+	if schema["additionalProperties"] != false {
+		t.Error("JSONSchema() allows unknown top-level keys")
+	}
+	policy := props["ssh_host_key_policy"].(map[string]any)
+	if enum, _ := policy["enum"].([]string); len(enum) != 3 {
+		t.Errorf("ssh_host_key_policy enum = %v", policy["enum"])
+	}
+	cluster := props["clusters"].(map[string]any)["items"].(map[string]any)
+	port := cluster["properties"].(map[string]any)["local_port"].(map[string]any)
+	if port["type"] != "integer" || port["maximum"] != 65535 {
+		t.Errorf("local_port schema = %v", port)
+	}
+}
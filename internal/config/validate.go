@@ -0,0 +1,471 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/scotttball/tunatap/internal/secrets"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// secretOptions are the options that can hold secret references.
+var secretOptions = []string{"ssh_private_key_passphrase", "ssh_socks_proxy_password"}
+
+// optionChoices are the values allowed for options that take one of a set.
+var optionChoices = map[string][]string{
+	"ssh_host_key_policy":      {"prompt", "accept-new", "strict"},
+	"oci_auth_type":            {"auto", "config", "instance_principal", "security_token", "resource_principal", "oke_workload_identity"},
+	"idle_action":              {"shutdown", "shrink"},
+	"endpoint_change_action":   {"warn", "reconnect"},
+	"discovery_method":         {"compartments", "search"},
+	"save_discovered_clusters": {"never", "prompt", "always"},
+	"ephemeral_key_algorithm":  {"ed25519", "rsa-4096"},
+	"defaults.bastion_type":    {"STANDARD", "INTERNAL"},
+}
+
+// regionPattern matches region identifiers such as us-ashburn-1, and the
+// three-letter region keys such as iad.
+var regionPattern = regexp.MustCompile(`^([a-z]{2}(-[a-z]+)+-[0-9]+|[a-z]{3})$`)
+
+// namespacePattern matches Kubernetes namespace names.
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// ValidationError is a problem found in a config. Path locates it in the
+// YAML, such as clusters[0].local_port, and Line is its line in the config
+// file once Locate has looked it up.
+type ValidationError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// Validate checks a config for mistakes that would only show up when
+// connecting, such as missing cluster fields, duplicate names, bad ports,
+// OCIDs and regions, and unknown option values. It returns every problem
+// found, as ValidationErrors.
+func Validate(config *Config) []error {
+	var errs []error
+	add := func(path, format string, args ...any) {
+		errs = append(errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if config.Version > CurrentVersion {
+		add(versionKey, "config version %d is newer than this tunatap supports (%d); upgrade tunatap", config.Version, CurrentVersion)
+	}
+
+	keys := make([]string, 0, len(optionChoices))
+	for key := range optionChoices {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		choices := optionChoices[key]
+		v, _ := GetOption(config, key)
+		if s, ok := v.(string); ok && !slices.Contains(choices, s) {
+			add(key, "%s must be one of %s, not '%s'", key, strings.Join(choices, ", "), s)
+		}
+	}
+	for _, key := range secretOptions {
+		v, _ := GetOption(config, key)
+		if s, ok := v.(string); ok {
+			validateSecret(s, key, key, add)
+		}
+	}
+	if config.SshSocksProxyPassword != "" && config.SshSocksProxyUser == "" {
+		add("ssh_socks_proxy_user", "ssh_socks_proxy_password is set without ssh_socks_proxy_user")
+	}
+	if config.SshConnectionPoolSize != nil && *config.SshConnectionPoolSize < 1 {
+		add("ssh_connection_pool_size", "ssh_connection_pool_size must be at least 1")
+	}
+	if config.SessionTTLMinutes != nil {
+		validateSessionTTL(*config.SessionTTLMinutes, "session_ttl_minutes", "session_ttl_minutes", add)
+	}
+	validateIncludes(config, add)
+	for i, region := range config.DiscoveryRegions {
+		validateRegion(region, fmt.Sprintf("discovery_regions[%d]", i), "discovery_regions", add)
+	}
+
+	defaultRegion := ""
+	if d := config.Defaults; d != nil {
+		if d.Region != "" {
+			validateRegion(d.Region, "defaults.region", "defaults region", add)
+			if regionPattern.MatchString(d.Region) {
+				defaultRegion = d.Region
+			}
+		}
+		if d.LocalPortRange != "" {
+			if _, _, err := ParsePortRange(d.LocalPortRange); err != nil {
+				add("defaults.local_port_range", "defaults local_port_range: %v", err)
+			}
+		}
+	}
+
+	// Aliases must not name another cluster
+	clusterNames := make(map[string]*Cluster)
+	for _, c := range config.Clusters {
+		if name := strings.ToLower(c.ClusterName); clusterNames[name] == nil {
+			clusterNames[name] = c
+		}
+	}
+	aliasOwners := make(map[string]*Cluster)
+
+	seen := make(map[string]bool)
+	for i, c := range config.Clusters {
+		path := fmt.Sprintf("clusters[%d]", i)
+		if c.ClusterName == "" {
+			add(path, "cluster %d has no cluster_name", i+1)
+			continue
+		}
+		what := fmt.Sprintf("cluster '%s'", c.ClusterName)
+		if name := strings.ToLower(c.ClusterName); seen[name] {
+			add(path+".cluster_name", "%s is configured more than once", what)
+		} else {
+			seen[name] = true
+		}
+		for j, alias := range c.Aliases {
+			aliasPath := fmt.Sprintf("%s.aliases[%d]", path, j)
+			name := strings.ToLower(alias)
+			switch {
+			case alias == "":
+				add(aliasPath, "%s has an empty alias", what)
+			case clusterNames[name] != nil && clusterNames[name] != c:
+				add(aliasPath, "alias '%s' of %s is the name of cluster '%s'", alias, what, clusterNames[name].ClusterName)
+			case aliasOwners[name] != nil && aliasOwners[name] != c:
+				add(aliasPath, "alias '%s' of %s is also an alias of cluster '%s'", alias, what, aliasOwners[name].ClusterName)
+			default:
+				aliasOwners[name] = c
+			}
+		}
+		if c.Region == "" && defaultRegion != "" {
+			// The cluster inherits the region of the defaults
+			inherited := *c
+			inherited.Region = defaultRegion
+			c = &inherited
+		}
+		validateCluster(c, path, what, add)
+	}
+
+	seen = make(map[string]bool)
+	for i, p := range config.Profiles {
+		path := fmt.Sprintf("profiles[%d]", i)
+		if p.Name == "" {
+			add(path, "profile %d has no name", i+1)
+			continue
+		}
+		if name := strings.ToLower(p.Name); seen[name] {
+			add(path+".name", "profile '%s' is configured more than once", p.Name)
+		} else {
+			seen[name] = true
+		}
+		if p.SocksPort != 0 {
+			validatePort(p.SocksPort, path+".socks_port", fmt.Sprintf("profile '%s' socks_port", p.Name), add)
+		}
+		if _, err := GetProfileForwards(p); err != nil {
+			add(path+".forwards", "%v", err)
+		}
+	}
+
+	seen = make(map[string]bool)
+	for i, s := range config.CatalogSources {
+		path := fmt.Sprintf("catalog_sources[%d]", i)
+		if s.Name == "" || s.URL == "" {
+			add(path, "catalog source %d needs a name and a url", i+1)
+			continue
+		}
+		if seen[s.Name] {
+			add(path+".name", "catalog source '%s' is configured more than once", s.Name)
+		}
+		seen[s.Name] = true
+		if s.OCIRegion != "" {
+			validateRegion(s.OCIRegion, path+".oci_region", fmt.Sprintf("catalog source '%s' oci_region", s.Name), add)
+		}
+		validateSecret(s.AuthToken, path+".auth_token", fmt.Sprintf("catalog source '%s' auth_token", s.Name), add)
+	}
+
+	for i, t := range config.TenancyList {
+		path := fmt.Sprintf("tenancy_list[%d]", i)
+		if t.Name == "" || t.ID == "" {
+			add(path, "tenancy %d of tenancy_list needs a name and an id", i+1)
+			continue
+		}
+		validateOCID(t.ID, "tenancy", path+".id", fmt.Sprintf("tenancy '%s' id", t.Name), add)
+		if t.OCIAuthType != "" {
+			validateAuthType(t.OCIAuthType, path+".oci_auth_type", fmt.Sprintf("tenancy '%s' oci_auth_type", t.Name), add)
+		}
+	}
+
+	for i, sink := range config.AuditSinks {
+		validateAuditSink(sink, fmt.Sprintf("audit_sinks[%d]", i), add)
+	}
+
+	if t := config.Tracing; t != nil {
+		validateTracing(t, add)
+	}
+
+	if r := config.OCIRetry; r != nil {
+		validateOCIRetry(r, add)
+	}
+
+	if l := config.LogFile; l != nil {
+		validateLogFile(l, add)
+	}
+
+	if rc := config.RemoteConfig; rc != nil {
+		if rc.Region != "" {
+			validateRegion(rc.Region, "remote_config.region", "remote_config region", add)
+		}
+		if rc.TenancyOcid != "" {
+			validateOCID(rc.TenancyOcid, "tenancy", "remote_config.tenancy_ocid", "remote_config tenancy_ocid", add)
+		}
+		fields := structFields(reflect.TypeOf(Config{}))
+		for i, key := range rc.Keys {
+			if _, ok := fields[key]; !ok || key == "remote_config" {
+				add(fmt.Sprintf("remote_config.keys[%d]", i), "remote_config keys: '%s' is not a config key that can be synced", key)
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateAuditSink checks the fields of the audit sink at path.
+func validateAuditSink(sink *AuditSink, path string, add func(string, string, ...any)) {
+	what := fmt.Sprintf("audit sink %s", path)
+	switch sink.Type {
+	case "webhook":
+		u, err := url.Parse(sink.URL)
+		switch {
+		case sink.URL == "":
+			add(path+".url", "%s has no url", what)
+		case err != nil || u.Host == "":
+			add(path+".url", "%s url '%s' is not a URL", what, sink.URL)
+		case u.Scheme != "https" && !isLoopbackHost(u.Hostname()):
+			add(path+".url", "%s url '%s' must be https", what, sink.URL)
+		}
+		for name, value := range sink.Headers {
+			validateSecret(value, path+".headers."+name, fmt.Sprintf("%s header %s", what, name), add)
+		}
+	case "oci_logging":
+		if sink.LogID == "" {
+			add(path+".log_id", "%s has no log_id", what)
+		} else {
+			validateOCID(sink.LogID, "log", path+".log_id", what+" log_id", add)
+		}
+		if sink.Region != "" {
+			validateRegion(sink.Region, path+".region", what+" region", add)
+		}
+	case "syslog":
+		if sink.Address != "" && sink.Network == "" {
+			add(path+".network", "%s has an address but no network, such as udp or tcp", what)
+		}
+	default:
+		add(path+".type", "%s type '%s' must be webhook, oci_logging or syslog", what, sink.Type)
+	}
+	if sink.BufferSize != nil && *sink.BufferSize < 1 {
+		add(path+".buffer_size", "%s buffer_size must be at least 1", what)
+	}
+	if sink.MaxRetries != nil && *sink.MaxRetries < 0 {
+		add(path+".max_retries", "%s max_retries cannot be negative", what)
+	}
+}
+
+// validateTracing checks the OTLP export settings of tracing.
+func validateTracing(t *Tracing, add func(string, string, ...any)) {
+	if t.Endpoint != "" {
+		u, err := url.Parse(t.Endpoint)
+		switch {
+		case err != nil || u.Host == "":
+			add("tracing.endpoint", "tracing endpoint '%s' is not a URL", t.Endpoint)
+		case u.Scheme != "https" && !(u.Scheme == "http" && isLoopbackHost(u.Hostname())):
+			add("tracing.endpoint", "tracing endpoint '%s' must be https", t.Endpoint)
+		}
+	}
+	for name, value := range t.Headers {
+		validateSecret(value, "tracing.headers."+name, "tracing header "+name, add)
+	}
+	if t.SampleRatio != nil && (*t.SampleRatio < 0 || *t.SampleRatio > 1) {
+		add("tracing.sample_ratio", "tracing sample_ratio must be between 0 and 1")
+	}
+}
+
+// validateOCIRetry checks the retry and circuit breaking settings of OCI
+// API calls.
+func validateOCIRetry(r *OCIRetry, add func(string, string, ...any)) {
+	if r.MaxAttempts != nil && *r.MaxAttempts < 1 {
+		add("oci_retry.max_attempts", "oci_retry max_attempts must be at least 1")
+	}
+	if r.InitialIntervalSeconds != nil && *r.InitialIntervalSeconds < 0 {
+		add("oci_retry.initial_interval_seconds", "oci_retry initial_interval_seconds cannot be negative")
+	}
+	if r.GetMaxIntervalSeconds() < r.GetInitialIntervalSeconds() {
+		add("oci_retry.max_interval_seconds", "oci_retry max_interval_seconds cannot be less than initial_interval_seconds")
+	}
+	if r.CircuitBreakerThreshold != nil && *r.CircuitBreakerThreshold < 0 {
+		add("oci_retry.circuit_breaker_threshold", "oci_retry circuit_breaker_threshold cannot be negative")
+	}
+	if r.CircuitBreakerCooldownSeconds != nil && *r.CircuitBreakerCooldownSeconds < 1 {
+		add("oci_retry.circuit_breaker_cooldown_seconds", "oci_retry circuit_breaker_cooldown_seconds must be at least 1")
+	}
+}
+
+// validateLogFile checks the level and rotation settings of the log file.
+func validateLogFile(l *LogFile, add func(string, string, ...any)) {
+	switch l.Level {
+	case "", "trace", "debug", "info", "warn", "error":
+	default:
+		add("log_file.level", "log_file level '%s' must be trace, debug, info, warn or error", l.Level)
+	}
+	if l.MaxSizeMB != nil && *l.MaxSizeMB < 1 {
+		add("log_file.max_size_mb", "log_file max_size_mb must be at least 1")
+	}
+	if l.MaxBackups != nil && *l.MaxBackups < 0 {
+		add("log_file.max_backups", "log_file max_backups cannot be negative")
+	}
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback address.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateCluster checks the fields of the cluster at path. what names it
+// in errors.
+func validateCluster(c *Cluster, path, what string, add func(string, string, ...any)) {
+	if c.Region == "" {
+		add(path, "%s has no region", what)
+	} else {
+		validateRegion(c.Region, path+".region", what+" region", add)
+	}
+	if c.Ocid != nil {
+		validateOCID(*c.Ocid, "", path+".ocid", what+" ocid", add)
+	}
+	if c.CompartmentOcid != nil {
+		validateOCID(*c.CompartmentOcid, "", path+".compartment_ocid", what+" compartment_ocid", add)
+	}
+	if c.TenantOcid != nil {
+		validateOCID(*c.TenantOcid, "tenancy", path+".tenant_ocid", what+" tenant_ocid", add)
+	}
+	if c.BastionId != nil {
+		validateOCID(*c.BastionId, "bastion", path+".bastion_id", what+" bastion_id", add)
+	}
+	for i, id := range c.FallbackBastionIds {
+		validateOCID(id, "bastion", fmt.Sprintf("%s.fallback_bastion_ids[%d]", path, i), what+" fallback_bastion_ids", add)
+	}
+	if c.OCIAuthType != nil {
+		validateAuthType(*c.OCIAuthType, path+".oci_auth_type", what+" oci_auth_type", add)
+	}
+	if c.LocalPort != nil {
+		validatePort(*c.LocalPort, path+".local_port", what+" local_port", add)
+	}
+	if c.PortStrategy != nil && !slices.Contains([]string{"increment", "fail", "takeover"}, *c.PortStrategy) {
+		add(path+".port_strategy", "%s port_strategy must be one of increment, fail, takeover, not '%s'", what, *c.PortStrategy)
+	}
+	if c.SessionTTLMinutes != nil {
+		validateSessionTTL(*c.SessionTTLMinutes, path+".session_ttl_minutes", what+" session_ttl_minutes", add)
+	}
+	if k := c.Kubeconfig; k != nil && k.Namespace != "" && !namespacePattern.MatchString(k.Namespace) {
+		add(path+".kubeconfig.namespace", "%s kubeconfig namespace '%s' is not a Kubernetes namespace name", what, k.Namespace)
+	}
+
+	names := make(map[string]bool)
+	for i, ep := range c.Endpoints {
+		epPath := fmt.Sprintf("%s.endpoints[%d]", path, i)
+		epWhat := fmt.Sprintf("endpoint %d of %s", i+1, what)
+		if ep.Name != "" {
+			epWhat = fmt.Sprintf("endpoint '%s' of %s", ep.Name, what)
+			if names[strings.ToLower(ep.Name)] {
+				add(epPath+".name", "%s is configured more than once", epWhat)
+			}
+			names[strings.ToLower(ep.Name)] = true
+		}
+		if ep.Host() == "" {
+			add(epPath, "%s needs an ip or fqdn", epWhat)
+		}
+		validatePort(ep.Port, epPath+".port", epWhat+" port", add)
+		if ep.LocalPort != nil {
+			validatePort(*ep.LocalPort, epPath+".local_port", epWhat+" local_port", add)
+		}
+		switch strings.ToLower(ep.Protocol) {
+		case "", "tcp":
+		case "udp":
+			if ep.Port != DNSPort {
+				add(epPath+".protocol", "%s is udp but not DNS on port %d, the only UDP service that can be forwarded", epWhat, DNSPort)
+			}
+		default:
+			add(epPath+".protocol", "%s has unknown protocol '%s'", epWhat, ep.Protocol)
+		}
+	}
+
+	for i, hop := range c.Hops {
+		hopPath := fmt.Sprintf("%s.hops[%d]", path, i)
+		if hop.Host == "" {
+			add(hopPath, "hop %d of %s has no host", i+1, what)
+		}
+		if hop.Port != nil {
+			validatePort(*hop.Port, hopPath+".port", fmt.Sprintf("hop %d of %s port", i+1, what), add)
+		}
+	}
+}
+
+// validatePort checks that port is a TCP or UDP port number.
+func validatePort(port int, path, what string, add func(string, string, ...any)) {
+	if port < 1 || port > 65535 {
+		add(path, "%s must be between 1 and 65535, not %d", what, port)
+	}
+}
+
+// validateSessionTTL checks a session TTL against the limits of the bastion
+// service.
+func validateSessionTTL(minutes int, path, what string, add func(string, string, ...any)) {
+	if minutes < 30 || minutes > 180 {
+		add(path, "%s must be between 30 and 180, not %d", what, minutes)
+	}
+}
+
+// validateAuthType checks that authType is one of the OCI authentication
+// types of oci_auth_type.
+func validateAuthType(authType, path, what string, add func(string, string, ...any)) {
+	if choices := optionChoices["oci_auth_type"]; !slices.Contains(choices, authType) {
+		add(path, "%s must be one of %s, not '%s'", what, strings.Join(choices, ", "), authType)
+	}
+}
+
+// validateRegion checks that region looks like an OCI region identifier or
+// key.
+// validateSecret checks the syntax of a secret reference.
+func validateSecret(value, path, what string, add func(string, string, ...any)) {
+	if err := secrets.Check(value); err != nil {
+		add(path, "%s: %v", what, err)
+	}
+}
+
+func validateRegion(region, path, what string, add func(string, string, ...any)) {
+	if !regionPattern.MatchString(region) {
+		add(path, "%s '%s' is not an OCI region such as us-ashburn-1", what, region)
+	}
+}
+
+// validateOCID checks that ocid is an OCID and, if resourceType is set,
+// one of that type.
+func validateOCID(ocid, resourceType, path, what string, add func(string, string, ...any)) {
+	if err := utils.ValidateOCID(ocid, resourceType, ""); err != nil {
+		add(path, "%s: %v", what, err)
+	}
+}
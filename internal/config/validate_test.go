@@ -0,0 +1,149 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	port := 6443
+	attempts := 3
+	valid := &Config{
+		SshHostKeyPolicy:       "accept-new",
+		SshSocksProxyUser:      "me",
+		SshSocksProxyPassword:  "keychain:tunatap/socks",
+		SaveDiscoveredClusters: "prompt",
+		Clusters: []*Cluster{{
+			ClusterName: "prod",
+			Region:      "us-ashburn-1",
+			LocalPort:   &port,
+			Endpoints:   []*ClusterEndpoint{{Name: "private", Ip: "10.0.0.5", Port: 6443}},
+		}},
+		AuditSinks: []*AuditSink{
+			{Type: "webhook", URL: "https://audit.example.com/events", Headers: map[string]string{"Authorization": "keychain:tunatap/audit-token"}},
+			{Type: "webhook", URL: "http://127.0.0.1:8080/events"},
+			{Type: "oci_logging", LogID: "ocid1.log.oc1.iad.aaaa"},
+			{Type: "syslog"},
+		},
+		Tracing:  &Tracing{Endpoint: "http://localhost:4318", Headers: map[string]string{"Api-Key": "keychain:tunatap/otlp-key"}},
+		LogFile:  &LogFile{Level: "info"},
+		OCIRetry: &OCIRetry{MaxAttempts: &attempts, CircuitBreakerThreshold: new(int)},
+	}
+	if errs := Validate(valid); len(errs) != 0 {
+		t.Errorf("Validate() of a valid config = %v", errs)
+	}
+
+	badPort := 70000
+	badRetries := -1
+	badRatio := 1.5
+	noSize := 0
+	invalid := &Config{
+		SshHostKeyPolicy:        "sometimes",
+		SshPrivateKeyPassphrase: "keychain:ssh-passphrase",
+		SaveDiscoveredClusters:  "ask",
+		Clusters: []*Cluster{
+			{ClusterName: "prod", Region: "us-ashburn-1", LocalPort: &badPort, Kubeconfig: &ClusterKubeconfig{Namespace: "Payments"}},
+			{ClusterName: "PROD", Endpoints: []*ClusterEndpoint{
+				{Name: "private", Port: 6443, Protocol: "sctp"},
+				{Name: "statsd", Ip: "10.0.0.9", Port: 8125, Protocol: "udp"},
+			}},
+			{Region: "us-phoenix-1"},
+		},
+		TenancyList:    []*TenantInfo{{Name: "acme", ID: "ocid1.tenancy.oc1..acme", OCIAuthType: "password"}},
+		CatalogSources: []*CatalogSource{{Name: "team"}},
+		RemoteConfig:   &RemoteConfig{Keys: []string{"clusters", "cluster"}},
+		AuditSinks: []*AuditSink{
+			{Type: "webhook", URL: "http://audit.example.com"},
+			{Type: "oci_logging", LogID: "ocid1.bucket.oc1.iad.aaaa"},
+			{Type: "syslog", Address: "logs.example.com:514", MaxRetries: &badRetries},
+			{Type: "kafka"},
+		},
+		Tracing:  &Tracing{Endpoint: "http://otel.example.com:4318", SampleRatio: &badRatio},
+		LogFile:  &LogFile{Level: "verbose", MaxSizeMB: &noSize},
+		OCIRetry: &OCIRetry{MaxAttempts: &noSize, MaxIntervalSeconds: &noSize},
+	}
+	want := []string{
+		"save_discovered_clusters must be one of never, prompt, always, not 'ask'",
+		"ssh_host_key_policy must be one of",
+		"ssh_private_key_passphrase: keychain reference 'keychain:ssh-passphrase' must be keychain:<service>/<account>",
+		"cluster 'prod' local_port must be between 1 and 65535",
+		"cluster 'prod' kubeconfig namespace 'Payments' is not a Kubernetes namespace name",
+		"cluster 'PROD' is configured more than once",
+		"cluster 'PROD' has no region",
+		"endpoint 'private' of cluster 'PROD' needs an ip or fqdn",
+		"endpoint 'private' of cluster 'PROD' has unknown protocol 'sctp'",
+		"endpoint 'statsd' of cluster 'PROD' is udp but not DNS on port 53",
+		"cluster 3 has no cluster_name",
+		"catalog source 1 needs a name and a url",
+		"tenancy 'acme' oci_auth_type must be one of",
+		"audit sink audit_sinks[0] url 'http://audit.example.com' must be https",
+		"audit sink audit_sinks[1] log_id",
+		"audit sink audit_sinks[2] has an address but no network",
+		"audit sink audit_sinks[2] max_retries cannot be negative",
+		"audit sink audit_sinks[3] type 'kafka' must be webhook, oci_logging or syslog",
+		"tracing endpoint 'http://otel.example.com:4318' must be https",
+		"tracing sample_ratio must be between 0 and 1",
+		"oci_retry max_attempts must be at least 1",
+		"oci_retry max_interval_seconds cannot be less than initial_interval_seconds",
+		"log_file level 'verbose' must be trace, debug, info, warn or error",
+		"log_file max_size_mb must be at least 1",
+		"remote_config keys: 'cluster' is not a config key",
+	}
+	errs := Validate(invalid)
+	if len(errs) != len(want) {
+		t.Fatalf("Validate() = %v, want %d errors", errs, len(want))
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), want[i]) {
+			t.Errorf("error %d = %q, want %q", i, err, want[i])
+		}
+	}
+}
+
+func TestValidateAliases(t *testing.T) {
+	cfg := &Config{Clusters: []*Cluster{
+		{ClusterName: "payments-prod", Region: "us-ashburn-1", Aliases: []string{"pp", "Payments-Prod"}},
+		{ClusterName: "pp-dr", Region: "us-phoenix-1", Aliases: []string{"PP", "payments-prod", ""}},
+	}}
+	want := []string{
+		"alias 'PP' of cluster 'pp-dr' is also an alias of cluster 'payments-prod'",
+		"alias 'payments-prod' of cluster 'pp-dr' is the name of cluster 'payments-prod'",
+		"cluster 'pp-dr' has an empty alias",
+	}
+	errs := Validate(cfg)
+	if len(errs) != len(want) {
+		t.Fatalf("Validate() = %v, want %d errors", errs, len(want))
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), want[i]) {
+			t.Errorf("error %d = %q, want %q", i, err, want[i])
+		}
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	cfg := &Config{
+		Defaults: &ClusterDefaults{Region: "us-ashburn-1"},
+		Clusters: []*Cluster{{ClusterName: "prod"}},
+	}
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Errorf("Validate() of a cluster with the default region = %v", errs)
+	}
+
+	cfg.Defaults = &ClusterDefaults{Region: "ashburn", BastionType: "standard", LocalPortRange: "7000-6000"}
+	want := []string{
+		"defaults.bastion_type must be one of STANDARD, INTERNAL",
+		"defaults region 'ashburn' is not an OCI region",
+		"defaults local_port_range: port range '7000-6000'",
+		"cluster 'prod' has no region",
+	}
+	errs := Validate(cfg)
+	if len(errs) != len(want) {
+		t.Fatalf("Validate() = %v, want %d errors", errs, len(want))
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), want[i]) {
+			t.Errorf("error %d = %q, want %q", i, err, want[i])
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package configsync
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Preference says how Merge settles a conflict: a value changed both
+// locally and remotely, to different values.
+type Preference string
+
+const (
+	// PreferNone leaves conflicts unsettled; the sync stops.
+	PreferNone Preference = ""
+	// PreferLocal keeps the local value.
+	PreferLocal Preference = "local"
+	// PreferRemote takes the remote value.
+	PreferRemote Preference = "remote"
+)
+
+// ParsePreference parses a --prefer value.
+func ParsePreference(s string) (Preference, error) {
+	switch p := Preference(s); p {
+	case PreferNone, PreferLocal, PreferRemote:
+		return p, nil
+	}
+	return PreferNone, fmt.Errorf("invalid preference %q: must be local or remote", s)
+}
+
+// identityKeys are the keys that name the items of a list, so that lists
+// such as clusters merge item by item rather than as a whole.
+var identityKeys = []string{"cluster_name", "name"}
+
+// MergeResult is the outcome of Merge.
+type MergeResult struct {
+	// Local is the local config with the merged keys.
+	Local []byte
+	// Remote is the remote config with the merged keys.
+	Remote []byte
+	// LocalChanged and RemoteChanged report whether the merge changed the
+	// values of the local and remote config.
+	LocalChanged  bool
+	RemoteChanged bool
+	// Conflicts are the paths of the values changed both locally and
+	// remotely, such as clusters[prod].region.
+	Conflicts []string
+}
+
+// Merge three-way merges the keys of the YAML configs local and remote,
+// both changed from base, which may be empty before the first sync. A value
+// changed on one side only takes that side's value; lists of named items,
+// such as clusters, merge item by item. The rest of each config is left
+// alone, with its comments.
+func Merge(base, local, remote []byte, keys []string, prefer Preference) (*MergeResult, error) {
+	baseDoc, err := parseMapping(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base config: %w", err)
+	}
+	localDoc, err := parseMapping(local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local config: %w", err)
+	}
+	remoteDoc, err := parseMapping(remote)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote config: %w", err)
+	}
+
+	m := &merger{prefer: prefer}
+	merged := make(map[string]*yaml.Node, len(keys))
+	for _, key := range keys {
+		merged[key] = m.merge(key, lookup(root(baseDoc), key), lookup(root(localDoc), key), lookup(root(remoteDoc), key))
+	}
+
+	result := &MergeResult{Conflicts: m.conflicts}
+	newLocal := withKeys(localDoc, keys, merged)
+	newRemote := withKeys(remoteDoc, keys, merged)
+	result.LocalChanged = !equal(root(localDoc), root(newLocal))
+	result.RemoteChanged = !equal(root(remoteDoc), root(newRemote))
+
+	result.Local = local
+	if result.LocalChanged {
+		if result.Local, err = encode(newLocal); err != nil {
+			return nil, err
+		}
+	}
+	result.Remote = remote
+	if result.RemoteChanged {
+		if result.Remote, err = encode(newRemote); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Extract returns a config holding only keys of the YAML config data, as
+// the base of the next merge.
+func Extract(data []byte, keys []string) ([]byte, error) {
+	doc, err := parseMapping(data)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]*yaml.Node, len(keys))
+	for _, key := range keys {
+		merged[key] = lookup(root(doc), key)
+	}
+	empty, _ := parseMapping(nil)
+	return encode(withKeys(empty, keys, merged))
+}
+
+// merger merges nodes, collecting conflicts.
+type merger struct {
+	prefer    Preference
+	conflicts []string
+}
+
+// merge returns the value at path merged from base, local and remote, any
+// of which is nil where the value is absent.
+func (m *merger) merge(path string, base, local, remote *yaml.Node) *yaml.Node {
+	switch {
+	case equal(local, remote), equal(remote, base):
+		return local
+	case equal(local, base):
+		return remote
+	}
+
+	if isKind(local, yaml.MappingNode) && isKind(remote, yaml.MappingNode) && (base == nil || isKind(base, yaml.MappingNode)) {
+		return m.mergeMapping(path, base, local, remote)
+	}
+	if key := itemKey(base, local, remote); key != "" {
+		return m.mergeList(path, key, base, local, remote)
+	}
+
+	m.conflicts = append(m.conflicts, path)
+	if m.prefer == PreferRemote {
+		return remote
+	}
+	return local
+}
+
+// mergeMapping merges mappings key by key, in the order of local with the
+// keys added remotely last.
+func (m *merger) mergeMapping(path string, base, local, remote *yaml.Node) *yaml.Node {
+	out := *local
+	out.Content = nil
+	for _, key := range unionKeys(local, remote) {
+		v := m.merge(path+"."+key.Value, lookup(base, key.Value), lookup(local, key.Value), lookup(remote, key.Value))
+		if v != nil {
+			out.Content = append(out.Content, key, v)
+		}
+	}
+	return &out
+}
+
+// mergeList merges lists of mappings named by key item by item, in the
+// order of local with the items added remotely last.
+func (m *merger) mergeList(path, key string, base, local, remote *yaml.Node) *yaml.Node {
+	out := *local
+	out.Content = nil
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, list := range []*yaml.Node{local, remote} {
+		for _, item := range list.Content {
+			if name := lookup(item, key).Value; !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		v := m.merge(fmt.Sprintf("%s[%s]", path, name), findItem(base, key, name), findItem(local, key, name), findItem(remote, key, name))
+		if v != nil {
+			out.Content = append(out.Content, v)
+		}
+	}
+	return &out
+}
+
+// itemKey returns the key naming every item of the lists base, local and
+// remote, or "" if they are not lists of uniquely named mappings.
+func itemKey(base, local, remote *yaml.Node) string {
+	if !isKind(local, yaml.SequenceNode) || !isKind(remote, yaml.SequenceNode) || (base != nil && !isKind(base, yaml.SequenceNode)) {
+		return ""
+	}
+
+next:
+	for _, key := range identityKeys {
+		for _, list := range []*yaml.Node{base, local, remote} {
+			if list == nil {
+				continue
+			}
+			seen := make(map[string]bool)
+			for _, item := range list.Content {
+				name := lookup(item, key)
+				if !isKind(item, yaml.MappingNode) || !isKind(name, yaml.ScalarNode) || seen[name.Value] {
+					continue next
+				}
+				seen[name.Value] = true
+			}
+		}
+		return key
+	}
+	return ""
+}
+
+// findItem returns the item of list whose key is name, or nil.
+func findItem(list *yaml.Node, key, name string) *yaml.Node {
+	if list == nil {
+		return nil
+	}
+	for _, item := range list.Content {
+		if v := lookup(item, key); v != nil && v.Value == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// unionKeys returns the key nodes of local, then those only in remote.
+func unionKeys(local, remote *yaml.Node) []*yaml.Node {
+	var keys []*yaml.Node
+	seen := make(map[string]bool)
+	for _, n := range []*yaml.Node{local, remote} {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if k := n.Content[i]; !seen[k.Value] {
+				seen[k.Value] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// withKeys returns a copy of the document doc with keys set to their values
+// in merged, removing those that are nil. Keys not in doc go last.
+func withKeys(doc *yaml.Node, keys []string, merged map[string]*yaml.Node) *yaml.Node {
+	top := root(doc)
+	out := *top
+	out.Content = nil
+	set := make(map[string]bool)
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		key, value := top.Content[i], top.Content[i+1]
+		if v, ok := merged[key.Value]; ok {
+			set[key.Value] = true
+			if v == nil {
+				continue
+			}
+			value = v
+		}
+		out.Content = append(out.Content, key, value)
+	}
+	for _, key := range keys {
+		if v := merged[key]; v != nil && !set[key] {
+			out.Content = append(out.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+		}
+	}
+	outDoc := *doc
+	outDoc.Content = []*yaml.Node{&out}
+	return &outDoc
+}
+
+// parseMapping parses a YAML config into a document holding a mapping,
+// which is empty for an empty config. The document keeps the comments
+// before the first key.
+func parseMapping(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, HeadComment: doc.HeadComment}
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config must be a mapping of keys to values")
+	}
+	return &doc, nil
+}
+
+// root returns the top-level mapping of the document doc.
+func root(doc *yaml.Node) *yaml.Node {
+	return doc.Content[0]
+}
+
+// lookup returns the value of key in the mapping n, or nil.
+func lookup(n *yaml.Node, key string) *yaml.Node {
+	if !isKind(n, yaml.MappingNode) {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func isKind(n *yaml.Node, kind yaml.Kind) bool {
+	return n != nil && n.Kind == kind
+}
+
+// equal reports whether a and b hold the same value, whatever their style
+// and comments. nil is an absent value.
+func equal(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	var va, vb any
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func encode(doc *yaml.Node) ([]byte, error) {
+	if len(root(doc).Content) == 0 && doc.HeadComment == "" {
+		return []byte{}, nil
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,109 @@
+package configsync
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const baseConfig = `clusters:
+  - cluster_name: prod
+    region: us-ashburn-1
+    local_port: 6443
+  - cluster_name: dev
+    region: us-phoenix-1
+`
+
+func TestMerge(t *testing.T) {
+	local := `# my settings
+ssh_private_key_file: ~/.ssh/id_ed25519
+clusters:
+  - cluster_name: prod
+    region: us-ashburn-1
+    local_port: 7443 # moved off 6443
+  - cluster_name: dev
+    region: us-phoenix-1
+  - cluster_name: scratch
+    region: us-phoenix-1
+`
+	remote := `clusters:
+  - cluster_name: prod
+    region: us-ashburn-1
+    local_port: 6443
+    bastion: prod-bastion
+  - cluster_name: staging
+    region: us-ashburn-1
+session_ttl_minutes: 60
+`
+	result, err := Merge([]byte(baseConfig), []byte(local), []byte(remote), []string{"clusters"}, PreferNone)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(result.Conflicts) != 0 {
+		t.Errorf("Merge() conflicts = %v", result.Conflicts)
+	}
+	if !result.LocalChanged || !result.RemoteChanged {
+		t.Errorf("Merge() changed local %v, remote %v; want both", result.LocalChanged, result.RemoteChanged)
+	}
+
+	got := string(result.Local)
+	for _, want := range []string{"# my settings", "ssh_private_key_file", "local_port: 7443 # moved off 6443", "bastion: prod-bastion", "scratch", "staging"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("merged local config has no %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "dev") || strings.Contains(got, "session_ttl_minutes") {
+		t.Errorf("merged local config kept the deleted cluster or took an unsynced key:\n%s", got)
+	}
+	if strings.Index(got, "scratch") > strings.Index(got, "staging") {
+		t.Errorf("remote cluster not added after the local ones:\n%s", got)
+	}
+
+	if r := string(result.Remote); !strings.Contains(r, "session_ttl_minutes: 60") || strings.Contains(r, "ssh_private_key_file") {
+		t.Errorf("merged remote config changed unsynced keys:\n%s", r)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	local := strings.Replace(baseConfig, "6443", "7443", 1)
+	remote := strings.Replace(baseConfig, "6443", "8443", 1)
+
+	result, err := Merge([]byte(baseConfig), []byte(local), []byte(remote), []string{"clusters"}, PreferNone)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if want := []string{"clusters[prod].local_port"}; !reflect.DeepEqual(result.Conflicts, want) {
+		t.Errorf("Merge() conflicts = %v, want %v", result.Conflicts, want)
+	}
+
+	result, _ = Merge([]byte(baseConfig), []byte(local), []byte(remote), []string{"clusters"}, PreferRemote)
+	if !strings.Contains(string(result.Local), "8443") || result.RemoteChanged {
+		t.Errorf("Merge() preferring remote:\n%s", result.Local)
+	}
+}
+
+func TestMergeFirstSync(t *testing.T) {
+	local := "clusters:\n  - cluster_name: mine\n    region: us-ashburn-1\n"
+	result, err := Merge(nil, []byte(local), []byte(baseConfig), DefaultKeys, PreferNone)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(result.Conflicts) != 0 || !strings.Contains(string(result.Local), "mine") || !strings.Contains(string(result.Local), "prod") {
+		t.Errorf("Merge() without a base = %v:\n%s", result.Conflicts, result.Local)
+	}
+
+	result, _ = Merge([]byte(baseConfig), []byte(baseConfig), []byte(baseConfig), DefaultKeys, PreferNone)
+	if result.LocalChanged || result.RemoteChanged {
+		t.Error("Merge() of unchanged configs reports changes")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	data, err := Extract([]byte("ssh_private_key_file: k\n"+baseConfig), []string{"clusters", "tenancies"})
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if s := string(data); strings.Contains(s, "ssh_private_key_file") || !strings.Contains(s, "prod") {
+		t.Errorf("Extract() = %s", s)
+	}
+}
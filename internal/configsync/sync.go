@@ -0,0 +1,231 @@
+// Package configsync keeps the shared parts of a config, such as its
+// clusters, in step with a team config in a remote store: local edits and
+// remote changes since the last sync are three-way merged, and the result
+// can be pushed back with the store's ETag guarding against overwriting
+// changes made in the meantime.
+package configsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StateFileName is the name of the file remembering the last sync, next to
+// the config.
+const StateFileName = "remote-config-sync.json"
+
+// DefaultKeys are the config keys synced when the remote config does not
+// name them: those describing what a team shares, not one user's setup.
+var DefaultKeys = []string{"clusters", "tenancies", "tenancy_list", "profiles"}
+
+var (
+	// ErrNoRemote is returned by Store.Get when the remote config does not
+	// exist yet.
+	ErrNoRemote = errors.New("remote config does not exist")
+
+	// ErrRemoteChanged is returned by Store.Put when the remote config
+	// changed since it was read.
+	ErrRemoteChanged = errors.New("remote config changed since it was read")
+)
+
+// Store holds the remote config.
+type Store interface {
+	// Get returns the remote config and its ETag, or ErrNoRemote.
+	Get(ctx context.Context) ([]byte, string, error)
+
+	// Put replaces the remote config if its ETag is still etag, or creates
+	// it if etag is empty, and returns the new ETag. It fails with
+	// ErrRemoteChanged otherwise.
+	Put(ctx context.Context, data []byte, etag string) (string, error)
+}
+
+// State is what the state file remembers between syncs.
+type State struct {
+	// Location identifies the remote config synced, so that the state of
+	// another one is not used as the base of a merge.
+	Location string `json:"location"`
+	// ETag is the ETag of the remote config when it was last synced.
+	ETag string `json:"etag,omitempty"`
+	// Base holds the synced keys as they were in the remote config then.
+	Base string `json:"base"`
+	// SyncedAt is when the last sync finished.
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// Syncer syncs the config at LocalPath with the remote config in Store.
+type Syncer struct {
+	Store     Store
+	Location  string
+	LocalPath string
+	StatePath string
+	Keys      []string
+	Prefer    Preference
+
+	// Push uploads the merged config when local edits changed it.
+	Push bool
+	// DryRun reports what a sync would do without writing anything.
+	DryRun bool
+	// Validate, if set, checks the merged local config before it is written
+	// or pushed.
+	Validate func(data []byte) error
+
+	now func() time.Time
+}
+
+// NewSyncer returns a syncer of the config at localPath, keeping its state
+// next to it.
+func NewSyncer(store Store, location, localPath string, keys []string) *Syncer {
+	if len(keys) == 0 {
+		keys = DefaultKeys
+	}
+	return &Syncer{
+		Store:     store,
+		Location:  location,
+		LocalPath: localPath,
+		StatePath: filepath.Join(filepath.Dir(localPath), StateFileName),
+		Keys:      keys,
+		now:       time.Now,
+	}
+}
+
+// Result is what a sync did, or would do with DryRun.
+type Result struct {
+	// Pulled is set when remote changes were merged into the local config.
+	Pulled bool
+	// Pushed is set when the merged config was uploaded.
+	Pushed bool
+	// Unpushed is set when the local config has changes the remote config
+	// does not, which Push would upload.
+	Unpushed bool
+	// Created is set when the remote config did not exist yet.
+	Created bool
+	// Conflicts are the paths changed on both sides, settled by Prefer.
+	Conflicts []string
+}
+
+// ConflictError is returned by Sync when values were changed both locally
+// and remotely and no preference settles them.
+type ConflictError struct {
+	Paths []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("changed both locally and in the remote config: %s\n\n"+
+		"Keep one side with --prefer local or --prefer remote", strings.Join(e.Paths, ", "))
+}
+
+// Sync merges the remote config and the local one, writes the result
+// locally and, with Push, uploads it.
+func (s *Syncer) Sync(ctx context.Context) (*Result, error) {
+	local, err := os.ReadFile(s.LocalPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	result := &Result{}
+	remote, etag, err := s.Store.Get(ctx)
+	if errors.Is(err, ErrNoRemote) {
+		if !s.Push {
+			return nil, fmt.Errorf("%s does not exist; create it from the local config with --push", s.Location)
+		}
+		result.Created = true
+	} else if err != nil {
+		return nil, err
+	}
+
+	var base []byte
+	if state, err := s.load(); err == nil && state.Location == s.Location {
+		base = []byte(state.Base)
+	}
+
+	merged, err := Merge(base, local, remote, s.Keys, s.Prefer)
+	if err != nil {
+		return nil, err
+	}
+	result.Conflicts = merged.Conflicts
+	if len(merged.Conflicts) > 0 && s.Prefer == PreferNone {
+		return result, &ConflictError{Paths: merged.Conflicts}
+	}
+	result.Pulled = merged.LocalChanged
+	result.Unpushed = merged.RemoteChanged || result.Created
+
+	if s.Validate != nil {
+		if err := s.Validate(merged.Local); err != nil {
+			return result, fmt.Errorf("the merged config is invalid: %w", err)
+		}
+	}
+	if s.DryRun {
+		return result, nil
+	}
+
+	// Push first, so that a conflicting remote change leaves the local
+	// config untouched
+	synced := remote
+	if s.Push && result.Unpushed {
+		if etag, err = s.Store.Put(ctx, merged.Remote, etag); err != nil {
+			if errors.Is(err, ErrRemoteChanged) {
+				return result, fmt.Errorf("%s changed during the sync; run it again", s.Location)
+			}
+			return result, err
+		}
+		synced = merged.Remote
+		result.Pushed, result.Unpushed = true, false
+	}
+
+	if merged.LocalChanged {
+		if err := os.MkdirAll(filepath.Dir(s.LocalPath), 0o750); err != nil {
+			return result, fmt.Errorf("failed to create config directory: %w", err)
+		}
+		if err := os.WriteFile(s.LocalPath, merged.Local, 0o600); err != nil {
+			return result, fmt.Errorf("failed to write config: %w", err)
+		}
+	}
+
+	// The base is what the remote config holds, so that local edits not
+	// pushed remain local edits in the next merge
+	newBase, err := Extract(synced, s.Keys)
+	if err != nil {
+		return result, err
+	}
+	state := &State{Location: s.Location, ETag: etag, Base: string(newBase), SyncedAt: s.clock()}
+	if err := s.save(state); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func (s *Syncer) load() (*State, error) {
+	data, err := os.ReadFile(s.StatePath)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *Syncer) save(state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.StatePath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save sync state: %w", err)
+	}
+	return nil
+}
+
+func (s *Syncer) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
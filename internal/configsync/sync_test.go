@@ -0,0 +1,140 @@
+package configsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memStore is a Store in memory, with a counter for ETag.
+type memStore struct {
+	data    []byte
+	version int
+	puts    int
+}
+
+func (m *memStore) etag() string {
+	return fmt.Sprintf("v%d", m.version)
+}
+
+func (m *memStore) Get(ctx context.Context) ([]byte, string, error) {
+	if m.version == 0 {
+		return nil, "", ErrNoRemote
+	}
+	return m.data, m.etag(), nil
+}
+
+func (m *memStore) Put(ctx context.Context, data []byte, etag string) (string, error) {
+	if (m.version == 0 && etag != "") || (m.version > 0 && etag != m.etag()) {
+		return "", ErrRemoteChanged
+	}
+	m.data = data
+	m.version++
+	m.puts++
+	return m.etag(), nil
+}
+
+func (m *memStore) set(data string) {
+	m.data = []byte(data)
+	m.version++
+}
+
+func TestSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("ssh_private_key_file: k\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := &memStore{}
+	store.set(baseConfig)
+	s := NewSyncer(store, "oci://ns/team/config.yaml", path, nil)
+	ctx := context.Background()
+
+	// Pull the team clusters
+	result, err := s.Sync(ctx)
+	if err != nil || !result.Pulled || result.Unpushed {
+		t.Fatalf("Sync() = %+v, %v; want a pull", result, err)
+	}
+
+	// A local edit is kept, and not pushed without Push
+	local, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, []byte(strings.Replace(string(local), "6443", "7443", 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store.set(baseConfig + "  - cluster_name: staging\n    region: us-ashburn-1\n")
+	result, err = s.Sync(ctx)
+	if err != nil || !result.Pulled || !result.Unpushed || store.puts != 0 {
+		t.Fatalf("Sync() = %+v, %v; want a pull with the local edit unpushed", result, err)
+	}
+	local, _ = os.ReadFile(path)
+	if !strings.Contains(string(local), "7443") || !strings.Contains(string(local), "staging") {
+		t.Errorf("local config after sync:\n%s", local)
+	}
+
+	// Pushing uploads it
+	s.Push = true
+	result, err = s.Sync(ctx)
+	if err != nil || !result.Pushed || store.puts != 1 || !strings.Contains(string(store.data), "7443") {
+		t.Fatalf("Sync() with Push = %+v, %v; remote:\n%s", result, err, store.data)
+	}
+	if strings.Contains(string(store.data), "ssh_private_key_file") {
+		t.Errorf("Sync() pushed an unsynced key:\n%s", store.data)
+	}
+}
+
+func TestSyncConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(baseConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := &memStore{}
+	store.set(baseConfig)
+	s := NewSyncer(store, "oci://ns/team/config.yaml", path, nil)
+	ctx := context.Background()
+	if _, err := s.Sync(ctx); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(baseConfig, "us-phoenix-1", "us-sanjose-1", 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store.set(strings.Replace(baseConfig, "us-phoenix-1", "us-chicago-1", 1))
+
+	var conflict *ConflictError
+	if _, err := s.Sync(ctx); !errors.As(err, &conflict) || conflict.Paths[0] != "clusters[dev].region" {
+		t.Fatalf("Sync() error = %v, want a conflict on the dev region", err)
+	}
+	if local, _ := os.ReadFile(path); !strings.Contains(string(local), "us-sanjose-1") {
+		t.Error("Sync() changed the local config despite the conflict")
+	}
+
+	s.Prefer = PreferLocal
+	s.Push = true
+	if result, err := s.Sync(ctx); err != nil || !result.Pushed {
+		t.Fatalf("Sync() preferring local = %+v, %v", result, err)
+	}
+	if !strings.Contains(string(store.data), "us-sanjose-1") {
+		t.Errorf("remote config after sync:\n%s", store.data)
+	}
+}
+
+func TestSyncCreatesRemote(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(baseConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := &memStore{}
+	s := NewSyncer(store, "oci://ns/team/config.yaml", path, nil)
+
+	if _, err := s.Sync(context.Background()); err == nil {
+		t.Error("Sync() of a missing remote config without Push = nil, want an error")
+	}
+	s.Push = true
+	result, err := s.Sync(context.Background())
+	if err != nil || !result.Created || !result.Pushed || !strings.Contains(string(store.data), "prod") {
+		t.Errorf("Sync() = %+v, %v; want the remote config created", result, err)
+	}
+}
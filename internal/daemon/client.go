@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/health"
+)
+
+const (
+	// dialTimeout is how long a client waits to connect to the socket.
+	dialTimeout = 2 * time.Second
+
+	// spawnWaitTimeout is how long Spawn waits for a new daemon to start listening.
+	spawnWaitTimeout = 10 * time.Second
+)
+
+// Client talks to a running daemon over its unix socket.
+type Client struct {
+	socketPath string
+}
+
+// NewClient creates a client for the daemon listening on socketPath.
+func NewClient(socketPath string) *Client {
+	return &Client{socketPath: socketPath}
+}
+
+// IsRunning returns true if a daemon is accepting connections on socketPath.
+func IsRunning(socketPath string) bool {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Connect asks the daemon to start a tunnel and returns its state once ready.
+func (c *Client) Connect(req *ConnectRequest) (*TunnelInfo, error) {
+	resp, err := c.do(&Request{Action: ActionConnect, Cluster: req.Cluster, Connect: req})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Tunnels) == 0 {
+		return nil, fmt.Errorf("daemon returned no tunnel information")
+	}
+	return resp.Tunnels[0], nil
+}
+
+// Status returns all tunnels managed by the daemon.
+func (c *Client) Status() ([]*TunnelInfo, error) {
+	resp, err := c.do(&Request{Action: ActionStatus})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Tunnels, nil
+}
+
+// Stats returns health and traffic statistics for tunnels running in the daemon.
+func (c *Client) Stats() ([]*health.TunnelStatus, error) {
+	resp, err := c.do(&Request{Action: ActionStats})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Stats, nil
+}
+
+// Stop asks the daemon to stop the tunnel for a cluster.
+func (c *Client) Stop(cluster string) error {
+	_, err := c.do(&Request{Action: ActionStop, Cluster: cluster})
+	return err
+}
+
+// Shutdown asks the daemon to stop all tunnels and exit.
+func (c *Client) Shutdown() error {
+	_, err := c.do(&Request{Action: ActionShutdown})
+	return err
+}
+
+// do sends a request and decodes the response.
+func (c *Client) do(req *Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", c.socketPath, dialTimeout)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request to daemon: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read daemon response: %w", err)
+	}
+
+	if !resp.OK {
+		if resp.Error == "" {
+			return &resp, errors.New("daemon request failed")
+		}
+		return &resp, errors.New(resp.Error)
+	}
+
+	return &resp, nil
+}
+
+// Spawn starts a detached daemon process and waits for it to accept connections.
+// Output from the daemon is appended to logPath.
+func Spawn(executable string, args []string, socketPath, logPath string) error {
+	logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open daemon log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcAttr()
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	// The daemon outlives us; release it so it is not left as a zombie on exit.
+	_ = cmd.Process.Release()
+
+	deadline := time.Now().Add(spawnWaitTimeout)
+	for time.Now().Before(deadline) {
+		if IsRunning(socketPath) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return fmt.Errorf("daemon did not start within %s (see %s)", spawnWaitTimeout, logPath)
+}
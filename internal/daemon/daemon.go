@@ -0,0 +1,446 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/health"
+	"github.com/scotttball/tunatap/internal/state"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+const (
+	socketFileName = "daemon.sock"
+	logFileName    = "daemon.log"
+
+	// requestReadTimeout bounds how long a client has to send its request.
+	requestReadTimeout = 10 * time.Second
+
+	// connectWaitTimeout bounds how long a connect request waits for the
+	// tunnel to become ready before replying with its current state.
+	connectWaitTimeout = 5 * time.Minute
+
+	// stopWaitTimeout bounds how long a stop request waits for a tunnel to exit.
+	stopWaitTimeout = 30 * time.Second
+)
+
+// Action identifies the operation requested from the daemon.
+type Action string
+
+const (
+	ActionConnect  Action = "connect"
+	ActionStatus   Action = "status"
+	ActionStats    Action = "stats"
+	ActionStop     Action = "stop"
+	ActionShutdown Action = "shutdown"
+)
+
+// Tunnel states reported by the daemon.
+const (
+	StateStarting = "starting"
+	StateReady    = "ready"
+	StateFailed   = "failed"
+)
+
+// ErrNotRunning is returned when no daemon is listening on the socket.
+var ErrNotRunning = errors.New("tunatap daemon is not running")
+
+// ConnectRequest describes a tunnel the daemon should start.
+type ConnectRequest struct {
+	Cluster       string   `json:"cluster"`
+	Endpoint      string   `json:"endpoint,omitempty"`
+	Bastion       string   `json:"bastion,omitempty"`
+	LocalPort     int      `json:"local_port,omitempty"`
+	Region        string   `json:"region,omitempty"`
+	OCIProfile    string   `json:"oci_profile,omitempty"`
+	NoCache       bool     `json:"no_cache,omitempty"`
+	SkipPreflight bool     `json:"skip_preflight,omitempty"`
+	ConfigFile    string   `json:"config_file,omitempty"`
+	SocksPort     int      `json:"socks_port,omitempty"`
+	AllEndpoints  bool     `json:"all_endpoints,omitempty"`
+	MaxBandwidth  string   `json:"max_bandwidth,omitempty"`
+	BindAddress   string   `json:"bind_address,omitempty"`
+	PortStrategy  string   `json:"port_strategy,omitempty"`
+	Profile       string   `json:"profile,omitempty"`
+	CreateBastion bool     `json:"create_bastion,omitempty"`
+	AllowCIDRs    []string `json:"allow_cidrs,omitempty"`
+	// Retry settings from the command line; nil ones come from config.
+	RetryInitialIntervalSeconds *int     `json:"retry_initial_interval_seconds,omitempty"`
+	RetryMultiplier             *float64 `json:"retry_multiplier,omitempty"`
+	RetryMaxAttempts            *int     `json:"retry_max_attempts,omitempty"`
+}
+
+// Request is a single message sent from a client to the daemon.
+type Request struct {
+	Action  Action          `json:"action"`
+	Cluster string          `json:"cluster,omitempty"`
+	Connect *ConnectRequest `json:"connect,omitempty"`
+}
+
+// Response is the daemon's reply to a Request.
+type Response struct {
+	OK      bool                   `json:"ok"`
+	Error   string                 `json:"error,omitempty"`
+	Tunnels []*TunnelInfo          `json:"tunnels,omitempty"`
+	Stats   []*health.TunnelStatus `json:"stats,omitempty"`
+}
+
+// TunnelInfo describes a tunnel managed by the daemon.
+type TunnelInfo struct {
+	Cluster    string    `json:"cluster"`
+	State      string    `json:"state"`
+	LocalPort  int       `json:"local_port,omitempty"`
+	RemoteHost string    `json:"remote_host,omitempty"`
+	RemotePort int       `json:"remote_port,omitempty"`
+	StartTime  time.Time `json:"start_time"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// ReadyFunc is called by a StartFunc once the tunnel accepts connections.
+type ReadyFunc func(localPort int, remoteHost string, remotePort int)
+
+// StartFunc establishes a tunnel and blocks until it exits or ctx is cancelled.
+type StartFunc func(ctx context.Context, req *ConnectRequest, onReady ReadyFunc) error
+
+// managedTunnel tracks a tunnel goroutine owned by the server.
+type managedTunnel struct {
+	info   TunnelInfo
+	cancel context.CancelFunc
+	ready  chan struct{}
+	done   chan struct{}
+}
+
+// Server is the long-running daemon that owns background tunnels.
+type Server struct {
+	socketPath string
+	start      StartFunc
+
+	mu       sync.Mutex
+	tunnels  map[string]*managedTunnel
+	shutdown chan struct{}
+	closeMu  sync.Once
+}
+
+// NewServer creates a daemon server listening on socketPath.
+func NewServer(socketPath string, start StartFunc) *Server {
+	return &Server{
+		socketPath: socketPath,
+		start:      start,
+		tunnels:    make(map[string]*managedTunnel),
+		shutdown:   make(chan struct{}),
+	}
+}
+
+// DefaultDir returns the directory holding the daemon socket and log,
+// respecting the configured home path.
+func DefaultDir() string {
+	if homePath := state.GetInstance().GetHomePath(); homePath != "" {
+		return homePath
+	}
+	return utils.DefaultTunatapDir()
+}
+
+// DefaultSocketPath returns the default daemon socket path.
+func DefaultSocketPath() string {
+	return filepath.Join(DefaultDir(), socketFileName)
+}
+
+// DefaultLogPath returns the default daemon log file path.
+func DefaultLogPath() string {
+	return filepath.Join(DefaultDir(), logFileName)
+}
+
+// Serve listens on the socket and handles requests until ctx is cancelled
+// or a shutdown request is received. All managed tunnels are stopped on exit.
+func (s *Server) Serve(ctx context.Context) error {
+	if IsRunning(s.socketPath) {
+		return fmt.Errorf("daemon already running on %s", s.socketPath)
+	}
+
+	// Remove a stale socket left behind by a daemon that did not exit cleanly
+	if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0o700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	if err := os.Chmod(s.socketPath, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	log.Info().Msgf("Daemon listening on %s", s.socketPath)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.shutdown:
+		}
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.closing(ctx) {
+				break
+			}
+			log.Error().Err(err).Msg("Daemon accept error")
+			s.stopAll()
+			return err
+		}
+		go s.handleConn(conn)
+	}
+
+	log.Info().Msg("Daemon shutting down, stopping all tunnels...")
+	s.stopAll()
+	_ = os.Remove(s.socketPath)
+	return nil
+}
+
+// Shutdown asks the server to stop accepting requests and exit Serve.
+func (s *Server) Shutdown() {
+	s.closeMu.Do(func() {
+		close(s.shutdown)
+	})
+}
+
+// closing reports whether the server has been asked to exit.
+func (s *Server) closing(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	case <-s.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// handleConn reads a single request from conn and writes the response.
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(requestReadTimeout))
+
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		writeResponse(conn, &Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	_ = conn.SetReadDeadline(time.Time{})
+
+	log.Debug().Str("action", string(req.Action)).Str("cluster", req.Cluster).Msg("Daemon request")
+
+	var resp *Response
+	switch req.Action {
+	case ActionConnect:
+		resp = s.handleConnect(req.Connect)
+	case ActionStatus:
+		resp = &Response{OK: true, Tunnels: s.List()}
+	case ActionStats:
+		resp = &Response{OK: true, Stats: health.GetRegistry().GetStatus().Tunnels}
+	case ActionStop:
+		resp = s.handleStop(req.Cluster)
+	case ActionShutdown:
+		resp = &Response{OK: true}
+		defer s.Shutdown()
+	default:
+		resp = &Response{Error: fmt.Sprintf("unknown action: %q", req.Action)}
+	}
+
+	writeResponse(conn, resp)
+}
+
+// handleConnect starts a tunnel and waits for it to become ready.
+func (s *Server) handleConnect(req *ConnectRequest) *Response {
+	if req == nil || req.Cluster == "" {
+		return &Response{Error: "cluster name is required"}
+	}
+
+	mt, err := s.startTunnel(req)
+	if err != nil {
+		return &Response{Error: err.Error()}
+	}
+
+	select {
+	case <-mt.ready:
+	case <-mt.done:
+	case <-time.After(connectWaitTimeout):
+	}
+
+	info := s.snapshot(mt)
+	if info.State == StateFailed {
+		return &Response{Error: info.LastError, Tunnels: []*TunnelInfo{info}}
+	}
+	return &Response{OK: true, Tunnels: []*TunnelInfo{info}}
+}
+
+// startTunnel registers a new managed tunnel and launches it in the background.
+func (s *Server) startTunnel(req *ConnectRequest) (*managedTunnel, error) {
+	key := strings.ToLower(req.Cluster)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.tunnels[key]; ok {
+		if existing.info.State != StateFailed {
+			return nil, fmt.Errorf("tunnel to '%s' is already %s on port %d",
+				req.Cluster, existing.info.State, existing.info.LocalPort)
+		}
+		// Replace a failed tunnel with a fresh attempt
+		existing.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mt := &managedTunnel{
+		info: TunnelInfo{
+			Cluster:   req.Cluster,
+			State:     StateStarting,
+			LocalPort: req.LocalPort,
+			StartTime: time.Now(),
+		},
+		cancel: cancel,
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	s.tunnels[key] = mt
+
+	var readyOnce sync.Once
+	onReady := func(localPort int, remoteHost string, remotePort int) {
+		s.mu.Lock()
+		mt.info.State = StateReady
+		mt.info.LocalPort = localPort
+		mt.info.RemoteHost = remoteHost
+		mt.info.RemotePort = remotePort
+		mt.info.LastError = ""
+		s.mu.Unlock()
+		readyOnce.Do(func() { close(mt.ready) })
+		log.Info().Msgf("Tunnel to %s ready on localhost:%d", req.Cluster, localPort)
+	}
+
+	go func() {
+		defer close(mt.done)
+		err := s.start(ctx, req, onReady)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msgf("Tunnel to %s failed", req.Cluster)
+			mt.info.State = StateFailed
+			mt.info.LastError = err.Error()
+			return
+		}
+
+		// Clean exit or cancellation: forget the tunnel if it is still ours
+		if s.tunnels[key] == mt {
+			delete(s.tunnels, key)
+		}
+	}()
+
+	return mt, nil
+}
+
+// handleStop stops the tunnel for a cluster.
+func (s *Server) handleStop(cluster string) *Response {
+	if cluster == "" {
+		return &Response{Error: "cluster name is required"}
+	}
+
+	if err := s.Stop(cluster); err != nil {
+		return &Response{Error: err.Error()}
+	}
+	return &Response{OK: true}
+}
+
+// Stop cancels the tunnel for a cluster and waits for it to exit.
+func (s *Server) Stop(cluster string) error {
+	key := strings.ToLower(cluster)
+
+	s.mu.Lock()
+	mt, ok := s.tunnels[key]
+	if ok {
+		delete(s.tunnels, key)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no tunnel running for cluster '%s'", cluster)
+	}
+
+	log.Info().Msgf("Stopping tunnel to %s", cluster)
+	mt.cancel()
+
+	select {
+	case <-mt.done:
+	case <-time.After(stopWaitTimeout):
+		log.Warn().Msgf("Timed out waiting for tunnel to %s to stop", cluster)
+	}
+	return nil
+}
+
+// stopAll stops every managed tunnel.
+func (s *Server) stopAll() {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.tunnels))
+	for _, mt := range s.tunnels {
+		names = append(names, mt.info.Cluster)
+	}
+	s.mu.Unlock()
+
+	for _, name := range names {
+		_ = s.Stop(name)
+	}
+}
+
+// List returns a snapshot of all managed tunnels sorted by cluster name.
+func (s *Server) List() []*TunnelInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tunnels := make([]*TunnelInfo, 0, len(s.tunnels))
+	for _, mt := range s.tunnels {
+		info := mt.info
+		tunnels = append(tunnels, &info)
+	}
+
+	sort.Slice(tunnels, func(i, j int) bool {
+		return tunnels[i].Cluster < tunnels[j].Cluster
+	})
+
+	return tunnels
+}
+
+// snapshot returns a copy of a managed tunnel's info.
+func (s *Server) snapshot(mt *managedTunnel) *TunnelInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := mt.info
+	return &info
+}
+
+// writeResponse encodes a response onto the connection.
+func writeResponse(conn net.Conn, resp *Response) {
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		log.Debug().Err(err).Msg("Failed to write daemon response")
+	}
+}
@@ -0,0 +1,209 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/health"
+)
+
+// startTestServer starts a server on a temp socket and returns a client for it.
+func startTestServer(t *testing.T, start StartFunc) (*Server, *Client) {
+	t.Helper()
+
+	// Unix socket paths are length-limited, so keep this short
+	dir, err := os.MkdirTemp("", "tt")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socketPath := filepath.Join(dir, "d.sock")
+	server := NewServer(socketPath, start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-serveErr:
+		case <-time.After(5 * time.Second):
+			t.Error("Serve() did not return after cancellation")
+		}
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !IsRunning(socketPath) {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon did not start listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return server, NewClient(socketPath)
+}
+
+// readyStart is a StartFunc that reports ready and blocks until cancelled.
+func readyStart(ctx context.Context, req *ConnectRequest, onReady ReadyFunc) error {
+	onReady(16443, "10.0.0.1", 6443)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestServer_ConnectStatusStop(t *testing.T) {
+	_, client := startTestServer(t, readyStart)
+
+	info, err := client.Connect(&ConnectRequest{Cluster: "prod"})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if info.State != StateReady {
+		t.Errorf("State = %q, want %q", info.State, StateReady)
+	}
+	if info.LocalPort != 16443 {
+		t.Errorf("LocalPort = %d, want %d", info.LocalPort, 16443)
+	}
+
+	tunnels, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(tunnels) != 1 {
+		t.Fatalf("Status() returned %d tunnels, want 1", len(tunnels))
+	}
+	if tunnels[0].RemoteHost != "10.0.0.1" || tunnels[0].RemotePort != 6443 {
+		t.Errorf("Remote = %s:%d, want 10.0.0.1:6443", tunnels[0].RemoteHost, tunnels[0].RemotePort)
+	}
+
+	if err := client.Stop("PROD"); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	tunnels, err = client.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(tunnels) != 0 {
+		t.Errorf("Status() returned %d tunnels after stop, want 0", len(tunnels))
+	}
+}
+
+func TestServer_ConnectDuplicate(t *testing.T) {
+	_, client := startTestServer(t, readyStart)
+
+	if _, err := client.Connect(&ConnectRequest{Cluster: "prod"}); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	if _, err := client.Connect(&ConnectRequest{Cluster: "prod"}); err == nil {
+		t.Error("second Connect() should fail while tunnel is running")
+	}
+}
+
+func TestServer_ConnectFailure(t *testing.T) {
+	failing := func(ctx context.Context, req *ConnectRequest, onReady ReadyFunc) error {
+		return errors.New("bastion unreachable")
+	}
+	_, client := startTestServer(t, failing)
+
+	_, err := client.Connect(&ConnectRequest{Cluster: "prod"})
+	if err == nil {
+		t.Fatal("Connect() should fail when the tunnel fails to start")
+	}
+	if err.Error() != "bastion unreachable" {
+		t.Errorf("error = %q, want %q", err.Error(), "bastion unreachable")
+	}
+
+	// Failed tunnels remain visible until retried or stopped
+	tunnels, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(tunnels) != 1 || tunnels[0].State != StateFailed {
+		t.Errorf("Status() = %+v, want one failed tunnel", tunnels)
+	}
+}
+
+func TestServer_ConnectRequiresCluster(t *testing.T) {
+	_, client := startTestServer(t, readyStart)
+
+	if _, err := client.Connect(&ConnectRequest{}); err == nil {
+		t.Error("Connect() without cluster should fail")
+	}
+}
+
+func TestServer_StopUnknown(t *testing.T) {
+	_, client := startTestServer(t, readyStart)
+
+	if err := client.Stop("missing"); err == nil {
+		t.Error("Stop() of unknown cluster should fail")
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	stopped := make(chan struct{})
+	start := func(ctx context.Context, req *ConnectRequest, onReady ReadyFunc) error {
+		onReady(1, "", 0)
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	}
+	server, client := startTestServer(t, start)
+
+	if _, err := client.Connect(&ConnectRequest{Cluster: "prod"}); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	if err := client.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tunnel was not stopped on shutdown")
+	}
+
+	if len(server.List()) != 0 {
+		t.Error("server should have no tunnels after shutdown")
+	}
+}
+
+func TestClient_NotRunning(t *testing.T) {
+	client := NewClient(filepath.Join(t.TempDir(), "missing.sock"))
+
+	if _, err := client.Status(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Status() error = %v, want ErrNotRunning", err)
+	}
+}
+
+func TestServer_Stats(t *testing.T) {
+	_, client := startTestServer(t, readyStart)
+
+	id := "daemon-stats-test"
+	health.GetRegistry().Register(&health.TunnelStatus{ID: id, Cluster: "prod", Healthy: true})
+	defer health.GetRegistry().Deregister(id)
+
+	stats, err := client.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+
+	found := false
+	for _, s := range stats {
+		if s.ID == id {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Stats() = %+v, want tunnel %q from the health registry", stats, id)
+	}
+}
@@ -0,0 +1,109 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+)
+
+// DiscoverAll finds every cluster, or resource of hints.Type, in
+// hints.Region or every subscribed region, with its endpoint and bastion,
+// and returns them as config clusters sorted by region and name. Resources
+// whose details cannot be read are skipped with a warning; those without a
+// bastion are kept without one. Regions that cannot be searched are skipped,
+// unless none can be. With hints.CompartmentOCID, only that compartment and
+// those in it are walked.
+func (d *Discoverer) DiscoverAll(ctx context.Context, hints *DiscoveryHints) ([]*config.Cluster, error) {
+	tenancyOCID, err := d.ociClient.GetTenancyOCID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenancy OCID: %w", err)
+	}
+
+	hints, err = d.scopeHints(ctx, hints)
+	if err != nil {
+		return nil, err
+	}
+
+	regions, err := d.getRegionsToSearch(ctx, tenancyOCID, hints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get regions: %w", err)
+	}
+
+	var found []*DiscoveredCluster
+	var lastErr error
+	failedRegions := 0
+	for _, region := range regions {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		log.Info().Msgf("Listing %s resources in region %s...", hints.resourceType(), region)
+
+		regionCtx, done := d.profile.track(ctx, region, "")
+		var matches []*DiscoveredCluster
+		if hints != nil && hints.Method == MethodSearch {
+			matches, err = d.searchResources(regionCtx, allResourcesQuery(d.kindOf(hints.resourceType())), hints.resourceType(), region,
+				func(resourcesearch.ResourceSummary) bool { return true })
+		} else {
+			matches, err = d.listInRegion(regionCtx, tenancyOCID, region, hints, func(string) bool { return true })
+		}
+		done()
+		if err != nil {
+			log.Warn().Err(err).Msgf("Error searching region %s", region)
+			lastErr = err
+			failedRegions++
+			continue
+		}
+		found = append(found, matches...)
+	}
+	if len(regions) > 0 && failedRegions == len(regions) {
+		return nil, fmt.Errorf("failed to search any region: %w", lastErr)
+	}
+
+	sortDiscovered(found)
+
+	clusters := make([]*config.Cluster, 0, len(found))
+	for _, c := range found {
+		cluster, err := d.CompleteCluster(ctx, c, c.CacheKey())
+		if err != nil {
+			log.Warn().Err(err).Msgf("Skipping '%s' in region %s", c.Name, c.Region)
+			continue
+		}
+
+		bastion, err := d.DiscoverBastion(ctx, cluster)
+		if err != nil {
+			if !errors.Is(err, ErrNoBastionFound) {
+				log.Warn().Err(err).Msgf("Failed to discover a bastion for '%s'", cluster.Name)
+			}
+			bastion = nil
+		}
+
+		entry, err := d.ResolveToConfig(cluster, bastion)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, entry)
+	}
+	return clusters, nil
+}
+
+// sortDiscovered orders clusters by region, then name, then OCID.
+func sortDiscovered(clusters []*DiscoveredCluster) {
+	slices.SortFunc(clusters, func(a, b *DiscoveredCluster) int {
+		if c := strings.Compare(a.Region, b.Region); c != 0 {
+			return c
+		}
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.OCID, b.OCID)
+	})
+}
@@ -0,0 +1,78 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+func TestDiscoverAll(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	endpoint := "10.0.1.10:6443"
+	for _, name := range []string{"payments", "Ledger"} {
+		id, name := "ocid1.cluster.oc1.iad."+name, name
+		mock.AddClusterToCompartment(mock.TenancyOCID, containerengine.ClusterSummary{Id: &id, Name: &name})
+		mock.AddCluster(&containerengine.Cluster{
+			Id: &id, Name: &name, Endpoints: &containerengine.ClusterEndpoints{PrivateEndpoint: &endpoint},
+		})
+	}
+	// Listed, but its details cannot be read
+	missing, missingName := "ocid1.cluster.oc1.iad.gone", "gone"
+	mock.AddClusterToCompartment(mock.TenancyOCID, containerengine.ClusterSummary{Id: &missing, Name: &missingName})
+
+	bastionID, standard := "ocid1.bastion.oc1.iad.a", "STANDARD"
+	mock.AddBastion(&bastion.Bastion{Id: &bastionID, Name: &bastionID, BastionType: &standard})
+
+	clusters, err := NewDiscoverer(mock, nil).DiscoverAll(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("DiscoverAll() error = %v", err)
+	}
+
+	if len(clusters) != 2 {
+		t.Fatalf("DiscoverAll() returned %d clusters, want 2", len(clusters))
+	}
+	if clusters[0].ClusterName != "Ledger" || clusters[1].ClusterName != "payments" {
+		t.Errorf("clusters = %s, %s; want Ledger, payments", clusters[0].ClusterName, clusters[1].ClusterName)
+	}
+	for _, c := range clusters {
+		if c.Region != "us-ashburn-1" || c.Ocid == nil || len(c.Endpoints) != 1 || c.Endpoints[0].Ip != "10.0.1.10" {
+			t.Errorf("cluster %s = %+v", c.ClusterName, c)
+		}
+		if c.BastionId == nil || *c.BastionId != bastionID {
+			t.Errorf("cluster %s bastion = %v, want %s", c.ClusterName, c.BastionId, bastionID)
+		}
+	}
+}
+
+func TestDiscoverAll_NoBastion(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name := "ocid1.cluster.oc1.iad.a", "payments"
+	mock.AddClusterToCompartment(mock.TenancyOCID, containerengine.ClusterSummary{Id: &id, Name: &name})
+	mock.AddCluster(&containerengine.Cluster{Id: &id, Name: &name})
+
+	clusters, err := NewDiscoverer(mock, nil).DiscoverAll(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("DiscoverAll() error = %v", err)
+	}
+	if len(clusters) != 1 || clusters[0].BastionId != nil {
+		t.Errorf("DiscoverAll() = %+v, want one cluster without a bastion", clusters)
+	}
+}
+
+func TestDiscoverAll_AllRegionsFail(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	mock.ShouldFailCluster = true
+
+	hints := &DiscoveryHints{Method: MethodSearch}
+	if _, err := NewDiscoverer(mock, nil).DiscoverAll(context.Background(), hints); err == nil {
+		t.Error("DiscoverAll() expected error when no region can be searched")
+	}
+}
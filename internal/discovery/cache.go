@@ -5,16 +5,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/cachecrypt"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/state"
+	"github.com/scotttball/tunatap/pkg/utils"
 )
 
 const (
 	// DefaultCacheTTL is the default time-to-live for cache entries.
 	DefaultCacheTTL = 24 * time.Hour
 
+	// DefaultNegativeCacheTTL is the default time-to-live for names that
+	// discovery did not find. It is short so that new clusters are found
+	// soon after they are created.
+	DefaultNegativeCacheTTL = 10 * time.Minute
+
 	// CacheFileName is the name of the cache file.
 	CacheFileName = "cache.json"
 )
@@ -28,23 +38,47 @@ type CacheEntry struct {
 	SubnetID        string    `json:"subnet_id,omitempty"`
 	CachedAt        time.Time `json:"cached_at"`
 
+	// ExpiresAt overrides the cache's TTL for this entry when set.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+
 	// Endpoint information for clusters
 	EndpointIP   string `json:"endpoint_ip,omitempty"`
 	EndpointPort int    `json:"endpoint_port,omitempty"`
+
+	// CompartmentPath is the compartment's path, like "root/prod/oke", for
+	// clusters
+	CompartmentPath string `json:"compartment_path,omitempty"`
+
+	// Fallbacks are other bastion OCIDs to fail over to, for bastions
+	Fallbacks []string `json:"fallbacks,omitempty"`
+
+	// Name is the name of a compartment, whose parent is CompartmentOCID
+	Name string `json:"name,omitempty"`
 }
 
 // CacheData represents the full cache file structure.
 type CacheData struct {
 	Clusters map[string]*CacheEntry `json:"clusters"`
 	Bastions map[string]*CacheEntry `json:"bastions"`
+	// NotFound records cluster names that discovery did not find. Only
+	// CachedAt and ExpiresAt are set.
+	NotFound map[string]*CacheEntry `json:"not_found,omitempty"`
+	// Compartments records the names and parents of compartments, keyed by
+	// OCID, to show compartment paths without looking them up again.
+	Compartments map[string]*CacheEntry `json:"compartments,omitempty"`
 }
 
 // Cache manages cluster and bastion discovery caching.
 type Cache struct {
-	mu   sync.RWMutex
-	data CacheData
-	path string
-	ttl  time.Duration
+	mu          sync.RWMutex
+	data        CacheData
+	path        string
+	ttl         time.Duration
+	negativeTTL time.Duration
+	// encrypt writes the cache encrypted. It is set when the cache was read
+	// encrypted, so that a cache is not written back in plain text unless
+	// SetEncrypted says so.
+	encrypt bool
 }
 
 // NewCache creates or loads a cache from the specified base directory.
@@ -57,11 +91,14 @@ func NewCache(basePath string, ttl time.Duration) (*Cache, error) {
 
 	cache := &Cache{
 		data: CacheData{
-			Clusters: make(map[string]*CacheEntry),
-			Bastions: make(map[string]*CacheEntry),
+			Clusters:     make(map[string]*CacheEntry),
+			Bastions:     make(map[string]*CacheEntry),
+			NotFound:     make(map[string]*CacheEntry),
+			Compartments: make(map[string]*CacheEntry),
 		},
-		path: cachePath,
-		ttl:  ttl,
+		path:        cachePath,
+		ttl:         ttl,
+		negativeTTL: DefaultNegativeCacheTTL,
 	}
 
 	// Try to load existing cache
@@ -75,6 +112,37 @@ func NewCache(basePath string, ttl time.Duration) (*Cache, error) {
 	return cache, nil
 }
 
+// OpenCache opens the discovery cache of the config in use, in the directory
+// of its config profile, with the TTLs and encryption setting of cfg.
+func OpenCache(cfg *config.Config) (*Cache, error) {
+	dir := state.GetInstance().GetConfigDir()
+	if dir == "" {
+		dir = utils.DefaultTunatapDir()
+	}
+	cache, err := NewCache(dir, time.Duration(cfg.GetCacheTTLHours())*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cache.SetNegativeTTL(time.Duration(cfg.GetNegativeCacheTTLMinutes()) * time.Minute)
+	cache.SetEncrypted(cfg.CacheEncryption)
+	return cache, nil
+}
+
+// SetEncrypted sets whether the cache is written encrypted with the key in
+// the OS keychain. It takes effect on the next save.
+func (c *Cache) SetEncrypted(encrypt bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.encrypt = encrypt
+}
+
+// Encrypted reports whether the cache is written encrypted.
+func (c *Cache) Encrypted() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.encrypt
+}
+
 // GetCluster retrieves a cached cluster entry by name.
 // Returns nil if entry doesn't exist or is expired.
 func (c *Cache) GetCluster(name string) *CacheEntry {
@@ -102,10 +170,103 @@ func (c *Cache) SetCluster(name string, entry *CacheEntry) error {
 
 	entry.CachedAt = time.Now()
 	c.data.Clusters[name] = entry
+	delete(c.data.NotFound, name)
+
+	return c.saveLocked()
+}
+
+// SetNegativeTTL sets how long names that were not found are remembered.
+// Zero or less disables negative caching.
+func (c *Cache) SetNegativeTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.negativeTTL = ttl
+}
+
+// SetNotFound records that discovery did not find a cluster named name.
+func (c *Cache) SetNotFound(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.negativeTTL <= 0 {
+		return nil
+	}
+	now := time.Now()
+	c.data.NotFound[name] = &CacheEntry{CachedAt: now, ExpiresAt: now.Add(c.negativeTTL)}
+
+	return c.saveLocked()
+}
+
+// GetNotFound returns when discovery last failed to find a cluster named
+// name, or nil if that is not cached or has expired.
+func (c *Cache) GetNotFound(name string) *CacheEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.data.NotFound[name]
+	if !ok || c.isExpired(entry) {
+		return nil
+	}
+	return entry
+}
+
+// GetAllNotFound returns all non-expired negative entries, keyed by name.
+func (c *Cache) GetAllNotFound() map[string]*CacheEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make(map[string]*CacheEntry)
+	for name, entry := range c.data.NotFound {
+		if !c.isExpired(entry) {
+			result[name] = entry
+		}
+	}
+	return result
+}
+
+// GetCompartment returns the cached name and parent of a compartment, or
+// nil if it is not cached or has expired.
+func (c *Cache) GetCompartment(compartmentID string) *CacheEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.compartmentLocked(compartmentID)
+}
+
+// compartmentLocked returns the unexpired entry for a compartment (must be
+// called with lock held).
+func (c *Cache) compartmentLocked(compartmentID string) *CacheEntry {
+	entry, ok := c.data.Compartments[compartmentID]
+	if !ok || c.isExpired(entry) {
+		return nil
+	}
+	return entry
+}
+
+// SetCompartment stores the name and parent of a compartment.
+func (c *Cache) SetCompartment(compartmentID string, entry *CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.CachedAt = time.Now()
+	c.data.Compartments[compartmentID] = entry
 
 	return c.saveLocked()
 }
 
+// CompartmentPath returns the path of a compartment from cached names
+// alone, or "" if it or an ancestor is not cached.
+func (c *Cache) CompartmentPath(compartmentID string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return compartmentPath(compartmentID, "", func(id string) (*CacheEntry, error) {
+		if entry := c.compartmentLocked(id); entry != nil {
+			return entry, nil
+		}
+		return nil, fmt.Errorf("compartment %s is not cached", id)
+	})
+}
+
 // GetBastion retrieves a cached bastion entry for a cluster.
 // Returns nil if entry doesn't exist or is expired.
 func (c *Cache) GetBastion(clusterName string) *CacheEntry {
@@ -137,17 +298,43 @@ func (c *Cache) SetBastion(clusterName string, entry *CacheEntry) error {
 	return c.saveLocked()
 }
 
-// Invalidate removes a cluster and its associated bastion from the cache.
+// Invalidate removes a cluster and its associated bastion from the cache,
+// along with any choice cached for the name with a region hint and any
+// record of the name not being found.
 func (c *Cache) Invalidate(clusterName string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	delete(c.data.Clusters, clusterName)
 	delete(c.data.Bastions, clusterName)
+	delete(c.data.NotFound, clusterName)
+	for _, entries := range []map[string]*CacheEntry{c.data.Clusters, c.data.NotFound} {
+		for key := range entries {
+			if strings.HasPrefix(key, clusterName+"@") {
+				delete(entries, key)
+			}
+		}
+	}
 
 	return c.saveLocked()
 }
 
+// Contains reports whether anything is cached for the cluster name,
+// expired or not, that Invalidate would remove.
+func (c *Cache) Contains(clusterName string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, entries := range []map[string]*CacheEntry{c.data.Clusters, c.data.Bastions, c.data.NotFound} {
+		for key := range entries {
+			if key == clusterName || strings.HasPrefix(key, clusterName+"@") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // InvalidateAll clears the entire cache.
 func (c *Cache) InvalidateAll() error {
 	c.mu.Lock()
@@ -155,6 +342,8 @@ func (c *Cache) InvalidateAll() error {
 
 	c.data.Clusters = make(map[string]*CacheEntry)
 	c.data.Bastions = make(map[string]*CacheEntry)
+	c.data.NotFound = make(map[string]*CacheEntry)
+	c.data.Compartments = make(map[string]*CacheEntry)
 
 	return c.saveLocked()
 }
@@ -173,6 +362,21 @@ func (c *Cache) GetAllClusters() map[string]*CacheEntry {
 	return result
 }
 
+// GetAllBastions returns all non-expired bastion entries, keyed by cluster
+// name.
+func (c *Cache) GetAllBastions() map[string]*CacheEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make(map[string]*CacheEntry)
+	for name, entry := range c.data.Bastions {
+		if !c.isExpired(entry) {
+			result[name] = entry
+		}
+	}
+	return result
+}
+
 // GetClusterTTL returns the remaining TTL for a cluster entry.
 // Returns 0 if the entry doesn't exist or is expired.
 func (c *Cache) GetClusterTTL(name string) time.Duration {
@@ -183,12 +387,24 @@ func (c *Cache) GetClusterTTL(name string) time.Duration {
 	if !ok {
 		return 0
 	}
+	return c.remaining(entry)
+}
 
-	remaining := c.ttl - time.Since(entry.CachedAt)
-	if remaining < 0 {
-		return 0
+// remaining returns how long entry has left before it expires.
+func (c *Cache) remaining(entry *CacheEntry) time.Duration {
+	expiresAt := entry.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = entry.CachedAt.Add(c.ttl)
 	}
-	return remaining
+	return max(time.Until(expiresAt), 0)
+}
+
+// Remaining returns how long entry, one of this cache's, has left before it
+// expires.
+func (c *Cache) Remaining(entry *CacheEntry) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.remaining(entry)
 }
 
 // Load reads the cache from disk.
@@ -200,6 +416,12 @@ func (c *Cache) Load() error {
 	if err != nil {
 		return err
 	}
+	if cachecrypt.IsEncrypted(data) {
+		c.encrypt = true
+		if data, err = cachecrypt.Decrypt(data); err != nil {
+			return err
+		}
+	}
 
 	var cacheData CacheData
 	if err := json.Unmarshal(data, &cacheData); err != nil {
@@ -213,6 +435,12 @@ func (c *Cache) Load() error {
 	if cacheData.Bastions == nil {
 		cacheData.Bastions = make(map[string]*CacheEntry)
 	}
+	if cacheData.NotFound == nil {
+		cacheData.NotFound = make(map[string]*CacheEntry)
+	}
+	if cacheData.Compartments == nil {
+		cacheData.Compartments = make(map[string]*CacheEntry)
+	}
 
 	c.data = cacheData
 	log.Debug().Msgf("Loaded cache with %d clusters and %d bastions",
@@ -241,15 +469,19 @@ func (c *Cache) saveLocked() error {
 		return fmt.Errorf("failed to marshal cache: %w", err)
 	}
 
-	if err := os.WriteFile(c.path, data, 0600); err != nil {
+	if err := cachecrypt.WriteFile(c.path, data, 0600, c.encrypt); err != nil {
 		return fmt.Errorf("failed to write cache: %w", err)
 	}
 
 	return nil
 }
 
-// isExpired checks if a cache entry has expired.
+// isExpired checks if a cache entry has expired, by its own expiry time if
+// it has one and by the cache's TTL otherwise.
 func (c *Cache) isExpired(entry *CacheEntry) bool {
+	if !entry.ExpiresAt.IsZero() {
+		return time.Now().After(entry.ExpiresAt)
+	}
 	return time.Since(entry.CachedAt) > c.ttl
 }
 
@@ -281,6 +513,22 @@ func (c *Cache) CleanExpired() error {
 		}
 	}
 
+	// Clean expired negative entries
+	for name, entry := range c.data.NotFound {
+		if c.isExpired(entry) {
+			delete(c.data.NotFound, name)
+			modified = true
+		}
+	}
+
+	// Clean expired compartment names
+	for id, entry := range c.data.Compartments {
+		if c.isExpired(entry) {
+			delete(c.data.Compartments, id)
+			modified = true
+		}
+	}
+
 	if modified {
 		return c.saveLocked()
 	}
@@ -3,8 +3,11 @@ package discovery
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/scotttball/tunatap/internal/cachecrypt"
 )
 
 func TestNewCache(t *testing.T) {
@@ -137,6 +140,73 @@ func TestCache_Invalidate(t *testing.T) {
 	}
 }
 
+func TestCache_NotFound(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+
+	if err := cache.SetNotFound("missing"); err != nil {
+		t.Fatalf("SetNotFound() error = %v", err)
+	}
+	if err := cache.SetNotFound("missing@us-phoenix-1"); err != nil {
+		t.Fatalf("SetNotFound() error = %v", err)
+	}
+	entry := cache.GetNotFound("missing")
+	if entry == nil {
+		t.Fatal("GetNotFound() should return the negative entry")
+	}
+	if remaining := cache.Remaining(entry); remaining <= 0 || remaining > DefaultNegativeCacheTTL {
+		t.Errorf("Remaining() = %s, want within the negative TTL", remaining)
+	}
+	if cache.GetCluster("missing") != nil {
+		t.Error("A negative entry should not be returned as a cluster")
+	}
+
+	// Finding the cluster replaces the negative entry
+	cache.SetCluster("missing", &CacheEntry{OCID: "cluster-ocid"})
+	if cache.GetNotFound("missing") != nil {
+		t.Error("SetCluster() should remove the negative entry")
+	}
+
+	if !cache.Contains("missing") {
+		t.Error("Contains() should report entries under a region hint")
+	}
+	if err := cache.Invalidate("missing"); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if cache.Contains("missing") {
+		t.Error("Invalidate() should remove negative entries under region hints")
+	}
+}
+
+func TestCache_NotFoundDisabled(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	cache.SetNegativeTTL(0)
+
+	if err := cache.SetNotFound("missing"); err != nil {
+		t.Fatalf("SetNotFound() error = %v", err)
+	}
+	if cache.GetNotFound("missing") != nil {
+		t.Error("Negative entries should not be cached with a zero TTL")
+	}
+}
+
+func TestCache_EntryExpiresAt(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+
+	cache.SetCluster("short-lived", &CacheEntry{OCID: "cluster-ocid", ExpiresAt: time.Now().Add(-time.Minute)})
+	if cache.GetCluster("short-lived") != nil {
+		t.Error("An entry past its own expiry should be expired despite the cache TTL")
+	}
+}
+
 func TestCache_InvalidateAll(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -321,3 +391,63 @@ func TestNewCache_CreatesDirectory(t *testing.T) {
 		t.Error("NewCache should create nested directory on save")
 	}
 }
+
+// memoryKeychain is a keychain held in memory.
+type memoryKeychain map[string]string
+
+func (k memoryKeychain) Get(service, account string) (string, error) {
+	secret, ok := k[service+"/"+account]
+	if !ok {
+		return "", cachecrypt.ErrKeyNotFound
+	}
+	return secret, nil
+}
+
+func (k memoryKeychain) Set(service, account, secret string) error {
+	k[service+"/"+account] = secret
+	return nil
+}
+
+func TestCache_Encrypted(t *testing.T) {
+	cachecrypt.SetKeychain(memoryKeychain{})
+	t.Cleanup(func() { cachecrypt.SetKeychain(nil) })
+
+	dir := t.TempDir()
+	cache, err := NewCache(dir, DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	cache.SetEncrypted(true)
+	if err := cache.SetCluster("prod", &CacheEntry{OCID: "ocid1.cluster.oc1..prod", EndpointIP: "10.0.1.10"}); err != nil {
+		t.Fatalf("SetCluster() error = %v", err)
+	}
+
+	data, err := os.ReadFile(cache.Path())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !cachecrypt.IsEncrypted(data) || strings.Contains(string(data), "10.0.1.10") {
+		t.Fatalf("cache file is not encrypted:\n%s", data)
+	}
+
+	// Reopened without the setting, it is read and kept encrypted
+	reopened, err := NewCache(dir, DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	if entry := reopened.GetCluster("prod"); entry == nil || entry.EndpointIP != "10.0.1.10" {
+		t.Errorf("GetCluster() = %+v, want the encrypted entry", entry)
+	}
+	if !reopened.Encrypted() {
+		t.Error("a cache read encrypted should be written encrypted")
+	}
+
+	// Turning encryption off writes it in plain text
+	reopened.SetEncrypted(false)
+	if err := reopened.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if data, _ := os.ReadFile(cache.Path()); cachecrypt.IsEncrypted(data) {
+		t.Error("cache file should be plain text after turning encryption off")
+	}
+}
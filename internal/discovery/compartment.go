@@ -3,6 +3,8 @@ package discovery
 import (
 	"context"
 	"fmt"
+	"path"
+	"slices"
 	"strings"
 	"sync"
 
@@ -18,26 +20,91 @@ type CompartmentNode struct {
 	Path     string // Full path like "root/prod/kubernetes"
 	ParentID string
 	Children []*CompartmentNode
+
+	depth int
+	// included is whether the compartment's resources are searched: it or
+	// an ancestor matches an include pattern, or there are none.
+	included bool
+}
+
+// CompartmentFilter limits which compartments discovery searches. Patterns
+// match a compartment's OCID, its name or its path (e.g. "root/prod/oke"),
+// ignoring case, and may use the wildcards of path.Match.
+type CompartmentFilter struct {
+	// Include limits searching to matching compartments and their
+	// descendants. Empty means all compartments.
+	Include []string
+	// Exclude skips matching compartments and their descendants, which are
+	// not listed at all.
+	Exclude []string
+	// MaxDepth skips compartments nested deeper than this below the tenancy,
+	// whose children are at depth 1. Zero means no limit.
+	MaxDepth int
+}
+
+// IsZero reports whether the filter lets every compartment through.
+func (f *CompartmentFilter) IsZero() bool {
+	return f == nil || (len(f.Include) == 0 && len(f.Exclude) == 0 && f.MaxDepth <= 0)
+}
+
+// matchesCompartment reports whether any of patterns names node.
+func matchesCompartment(patterns []string, node *CompartmentNode) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		for _, s := range []string{node.ID, node.Name, node.Path} {
+			if ok, _ := path.Match(pattern, strings.ToLower(s)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// excludes reports whether node and its descendants are left out of the
+// tree.
+func (f *CompartmentFilter) excludes(node *CompartmentNode) bool {
+	if f == nil {
+		return false
+	}
+	if f.MaxDepth > 0 && node.depth > f.MaxDepth {
+		return true
+	}
+	return matchesCompartment(f.Exclude, node)
+}
+
+// includes reports whether node's resources are searched, given whether
+// its parent's are.
+func (f *CompartmentFilter) includes(node *CompartmentNode, parentIncluded bool) bool {
+	if f == nil || len(f.Include) == 0 || parentIncluded {
+		return true
+	}
+	return matchesCompartment(f.Include, node)
 }
 
 // CompartmentTree manages compartment hierarchy for discovery.
 type CompartmentTree struct {
 	root     *CompartmentNode
 	flatList []*CompartmentNode
+	filter   *CompartmentFilter
 	mu       sync.RWMutex
 }
 
-// BuildCompartmentTree builds a tree of all compartments in the tenancy.
-func BuildCompartmentTree(ctx context.Context, ociClient client.OCIClientInterface, tenancyID string) (*CompartmentTree, error) {
+// BuildCompartmentTree builds a tree of the compartments in the tenancy
+// that filter lets through. A nil filter keeps all of them.
+func BuildCompartmentTree(ctx context.Context, ociClient client.OCIClientInterface, tenancyID string, filter *CompartmentFilter) (*CompartmentTree, error) {
+	return buildCompartmentTree(ctx, ociClient, &CompartmentNode{ID: tenancyID, Name: "root", Path: "root"}, filter)
+}
+
+// buildCompartmentTree builds the tree of root and the compartments in it
+// that filter lets through.
+func buildCompartmentTree(ctx context.Context, ociClient client.OCIClientInterface, root *CompartmentNode, filter *CompartmentFilter) (*CompartmentTree, error) {
+	root.Children = make([]*CompartmentNode, 0)
 	tree := &CompartmentTree{
-		root: &CompartmentNode{
-			ID:       tenancyID,
-			Name:     "root",
-			Path:     "root",
-			Children: make([]*CompartmentNode, 0),
-		},
+		root:     root,
 		flatList: make([]*CompartmentNode, 0),
+		filter:   filter,
 	}
+	tree.root.included = filter.includes(tree.root, false)
 
 	// Add root to flat list
 	tree.flatList = append(tree.flatList, tree.root)
@@ -77,7 +144,13 @@ func buildTreeRecursive(ctx context.Context, ociClient client.OCIClientInterface
 			Path:     parent.Path + "/" + *c.Name,
 			ParentID: parent.ID,
 			Children: make([]*CompartmentNode, 0),
+			depth:    parent.depth + 1,
+		}
+		if tree.filter.excludes(child) {
+			log.Debug().Msgf("Skipping compartment %s", child.Path)
+			continue
 		}
+		child.included = tree.filter.includes(child, parent.included)
 
 		parent.Children = append(parent.Children, child)
 
@@ -156,13 +229,26 @@ func (t *CompartmentTree) Size() int {
 	return len(t.flatList)
 }
 
-// ForEach iterates over all compartments and calls the callback function.
-// If the callback returns an error, iteration stops and the error is returned.
-func (t *CompartmentTree) ForEach(fn func(node *CompartmentNode) error) error {
+// Searched returns the compartments whose resources are searched: all of
+// them, or with include patterns, the matching ones and their descendants.
+func (t *CompartmentTree) Searched() []*CompartmentNode {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
+	result := make([]*CompartmentNode, 0, len(t.flatList))
 	for _, node := range t.flatList {
+		if node.included {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// ForEach iterates over the searched compartments and calls the callback
+// function. If the callback returns an error, iteration stops and the error
+// is returned.
+func (t *CompartmentTree) ForEach(fn func(node *CompartmentNode) error) error {
+	for _, node := range t.Searched() {
 		if err := fn(node); err != nil {
 			return err
 		}
@@ -170,13 +256,10 @@ func (t *CompartmentTree) ForEach(fn func(node *CompartmentNode) error) error {
 	return nil
 }
 
-// ForEachParallel iterates over all compartments in parallel.
+// ForEachParallel iterates over the searched compartments in parallel.
 // maxConcurrency limits concurrent executions (0 = unlimited).
 func (t *CompartmentTree) ForEachParallel(ctx context.Context, maxConcurrency int, fn func(ctx context.Context, node *CompartmentNode) error) error {
-	t.mu.RLock()
-	nodes := make([]*CompartmentNode, len(t.flatList))
-	copy(nodes, t.flatList)
-	t.mu.RUnlock()
+	nodes := t.Searched()
 
 	if maxConcurrency <= 0 {
 		maxConcurrency = 10 // Default concurrency
@@ -253,3 +336,67 @@ func GetCompartmentPath(compartments []identity.Compartment, targetID string) st
 	}
 	return "root/" + strings.Join(path, "/")
 }
+
+// maxCompartmentDepth bounds the walk up from a compartment to the tenancy.
+// OCI nests compartments at most six deep.
+const maxCompartmentDepth = 10
+
+// compartmentPath returns the path of a compartment, like "root/prod/oke",
+// walking up to tenancyID, or any tenancy, with lookup, which returns the
+// name of a compartment and its parent as CompartmentOCID. It returns "" if
+// a lookup fails.
+func compartmentPath(compartmentID, tenancyID string, lookup func(id string) (*CacheEntry, error)) string {
+	if compartmentID == "" {
+		return ""
+	}
+
+	var names []string
+	id := compartmentID
+	for range maxCompartmentDepth {
+		if id == "" || id == tenancyID || strings.HasPrefix(id, "ocid1.tenancy.") {
+			slices.Reverse(names)
+			return path.Join(append([]string{"root"}, names...)...)
+		}
+		entry, err := lookup(id)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Failed to look up compartment %s", id)
+			return ""
+		}
+		names = append(names, entry.Name)
+		id = entry.CompartmentOCID
+	}
+	return ""
+}
+
+// CompartmentPath returns the path of a compartment, like "root/prod/oke",
+// looking up the names of it and its ancestors that are not cached and
+// caching them. It returns "" if one cannot be looked up.
+func (d *Discoverer) CompartmentPath(ctx context.Context, compartmentID string) string {
+	tenancyID, _ := d.ociClient.GetTenancyOCID()
+	return compartmentPath(compartmentID, tenancyID, func(id string) (*CacheEntry, error) {
+		if d.cache != nil {
+			if entry := d.cache.GetCompartment(id); entry != nil {
+				return entry, nil
+			}
+		}
+
+		compartment, err := d.ociClient.GetCompartment(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if compartment.Name == nil {
+			return nil, fmt.Errorf("compartment %s has no name", id)
+		}
+		entry := &CacheEntry{OCID: id, Name: *compartment.Name}
+		if compartment.CompartmentId != nil {
+			entry.CompartmentOCID = *compartment.CompartmentId
+		}
+
+		if d.cache != nil {
+			if err := d.cache.SetCompartment(id, entry); err != nil {
+				log.Warn().Err(err).Msg("Failed to cache compartment name")
+			}
+		}
+		return entry, nil
+	})
+}
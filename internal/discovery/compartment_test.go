@@ -0,0 +1,231 @@
+package discovery
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+// newCompartmentTestClient returns a mock tenancy with compartments
+// root/prod/oke, root/prod/oke/team, root/sandbox/scratch and root/dev.
+func newCompartmentTestClient() *client.MockOCIClient {
+	mock := client.NewMockOCIClient()
+	compartment := func(id, name string) identity.Compartment {
+		return identity.Compartment{Id: &id, Name: &name}
+	}
+	mock.CompartmentsByID["tenancy"] = []identity.Compartment{
+		compartment("ocid1.compartment.oc1..prod", "prod"),
+		compartment("ocid1.compartment.oc1..sandbox", "sandbox"),
+		compartment("ocid1.compartment.oc1..dev", "dev"),
+	}
+	mock.CompartmentsByID["ocid1.compartment.oc1..prod"] = []identity.Compartment{
+		compartment("ocid1.compartment.oc1..oke", "oke"),
+	}
+	mock.CompartmentsByID["ocid1.compartment.oc1..oke"] = []identity.Compartment{
+		compartment("ocid1.compartment.oc1..team", "team"),
+	}
+	mock.CompartmentsByID["ocid1.compartment.oc1..sandbox"] = []identity.Compartment{
+		compartment("ocid1.compartment.oc1..scratch", "scratch"),
+	}
+	return mock
+}
+
+func searchedPaths(tree *CompartmentTree) []string {
+	var paths []string
+	_ = tree.ForEach(func(node *CompartmentNode) error {
+		paths = append(paths, node.Path)
+		return nil
+	})
+	sort.Strings(paths)
+	return paths
+}
+
+func TestBuildCompartmentTree_Filter(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    *CompartmentFilter
+		want      []string
+		wantSize  int
+		notListed string
+	}{
+		{
+			name:     "no filter",
+			filter:   nil,
+			want:     []string{"root", "root/dev", "root/prod", "root/prod/oke", "root/prod/oke/team", "root/sandbox", "root/sandbox/scratch"},
+			wantSize: 7,
+		},
+		{
+			name:      "exclude by name",
+			filter:    &CompartmentFilter{Exclude: []string{"Sandbox"}},
+			want:      []string{"root", "root/dev", "root/prod", "root/prod/oke", "root/prod/oke/team"},
+			wantSize:  5,
+			notListed: "ocid1.compartment.oc1..sandbox",
+		},
+		{
+			name:     "exclude by OCID",
+			filter:   &CompartmentFilter{Exclude: []string{"ocid1.compartment.oc1..oke"}},
+			want:     []string{"root", "root/dev", "root/prod", "root/sandbox", "root/sandbox/scratch"},
+			wantSize: 5,
+		},
+		{
+			name:     "include by path",
+			filter:   &CompartmentFilter{Include: []string{"root/prod"}},
+			want:     []string{"root/prod", "root/prod/oke", "root/prod/oke/team"},
+			wantSize: 7,
+		},
+		{
+			name:     "include with wildcard",
+			filter:   &CompartmentFilter{Include: []string{"root/*/oke"}},
+			want:     []string{"root/prod/oke", "root/prod/oke/team"},
+			wantSize: 7,
+		},
+		{
+			name:      "max depth",
+			filter:    &CompartmentFilter{MaxDepth: 1},
+			want:      []string{"root", "root/dev", "root/prod", "root/sandbox"},
+			wantSize:  4,
+			notListed: "ocid1.compartment.oc1..oke",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newCompartmentTestClient()
+			tree, err := BuildCompartmentTree(context.Background(), mock, "tenancy", tt.filter)
+			if err != nil {
+				t.Fatalf("BuildCompartmentTree() error = %v", err)
+			}
+
+			got := searchedPaths(tree)
+			if len(got) != len(tt.want) {
+				t.Fatalf("searched = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("searched = %v, want %v", got, tt.want)
+					break
+				}
+			}
+			if tree.Size() != tt.wantSize {
+				t.Errorf("Size() = %d, want %d", tree.Size(), tt.wantSize)
+			}
+
+			if tt.notListed != "" {
+				for _, call := range mock.GetCalls() {
+					if call.Method == "ListCompartments" && len(call.Args) > 0 && call.Args[0] == tt.notListed {
+						t.Errorf("ListCompartments called for skipped compartment %s", tt.notListed)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompartmentFilter_IsZero(t *testing.T) {
+	var nilFilter *CompartmentFilter
+	if !nilFilter.IsZero() {
+		t.Error("nil filter should be zero")
+	}
+	if !(&CompartmentFilter{}).IsZero() {
+		t.Error("empty filter should be zero")
+	}
+	if (&CompartmentFilter{MaxDepth: 2}).IsZero() {
+		t.Error("filter with max depth should not be zero")
+	}
+}
+
+func TestDiscoverer_CompartmentPath(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	compartment := func(id, name string) identity.Compartment {
+		return identity.Compartment{Id: &id, Name: &name}
+	}
+	mock.AddCompartmentByID(mock.TenancyOCID, compartment("ocid1.compartment.oc1..prod", "prod"))
+	mock.AddCompartmentByID(mock.TenancyOCID, compartment("ocid1.compartment.oc1..sandbox", "sandbox"))
+	mock.AddCompartmentByID("ocid1.compartment.oc1..prod", compartment("ocid1.compartment.oc1..oke", "oke"))
+	mock.AddCompartmentByID("ocid1.compartment.oc1..oke", compartment("ocid1.compartment.oc1..team", "team"))
+
+	dir := t.TempDir()
+	cache, err := NewCache(dir, DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	discoverer := NewDiscoverer(mock, cache)
+	ctx := context.Background()
+
+	if got := discoverer.CompartmentPath(ctx, "ocid1.compartment.oc1..team"); got != "root/prod/oke/team" {
+		t.Errorf("CompartmentPath() = %q, want root/prod/oke/team", got)
+	}
+	if got := discoverer.CompartmentPath(ctx, mock.TenancyOCID); got != "root" {
+		t.Errorf("CompartmentPath(tenancy) = %q, want root", got)
+	}
+	if got := discoverer.CompartmentPath(ctx, "ocid1.compartment.oc1..unknown"); got != "" {
+		t.Errorf("CompartmentPath(unknown) = %q, want empty", got)
+	}
+
+	// Names are looked up once
+	mock.ResetCalls()
+	if got := discoverer.CompartmentPath(ctx, "ocid1.compartment.oc1..oke"); got != "root/prod/oke" {
+		t.Errorf("CompartmentPath() = %q, want root/prod/oke", got)
+	}
+	for _, call := range mock.GetCalls() {
+		if call.Method == "GetCompartment" {
+			t.Errorf("GetCompartment(%v) called for a cached compartment", call.Args[0])
+		}
+	}
+
+	// and kept in the cache file
+	reloaded, err := NewCache(dir, DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	if got := reloaded.CompartmentPath("ocid1.compartment.oc1..team"); got != "root/prod/oke/team" {
+		t.Errorf("Cache.CompartmentPath() = %q, want root/prod/oke/team", got)
+	}
+	if got := reloaded.CompartmentPath("ocid1.compartment.oc1..sandbox"); got != "" {
+		t.Errorf("Cache.CompartmentPath(uncached) = %q, want empty", got)
+	}
+}
+
+func TestDiscoverClusterWithHints_SearchCompartmentPath(t *testing.T) {
+	mock := newCompartmentTestClient()
+	mock.TenancyOCID = "tenancy"
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name, compartment := "ocid1.cluster.oc1.iad.prod", "prod", "ocid1.compartment.oc1..oke"
+	mock.SearchResults["us-ashburn-1"] = []resourcesearch.ResourceSummary{
+		{Identifier: &id, DisplayName: &name, CompartmentId: &compartment},
+	}
+	mock.AddCluster(&containerengine.Cluster{Id: &id, Name: &name, CompartmentId: &compartment})
+
+	cache, err := NewCache(t.TempDir(), DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	discoverer := NewDiscoverer(mock, cache)
+	hints := &DiscoveryHints{Method: MethodSearch}
+
+	cluster, err := discoverer.DiscoverClusterWithHints(context.Background(), name, hints)
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if cluster.CompartmentPath != "root/prod/oke" {
+		t.Errorf("CompartmentPath = %q, want root/prod/oke", cluster.CompartmentPath)
+	}
+	if entry := cache.GetCluster(name); entry == nil || entry.CompartmentPath != "root/prod/oke" {
+		t.Errorf("cached entry = %+v, want the compartment path", entry)
+	}
+
+	// Restored from the cache
+	cached, err := discoverer.DiscoverClusterWithHints(context.Background(), name, hints)
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if cached.CompartmentPath != "root/prod/oke" {
+		t.Errorf("cached CompartmentPath = %q, want root/prod/oke", cached.CompartmentPath)
+	}
+}
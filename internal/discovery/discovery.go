@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
 	"github.com/rs/zerolog/log"
 	"github.com/scotttball/tunatap/internal/client"
 	"github.com/scotttball/tunatap/internal/config"
@@ -34,8 +37,11 @@ var (
 	ErrInvalidOCID = errors.New("invalid OCID format")
 )
 
-// DiscoveredCluster contains information about a discovered cluster.
+// DiscoveredCluster contains information about a discovered cluster, or
+// another private resource reached the same way.
 type DiscoveredCluster struct {
+	// Type is the kind of resource; empty means ResourceCluster.
+	Type            ResourceType
 	OCID            string
 	Name            string
 	CompartmentID   string
@@ -53,6 +59,10 @@ type DiscoveredBastion struct {
 	Name          string
 	Type          string
 	CompartmentID string
+
+	// Fallbacks are the OCIDs of other active standard bastions in the
+	// compartment, tried if this one cannot create a session.
+	Fallbacks []string
 }
 
 // DiscoveryHints provides optional hints to speed up discovery.
@@ -60,19 +70,44 @@ type DiscoveryHints struct {
 	Region          string
 	CompartmentPath string
 	TenancyOCID     string
+	// Method is how clusters are found in each region; empty means
+	// MethodCompartments.
+	Method Method
+	// Compartments limits the compartments walked by MethodCompartments.
+	Compartments *CompartmentFilter
+	// Type is the kind of resource to find; empty means ResourceCluster.
+	Type ResourceType
+	// PreferredRegions are searched first, in order, when Region is empty.
+	PreferredRegions []string
+	// CompartmentOCID limits discovery to the compartment, and those in
+	// it, with that OCID.
+	CompartmentOCID string
+
+	// compartment is the compartment of CompartmentOCID, once looked up.
+	compartment *CompartmentNode
+}
+
+// CacheKey returns the name the resource is cached under.
+func (c *DiscoveredCluster) CacheKey() string {
+	return cacheName(c.Type, c.Name)
 }
 
 // Discoverer handles cluster and bastion discovery.
 type Discoverer struct {
-	ociClient client.OCIClientInterface
-	cache     *Cache
+	ociClient       client.OCIClientInterface
+	cache           *Cache
+	kubeconfigPaths []string
+	clusterStates   []containerengine.ClusterLifecycleStateEnum
+	profile         *Profile
 }
 
-// NewDiscoverer creates a new discovery service.
+// NewDiscoverer creates a new discovery service that finds clusters in
+// DefaultClusterStates.
 func NewDiscoverer(ociClient client.OCIClientInterface, cache *Cache) *Discoverer {
 	return &Discoverer{
-		ociClient: ociClient,
-		cache:     cache,
+		ociClient:     ociClient,
+		cache:         cache,
+		clusterStates: DefaultClusterStates,
 	}
 }
 
@@ -85,13 +120,9 @@ func (d *Discoverer) DiscoverCluster(ctx context.Context, clusterName string) (*
 // This is more efficient than name-based discovery and should be used when the OCID is known.
 func (d *Discoverer) DiscoverClusterByOCID(ctx context.Context, clusterOCID string) (*DiscoveredCluster, error) {
 	// Validate OCID format
-	ocidParts := utils.ParseOCID(clusterOCID)
-	if ocidParts == nil {
-		return nil, fmt.Errorf("%w: '%s' is not a valid OCID", ErrInvalidOCID, clusterOCID)
-	}
-
-	if ocidParts.ResourceType != "cluster" {
-		return nil, fmt.Errorf("%w: expected cluster OCID but got '%s' OCID", ErrInvalidOCID, ocidParts.ResourceType)
+	ocidParts, err := parseOCIDOf(clusterOCID, "cluster")
+	if err != nil {
+		return nil, err
 	}
 
 	// Extract region from OCID
@@ -102,29 +133,10 @@ func (d *Discoverer) DiscoverClusterByOCID(ctx context.Context, clusterOCID stri
 
 	log.Info().Msgf("Looking up cluster by OCID in region %s...", region)
 
-	// Set region and fetch cluster directly
-	d.ociClient.SetRegion(region)
-
-	fullCluster, err := d.ociClient.GetCluster(ctx, clusterOCID)
+	// Fetch the cluster directly from its region
+	fullCluster, err := d.ociClient.RegionClient(region).GetCluster(ctx, clusterOCID)
 	if err != nil {
-		// Classify the error to provide better messaging
-		ociErr := client.ClassifyOCIError(err, "get cluster by OCID")
-
-		switch ociErr.Type {
-		case client.ErrorTypeNotAuthorizedOrNotFound:
-			return nil, fmt.Errorf("%w: cluster OCID '%s' not accessible\n\n%s",
-				ErrClusterAccessDenied, clusterOCID, ociErr.Suggestion)
-		case client.ErrorTypeNotAuthenticated:
-			return nil, fmt.Errorf("authentication failed when accessing cluster\n\n%s", ociErr.Suggestion)
-		case client.ErrorTypeNotAuthorized:
-			return nil, fmt.Errorf("%w: insufficient permissions to access cluster '%s'\n\n%s",
-				ErrClusterAccessDenied, clusterOCID, ociErr.Suggestion)
-		case client.ErrorTypeNotFound:
-			return nil, fmt.Errorf("%w: cluster '%s' does not exist in region %s\n\n%s",
-				ErrClusterNotFound, clusterOCID, region, ociErr.Suggestion)
-		default:
-			return nil, fmt.Errorf("failed to get cluster: %w", err)
-		}
+		return nil, lookupError(err, "cluster", clusterOCID, region, ErrClusterAccessDenied, ErrClusterNotFound)
 	}
 
 	// Build discovered cluster from response
@@ -139,6 +151,7 @@ func (d *Discoverer) DiscoverClusterByOCID(ctx context.Context, clusterOCID stri
 
 	if fullCluster.CompartmentId != nil {
 		cluster.CompartmentID = *fullCluster.CompartmentId
+		cluster.CompartmentPath = d.CompartmentPath(ctx, cluster.CompartmentID)
 	}
 
 	if fullCluster.VcnId != nil {
@@ -159,6 +172,7 @@ func (d *Discoverer) DiscoverClusterByOCID(ctx context.Context, clusterOCID stri
 			OCID:            cluster.OCID,
 			Region:          cluster.Region,
 			CompartmentOCID: cluster.CompartmentID,
+			CompartmentPath: cluster.CompartmentPath,
 			VcnID:           cluster.VcnID,
 			SubnetID:        cluster.SubnetID,
 			EndpointIP:      cluster.EndpointIP,
@@ -180,25 +194,46 @@ func IsClusterOCID(input string) bool {
 
 // DiscoverClusterWithHints finds a cluster using optional hints to speed up discovery.
 func (d *Discoverer) DiscoverClusterWithHints(ctx context.Context, clusterName string, hints *DiscoveryHints) (*DiscoveredCluster, error) {
-	// Check cache first
+	// Check cache first, preferring a choice made with the same hint
 	if d.cache != nil {
-		if cached := d.cache.GetCluster(clusterName); cached != nil {
+		key := ChoiceCacheKey(clusterName, hints)
+		cached := d.cache.GetCluster(key)
+		if cached == nil {
+			key = cacheName(hints.resourceType(), clusterName)
+			cached = d.cache.GetCluster(key)
+		}
+		if cached != nil {
 			log.Info().Msgf("Using cached cluster info for '%s' (expires in %s)",
-				clusterName, d.cache.GetClusterTTL(clusterName).Round(time.Minute))
+				clusterName, d.cache.GetClusterTTL(key).Round(time.Minute))
 			return &DiscoveredCluster{
-				OCID:          cached.OCID,
-				Name:          clusterName,
-				CompartmentID: cached.CompartmentOCID,
-				Region:        cached.Region,
-				VcnID:         cached.VcnID,
-				SubnetID:      cached.SubnetID,
-				EndpointIP:    cached.EndpointIP,
-				EndpointPort:  cached.EndpointPort,
+				Type:            hints.resourceType(),
+				OCID:            cached.OCID,
+				Name:            clusterName,
+				CompartmentID:   cached.CompartmentOCID,
+				CompartmentPath: cached.CompartmentPath,
+				Region:          cached.Region,
+				VcnID:           cached.VcnID,
+				SubnetID:        cached.SubnetID,
+				EndpointIP:      cached.EndpointIP,
+				EndpointPort:    cached.EndpointPort,
 			}, nil
 		}
+
+		// A name not found everywhere is not found in the hinted region either
+		notFound := d.cache.GetNotFound(ChoiceCacheKey(clusterName, hints))
+		if notFound == nil {
+			notFound = d.cache.GetNotFound(cacheName(hints.resourceType(), clusterName))
+		}
+		if notFound != nil {
+			return nil, &ClusterNotFoundError{Name: clusterName, CachedAt: notFound.CachedAt}
+		}
 	}
 
-	log.Info().Msgf("Discovering cluster '%s'...", clusterName)
+	if t := hints.resourceType(); t != ResourceCluster {
+		log.Info().Msgf("Discovering %s '%s'...", t, clusterName)
+	} else {
+		log.Info().Msgf("Discovering cluster '%s'...", clusterName)
+	}
 
 	// Get tenancy OCID
 	tenancyOCID, err := d.ociClient.GetTenancyOCID()
@@ -206,17 +241,19 @@ func (d *Discoverer) DiscoverClusterWithHints(ctx context.Context, clusterName s
 		return nil, fmt.Errorf("failed to get tenancy OCID: %w", err)
 	}
 
-	// Get regions to search
-	regions, err := d.getRegionsToSearch(ctx, tenancyOCID, hints)
+	// Get regions to search, those the cluster is likely in first
+	regions, err := d.getRegionsToSearch(ctx, tenancyOCID, d.seedHints(clusterName, hints))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get regions: %w", err)
 	}
 
 	log.Debug().Msgf("Searching %d regions: %v", len(regions), regions)
 
-	// Search each region
-	var allMatches []*DiscoveredCluster
+	// Search each region, keeping similar names to suggest if there is no
+	// exact match
+	var allMatches, similar []*DiscoveredCluster
 	var mu sync.Mutex
+	failedRegions := 0
 
 	for _, region := range regions {
 		select {
@@ -226,16 +263,28 @@ func (d *Discoverer) DiscoverClusterWithHints(ctx context.Context, clusterName s
 		}
 
 		log.Debug().Msgf("Searching region: %s", region)
-		d.ociClient.SetRegion(region)
 
-		matches, err := d.searchClusterInRegion(ctx, tenancyOCID, clusterName, region, hints)
+		search := d.searchClusterInRegion
+		if hints != nil && hints.Method == MethodSearch {
+			search = d.searchClusterWithResourceSearch
+		}
+		regionCtx, done := d.profile.track(ctx, region, "")
+		matches, err := search(regionCtx, tenancyOCID, clusterName, region, hints)
+		done()
 		if err != nil {
 			log.Warn().Err(err).Msgf("Error searching region %s", region)
+			failedRegions++
 			continue
 		}
 
 		mu.Lock()
-		allMatches = append(allMatches, matches...)
+		for _, m := range matches {
+			if strings.EqualFold(m.Name, clusterName) {
+				allMatches = append(allMatches, m)
+			} else {
+				similar = append(similar, m)
+			}
+		}
 		mu.Unlock()
 
 		// If we found exactly one and no hints specified, we can return early
@@ -245,49 +294,56 @@ func (d *Discoverer) DiscoverClusterWithHints(ctx context.Context, clusterName s
 	}
 
 	if len(allMatches) == 0 {
-		return nil, fmt.Errorf("%w: '%s' not found in any accessible compartment across %d regions",
-			ErrClusterNotFound, clusterName, len(regions))
+		// A region that could not be searched may have the cluster
+		if d.cache != nil && failedRegions == 0 {
+			if err := d.cache.SetNotFound(ChoiceCacheKey(clusterName, hints)); err != nil {
+				log.Warn().Err(err).Msg("Failed to cache cluster not found")
+			}
+		}
+		return nil, &ClusterNotFoundError{
+			Name:        clusterName,
+			Regions:     len(regions),
+			Suggestions: rankSuggestions(clusterName, similar),
+		}
 	}
 
 	if len(allMatches) > 1 {
-		// Return error with details about all matches
-		var details []string
-		for _, m := range allMatches {
-			details = append(details, fmt.Sprintf("  - %s (region: %s, compartment: %s)",
-				m.OCID, m.Region, m.CompartmentPath))
-		}
-		return nil, fmt.Errorf("%w: '%s' found in multiple locations:\n%s\n\nUse --region to specify which one to use",
-			ErrMultipleClustersFound, clusterName, strings.Join(details, "\n"))
+		return nil, &MultipleClustersError{Name: clusterName, Matches: allMatches}
 	}
 
-	cluster := allMatches[0]
-
-	// Get full cluster details
-	d.ociClient.SetRegion(cluster.Region)
-	fullCluster, err := d.ociClient.GetCluster(ctx, cluster.OCID)
+	cluster, err := d.CompleteCluster(ctx, allMatches[0], allMatches[0].CacheKey())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cluster details: %w", err)
+		return nil, err
 	}
 
-	// Extract endpoint info
-	if fullCluster.Endpoints != nil && fullCluster.Endpoints.PrivateEndpoint != nil {
-		cluster.EndpointIP, cluster.EndpointPort = parseEndpoint(*fullCluster.Endpoints.PrivateEndpoint)
-	}
+	log.Info().Msgf("Discovered cluster '%s' in region %s (compartment: %s)",
+		clusterName, cluster.Region, cluster.CompartmentPath)
 
-	// Extract VCN and subnet
-	if fullCluster.VcnId != nil {
-		cluster.VcnID = *fullCluster.VcnId
+	return cluster, nil
+}
+
+// CompleteCluster fills in the endpoint, VCN and subnet of a cluster found
+// by name, such as a suggestion from ClusterNotFoundError, and caches it
+// under cacheKey.
+func (d *Discoverer) CompleteCluster(ctx context.Context, cluster *DiscoveredCluster, cacheKey string) (*DiscoveredCluster, error) {
+	if err := d.kindOf(cluster.Type).complete(ctx, d.ociClient.RegionClient(cluster.Region), cluster); err != nil {
+		return nil, err
 	}
-	if fullCluster.EndpointConfig != nil && fullCluster.EndpointConfig.SubnetId != nil {
-		cluster.SubnetID = *fullCluster.EndpointConfig.SubnetId
+
+	// Resource Search gives only the compartment's OCID
+	if cluster.CompartmentPath == "" || cluster.CompartmentPath == cluster.CompartmentID {
+		if path := d.CompartmentPath(ctx, cluster.CompartmentID); path != "" {
+			cluster.CompartmentPath = path
+		}
 	}
 
 	// Cache the result
 	if d.cache != nil {
-		if err := d.cache.SetCluster(clusterName, &CacheEntry{
+		if err := d.cache.SetCluster(cacheKey, &CacheEntry{
 			OCID:            cluster.OCID,
 			Region:          cluster.Region,
 			CompartmentOCID: cluster.CompartmentID,
+			CompartmentPath: cluster.CompartmentPath,
 			VcnID:           cluster.VcnID,
 			SubnetID:        cluster.SubnetID,
 			EndpointIP:      cluster.EndpointIP,
@@ -297,9 +353,6 @@ func (d *Discoverer) DiscoverClusterWithHints(ctx context.Context, clusterName s
 		}
 	}
 
-	log.Info().Msgf("Discovered cluster '%s' in region %s (compartment: %s)",
-		clusterName, cluster.Region, cluster.CompartmentPath)
-
 	return cluster, nil
 }
 
@@ -335,34 +388,63 @@ func (d *Discoverer) getRegionsToSearch(ctx context.Context, tenancyOCID string,
 		regions = append([]string{homeRegion}, regions...)
 	}
 
+	if hints != nil {
+		regions = preferRegions(regions, hints.PreferredRegions)
+	}
 	return regions, nil
 }
 
-// searchClusterInRegion searches for a cluster in a specific region.
-func (d *Discoverer) searchClusterInRegion(ctx context.Context, tenancyOCID, clusterName, region string, _ *DiscoveryHints) ([]*DiscoveredCluster, error) {
-	// Build compartment tree
-	tree, err := BuildCompartmentTree(ctx, d.ociClient, tenancyOCID)
+// searchClusterInRegion searches for a cluster in a specific region,
+// returning the clusters with the name or a similar one.
+func (d *Discoverer) searchClusterInRegion(ctx context.Context, tenancyOCID, clusterName, region string, hints *DiscoveryHints) ([]*DiscoveredCluster, error) {
+	return d.listInRegion(ctx, tenancyOCID, region, hints, func(name string) bool {
+		return isCandidate(clusterName, name)
+	})
+}
+
+// listInRegion walks the compartments of region and returns the clusters,
+// or resources of hints.Type, whose names keep accepts.
+func (d *Discoverer) listInRegion(ctx context.Context, tenancyOCID, region string, hints *DiscoveryHints, keep func(name string) bool) ([]*DiscoveredCluster, error) {
+	var filter *CompartmentFilter
+	if hints != nil {
+		filter = hints.Compartments
+	}
+
+	// Build compartment tree, from the compartment discovery is limited to
+	// if there is one
+	ociClient := d.ociClient.RegionClient(region)
+	root := &CompartmentNode{ID: tenancyOCID, Name: "root", Path: "root"}
+	if hints != nil && hints.compartment != nil {
+		scoped := *hints.compartment
+		root = &scoped
+	}
+	tree, err := buildCompartmentTree(ctx, ociClient, root, filter)
 	if err != nil {
 		return nil, err
 	}
 
 	var matches []*DiscoveredCluster
 	var mu sync.Mutex
+	resourceType := hints.resourceType()
+	kind := d.kindOf(resourceType)
 
 	// Search each compartment
 	err = tree.ForEachParallel(ctx, 5, func(ctx context.Context, node *CompartmentNode) error {
-		clusters, err := d.ociClient.ListClustersInCompartment(ctx, node.ID)
+		ctx, done := d.profile.track(ctx, region, node.Path)
+		resources, err := kind.list(ctx, ociClient, node.ID)
+		done()
 		if err != nil {
 			// Log but don't fail - user may not have access to all compartments
-			log.Debug().Err(err).Msgf("Failed to list clusters in compartment %s", node.Path)
+			log.Debug().Err(err).Msgf("Failed to list %s resources in compartment %s", resourceType, node.Path)
 			return nil
 		}
 
-		for _, c := range clusters {
-			if c.Name != nil && strings.EqualFold(*c.Name, clusterName) {
+		for _, r := range resources {
+			if keep(r.name) {
 				match := &DiscoveredCluster{
-					OCID:            *c.Id,
-					Name:            *c.Name,
+					Type:            resourceType,
+					OCID:            r.id,
+					Name:            r.name,
 					CompartmentID:   node.ID,
 					CompartmentPath: node.Path,
 					Region:          region,
@@ -388,22 +470,21 @@ func (d *Discoverer) searchClusterInRegion(ctx context.Context, tenancyOCID, clu
 func (d *Discoverer) DiscoverBastion(ctx context.Context, cluster *DiscoveredCluster) (*DiscoveredBastion, error) {
 	// Check cache first
 	if d.cache != nil {
-		if cached := d.cache.GetBastion(cluster.Name); cached != nil {
+		if cached := d.cache.GetBastion(cluster.CacheKey()); cached != nil {
 			log.Info().Msgf("Using cached bastion info for cluster '%s'", cluster.Name)
 			return &DiscoveredBastion{
 				OCID:          cached.OCID,
 				CompartmentID: cached.CompartmentOCID,
+				Fallbacks:     cached.Fallbacks,
 			}, nil
 		}
 	}
 
 	log.Info().Msgf("Discovering bastion for cluster '%s'...", cluster.Name)
 
-	// Set region
-	d.ociClient.SetRegion(cluster.Region)
-
 	// List bastions in the cluster's compartment
-	bastions, err := d.ociClient.ListBastions(ctx, cluster.CompartmentID)
+	ociClient := d.ociClient.RegionClient(cluster.Region)
+	bastions, err := ociClient.ListBastions(ctx, cluster.CompartmentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list bastions: %w", err)
 	}
@@ -412,50 +493,139 @@ func (d *Discoverer) DiscoverBastion(ctx context.Context, cluster *DiscoveredClu
 		return nil, fmt.Errorf("%w: no bastions found in compartment %s", ErrNoBastionFound, cluster.CompartmentPath)
 	}
 
-	// Find the first active bastion
-	// TODO: Could be smarter about matching bastion to cluster's subnet
-	for _, b := range bastions {
-		if b.LifecycleState == "ACTIVE" && b.Id != nil {
-			// Get full bastion details
-			fullBastion, err := d.ociClient.GetBastion(ctx, *b.Id)
-			if err != nil {
-				continue
-			}
+	// Prefer bastions that reach the cluster's subnet, then its VCN
+	candidates := rankBastions(bastions, cluster)
+	for i, b := range candidates {
+		// Get full bastion details
+		fullBastion, err := ociClient.GetBastion(ctx, *b.Id)
+		if err != nil {
+			continue
+		}
 
-			bastion := &DiscoveredBastion{
-				OCID:          *b.Id,
-				CompartmentID: cluster.CompartmentID,
-			}
+		if fullBastion.ClientCidrBlockAllowList != nil && len(fullBastion.ClientCidrBlockAllowList) == 0 {
+			log.Debug().Msgf("Skipping bastion %s: its client CIDR allowlist is empty", *b.Id)
+			continue
+		}
 
-			if b.Name != nil {
-				bastion.Name = *b.Name
-			}
+		bastion := &DiscoveredBastion{
+			OCID:          *b.Id,
+			CompartmentID: cluster.CompartmentID,
+		}
 
-			if fullBastion.BastionType != nil {
-				bastion.Type = *fullBastion.BastionType
-			} else {
-				bastion.Type = "STANDARD"
-			}
+		if b.Name != nil {
+			bastion.Name = *b.Name
+		}
 
-			// Cache the result
-			if d.cache != nil {
-				if err := d.cache.SetBastion(cluster.Name, &CacheEntry{
-					OCID:            bastion.OCID,
-					CompartmentOCID: bastion.CompartmentID,
-					Region:          cluster.Region,
-				}); err != nil {
-					log.Warn().Err(err).Msg("Failed to cache bastion info")
-				}
-			}
+		if fullBastion.BastionType != nil {
+			bastion.Type = *fullBastion.BastionType
+		} else {
+			bastion.Type = "STANDARD"
+		}
 
-			log.Info().Msgf("Discovered bastion '%s' (%s)", bastion.Name, bastion.Type)
-			return bastion, nil
+		reach := bastionReach(b, cluster)
+		if reach == reachOtherVcn {
+			log.Warn().Msgf("Bastion '%s' targets a different VCN than cluster '%s' and may not reach its endpoint", bastion.Name, cluster.Name)
 		}
+
+		if bastion.Type == "STANDARD" {
+			// Only fall back to bastions that reach the cluster as well
+			bastion.Fallbacks = fallbackBastions(ctx, ociClient, reachableBastions(candidates[i+1:], cluster, reach))
+		}
+
+		// Cache the result
+		if d.cache != nil {
+			if err := d.cache.SetBastion(cluster.CacheKey(), &CacheEntry{
+				OCID:            bastion.OCID,
+				CompartmentOCID: bastion.CompartmentID,
+				Region:          cluster.Region,
+				Fallbacks:       bastion.Fallbacks,
+			}); err != nil {
+				log.Warn().Err(err).Msg("Failed to cache bastion info")
+			}
+		}
+
+		log.Info().Msgf("Discovered bastion '%s' (%s)", bastion.Name, bastion.Type)
+		return bastion, nil
 	}
 
 	return nil, fmt.Errorf("%w: no active bastions found", ErrNoBastionFound)
 }
 
+// bastionReachability ranks how likely a bastion is to reach a cluster's
+// private endpoint. Lower is better.
+type bastionReachability int
+
+const (
+	// reachSubnet means the bastion targets the cluster's endpoint subnet.
+	reachSubnet bastionReachability = iota
+	// reachVcn means the bastion targets another subnet in the cluster's VCN.
+	reachVcn
+	// reachUnknown means the cluster's or bastion's VCN is not known.
+	reachUnknown
+	// reachOtherVcn means the bastion targets a different VCN, which only
+	// works with peering or a DRG.
+	reachOtherVcn
+)
+
+// bastionReach returns how likely bastion b is to reach cluster.
+func bastionReach(b bastion.BastionSummary, cluster *DiscoveredCluster) bastionReachability {
+	if cluster.SubnetID != "" && b.TargetSubnetId != nil && *b.TargetSubnetId == cluster.SubnetID {
+		return reachSubnet
+	}
+	if cluster.VcnID == "" || b.TargetVcnId == nil || *b.TargetVcnId == "" {
+		return reachUnknown
+	}
+	if *b.TargetVcnId == cluster.VcnID {
+		return reachVcn
+	}
+	return reachOtherVcn
+}
+
+// rankBastions returns the active bastions, best reachability to cluster
+// first. Bastions of equal reachability keep their listed order.
+func rankBastions(bastions []bastion.BastionSummary, cluster *DiscoveredCluster) []bastion.BastionSummary {
+	var active []bastion.BastionSummary
+	for _, b := range bastions {
+		if b.LifecycleState == "ACTIVE" && b.Id != nil {
+			active = append(active, b)
+		}
+	}
+	slices.SortStableFunc(active, func(a, b bastion.BastionSummary) int {
+		return int(bastionReach(a, cluster)) - int(bastionReach(b, cluster))
+	})
+	return active
+}
+
+// reachableBastions returns the bastions worth failing over to from a
+// bastion with reachability reach: bastions in other VCNs are dropped unless
+// the primary is in another VCN as well.
+func reachableBastions(bastions []bastion.BastionSummary, cluster *DiscoveredCluster, reach bastionReachability) []bastion.BastionSummary {
+	var reachable []bastion.BastionSummary
+	for _, b := range bastions {
+		if reach == reachOtherVcn || bastionReach(b, cluster) != reachOtherVcn {
+			reachable = append(reachable, b)
+		}
+	}
+	return reachable
+}
+
+// fallbackBastions returns the OCIDs of the active standard bastions among
+// bastions, looked up with ociClient.
+func fallbackBastions(ctx context.Context, ociClient client.OCIClientInterface, bastions []bastion.BastionSummary) []string {
+	var ids []string
+	for _, b := range bastions {
+		if b.LifecycleState != "ACTIVE" || b.Id == nil {
+			continue
+		}
+		full, err := ociClient.GetBastion(ctx, *b.Id)
+		if err != nil || (full.BastionType != nil && *full.BastionType != "STANDARD") {
+			continue
+		}
+		ids = append(ids, *b.Id)
+	}
+	return ids
+}
+
 // ResolveToConfig converts discovered resources to config.Cluster format.
 func (d *Discoverer) ResolveToConfig(discovered *DiscoveredCluster, bastion *DiscoveredBastion) (*config.Cluster, error) {
 	cluster := &config.Cluster{
@@ -468,6 +638,7 @@ func (d *Discoverer) ResolveToConfig(discovered *DiscoveredCluster, bastion *Dis
 	if bastion != nil {
 		cluster.BastionId = &bastion.OCID
 		cluster.BastionType = &bastion.Type
+		cluster.FallbackBastionIds = bastion.Fallbacks
 	}
 
 	if discovered.EndpointIP != "" {
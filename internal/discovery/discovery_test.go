@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/oracle/oci-go-sdk/v65/bastion"
 	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
 	"github.com/scotttball/tunatap/internal/client"
 )
 
@@ -64,17 +67,17 @@ func TestDiscoverClusterByOCID_Success(t *testing.T) {
 		t.Errorf("Expected endpoint port 6443, got %d", cluster.EndpointPort)
 	}
 
-	// Verify the region was set on the client
+	// Verify the client for the cluster's region was used
 	calls := mock.GetCalls()
-	var setRegionCalled bool
+	var regionClientCalled bool
 	for _, call := range calls {
-		if call.Method == "SetRegion" && len(call.Args) > 0 && call.Args[0] == "us-ashburn-1" {
-			setRegionCalled = true
+		if call.Method == "RegionClient" && len(call.Args) > 0 && call.Args[0] == "us-ashburn-1" {
+			regionClientCalled = true
 			break
 		}
 	}
-	if !setRegionCalled {
-		t.Error("Expected SetRegion to be called with us-ashburn-1")
+	if !regionClientCalled {
+		t.Error("Expected RegionClient to be called with us-ashburn-1")
 	}
 }
 
@@ -303,6 +306,79 @@ func TestDiscoverClusterWithHints_ClusterNotFound(t *testing.T) {
 	}
 }
 
+func TestDiscoverClusterWithHints_CachedNotFound(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	cache, err := NewCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	discoverer := NewDiscoverer(mock, cache)
+
+	if _, err := discoverer.DiscoverClusterWithHints(context.Background(), "nonexistent-cluster", nil); !errors.Is(err, ErrClusterNotFound) {
+		t.Fatalf("Expected ErrClusterNotFound, got: %v", err)
+	}
+
+	mock.ResetCalls()
+	_, err = discoverer.DiscoverClusterWithHints(context.Background(), "nonexistent-cluster", &DiscoveryHints{Region: "us-ashburn-1"})
+	var notFound *ClusterNotFoundError
+	if !errors.As(err, &notFound) || notFound.CachedAt.IsZero() {
+		t.Fatalf("Expected a cached ClusterNotFoundError, got: %v", err)
+	}
+	if len(mock.GetCalls()) != 0 {
+		t.Errorf("DiscoverClusterWithHints() made %d OCI calls, want 0", len(mock.GetCalls()))
+	}
+}
+
+func TestDiscoverClusterWithHints_ResourceSearch(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	clusterOCID := "ocid1.cluster.oc1.iad.prod"
+	compartmentID := "ocid1.compartment.oc1..prod"
+	privateEndpoint := "10.0.1.100:6443"
+	mock.AddCluster(&containerengine.Cluster{
+		Id:        &clusterOCID,
+		Endpoints: &containerengine.ClusterEndpoints{PrivateEndpoint: &privateEndpoint},
+	})
+
+	resource := func(id, name, state string) resourcesearch.ResourceSummary {
+		return resourcesearch.ResourceSummary{Identifier: &id, DisplayName: &name, CompartmentId: &compartmentID, LifecycleState: &state}
+	}
+	mock.SearchResults["us-ashburn-1"] = []resourcesearch.ResourceSummary{
+		resource(clusterOCID, "Prod", "ACTIVE"),
+		resource("ocid1.cluster.oc1.iad.prodold", "prod-old", "ACTIVE"),
+		resource("ocid1.cluster.oc1.iad.deleted", "prod", "DELETED"),
+	}
+
+	discoverer := NewDiscoverer(mock, nil)
+	cluster, err := discoverer.DiscoverClusterWithHints(context.Background(), "prod", &DiscoveryHints{Method: MethodSearch})
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if cluster.OCID != clusterOCID || cluster.CompartmentID != compartmentID || cluster.EndpointIP != "10.0.1.100" {
+		t.Errorf("DiscoverClusterWithHints() = %+v, want %s in %s", cluster, clusterOCID, compartmentID)
+	}
+
+	for _, call := range mock.Calls {
+		if call.Method == "ListCompartments" || call.Method == "ListClustersInCompartment" {
+			t.Errorf("search discovery made a %s call", call.Method)
+		}
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	for _, s := range []string{"", "compartments", "search"} {
+		if _, err := ParseMethod(s); err != nil {
+			t.Errorf("ParseMethod(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseMethod("graph"); err == nil {
+		t.Error("ParseMethod(\"graph\") error = nil, want an error")
+	}
+}
+
 // mockServiceError implements common.ServiceError for testing.
 type mockServiceError struct {
 	statusCode int
@@ -324,3 +400,69 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestDiscoverBastion_Fallbacks(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	standard := "STANDARD"
+	for _, id := range []string{"ocid1.bastion.oc1..a", "ocid1.bastion.oc1..b", "ocid1.bastion.oc1..c"} {
+		id := id
+		mock.AddBastion(&bastion.Bastion{Id: &id, Name: &id, BastionType: &standard})
+	}
+
+	discoverer := NewDiscoverer(mock, nil)
+	found, err := discoverer.DiscoverBastion(context.Background(), &DiscoveredCluster{
+		Name:          "test-cluster",
+		Region:        "us-ashburn-1",
+		CompartmentID: "ocid1.compartment.oc1..test",
+	})
+	if err != nil {
+		t.Fatalf("DiscoverBastion failed: %v", err)
+	}
+
+	if len(found.Fallbacks) != 2 {
+		t.Fatalf("Expected 2 fallback bastions, got %v", found.Fallbacks)
+	}
+	for _, id := range found.Fallbacks {
+		if id == found.OCID {
+			t.Errorf("Fallbacks %v should not include the primary bastion %s", found.Fallbacks, found.OCID)
+		}
+	}
+
+	cluster, err := discoverer.ResolveToConfig(&DiscoveredCluster{Name: "test-cluster"}, found)
+	if err != nil {
+		t.Fatalf("ResolveToConfig failed: %v", err)
+	}
+	if len(cluster.FallbackBastionIds) != 2 {
+		t.Errorf("Expected FallbackBastionIds to carry the fallbacks, got %v", cluster.FallbackBastionIds)
+	}
+}
+
+func TestDiscoverBastion_PrefersClusterVcn(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	standard := "STANDARD"
+	add := func(id, vcn, subnet string) {
+		mock.AddBastion(&bastion.Bastion{Id: &id, Name: &id, BastionType: &standard, TargetVcnId: &vcn, TargetSubnetId: &subnet})
+	}
+	add("ocid1.bastion.oc1..other", "ocid1.vcn.oc1..other", "ocid1.subnet.oc1..other")
+	add("ocid1.bastion.oc1..vcn", "ocid1.vcn.oc1..cluster", "ocid1.subnet.oc1..workers")
+	add("ocid1.bastion.oc1..subnet", "ocid1.vcn.oc1..cluster", "ocid1.subnet.oc1..api")
+
+	discoverer := NewDiscoverer(mock, nil)
+	found, err := discoverer.DiscoverBastion(context.Background(), &DiscoveredCluster{
+		Name:          "test-cluster",
+		Region:        "us-ashburn-1",
+		CompartmentID: "ocid1.compartment.oc1..test",
+		VcnID:         "ocid1.vcn.oc1..cluster",
+		SubnetID:      "ocid1.subnet.oc1..api",
+	})
+	if err != nil {
+		t.Fatalf("DiscoverBastion failed: %v", err)
+	}
+
+	if found.OCID != "ocid1.bastion.oc1..subnet" {
+		t.Errorf("DiscoverBastion() = %s, want the bastion in the endpoint subnet", found.OCID)
+	}
+	if len(found.Fallbacks) != 1 || found.Fallbacks[0] != "ocid1.bastion.oc1..vcn" {
+		t.Errorf("Fallbacks = %v, want only the other bastion in the cluster's VCN", found.Fallbacks)
+	}
+}
@@ -0,0 +1,176 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSuggestions bounds the close matches offered when a name is not found.
+const maxSuggestions = 10
+
+// ClusterNotFoundError is returned by DiscoverClusterWithHints when no
+// cluster has the name. It wraps ErrClusterNotFound and carries the clusters
+// with similar names, best first.
+type ClusterNotFoundError struct {
+	Name    string
+	Regions int
+	// Suggestions are clusters whose names start with, contain or are a few
+	// edits away from Name. Their details are not filled in; pass the one
+	// chosen to CompleteCluster.
+	Suggestions []*DiscoveredCluster
+	// CachedAt is when the name was not found, if this error comes from the
+	// discovery cache rather than a search.
+	CachedAt time.Time
+	// Tagged is set when Name is a tag selection rather than a name.
+	Tagged bool
+}
+
+func (e *ClusterNotFoundError) Error() string {
+	if e.Tagged {
+		return fmt.Sprintf("%s: none tagged %s in any accessible compartment across %d regions", ErrClusterNotFound, e.Name, e.Regions)
+	}
+	if !e.CachedAt.IsZero() {
+		return fmt.Sprintf("%s: '%s' was not found by discovery at %s (cached; run 'tunatap cache invalidate %s' or use --no-cache to search again)",
+			ErrClusterNotFound, e.Name, e.CachedAt.Format(time.RFC3339), e.Name)
+	}
+	msg := fmt.Sprintf("%s: '%s' not found in any accessible compartment across %d regions", ErrClusterNotFound, e.Name, e.Regions)
+	if len(e.Suggestions) > 0 {
+		msg += "\n\nSimilar clusters:\n" + FormatSuggestions(e.Suggestions)
+	}
+	return msg
+}
+
+func (e *ClusterNotFoundError) Unwrap() error {
+	return ErrClusterNotFound
+}
+
+// MultipleClustersError is returned by DiscoverClusterWithHints when more
+// than one cluster has the name. It wraps ErrMultipleClustersFound.
+type MultipleClustersError struct {
+	Name string
+	// Matches are the clusters with the name. Their details are not filled
+	// in; pass the one chosen to CompleteCluster.
+	Matches []*DiscoveredCluster
+	// Tagged is set when Name is a tag selection rather than a name. The
+	// choice among the matches is then not cached for it.
+	Tagged bool
+}
+
+func (e *MultipleClustersError) Error() string {
+	details := make([]string, len(e.Matches))
+	for i, m := range e.Matches {
+		details[i] = fmt.Sprintf("  - %s (region: %s, compartment: %s)", m.OCID, m.Region, m.CompartmentPath)
+	}
+	if e.Tagged {
+		return fmt.Sprintf("%s: %d resources are tagged %s:\n%s\n\nAdd --tag or --region to narrow the selection",
+			ErrMultipleClustersFound, len(e.Matches), e.Name, strings.Join(details, "\n"))
+	}
+	return fmt.Sprintf("%s: '%s' found in multiple locations:\n%s\n\nUse --region to specify which one to use",
+		ErrMultipleClustersFound, e.Name, strings.Join(details, "\n"))
+}
+
+func (e *MultipleClustersError) Unwrap() error {
+	return ErrMultipleClustersFound
+}
+
+// ChoiceCacheKey returns the cache key under which the cluster chosen for
+// name among several matches is kept, so that the same name and hint find
+// it again without asking.
+func ChoiceCacheKey(name string, hints *DiscoveryHints) string {
+	key := cacheName(hints.resourceType(), name)
+	if hints == nil || hints.Region == "" {
+		return key
+	}
+	return key + "@" + hints.Region
+}
+
+// FormatSuggestions lists clusters one per line with their locations.
+func FormatSuggestions(clusters []*DiscoveredCluster) string {
+	lines := make([]string, len(clusters))
+	for i, choice := range GetMultipleClusterChoices(clusters) {
+		lines[i] = "  - " + choice
+	}
+	return strings.Join(lines, "\n")
+}
+
+// matchScore rates how closely name matches what the user typed, lower
+// being closer: 0 for a prefix, 1 for a substring, and 2 plus the edit
+// distance for a near miss. ok is false when name is not close at all.
+// Matching ignores case.
+func matchScore(typed, name string) (score int, ok bool) {
+	typed, name = strings.ToLower(typed), strings.ToLower(name)
+	switch {
+	case typed == "":
+		return 0, false
+	case strings.HasPrefix(name, typed):
+		return 0, true
+	case strings.Contains(name, typed):
+		return 1, true
+	}
+
+	// Allow roughly one typo per four characters
+	maxDistance := max(1, len(typed)/4)
+	if d := levenshtein(typed, name); d <= maxDistance {
+		return 2 + d, true
+	}
+	return 0, false
+}
+
+// isCandidate reports whether a cluster named name should be kept while
+// searching for typed: an exact match or a close one.
+func isCandidate(typed, name string) bool {
+	if strings.EqualFold(typed, name) {
+		return true
+	}
+	_, ok := matchScore(typed, name)
+	return ok
+}
+
+// rankSuggestions orders close matches for typed best first, keeping at
+// most maxSuggestions.
+func rankSuggestions(typed string, clusters []*DiscoveredCluster) []*DiscoveredCluster {
+	ranked := make([]*DiscoveredCluster, 0, len(clusters))
+	scores := make(map[*DiscoveredCluster]int, len(clusters))
+	for _, c := range clusters {
+		if score, ok := matchScore(typed, c.Name); ok {
+			scores[c] = score
+			ranked = append(ranked, c)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if scores[ranked[i]] != scores[ranked[j]] {
+			return scores[ranked[i]] < scores[ranked[j]]
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if len(ranked) > maxSuggestions {
+		ranked = ranked[:maxSuggestions]
+	}
+	return ranked
+}
+
+// levenshtein returns the number of single-byte insertions, deletions and
+// substitutions that turn a into b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,132 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		typed, name string
+		want        int
+		wantOK      bool
+	}{
+		{"prod-oke", "prod-oke-iad-01", 0, true},
+		{"PROD-OKE", "prod-oke-iad-01", 0, true},
+		{"oke-iad", "prod-oke-iad-01", 1, true},
+		{"prod-oek", "prod-oke", 4, true},
+		{"prod-okee", "prod-oke", 3, true},
+		{"prod-oke", "staging-aks", 0, false},
+		{"", "prod-oke", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := matchScore(tt.typed, tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("matchScore(%q, %q) = %d, %v, want %d, %v", tt.typed, tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"prod", "prod", 0},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverClusterWithHints_Suggestions(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	for _, name := range []string{"prod-oke-iad-01", "my-prod-oke", "prod-oek", "staging"} {
+		id, name := "ocid1.cluster.oc1.iad."+name, name
+		mock.AddClusterToCompartment(mock.TenancyOCID, containerengine.ClusterSummary{Id: &id, Name: &name})
+	}
+
+	discoverer := NewDiscoverer(mock, nil)
+	_, err := discoverer.DiscoverClusterWithHints(context.Background(), "prod-oke", nil)
+
+	var notFound *ClusterNotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, ErrClusterNotFound) {
+		t.Fatalf("DiscoverClusterWithHints() error = %v, want a ClusterNotFoundError", err)
+	}
+
+	var got []string
+	for _, c := range notFound.Suggestions {
+		got = append(got, c.Name)
+	}
+	want := []string{"prod-oke-iad-01", "my-prod-oke", "prod-oek"}
+	if len(got) != len(want) {
+		t.Fatalf("Suggestions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Suggestions = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestDiscoverClusterWithHints_MultipleMatches(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	for _, id := range []string{"ocid1.cluster.oc1.iad.a", "ocid1.cluster.oc1.iad.b"} {
+		id, name := id, "prod"
+		mock.AddClusterToCompartment(mock.TenancyOCID, containerengine.ClusterSummary{Id: &id, Name: &name})
+	}
+
+	discoverer := NewDiscoverer(mock, nil)
+	_, err := discoverer.DiscoverClusterWithHints(context.Background(), "prod", nil)
+
+	var multiple *MultipleClustersError
+	if !errors.As(err, &multiple) || !errors.Is(err, ErrMultipleClustersFound) {
+		t.Fatalf("DiscoverClusterWithHints() error = %v, want a MultipleClustersError", err)
+	}
+	if len(multiple.Matches) != 2 {
+		t.Errorf("Matches = %d, want 2", len(multiple.Matches))
+	}
+}
+
+func TestDiscoverClusterWithHints_CachedChoice(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	hints := &DiscoveryHints{Region: "us-phoenix-1"}
+	if err := cache.SetCluster(ChoiceCacheKey("prod", hints), &CacheEntry{OCID: "ocid1.cluster.oc1.phx.b", Region: "us-phoenix-1"}); err != nil {
+		t.Fatalf("SetCluster() error = %v", err)
+	}
+
+	mock := client.NewMockOCIClient()
+	discoverer := NewDiscoverer(mock, cache)
+	cluster, err := discoverer.DiscoverClusterWithHints(context.Background(), "prod", hints)
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if cluster.OCID != "ocid1.cluster.oc1.phx.b" || cluster.Name != "prod" {
+		t.Errorf("DiscoverClusterWithHints() = %s (%s), want the cached choice", cluster.OCID, cluster.Name)
+	}
+	if len(mock.Calls) != 0 {
+		t.Errorf("DiscoverClusterWithHints() made %d OCI calls, want 0", len(mock.Calls))
+	}
+
+	if got := ChoiceCacheKey("prod", nil); got != "prod" {
+		t.Errorf("ChoiceCacheKey() = %q, want %q", got, "prod")
+	}
+}
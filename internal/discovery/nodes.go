@@ -0,0 +1,92 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/rs/zerolog/log"
+)
+
+// DiscoveredNode is a worker node of a cluster's node pool.
+type DiscoveredNode struct {
+	// InstanceID is the OCID of the node's compute instance.
+	InstanceID string
+	Name       string
+	PrivateIP  string
+	NodePool   string
+	State      string
+}
+
+// Matches reports whether ref names the node, by instance OCID, node name
+// or private IP. Kubernetes names OKE nodes after their private IP.
+func (n *DiscoveredNode) Matches(ref string) bool {
+	return ref == n.InstanceID || ref == n.PrivateIP || strings.EqualFold(ref, n.Name)
+}
+
+// DiscoverNodes lists the worker nodes of the cluster's node pools in
+// compartmentID, sorted by node pool and name. Nodes being deleted, or not
+// yet given an address, are left out.
+func (d *Discoverer) DiscoverNodes(ctx context.Context, compartmentID, clusterID string) ([]*DiscoveredNode, error) {
+	summaries, err := d.ociClient.ListNodePools(ctx, compartmentID, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []*DiscoveredNode
+	for _, summary := range summaries {
+		if summary.Id == nil {
+			continue
+		}
+		nodePool, err := d.ociClient.GetNodePool(ctx, *summary.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		poolName := ""
+		if nodePool.Name != nil {
+			poolName = *nodePool.Name
+		}
+		for _, n := range nodePool.Nodes {
+			if n.Id == nil || n.PrivateIp == nil || *n.PrivateIp == "" {
+				continue
+			}
+			if n.LifecycleState == containerengine.NodeLifecycleStateDeleting || n.LifecycleState == containerengine.NodeLifecycleStateDeleted {
+				continue
+			}
+
+			node := &DiscoveredNode{
+				InstanceID: *n.Id,
+				PrivateIP:  *n.PrivateIp,
+				NodePool:   poolName,
+				State:      string(n.LifecycleState),
+			}
+			if n.Name != nil {
+				node.Name = *n.Name
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].NodePool != nodes[j].NodePool {
+			return nodes[i].NodePool < nodes[j].NodePool
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	log.Debug().Msgf("Found %d nodes in %d node pools of cluster %s", len(nodes), len(summaries), clusterID)
+	return nodes, nil
+}
+
+// FindNode returns the node ref names, by instance OCID, node name or
+// private IP.
+func FindNode(nodes []*DiscoveredNode, ref string) (*DiscoveredNode, error) {
+	for _, n := range nodes {
+		if n.Matches(ref) {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("no node '%s' in the cluster's node pools", ref)
+}
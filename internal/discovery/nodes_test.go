@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+func newTestNode(id, name, ip string, state containerengine.NodeLifecycleStateEnum) containerengine.Node {
+	return containerengine.Node{Id: &id, Name: &name, PrivateIp: &ip, LifecycleState: state}
+}
+
+func TestDiscoverNodes(t *testing.T) {
+	mock := client.NewMockOCIClient()
+
+	clusterID := "ocid1.cluster.oc1.iad.test"
+	otherClusterID := "ocid1.cluster.oc1.iad.other"
+	poolA, poolAName := "ocid1.nodepool.oc1.iad.a", "pool-a"
+	poolB, poolBName := "ocid1.nodepool.oc1.iad.b", "pool-b"
+	poolC, poolCName := "ocid1.nodepool.oc1.iad.c", "pool-c"
+
+	mock.NodePools[poolB] = &containerengine.NodePool{
+		Id: &poolB, Name: &poolBName, ClusterId: &clusterID,
+		Nodes: []containerengine.Node{
+			newTestNode("ocid1.instance.oc1.iad.b2", "10.0.10.9", "10.0.10.9", containerengine.NodeLifecycleStateActive),
+			newTestNode("ocid1.instance.oc1.iad.b1", "10.0.10.7", "10.0.10.7", containerengine.NodeLifecycleStateUpdating),
+			newTestNode("ocid1.instance.oc1.iad.b3", "10.0.10.8", "10.0.10.8", containerengine.NodeLifecycleStateDeleting),
+		},
+	}
+	mock.NodePools[poolA] = &containerengine.NodePool{
+		Id: &poolA, Name: &poolAName, ClusterId: &clusterID,
+		Nodes: []containerengine.Node{
+			newTestNode("ocid1.instance.oc1.iad.a1", "10.0.10.5", "10.0.10.5", containerengine.NodeLifecycleStateActive),
+			newTestNode("ocid1.instance.oc1.iad.a2", "10.0.10.6", "", containerengine.NodeLifecycleStateCreating),
+		},
+	}
+	mock.NodePools[poolC] = &containerengine.NodePool{
+		Id: &poolC, Name: &poolCName, ClusterId: &otherClusterID,
+		Nodes: []containerengine.Node{
+			newTestNode("ocid1.instance.oc1.iad.c1", "10.0.20.5", "10.0.20.5", containerengine.NodeLifecycleStateActive),
+		},
+	}
+
+	nodes, err := NewDiscoverer(mock, nil).DiscoverNodes(context.Background(), "ocid1.compartment.oc1..test", clusterID)
+	if err != nil {
+		t.Fatalf("DiscoverNodes() error = %v", err)
+	}
+
+	want := []string{"ocid1.instance.oc1.iad.a1", "ocid1.instance.oc1.iad.b1", "ocid1.instance.oc1.iad.b2"}
+	if len(nodes) != len(want) {
+		t.Fatalf("DiscoverNodes() returned %d nodes, want %d", len(nodes), len(want))
+	}
+	for i, id := range want {
+		if nodes[i].InstanceID != id {
+			t.Errorf("nodes[%d].InstanceID = %s, want %s", i, nodes[i].InstanceID, id)
+		}
+	}
+	if nodes[0].NodePool != poolAName {
+		t.Errorf("nodes[0].NodePool = %s, want %s", nodes[0].NodePool, poolAName)
+	}
+}
+
+func TestDiscoverNodes_Error(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.ShouldFailCluster = true
+
+	if _, err := NewDiscoverer(mock, nil).DiscoverNodes(context.Background(), "ocid1.compartment.oc1..test", "ocid1.cluster.oc1.iad.test"); err == nil {
+		t.Error("DiscoverNodes() expected error")
+	}
+}
+
+func TestFindNode(t *testing.T) {
+	nodes := []*DiscoveredNode{
+		{InstanceID: "ocid1.instance.oc1.iad.a1", Name: "10.0.10.5", PrivateIP: "10.0.10.5"},
+		{InstanceID: "ocid1.instance.oc1.iad.b1", Name: "worker-b1", PrivateIP: "10.0.10.7"},
+	}
+
+	tests := []struct {
+		ref    string
+		wantID string
+	}{
+		{"10.0.10.5", "ocid1.instance.oc1.iad.a1"},
+		{"ocid1.instance.oc1.iad.b1", "ocid1.instance.oc1.iad.b1"},
+		{"WORKER-B1", "ocid1.instance.oc1.iad.b1"},
+		{"10.0.10.7", "ocid1.instance.oc1.iad.b1"},
+		{"10.0.10.99", ""},
+	}
+
+	for _, tt := range tests {
+		node, err := FindNode(nodes, tt.ref)
+		if tt.wantID == "" {
+			if err == nil {
+				t.Errorf("FindNode(%q) expected error", tt.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FindNode(%q) error = %v", tt.ref, err)
+			continue
+		}
+		if node.InstanceID != tt.wantID {
+			t.Errorf("FindNode(%q) = %s, want %s", tt.ref, node.InstanceID, tt.wantID)
+		}
+	}
+}
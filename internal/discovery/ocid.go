@@ -0,0 +1,139 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+var (
+	// ErrBastionAccessDenied is returned when the user lacks permission to access a bastion given by OCID.
+	ErrBastionAccessDenied = errors.New("bastion access denied")
+
+	// ErrBastionNotFound is returned when a bastion given by OCID does not exist.
+	ErrBastionNotFound = errors.New("bastion not found")
+
+	// ErrCompartmentAccessDenied is returned when the user lacks permission to access a compartment given by OCID.
+	ErrCompartmentAccessDenied = errors.New("compartment access denied")
+
+	// ErrCompartmentNotFound is returned when a compartment given by OCID does not exist.
+	ErrCompartmentNotFound = errors.New("compartment not found")
+)
+
+// parseOCIDOf parses ocid, which must be the OCID of one of types.
+func parseOCIDOf(ocid string, types ...string) (*utils.OCIDParts, error) {
+	parts := utils.ParseOCID(ocid)
+	if parts == nil {
+		return nil, fmt.Errorf("%w: '%s' is not a valid OCID", ErrInvalidOCID, ocid)
+	}
+	if !slices.Contains(types, parts.ResourceType) {
+		return nil, fmt.Errorf("%w: expected %s OCID but got '%s' OCID", ErrInvalidOCID, types[0], parts.ResourceType)
+	}
+	return parts, nil
+}
+
+// lookupError classifies err, returned when looking up the resource of kind
+// what by its OCID, into denied or notFound with a suggestion. region is
+// where it was looked up, if it has one.
+func lookupError(err error, what, ocid, region string, denied, notFound error) error {
+	ociErr := client.ClassifyOCIError(err, "get "+what+" by OCID")
+
+	switch ociErr.Type {
+	case client.ErrorTypeNotAuthorizedOrNotFound:
+		return fmt.Errorf("%w: %s OCID '%s' not accessible\n\n%s", denied, what, ocid, ociErr.Suggestion)
+	case client.ErrorTypeNotAuthenticated:
+		return fmt.Errorf("authentication failed when accessing %s\n\n%s", what, ociErr.Suggestion)
+	case client.ErrorTypeNotAuthorized:
+		return fmt.Errorf("%w: insufficient permissions to access %s '%s'\n\n%s", denied, what, ocid, ociErr.Suggestion)
+	case client.ErrorTypeNotFound:
+		if region == "" {
+			return fmt.Errorf("%w: %s '%s' does not exist\n\n%s", notFound, what, ocid, ociErr.Suggestion)
+		}
+		return fmt.Errorf("%w: %s '%s' does not exist in region %s\n\n%s", notFound, what, ocid, region, ociErr.Suggestion)
+	default:
+		return fmt.Errorf("failed to get %s: %w", what, err)
+	}
+}
+
+// DiscoverBastionByOCID looks up a bastion directly by its OCID, in the
+// region the OCID names, rather than among the bastions of a compartment.
+func (d *Discoverer) DiscoverBastionByOCID(ctx context.Context, bastionOCID string) (*DiscoveredBastion, error) {
+	ocidParts, err := parseOCIDOf(bastionOCID, "bastion")
+	if err != nil {
+		return nil, err
+	}
+	region := ocidParts.Region
+	if region == "" {
+		return nil, fmt.Errorf("%w: could not extract region from OCID", ErrInvalidOCID)
+	}
+
+	log.Info().Msgf("Looking up bastion by OCID in region %s...", region)
+
+	b, err := d.ociClient.RegionClient(region).GetBastion(ctx, bastionOCID)
+	if err != nil {
+		return nil, lookupError(err, "bastion", bastionOCID, region, ErrBastionAccessDenied, ErrBastionNotFound)
+	}
+
+	bastion := &DiscoveredBastion{OCID: bastionOCID, Type: "STANDARD"}
+	if b.Name != nil {
+		bastion.Name = *b.Name
+	}
+	if b.BastionType != nil {
+		bastion.Type = *b.BastionType
+	}
+	if b.CompartmentId != nil {
+		bastion.CompartmentID = *b.CompartmentId
+	}
+	return bastion, nil
+}
+
+// DiscoverCompartmentByOCID looks up a compartment, or the tenancy, directly
+// by its OCID and returns it with its path, without walking the tenancy.
+func (d *Discoverer) DiscoverCompartmentByOCID(ctx context.Context, compartmentOCID string) (*CompartmentNode, error) {
+	if _, err := parseOCIDOf(compartmentOCID, "compartment", "tenancy"); err != nil {
+		return nil, err
+	}
+
+	compartment, err := d.ociClient.GetCompartment(ctx, compartmentOCID)
+	if err != nil {
+		return nil, lookupError(err, "compartment", compartmentOCID, "", ErrCompartmentAccessDenied, ErrCompartmentNotFound)
+	}
+
+	node := &CompartmentNode{ID: compartmentOCID, Path: d.CompartmentPath(ctx, compartmentOCID)}
+	if compartment.Name != nil {
+		node.Name = *compartment.Name
+	}
+	if compartment.CompartmentId != nil {
+		node.ParentID = *compartment.CompartmentId
+	}
+	if node.Path == "" {
+		node.Path = compartmentOCID
+	}
+	node.depth = strings.Count(node.Path, "/")
+	return node, nil
+}
+
+// scopeHints returns hints with the compartment of hints.CompartmentOCID
+// looked up, if it is set, for the compartment walk to start from. Resource
+// search queries the whole tenancy, so scoped discovery always walks.
+func (d *Discoverer) scopeHints(ctx context.Context, hints *DiscoveryHints) (*DiscoveryHints, error) {
+	if hints == nil || hints.CompartmentOCID == "" || hints.compartment != nil {
+		return hints, nil
+	}
+	compartment, err := d.DiscoverCompartmentByOCID(ctx, hints.CompartmentOCID)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Msgf("Limiting discovery to compartment %s", compartment.Path)
+
+	scoped := *hints
+	scoped.Method = MethodCompartments
+	scoped.compartment = compartment
+	return &scoped, nil
+}
@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/bastion"
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+func TestDiscoverBastionByOCID(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	id, name, compartmentID := "ocid1.bastion.oc1.eu-frankfurt-1.a", "jump", "ocid1.compartment.oc1..net"
+	mock.AddBastion(&bastion.Bastion{Id: &id, Name: &name, CompartmentId: &compartmentID})
+	discoverer := NewDiscoverer(mock, nil)
+
+	b, err := discoverer.DiscoverBastionByOCID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("DiscoverBastionByOCID() error = %v", err)
+	}
+	if b.OCID != id || b.Name != name || b.Type != "STANDARD" || b.CompartmentID != compartmentID {
+		t.Errorf("DiscoverBastionByOCID() = %+v", b)
+	}
+	var regionClientCalled bool
+	for _, call := range mock.GetCalls() {
+		if call.Method == "RegionClient" && call.Args[0] == "eu-frankfurt-1" {
+			regionClientCalled = true
+		}
+	}
+	if !regionClientCalled {
+		t.Error("the bastion should be looked up in the region of its OCID")
+	}
+
+	if _, err := discoverer.DiscoverBastionByOCID(context.Background(), "ocid1.cluster.oc1.iad.a"); !errors.Is(err, ErrInvalidOCID) ||
+		!strings.Contains(err.Error(), "expected bastion OCID but got 'cluster' OCID") {
+		t.Errorf("DiscoverBastionByOCID(cluster OCID) error = %v", err)
+	}
+
+	mock.BastionError = &mockServiceError{statusCode: 404, code: "NotAuthorizedOrNotFound", message: "not found"}
+	if _, err := discoverer.DiscoverBastionByOCID(context.Background(), id); !errors.Is(err, ErrBastionAccessDenied) {
+		t.Errorf("DiscoverBastionByOCID() error = %v, want ErrBastionAccessDenied", err)
+	}
+}
+
+func TestDiscoverCompartmentByOCID(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	compartment := func(id, name string) identity.Compartment {
+		return identity.Compartment{Id: &id, Name: &name}
+	}
+	mock.AddCompartmentByID(mock.TenancyOCID, compartment("ocid1.compartment.oc1..prod", "prod"))
+	mock.AddCompartmentByID("ocid1.compartment.oc1..prod", compartment("ocid1.compartment.oc1..oke", "oke"))
+	discoverer := NewDiscoverer(mock, nil)
+
+	node, err := discoverer.DiscoverCompartmentByOCID(context.Background(), "ocid1.compartment.oc1..oke")
+	if err != nil {
+		t.Fatalf("DiscoverCompartmentByOCID() error = %v", err)
+	}
+	if node.Name != "oke" || node.Path != "root/prod/oke" || node.ParentID != "ocid1.compartment.oc1..prod" || node.depth != 2 {
+		t.Errorf("DiscoverCompartmentByOCID() = %+v", node)
+	}
+
+	if _, err := discoverer.DiscoverCompartmentByOCID(context.Background(), "ocid1.vcn.oc1.iad.a"); !errors.Is(err, ErrInvalidOCID) {
+		t.Errorf("DiscoverCompartmentByOCID(vcn OCID) error = %v, want ErrInvalidOCID", err)
+	}
+
+	mock.CompartmentError = &mockServiceError{statusCode: 403, code: "NotAuthorized", message: "forbidden"}
+	if _, err := discoverer.DiscoverCompartmentByOCID(context.Background(), "ocid1.compartment.oc1..oke"); !errors.Is(err, ErrCompartmentAccessDenied) {
+		t.Errorf("DiscoverCompartmentByOCID() error = %v, want ErrCompartmentAccessDenied", err)
+	}
+}
+
+func TestDiscoverAll_Compartment(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	compartment := func(id, name string) identity.Compartment {
+		return identity.Compartment{Id: &id, Name: &name}
+	}
+	mock.AddCompartmentByID(mock.TenancyOCID, compartment("ocid1.compartment.oc1..prod", "prod"))
+	mock.AddCompartmentByID(mock.TenancyOCID, compartment("ocid1.compartment.oc1..dev", "dev"))
+	mock.AddCompartmentByID("ocid1.compartment.oc1..prod", compartment("ocid1.compartment.oc1..oke", "oke"))
+	for compartmentID, name := range map[string]string{
+		"ocid1.compartment.oc1..oke": "payments",
+		"ocid1.compartment.oc1..dev": "sandbox",
+	} {
+		id, name := "ocid1.cluster.oc1.iad."+name, name
+		mock.AddClusterToCompartment(compartmentID, containerengine.ClusterSummary{Id: &id, Name: &name})
+		mock.AddCluster(&containerengine.Cluster{Id: &id, Name: &name})
+	}
+
+	hints := &DiscoveryHints{Method: MethodSearch, CompartmentOCID: "ocid1.compartment.oc1..prod"}
+	clusters, err := NewDiscoverer(mock, nil).DiscoverAll(context.Background(), hints)
+	if err != nil {
+		t.Fatalf("DiscoverAll() error = %v", err)
+	}
+	if len(clusters) != 1 || clusters[0].ClusterName != "payments" {
+		t.Fatalf("DiscoverAll() = %+v, want only payments", clusters)
+	}
+	if c := clusters[0]; c.CompartmentOcid == nil || *c.CompartmentOcid != "ocid1.compartment.oc1..oke" {
+		t.Errorf("payments compartment = %v, want ocid1.compartment.oc1..oke", c.CompartmentOcid)
+	}
+	if hints.compartment != nil {
+		t.Error("DiscoverAll() should not change the hints it is given")
+	}
+}
@@ -0,0 +1,233 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+// Profile records where discovery spends its time: how long each region and
+// compartment took to search, and the OCI API calls made in each, so that
+// region hints and compartment filters can be tuned. A nil Profile records
+// nothing.
+type Profile struct {
+	mu           sync.Mutex
+	start        time.Time
+	total        ProfileStats
+	regions      map[string]*ProfileStats
+	regionOrder  []string
+	compartments map[profileScope]*ProfileStats
+	operations   map[string]*ProfileStats
+}
+
+// ProfileStats is the time spent and API calls made in one region,
+// compartment or operation.
+type ProfileStats struct {
+	Duration    time.Duration
+	Calls       int
+	RateLimited int
+	Errors      int
+}
+
+func (s *ProfileStats) add(call client.APICall) {
+	s.Calls++
+	if call.RateLimited() {
+		s.RateLimited++
+	}
+	if call.Failed() {
+		s.Errors++
+	}
+}
+
+// profileScope is the region, and compartment path within it, that calls
+// made with a context are attributed to.
+type profileScope struct {
+	region      string
+	compartment string
+}
+
+type profileScopeKey struct{}
+
+type profileKey struct{}
+
+// NewProfile returns an empty profile, timed from now.
+func NewProfile() *Profile {
+	return &Profile{
+		start:        time.Now(),
+		regions:      make(map[string]*ProfileStats),
+		compartments: make(map[profileScope]*ProfileStats),
+		operations:   make(map[string]*ProfileStats),
+	}
+}
+
+// WithProfile returns a context carrying p, for discovery started with it to
+// record into.
+func WithProfile(ctx context.Context, p *Profile) context.Context {
+	return context.WithValue(ctx, profileKey{}, p)
+}
+
+// ProfileFromContext returns the profile carried by ctx, or nil.
+func ProfileFromContext(ctx context.Context) *Profile {
+	p, _ := ctx.Value(profileKey{}).(*Profile)
+	return p
+}
+
+// SetProfile records the discoverer's searches and API calls into p, or
+// stops recording if p is nil.
+func (d *Discoverer) SetProfile(p *Profile) {
+	d.profile = p
+	if p == nil {
+		d.ociClient.SetCallObserver(nil)
+		return
+	}
+	d.ociClient.SetCallObserver(p.observe)
+}
+
+// track attributes the calls made with the returned context to region and,
+// if not empty, the compartment at path, and returns a function that records
+// how long they took.
+func (p *Profile) track(ctx context.Context, region, path string) (context.Context, func()) {
+	if p == nil {
+		return ctx, func() {}
+	}
+	scope := profileScope{region: region, compartment: path}
+	start := time.Now()
+	return context.WithValue(ctx, profileScopeKey{}, scope), func() {
+		elapsed := time.Since(start)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.statsLocked(scope).Duration += elapsed
+	}
+}
+
+// statsLocked returns the stats of scope, creating them if needed.
+func (p *Profile) statsLocked(scope profileScope) *ProfileStats {
+	if scope.compartment != "" {
+		stats, ok := p.compartments[scope]
+		if !ok {
+			stats = &ProfileStats{}
+			p.compartments[scope] = stats
+		}
+		return stats
+	}
+	stats, ok := p.regions[scope.region]
+	if !ok {
+		stats = &ProfileStats{}
+		p.regions[scope.region] = stats
+		p.regionOrder = append(p.regionOrder, scope.region)
+	}
+	return stats
+}
+
+// observe counts an API call against its operation and the region and
+// compartment it was made in.
+func (p *Profile) observe(ctx context.Context, call client.APICall) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.total.add(call)
+	op, ok := p.operations[call.Operation]
+	if !ok {
+		op = &ProfileStats{}
+		p.operations[call.Operation] = op
+	}
+	op.add(call)
+	op.Duration += call.Duration
+
+	scope, ok := ctx.Value(profileScopeKey{}).(profileScope)
+	if !ok {
+		return
+	}
+	p.statsLocked(profileScope{region: scope.region}).add(call)
+	if scope.compartment != "" {
+		p.statsLocked(scope).add(call)
+	}
+}
+
+// Total returns the API calls made so far.
+func (p *Profile) Total() ProfileStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.total
+}
+
+// Report writes the time spent in each region searched, the compartments
+// searched slowest first, and the API calls made by operation.
+func (p *Profile) Report(w io.Writer) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.total.Calls == 0 && len(p.regionOrder) == 0 {
+		fmt.Fprintln(w, "\nDiscovery profile: nothing was discovered; clusters came from config or the cache")
+		return
+	}
+
+	fmt.Fprintf(w, "\nDiscovery profile: %s, %d API calls, %d rate-limited, %d failed\n",
+		time.Since(p.start).Round(time.Millisecond), p.total.Calls, p.total.RateLimited, p.total.Errors)
+
+	if len(p.regionOrder) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "\nREGION\tTIME\tCALLS\tRATE-LIMITED\tFAILED")
+		for _, region := range p.regionOrder {
+			writeProfileRow(tw, region, p.regions[region])
+		}
+		tw.Flush()
+	}
+
+	if len(p.compartments) > 0 {
+		scopes := make([]profileScope, 0, len(p.compartments))
+		for scope := range p.compartments {
+			scopes = append(scopes, scope)
+		}
+		sort.Slice(scopes, func(i, j int) bool {
+			a, b := p.compartments[scopes[i]], p.compartments[scopes[j]]
+			if a.Duration != b.Duration {
+				return a.Duration > b.Duration
+			}
+			return scopes[i].region+scopes[i].compartment < scopes[j].region+scopes[j].compartment
+		})
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "\nCOMPARTMENT\tREGION\tTIME\tCALLS\tRATE-LIMITED\tFAILED")
+		for _, scope := range scopes {
+			writeProfileRow(tw, scope.compartment+"\t"+scope.region, p.compartments[scope])
+		}
+		tw.Flush()
+	}
+
+	if len(p.operations) > 0 {
+		ops := make([]string, 0, len(p.operations))
+		for op := range p.operations {
+			ops = append(ops, op)
+		}
+		sort.Slice(ops, func(i, j int) bool {
+			a, b := p.operations[ops[i]], p.operations[ops[j]]
+			if a.Calls != b.Calls {
+				return a.Calls > b.Calls
+			}
+			return ops[i] < ops[j]
+		})
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "\nOPERATION\tTIME\tCALLS\tRATE-LIMITED\tFAILED")
+		for _, op := range ops {
+			writeProfileRow(tw, op, p.operations[op])
+		}
+		tw.Flush()
+	}
+
+	if p.total.RateLimited > 0 {
+		fmt.Fprintln(w, "\nRate-limited calls are retried after a back-off, slowing discovery. Narrow the search with --region or discovery_include_compartments to make fewer calls.")
+	}
+}
+
+func writeProfileRow(w io.Writer, name string, s *ProfileStats) {
+	fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", name, s.Duration.Round(time.Millisecond), s.Calls, s.RateLimited, s.Errors)
+}
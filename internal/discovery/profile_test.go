@@ -0,0 +1,110 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+func TestProfile_DiscoverClusterWithHints(t *testing.T) {
+	mock := newCompartmentTestClient()
+	mock.TenancyOCID = "tenancy"
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name, compartment := "ocid1.cluster.oc1.iad.prod", "prod", "ocid1.compartment.oc1..oke"
+	mock.AddClusterToCompartment(compartment, containerengine.ClusterSummary{
+		Id: &id, Name: &name, LifecycleState: containerengine.ClusterLifecycleStateActive,
+	})
+	mock.AddCluster(&containerengine.Cluster{Id: &id, Name: &name, CompartmentId: &compartment})
+
+	profile := NewProfile()
+	discoverer := NewDiscoverer(mock, nil)
+	discoverer.SetProfile(profile)
+
+	if _, err := discoverer.DiscoverClusterWithHints(context.Background(), name, nil); err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+
+	region := profile.regions["us-ashburn-1"]
+	if region == nil || region.Calls == 0 || region.Duration == 0 {
+		t.Fatalf("region stats = %+v, want the calls and time of the region search", region)
+	}
+	oke := profile.compartments[profileScope{region: "us-ashburn-1", compartment: "root/prod/oke"}]
+	if oke == nil || oke.Calls != 1 {
+		t.Errorf("compartment stats = %+v, want one listing call", oke)
+	}
+	if total := profile.Total(); total.Calls <= region.Calls {
+		t.Errorf("total calls = %d, want more than the %d in the region, counting region lookup", total.Calls, region.Calls)
+	}
+	if got := profile.operations["ListClustersInCompartment"]; got == nil || got.Calls != len(profile.compartments) {
+		t.Errorf("ListClustersInCompartment stats = %+v, want one call per compartment", got)
+	}
+
+	var out bytes.Buffer
+	profile.Report(&out)
+	for _, want := range []string{"REGION", "us-ashburn-1", "root/prod/oke", "ListClustersInCompartment"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Report() missing %q:\n%s", want, out.String())
+		}
+	}
+
+	discoverer.SetProfile(nil)
+	calls := profile.Total().Calls
+	if _, err := discoverer.DiscoverClusterWithHints(context.Background(), name, nil); err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if profile.Total().Calls != calls {
+		t.Error("calls were recorded after the profile was removed")
+	}
+}
+
+func TestProfile_RateLimited(t *testing.T) {
+	profile := NewProfile()
+	ctx, done := profile.track(context.Background(), "us-phoenix-1", "root/prod")
+	profile.observe(ctx, client.APICall{Operation: "GET /clusters", Duration: time.Millisecond, StatusCode: 429})
+	profile.observe(ctx, client.APICall{Operation: "GET /clusters", Duration: time.Millisecond, StatusCode: 404})
+	profile.observe(ctx, client.APICall{Operation: "GET /clusters", Duration: time.Millisecond, StatusCode: 200})
+	done()
+
+	want := ProfileStats{Calls: 3, RateLimited: 1, Errors: 1}
+	if got := profile.Total(); got != want {
+		t.Errorf("Total() = %+v, want %+v", got, want)
+	}
+	if got := profile.regions["us-phoenix-1"]; got == nil || got.RateLimited != 1 {
+		t.Errorf("region stats = %+v, want the rate-limited call", got)
+	}
+
+	var out bytes.Buffer
+	profile.Report(&out)
+	if !strings.Contains(out.String(), "1 rate-limited") || !strings.Contains(out.String(), "Rate-limited calls are retried") {
+		t.Errorf("Report() does not report the rate limit:\n%s", out.String())
+	}
+}
+
+func TestProfile_Nil(t *testing.T) {
+	var profile *Profile
+	ctx, done := profile.track(context.Background(), "us-ashburn-1", "")
+	done()
+	if ctx != context.Background() {
+		t.Error("a nil profile should not scope the context")
+	}
+
+	var out bytes.Buffer
+	profile.Report(&out)
+	if out.Len() != 0 {
+		t.Errorf("Report() on a nil profile wrote %q", out.String())
+	}
+
+	if ProfileFromContext(context.Background()) != nil {
+		t.Error("ProfileFromContext() should be nil without a profile")
+	}
+	p := NewProfile()
+	if ProfileFromContext(WithProfile(context.Background(), p)) != p {
+		t.Error("ProfileFromContext() should return the profile carried")
+	}
+}
@@ -0,0 +1,408 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+// ResourceType is a kind of private resource that discovery finds by name.
+// Every type is reached through a bastion the same way; only how it is
+// found and where its endpoint is differ.
+type ResourceType string
+
+const (
+	// ResourceCluster is an OKE cluster's private Kubernetes API endpoint.
+	ResourceCluster ResourceType = "oke"
+
+	// ResourceDBSystem is a DB system's SCAN listener.
+	ResourceDBSystem ResourceType = "dbsystem"
+
+	// ResourceAutonomousDB is an Autonomous Database's private endpoint.
+	ResourceAutonomousDB ResourceType = "adb"
+
+	// ResourceInstance is a compute instance's primary private IP.
+	ResourceInstance ResourceType = "instance"
+
+	// ResourceMySQL is a MySQL HeatWave DB system's endpoint.
+	ResourceMySQL ResourceType = "mysql"
+
+	// ResourcePostgres is an OCI Database with PostgreSQL DB system's
+	// primary endpoint.
+	ResourcePostgres ResourceType = "postgresql"
+)
+
+// DatabaseTypes are the resource types that are databases, in the order
+// they are searched when the type of a database is not known.
+var DatabaseTypes = []ResourceType{ResourceDBSystem, ResourceAutonomousDB, ResourceMySQL, ResourcePostgres}
+
+// ParseResourceType parses a resource type name. An empty name means
+// ResourceCluster.
+func ParseResourceType(s string) (ResourceType, error) {
+	switch t := ResourceType(strings.ToLower(s)); t {
+	case "", "cluster", ResourceCluster:
+		return ResourceCluster, nil
+	case "db", ResourceDBSystem:
+		return ResourceDBSystem, nil
+	case "autonomous", ResourceAutonomousDB:
+		return ResourceAutonomousDB, nil
+	case ResourceInstance:
+		return ResourceInstance, nil
+	case ResourceMySQL:
+		return ResourceMySQL, nil
+	case "postgres", "psql", ResourcePostgres:
+		return ResourcePostgres, nil
+	default:
+		return "", fmt.Errorf("unknown resource type %q (expected %s, %s, %s, %s, %s or %s)",
+			s, ResourceCluster, ResourceDBSystem, ResourceAutonomousDB, ResourceInstance, ResourceMySQL, ResourcePostgres)
+	}
+}
+
+// cacheName returns the name a resource of type t is cached under. Clusters
+// keep their bare names so existing cache entries stay valid.
+func cacheName(t ResourceType, name string) string {
+	if t == "" || t == ResourceCluster {
+		return name
+	}
+	return string(t) + ":" + name
+}
+
+// resourceType returns the type of resource to find, ResourceCluster when
+// none is set.
+func (h *DiscoveryHints) resourceType() ResourceType {
+	if h == nil || h.Type == "" {
+		return ResourceCluster
+	}
+	return h.Type
+}
+
+// listedResource is a resource found in a compartment, before its endpoint
+// is looked up.
+type listedResource struct {
+	id   string
+	name string
+}
+
+// resourceKind finds resources of one type.
+type resourceKind interface {
+	// searchType is the resource type in Resource Search queries.
+	searchType() string
+	// listed reports whether a resource in lifecycle state, as reported by
+	// Resource Search, is one list would return.
+	listed(state string) bool
+	// list returns the usable resources of a compartment.
+	list(ctx context.Context, ociClient client.OCIClientInterface, compartmentID string) ([]listedResource, error)
+	// complete fills in the endpoint, VCN and subnet of r, whose OCID,
+	// compartment and region are set.
+	complete(ctx context.Context, ociClient client.OCIClientInterface, r *DiscoveredCluster) error
+}
+
+// resourceKinds holds the finder of every resource type but clusters, whose
+// finder depends on the discoverer's cluster states.
+var resourceKinds = map[ResourceType]resourceKind{
+	ResourceDBSystem:     dbSystemKind{},
+	ResourceAutonomousDB: autonomousDBKind{},
+	ResourceInstance:     instanceKind{},
+	ResourceMySQL:        mysqlKind{},
+	ResourcePostgres:     postgresKind{},
+}
+
+// kindOf returns the finder of resource type t, clusters by default.
+func (d *Discoverer) kindOf(t ResourceType) resourceKind {
+	if kind, ok := resourceKinds[t]; ok {
+		return kind
+	}
+	return clusterKind{states: d.clusterStates}
+}
+
+// DefaultClusterStates are the lifecycle states of the clusters discovery
+// finds unless told otherwise: those that can be connected to.
+var DefaultClusterStates = []containerengine.ClusterLifecycleStateEnum{
+	containerengine.ClusterLifecycleStateActive,
+	containerengine.ClusterLifecycleStateUpdating,
+}
+
+// SetClusterStates limits the clusters found to those in states, such as
+// DefaultClusterStates. No states means clusters in any state, including
+// deleted ones.
+func (d *Discoverer) SetClusterStates(states []containerengine.ClusterLifecycleStateEnum) {
+	d.clusterStates = states
+}
+
+// clusterKind finds OKE clusters in states, or in any state if it is empty.
+type clusterKind struct {
+	states []containerengine.ClusterLifecycleStateEnum
+}
+
+func (clusterKind) searchType() string { return "cluster" }
+
+func (k clusterKind) listed(state string) bool {
+	return len(k.states) == 0 || slices.Contains(k.states, containerengine.ClusterLifecycleStateEnum(strings.ToUpper(state)))
+}
+
+func (k clusterKind) list(ctx context.Context, ociClient client.OCIClientInterface, compartmentID string) ([]listedResource, error) {
+	clusters, err := ociClient.ListClustersInCompartment(ctx, compartmentID, k.states)
+	if err != nil {
+		return nil, err
+	}
+	var resources []listedResource
+	for _, c := range clusters {
+		// The API filters by state as well; summaries without one are kept
+		if c.Id == nil || c.Name == nil || (c.LifecycleState != "" && !k.listed(string(c.LifecycleState))) {
+			continue
+		}
+		resources = append(resources, listedResource{id: *c.Id, name: *c.Name})
+	}
+	return resources, nil
+}
+
+func (clusterKind) complete(ctx context.Context, ociClient client.OCIClientInterface, r *DiscoveredCluster) error {
+	fullCluster, err := ociClient.GetCluster(ctx, r.OCID)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster details: %w", err)
+	}
+
+	// Extract endpoint info
+	if fullCluster.Endpoints != nil && fullCluster.Endpoints.PrivateEndpoint != nil {
+		r.EndpointIP, r.EndpointPort = parseEndpoint(*fullCluster.Endpoints.PrivateEndpoint)
+	}
+
+	// Extract VCN and subnet
+	if fullCluster.VcnId != nil {
+		r.VcnID = *fullCluster.VcnId
+	}
+	if fullCluster.EndpointConfig != nil && fullCluster.EndpointConfig.SubnetId != nil {
+		r.SubnetID = *fullCluster.EndpointConfig.SubnetId
+	}
+	return nil
+}
+
+// dbSystemKind finds DB systems, reached on their SCAN listener.
+type dbSystemKind struct{}
+
+// defaultDBListenerPort is the listener port of DB systems that do not
+// report one.
+const defaultDBListenerPort = 1521
+
+func (dbSystemKind) searchType() string { return "dbsystem" }
+
+func (dbSystemKind) listed(state string) bool { return strings.EqualFold(state, "AVAILABLE") }
+
+func (dbSystemKind) list(ctx context.Context, ociClient client.OCIClientInterface, compartmentID string) ([]listedResource, error) {
+	systems, err := ociClient.ListDbSystems(ctx, compartmentID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []listedResource
+	for _, s := range systems {
+		if s.Id != nil && s.DisplayName != nil {
+			resources = append(resources, listedResource{id: *s.Id, name: *s.DisplayName})
+		}
+	}
+	return resources, nil
+}
+
+func (dbSystemKind) complete(ctx context.Context, ociClient client.OCIClientInterface, r *DiscoveredCluster) error {
+	system, err := ociClient.GetDbSystem(ctx, r.OCID)
+	if err != nil {
+		return err
+	}
+
+	// Single-node systems without a SCAN listener are reached on their VIP
+	ipIDs := slices.Concat(system.ScanIpIds, system.VipIds)
+	if len(ipIDs) == 0 {
+		return fmt.Errorf("DB system '%s' has no SCAN or virtual IP", r.Name)
+	}
+	ip, err := ociClient.GetPrivateIP(ctx, ipIDs[0])
+	if err != nil {
+		return err
+	}
+	if ip.IpAddress == nil {
+		return fmt.Errorf("DB system '%s' has no private IP address", r.Name)
+	}
+
+	r.EndpointIP = *ip.IpAddress
+	r.EndpointPort = defaultDBListenerPort
+	if system.ListenerPort != nil {
+		r.EndpointPort = *system.ListenerPort
+	}
+	if system.SubnetId != nil {
+		r.SubnetID = *system.SubnetId
+	}
+	return nil
+}
+
+// autonomousDBKind finds Autonomous Databases with private endpoints.
+type autonomousDBKind struct{}
+
+// autonomousDBPort is the TLS listener port of Autonomous Databases.
+const autonomousDBPort = 1522
+
+func (autonomousDBKind) searchType() string { return "autonomousdatabase" }
+
+func (autonomousDBKind) listed(state string) bool { return strings.EqualFold(state, "AVAILABLE") }
+
+func (autonomousDBKind) list(ctx context.Context, ociClient client.OCIClientInterface, compartmentID string) ([]listedResource, error) {
+	databases, err := ociClient.ListAutonomousDatabases(ctx, compartmentID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []listedResource
+	for _, db := range databases {
+		if db.Id != nil && db.DisplayName != nil {
+			resources = append(resources, listedResource{id: *db.Id, name: *db.DisplayName})
+		}
+	}
+	return resources, nil
+}
+
+func (autonomousDBKind) complete(ctx context.Context, ociClient client.OCIClientInterface, r *DiscoveredCluster) error {
+	db, err := ociClient.GetAutonomousDatabase(ctx, r.OCID)
+	if err != nil {
+		return err
+	}
+	if db.PrivateEndpointIp == nil || *db.PrivateEndpointIp == "" {
+		return fmt.Errorf("autonomous database '%s' has no private endpoint; databases on a public endpoint need no bastion", r.Name)
+	}
+
+	r.EndpointIP = *db.PrivateEndpointIp
+	r.EndpointPort = autonomousDBPort
+	if db.SubnetId != nil {
+		r.SubnetID = *db.SubnetId
+	}
+	return nil
+}
+
+// instanceKind finds running compute instances, reached on their primary
+// private IP.
+type instanceKind struct{}
+
+// instanceSSHPort is the port forwarded to instances by default.
+const instanceSSHPort = 22
+
+func (instanceKind) searchType() string { return "instance" }
+
+func (instanceKind) listed(state string) bool { return strings.EqualFold(state, "RUNNING") }
+
+func (instanceKind) list(ctx context.Context, ociClient client.OCIClientInterface, compartmentID string) ([]listedResource, error) {
+	instances, err := ociClient.ListInstances(ctx, compartmentID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []listedResource
+	for _, instance := range instances {
+		if instance.Id != nil && instance.DisplayName != nil {
+			resources = append(resources, listedResource{id: *instance.Id, name: *instance.DisplayName})
+		}
+	}
+	return resources, nil
+}
+
+func (instanceKind) complete(ctx context.Context, ociClient client.OCIClientInterface, r *DiscoveredCluster) error {
+	vnic, err := ociClient.GetPrimaryVnic(ctx, r.CompartmentID, r.OCID)
+	if err != nil {
+		return err
+	}
+	if vnic.PrivateIp == nil {
+		return fmt.Errorf("instance '%s' has no private IP address", r.Name)
+	}
+
+	r.EndpointIP = *vnic.PrivateIp
+	r.EndpointPort = instanceSSHPort
+	if vnic.SubnetId != nil {
+		r.SubnetID = *vnic.SubnetId
+	}
+	return nil
+}
+
+// mysqlKind finds MySQL HeatWave DB systems, reached on their primary
+// endpoint.
+type mysqlKind struct{}
+
+// mysqlPort is the MySQL classic protocol port.
+const mysqlPort = 3306
+
+func (mysqlKind) searchType() string { return "mysqldbsystem" }
+
+func (mysqlKind) listed(state string) bool { return strings.EqualFold(state, "ACTIVE") }
+
+func (mysqlKind) list(ctx context.Context, ociClient client.OCIClientInterface, compartmentID string) ([]listedResource, error) {
+	systems, err := ociClient.ListMySQLDbSystems(ctx, compartmentID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []listedResource
+	for _, s := range systems {
+		if s.Id != nil && s.DisplayName != nil {
+			resources = append(resources, listedResource{id: *s.Id, name: *s.DisplayName})
+		}
+	}
+	return resources, nil
+}
+
+func (mysqlKind) complete(ctx context.Context, ociClient client.OCIClientInterface, r *DiscoveredCluster) error {
+	system, err := ociClient.GetMySQLDbSystem(ctx, r.OCID)
+	if err != nil {
+		return err
+	}
+	if system.IpAddress == nil || *system.IpAddress == "" {
+		return fmt.Errorf("MySQL DB system '%s' has no private IP address", r.Name)
+	}
+
+	r.EndpointIP = *system.IpAddress
+	r.EndpointPort = mysqlPort
+	if system.Port != nil {
+		r.EndpointPort = *system.Port
+	}
+	if system.SubnetId != nil {
+		r.SubnetID = *system.SubnetId
+	}
+	return nil
+}
+
+// postgresKind finds OCI Database with PostgreSQL DB systems, reached on
+// their primary endpoint.
+type postgresKind struct{}
+
+// postgresPort is the port of PostgreSQL DB system endpoints.
+const postgresPort = 5432
+
+func (postgresKind) searchType() string { return "postgresqldbsystem" }
+
+func (postgresKind) listed(state string) bool { return strings.EqualFold(state, "ACTIVE") }
+
+func (postgresKind) list(ctx context.Context, ociClient client.OCIClientInterface, compartmentID string) ([]listedResource, error) {
+	systems, err := ociClient.ListPostgresDbSystems(ctx, compartmentID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []listedResource
+	for _, s := range systems {
+		if s.Id != nil && s.DisplayName != nil {
+			resources = append(resources, listedResource{id: *s.Id, name: *s.DisplayName})
+		}
+	}
+	return resources, nil
+}
+
+func (postgresKind) complete(ctx context.Context, ociClient client.OCIClientInterface, r *DiscoveredCluster) error {
+	system, err := ociClient.GetPostgresDbSystem(ctx, r.OCID)
+	if err != nil {
+		return err
+	}
+	network := system.NetworkDetails
+	if network == nil || network.PrimaryDbEndpointPrivateIp == nil || *network.PrimaryDbEndpointPrivateIp == "" {
+		return fmt.Errorf("PostgreSQL DB system '%s' has no private endpoint", r.Name)
+	}
+
+	r.EndpointIP = *network.PrimaryDbEndpointPrivateIp
+	r.EndpointPort = postgresPort
+	if network.SubnetId != nil {
+		r.SubnetID = *network.SubnetId
+	}
+	return nil
+}
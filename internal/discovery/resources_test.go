@@ -0,0 +1,222 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/oracle/oci-go-sdk/v65/database"
+	"github.com/oracle/oci-go-sdk/v65/mysql"
+	"github.com/oracle/oci-go-sdk/v65/psql"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+func TestParseResourceType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ResourceType
+		wantErr bool
+	}{
+		{"", ResourceCluster, false},
+		{"cluster", ResourceCluster, false},
+		{"OKE", ResourceCluster, false},
+		{"adb", ResourceAutonomousDB, false},
+		{"dbsystem", ResourceDBSystem, false},
+		{"instance", ResourceInstance, false},
+		{"mysql", ResourceMySQL, false},
+		{"psql", ResourcePostgres, false},
+		{"PostgreSQL", ResourcePostgres, false},
+		{"bucket", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseResourceType(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseResourceType(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDiscoverClusterWithHints_AutonomousDB(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name, compartment := "ocid1.autonomousdatabase.oc1.iad.a", "orders-db", mock.TenancyOCID
+	ip, subnet := "10.0.3.15", "ocid1.subnet.oc1.iad.db"
+	mock.AutonomousDatabases[id] = &database.AutonomousDatabase{
+		Id: &id, DisplayName: &name, CompartmentId: &compartment, PrivateEndpointIp: &ip, SubnetId: &subnet,
+	}
+
+	cache, err := NewCache(t.TempDir(), DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	discoverer := NewDiscoverer(mock, cache)
+	hints := &DiscoveryHints{Type: ResourceAutonomousDB}
+
+	db, err := discoverer.DiscoverClusterWithHints(context.Background(), "ORDERS-DB", hints)
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if db.OCID != id || db.EndpointIP != ip || db.EndpointPort != autonomousDBPort || db.SubnetID != subnet {
+		t.Errorf("DiscoverClusterWithHints() = %+v", db)
+	}
+
+	// Cached apart from a cluster of the same name
+	if cache.GetCluster("orders-db") != nil {
+		t.Error("An Autonomous Database should not be cached under the bare name")
+	}
+	if cache.GetCluster("adb:orders-db") == nil {
+		t.Error("The Autonomous Database should be cached under its type")
+	}
+}
+
+func TestDiscoverClusterWithHints_AutonomousDBWithoutPrivateEndpoint(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name, compartment := "ocid1.autonomousdatabase.oc1.iad.a", "public-db", mock.TenancyOCID
+	mock.AutonomousDatabases[id] = &database.AutonomousDatabase{Id: &id, DisplayName: &name, CompartmentId: &compartment}
+
+	_, err := NewDiscoverer(mock, nil).DiscoverClusterWithHints(context.Background(), name, &DiscoveryHints{Type: ResourceAutonomousDB})
+	if err == nil {
+		t.Fatal("Expected an error for a database without a private endpoint")
+	}
+}
+
+func TestDiscoverClusterWithHints_DBSystem(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name, compartment := "ocid1.dbsystem.oc1.iad.a", "erp", mock.TenancyOCID
+	scanID, scanIP, port := "ocid1.privateip.oc1.iad.scan", "10.0.4.20", 1525
+	mock.DbSystems[id] = &database.DbSystem{
+		Id: &id, DisplayName: &name, CompartmentId: &compartment, ScanIpIds: []string{scanID}, ListenerPort: &port,
+	}
+	mock.PrivateIPs[scanID] = &core.PrivateIp{IpAddress: &scanIP}
+
+	system, err := NewDiscoverer(mock, nil).DiscoverClusterWithHints(context.Background(), name, &DiscoveryHints{Type: ResourceDBSystem})
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if system.EndpointIP != scanIP || system.EndpointPort != port {
+		t.Errorf("endpoint = %s:%d, want %s:%d", system.EndpointIP, system.EndpointPort, scanIP, port)
+	}
+}
+
+func TestDiscoverClusterWithHints_MySQL(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name, compartment := "ocid1.mysqldbsystem.oc1.iad.a", "orders", mock.TenancyOCID
+	ip, port := "10.0.6.12", 3307
+	mock.MySQLDbSystems[id] = &mysql.DbSystem{Id: &id, DisplayName: &name, CompartmentId: &compartment, IpAddress: &ip, Port: &port}
+
+	system, err := NewDiscoverer(mock, nil).DiscoverClusterWithHints(context.Background(), name, &DiscoveryHints{Type: ResourceMySQL})
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if system.EndpointIP != ip || system.EndpointPort != port || system.Type != ResourceMySQL {
+		t.Errorf("DiscoverClusterWithHints() = %+v", system)
+	}
+}
+
+func TestDiscoverClusterWithHints_Postgres(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name, compartment := "ocid1.postgresqldbsystem.oc1.iad.a", "ledger", mock.TenancyOCID
+	ip, subnet := "10.0.7.30", "ocid1.subnet.oc1.iad.pg"
+	mock.PostgresDbSystems[id] = &psql.DbSystem{
+		Id: &id, DisplayName: &name, CompartmentId: &compartment,
+		NetworkDetails: &psql.NetworkDetails{SubnetId: &subnet, PrimaryDbEndpointPrivateIp: &ip},
+	}
+
+	system, err := NewDiscoverer(mock, nil).DiscoverClusterWithHints(context.Background(), name, &DiscoveryHints{Type: ResourcePostgres})
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if system.EndpointIP != ip || system.EndpointPort != postgresPort || system.SubnetID != subnet {
+		t.Errorf("DiscoverClusterWithHints() = %+v", system)
+	}
+}
+
+func TestDiscoverClusterWithHints_InstanceSearch(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.Region = "us-ashburn-1"
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+
+	id, name, compartment, state := "ocid1.instance.oc1.iad.a", "jump-box", "ocid1.compartment.oc1..ops", "RUNNING"
+	ip := "10.0.5.9"
+	mock.SearchResults["us-ashburn-1"] = []resourcesearch.ResourceSummary{
+		{Identifier: &id, DisplayName: &name, CompartmentId: &compartment, LifecycleState: &state},
+	}
+	mock.PrimaryVnics[id] = &core.Vnic{PrivateIp: &ip}
+
+	hints := &DiscoveryHints{Type: ResourceInstance, Method: MethodSearch}
+	instance, err := NewDiscoverer(mock, nil).DiscoverClusterWithHints(context.Background(), name, hints)
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if instance.EndpointIP != ip || instance.EndpointPort != instanceSSHPort || instance.Type != ResourceInstance {
+		t.Errorf("DiscoverClusterWithHints() = %+v", instance)
+	}
+
+	for _, call := range mock.GetCalls() {
+		if call.Method == "SearchResources" && call.Args[0] != "query instance resources where displayName =~ 'jump-box'" {
+			t.Errorf("SearchResources() query = %v", call.Args[0])
+		}
+	}
+}
+
+func TestDiscoverClusterWithHints_ClusterStates(t *testing.T) {
+	newClient := func() *client.MockOCIClient {
+		mock := client.NewMockOCIClient()
+		mock.AddSubscribedRegion("us-ashburn-1", true)
+		for _, c := range []struct {
+			id    string
+			state containerengine.ClusterLifecycleStateEnum
+		}{
+			{"ocid1.cluster.oc1.iad.deleted", containerengine.ClusterLifecycleStateDeleted},
+			{"ocid1.cluster.oc1.iad.failed", containerengine.ClusterLifecycleStateFailed},
+			{"ocid1.cluster.oc1.iad.active", containerengine.ClusterLifecycleStateActive},
+		} {
+			id, name := c.id, "prod"
+			mock.AddClusterToCompartment(mock.TenancyOCID, containerengine.ClusterSummary{Id: &id, Name: &name, LifecycleState: c.state})
+			mock.AddCluster(&containerengine.Cluster{Id: &id, Name: &name, LifecycleState: c.state})
+		}
+		return mock
+	}
+
+	cluster, err := NewDiscoverer(newClient(), nil).DiscoverClusterWithHints(context.Background(), "prod", nil)
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if cluster.OCID != "ocid1.cluster.oc1.iad.active" {
+		t.Errorf("OCID = %s, want the active cluster", cluster.OCID)
+	}
+
+	// Every state
+	discoverer := NewDiscoverer(newClient(), nil)
+	discoverer.SetClusterStates(nil)
+	_, err = discoverer.DiscoverClusterWithHints(context.Background(), "prod", nil)
+	var multiple *MultipleClustersError
+	if !errors.As(err, &multiple) || len(multiple.Matches) != 3 {
+		t.Errorf("DiscoverClusterWithHints() error = %v, want all 3 clusters", err)
+	}
+}
+
+func TestClusterKindListed(t *testing.T) {
+	kind := clusterKind{states: DefaultClusterStates}
+	for state, want := range map[string]bool{"ACTIVE": true, "Updating": true, "DELETED": false, "FAILED": false, "CREATING": false} {
+		if got := kind.listed(state); got != want {
+			t.Errorf("listed(%q) = %v, want %v", state, got, want)
+		}
+	}
+	if !(clusterKind{}).listed("DELETED") {
+		t.Error("a cluster kind without states should list every state")
+	}
+}
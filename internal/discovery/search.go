@@ -0,0 +1,122 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+)
+
+// Method is how name-based discovery finds clusters in a region.
+type Method string
+
+const (
+	// MethodCompartments lists the clusters of every compartment in the
+	// tenancy. It needs only permission to list clusters, but takes one
+	// call per compartment.
+	MethodCompartments Method = "compartments"
+
+	// MethodSearch asks OCI Resource Search for clusters with the name,
+	// taking one call per region. Search results lag resource changes by
+	// a few minutes.
+	MethodSearch Method = "search"
+)
+
+// ParseMethod parses a discovery method name. An empty name means
+// compartments.
+func ParseMethod(s string) (Method, error) {
+	switch Method(s) {
+	case "", MethodCompartments:
+		return MethodCompartments, nil
+	case MethodSearch:
+		return MethodSearch, nil
+	default:
+		return "", fmt.Errorf("unknown discovery method %q (expected %q or %q)", s, MethodCompartments, MethodSearch)
+	}
+}
+
+// allResourcesQuery returns the Resource Search query for every resource
+// of kind, used to find names a few edits away from one that does not exist.
+func allResourcesQuery(kind resourceKind) string {
+	return fmt.Sprintf("query %s resources", kind.searchType())
+}
+
+// clusterSearchQuery returns the Resource Search query for resources of
+// kind named clusterName. "=~" matches without regard to case, like the
+// compartment walk, but also matches longer names, which are kept as
+// suggestions.
+func clusterSearchQuery(kind resourceKind, clusterName string) (string, bool) {
+	// Quotes cannot be escaped in the query language
+	if strings.ContainsAny(clusterName, `'\`) {
+		return "", false
+	}
+	return fmt.Sprintf("query %s resources where displayName =~ '%s'", kind.searchType(), clusterName), true
+}
+
+// searchClusterWithResourceSearch finds clusters named clusterName, or a
+// similar name, in region with Resource Search. Names the query language
+// cannot express fall back to the compartment walk.
+func (d *Discoverer) searchClusterWithResourceSearch(ctx context.Context, tenancyOCID, clusterName, region string, hints *DiscoveryHints) ([]*DiscoveredCluster, error) {
+	resourceType := hints.resourceType()
+	query, ok := clusterSearchQuery(d.kindOf(resourceType), clusterName)
+	if !ok {
+		return d.searchClusterInRegion(ctx, tenancyOCID, clusterName, region, hints)
+	}
+
+	matches, err := d.searchClusters(ctx, query, resourceType, clusterName, region)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matches {
+		if strings.EqualFold(m.Name, clusterName) {
+			return matches, nil
+		}
+	}
+
+	// Only now is it worth listing every cluster to catch typos
+	return d.searchClusters(ctx, allResourcesQuery(d.kindOf(resourceType)), resourceType, clusterName, region)
+}
+
+// searchClusters runs a Resource Search query for resources of type
+// resourceType in region and returns those with clusterName or a similar
+// name.
+func (d *Discoverer) searchClusters(ctx context.Context, query string, resourceType ResourceType, clusterName, region string) ([]*DiscoveredCluster, error) {
+	return d.searchResources(ctx, query, resourceType, region, func(r resourcesearch.ResourceSummary) bool {
+		return isCandidate(clusterName, *r.DisplayName)
+	})
+}
+
+// searchResources runs a Resource Search query for resources of type
+// resourceType in region and returns those keep accepts.
+func (d *Discoverer) searchResources(ctx context.Context, query string, resourceType ResourceType, region string, keep func(resourcesearch.ResourceSummary) bool) ([]*DiscoveredCluster, error) {
+	resources, err := d.ociClient.RegionClient(region).SearchResources(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*DiscoveredCluster
+	for _, r := range resources {
+		if r.Identifier == nil || r.DisplayName == nil || !keep(r) {
+			continue
+		}
+		// Match the lifecycle states the compartment walk lists
+		if r.LifecycleState != nil && !d.kindOf(resourceType).listed(*r.LifecycleState) {
+			continue
+		}
+
+		match := &DiscoveredCluster{
+			Type:   resourceType,
+			OCID:   *r.Identifier,
+			Name:   *r.DisplayName,
+			Region: region,
+		}
+		if r.CompartmentId != nil {
+			match.CompartmentID = *r.CompartmentId
+			// Search does not return compartment paths
+			match.CompartmentPath = *r.CompartmentId
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/kubeconfig"
+)
+
+// SetKubeconfigPaths sets the kubeconfig files whose OKE contexts hint at
+// the regions to search first. None are read by default.
+func (d *Discoverer) SetKubeconfigPaths(paths []string) {
+	d.kubeconfigPaths = paths
+}
+
+// seedHints returns hints with PreferredRegions set, unless a region is
+// already hinted, to where a cluster named clusterName most likely is: the
+// regions of kubeconfig contexts or clusters with the name, then the OCI CLI
+// profile's region, then the regions of the other OKE clusters in
+// kubeconfig. hints itself is not modified.
+func (d *Discoverer) seedHints(clusterName string, hints *DiscoveryHints) *DiscoveryHints {
+	if hints != nil && (hints.Region != "" || len(hints.PreferredRegions) > 0) {
+		return hints
+	}
+
+	var named, other []string
+	for _, path := range d.kubeconfigPaths {
+		k, err := kubeconfig.LoadFromFile(path)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Not using kubeconfig %s for discovery hints", path)
+			continue
+		}
+		for _, c := range k.OKEClusters() {
+			if strings.EqualFold(c.Context, clusterName) || strings.EqualFold(c.Cluster, clusterName) {
+				named = append(named, c.Region)
+			} else {
+				other = append(other, c.Region)
+			}
+		}
+	}
+
+	var regions []string
+	for _, r := range slices.Concat(named, []string{d.ociClient.GetConfiguredRegion()}, other) {
+		if r == "" {
+			continue
+		}
+		// Accept short region keys such as "iad", as used in OCIDs
+		r = string(common.StringToRegion(r))
+		if !slices.Contains(regions, r) {
+			regions = append(regions, r)
+		}
+	}
+	if len(regions) == 0 {
+		return hints
+	}
+
+	log.Debug().Msgf("Searching regions %v first", regions)
+	seeded := &DiscoveryHints{}
+	if hints != nil {
+		*seeded = *hints
+	}
+	seeded.PreferredRegions = regions
+	return seeded
+}
+
+// preferRegions moves the regions of regions that are in preferred to the
+// front, in the order of preferred.
+func preferRegions(regions, preferred []string) []string {
+	ordered := make([]string, 0, len(regions))
+	for _, p := range preferred {
+		if slices.Contains(regions, p) && !slices.Contains(ordered, p) {
+			ordered = append(ordered, p)
+		}
+	}
+	for _, r := range regions {
+		if !slices.Contains(ordered, r) {
+			ordered = append(ordered, r)
+		}
+	}
+	return ordered
+}
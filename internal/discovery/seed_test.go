@@ -0,0 +1,87 @@
+package discovery
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/kubeconfig"
+)
+
+func TestPreferRegions(t *testing.T) {
+	regions := []string{"us-ashburn-1", "us-phoenix-1", "eu-frankfurt-1"}
+	got := preferRegions(regions, []string{"eu-frankfurt-1", "ap-tokyo-1", "us-phoenix-1"})
+	want := []string{"eu-frankfurt-1", "us-phoenix-1", "us-ashburn-1"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("preferRegions() = %v, want %v", got, want)
+		}
+	}
+}
+
+// newSeedTestClient returns a mock with a cluster named prod that every
+// region finds, so the region searched first is the one returned.
+func newSeedTestClient() *client.MockOCIClient {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	mock.AddSubscribedRegion("us-phoenix-1", false)
+	mock.AddSubscribedRegion("eu-frankfurt-1", false)
+
+	id, name := "ocid1.cluster.oc1.phx.prod", "prod"
+	mock.AddClusterToCompartment(mock.TenancyOCID, containerengine.ClusterSummary{Id: &id, Name: &name})
+	mock.AddCluster(&containerengine.Cluster{Id: &id, Name: &name})
+	return mock
+}
+
+func TestDiscoverClusterWithHints_KubeconfigRegion(t *testing.T) {
+	k := kubeconfig.NewKubeconfig()
+	k.AddCluster("cluster-a", "https://10.0.1.10:6443", false)
+	k.AddContext("staging", "cluster-a", "user-a")
+	k.AddOCIUser("user-a", "ocid1.cluster.oc1.iad.a", "eu-frankfurt-1")
+	k.AddCluster("cluster-b", "https://10.0.2.10:6443", false)
+	k.AddContext("prod", "cluster-b", "user-b")
+	k.AddOCIUser("user-b", "ocid1.cluster.oc1.phx.b", "us-phoenix-1")
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := k.WriteToFile(path); err != nil {
+		t.Fatalf("WriteToFile() error = %v", err)
+	}
+
+	mock := newSeedTestClient()
+	mock.ConfiguredRegion = "eu-frankfurt-1"
+	discoverer := NewDiscoverer(mock, nil)
+	discoverer.SetKubeconfigPaths([]string{path, filepath.Join(t.TempDir(), "missing")})
+
+	cluster, err := discoverer.DiscoverClusterWithHints(context.Background(), "prod", nil)
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if cluster.Region != "us-phoenix-1" {
+		t.Errorf("Region = %s, want the region of the kubeconfig context named prod", cluster.Region)
+	}
+}
+
+func TestDiscoverClusterWithHints_ConfiguredRegion(t *testing.T) {
+	mock := newSeedTestClient()
+	mock.ConfiguredRegion = "fra"
+
+	cluster, err := NewDiscoverer(mock, nil).DiscoverClusterWithHints(context.Background(), "prod", nil)
+	if err != nil {
+		t.Fatalf("DiscoverClusterWithHints() error = %v", err)
+	}
+	if cluster.Region != "eu-frankfurt-1" {
+		t.Errorf("Region = %s, want the OCI CLI profile's region", cluster.Region)
+	}
+}
+
+func TestSeedHints_RegionHint(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.ConfiguredRegion = "us-phoenix-1"
+	hints := &DiscoveryHints{Region: "us-ashburn-1"}
+
+	if got := NewDiscoverer(mock, nil).seedHints("prod", hints); got != hints {
+		t.Errorf("seedHints() = %+v, want hints unchanged", got)
+	}
+}
@@ -0,0 +1,200 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+	"github.com/rs/zerolog/log"
+)
+
+// TagSelector selects resources carrying a tag with a value. Without a
+// namespace it matches a freeform tag, with one a defined tag.
+type TagSelector struct {
+	Namespace string
+	Key       string
+	Value     string
+}
+
+// ParseTagSelector parses "key=value" for a freeform tag or
+// "namespace.key=value" for a defined tag.
+func ParseTagSelector(s string) (TagSelector, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return TagSelector{}, fmt.Errorf("invalid tag %q (expected key=value or namespace.key=value)", s)
+	}
+
+	var t TagSelector
+	if namespace, k, ok := strings.Cut(key, "."); ok {
+		if namespace == "" || k == "" {
+			return TagSelector{}, fmt.Errorf("invalid tag %q (expected key=value or namespace.key=value)", s)
+		}
+		t.Namespace, key = namespace, k
+	}
+	t.Key, t.Value = key, value
+
+	// Quotes cannot be escaped in the query language
+	if strings.ContainsAny(s, `'\`) {
+		return TagSelector{}, fmt.Errorf("invalid tag %q: quotes and backslashes are not supported", s)
+	}
+	return t, nil
+}
+
+func (t TagSelector) String() string {
+	if t.Namespace != "" {
+		return t.Namespace + "." + t.Key + "=" + t.Value
+	}
+	return t.Key + "=" + t.Value
+}
+
+// condition returns the Resource Search condition for the tag.
+func (t TagSelector) condition() string {
+	if t.Namespace != "" {
+		return fmt.Sprintf("(definedTags.namespace = '%s' && definedTags.key = '%s' && definedTags.value = '%s')",
+			t.Namespace, t.Key, t.Value)
+	}
+	return fmt.Sprintf("(freeformTags.key = '%s' && freeformTags.value = '%s')", t.Key, t.Value)
+}
+
+// matches reports whether r carries the tag. Search conditions on several
+// tags may each be met by a different tag, so results are checked again.
+// Tag namespaces and keys are case-insensitive in OCI; values are not.
+func (t TagSelector) matches(r resourcesearch.ResourceSummary) bool {
+	if t.Namespace == "" {
+		for k, v := range r.FreeformTags {
+			if strings.EqualFold(k, t.Key) && v == t.Value {
+				return true
+			}
+		}
+		return false
+	}
+
+	for namespace, tags := range r.DefinedTags {
+		if !strings.EqualFold(namespace, t.Namespace) {
+			continue
+		}
+		for k, v := range tags {
+			if strings.EqualFold(k, t.Key) && fmt.Sprint(v) == t.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FormatTags joins tags for messages, as in "env=prod,team=payments".
+func FormatTags(tags []TagSelector) string {
+	parts := make([]string, len(tags))
+	for i, t := range tags {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// tagSearchQuery returns the Resource Search query for resources of kind
+// carrying every tag.
+func tagSearchQuery(kind resourceKind, tags []TagSelector) string {
+	conditions := make([]string, len(tags))
+	for i, t := range tags {
+		conditions[i] = t.condition()
+	}
+	return fmt.Sprintf("query %s resources where %s", kind.searchType(), strings.Join(conditions, " && "))
+}
+
+// DiscoverClusterByTags finds the cluster, or resource of hints.Type,
+// carrying every one of tags with Resource Search, in hints.Region or every
+// subscribed region. Several matches are returned as a MultipleClustersError
+// with Tagged set. Which cluster carries the tags is not cached, as tags
+// move between clusters; the cluster found is cached under its name.
+func (d *Discoverer) DiscoverClusterByTags(ctx context.Context, tags []TagSelector, hints *DiscoveryHints) (*DiscoveredCluster, error) {
+	matches, err := d.searchTagged(ctx, tags, hints)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) > 1 {
+		return nil, &MultipleClustersError{Name: FormatTags(tags), Matches: matches, Tagged: true}
+	}
+
+	cluster, err := d.CompleteCluster(ctx, matches[0], matches[0].CacheKey())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msgf("Discovered cluster '%s' in region %s (tagged %s)", cluster.Name, cluster.Region, FormatTags(tags))
+	return cluster, nil
+}
+
+// DiscoverClustersByTags is DiscoverClusterByTags returning every cluster
+// carrying the tags rather than failing when there are several.
+func (d *Discoverer) DiscoverClustersByTags(ctx context.Context, tags []TagSelector, hints *DiscoveryHints) ([]*DiscoveredCluster, error) {
+	matches, err := d.searchTagged(ctx, tags, hints)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]*DiscoveredCluster, 0, len(matches))
+	for _, m := range matches {
+		cluster, err := d.CompleteCluster(ctx, m, m.CacheKey())
+		if err != nil {
+			return nil, fmt.Errorf("cluster '%s': %w", m.Name, err)
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	log.Info().Msgf("Discovered %d clusters tagged %s", len(clusters), FormatTags(tags))
+	return clusters, nil
+}
+
+// searchTagged returns the clusters carrying every one of tags, or a
+// ClusterNotFoundError with Tagged set if there are none.
+func (d *Discoverer) searchTagged(ctx context.Context, tags []TagSelector, hints *DiscoveryHints) ([]*DiscoveredCluster, error) {
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags to select by")
+	}
+	selection := FormatTags(tags)
+	resourceType := hints.resourceType()
+	query := tagSearchQuery(d.kindOf(resourceType), tags)
+
+	log.Info().Msgf("Discovering %s tagged %s...", resourceType, selection)
+
+	tenancyOCID, err := d.ociClient.GetTenancyOCID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenancy OCID: %w", err)
+	}
+
+	regions, err := d.getRegionsToSearch(ctx, tenancyOCID, hints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get regions: %w", err)
+	}
+
+	var matches []*DiscoveredCluster
+	for _, region := range regions {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		regionCtx, done := d.profile.track(ctx, region, "")
+		found, err := d.searchResources(regionCtx, query, resourceType, region, func(r resourcesearch.ResourceSummary) bool {
+			for _, t := range tags {
+				if !t.matches(r) {
+					return false
+				}
+			}
+			return true
+		})
+		done()
+		if err != nil {
+			log.Warn().Err(err).Msgf("Error searching region %s", region)
+			continue
+		}
+		matches = append(matches, found...)
+	}
+
+	if len(matches) == 0 {
+		return nil, &ClusterNotFoundError{Name: selection, Regions: len(regions), Tagged: true}
+	}
+	return matches, nil
+}
@@ -0,0 +1,135 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/containerengine"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+	"github.com/scotttball/tunatap/internal/client"
+)
+
+func TestParseTagSelector(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    TagSelector
+		wantErr bool
+	}{
+		{"env=prod", TagSelector{Key: "env", Value: "prod"}, false},
+		{"Operations.team=payments", TagSelector{Namespace: "Operations", Key: "team", Value: "payments"}, false},
+		{"env=", TagSelector{Key: "env"}, false},
+		{"env", TagSelector{}, true},
+		{"=prod", TagSelector{}, true},
+		{".team=payments", TagSelector{}, true},
+		{"env=it's", TagSelector{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTagSelector(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTagSelector(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+		if err == nil && got.String() != tt.in {
+			t.Errorf("String() = %q, want %q", got.String(), tt.in)
+		}
+	}
+}
+
+func TestTagSearchQuery(t *testing.T) {
+	tags := []TagSelector{{Key: "env", Value: "prod"}, {Namespace: "Operations", Key: "team", Value: "payments"}}
+	want := "query cluster resources where (freeformTags.key = 'env' && freeformTags.value = 'prod') && " +
+		"(definedTags.namespace = 'Operations' && definedTags.key = 'team' && definedTags.value = 'payments')"
+	if got := tagSearchQuery(clusterKind{}, tags); got != want {
+		t.Errorf("tagSearchQuery() = %q, want %q", got, want)
+	}
+}
+
+// newTaggedCluster returns a search result for an active cluster.
+func newTaggedCluster(id, name string, freeform map[string]string, defined map[string]map[string]interface{}) resourcesearch.ResourceSummary {
+	compartment, state := "ocid1.compartment.oc1..oke", string(containerengine.ClusterLifecycleStateActive)
+	return resourcesearch.ResourceSummary{
+		Identifier: &id, DisplayName: &name, CompartmentId: &compartment, LifecycleState: &state,
+		FreeformTags: freeform, DefinedTags: defined,
+	}
+}
+
+func TestDiscoverClusterByTags(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	mock.AddSubscribedRegion("us-phoenix-1", false)
+
+	id := "ocid1.cluster.oc1.phx.payments"
+	mock.SearchResults["us-ashburn-1"] = []resourcesearch.ResourceSummary{
+		// Search matched each tag on a different cluster tag
+		newTaggedCluster("ocid1.cluster.oc1.iad.mixed", "oke-7f3a", map[string]string{"env": "dev", "team": "prod"},
+			map[string]map[string]interface{}{"Operations": {"team": "payments"}}),
+	}
+	mock.SearchResults["us-phoenix-1"] = []resourcesearch.ResourceSummary{
+		newTaggedCluster(id, "oke-91bc", map[string]string{"Env": "prod"},
+			map[string]map[string]interface{}{"operations": {"Team": "payments"}}),
+	}
+	mock.AddCluster(&containerengine.Cluster{Id: &id})
+
+	tags := []TagSelector{{Key: "env", Value: "prod"}, {Namespace: "Operations", Key: "team", Value: "payments"}}
+	cluster, err := NewDiscoverer(mock, nil).DiscoverClusterByTags(context.Background(), tags, nil)
+	if err != nil {
+		t.Fatalf("DiscoverClusterByTags() error = %v", err)
+	}
+	if cluster.OCID != id || cluster.Name != "oke-91bc" || cluster.Region != "us-phoenix-1" {
+		t.Errorf("DiscoverClusterByTags() = %+v", cluster)
+	}
+}
+
+func TestDiscoverClusterByTags_Multiple(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	prod := map[string]string{"env": "prod"}
+	mock.SearchResults["us-ashburn-1"] = []resourcesearch.ResourceSummary{
+		newTaggedCluster("ocid1.cluster.oc1.iad.a", "oke-a", prod, nil),
+		newTaggedCluster("ocid1.cluster.oc1.iad.b", "oke-b", prod, nil),
+	}
+
+	_, err := NewDiscoverer(mock, nil).DiscoverClusterByTags(context.Background(), []TagSelector{{Key: "env", Value: "prod"}}, nil)
+	var multiple *MultipleClustersError
+	if !errors.As(err, &multiple) || !multiple.Tagged || len(multiple.Matches) != 2 {
+		t.Fatalf("DiscoverClusterByTags() error = %v, want tagged MultipleClustersError with 2 matches", err)
+	}
+}
+
+func TestDiscoverClusterByTags_NotFound(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	mock.SearchResults["us-ashburn-1"] = []resourcesearch.ResourceSummary{
+		// Tag values are case-sensitive
+		newTaggedCluster("ocid1.cluster.oc1.iad.a", "oke-a", map[string]string{"env": "Prod"}, nil),
+	}
+
+	_, err := NewDiscoverer(mock, nil).DiscoverClusterByTags(context.Background(), []TagSelector{{Key: "env", Value: "prod"}}, nil)
+	if !errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("DiscoverClusterByTags() error = %v, want ErrClusterNotFound", err)
+	}
+}
+
+func TestDiscoverClustersByTags(t *testing.T) {
+	mock := client.NewMockOCIClient()
+	mock.AddSubscribedRegion("us-ashburn-1", true)
+	prod := map[string]string{"env": "prod"}
+	ids := []string{"ocid1.cluster.oc1.iad.a", "ocid1.cluster.oc1.iad.b"}
+	mock.SearchResults["us-ashburn-1"] = []resourcesearch.ResourceSummary{
+		newTaggedCluster(ids[0], "oke-a", prod, nil),
+		newTaggedCluster(ids[1], "oke-b", prod, nil),
+		newTaggedCluster("ocid1.cluster.oc1.iad.c", "oke-c", map[string]string{"env": "dev"}, nil),
+	}
+	for i := range ids {
+		mock.AddCluster(&containerengine.Cluster{Id: &ids[i]})
+	}
+
+	clusters, err := NewDiscoverer(mock, nil).DiscoverClustersByTags(context.Background(), []TagSelector{{Key: "env", Value: "prod"}}, nil)
+	if err != nil {
+		t.Fatalf("DiscoverClustersByTags() error = %v", err)
+	}
+	if len(clusters) != 2 || clusters[0].Name != "oke-a" || clusters[1].Name != "oke-b" {
+		t.Errorf("DiscoverClustersByTags() = %+v, want oke-a and oke-b", clusters)
+	}
+}
@@ -0,0 +1,107 @@
+// Package events writes machine-readable tunnel lifecycle events as JSON
+// lines, for wrapper scripts and editor plugins that track tunnel state.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Type is the kind of lifecycle event.
+type Type string
+
+const (
+	// Discovering is emitted when a cluster is looked up in OCI because it
+	// is not in config.
+	Discovering Type = "discovering"
+
+	// SessionCreated is emitted when a bastion session is obtained.
+	SessionCreated Type = "session-created"
+
+	// TunnelReady is emitted each time the tunnel starts accepting
+	// connections, including after a reconnect.
+	TunnelReady Type = "tunnel-ready"
+
+	// Refresh is emitted when the bastion session is replaced by a new one.
+	Refresh Type = "refresh"
+
+	// RefreshFailed is emitted when checking or replacing the bastion
+	// session failed; the tunnel keeps its current session meanwhile.
+	RefreshFailed Type = "refresh-failed"
+
+	// EndpointChanged is emitted when the cluster's private endpoint moved
+	// while the tunnel was watching it.
+	EndpointChanged Type = "endpoint-changed"
+
+	// Reconnecting is emitted when the tunnel failed and will be retried.
+	Reconnecting Type = "reconnecting"
+
+	// Closed is emitted once when the tunnel stops for good.
+	Closed Type = "closed"
+)
+
+// Event is a single lifecycle event. Fields that do not apply to the event
+// type are omitted.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    Type      `json:"event"`
+	Cluster string    `json:"cluster,omitempty"`
+
+	// SessionID is the bastion session OCID.
+	SessionID string `json:"session_id,omitempty"`
+
+	// Address and Port are where the tunnel listens; Socket replaces them
+	// for tunnels on a unix socket.
+	Address string `json:"address,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	Socket  string `json:"socket,omitempty"`
+
+	// Endpoint is the cluster's new private endpoint, as host:port.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Attempt and RetryInMs describe the next reconnect attempt.
+	Attempt   int   `json:"attempt,omitempty"`
+	RetryInMs int64 `json:"retry_in_ms,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Writer writes events as one JSON object per line. A nil *Writer discards
+// events, so callers need not check whether events are enabled.
+type Writer struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	handler func(Event)
+}
+
+// NewWriter creates a Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+// NewHandlerWriter creates a Writer that passes each event to handler
+// instead of writing it, one at a time.
+func NewHandlerWriter(handler func(Event)) *Writer {
+	return &Writer{handler: handler}
+}
+
+// Emit writes e, stamping it with the current time if it has none.
+func (w *Writer) Emit(e Event) {
+	if w == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.handler != nil {
+		w.handler(e)
+		return
+	}
+	// Best effort: a consumer that went away must not stop the tunnel
+	_ = w.enc.Encode(e)
+}
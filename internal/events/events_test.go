@@ -0,0 +1,57 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriterEmitsJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	w.Emit(Event{Type: SessionCreated, Cluster: "prod", SessionID: "ocid1.bastionsession.oc1..abc"})
+	w.Emit(Event{Type: TunnelReady, Cluster: "prod", Address: "localhost", Port: 6443})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+
+	var ready map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &ready); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if ready["event"] != "tunnel-ready" {
+		t.Errorf("event = %v, want %q", ready["event"], "tunnel-ready")
+	}
+	if ready["port"] != float64(6443) {
+		t.Errorf("port = %v, want 6443", ready["port"])
+	}
+	if ready["time"] == nil {
+		t.Error("event should be timestamped")
+	}
+	if _, ok := ready["session_id"]; ok {
+		t.Error("unset fields should be omitted")
+	}
+}
+
+func TestNilWriterDiscards(t *testing.T) {
+	var w *Writer
+	w.Emit(Event{Type: Closed})
+}
+
+func TestHandlerWriter(t *testing.T) {
+	var got []Event
+	w := NewHandlerWriter(func(e Event) { got = append(got, e) })
+
+	w.Emit(Event{Type: RefreshFailed, Cluster: "prod", Error: "service unavailable"})
+
+	if len(got) != 1 || got[0].Type != RefreshFailed || got[0].Error != "service unavailable" {
+		t.Fatalf("handler got %+v", got)
+	}
+	if got[0].Time.IsZero() {
+		t.Error("event should be timestamped")
+	}
+}
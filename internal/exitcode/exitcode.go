@@ -0,0 +1,105 @@
+// Package exitcode defines the exit codes of tunatap, one per class of
+// failure, so that scripts can tell why a command failed without parsing
+// its messages.
+package exitcode
+
+import "errors"
+
+// Code is a process exit code.
+type Code int
+
+const (
+	// OK is success.
+	OK Code = 0
+	// Failure is any failure without a more specific code.
+	Failure Code = 1
+	// Usage is an invalid flag or argument.
+	Usage Code = 2
+	// Auth is a failure to authenticate to OCI, or a request OCI refused
+	// as unauthenticated or unauthorized.
+	Auth Code = 3
+	// NotFound is a cluster or other target that neither the config nor
+	// discovery could find.
+	NotFound Code = 4
+	// BastionUnavailable is a bastion that could not be found, chosen or
+	// used, including a failure to create a bastion session.
+	BastionUnavailable Code = 5
+	// TunnelFailed is a tunnel that could not be opened or was lost for
+	// good.
+	TunnelFailed Code = 6
+	// PreflightFailed is a preflight or doctor check that found errors.
+	PreflightFailed Code = 7
+	// Cancelled is an operation the user cancelled, such as a selector
+	// closed without a choice, or Ctrl-C. It matches the shell's code for
+	// SIGINT.
+	Cancelled Code = 130
+)
+
+// categories are the names of the codes, as printed with
+// --error-format json.
+var categories = map[Code]string{
+	OK:                 "ok",
+	Failure:            "error",
+	Usage:              "usage",
+	Auth:               "auth",
+	NotFound:           "not_found",
+	BastionUnavailable: "bastion_unavailable",
+	TunnelFailed:       "tunnel_failed",
+	PreflightFailed:    "preflight_failed",
+	Cancelled:          "cancelled",
+}
+
+// Category returns the machine-readable name of the code, such as
+// "not_found".
+func (c Code) Category() string {
+	if name, ok := categories[c]; ok {
+		return name
+	}
+	return categories[Failure]
+}
+
+// Codes returns every code, in increasing order.
+func Codes() []Code {
+	return []Code{OK, Failure, Usage, Auth, NotFound, BastionUnavailable, TunnelFailed, PreflightFailed, Cancelled}
+}
+
+// Error is an error that exits with Code.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap marks err as exiting with code. It returns nil if err is nil.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Of returns the code err was marked with. When it was marked more than
+// once, the innermost mark wins, being closest to the cause: a bastion
+// session refused while opening a tunnel is BastionUnavailable rather than
+// TunnelFailed.
+func Of(err error) (Code, bool) {
+	var found *Error
+	for {
+		var e *Error
+		if !errors.As(err, &e) {
+			break
+		}
+		found, err = e, e.Err
+	}
+	if found == nil {
+		return Failure, false
+	}
+	return found.Code, true
+}
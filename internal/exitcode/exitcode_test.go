@@ -0,0 +1,50 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	base := errors.New("session refused")
+
+	if code, ok := Of(base); ok || code != Failure {
+		t.Errorf("Of(unmarked) = %d, %v; want Failure, false", code, ok)
+	}
+	if code, ok := Of(nil); ok || code != Failure {
+		t.Errorf("Of(nil) = %d, %v; want Failure, false", code, ok)
+	}
+
+	marked := fmt.Errorf("connect: %w", Wrap(BastionUnavailable, base))
+	if code, ok := Of(marked); !ok || code != BastionUnavailable {
+		t.Errorf("Of(wrapped) = %d, %v; want BastionUnavailable", code, ok)
+	}
+
+	// The mark closest to the cause wins
+	twice := Wrap(TunnelFailed, fmt.Errorf("tunnel: %w", marked))
+	if code, _ := Of(twice); code != BastionUnavailable {
+		t.Errorf("Of(marked twice) = %d, want BastionUnavailable", code)
+	}
+	if !errors.Is(twice, base) || twice.Error() != "tunnel: connect: session refused" {
+		t.Errorf("Wrap() changed the error: %v", twice)
+	}
+
+	if Wrap(Auth, nil) != nil {
+		t.Error("Wrap(nil) != nil")
+	}
+}
+
+func TestCategory(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, c := range Codes() {
+		name := c.Category()
+		if seen[name] {
+			t.Errorf("Category %q is used by more than one code", name)
+		}
+		seen[name] = true
+	}
+	if got := Code(42).Category(); got != "error" {
+		t.Errorf("Code(42).Category() = %q, want error", got)
+	}
+}
@@ -0,0 +1,5 @@
+// Package flock takes exclusive locks on open files that other processes
+// honour: flock on Unix and LockFileEx on Windows. A lock belongs to the
+// open file, so two files opened on the same path in one process exclude
+// each other as well.
+package flock
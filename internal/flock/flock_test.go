@@ -0,0 +1,33 @@
+package flock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+	open := func() *os.File {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+	first, second := open(), open()
+
+	if err := Lock(first); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if locked, err := TryLock(second); err != nil || locked {
+		t.Errorf("TryLock() of a locked file = %v, %v, want false", locked, err)
+	}
+
+	Unlock(first)
+	if locked, err := TryLock(second); err != nil || !locked {
+		t.Errorf("TryLock() of an unlocked file = %v, %v, want true", locked, err)
+	}
+	Unlock(second)
+}
@@ -8,18 +8,40 @@ import (
 
 // TunnelStatus represents the health status of a single tunnel.
 type TunnelStatus struct {
-	ID         string        `json:"id"`
-	Cluster    string        `json:"cluster"`
-	Region     string        `json:"region,omitempty"`
-	LocalPort  int           `json:"local_port"`
-	RemoteHost string        `json:"remote_host"`
-	RemotePort int           `json:"remote_port"`
-	SessionID  string        `json:"session_id,omitempty"`
-	StartTime  time.Time     `json:"start_time"`
-	Uptime     time.Duration `json:"uptime_ns"`
-	Healthy    bool          `json:"healthy"`
-	LastError  string        `json:"last_error,omitempty"`
-	Pool       *PoolStatus   `json:"pool,omitempty"`
+	ID          string         `json:"id"`
+	Cluster     string         `json:"cluster"`
+	Region      string         `json:"region,omitempty"`
+	LocalPort   int            `json:"local_port"`
+	LocalSocket string         `json:"local_socket,omitempty"`
+	RemoteHost  string         `json:"remote_host"`
+	RemotePort  int            `json:"remote_port"`
+	SessionID   string         `json:"session_id,omitempty"`
+	StartTime   time.Time      `json:"start_time"`
+	Uptime      time.Duration  `json:"uptime_ns"`
+	Healthy     bool           `json:"healthy"`
+	LastError   string         `json:"last_error,omitempty"`
+	Pool        *PoolStatus    `json:"pool,omitempty"`
+	Traffic     *TrafficStatus `json:"traffic,omitempty"`
+
+	// Reconnects counts connection attempts made after the first one failed.
+	Reconnects int64 `json:"reconnects"`
+	// SessionRefreshes counts bastion sessions replaced while the tunnel ran.
+	SessionRefreshes int64 `json:"session_refreshes"`
+	// UnhealthySince is when the tunnel last stopped being healthy, or when
+	// it was registered if it has not been healthy yet.
+	UnhealthySince *time.Time `json:"unhealthy_since,omitempty"`
+	// LastRefresh is when the bastion session was last checked and, if
+	// needed, replaced.
+	LastRefresh *time.Time `json:"last_refresh,omitempty"`
+}
+
+// Live reports whether the tunnel is healthy or has been unhealthy for less
+// than grace, as while it connects or reconnects.
+func (t *TunnelStatus) Live(now time.Time, grace time.Duration) bool {
+	if t.Healthy || t.UnhealthySince == nil {
+		return true
+	}
+	return now.Sub(*t.UnhealthySince) < grace
 }
 
 // PoolStatus represents the status of the connection pool.
@@ -29,6 +51,16 @@ type PoolStatus struct {
 	Available  int `json:"available"`
 }
 
+// TrafficStatus represents bandwidth and connection statistics for a tunnel.
+type TrafficStatus struct {
+	BytesIn               int64         `json:"bytes_in"`
+	BytesOut              int64         `json:"bytes_out"`
+	ActiveConnections     int64         `json:"active_connections"`
+	TotalConnections      int64         `json:"total_connections"`
+	AvgConnectionDuration time.Duration `json:"avg_connection_duration_ns"`
+	MaxConnectionDuration time.Duration `json:"max_connection_duration_ns"`
+}
+
 // HealthStatus represents the overall health status.
 type HealthStatus struct {
 	Healthy   bool            `json:"healthy"`
@@ -42,6 +74,19 @@ type Registry struct {
 	mu        sync.RWMutex
 	tunnels   map[string]*TunnelStatus
 	startTime time.Time
+
+	// ociErrors counts failed OCI API calls by operation.
+	ociErrors map[string]int64
+
+	// publishPath is the status file the registry is published to, if any,
+	// while publishers, the Publish calls whose context is not done, is not
+	// zero. Closing stopPublish stops publishing on PublishInterval.
+	publishPath string
+	publishers  int
+	stopPublish chan struct{}
+
+	// fileMu serializes writing and removing the status file.
+	fileMu sync.Mutex
 }
 
 var globalRegistry *Registry
@@ -62,12 +107,17 @@ func GetRegistry() *Registry {
 // If StartTime is zero, it will be set to now.
 // Healthy defaults to true only if not explicitly set (zero value).
 func (r *Registry) Register(status *TunnelStatus) {
+	defer r.publish()
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if status.StartTime.IsZero() {
 		status.StartTime = time.Now()
 	}
+	if !status.Healthy && status.UnhealthySince == nil {
+		since := status.StartTime
+		status.UnhealthySince = &since
+	}
 	// Don't override explicit Healthy setting - only default for new tunnels
 	// Note: We can't distinguish unset from explicitly false, so we trust the caller
 	r.tunnels[status.ID] = status
@@ -75,6 +125,7 @@ func (r *Registry) Register(status *TunnelStatus) {
 
 // Deregister removes a tunnel from the registry.
 func (r *Registry) Deregister(id string) {
+	defer r.publish()
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	delete(r.tunnels, id)
@@ -86,6 +137,12 @@ func (r *Registry) UpdateHealth(id string, healthy bool, lastError string) {
 	defer r.mu.Unlock()
 
 	if status, ok := r.tunnels[id]; ok {
+		if healthy {
+			status.UnhealthySince = nil
+		} else if status.Healthy || status.UnhealthySince == nil {
+			now := time.Now()
+			status.UnhealthySince = &now
+		}
 		status.Healthy = healthy
 		if lastError != "" {
 			status.LastError = lastError
@@ -103,6 +160,84 @@ func (r *Registry) UpdatePoolStatus(id string, pool *PoolStatus) {
 	}
 }
 
+// UpdateTraffic updates the traffic statistics for a tunnel.
+func (r *Registry) UpdateTraffic(id string, traffic *TrafficStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if status, ok := r.tunnels[id]; ok {
+		status.Traffic = traffic
+	}
+}
+
+// UpdateRemote updates where a tunnel forwards to, after its cluster's
+// endpoint moved.
+func (r *Registry) UpdateRemote(id, host string, port int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if status, ok := r.tunnels[id]; ok {
+		status.RemoteHost = host
+		status.RemotePort = port
+	}
+}
+
+// RecordReconnect counts a reconnection attempt for a tunnel.
+func (r *Registry) RecordReconnect(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if status, ok := r.tunnels[id]; ok {
+		status.Reconnects++
+	}
+}
+
+// RecordSessionRefresh counts a bastion session replacement for a tunnel.
+func (r *Registry) RecordSessionRefresh(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if status, ok := r.tunnels[id]; ok {
+		status.SessionRefreshes++
+	}
+}
+
+// SetSession records the bastion session a tunnel uses, as it is checked
+// and replaced.
+func (r *Registry) SetSession(id, sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if status, ok := r.tunnels[id]; ok {
+		now := time.Now()
+		status.SessionID = sessionID
+		status.LastRefresh = &now
+	}
+}
+
+// RecordOCIError counts a failed OCI API call for the given operation.
+func (r *Registry) RecordOCIError(operation string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ociErrors == nil {
+		r.ociErrors = make(map[string]int64)
+	}
+	r.ociErrors[operation]++
+}
+
+// OCIErrorCounts returns a copy of the failed OCI API call counts by operation.
+func (r *Registry) OCIErrorCounts() map[string]int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int64, len(r.ociErrors))
+	for op, n := range r.ociErrors {
+		counts[op] = n
+	}
+	return counts
+}
+
 // GetStatus returns the overall health status with sensitive data redacted.
 // Session IDs and remote hosts are redacted for security.
 func (r *Registry) GetStatus() *HealthStatus {
@@ -116,18 +251,24 @@ func (r *Registry) GetStatus() *HealthStatus {
 	for _, t := range r.tunnels {
 		// Create redacted copy
 		redacted := &TunnelStatus{
-			ID:         t.ID,
-			Cluster:    t.Cluster,
-			Region:     t.Region,
-			LocalPort:  t.LocalPort,
-			RemoteHost: redactHost(t.RemoteHost), // Redact internal IPs
-			RemotePort: t.RemotePort,
-			SessionID:  "", // Never expose session IDs
-			StartTime:  t.StartTime,
-			Uptime:     time.Since(t.StartTime),
-			Healthy:    t.Healthy,
-			LastError:  redactError(t.LastError), // Redact sensitive error details
-			Pool:       t.Pool,
+			ID:               t.ID,
+			Cluster:          t.Cluster,
+			Region:           t.Region,
+			LocalPort:        t.LocalPort,
+			LocalSocket:      t.LocalSocket,
+			RemoteHost:       redactHost(t.RemoteHost), // Redact internal IPs
+			RemotePort:       t.RemotePort,
+			SessionID:        "", // Never expose session IDs
+			StartTime:        t.StartTime,
+			Uptime:           time.Since(t.StartTime),
+			Healthy:          t.Healthy,
+			LastError:        redactError(t.LastError), // Redact sensitive error details
+			Pool:             t.Pool,
+			Traffic:          t.Traffic,
+			Reconnects:       t.Reconnects,
+			SessionRefreshes: t.SessionRefreshes,
+			UnhealthySince:   t.UnhealthySince,
+			LastRefresh:      t.LastRefresh,
 		}
 		tunnels = append(tunnels, redacted)
 		if !t.Healthy {
@@ -81,6 +81,24 @@ func TestRegistry_UpdatePoolStatus(t *testing.T) {
 	}
 }
 
+func TestRegistry_UpdateTraffic(t *testing.T) {
+	r := &Registry{
+		tunnels:   make(map[string]*TunnelStatus),
+		startTime: time.Now(),
+	}
+
+	r.Register(&TunnelStatus{ID: "test-1", Cluster: "my-cluster"})
+	r.UpdateTraffic("test-1", &TrafficStatus{BytesIn: 1024, BytesOut: 512, ActiveConnections: 2})
+
+	status := r.GetStatus()
+	if len(status.Tunnels) != 1 || status.Tunnels[0].Traffic == nil {
+		t.Fatal("Traffic should be included in status")
+	}
+	if status.Tunnels[0].Traffic.BytesIn != 1024 {
+		t.Errorf("Traffic.BytesIn = %d, want 1024", status.Tunnels[0].Traffic.BytesIn)
+	}
+}
+
 func TestRegistry_GetStatus(t *testing.T) {
 	r := &Registry{
 		tunnels:   make(map[string]*TunnelStatus),
@@ -227,6 +245,43 @@ func TestServer_HandleMetrics(t *testing.T) {
 	}
 }
 
+func TestServer_HandleMetricsCounters(t *testing.T) {
+	r := &Registry{
+		tunnels:   make(map[string]*TunnelStatus),
+		startTime: time.Now(),
+	}
+	r.Register(&TunnelStatus{ID: "test-1", LocalPort: 6443, Healthy: true})
+	r.UpdateTraffic("test-1", &TrafficStatus{BytesIn: 4096, BytesOut: 1024, ActiveConnections: 3, TotalConnections: 10})
+	r.RecordReconnect("test-1")
+	r.RecordReconnect("test-1")
+	r.RecordSessionRefresh("test-1")
+	r.RecordOCIError("CreateSession")
+
+	s := &Server{registry: r}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	s.handleMetrics(rec, req)
+
+	body := rec.Body.String()
+
+	expectedMetrics := []string{
+		`tunatap_tunnel_received_bytes_total{tunnel="0",local_port="6443"} 4096`,
+		`tunatap_tunnel_sent_bytes_total{tunnel="0",local_port="6443"} 1024`,
+		`tunatap_tunnel_connections_active{tunnel="0",local_port="6443"} 3`,
+		`tunatap_tunnel_connections_total{tunnel="0",local_port="6443"} 10`,
+		`tunatap_tunnel_reconnects_total{tunnel="0",local_port="6443"} 2`,
+		`tunatap_tunnel_session_refreshes_total{tunnel="0",local_port="6443"} 1`,
+		`tunatap_oci_api_errors_total{operation="CreateSession"} 1`,
+	}
+
+	for _, metric := range expectedMetrics {
+		if !strings.Contains(body, metric) {
+			t.Errorf("Missing metric: %s", metric)
+		}
+	}
+}
+
 func TestFormatDuration(t *testing.T) {
 	tests := []struct {
 		d    time.Duration
@@ -244,3 +299,112 @@ func TestFormatDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestServer_HandleHealthzLiveness(t *testing.T) {
+	r := &Registry{tunnels: make(map[string]*TunnelStatus), startTime: time.Now()}
+	s := &Server{registry: r, livenessGrace: time.Minute}
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	// A tunnel that is still connecting is within the grace period
+	r.Register(&TunnelStatus{ID: "test-1"})
+	rec := httptest.NewRecorder()
+	s.handleHealthz(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Connecting: Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	r.Register(&TunnelStatus{ID: "test-2", Healthy: true, StartTime: time.Now().Add(-time.Hour)})
+	r.Register(&TunnelStatus{ID: "test-3", StartTime: time.Now().Add(-time.Hour)})
+	rec = httptest.NewRecorder()
+	s.handleHealthz(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Failed tunnel: Status code = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if body := rec.Body.String(); body != "1 of 3 tunnels unhealthy\n" {
+		t.Errorf("Body = %q", body)
+	}
+
+	r.UpdateHealth("test-3", true, "")
+	rec = httptest.NewRecorder()
+	s.handleHealthz(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Recovered: Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestRegistry_UnhealthySince(t *testing.T) {
+	r := &Registry{tunnels: make(map[string]*TunnelStatus), startTime: time.Now()}
+
+	start := time.Now().Add(-time.Hour)
+	r.Register(&TunnelStatus{ID: "test-1", StartTime: start})
+	if s := r.GetTunnelStatus("test-1"); s.UnhealthySince == nil || !s.UnhealthySince.Equal(start) {
+		t.Errorf("UnhealthySince = %v, want the start time of a tunnel that was never healthy", s.UnhealthySince)
+	}
+
+	r.UpdateHealth("test-1", true, "")
+	if s := r.GetTunnelStatus("test-1"); s.UnhealthySince != nil {
+		t.Errorf("UnhealthySince = %v, want nil once healthy", s.UnhealthySince)
+	}
+
+	r.UpdateHealth("test-1", false, "connection failed")
+	first := r.GetTunnelStatus("test-1").UnhealthySince
+	if first == nil || time.Since(*first) > time.Minute {
+		t.Fatalf("UnhealthySince = %v, want when the tunnel failed", first)
+	}
+	r.UpdateHealth("test-1", false, "connection failed again")
+	if s := r.GetTunnelStatus("test-1"); s.UnhealthySince != first {
+		t.Error("UnhealthySince should not move while the tunnel stays unhealthy")
+	}
+}
+
+func TestServer_Tunnels(t *testing.T) {
+	r := &Registry{tunnels: make(map[string]*TunnelStatus), startTime: time.Now()}
+	s := &Server{registry: r, livenessGrace: time.Minute}
+	handler := s.routes()
+
+	now := time.Now()
+	r.Register(&TunnelStatus{ID: "b", Cluster: "staging", Healthy: true, StartTime: now, SessionID: "ocid1.session"})
+	r.Register(&TunnelStatus{ID: "a", Cluster: "prod", StartTime: now.Add(-time.Hour)})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/tunnels")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/tunnels: Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var tunnels []TunnelStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &tunnels); err != nil {
+		t.Fatal(err)
+	}
+	if len(tunnels) != 2 || tunnels[0].ID != "a" || tunnels[1].ID != "b" {
+		t.Errorf("/tunnels = %+v, want a then b", tunnels)
+	}
+	if strings.Contains(rec.Body.String(), "ocid1.session") {
+		t.Error("/tunnels should not expose session IDs")
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/tunnels/b", http.StatusOK},
+		{"/tunnels/a", http.StatusServiceUnavailable},
+		{"/tunnels/missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := get(tt.path)
+		if rec.Code != tt.want {
+			t.Errorf("%s: Status code = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+		if tt.want != http.StatusNotFound {
+			var status TunnelStatus
+			if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || "/tunnels/"+status.ID != tt.path {
+				t.Errorf("%s: body = %s", tt.path, rec.Body.String())
+			}
+		}
+	}
+}
@@ -7,17 +7,25 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+// LivenessGrace is how long a tunnel may be unhealthy, while it connects or
+// reconnects, before the liveness endpoints report it as failed.
+const LivenessGrace = 5 * time.Minute
+
 // Server represents the health HTTP server.
 type Server struct {
 	addr     string
 	server   *http.Server
 	registry *Registry
+
+	// livenessGrace is LivenessGrace, except in tests.
+	livenessGrace time.Duration
 }
 
 // NewServer creates a new health server.
@@ -27,8 +35,9 @@ func NewServer(addr string) *Server {
 	// Force localhost binding for security
 	addr = sanitizeBindAddress(addr)
 	return &Server{
-		addr:     addr,
-		registry: GetRegistry(),
+		addr:          addr,
+		registry:      GetRegistry(),
+		livenessGrace: LivenessGrace,
 	}
 }
 
@@ -79,17 +88,9 @@ func isLocalhostAddress(host string) bool {
 
 // Start starts the health HTTP server.
 func (s *Server) Start() error {
-	mux := http.NewServeMux()
-
-	// Health check endpoints
-	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/healthz", s.handleHealthz) // Kubernetes-style liveness probe
-	mux.HandleFunc("/readyz", s.handleReadyz)   // Kubernetes-style readiness probe
-	mux.HandleFunc("/metrics", s.handleMetrics) // Prometheus-style metrics
-
 	s.server = &http.Server{
 		Addr:              s.addr,
-		Handler:           mux,
+		Handler:           s.routes(),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
@@ -110,6 +111,20 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// routes returns the handler serving the health endpoints.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// Health check endpoints
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/healthz", s.handleHealthz)     // Kubernetes-style liveness probe
+	mux.HandleFunc("/readyz", s.handleReadyz)       // Kubernetes-style readiness probe
+	mux.HandleFunc("/metrics", s.handleMetrics)     // Prometheus-style metrics
+	mux.HandleFunc("/tunnels", s.handleTunnels)     // JSON list of tunnels
+	mux.HandleFunc("/tunnels/{id}", s.handleTunnel) // Liveness of one tunnel
+	return mux
+}
+
 // Stop gracefully stops the health server.
 func (s *Server) Stop(ctx context.Context) error {
 	if s.server == nil {
@@ -122,27 +137,84 @@ func (s *Server) Stop(ctx context.Context) error {
 func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	status := s.registry.GetStatus()
 
-	w.Header().Set("Content-Type", "application/json")
-
 	if !status.Healthy {
-		w.WriteHeader(http.StatusServiceUnavailable)
+		writeJSON(w, http.StatusServiceUnavailable, status)
 	} else {
-		w.WriteHeader(http.StatusOK)
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+// handleHealthz returns a simple ok/fail for liveness probes: it fails once
+// any tunnel has been unhealthy for longer than the liveness grace period,
+// so that a supervisor restarts a process whose tunnels cannot recover.
+func (s *Server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	status := s.registry.GetStatus()
+	now := time.Now()
+
+	failed := 0
+	for _, t := range status.Tunnels {
+		if !t.Live(now, s.livenessGrace) {
+			failed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	if failed > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprintf(w, "%d of %d tunnels unhealthy\n", failed, len(status.Tunnels))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// handleTunnels returns the status of every tunnel as a JSON list.
+func (s *Server) handleTunnels(w http.ResponseWriter, _ *http.Request) {
+	tunnels := s.registry.GetStatus().Tunnels
+	sortTunnels(tunnels)
+	writeJSON(w, http.StatusOK, tunnels)
+}
+
+// handleTunnel returns the status of one tunnel as JSON, with the liveness
+// semantics of /healthz: 503 once it has been unhealthy for longer than the
+// grace period, and 404 if there is no tunnel with the ID.
+func (s *Server) handleTunnel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	for _, t := range s.registry.GetStatus().Tunnels {
+		if t.ID != id {
+			continue
+		}
+		code := http.StatusOK
+		if !t.Live(time.Now(), s.livenessGrace) {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, t)
+		return
 	}
+	http.Error(w, "unknown tunnel", http.StatusNotFound)
+}
+
+// writeJSON writes v as indented JSON with the status code.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(status); err != nil {
+	if err := encoder.Encode(v); err != nil {
 		log.Error().Err(err).Msg("Failed to encode health response")
 	}
 }
 
-// handleHealthz returns a simple ok/fail for liveness probes.
-func (s *Server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
-	// Liveness: are we running?
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("ok\n"))
+// sortTunnels orders tunnels by start time, then ID, so that listings and
+// metric labels stay stable between requests.
+func sortTunnels(tunnels []*TunnelStatus) {
+	sort.Slice(tunnels, func(i, j int) bool {
+		if !tunnels[i].StartTime.Equal(tunnels[j].StartTime) {
+			return tunnels[i].StartTime.Before(tunnels[j].StartTime)
+		}
+		return tunnels[i].ID < tunnels[j].ID
+	})
 }
 
 // handleReadyz returns readiness status.
@@ -168,6 +240,9 @@ func (s *Server) handleReadyz(w http.ResponseWriter, _ *http.Request) {
 func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
 	status := s.registry.GetStatus()
 
+	// Keep tunnel index labels stable between scrapes
+	sortTunnels(status.Tunnels)
+
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
 
 	// Helper to write metrics (ignoring errors - standard for HTTP handlers)
@@ -224,6 +299,56 @@ func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
 				i, t.LocalPort, t.Pool.ActiveUses)
 		}
 	}
+
+	write("# HELP tunatap_tunnel_reconnects_total Reconnection attempts after a tunnel failure\n")
+	write("# TYPE tunatap_tunnel_reconnects_total counter\n")
+	for i, t := range status.Tunnels {
+		write("tunatap_tunnel_reconnects_total{tunnel=\"%d\",local_port=\"%d\"} %d\n",
+			i, t.LocalPort, t.Reconnects)
+	}
+
+	write("# HELP tunatap_tunnel_session_refreshes_total Bastion sessions replaced while the tunnel ran\n")
+	write("# TYPE tunatap_tunnel_session_refreshes_total counter\n")
+	for i, t := range status.Tunnels {
+		write("tunatap_tunnel_session_refreshes_total{tunnel=\"%d\",local_port=\"%d\"} %d\n",
+			i, t.LocalPort, t.SessionRefreshes)
+	}
+
+	// Traffic metrics if available
+	write("# HELP tunatap_tunnel_received_bytes_total Bytes received from remote endpoints\n")
+	write("# TYPE tunatap_tunnel_received_bytes_total counter\n")
+	write("# HELP tunatap_tunnel_sent_bytes_total Bytes sent to remote endpoints\n")
+	write("# TYPE tunatap_tunnel_sent_bytes_total counter\n")
+	write("# HELP tunatap_tunnel_connections_active Connections currently being forwarded\n")
+	write("# TYPE tunatap_tunnel_connections_active gauge\n")
+	write("# HELP tunatap_tunnel_connections_total Connections forwarded since the tunnel started\n")
+	write("# TYPE tunatap_tunnel_connections_total counter\n")
+	for i, t := range status.Tunnels {
+		if t.Traffic != nil {
+			write("tunatap_tunnel_received_bytes_total{tunnel=\"%d\",local_port=\"%d\"} %d\n",
+				i, t.LocalPort, t.Traffic.BytesIn)
+			write("tunatap_tunnel_sent_bytes_total{tunnel=\"%d\",local_port=\"%d\"} %d\n",
+				i, t.LocalPort, t.Traffic.BytesOut)
+			write("tunatap_tunnel_connections_active{tunnel=\"%d\",local_port=\"%d\"} %d\n",
+				i, t.LocalPort, t.Traffic.ActiveConnections)
+			write("tunatap_tunnel_connections_total{tunnel=\"%d\",local_port=\"%d\"} %d\n",
+				i, t.LocalPort, t.Traffic.TotalConnections)
+		}
+	}
+
+	// OCI API errors by operation
+	ociErrors := s.registry.OCIErrorCounts()
+	operations := make([]string, 0, len(ociErrors))
+	for op := range ociErrors {
+		operations = append(operations, op)
+	}
+	sort.Strings(operations)
+
+	write("# HELP tunatap_oci_api_errors_total Failed OCI API calls by operation\n")
+	write("# TYPE tunatap_oci_api_errors_total counter\n")
+	for _, op := range operations {
+		write("tunatap_oci_api_errors_total{operation=\"%s\"} %d\n", op, ociErrors[op])
+	}
 }
 
 // StartHealthServer is a convenience function to start a health server.
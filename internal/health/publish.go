@@ -0,0 +1,195 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/state"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+const (
+	// PublishInterval is how often a process with tunnels writes their
+	// status to its status file.
+	PublishInterval = 2 * time.Second
+
+	// publishStale is how old a status file may be before it is taken to
+	// have been left behind by a process that exited without removing it.
+	publishStale = 5 * PublishInterval
+
+	statusDirName = "status"
+)
+
+// ProcessStatus is the status of the tunnels of one tunatap process, as
+// written to its status file for 'tunatap status' to read.
+type ProcessStatus struct {
+	PID     int             `json:"pid"`
+	Updated time.Time       `json:"updated"`
+	Tunnels []*TunnelStatus `json:"tunnels"`
+}
+
+// DefaultStatusDir returns the directory of status files, respecting the
+// configured home path.
+func DefaultStatusDir() string {
+	if homePath := state.GetInstance().GetHomePath(); homePath != "" {
+		return filepath.Join(homePath, statusDirName)
+	}
+	return filepath.Join(utils.DefaultTunatapDir(), statusDirName)
+}
+
+// Publish makes the registry write the status of its tunnels to a file of
+// this process in dir, which other tunatap processes read with
+// ReadPublished. The file is written when a tunnel is registered or
+// deregistered and every PublishInterval while there are any, and removed
+// when there are none. Each tunnel publishes until its ctx is done; the
+// file is removed and publishing stops once every ctx is.
+func (r *Registry) Publish(ctx context.Context, dir string) {
+	r.mu.Lock()
+	r.publishers++
+	start := r.publishers == 1
+	if start {
+		r.publishPath = filepath.Join(dir, strconv.Itoa(os.Getpid())+".json")
+		r.stopPublish = make(chan struct{})
+	}
+	stop := r.stopPublish
+	r.mu.Unlock()
+
+	if start {
+		r.publish()
+		go func() {
+			ticker := time.NewTicker(PublishInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					r.publish()
+				}
+			}
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		r.fileMu.Lock()
+		defer r.fileMu.Unlock()
+		r.mu.Lock()
+		r.publishers--
+		if r.publishers > 0 {
+			r.mu.Unlock()
+			return
+		}
+		path := r.publishPath
+		r.publishPath = ""
+		close(r.stopPublish)
+		r.mu.Unlock()
+		os.Remove(path)
+	}()
+}
+
+// publish writes the status file, if the registry publishes one, or
+// removes it if there are no tunnels.
+func (r *Registry) publish() {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	r.mu.RLock()
+	path := r.publishPath
+	status := &ProcessStatus{PID: os.Getpid(), Updated: time.Now(), Tunnels: make([]*TunnelStatus, 0, len(r.tunnels))}
+	for _, t := range r.tunnels {
+		snapshot := *t
+		snapshot.Uptime = time.Since(t.StartTime)
+		status.Tunnels = append(status.Tunnels, &snapshot)
+	}
+	r.mu.RUnlock()
+
+	if path == "" {
+		return
+	}
+	if len(status.Tunnels) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Debug().Err(err).Msg("Failed to remove tunnel status file")
+		}
+		return
+	}
+	sortTunnels(status.Tunnels)
+	if err := writeStatusFile(path, status); err != nil {
+		log.Debug().Err(err).Msg("Failed to write tunnel status file")
+	}
+}
+
+func writeStatusFile(path string, status *ProcessStatus) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so a concurrent reader never sees a partial file
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// ReadPublished returns the status published by every tunatap process with
+// tunnels to dir, ordered by PID. Files not updated for a while, left
+// behind by processes that did not exit cleanly, are removed.
+func ReadPublished(dir string) ([]*ProcessStatus, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tunnel status: %w", err)
+	}
+
+	var statuses []*ProcessStatus
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// Removed by its process since the directory was read
+			continue
+		}
+		var status ProcessStatus
+		if err := json.Unmarshal(data, &status); err != nil {
+			log.Debug().Err(err).Msgf("Ignoring tunnel status file %s", path)
+			continue
+		}
+		if time.Since(status.Updated) > publishStale {
+			os.Remove(path)
+			continue
+		}
+		statuses = append(statuses, &status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].PID < statuses[j].PID })
+	return statuses, nil
+}
@@ -0,0 +1,143 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Publish(t *testing.T) {
+	dir := t.TempDir()
+	r := &Registry{
+		tunnels:   make(map[string]*TunnelStatus),
+		startTime: time.Now(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r.Publish(ctx, dir)
+	if statuses, err := ReadPublished(dir); err != nil || len(statuses) != 0 {
+		t.Fatalf("ReadPublished() with no tunnels = %v, %v, want none", statuses, err)
+	}
+
+	r.Register(&TunnelStatus{ID: "t1", Cluster: "prod", LocalPort: 6443, Healthy: true, StartTime: time.Now()})
+	r.SetSession("t1", "ocid1.bastionsession.oc1..s")
+
+	statuses, err := ReadPublished(dir)
+	if err != nil {
+		t.Fatalf("ReadPublished() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].PID != os.Getpid() || len(statuses[0].Tunnels) != 1 {
+		t.Fatalf("ReadPublished() = %+v, want this process with one tunnel", statuses)
+	}
+	if tun := statuses[0].Tunnels[0]; tun.Cluster != "prod" || tun.LocalPort != 6443 || !tun.Healthy {
+		t.Errorf("published tunnel = %+v", tun)
+	}
+
+	// The session is published on the next tick
+	r.publish()
+	statuses, _ = ReadPublished(dir)
+	if tun := statuses[0].Tunnels[0]; tun.SessionID != "ocid1.bastionsession.oc1..s" || tun.LastRefresh == nil {
+		t.Errorf("published tunnel = %+v, want its session and refresh time", tun)
+	}
+
+	r.Deregister("t1")
+	if _, err := os.Stat(filepath.Join(dir, strconv.Itoa(os.Getpid())+".json")); !os.IsNotExist(err) {
+		t.Errorf("status file should be removed once there are no tunnels, stat error = %v", err)
+	}
+}
+
+func TestReadPublished_Stale(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, status ProcessStatus) string {
+		data, err := json.Marshal(status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("20.json", ProcessStatus{PID: 20, Updated: time.Now(), Tunnels: []*TunnelStatus{{ID: "b"}}})
+	write("10.json", ProcessStatus{PID: 10, Updated: time.Now(), Tunnels: []*TunnelStatus{{ID: "a"}}})
+	stale := write("30.json", ProcessStatus{PID: 30, Updated: time.Now().Add(-time.Hour), Tunnels: []*TunnelStatus{{ID: "c"}}})
+	os.WriteFile(filepath.Join(dir, "40.json"), []byte("{"), 0o600)
+
+	statuses, err := ReadPublished(dir)
+	if err != nil {
+		t.Fatalf("ReadPublished() error = %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].PID != 10 || statuses[1].PID != 20 {
+		t.Errorf("ReadPublished() = %+v, want PIDs 10 and 20", statuses)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale status file should be removed")
+	}
+
+	if statuses, err := ReadPublished(filepath.Join(dir, "missing")); err != nil || statuses != nil {
+		t.Errorf("ReadPublished() of a missing directory = %v, %v, want nothing", statuses, err)
+	}
+}
+
+func TestRegistry_PublishStops(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, strconv.Itoa(os.Getpid())+".json")
+	r := &Registry{
+		tunnels:   make(map[string]*TunnelStatus),
+		startTime: time.Now(),
+	}
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+
+	r.Publish(first, dir)
+	r.Publish(second, dir)
+
+	// Tunnels registering at once each write the file whole
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Register(&TunnelStatus{ID: strconv.Itoa(i), Healthy: true})
+		}()
+	}
+	wg.Wait()
+	if statuses, err := ReadPublished(dir); err != nil || len(statuses) != 1 || len(statuses[0].Tunnels) != 8 {
+		t.Fatalf("ReadPublished() = %+v, %v, want this process with 8 tunnels", statuses, err)
+	}
+
+	cancelFirst()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("status file should be kept while a tunnel still publishes, stat error = %v", err)
+	}
+
+	cancelSecond()
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status file should be removed once every tunnel stopped publishing, stat error = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.publish()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("publish() after publishing stopped wrote the status file, stat error = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("publishing left %d files behind", len(entries))
+	}
+}
@@ -0,0 +1,121 @@
+// Package hooks runs user-configured commands at points in a tunnel's
+// lifecycle, describing the session to them in environment variables.
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// Event is the point in a tunnel's lifecycle a hook runs at.
+type Event string
+
+const (
+	// Connect runs when a tunnel is about to start listening, after its
+	// bastion session has been obtained.
+	Connect Event = "connect"
+
+	// Ready runs each time the tunnel starts accepting connections.
+	Ready Event = "ready"
+
+	// Refresh runs when the bastion session is replaced by a new one.
+	Refresh Event = "refresh"
+
+	// Disconnect runs when a tunnel that ran Connect stops, whether for
+	// good or before a reconnect.
+	Disconnect Event = "disconnect"
+)
+
+// Timeout bounds how long a hook may run before it is killed.
+const Timeout = 30 * time.Second
+
+// Env describes a tunnel and its session to a hook.
+type Env struct {
+	Cluster      string
+	Region       string
+	BastionID    string
+	SessionID    string
+	LocalAddress string
+	LocalPort    int
+	LocalSocket  string
+	RemoteHost   string
+	RemotePort   int
+	// Error is why the tunnel stopped, for Disconnect.
+	Error string
+}
+
+// Environ returns the environment variables describing e to a hook for
+// event. Unset values are left out.
+func (e Env) Environ(event Event) []string {
+	vars := []string{"TUNATAP_EVENT=" + string(event)}
+	add := func(name, value string) {
+		if value != "" {
+			vars = append(vars, fmt.Sprintf("TUNATAP_%s=%s", name, value))
+		}
+	}
+	addPort := func(name string, port int) {
+		if port != 0 {
+			add(name, strconv.Itoa(port))
+		}
+	}
+
+	add("CLUSTER", e.Cluster)
+	add("REGION", e.Region)
+	add("BASTION_ID", e.BastionID)
+	add("SESSION_ID", e.SessionID)
+	add("LOCAL_ADDRESS", e.LocalAddress)
+	addPort("LOCAL_PORT", e.LocalPort)
+	add("LOCAL_SOCKET", e.LocalSocket)
+	add("REMOTE_HOST", e.RemoteHost)
+	addPort("REMOTE_PORT", e.RemotePort)
+	add("ERROR", e.Error)
+	return vars
+}
+
+// Run runs command for event through the shell, with the environment
+// describing env, and waits for it to finish or Timeout to pass. Hooks
+// cannot stop a tunnel, so failures are only logged. An empty command does
+// nothing.
+func Run(command string, event Event, env Env) {
+	if command == "" {
+		return
+	}
+
+	// Not tied to the tunnel's context, so disconnect hooks still run
+	// while the tunnel shuts down
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(), env.Environ(event)...)
+
+	log.Debug().Msgf("Running %s hook: %s", event, command)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Warn().Msgf("The %s hook was killed after %s", event, Timeout)
+		return
+	}
+	if err != nil {
+		log.Warn().Err(err).Msgf("The %s hook failed: %s", event, output)
+		return
+	}
+	if len(output) > 0 {
+		log.Debug().Msgf("The %s hook printed: %s", event, output)
+	}
+}
+
+// shellCommand returns a command that runs command through the platform's
+// shell.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if utils.IsWindows() {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
@@ -0,0 +1,60 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEnviron(t *testing.T) {
+	env := Env{
+		Cluster:      "prod",
+		SessionID:    "ocid1.bastionsession.oc1..abc",
+		LocalAddress: "localhost",
+		LocalPort:    6443,
+		RemoteHost:   "10.0.1.100",
+		RemotePort:   6443,
+	}
+
+	got := env.Environ(Ready)
+	for _, want := range []string{
+		"TUNATAP_EVENT=ready",
+		"TUNATAP_CLUSTER=prod",
+		"TUNATAP_SESSION_ID=ocid1.bastionsession.oc1..abc",
+		"TUNATAP_LOCAL_PORT=6443",
+		"TUNATAP_REMOTE_HOST=10.0.1.100",
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("Environ() = %v, missing %s", got, want)
+		}
+	}
+	for _, v := range got {
+		if strings.HasPrefix(v, "TUNATAP_ERROR=") || strings.HasPrefix(v, "TUNATAP_LOCAL_SOCKET=") {
+			t.Errorf("Environ() includes unset %s", v)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	out := filepath.Join(t.TempDir(), "hook.out")
+	Run(`echo "$TUNATAP_EVENT $TUNATAP_CLUSTER $TUNATAP_LOCAL_PORT" > `+out, Connect, Env{Cluster: "prod", LocalPort: 6443})
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "connect prod 6443" {
+		t.Errorf("hook saw %q, want %q", strings.TrimSpace(string(got)), "connect prod 6443")
+	}
+
+	// Failures are logged, not returned
+	Run("exit 3", Disconnect, Env{})
+	Run("", Ready, Env{})
+}
@@ -0,0 +1,289 @@
+// Package importer converts cluster definitions from other tools, such as
+// the OCI CLI, Terraform state and kubeconfig files, into config clusters.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/kubeconfig"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// EndpointName is the name given to imported private endpoints.
+const EndpointName = "private"
+
+// ociCluster is a cluster as printed by "oci ce cluster list" and
+// "oci ce cluster get".
+type ociCluster struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	CompartmentID  string `json:"compartment-id"`
+	LifecycleState string `json:"lifecycle-state"`
+	Endpoints      *struct {
+		PrivateEndpoint string `json:"private-endpoint"`
+	} `json:"endpoints"`
+}
+
+// FromOCICLI converts the JSON output of "oci ce cluster list" or
+// "oci ce cluster get". Deleted clusters are left out.
+func FromOCICLI(data []byte) ([]*config.Cluster, error) {
+	var out struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("invalid OCI CLI output: %w", err)
+	}
+
+	var list []ociCluster
+	if err := json.Unmarshal(out.Data, &list); err != nil {
+		var one ociCluster
+		if err := json.Unmarshal(out.Data, &one); err != nil {
+			return nil, fmt.Errorf("invalid OCI CLI output: expected cluster data")
+		}
+		list = []ociCluster{one}
+	}
+
+	var clusters []*config.Cluster
+	for _, c := range list {
+		if c.LifecycleState == "DELETED" || c.LifecycleState == "DELETING" {
+			continue
+		}
+		var endpoint string
+		if c.Endpoints != nil {
+			endpoint = c.Endpoints.PrivateEndpoint
+		}
+		cluster, err := newCluster(c.Name, c.ID, c.CompartmentID, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+// tfState is the part of a Terraform state file (format version 4) that
+// describes resources.
+type tfState struct {
+	Version   int `json:"version"`
+	Resources []struct {
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Instances []struct {
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// tfCluster holds the attributes of an oci_containerengine_cluster.
+type tfCluster struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	CompartmentID string `json:"compartment_id"`
+	Endpoints     []struct {
+		PrivateEndpoint string `json:"private_endpoint"`
+	} `json:"endpoints"`
+	EndpointConfig []struct {
+		SubnetID string `json:"subnet_id"`
+	} `json:"endpoint_config"`
+}
+
+// tfBastion holds the attributes of an oci_bastion_bastion.
+type tfBastion struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	TargetSubnetID string `json:"target_subnet_id"`
+}
+
+// FromTerraformState converts the OKE clusters of a Terraform state file,
+// as written to terraform.tfstate or by "terraform state pull". A cluster
+// whose API endpoint subnet is the target subnet of a bastion in the same
+// state gets that bastion.
+func FromTerraformState(data []byte) ([]*config.Cluster, error) {
+	var state tfState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid Terraform state: %w", err)
+	}
+	if state.Version != 4 {
+		return nil, fmt.Errorf("unsupported Terraform state version %d (expected 4)", state.Version)
+	}
+
+	var tfClusters []tfCluster
+	bastions := make(map[string]tfBastion)
+	for _, r := range state.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		for _, inst := range r.Instances {
+			switch r.Type {
+			case "oci_containerengine_cluster":
+				var c tfCluster
+				if err := json.Unmarshal(inst.Attributes, &c); err != nil {
+					return nil, fmt.Errorf("invalid oci_containerengine_cluster in Terraform state: %w", err)
+				}
+				tfClusters = append(tfClusters, c)
+			case "oci_bastion_bastion":
+				var b tfBastion
+				if err := json.Unmarshal(inst.Attributes, &b); err != nil {
+					return nil, fmt.Errorf("invalid oci_bastion_bastion in Terraform state: %w", err)
+				}
+				if b.TargetSubnetID != "" {
+					bastions[b.TargetSubnetID] = b
+				}
+			}
+		}
+	}
+
+	var clusters []*config.Cluster
+	for _, c := range tfClusters {
+		var endpoint string
+		if len(c.Endpoints) > 0 {
+			endpoint = c.Endpoints[0].PrivateEndpoint
+		}
+		cluster, err := newCluster(c.Name, c.ID, c.CompartmentID, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.EndpointConfig) > 0 {
+			if b, ok := bastions[c.EndpointConfig[0].SubnetID]; ok {
+				cluster.BastionId = utils.StringPtr(b.ID)
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+// FromKubeconfig converts the OKE contexts of a kubeconfig, as printed by
+// "kubectl config view", naming each cluster after its context. A context
+// whose server is on the loopback address, such as one written for a
+// tunatap tunnel, gets that local port instead of an endpoint.
+func FromKubeconfig(data []byte) ([]*config.Cluster, error) {
+	var k kubeconfig.Kubeconfig
+	if err := yaml.Unmarshal(data, &k); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	var clusters []*config.Cluster
+	for _, c := range k.OKEClusters() {
+		var endpoint string
+		var localPort int
+		if u, err := url.Parse(c.Server); err == nil && u.Port() != "" {
+			if ip := net.ParseIP(u.Hostname()); (ip != nil && ip.IsLoopback()) || u.Hostname() == "localhost" {
+				localPort, _ = strconv.Atoi(u.Port())
+			} else {
+				endpoint = u.Host
+			}
+		}
+
+		cluster, err := newCluster(c.Context, c.ClusterID, "", endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if c.Region != "" {
+			cluster.Region = c.Region
+		}
+		if localPort != 0 {
+			cluster.LocalPort = &localPort
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+// newCluster returns a cluster with the region of its OCID and, if endpoint
+// is a host:port, a private endpoint.
+func newCluster(name, ocid, compartmentID, endpoint string) (*config.Cluster, error) {
+	if name == "" {
+		return nil, fmt.Errorf("cluster %s has no name", ocid)
+	}
+
+	c := &config.Cluster{
+		ClusterName: name,
+		Region:      utils.ExtractRegionFromOCID(ocid),
+	}
+	if ocid != "" {
+		c.Ocid = utils.StringPtr(ocid)
+	}
+	if compartmentID != "" {
+		c.CompartmentOcid = utils.StringPtr(compartmentID)
+	}
+
+	if endpoint != "" {
+		host, portStr, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q of cluster %s: %w", endpoint, name, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q of cluster %s", endpoint, name)
+		}
+		ep := &config.ClusterEndpoint{Name: EndpointName, Port: port}
+		if net.ParseIP(host) != nil {
+			ep.Ip = host
+		} else {
+			ep.Fqdn = host
+		}
+		c.Endpoints = []*config.ClusterEndpoint{ep}
+	}
+	return c, nil
+}
+
+// Merge adds the imported clusters to cfg. Clusters already configured
+// under the same name are skipped, or updated if replace is set, keeping
+// local settings such as local_port, hooks and other endpoints. It returns
+// the names of the clusters added, replaced and skipped.
+func Merge(cfg *config.Config, clusters []*config.Cluster, replace bool) (added, replaced, skipped []string) {
+	seen := make(map[string]bool)
+	for _, c := range clusters {
+		key := strings.ToLower(c.ClusterName)
+		if seen[key] {
+			skipped = append(skipped, c.ClusterName)
+			continue
+		}
+		seen[key] = true
+
+		existing := config.FindClusterByName(cfg, c.ClusterName)
+		switch {
+		case existing == nil:
+			cfg.Clusters = append(cfg.Clusters, c)
+			added = append(added, c.ClusterName)
+		case replace:
+			existing.Region = c.Region
+			existing.Ocid = c.Ocid
+			if c.CompartmentOcid != nil {
+				existing.CompartmentOcid = c.CompartmentOcid
+			}
+			if c.BastionId != nil {
+				existing.BastionId = c.BastionId
+			}
+			for _, ep := range c.Endpoints {
+				setEndpoint(existing, ep)
+			}
+			if existing.LocalPort == nil {
+				existing.LocalPort = c.LocalPort
+			}
+			replaced = append(replaced, existing.ClusterName)
+		default:
+			skipped = append(skipped, c.ClusterName)
+		}
+	}
+	return added, replaced, skipped
+}
+
+// setEndpoint adds ep to a cluster, replacing the endpoint of the same name.
+func setEndpoint(c *config.Cluster, ep *config.ClusterEndpoint) {
+	for i, existing := range c.Endpoints {
+		if strings.EqualFold(existing.Name, ep.Name) {
+			c.Endpoints[i] = ep
+			return
+		}
+	}
+	c.Endpoints = append(c.Endpoints, ep)
+}
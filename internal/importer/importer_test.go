@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/config"
+)
+
+func TestFromOCICLI(t *testing.T) {
+	data := []byte(`{
+  "data": [
+    {
+      "id": "ocid1.cluster.oc1.iad.aaaa",
+      "name": "prod",
+      "compartment-id": "ocid1.compartment.oc1..bbbb",
+      "lifecycle-state": "ACTIVE",
+      "endpoints": {"kubernetes": null, "private-endpoint": "10.0.0.5:6443", "public-endpoint": null}
+    },
+    {
+      "id": "ocid1.cluster.oc1.iad.cccc",
+      "name": "old",
+      "lifecycle-state": "DELETED"
+    }
+  ]
+}`)
+
+	clusters, err := FromOCICLI(data)
+	if err != nil {
+		t.Fatalf("FromOCICLI() error = %v", err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("FromOCICLI() = %d clusters, want 1", len(clusters))
+	}
+	c := clusters[0]
+	if c.ClusterName != "prod" || c.Region != "iad" || *c.Ocid != "ocid1.cluster.oc1.iad.aaaa" || *c.CompartmentOcid != "ocid1.compartment.oc1..bbbb" {
+		t.Errorf("cluster = %+v", c)
+	}
+	if len(c.Endpoints) != 1 || c.Endpoints[0].Ip != "10.0.0.5" || c.Endpoints[0].Port != 6443 {
+		t.Errorf("endpoints = %+v", c.Endpoints)
+	}
+
+	// oci ce cluster get prints a single cluster
+	one, err := FromOCICLI([]byte(`{"data": {"id": "ocid1.cluster.oc1.phx.dddd", "name": "dev", "lifecycle-state": "ACTIVE"}}`))
+	if err != nil || len(one) != 1 || one[0].ClusterName != "dev" {
+		t.Errorf("FromOCICLI(get) = %+v, %v", one, err)
+	}
+
+	if _, err := FromOCICLI([]byte(`not json`)); err == nil {
+		t.Error("FromOCICLI() should fail on invalid input")
+	}
+}
+
+func TestFromTerraformState(t *testing.T) {
+	data := []byte(`{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "oci_containerengine_cluster",
+      "name": "prod",
+      "instances": [{"attributes": {
+        "id": "ocid1.cluster.oc1.iad.aaaa",
+        "name": "prod",
+        "compartment_id": "ocid1.compartment.oc1..bbbb",
+        "endpoints": [{"private_endpoint": "10.0.0.5:6443", "public_endpoint": ""}],
+        "endpoint_config": [{"subnet_id": "ocid1.subnet.oc1.iad.api"}]
+      }}]
+    },
+    {
+      "mode": "managed",
+      "type": "oci_bastion_bastion",
+      "name": "prod",
+      "instances": [{"attributes": {"id": "ocid1.bastion.oc1.iad.eeee", "name": "prod-bastion", "target_subnet_id": "ocid1.subnet.oc1.iad.api"}}]
+    },
+    {
+      "mode": "data",
+      "type": "oci_containerengine_cluster",
+      "name": "other",
+      "instances": [{"attributes": {"id": "ocid1.cluster.oc1.iad.ffff", "name": "other"}}]
+    }
+  ]
+}`)
+
+	clusters, err := FromTerraformState(data)
+	if err != nil {
+		t.Fatalf("FromTerraformState() error = %v", err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("FromTerraformState() = %d clusters, want 1", len(clusters))
+	}
+	c := clusters[0]
+	if c.ClusterName != "prod" || c.BastionId == nil || *c.BastionId != "ocid1.bastion.oc1.iad.eeee" {
+		t.Errorf("cluster = %+v", c)
+	}
+	if len(c.Endpoints) != 1 || c.Endpoints[0].Ip != "10.0.0.5" {
+		t.Errorf("endpoints = %+v", c.Endpoints)
+	}
+
+	if _, err := FromTerraformState([]byte(`{"version": 3}`)); err == nil {
+		t.Error("FromTerraformState() should reject old state versions")
+	}
+}
+
+func TestFromKubeconfig(t *testing.T) {
+	data := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster-prod
+  cluster:
+    server: https://10.0.0.5:6443
+- name: cluster-tunnel
+  cluster:
+    server: https://127.0.0.1:16443
+- name: cluster-kind
+  cluster:
+    server: https://127.0.0.1:41000
+contexts:
+- name: prod
+  context: {cluster: cluster-prod, user: user-prod}
+- name: prod-tunnel
+  context: {cluster: cluster-tunnel, user: user-prod}
+- name: kind
+  context: {cluster: cluster-kind, user: kind}
+users:
+- name: user-prod
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: oci
+      args: [ce, cluster, generate-token, --cluster-id, ocid1.cluster.oc1.iad.aaaa, --region, us-ashburn-1]
+- name: kind
+  user:
+    token: abc
+`)
+
+	clusters, err := FromKubeconfig(data)
+	if err != nil {
+		t.Fatalf("FromKubeconfig() error = %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("FromKubeconfig() = %d clusters, want 2", len(clusters))
+	}
+	if c := clusters[0]; c.ClusterName != "prod" || c.Region != "us-ashburn-1" || len(c.Endpoints) != 1 || c.Endpoints[0].Ip != "10.0.0.5" {
+		t.Errorf("prod = %+v", c)
+	}
+	if c := clusters[1]; c.ClusterName != "prod-tunnel" || len(c.Endpoints) != 0 || c.LocalPort == nil || *c.LocalPort != 16443 {
+		t.Errorf("prod-tunnel = %+v", c)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	port := 6443
+	cfg := &config.Config{Clusters: []*config.Cluster{{
+		ClusterName: "prod",
+		Region:      "us-ashburn-1",
+		LocalPort:   &port,
+		Endpoints:   []*config.ClusterEndpoint{{Name: "metrics", Ip: "10.0.0.9", Port: 9090}},
+	}}}
+	imported := []*config.Cluster{
+		{ClusterName: "prod", Region: "us-phoenix-1", Endpoints: []*config.ClusterEndpoint{{Name: EndpointName, Ip: "10.0.0.5", Port: 6443}}},
+		{ClusterName: "dev", Region: "us-phoenix-1"},
+		{ClusterName: "DEV", Region: "us-ashburn-1"},
+	}
+
+	added, replaced, skipped := Merge(cfg, imported, false)
+	if len(added) != 1 || added[0] != "dev" || len(replaced) != 0 || len(skipped) != 2 {
+		t.Errorf("Merge() = %v, %v, %v", added, replaced, skipped)
+	}
+	if cfg.Clusters[0].Region != "us-ashburn-1" {
+		t.Error("existing cluster changed without replace")
+	}
+
+	cfg.Clusters = cfg.Clusters[:1]
+	_, replaced, _ = Merge(cfg, imported[:1], true)
+	if len(replaced) != 1 {
+		t.Fatalf("replaced = %v", replaced)
+	}
+	c := cfg.Clusters[0]
+	if c.Region != "us-phoenix-1" || *c.LocalPort != 6443 || len(c.Endpoints) != 2 {
+		t.Errorf("replaced cluster = %+v", c)
+	}
+}
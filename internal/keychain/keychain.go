@@ -0,0 +1,24 @@
+// Package keychain keeps secrets in the OS keychain: the macOS login
+// keychain, the Secret Service (through secret-tool) on Linux, or files
+// protected with DPAPI on Windows. Secrets are named by a service and an
+// account.
+package keychain
+
+import "errors"
+
+// ErrNotFound is returned by a Keychain that holds no such secret.
+var ErrNotFound = errors.New("secret not found in keychain")
+
+// Keychain stores secrets.
+type Keychain interface {
+	// Get returns the secret stored for service and account, or
+	// ErrNotFound.
+	Get(service, account string) (string, error)
+	// Set stores secret for service and account, replacing any other.
+	Set(service, account, secret string) error
+}
+
+// System returns the OS keychain.
+func System() Keychain {
+	return systemKeychain{}
+}
@@ -0,0 +1,36 @@
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// systemKeychain keeps secrets in the login keychain with the security
+// command.
+type systemKeychain struct{}
+
+// errSecItemNotFound is the exit status of security when there is no such
+// item.
+const errSecItemNotFound = 44
+
+func (systemKeychain) Get(service, account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to read keychain: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (systemKeychain) Set(service, account, secret string) error {
+	out, err := exec.Command("security", "add-generic-password", "-U", "-s", service, "-a", account, "-w", secret).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("security add-generic-password: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build !darwin && !windows
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// systemKeychain keeps secrets in the Secret Service, such as GNOME Keyring
+// or KWallet, with secret-tool.
+type systemKeychain struct{}
+
+func (systemKeychain) Get(service, account string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+			// secret-tool fails silently when there is no such secret
+			return "", ErrNotFound
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("secret-tool not found; install libsecret-tools to use the keychain")
+		}
+		return "", fmt.Errorf("failed to read keychain: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (systemKeychain) Set(service, account, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", service+" "+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("secret-tool not found; install libsecret-tools to use the keychain")
+		}
+		return fmt.Errorf("secret-tool store: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
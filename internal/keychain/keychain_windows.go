@@ -0,0 +1,62 @@
+package keychain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/scotttball/tunatap/pkg/utils"
+	"golang.org/x/sys/windows"
+)
+
+// systemKeychain keeps each secret in a file in the tunatap directory,
+// protected with DPAPI so that only the current Windows user can read it.
+type systemKeychain struct{}
+
+// fileNameReplacer replaces the characters of service and account names
+// that cannot be in a file name.
+var fileNameReplacer = strings.NewReplacer("/", "_", "\\", "_", ":", "_")
+
+func keyFilePath(service, account string) string {
+	return filepath.Join(utils.DefaultTunatapDir(), fileNameReplacer.Replace(service+"-"+account)+".dpapi")
+}
+
+func (systemKeychain) Get(service, account string) (string, error) {
+	protected, err := os.ReadFile(keyFilePath(service, account))
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(newBlob(protected), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return "", fmt.Errorf("failed to unprotect secret: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return string(unsafe.Slice(out.Data, out.Size)), nil
+}
+
+func (systemKeychain) Set(service, account, secret string) error {
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(newBlob([]byte(secret)), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return fmt.Errorf("failed to protect secret: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	path := keyFilePath(service, account)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, unsafe.Slice(out.Data, out.Size), 0600)
+}
+
+func newBlob(data []byte) *windows.DataBlob {
+	if len(data) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+}
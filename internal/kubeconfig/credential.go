@@ -0,0 +1,47 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ExecCredentialAPIVersion is the client.authentication.k8s.io version of
+// the ExecCredentials tunatap returns when kubectl does not ask for one.
+const ExecCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// ExecCredential is the response of an exec credential plugin, written to
+// stdout for kubectl to authenticate with.
+type ExecCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+// ExecCredentialStatus holds the token of an ExecCredential.
+type ExecCredentialStatus struct {
+	Token string `json:"token"`
+	// ExpirationTimestamp is when kubectl runs the plugin again, in RFC 3339.
+	ExpirationTimestamp string `json:"expirationTimestamp"`
+}
+
+// NewExecCredential returns the ExecCredential of a bearer token that
+// expires at expires. execInfo is the KUBERNETES_EXEC_INFO kubectl passes
+// the plugin; the credential has the API version it asks for, or
+// ExecCredentialAPIVersion if it asks for none.
+func NewExecCredential(token string, expires time.Time, execInfo string) *ExecCredential {
+	apiVersion := ExecCredentialAPIVersion
+	var info struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if execInfo != "" && json.Unmarshal([]byte(execInfo), &info) == nil && info.APIVersion != "" {
+		apiVersion = info.APIVersion
+	}
+	return &ExecCredential{
+		APIVersion: apiVersion,
+		Kind:       "ExecCredential",
+		Status: ExecCredentialStatus{
+			Token:               token,
+			ExpirationTimestamp: expires.UTC().Format(time.RFC3339),
+		},
+	}
+}
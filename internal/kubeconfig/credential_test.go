@@ -0,0 +1,29 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewExecCredential(t *testing.T) {
+	expires := time.Date(2026, 3, 1, 12, 4, 0, 0, time.FixedZone("CET", 3600))
+
+	cred := NewExecCredential("abc", expires, "")
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{"token":"abc","expirationTimestamp":"2026-03-01T11:04:00Z"}}`
+	if string(data) != want {
+		t.Errorf("ExecCredential = %s, want %s", data, want)
+	}
+
+	cred = NewExecCredential("abc", expires, `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","spec":{"interactive":false}}`)
+	if cred.APIVersion != "client.authentication.k8s.io/v1" {
+		t.Errorf("APIVersion = %q, want the version of KUBERNETES_EXEC_INFO", cred.APIVersion)
+	}
+	if cred = NewExecCredential("abc", expires, "not json"); cred.APIVersion != ExecCredentialAPIVersion {
+		t.Errorf("APIVersion for bad exec info = %q, want the default", cred.APIVersion)
+	}
+}
@@ -2,8 +2,10 @@ package kubeconfig
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -30,6 +32,7 @@ type ClusterConfig struct {
 	Server                   string `yaml:"server"`
 	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify,omitempty"`
 	CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
+	TLSServerName            string `yaml:"tls-server-name,omitempty"`
 }
 
 // ContextEntry represents a context configuration.
@@ -158,13 +161,30 @@ func (k *Kubeconfig) AddUserWithExec(name, command string, args []string) {
 
 // AddOCIUser adds a user configured for OCI OKE authentication.
 func (k *Kubeconfig) AddOCIUser(name, clusterID, region string) {
-	k.AddOCIUserWithProfile(name, clusterID, region, "")
+	k.AddOCIUserWithAuth(name, clusterID, region, "", "")
 }
 
+// TokenCommand is the command run by the users AddOCIUser adds, as
+// "TokenCommand token --cluster-id ...", to get tokens for OKE clusters.
+// Set it to the path of the tunatap binary when kubectl may not find
+// tunatap on its PATH.
+var TokenCommand = "tunatap"
+
+// TokenConfigArgs are added to the token command of the users AddOCIUser
+// adds, such as "--config" and "--config-profile", so that it reads the
+// config the kubeconfig was generated from.
+var TokenConfigArgs []string
+
 // AddOCIUserWithProfile adds a user configured for OCI OKE authentication with a specific profile.
 func (k *Kubeconfig) AddOCIUserWithProfile(name, clusterID, region, profile string) {
+	k.AddOCIUserWithAuth(name, clusterID, region, profile, "")
+}
+
+// AddOCIUserWithAuth adds a user configured for OCI OKE authentication with
+// a specific profile and OCI authentication type.
+func (k *Kubeconfig) AddOCIUserWithAuth(name, clusterID, region, profile, authType string) {
 	args := []string{
-		"ce", "cluster", "generate-token",
+		"token",
 		"--cluster-id", clusterID,
 		"--region", region,
 	}
@@ -172,14 +192,19 @@ func (k *Kubeconfig) AddOCIUserWithProfile(name, clusterID, region, profile stri
 	if profile != "" {
 		args = append(args, "--profile", profile)
 	}
+	if authType != "" {
+		args = append(args, "--auth-type", authType)
+	}
+	args = append(args, TokenConfigArgs...)
 
 	k.Users = append(k.Users, UserEntry{
 		Name: name,
 		User: UserConfig{
 			Exec: &ExecConfig{
-				APIVersion: "client.authentication.k8s.io/v1beta1",
-				Command:    "oci",
-				Args:       args,
+				APIVersion:      ExecCredentialAPIVersion,
+				Command:         TokenCommand,
+				Args:            args,
+				InteractiveMode: "Never",
 			},
 		},
 	})
@@ -192,6 +217,7 @@ type OCIKubeconfigOptions struct {
 	Region      string
 	Endpoint    string // The API server endpoint (e.g., https://localhost:6443)
 	Profile     string // OCI config profile
+	AuthType    string // OCI authentication type
 	Namespace   string // Default namespace
 	CAData      string // Certificate authority data (base64 encoded)
 }
@@ -201,7 +227,7 @@ type OCIKubeconfigOptions struct {
 func NewOCIKubeconfig(opts OCIKubeconfigOptions) *Kubeconfig {
 	k := NewKubeconfig()
 
-	contextName := fmt.Sprintf("tuna-%s", opts.ClusterName)
+	contextName := ContextName("", opts.ClusterName, opts.Region)
 	clusterName := contextName
 	userName := contextName
 
@@ -213,7 +239,7 @@ func NewOCIKubeconfig(opts OCIKubeconfigOptions) *Kubeconfig {
 	}
 
 	// Add user with OCI exec-auth
-	k.AddOCIUserWithProfile(userName, opts.ClusterID, opts.Region, opts.Profile)
+	k.AddOCIUserWithAuth(userName, opts.ClusterID, opts.Region, opts.Profile, opts.AuthType)
 
 	// Add context
 	if opts.Namespace != "" {
@@ -228,28 +254,112 @@ func NewOCIKubeconfig(opts OCIKubeconfigOptions) *Kubeconfig {
 	return k
 }
 
+// DefaultContextName is the template of the names of the contexts tunatap
+// writes.
+const DefaultContextName = "tuna-{cluster}"
+
+// ContextName returns the context name template gives a cluster, with
+// "{cluster}" and "{region}" replaced. An empty template is
+// DefaultContextName.
+func ContextName(template, clusterName, region string) string {
+	if template == "" {
+		template = DefaultContextName
+	}
+	return strings.NewReplacer("{cluster}", clusterName, "{region}", region).Replace(template)
+}
+
+// TunnelOptions describe a kubeconfig for tunneled access to a cluster on
+// localhost.
+type TunnelOptions struct {
+	ClusterName string
+	Region      string
+	Port        int
+
+	// ContextName is the template of the context name, see ContextName.
+	ContextName string
+	// Namespace is the default namespace of the context.
+	Namespace string
+
+	// ClusterID enables OCI exec-auth for the cluster, with the OCI config
+	// profile Profile and authentication type AuthType. Without it the
+	// context has no user.
+	ClusterID string
+	Profile   string
+	AuthType  string
+
+	// CAData verifies the API server with this certificate authority
+	// (base64 encoded), expecting a certificate issued for TLSServerName as
+	// it is not issued for localhost. Without it TLS verification is
+	// skipped.
+	CAData        string
+	TLSServerName string
+}
+
+// NewTunnelKubeconfig creates a kubeconfig for tunneled access to a cluster.
+func NewTunnelKubeconfig(opts TunnelOptions) *Kubeconfig {
+	k := NewKubeconfig()
+
+	contextName := ContextName(opts.ContextName, opts.ClusterName, opts.Region)
+	server := fmt.Sprintf("https://localhost:%d", opts.Port)
+	if opts.CAData != "" {
+		k.AddClusterWithCA(contextName, server, opts.CAData)
+		k.Clusters[0].Cluster.TLSServerName = opts.TLSServerName
+	} else {
+		k.AddCluster(contextName, server, true)
+	}
+
+	userName := ""
+	if opts.ClusterID != "" {
+		userName = contextName
+		k.AddOCIUserWithAuth(userName, opts.ClusterID, opts.Region, opts.Profile, opts.AuthType)
+	}
+	k.AddContextWithNamespace(contextName, contextName, userName, opts.Namespace)
+	k.SetCurrentContext(contextName)
+
+	return k
+}
+
 // NewOCIKubeconfigForTunnel creates a kubeconfig for tunneled access to an OCI OKE cluster.
 // Uses localhost endpoint with the tunnel port and OCI exec-auth for token generation.
 func NewOCIKubeconfigForTunnel(clusterName, clusterID, region string, port int, profile string) *Kubeconfig {
-	return NewOCIKubeconfig(OCIKubeconfigOptions{
+	return NewTunnelKubeconfig(TunnelOptions{
 		ClusterName: clusterName,
 		ClusterID:   clusterID,
 		Region:      region,
-		Endpoint:    fmt.Sprintf("https://localhost:%d", port),
+		Port:        port,
 		Profile:     profile,
 	})
 }
 
 // NewInsecureKubeconfig creates a simple kubeconfig without OCI auth (for testing/development).
 func NewInsecureKubeconfig(clusterName string, port int) *Kubeconfig {
-	k := NewKubeconfig()
-
-	contextName := fmt.Sprintf("tuna-%s", clusterName)
-	k.AddCluster(contextName, fmt.Sprintf("https://localhost:%d", port), true)
-	k.AddContext(contextName, contextName, "")
-	k.SetCurrentContext(contextName)
+	return NewTunnelKubeconfig(TunnelOptions{ClusterName: clusterName, Port: port})
+}
 
-	return k
+// ClusterCA returns the certificate authority data of the current
+// context's cluster in k, and the host of its server, for which the
+// server's certificate is issued.
+func (k *Kubeconfig) ClusterCA() (caData, serverName string, err error) {
+	clusterName := ""
+	for _, ctx := range k.Contexts {
+		if ctx.Name == k.CurrentContext {
+			clusterName = ctx.Context.Cluster
+		}
+	}
+	for _, c := range k.Clusters {
+		if c.Name != clusterName && clusterName != "" {
+			continue
+		}
+		if c.Cluster.CertificateAuthorityData == "" {
+			break
+		}
+		u, err := url.Parse(c.Cluster.Server)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid server '%s' in kubeconfig: %w", c.Cluster.Server, err)
+		}
+		return c.Cluster.CertificateAuthorityData, u.Hostname(), nil
+	}
+	return "", "", fmt.Errorf("kubeconfig has no certificate authority data")
 }
 
 // SetCurrentContext sets the current context.
@@ -292,7 +402,11 @@ func LoadFromFile(path string) (*Kubeconfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
 	}
+	return Parse(data)
+}
 
+// Parse parses a kubeconfig.
+func Parse(data []byte) (*Kubeconfig, error) {
 	var k Kubeconfig
 	if err := yaml.Unmarshal(data, &k); err != nil {
 		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
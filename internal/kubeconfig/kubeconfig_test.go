@@ -152,14 +152,14 @@ func TestAddOCIUser(t *testing.T) {
 		t.Fatal("Exec should not be nil")
 	}
 
-	if user.User.Exec.Command != "oci" {
-		t.Errorf("Command = %q, want %q", user.User.Exec.Command, "oci")
+	if user.User.Exec.Command != "tunatap" {
+		t.Errorf("Command = %q, want %q", user.User.Exec.Command, "tunatap")
 	}
 
 	// Check args contain expected values
 	argsStr := strings.Join(user.User.Exec.Args, " ")
-	if !strings.Contains(argsStr, "ce cluster generate-token") {
-		t.Errorf("Args should contain 'ce cluster generate-token', got %q", argsStr)
+	if !strings.HasPrefix(argsStr, "token ") {
+		t.Errorf("Args should start with 'token', got %q", argsStr)
 	}
 
 	if !strings.Contains(argsStr, "--cluster-id ocid1.cluster.oc1.iad.test") {
@@ -182,6 +182,27 @@ func TestAddOCIUserWithProfile(t *testing.T) {
 	}
 }
 
+func TestAddOCIUserWithAuth(t *testing.T) {
+	defer func(args []string) { TokenConfigArgs = args }(TokenConfigArgs)
+	TokenConfigArgs = []string{"--config", "/etc/tunatap/work.yaml", "--config-profile", "work"}
+
+	k := NewTunnelKubeconfig(TunnelOptions{
+		ClusterName: "prod",
+		Region:      "us-ashburn-1",
+		Port:        6443,
+		ClusterID:   "ocid1.cluster.oc1.iad.test",
+		Profile:     "my-profile",
+		AuthType:    "instance_principal",
+	})
+
+	got := strings.Join(k.Users[0].User.Exec.Args, " ")
+	want := "token --cluster-id ocid1.cluster.oc1.iad.test --region us-ashburn-1 --profile my-profile " +
+		"--auth-type instance_principal --config /etc/tunatap/work.yaml --config-profile work"
+	if got != want {
+		t.Errorf("Args = %q, want %q", got, want)
+	}
+}
+
 func TestNewOCIKubeconfig(t *testing.T) {
 	k := NewOCIKubeconfig(OCIKubeconfigOptions{
 		ClusterName: "my-cluster",
@@ -218,8 +239,8 @@ func TestNewOCIKubeconfig(t *testing.T) {
 		t.Fatal("User should have exec config")
 	}
 
-	if k.Users[0].User.Exec.Command != "oci" {
-		t.Errorf("Exec command = %q, want %q", k.Users[0].User.Exec.Command, "oci")
+	if k.Users[0].User.Exec.Command != "tunatap" {
+		t.Errorf("Exec command = %q, want %q", k.Users[0].User.Exec.Command, "tunatap")
 	}
 
 	// Check current context
@@ -275,6 +296,80 @@ func TestNewInsecureKubeconfig(t *testing.T) {
 	}
 }
 
+func TestContextName(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"", "tuna-prod"},
+		{"{cluster}", "prod"},
+		{"oke-{region}-{cluster}", "oke-us-ashburn-1-prod"},
+	}
+	for _, tt := range tests {
+		if got := ContextName(tt.template, "prod", "us-ashburn-1"); got != tt.want {
+			t.Errorf("ContextName(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestNewTunnelKubeconfig(t *testing.T) {
+	k := NewTunnelKubeconfig(TunnelOptions{
+		ClusterName:   "prod",
+		Region:        "us-ashburn-1",
+		Port:          7443,
+		ContextName:   "oke-{cluster}",
+		Namespace:     "payments",
+		ClusterID:     "ocid1.cluster.oc1.iad.test",
+		Profile:       "PROD",
+		CAData:        "base64-ca-data",
+		TLSServerName: "10.0.0.5",
+	})
+
+	c := k.Clusters[0]
+	if c.Name != "oke-prod" || c.Cluster.Server != "https://localhost:7443" {
+		t.Errorf("cluster = %+v", c)
+	}
+	if c.Cluster.InsecureSkipTLSVerify || c.Cluster.CertificateAuthorityData != "base64-ca-data" || c.Cluster.TLSServerName != "10.0.0.5" {
+		t.Errorf("cluster TLS = %+v, want the CA verifying 10.0.0.5", c.Cluster)
+	}
+	ctx := k.Contexts[0]
+	if k.CurrentContext != "oke-prod" || ctx.Context.Namespace != "payments" || ctx.Context.User != "oke-prod" {
+		t.Errorf("context = %+v, current %q", ctx, k.CurrentContext)
+	}
+	if args := strings.Join(k.Users[0].User.Exec.Args, " "); !strings.Contains(args, "--profile PROD") {
+		t.Errorf("exec args = %q, want the profile", args)
+	}
+}
+
+func TestClusterCA(t *testing.T) {
+	data := []byte(`apiVersion: v1
+kind: Config
+current-context: context-abc
+clusters:
+- name: cluster-abc
+  cluster:
+    server: https://10.0.0.5:6443
+    certificate-authority-data: LS0tLS1CRUdJTg==
+contexts:
+- name: context-abc
+  context:
+    cluster: cluster-abc
+    user: user-abc
+`)
+	k, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caData, serverName, err := k.ClusterCA()
+	if err != nil || caData != "LS0tLS1CRUdJTg==" || serverName != "10.0.0.5" {
+		t.Errorf("ClusterCA() = %q, %q, %v", caData, serverName, err)
+	}
+
+	if _, _, err := NewInsecureKubeconfig("prod", 6443).ClusterCA(); err == nil {
+		t.Error("ClusterCA() of a kubeconfig without CA data = nil error")
+	}
+}
+
 func TestToYAML(t *testing.T) {
 	k := NewKubeconfig()
 	k.AddCluster("test", "https://localhost:6443", true)
@@ -396,3 +491,46 @@ func TestMergeKubeconfigsEmpty(t *testing.T) {
 		t.Errorf("CurrentContext = %q, want %q", merged.CurrentContext, "ctx2")
 	}
 }
+
+func TestOKEClusters(t *testing.T) {
+	k := NewKubeconfig()
+	k.AddCluster("cluster-c4abc", "https://10.0.1.10:6443", false)
+	k.AddContext("prod", "cluster-c4abc", "user-c4abc")
+	k.AddOCIUser("user-c4abc", "ocid1.cluster.oc1.iad.abc", "us-ashburn-1")
+
+	k.AddCluster("cluster-d5def", "https://10.0.2.10:6443", false)
+	k.AddContext("context-d5def", "cluster-d5def", "user-d5def")
+	k.AddUserWithExec("user-d5def", "/usr/local/bin/oci", []string{"ce", "cluster", "generate-token", "--cluster-id=ocid1.cluster.oc1.eu-frankfurt-1.def"})
+
+	k.AddCluster("kind", "https://127.0.0.1:6443", false)
+	k.AddContext("kind", "kind", "kind")
+	k.AddUserWithToken("kind", "token")
+
+	k.AddCluster("cluster-e6ghi", "https://10.0.3.10:6443", false)
+	k.AddContext("context-e6ghi", "cluster-e6ghi", "user-e6ghi")
+	k.AddUserWithExec("user-e6ghi", "/opt/homebrew/bin/tunatap", []string{"token", "--cluster-id", "ocid1.cluster.oc1.phx.ghi"})
+
+	clusters := k.OKEClusters()
+	if len(clusters) != 3 {
+		t.Fatalf("OKEClusters() returned %d clusters, want 3", len(clusters))
+	}
+
+	want := OKECluster{Context: "prod", Cluster: "cluster-c4abc", Server: "https://10.0.1.10:6443", ClusterID: "ocid1.cluster.oc1.iad.abc", Region: "us-ashburn-1"}
+	if clusters[0] != want {
+		t.Errorf("clusters[0] = %+v, want %+v", clusters[0], want)
+	}
+	if clusters[1].Region != "eu-frankfurt-1" {
+		t.Errorf("clusters[1].Region = %q, want the region of the cluster OCID", clusters[1].Region)
+	}
+	if clusters[2].ClusterID != "ocid1.cluster.oc1.phx.ghi" {
+		t.Errorf("clusters[2].ClusterID = %q, want that of the tunatap token command", clusters[2].ClusterID)
+	}
+}
+
+func TestDefaultPaths(t *testing.T) {
+	t.Setenv("KUBECONFIG", "/tmp/a"+string(os.PathListSeparator)+"/tmp/b")
+	paths := DefaultPaths()
+	if len(paths) != 2 || paths[0] != "/tmp/a" || paths[1] != "/tmp/b" {
+		t.Errorf("DefaultPaths() = %v, want [/tmp/a /tmp/b]", paths)
+	}
+}
@@ -0,0 +1,294 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// entryLists are the keys of the named entries of a kubeconfig.
+var entryLists = []string{"clusters", "contexts", "users"}
+
+// lockTimeout is how long MergeFile and RemoveFile wait for another
+// writer's lock on a kubeconfig file.
+const lockTimeout = 5 * time.Second
+
+// Merge merges the clusters, contexts and users of k into the kubeconfig
+// data, replacing entries of the same name, and makes k's current context
+// the current one. Everything else in data, including fields this package
+// does not model such as extensions and other auth providers, is kept, so
+// merging the same kubeconfig again changes nothing.
+func Merge(data []byte, k *Kubeconfig) ([]byte, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.Content[0]
+
+	var entries yaml.Node
+	if err := entries.Encode(k); err != nil {
+		return nil, fmt.Errorf("failed to encode kubeconfig: %w", err)
+	}
+	for _, key := range entryLists {
+		list := sequence(root, key)
+		for _, entry := range sequenceOf(&entries, key) {
+			name := entryName(entry)
+			if i := slices.IndexFunc(list.Content, func(n *yaml.Node) bool { return entryName(n) == name }); i >= 0 {
+				list.Content[i] = entry
+			} else {
+				list.Content = append(list.Content, entry)
+			}
+		}
+	}
+	if k.CurrentContext != "" {
+		setScalar(root, "current-context", k.CurrentContext)
+	}
+	return encodeDocument(doc)
+}
+
+// Remove removes the clusters, contexts and users named names from the
+// kubeconfig data, and the current context if it is one of them. It
+// reports whether anything was removed.
+func Remove(data []byte, names []string) ([]byte, bool, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, false, err
+	}
+	root := doc.Content[0]
+
+	removed := false
+	for _, key := range entryLists {
+		list := sequenceOf(root, key)
+		if list == nil {
+			continue
+		}
+		kept := slices.DeleteFunc(list, func(n *yaml.Node) bool {
+			return slices.Contains(names, entryName(n))
+		})
+		if len(kept) != len(list) {
+			removed = true
+			sequence(root, key).Content = kept
+		}
+	}
+	if i := mappingIndex(root, "current-context"); i >= 0 && slices.Contains(names, root.Content[i+1].Value) {
+		root.Content[i+1].Value = ""
+		removed = true
+	}
+	if !removed {
+		return data, false, nil
+	}
+	out, err := encodeDocument(doc)
+	return out, true, err
+}
+
+// MergeFile merges k into the kubeconfig file at path as Merge does,
+// creating the file if it does not exist.
+func MergeFile(path string, k *Kubeconfig) error {
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	merged, err := Merge(data, k)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return writeFile(path, merged)
+}
+
+// RemoveFile removes the entries named names from the kubeconfig file at
+// path as Remove does. A file that does not exist has nothing to remove.
+func RemoveFile(path string, names []string) (bool, error) {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	unlock, err := lockFile(path)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	out, removed, err := Remove(data, names)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if !removed {
+		return false, nil
+	}
+	return true, writeFile(path, out)
+}
+
+// MergePath returns the file of paths, as DefaultPaths returns them, to
+// merge the context contextName into: the first that already defines it,
+// as kubectl does, or else the first.
+func MergePath(paths []string, contextName string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	for _, path := range paths {
+		k, err := LoadFromFile(path)
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(k.Contexts, func(c ContextEntry) bool { return c.Name == contextName }) {
+			return path
+		}
+	}
+	return paths[0]
+}
+
+// parseDocument parses kubeconfig data into a document whose root is a
+// mapping. Empty data is an empty kubeconfig.
+func parseDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+		setScalar(doc.Content[0], "apiVersion", "v1")
+		setScalar(doc.Content[0], "kind", "Config")
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse kubeconfig: not a mapping")
+	}
+	return &doc, nil
+}
+
+// encodeDocument encodes doc with the two-space indent of kubectl.
+func encodeDocument(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode kubeconfig: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode kubeconfig: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// lockFile takes kubectl's lock on the kubeconfig file at path, the file
+// path.lock created exclusively, waiting up to lockTimeout for another
+// writer to release it. The returned func releases the lock.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	lock := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock kubeconfig: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock kubeconfig: %s exists; remove it if no kubectl is running", lock)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// writeFile replaces the file at path with data through a synced temporary
+// file in the same directory, so a crash or a concurrent reader never sees
+// a partly written kubeconfig.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return nil
+}
+
+func mappingIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// sequenceOf returns the entries of the sequence under key in mapping n.
+func sequenceOf(n *yaml.Node, key string) []*yaml.Node {
+	if i := mappingIndex(n, key); i >= 0 && n.Content[i+1].Kind == yaml.SequenceNode {
+		return n.Content[i+1].Content
+	}
+	return nil
+}
+
+// sequence returns the sequence under key in mapping n, adding it, or
+// replacing a null, if there is none.
+func sequence(n *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(n, key); i >= 0 {
+		if v := n.Content[i+1]; v.Kind == yaml.SequenceNode {
+			return v
+		}
+		n.Content[i+1] = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		return n.Content[i+1]
+	}
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, seq)
+	return seq
+}
+
+// setScalar sets the string under key in mapping n.
+func setScalar(n *yaml.Node, key, value string) {
+	if i := mappingIndex(n, key); i >= 0 {
+		n.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+		return
+	}
+	n.Content = append(n.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+// entryName returns the name of a named kubeconfig entry.
+func entryName(n *yaml.Node) string {
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+	if i := mappingIndex(n, "name"); i >= 0 {
+		return n.Content[i+1].Value
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package kubeconfig
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const existingKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+  - name: dev
+    cluster:
+      server: https://dev.example.com
+      extensions:
+        - name: client.authentication.k8s.io/exec
+          extension: {}
+contexts:
+  - name: dev
+    context:
+      cluster: dev
+      user: dev
+users:
+  - name: dev
+    user:
+      client-certificate: /home/me/dev.crt
+      client-key: /home/me/dev.key
+`
+
+func TestMerge(t *testing.T) {
+	k := NewTunnelKubeconfig(TunnelOptions{ClusterName: "prod", Region: "us-ashburn-1", Port: 6443, ClusterID: "ocid1.cluster.oc1.iad.aaaa"})
+
+	merged, err := Merge([]byte(existingKubeconfig), k)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	got := string(merged)
+	// Fields this package does not model are kept
+	for _, want := range []string{"client-certificate: /home/me/dev.crt", "extensions:", "server: https://localhost:6443", "current-context: tuna-prod"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("merged kubeconfig has no %q:\n%s", want, got)
+		}
+	}
+
+	parsed, err := Parse(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Clusters) != 2 || len(parsed.Contexts) != 2 || len(parsed.Users) != 2 {
+		t.Errorf("merged kubeconfig has %d clusters, %d contexts, %d users; want 2 of each", len(parsed.Clusters), len(parsed.Contexts), len(parsed.Users))
+	}
+
+	// Merging again, on another port, replaces the entries
+	k = NewTunnelKubeconfig(TunnelOptions{ClusterName: "prod", Region: "us-ashburn-1", Port: 7443, ClusterID: "ocid1.cluster.oc1.iad.aaaa"})
+	again, err := Merge(merged, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ = Parse(again)
+	if len(parsed.Clusters) != 2 || len(parsed.Contexts) != 2 || len(parsed.Users) != 2 {
+		t.Errorf("merging again left %d clusters, %d contexts, %d users; want 2 of each", len(parsed.Clusters), len(parsed.Contexts), len(parsed.Users))
+	}
+	if server := parsed.Clusters[1].Cluster.Server; server != "https://localhost:7443" {
+		t.Errorf("merged server = %q, want the new port", server)
+	}
+	if same, _ := Merge(again, k); string(same) != string(again) {
+		t.Errorf("Merge() of the same kubeconfig changed it:\n%s", same)
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	merged, err := Merge(nil, NewInsecureKubeconfig("prod", 6443))
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	parsed, err := Parse(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.APIVersion != "v1" || parsed.Kind != "Config" || parsed.CurrentContext != "tuna-prod" || len(parsed.Contexts) != 1 {
+		t.Errorf("Merge() into nothing = %+v", parsed)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	merged, err := Merge([]byte(existingKubeconfig), NewInsecureKubeconfig("prod", 6443))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, removed, err := Remove(merged, []string{"tuna-prod"})
+	if err != nil || !removed {
+		t.Fatalf("Remove() = %v, %v", removed, err)
+	}
+	parsed, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Clusters) != 1 || len(parsed.Contexts) != 1 || parsed.Contexts[0].Name != "dev" {
+		t.Errorf("Remove() left %+v", parsed.Contexts)
+	}
+	if parsed.CurrentContext != "" {
+		t.Errorf("current context = %q, want it unset", parsed.CurrentContext)
+	}
+	if !strings.Contains(string(out), "client-certificate: /home/me/dev.crt") {
+		t.Errorf("Remove() lost other entries:\n%s", out)
+	}
+
+	if _, removed, err := Remove(out, []string{"tuna-prod"}); err != nil || removed {
+		t.Errorf("Remove() again = %v, %v; want nothing removed", removed, err)
+	}
+}
+
+func TestMergeFileAndPath(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	if err := os.WriteFile(second, []byte(existingKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{first, second}
+
+	if got := MergePath(paths, "dev"); got != second {
+		t.Errorf("MergePath() of a defined context = %q, want %q", got, second)
+	}
+	if got := MergePath(paths, "tuna-prod"); got != first {
+		t.Errorf("MergePath() of a new context = %q, want %q", got, first)
+	}
+
+	if err := MergeFile(first, NewInsecureKubeconfig("prod", 6443)); err != nil {
+		t.Fatalf("MergeFile() error = %v", err)
+	}
+	if got := MergePath(paths, "tuna-prod"); got != first {
+		t.Errorf("MergePath() after merging = %q, want %q", got, first)
+	}
+
+	if removed, err := RemoveFile(first, []string{"tuna-prod"}); err != nil || !removed {
+		t.Errorf("RemoveFile() = %v, %v", removed, err)
+	}
+	if removed, err := RemoveFile(filepath.Join(dir, "missing"), []string{"tuna-prod"}); err != nil || removed {
+		t.Errorf("RemoveFile() of a missing file = %v, %v", removed, err)
+	}
+}
+
+func TestMergeFileLocking(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := MergeFile(path, NewInsecureKubeconfig("prod", 6443)); err != nil {
+		t.Fatalf("MergeFile() error = %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("MergeFile() left %d files, want only the kubeconfig", len(entries))
+	}
+
+	// kubectl's lock file holds off writers until it is removed.
+	lock := path + ".lock"
+	if err := os.WriteFile(lock, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.Remove(lock)
+	}()
+	if removed, err := RemoveFile(path, []string{"tuna-prod"}); err != nil || !removed {
+		t.Errorf("RemoveFile() after the lock is released = %v, %v", removed, err)
+	}
+	if _, err := os.Stat(lock); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("RemoveFile() left the lock file: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// OKECluster is a context of a kubeconfig whose user gets tokens from
+// "oci ce cluster generate-token", as in kubeconfigs written by the OCI CLI,
+// or from "tunatap token", as in those written by tunatap.
+type OKECluster struct {
+	// Context is the name of the context.
+	Context string
+	// Cluster is the name of the context's cluster entry.
+	Cluster string
+	// Server is the cluster entry's API server URL.
+	Server string
+	// ClusterID is the OCID passed as --cluster-id.
+	ClusterID string
+	// Region is the region passed as --region, or else that of ClusterID.
+	Region string
+}
+
+// OKEClusters returns the contexts of k that reach OKE clusters, in order.
+func (k *Kubeconfig) OKEClusters() []OKECluster {
+	servers := make(map[string]string, len(k.Clusters))
+	for _, c := range k.Clusters {
+		servers[c.Name] = c.Cluster.Server
+	}
+	users := make(map[string]*ExecConfig, len(k.Users))
+	for _, u := range k.Users {
+		users[u.Name] = u.User.Exec
+	}
+
+	var clusters []OKECluster
+	for _, ctx := range k.Contexts {
+		exec := users[ctx.Context.User]
+		if exec == nil || !isOKETokenCommand(exec) {
+			continue
+		}
+
+		c := OKECluster{
+			Context:   ctx.Name,
+			Cluster:   ctx.Context.Cluster,
+			Server:    servers[ctx.Context.Cluster],
+			ClusterID: flagValue(exec.Args, "--cluster-id"),
+			Region:    flagValue(exec.Args, "--region"),
+		}
+		if c.Region == "" {
+			c.Region = utils.ExtractRegionFromOCID(c.ClusterID)
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters
+}
+
+// isOKETokenCommand reports whether exec runs the token command of the OCI
+// CLI or of tunatap.
+func isOKETokenCommand(exec *ExecConfig) bool {
+	switch strings.TrimSuffix(filepath.Base(exec.Command), ".exe") {
+	case "oci":
+		return strings.Contains(strings.Join(exec.Args, " "), "ce cluster generate-token")
+	case "tunatap":
+		return isTunatapTokenCommand(exec)
+	}
+	return false
+}
+
+// isTunatapTokenCommand reports whether exec runs "tunatap token".
+func isTunatapTokenCommand(exec *ExecConfig) bool {
+	return strings.TrimSuffix(filepath.Base(exec.Command), ".exe") == "tunatap" && len(exec.Args) > 0 && exec.Args[0] == "token"
+}
+
+// flagValue returns the value of flag in args, given as "flag value" or
+// "flag=value".
+func flagValue(args []string, flag string) string {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// DefaultPaths returns the kubeconfig files kubectl reads: those listed in
+// $KUBECONFIG, or else ~/.kube/config.
+func DefaultPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		var paths []string
+		for _, p := range filepath.SplitList(env) {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	}
+
+	home, err := utils.HomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".kube", "config")}
+}
@@ -0,0 +1,93 @@
+package kubeconfig
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TunnelContext is a context of a kubeconfig, written by tunatap, that
+// reaches its cluster through a tunnel on a local port.
+type TunnelContext struct {
+	// Context is the name of the context.
+	Context string
+	// Port is the local port of the context's API server URL.
+	Port int
+}
+
+// TunnelContexts returns the contexts of k, in order, that tunatap wrote
+// for a tunnel: those whose API server is on localhost and which are named
+// "tuna-..." or whose user gets tokens from "tunatap token".
+func (k *Kubeconfig) TunnelContexts() []TunnelContext {
+	servers := make(map[string]string, len(k.Clusters))
+	for _, c := range k.Clusters {
+		servers[c.Name] = c.Cluster.Server
+	}
+	tunatapUsers := make(map[string]bool, len(k.Users))
+	for _, u := range k.Users {
+		if u.User.Exec != nil && isTunatapTokenCommand(u.User.Exec) {
+			tunatapUsers[u.Name] = true
+		}
+	}
+
+	var contexts []TunnelContext
+	for _, ctx := range k.Contexts {
+		port := localPort(servers[ctx.Context.Cluster])
+		if port == 0 {
+			continue
+		}
+		if !strings.HasPrefix(ctx.Name, "tuna-") && !tunatapUsers[ctx.Context.User] {
+			continue
+		}
+		contexts = append(contexts, TunnelContext{Context: ctx.Name, Port: port})
+	}
+	return contexts
+}
+
+// EntryNames returns the names of the contexts and of their clusters and
+// users, for Remove. Clusters and users still used by other contexts of k
+// are left out.
+func (k *Kubeconfig) EntryNames(contexts []string) []string {
+	kept := make(map[string]bool)
+	for _, ctx := range k.Contexts {
+		if !slices.Contains(contexts, ctx.Name) {
+			kept[ctx.Context.Cluster] = true
+			kept[ctx.Context.User] = true
+		}
+	}
+
+	names := slices.Clone(contexts)
+	for _, ctx := range k.Contexts {
+		if !slices.Contains(contexts, ctx.Name) {
+			continue
+		}
+		for _, name := range []string{ctx.Context.Cluster, ctx.Context.User} {
+			if name != "" && !kept[name] && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// localPort returns the port of an https://localhost API server URL, or 0
+// if server is not one.
+func localPort(server string) int {
+	u, err := url.Parse(server)
+	if err != nil {
+		return 0
+	}
+	host := u.Hostname()
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return 0
+		}
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return 0
+	}
+	return port
+}
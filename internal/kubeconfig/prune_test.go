@@ -0,0 +1,42 @@
+package kubeconfig
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTunnelContexts(t *testing.T) {
+	k := NewTunnelKubeconfig(TunnelOptions{ClusterName: "prod", Region: "us-ashburn-1", Port: 6443, ClusterID: "ocid1.cluster.oc1.iad.aaaa"})
+	k.AddCluster("dev-tunnel", "https://127.0.0.1:7443", false)
+	k.AddContext("dev", "dev-tunnel", "dev-user")
+	k.AddOCIUser("dev-user", "ocid1.cluster.oc1.iad.bbbb", "us-ashburn-1")
+	k.AddCluster("tuna-remote", "https://10.0.0.5:6443", false)
+	k.AddContext("tuna-remote", "tuna-remote", "tuna-remote")
+	k.AddCluster("kind", "https://localhost:8443", false)
+	k.AddContext("kind", "kind", "kind")
+	k.AddUserWithToken("kind", "token")
+
+	got := k.TunnelContexts()
+	want := []TunnelContext{{Context: "tuna-prod", Port: 6443}, {Context: "dev", Port: 7443}}
+	if !slices.Equal(got, want) {
+		t.Errorf("TunnelContexts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEntryNames(t *testing.T) {
+	k := NewKubeconfig()
+	k.AddCluster("shared", "https://localhost:6443", false)
+	k.AddContext("tuna-a", "shared", "tuna-a")
+	k.AddContext("tuna-b", "shared", "tuna-b")
+	k.AddUserWithToken("tuna-a", "x")
+	k.AddUserWithToken("tuna-b", "y")
+
+	got := k.EntryNames([]string{"tuna-a"})
+	if !slices.Equal(got, []string{"tuna-a"}) {
+		t.Errorf("EntryNames(tuna-a) = %v, want the context and its user only", got)
+	}
+	got = k.EntryNames([]string{"tuna-a", "tuna-b"})
+	if !slices.Equal(got, []string{"tuna-a", "tuna-b", "shared"}) {
+		t.Errorf("EntryNames(tuna-a, tuna-b) = %v, want the shared cluster too", got)
+	}
+}
@@ -0,0 +1,128 @@
+// Package logfile writes tunatap's own log to a file that is rotated by
+// size, so that the detailed log of a failure is still there when support
+// asks for it, without the user re-running the command with --debug.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Writer appends to a log file, renaming it to path.1 once it grows past a
+// size and starting a new one. Older files move up to path.2 and so on, and
+// the oldest beyond the number kept is removed.
+//
+// Several tunatap processes may write to the same file. Each counts what it
+// wrote, so the file may grow somewhat past the size before one of them
+// rotates it; the others notice it was rotated when they next rotate.
+type Writer struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens the log file at path for appending, creating it and its
+// directory if needed. It is rotated once it grows past maxSize bytes,
+// keeping maxBackups rotated files.
+func Open(path string, maxSize int64, maxBackups int) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	w := &Writer{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the file at w.path and takes its size.
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write appends p to the log file, rotating it first if p would take it
+// past its size. A log file that cannot be rotated is written to anyway.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate moves the log file aside and opens a new one, unless another
+// process rotated it already, in which case it opens the new file of that
+// process.
+func (w *Writer) rotate() error {
+	current, statErr := os.Stat(w.path)
+	ours, _ := w.file.Stat()
+	rotated := statErr == nil && ours != nil && !os.SameFile(current, ours)
+
+	// Open files cannot be renamed on Windows
+	w.file.Close()
+	if !rotated {
+		for i := w.maxBackups - 1; i > 0; i-- {
+			_ = os.Rename(w.backup(i), w.backup(i+1))
+		}
+		if w.maxBackups > 0 {
+			_ = os.Rename(w.path, w.backup(1))
+		} else {
+			_ = os.Remove(w.path)
+		}
+	}
+
+	if err := w.open(); err != nil {
+		w.file = nil
+		return err
+	}
+	if w.size >= w.maxSize {
+		// Another process holds the file open and it could not be moved;
+		// try again once as much has been written
+		w.size = 0
+	}
+	return nil
+}
+
+// backup returns the path of the i-th rotated file.
+func (w *Writer) backup(i int) string {
+	return w.path + "." + strconv.Itoa(i)
+}
+
+// Close closes the log file. Writes after Close fail.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
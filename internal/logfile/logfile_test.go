@@ -0,0 +1,110 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriterRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "tunatap.log")
+	w, err := Open(path, 20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := readFile(t, path); got != "fourth line\n" {
+		t.Errorf("log file = %q, want the last line", got)
+	}
+	if got := readFile(t, path+".1"); got != "third line\n" {
+		t.Errorf("%s.1 = %q", filepath.Base(path), got)
+	}
+	if got := readFile(t, path+".2"); got != "second line\n" {
+		t.Errorf("%s.2 = %q", filepath.Base(path), got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("only 2 rotated files should be kept")
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm()&0o077 != 0 {
+			t.Errorf("log file mode = %v, want it readable by its owner only", info.Mode())
+		}
+	}
+}
+
+func TestWriterAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tunatap.log")
+	if err := os.WriteFile(path, []byte("earlier\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := Open(path, 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("later\n"))
+	w.Close()
+
+	if got := readFile(t, path); got != "earlier\nlater\n" {
+		t.Errorf("log file = %q, want the new line appended", got)
+	}
+	if _, err := w.Write([]byte("closed\n")); err == nil {
+		t.Error("Write() after Close() should fail")
+	}
+}
+
+func TestWriterRotatedByAnotherProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("files open in another writer cannot be renamed on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "tunatap.log")
+	a, err := Open(path, 20, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := Open(path, 20, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	a.Write([]byte("a: first line\n"))
+	b.Write([]byte("b: first line\n"))
+	a.Write([]byte("a: second line\n"))
+	// b finds the file it wrote to rotated by a and follows it
+	b.Write([]byte("b: second line\n"))
+
+	if got := readFile(t, path); got != "a: second line\nb: second line\n" {
+		t.Errorf("log file = %q", got)
+	}
+	if got := readFile(t, path+".1"); !strings.HasPrefix(got, "a: first line\nb: first line\n") {
+		t.Errorf("%s.1 = %q", filepath.Base(path), got)
+	}
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Error("the log file should have been rotated once")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build darwin
+
+package notify
+
+import "os/exec"
+
+// showScript displays the title and message given as arguments, so that
+// neither needs quoting for AppleScript.
+var showScript = []string{
+	"-e", "on run argv",
+	"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+	"-e", "end run",
+}
+
+// Desktop shows a notification in the macOS notification center.
+func Desktop(title, message string) error {
+	args := append(append([]string{}, showScript...), title, message)
+	return exec.Command("osascript", args...).Run()
+}
@@ -0,0 +1,19 @@
+//go:build !darwin && !windows
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// Desktop shows a notification with libnotify's notify-send, which the
+// desktop environment displays.
+func Desktop(title, message string) error {
+	err := exec.Command("notify-send", "--app-name=tunatap", title, message).Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("notify-send not found; install libnotify to get desktop notifications")
+	}
+	return err
+}
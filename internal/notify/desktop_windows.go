@@ -0,0 +1,28 @@
+//go:build windows
+
+package notify
+
+import (
+	"os"
+	"os/exec"
+)
+
+// toastScript shows a toast with the title and message from the
+// environment, so that neither needs quoting for PowerShell. Toasts are
+// shown as PowerShell's, as unpackaged programs have no app ID of their own.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:TUNATAP_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:TUNATAP_NOTIFY_MESSAGE)) > $null
+$appID = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($appID).Show([Windows.UI.Notifications.ToastNotification]::new($template))
+`
+
+// Desktop shows a Windows toast notification.
+func Desktop(title, message string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "TUNATAP_NOTIFY_TITLE="+title, "TUNATAP_NOTIFY_MESSAGE="+message)
+	return cmd.Run()
+}
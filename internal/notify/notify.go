@@ -0,0 +1,149 @@
+// Package notify shows desktop notifications for the lifecycle events of
+// tunnels running in the background, whose log output nobody sees.
+package notify
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/events"
+)
+
+// maxMessageLength keeps long errors from filling the screen; notification
+// centers cut them off anyway.
+const maxMessageLength = 200
+
+// Sender shows a notification with a title and a message.
+type Sender func(title, message string) error
+
+// Notifier turns the lifecycle events of tunnels into notifications: when a
+// tunnel drops and is reconnecting, when it is back up, when it stops with
+// an error, and when its bastion session could not be refreshed. A tunnel
+// retrying over and over, or failing to refresh every check, notifies once.
+type Notifier struct {
+	send Sender
+
+	mu sync.Mutex
+	// down and refreshFailing hold the clusters currently notified about
+	down           map[string]bool
+	refreshFailing map[string]bool
+	// pending are the notifications not sent yet, in order; sending is
+	// whether a goroutine is sending them
+	pending []notification
+	sending bool
+}
+
+// notification is a title and message to show.
+type notification struct {
+	title, message string
+}
+
+// New creates a Notifier that shows notifications with send.
+func New(send Sender) *Notifier {
+	return &Notifier{
+		send:           send,
+		down:           make(map[string]bool),
+		refreshFailing: make(map[string]bool),
+	}
+}
+
+// Handle shows the notification for e, if it warrants one. Notifications
+// are sent in order in the background, so that a slow notification service
+// does not hold up the tunnel.
+func (n *Notifier) Handle(e events.Event) {
+	title, message := n.notification(e)
+	if title == "" {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.pending = append(n.pending, notification{title, message})
+	if !n.sending {
+		n.sending = true
+		go n.deliver()
+	}
+}
+
+// deliver sends the pending notifications until there are none left.
+func (n *Notifier) deliver() {
+	for {
+		n.mu.Lock()
+		if len(n.pending) == 0 {
+			n.sending = false
+			n.mu.Unlock()
+			return
+		}
+		next := n.pending[0]
+		n.pending = n.pending[1:]
+		n.mu.Unlock()
+
+		if err := n.send(next.title, next.message); err != nil {
+			log.Debug().Err(err).Msg("Failed to show desktop notification")
+		}
+	}
+}
+
+// notification returns the title and message of the notification for e, or
+// an empty title if there is none.
+func (n *Notifier) notification(e events.Event) (string, string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch e.Type {
+	case events.Reconnecting:
+		delete(n.refreshFailing, e.Cluster)
+		if n.down[e.Cluster] {
+			return "", ""
+		}
+		n.down[e.Cluster] = true
+		return fmt.Sprintf("tunatap: %s disconnected", e.Cluster), truncate("Reconnecting after: " + e.Error)
+	case events.TunnelReady:
+		delete(n.refreshFailing, e.Cluster)
+		if !n.down[e.Cluster] {
+			return "", ""
+		}
+		delete(n.down, e.Cluster)
+		return fmt.Sprintf("tunatap: %s reconnected", e.Cluster), "The tunnel is " + listening(e)
+	case events.Refresh:
+		delete(n.refreshFailing, e.Cluster)
+	case events.RefreshFailed:
+		if n.refreshFailing[e.Cluster] {
+			return "", ""
+		}
+		n.refreshFailing[e.Cluster] = true
+		return fmt.Sprintf("tunatap: %s session refresh failed", e.Cluster),
+			truncate("The tunnel keeps its current session until it expires: " + e.Error)
+	case events.Closed:
+		delete(n.down, e.Cluster)
+		delete(n.refreshFailing, e.Cluster)
+		if e.Error == "" {
+			return "", ""
+		}
+		return fmt.Sprintf("tunatap: %s is down", e.Cluster), truncate(e.Error)
+	}
+	return "", ""
+}
+
+// listening describes where the tunnel of a ready event listens.
+func listening(e events.Event) string {
+	if e.Socket != "" {
+		return "up again on " + e.Socket
+	}
+	if e.Port != 0 {
+		return "up again on " + net.JoinHostPort(e.Address, strconv.Itoa(e.Port))
+	}
+	return "up again"
+}
+
+// truncate shortens s to maxMessageLength characters.
+func truncate(s string) string {
+	r := []rune(s)
+	if len(r) <= maxMessageLength {
+		return s
+	}
+	return string(r[:maxMessageLength-3]) + "..."
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/events"
+)
+
+func TestNotifications(t *testing.T) {
+	n := New(nil)
+
+	tests := []struct {
+		event events.Event
+		title string
+	}{
+		{events.Event{Type: events.TunnelReady, Cluster: "prod", Address: "localhost", Port: 6443}, ""},
+		{events.Event{Type: events.Reconnecting, Cluster: "prod", Attempt: 1, Error: "connection reset"}, "tunatap: prod disconnected"},
+		// Further attempts of the same outage are quiet
+		{events.Event{Type: events.Reconnecting, Cluster: "prod", Attempt: 2, Error: "connection refused"}, ""},
+		{events.Event{Type: events.Reconnecting, Cluster: "staging", Attempt: 1, Error: "EOF"}, "tunatap: staging disconnected"},
+		{events.Event{Type: events.TunnelReady, Cluster: "prod", Address: "localhost", Port: 6443}, "tunatap: prod reconnected"},
+		{events.Event{Type: events.RefreshFailed, Cluster: "prod", Error: "service unavailable"}, "tunatap: prod session refresh failed"},
+		{events.Event{Type: events.RefreshFailed, Cluster: "prod", Error: "service unavailable"}, ""},
+		{events.Event{Type: events.Refresh, Cluster: "prod"}, ""},
+		{events.Event{Type: events.RefreshFailed, Cluster: "prod", Error: "throttled"}, "tunatap: prod session refresh failed"},
+		{events.Event{Type: events.SessionCreated, Cluster: "prod"}, ""},
+		{events.Event{Type: events.Closed, Cluster: "staging", Error: "max retry attempts (15) exceeded"}, "tunatap: staging is down"},
+		{events.Event{Type: events.Closed, Cluster: "prod"}, ""},
+	}
+	for i, tt := range tests {
+		title, message := n.notification(tt.event)
+		if title != tt.title {
+			t.Errorf("event %d (%s %s): title = %q, want %q", i, tt.event.Type, tt.event.Cluster, title, tt.title)
+		}
+		if title != "" && message == "" {
+			t.Errorf("event %d: notification %q has no message", i, title)
+		}
+	}
+
+	_, message := New(nil).notification(events.Event{Type: events.Closed, Cluster: "prod", Error: strings.Repeat("é", 500)})
+	if n := len([]rune(message)); n != maxMessageLength || !strings.HasSuffix(message, "...") {
+		t.Errorf("long error message has %d characters, want %d ending in ...", n, maxMessageLength)
+	}
+}
+
+func TestHandleSends(t *testing.T) {
+	sent := make(chan notification, 1)
+	n := New(func(title, message string) error {
+		sent <- notification{title, message}
+		return nil
+	})
+
+	n.Handle(events.Event{Type: events.TunnelReady, Cluster: "prod", Socket: "/tmp/prod.sock"})
+	n.Handle(events.Event{Type: events.Reconnecting, Cluster: "prod", Error: "EOF"})
+	n.Handle(events.Event{Type: events.TunnelReady, Cluster: "prod", Socket: "/tmp/prod.sock"})
+
+	for _, want := range []notification{
+		{"tunatap: prod disconnected", "Reconnecting after: EOF"},
+		{"tunatap: prod reconnected", "The tunnel is up again on /tmp/prod.sock"},
+	} {
+		select {
+		case got := <-sent:
+			if got != want {
+				t.Errorf("sent %+v, want %+v", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notification %q was not sent", want.title)
+		}
+	}
+}
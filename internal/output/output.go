@@ -0,0 +1,83 @@
+// Package output writes command results as a table for people or as JSON,
+// YAML or CSV for other tools.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is how a command writes its results.
+type Format string
+
+const (
+	// Table is the human-readable output each command prints by default.
+	Table Format = "table"
+	// JSON writes the results as indented JSON.
+	JSON Format = "json"
+	// YAML writes the results as YAML.
+	YAML Format = "yaml"
+	// CSV writes rows of results as comma-separated values. Only commands
+	// whose results are rows offer it, so ParseFormat does not accept it.
+	CSV Format = "csv"
+	// Wide is a table with more columns than fit most terminals. Only
+	// commands with such columns offer it, so ParseFormat does not accept
+	// it either.
+	Wide Format = "wide"
+)
+
+// ParseFormat parses an --output value. An empty value means Table.
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case "", Table:
+		return Table, nil
+	case JSON:
+		return JSON, nil
+	case YAML:
+		return YAML, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (expected %s, %s or %s)", s, Table, JSON, YAML)
+	}
+}
+
+// Structured reports whether f is meant for other tools rather than people.
+func (f Format) Structured() bool {
+	return f == JSON || f == YAML
+}
+
+// Write writes v to w in format f, which must be JSON or YAML. Tables are
+// printed by each command.
+func Write(w io.Writer, f Format, v any) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("output format %q is not structured", f)
+	}
+}
+
+// WriteCSV writes header and rows to w as comma-separated values.
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
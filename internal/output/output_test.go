@@ -0,0 +1,65 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", Table, false},
+		{"table", Table, false},
+		{"JSON", JSON, false},
+		{"yaml", YAML, false},
+		{"xml", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	type result struct {
+		Name   string `json:"name" yaml:"name"`
+		Status string `json:"status" yaml:"status"`
+	}
+	v := []result{{Name: "SSH Agent", Status: "ok"}}
+
+	var b bytes.Buffer
+	if err := Write(&b, JSON, v); err != nil {
+		t.Fatalf("Write(JSON) error = %v", err)
+	}
+	if want := "[\n  {\n    \"name\": \"SSH Agent\",\n    \"status\": \"ok\"\n  }\n]\n"; b.String() != want {
+		t.Errorf("Write(JSON) = %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := Write(&b, YAML, v); err != nil {
+		t.Fatalf("Write(YAML) error = %v", err)
+	}
+	if want := "- name: SSH Agent\n  status: ok\n"; b.String() != want {
+		t.Errorf("Write(YAML) = %q, want %q", b.String(), want)
+	}
+
+	if err := Write(&b, Table, v); err == nil {
+		t.Error("Write(Table) should fail")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	rows := [][]string{{"prod", "2"}, {"dev, test", "0"}}
+	if err := WriteCSV(&b, []string{"cluster", "connections"}, rows); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	if want := "cluster,connections\nprod,2\n\"dev, test\",0\n"; b.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", b.String(), want)
+	}
+}
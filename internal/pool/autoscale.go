@@ -0,0 +1,115 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// scaleUpUtilization is the fraction of pool capacity in use at which the
+// pool adds a connection ahead of demand.
+const scaleUpUtilization = 0.8
+
+// AutoscaleConfig bounds and paces dynamic sizing of a ConnectionPool.
+type AutoscaleConfig struct {
+	// MinSize is the number of connections kept open even when idle.
+	MinSize int
+	// MaxSize is the most connections the pool may hold.
+	MaxSize int
+	// ScaleUpCooldown is the minimum time between connections added ahead
+	// of demand. Connections needed to serve a request are always created.
+	ScaleUpCooldown time.Duration
+	// ScaleDownCooldown is how long a connection must sit idle before it is
+	// closed.
+	ScaleDownCooldown time.Duration
+}
+
+// EnableAutoscaling makes the pool size itself between cfg.MinSize and
+// cfg.MaxSize. Call Autoscale periodically to apply it.
+func (p *ConnectionPool) EnableAutoscaling(cfg AutoscaleConfig) {
+	if cfg.MinSize < 0 {
+		cfg.MinSize = 0
+	}
+	if cfg.MaxSize < cfg.MinSize {
+		cfg.MaxSize = cfg.MinSize
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.autoscale = &cfg
+	p.maxSize = cfg.MaxSize
+	log.Info().Msgf("Connection pool autoscaling enabled (min: %d, max: %d)", cfg.MinSize, cfg.MaxSize)
+}
+
+// Autoscale grows the pool when it is close to saturation or below its
+// minimum size, and closes connections that have been idle longer than the
+// scale-down cooldown. It is a no-op unless autoscaling is enabled.
+func (p *ConnectionPool) Autoscale() {
+	p.mu.Lock()
+	cfg := p.autoscale
+	if cfg == nil {
+		p.mu.Unlock()
+		return
+	}
+
+	p.removeIdleInvalidConnections()
+
+	size := len(p.connections)
+	active := 0
+	for _, conn := range p.connections {
+		active += conn.GetUseCount()
+	}
+
+	grow := size < cfg.MinSize
+	if !grow && size < cfg.MaxSize && size > 0 && time.Since(p.lastScaleUp) >= cfg.ScaleUpCooldown {
+		grow = float64(active) >= scaleUpUtilization*float64(size*p.maxConcurrent)
+	}
+
+	if !grow {
+		p.scaleDown(cfg)
+		p.mu.Unlock()
+		return
+	}
+
+	p.lastScaleUp = time.Now()
+	p.mu.Unlock()
+
+	// Dial without holding the lock so Get is not blocked by a slow bastion
+	client, err := p.factory()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to add connection while scaling up pool")
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conn := NewTrackedConnection(client, p.maxConcurrent)
+	if len(p.connections) >= p.maxSize {
+		conn.Close()
+		return
+	}
+	p.connections = append(p.connections, conn)
+	log.Debug().Msgf("Scaled up connection pool to %d connections (%d active uses)", len(p.connections), active)
+}
+
+// scaleDown closes connections idle longer than the scale-down cooldown
+// while the pool is above its minimum size. Callers must hold p.mu.
+func (p *ConnectionPool) scaleDown(cfg *AutoscaleConfig) {
+	kept := make([]*TrackedSSHConnection, 0, len(p.connections))
+	removed := 0
+	for _, conn := range p.connections {
+		if len(p.connections)-removed > cfg.MinSize && conn.IsIdle() && conn.IdleFor() >= cfg.ScaleDownCooldown {
+			conn.Close()
+			removed++
+			continue
+		}
+		kept = append(kept, conn)
+	}
+	p.connections = kept
+
+	if removed > 0 {
+		log.Debug().Msgf("Scaled down connection pool to %d connections", len(p.connections))
+	}
+}
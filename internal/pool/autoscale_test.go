@@ -0,0 +1,111 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoscaleDisabled(t *testing.T) {
+	pool, err := NewConnectionPool(5, 1, mockFactory(false, nil), 1)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	pool.Get()
+	pool.Autoscale()
+
+	if pool.Size() != 1 {
+		t.Errorf("Size() = %d, want 1 without autoscaling", pool.Size())
+	}
+}
+
+func TestAutoscaleFillsToMin(t *testing.T) {
+	pool, err := NewConnectionPool(5, 10, mockFactory(false, nil), 0)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	pool.EnableAutoscaling(AutoscaleConfig{MinSize: 2, MaxSize: 4, ScaleDownCooldown: time.Hour})
+
+	pool.Autoscale()
+	pool.Autoscale()
+	pool.Autoscale()
+
+	if pool.Size() != 2 {
+		t.Errorf("Size() = %d, want min size 2", pool.Size())
+	}
+}
+
+func TestAutoscaleScalesUpUnderLoad(t *testing.T) {
+	pool, err := NewConnectionPool(2, 5, mockFactory(false, nil), 1)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	pool.EnableAutoscaling(AutoscaleConfig{MinSize: 1, MaxSize: 3, ScaleDownCooldown: time.Hour})
+
+	if pool.Capacity() != 15 {
+		t.Errorf("Capacity() = %d, want 15", pool.Capacity())
+	}
+
+	// 4 of 5 uses is at the scale-up threshold
+	for i := 0; i < 4; i++ {
+		if _, err := pool.Get(); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+
+	pool.Autoscale()
+	if pool.Size() != 2 {
+		t.Fatalf("Size() = %d, want 2 after scaling up", pool.Size())
+	}
+
+	// Utilization is now 4/10, below the threshold
+	pool.Autoscale()
+	if pool.Size() != 2 {
+		t.Errorf("Size() = %d, want 2 below threshold", pool.Size())
+	}
+}
+
+func TestAutoscaleScaleUpCooldown(t *testing.T) {
+	pool, err := NewConnectionPool(5, 1, mockFactory(false, nil), 1)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	pool.EnableAutoscaling(AutoscaleConfig{MinSize: 1, MaxSize: 5, ScaleUpCooldown: time.Hour, ScaleDownCooldown: time.Hour})
+
+	pool.Get()
+	pool.Autoscale()
+	pool.Get()
+	pool.Autoscale()
+
+	if pool.Size() != 2 {
+		t.Errorf("Size() = %d, want 2 while scale-up cooldown is active", pool.Size())
+	}
+}
+
+func TestAutoscaleScalesDownIdle(t *testing.T) {
+	pool, err := NewConnectionPool(5, 10, mockFactory(false, nil), 4)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	pool.EnableAutoscaling(AutoscaleConfig{MinSize: 1, MaxSize: 5, ScaleDownCooldown: 0})
+
+	busy, _ := pool.Get()
+	pool.Autoscale()
+
+	// The busy connection is kept, everything else is idle beyond the cooldown
+	if pool.Size() != 1 {
+		t.Errorf("Size() = %d, want 1 after scaling down", pool.Size())
+	}
+	if busy.IsInvalid() {
+		t.Error("connection in use should not be closed")
+	}
+}
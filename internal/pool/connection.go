@@ -2,6 +2,7 @@ package pool
 
 import (
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/ssh"
@@ -13,14 +14,17 @@ type TrackedSSHConnection struct {
 	useCount int
 	maxUses  int
 	invalid  bool
+	draining bool
+	lastUsed time.Time
 	mu       sync.Mutex
 }
 
 // NewTrackedConnection creates a new tracked connection.
 func NewTrackedConnection(client *ssh.Client, maxUses int) *TrackedSSHConnection {
 	return &TrackedSSHConnection{
-		Client:  client,
-		maxUses: maxUses,
+		Client:   client,
+		maxUses:  maxUses,
+		lastUsed: time.Now(),
 	}
 }
 
@@ -37,7 +41,7 @@ func (conn *TrackedSSHConnection) Increment() bool {
 	conn.mu.Lock()
 	defer conn.mu.Unlock()
 
-	if conn.invalid {
+	if conn.invalid || conn.draining {
 		return false
 	}
 
@@ -56,6 +60,7 @@ func (conn *TrackedSSHConnection) Decrement() {
 	if conn.useCount > 0 {
 		conn.useCount--
 	}
+	conn.lastUsed = time.Now()
 
 	log.Debug().Msgf("Decremented use count for server connection, current use: %v", conn.useCount)
 }
@@ -67,6 +72,14 @@ func (conn *TrackedSSHConnection) Invalidate() {
 	conn.invalid = true
 }
 
+// Drain stops the connection from taking new uses while its current ones
+// carry on.
+func (conn *TrackedSSHConnection) Drain() {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	conn.draining = true
+}
+
 // IsInvalid returns whether the connection is invalid.
 func (conn *TrackedSSHConnection) IsInvalid() bool {
 	conn.mu.Lock()
@@ -78,7 +91,7 @@ func (conn *TrackedSSHConnection) IsInvalid() bool {
 func (conn *TrackedSSHConnection) CanAcceptMore() bool {
 	conn.mu.Lock()
 	defer conn.mu.Unlock()
-	return !conn.invalid && conn.useCount < conn.maxUses
+	return !conn.invalid && !conn.draining && conn.useCount < conn.maxUses
 }
 
 // IsIdle returns true if the connection has no active uses.
@@ -88,6 +101,18 @@ func (conn *TrackedSSHConnection) IsIdle() bool {
 	return conn.useCount == 0
 }
 
+// IdleFor returns how long the connection has had no active uses, or zero
+// if it is in use.
+func (conn *TrackedSSHConnection) IdleFor() time.Duration {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.useCount > 0 {
+		return 0
+	}
+	return time.Since(conn.lastUsed)
+}
+
 // Close closes the underlying SSH client.
 func (conn *TrackedSSHConnection) Close() error {
 	conn.mu.Lock()
@@ -0,0 +1,63 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// StartKeepalive sends a keepalive on client every interval, like OpenSSH's
+// ServerAliveInterval, so NAT devices and firewalls do not silently drop an
+// idle connection. After maxMissed consecutive unanswered keepalives the
+// client is closed, which lets the pool replace it. The keepalive stops when
+// the client is closed. A non-positive interval disables keepalives.
+func StartKeepalive(client *ssh.Client, interval time.Duration, maxMissed int) {
+	if client == nil || interval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(stop)
+	}()
+
+	go func() {
+		send := func() error { return sendKeepalive(client, interval) }
+		if keepaliveLoop(send, interval, maxMissed, stop) {
+			log.Warn().Msgf("SSH connection missed %d keepalives, closing", maxMissed)
+			client.Close()
+		}
+	}()
+}
+
+// keepaliveLoop calls send every interval until stop is closed or maxMissed
+// consecutive sends fail. It returns true if the connection should be closed.
+func keepaliveLoop(send func() error, interval time.Duration, maxMissed int, stop <-chan struct{}) bool {
+	if maxMissed < 1 {
+		maxMissed = 1
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+
+		if err := send(); err != nil {
+			missed++
+			log.Debug().Err(err).Msgf("SSH keepalive missed (%d/%d)", missed, maxMissed)
+			if missed >= maxMissed {
+				return true
+			}
+			continue
+		}
+		missed = 0
+	}
+}
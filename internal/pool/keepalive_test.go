@@ -0,0 +1,49 @@
+package pool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeepaliveLoopClosesAfterMaxMissed(t *testing.T) {
+	var calls int32
+	send := func() error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("no reply")
+	}
+
+	if !keepaliveLoop(send, time.Millisecond, 3, make(chan struct{})) {
+		t.Error("keepaliveLoop() = false, want true after missed keepalives")
+	}
+	if calls != 3 {
+		t.Errorf("send called %d times, want 3", calls)
+	}
+}
+
+func TestKeepaliveLoopResetsOnReply(t *testing.T) {
+	// Alternate failures and replies: never reaches two consecutive misses
+	var calls int32
+	stop := make(chan struct{})
+	send := func() error {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 10 {
+			close(stop)
+		}
+		if n%2 == 0 {
+			return errors.New("no reply")
+		}
+		return nil
+	}
+
+	if keepaliveLoop(send, time.Millisecond, 2, stop) {
+		t.Error("keepaliveLoop() = true, want false when replies arrive between misses")
+	}
+}
+
+func TestStartKeepaliveDisabled(t *testing.T) {
+	// Must not panic or start goroutines for a nil client or zero interval
+	StartKeepalive(nil, time.Second, 3)
+	StartKeepalive(nil, 0, 3)
+}
@@ -3,6 +3,7 @@ package pool
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/ssh"
@@ -16,11 +17,20 @@ type HealthCheckFunc func(*ssh.Client) bool
 
 // ConnectionPool manages a pool of SSH connections.
 type ConnectionPool struct {
-	mu            sync.Mutex
-	connections   []*TrackedSSHConnection
-	maxSize       int
+	mu          sync.Mutex
+	connections []*TrackedSSHConnection
+	maxSize     int
+
+	// draining holds connections replaced by Rotate. They take no new uses
+	// and are closed once their last use ends.
+	draining []*TrackedSSHConnection
+
 	maxConcurrent int
 	factory       ConnectionFactory
+
+	// autoscale is set when the pool sizes itself dynamically.
+	autoscale   *AutoscaleConfig
+	lastScaleUp time.Time
 }
 
 // NewConnectionPool creates a new connection pool.
@@ -103,11 +113,15 @@ func (p *ConnectionPool) removeInvalidConnections() {
 }
 
 // HealthCheck performs a health check on all connections.
+// Checks run without holding the pool lock so that a connection hanging on a
+// dead network does not block Get.
 func (p *ConnectionPool) HealthCheck(checkFunc HealthCheckFunc) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	connections := make([]*TrackedSSHConnection, len(p.connections))
+	copy(connections, p.connections)
+	p.mu.Unlock()
 
-	for _, conn := range p.connections {
+	for _, conn := range connections {
 		if conn.IsInvalid() {
 			continue
 		}
@@ -119,7 +133,10 @@ func (p *ConnectionPool) HealthCheck(checkFunc HealthCheckFunc) {
 	}
 
 	// Clean up invalid connections that are idle
+	p.mu.Lock()
 	p.removeIdleInvalidConnections()
+	p.closeDrainedConnections()
+	p.mu.Unlock()
 }
 
 // removeIdleInvalidConnections removes invalid connections that have no active uses.
@@ -135,6 +152,114 @@ func (p *ConnectionPool) removeIdleInvalidConnections() {
 	p.connections = valid
 }
 
+// Rotate replaces the pool's connections with new ones from the factory,
+// typically after the factory's credentials changed. Up to warmup
+// connections (at least one) are dialed before anything is replaced, so a
+// failure leaves the pool as it was. Replaced connections keep serving their
+// current uses and are closed once idle. Returns the number still draining.
+func (p *ConnectionPool) Rotate(warmup int) (int, error) {
+	warmup = max(1, min(warmup, p.maxSize))
+
+	// Dial without the lock so Get keeps serving from the old connections
+	fresh := make([]*TrackedSSHConnection, 0, warmup)
+	var lastErr error
+	for i := 0; i < warmup; i++ {
+		client, err := p.factory()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		fresh = append(fresh, NewTrackedConnection(client, p.maxConcurrent))
+	}
+	if len(fresh) == 0 {
+		return 0, fmt.Errorf("failed to create replacement connections: %w", lastErr)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, conn := range p.connections {
+		conn.Drain()
+	}
+	p.draining = append(p.draining, p.connections...)
+	p.connections = fresh
+	p.closeDrainedConnections()
+
+	log.Info().Msgf("Rotated connection pool: %d new connections, %d draining", len(fresh), len(p.draining))
+	return len(p.draining), nil
+}
+
+// closeDrainedConnections closes draining connections that have no uses left.
+func (p *ConnectionPool) closeDrainedConnections() {
+	remaining := p.draining[:0]
+	for _, conn := range p.draining {
+		if conn.IsIdle() || conn.IsInvalid() {
+			conn.Close()
+		} else {
+			remaining = append(remaining, conn)
+		}
+	}
+	clear(p.draining[len(remaining):])
+	p.draining = remaining
+}
+
+// DrainingCount returns the number of replaced connections still in use.
+func (p *ConnectionPool) DrainingCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeDrainedConnections()
+	return len(p.draining)
+}
+
+// Shrink closes idle connections until at most minSize remain and returns
+// the number of connections closed. Connections in use are never closed.
+// The pool grows again on demand through Get.
+func (p *ConnectionPool) Shrink(minSize int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	closed := 0
+	kept := make([]*TrackedSSHConnection, 0, len(p.connections))
+	for _, conn := range p.connections {
+		if len(p.connections)-closed > minSize && conn.IsIdle() {
+			conn.Close()
+			closed++
+			continue
+		}
+		kept = append(kept, conn)
+	}
+	p.connections = kept
+
+	if closed > 0 {
+		log.Debug().Msgf("Shrunk connection pool by %d, pool size: %d", closed, len(p.connections))
+	}
+	return closed
+}
+
+// Reset closes every connection in the pool, including ones in use, and
+// returns the number closed. Use it when the network underneath the pool is
+// known to have changed; new connections are created on demand by Get.
+func (p *ConnectionPool) Reset() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	closed := len(p.connections)
+	for _, conn := range p.connections {
+		if err := conn.Close(); err != nil {
+			log.Debug().Err(err).Msg("Error closing connection during reset")
+		}
+	}
+	p.connections = p.connections[:0]
+	for _, conn := range p.draining {
+		conn.Close()
+	}
+	closed += len(p.draining)
+	p.draining = nil
+
+	log.Debug().Msgf("Reset connection pool, closed %d connections", closed)
+	return closed
+}
+
 // Size returns the current number of connections in the pool.
 func (p *ConnectionPool) Size() int {
 	p.mu.Lock()
@@ -142,6 +267,13 @@ func (p *ConnectionPool) Size() int {
 	return len(p.connections)
 }
 
+// Capacity returns the maximum number of concurrent uses the pool allows.
+func (p *ConnectionPool) Capacity() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.maxSize * p.maxConcurrent
+}
+
 // ActiveCount returns the total number of active uses across all connections.
 func (p *ConnectionPool) ActiveCount() int {
 	p.mu.Lock()
@@ -165,15 +297,40 @@ func (p *ConnectionPool) Close() {
 		}
 	}
 	p.connections = nil
+	for _, conn := range p.draining {
+		conn.Close()
+	}
+	p.draining = nil
 
 	log.Info().Msg("Connection pool closed")
 }
 
+// healthCheckTimeout bounds how long a keepalive may go unanswered before the
+// connection is considered dead. Without it a keepalive on a connection whose
+// network vanished (e.g. after suspend) can block until TCP gives up.
+const healthCheckTimeout = 5 * time.Second
+
 // CheckSSHClientHealth checks if an SSH client is healthy by sending a keepalive.
 func CheckSSHClientHealth(client *ssh.Client) bool {
 	if client == nil {
 		return false
 	}
-	_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
-	return err == nil
+	return sendKeepalive(client, healthCheckTimeout) == nil
+}
+
+// sendKeepalive sends an OpenSSH keepalive request and waits up to timeout
+// for the reply.
+func sendKeepalive(client *ssh.Client, timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("keepalive not answered within %s", timeout)
+	}
 }
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -289,3 +290,165 @@ func TestCheckSSHClientHealth(t *testing.T) {
 		t.Error("CheckSSHClientHealth(nil) = true, want false")
 	}
 }
+
+func TestConnectionPoolShrink(t *testing.T) {
+	factory := mockFactory(false, nil)
+
+	pool, err := NewConnectionPool(5, 1, factory, 3)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	// Keep one connection busy
+	busy, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if closed := pool.Shrink(0); closed != 2 {
+		t.Errorf("Shrink(0) closed %d, want 2", closed)
+	}
+	if pool.Size() != 1 {
+		t.Errorf("Size() after Shrink = %d, want 1 busy connection", pool.Size())
+	}
+
+	busy.Decrement()
+	if closed := pool.Shrink(1); closed != 0 {
+		t.Errorf("Shrink(1) closed %d, want 0", closed)
+	}
+	if closed := pool.Shrink(0); closed != 1 {
+		t.Errorf("Shrink(0) closed %d, want 1", closed)
+	}
+}
+
+func TestConnectionPoolReset(t *testing.T) {
+	var callCount int32
+	factory := mockFactory(false, &callCount)
+
+	pool, err := NewConnectionPool(5, 10, factory, 2)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	busy, _ := pool.Get()
+
+	if closed := pool.Reset(); closed != 2 {
+		t.Errorf("Reset() closed %d, want 2", closed)
+	}
+	if pool.Size() != 0 {
+		t.Errorf("Size() after Reset = %d, want 0", pool.Size())
+	}
+	if !busy.IsInvalid() {
+		t.Error("connections in use should be invalidated by Reset")
+	}
+
+	// The pool refills on demand
+	if _, err := pool.Get(); err != nil {
+		t.Fatalf("Get() after Reset error = %v", err)
+	}
+	if callCount != 3 {
+		t.Errorf("Factory called %d times, want 3", callCount)
+	}
+}
+
+func TestConnectionPoolHealthCheckDoesNotBlockGet(t *testing.T) {
+	factory := mockFactory(false, nil)
+
+	pool, err := NewConnectionPool(5, 10, factory, 1)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	release := make(chan struct{})
+	go pool.HealthCheck(func(c *ssh.Client) bool {
+		<-release
+		return true
+	})
+	defer close(release)
+
+	got := make(chan error, 1)
+	go func() {
+		_, err := pool.Get()
+		got <- err
+	}()
+
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Errorf("Get() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Get() blocked while a health check was in progress")
+	}
+}
+
+func TestConnectionPoolRotate(t *testing.T) {
+	pool, err := NewConnectionPool(5, 10, mockFactory(false, nil), 2)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	busy, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	draining, err := pool.Rotate(2)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	// The idle warmup connection is closed at once; the busy one drains
+	if draining != 1 {
+		t.Errorf("Rotate() draining = %d, want 1", draining)
+	}
+	if pool.Size() != 2 {
+		t.Errorf("Size() after Rotate = %d, want 2", pool.Size())
+	}
+	if busy.IsInvalid() {
+		t.Error("Connection in use should stay open while draining")
+	}
+
+	next, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if next == busy {
+		t.Error("Get() should not hand out a draining connection")
+	}
+
+	busy.Decrement()
+	if got := pool.DrainingCount(); got != 0 {
+		t.Errorf("DrainingCount() after last use = %d, want 0", got)
+	}
+	if !busy.IsInvalid() {
+		t.Error("Drained connection should be closed")
+	}
+}
+
+func TestConnectionPoolRotateFailureKeepsConnections(t *testing.T) {
+	var fail atomic.Bool
+	factory := func() (*ssh.Client, error) {
+		if fail.Load() {
+			return nil, errors.New("mock connection failure")
+		}
+		return nil, nil
+	}
+
+	pool, err := NewConnectionPool(5, 10, factory, 2)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	fail.Store(true)
+	if _, err := pool.Rotate(2); err == nil {
+		t.Error("Rotate() should error when no replacement connects")
+	}
+	if pool.Size() != 2 || pool.DrainingCount() != 0 {
+		t.Errorf("Pool after failed Rotate = %d connections / %d draining, want 2 / 0", pool.Size(), pool.DrainingCount())
+	}
+}
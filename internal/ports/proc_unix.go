@@ -0,0 +1,31 @@
+//go:build !windows
+
+package ports
+
+import (
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether a process with pid exists and can be
+// signalled. A process of another user, which answers EPERM, cannot be the
+// tunatap process that claimed a port.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// terminateProcess asks pid to shut down cleanly.
+func terminateProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(syscall.SIGTERM)
+}
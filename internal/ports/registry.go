@@ -0,0 +1,307 @@
+// Package ports records which tunatap process holds each local tunnel port,
+// so that a new tunnel can take a port over from a previous one.
+package ports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/daemon"
+	"github.com/scotttball/tunatap/internal/flock"
+	"github.com/scotttball/tunatap/internal/state"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+// Strategy decides what happens when a tunnel's local port is already in use.
+type Strategy string
+
+const (
+	// StrategyIncrement tries the following ports until one is free.
+	StrategyIncrement Strategy = "increment"
+
+	// StrategyFail returns an error.
+	StrategyFail Strategy = "fail"
+
+	// StrategyTakeover stops the tunatap process holding the port and
+	// reuses it. Ports held by anything else are never taken over.
+	StrategyTakeover Strategy = "takeover"
+)
+
+// ParseStrategy parses a port strategy name. An empty name means increment.
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case "", StrategyIncrement:
+		return StrategyIncrement, nil
+	case StrategyFail:
+		return StrategyFail, nil
+	case StrategyTakeover:
+		return StrategyTakeover, nil
+	default:
+		return "", fmt.Errorf("unknown port strategy %q (expected %q, %q or %q)",
+			s, StrategyIncrement, StrategyFail, StrategyTakeover)
+	}
+}
+
+const (
+	registryDirName = "ports"
+
+	// takeoverTimeout bounds how long Takeover waits for the port to be freed.
+	takeoverTimeout = 10 * time.Second
+
+	// claimTimeout bounds how long Claim waits for a previous holder, such
+	// as one being taken over, to exit and release its lock on the port.
+	claimTimeout = 5 * time.Second
+)
+
+// Entry describes the tunatap process holding a port.
+type Entry struct {
+	PID     int    `json:"pid"`
+	Cluster string `json:"cluster"`
+
+	// DaemonSocket is set when the port is held by a daemon-managed tunnel,
+	// which is stopped through the daemon rather than by signalling it.
+	DaemonSocket string `json:"daemon_socket,omitempty"`
+}
+
+// Registry is a directory of one pidfile per claimed port. The claimant of
+// a port also holds a lock on a lock file beside its pidfile for as long as
+// it holds the port, which the OS releases when the process exits, so that a
+// pidfile left behind is never mistaken for a live claim when its PID has
+// been reused by another process.
+type Registry struct {
+	dir string
+
+	mu    sync.Mutex
+	locks map[int]*os.File
+}
+
+// NewRegistry creates a registry stored in dir.
+func NewRegistry(dir string) *Registry {
+	return &Registry{dir: dir, locks: make(map[int]*os.File)}
+}
+
+// DefaultDir returns the registry directory, respecting the configured home
+// path.
+func DefaultDir() string {
+	if homePath := state.GetInstance().GetHomePath(); homePath != "" {
+		return filepath.Join(homePath, registryDirName)
+	}
+	return filepath.Join(utils.DefaultTunatapDir(), registryDirName)
+}
+
+// Default returns the registry in DefaultDir.
+func Default() *Registry {
+	return NewRegistry(DefaultDir())
+}
+
+func (r *Registry) path(port int) string {
+	return filepath.Join(r.dir, strconv.Itoa(port)+".json")
+}
+
+// lockPath returns the lock file of port. Lock files are left in place when
+// a claim is released, as removing one could let two processes lock
+// different files of the same name.
+func (r *Registry) lockPath(port int) string {
+	return filepath.Join(r.dir, strconv.Itoa(port)+".lock")
+}
+
+// Claim records entry as the holder of port, replacing any previous holder.
+// It locks the port for this process until Release, waiting for a previous
+// holder that is exiting to release it.
+func (r *Registry) Claim(port int, entry Entry) error {
+	if err := os.MkdirAll(r.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create port registry: %w", err)
+	}
+	if err := r.lock(port); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so a concurrent Lookup never sees a partial file
+	tmp := r.path(port) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write port registry: %w", err)
+	}
+	if err := os.Rename(tmp, r.path(port)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write port registry: %w", err)
+	}
+	return nil
+}
+
+// Release removes the claim on port if it still belongs to this process and
+// cluster. A claim taken over by another process is left alone.
+func (r *Registry) Release(port int, cluster string) {
+	entry, err := r.read(port)
+	if err != nil || entry == nil {
+		return
+	}
+	if entry.PID == os.Getpid() && entry.Cluster == cluster {
+		os.Remove(r.path(port))
+		r.unlock(port)
+	}
+}
+
+// lock takes the lock on port for this process, if it does not hold it yet.
+func (r *Registry) lock(port int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.locks[port]; ok {
+		return nil
+	}
+
+	f, err := os.OpenFile(r.lockPath(port), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to lock port %d in the port registry: %w", port, err)
+	}
+	deadline := time.Now().Add(claimTimeout)
+	for {
+		locked, err := flock.TryLock(f)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to lock port %d in the port registry: %w", port, err)
+		}
+		if locked {
+			r.locks[port] = f
+			return nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return fmt.Errorf("port %d is still claimed by another tunatap process after %s", port, claimTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// unlock releases the lock this process holds on port, if any.
+func (r *Registry) unlock(port int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.locks[port]; ok {
+		flock.Unlock(f)
+		f.Close()
+		delete(r.locks, port)
+	}
+}
+
+// locked reports whether a process holds the lock on port.
+func (r *Registry) locked(port int) (bool, error) {
+	r.mu.Lock()
+	_, held := r.locks[port]
+	r.mu.Unlock()
+	if held {
+		return true, nil
+	}
+
+	f, err := os.OpenFile(r.lockPath(port), os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read port registry: %w", err)
+	}
+	defer f.Close()
+
+	free, err := flock.TryLock(f)
+	if err != nil {
+		return false, fmt.Errorf("failed to read port registry: %w", err)
+	}
+	if free {
+		flock.Unlock(f)
+	}
+	return !free, nil
+}
+
+// Lookup returns the live holder of port, or nil if there is none. Claims
+// left behind by processes that have exited, whose lock on the port was
+// released with them, are removed.
+func (r *Registry) Lookup(port int) (*Entry, error) {
+	entry, err := r.read(port)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	held, err := r.locked(port)
+	if err != nil {
+		return nil, err
+	}
+	if !held || !processAlive(entry.PID) {
+		os.Remove(r.path(port))
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func (r *Registry) read(port int) (*Entry, error) {
+	data, err := os.ReadFile(r.path(port))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read port registry: %w", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse port registry entry for %d: %w", port, err)
+	}
+	return &entry, nil
+}
+
+// Takeover stops the tunatap tunnel holding port and waits for the port to
+// become free. Only a holder whose claim is still locked is stopped, so that
+// a PID reused since a claim was left behind is never signalled.
+func (r *Registry) Takeover(port int) error {
+	entry, err := r.Lookup(port)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("port %d is in use by a process tunatap did not start; refusing to take it over", port)
+	}
+	if entry.PID == os.Getpid() && entry.DaemonSocket == "" {
+		return fmt.Errorf("port %d is already used by this process for cluster '%s'", port, entry.Cluster)
+	}
+
+	if entry.DaemonSocket != "" {
+		log.Warn().Msgf("Taking over port %d from the daemon tunnel to %s", port, entry.Cluster)
+		if err := daemon.NewClient(entry.DaemonSocket).Stop(entry.Cluster); err != nil {
+			return fmt.Errorf("failed to stop daemon tunnel to '%s': %w", entry.Cluster, err)
+		}
+	} else {
+		log.Warn().Msgf("Taking over port %d from tunatap process %d (cluster %s)", port, entry.PID, entry.Cluster)
+		if err := terminateProcess(entry.PID); err != nil {
+			return fmt.Errorf("failed to stop process %d: %w", entry.PID, err)
+		}
+	}
+
+	deadline := time.Now().Add(takeoverTimeout)
+	for time.Now().Before(deadline) {
+		if IsAvailable(port) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("port %d is still in use %s after stopping its previous holder", port, takeoverTimeout)
+}
+
+// IsAvailable returns true if port can be listened on at localhost.
+func IsAvailable(port int) bool {
+	ln, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
@@ -0,0 +1,139 @@
+package ports
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Strategy
+	}{
+		{"", StrategyIncrement},
+		{"increment", StrategyIncrement},
+		{"fail", StrategyFail},
+		{"takeover", StrategyTakeover},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStrategy(tt.input)
+		if err != nil {
+			t.Errorf("ParseStrategy(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStrategy(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := ParseStrategy("kill"); err == nil {
+		t.Error("ParseStrategy(\"kill\") should error")
+	}
+}
+
+func TestRegistryClaimLookupRelease(t *testing.T) {
+	registry := NewRegistry(t.TempDir())
+
+	if err := registry.Claim(16443, Entry{PID: os.Getpid(), Cluster: "prod"}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	entry, err := registry.Lookup(16443)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if entry == nil || entry.PID != os.Getpid() || entry.Cluster != "prod" {
+		t.Fatalf("Lookup() = %+v, want this process for prod", entry)
+	}
+
+	// Another cluster's release must not remove the claim
+	registry.Release(16443, "staging")
+	if entry, _ := registry.Lookup(16443); entry == nil {
+		t.Error("Release() for a different cluster removed the claim")
+	}
+
+	registry.Release(16443, "prod")
+	if entry, _ := registry.Lookup(16443); entry != nil {
+		t.Errorf("Lookup() after Release() = %+v, want nil", entry)
+	}
+}
+
+func TestRegistryLookupRemovesStaleEntry(t *testing.T) {
+	registry := NewRegistry(t.TempDir())
+
+	// PID 0 is never a live tunatap process
+	if err := registry.Claim(16443, Entry{PID: 0, Cluster: "prod"}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	entry, err := registry.Lookup(16443)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if entry != nil {
+		t.Errorf("Lookup() = %+v, want nil for a dead process", entry)
+	}
+	if _, err := os.Stat(registry.path(16443)); !os.IsNotExist(err) {
+		t.Error("stale registry entry should be removed")
+	}
+}
+
+func TestRegistryLookupRequiresLock(t *testing.T) {
+	dir := t.TempDir()
+	registry := NewRegistry(dir)
+	if err := registry.Claim(16443, Entry{PID: os.Getpid(), Cluster: "prod"}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	// Another registry, as of another process, sees the claim as locked
+	if entry, err := NewRegistry(dir).Lookup(16443); err != nil || entry == nil {
+		t.Fatalf("Lookup() = %+v, %v, want the locked claim", entry, err)
+	}
+
+	// A claim left without its lock belongs to a process that has exited,
+	// even if a live process now has its PID
+	data, err := json.Marshal(Entry{PID: os.Getppid(), Cluster: "staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(registry.path(17443), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := NewRegistry(dir).Lookup(17443)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if entry != nil {
+		t.Errorf("Lookup() = %+v, want nil for a claim that is not locked", entry)
+	}
+	if _, err := os.Stat(registry.path(17443)); !os.IsNotExist(err) {
+		t.Error("unlocked registry entry should be removed")
+	}
+}
+
+func TestTakeoverRefusesUnknownHolder(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	registry := NewRegistry(t.TempDir())
+	if err := registry.Takeover(port); err == nil {
+		t.Error("Takeover() should refuse a port tunatap did not claim")
+	}
+}
+
+func TestTakeoverRefusesOwnPort(t *testing.T) {
+	registry := NewRegistry(t.TempDir())
+	if err := registry.Claim(16443, Entry{PID: os.Getpid(), Cluster: "prod"}); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if err := registry.Takeover(16443); err == nil {
+		t.Error("Takeover() should refuse to stop this process")
+	}
+}
@@ -0,0 +1,140 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/config"
+)
+
+// AllowlistCheckName is the name of the CheckBastionClientAllowlist result.
+const AllowlistCheckName = "Bastion Client Allowlist"
+
+// publicIPTimeout bounds the public IP lookup when no timeout is set.
+const publicIPTimeout = 5 * time.Second
+
+// PublicIP returns this machine's public IP address as seen by the lookup
+// service at url, which must respond with the address as plain text.
+func PublicIP(ctx context.Context, url string) (net.IP, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up public IP: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to look up public IP: HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up public IP: %w", err)
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, fmt.Errorf("public IP lookup returned %q, not an IP address", strings.TrimSpace(string(body)))
+	}
+	return ip, nil
+}
+
+// AllowlistContains reports whether ip is in any of the CIDR blocks.
+// Invalid blocks are ignored.
+func AllowlistContains(cidrs []string, ip net.IP) bool {
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostCIDR returns the single-address CIDR block for ip.
+func HostCIDR(ip net.IP) string {
+	if ip.To4() != nil {
+		return ip.String() + "/32"
+	}
+	return ip.String() + "/128"
+}
+
+// CheckBastionClientAllowlist verifies that this machine's public IP is in
+// the bastion's client CIDR allowlist. Otherwise SSH to the bastion just
+// times out.
+func CheckBastionClientAllowlist(ctx context.Context, opts *CheckOptions) CheckResult {
+	result := CheckResult{
+		Name:        AllowlistCheckName,
+		AutoFixable: false,
+	}
+
+	if opts.OCIClient == nil {
+		result.Status = StatusSkipped
+		result.Message = "OCI client not available"
+		return result
+	}
+
+	if opts.Cluster == nil || opts.Cluster.BastionId == nil {
+		result.Status = StatusSkipped
+		result.Message = "No bastion configured for cluster"
+		return result
+	}
+
+	bastionInfo, err := opts.OCIClient.GetBastion(ctx, *opts.Cluster.BastionId)
+	if err != nil {
+		result.Status = StatusError
+		result.Message = "Failed to get bastion details"
+		result.Details = err.Error()
+		return result
+	}
+
+	if bastionInfo.BastionType != nil && *bastionInfo.BastionType != "STANDARD" {
+		result.Status = StatusSkipped
+		result.Message = "Only standard bastions have a client allowlist"
+		return result
+	}
+
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = publicIPTimeout
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ip, err := PublicIP(lookupCtx, cfg.GetPublicIPURL())
+	if err != nil {
+		result.Status = StatusWarning
+		result.Message = "Could not determine public IP address"
+		result.Details = err.Error()
+		result.Suggestion = "Set public_ip_url in config to a reachable lookup service"
+		return result
+	}
+
+	allowlist := strings.Join(bastionInfo.ClientCidrBlockAllowList, ", ")
+	if AllowlistContains(bastionInfo.ClientCidrBlockAllowList, ip) {
+		result.Status = StatusOK
+		result.Message = fmt.Sprintf("Public IP %s is allowed by the bastion", ip)
+		result.Details = fmt.Sprintf("Allowlist: %s", allowlist)
+		return result
+	}
+
+	result.Status = StatusError
+	result.Message = fmt.Sprintf("Public IP %s is not in the bastion's client allowlist", ip)
+	result.Details = fmt.Sprintf("Allowlist: %s", allowlist)
+	result.Suggestion = fmt.Sprintf("Add %s to the bastion's allowlist with 'tunatap preflight %s --fix-allowlist' (needs permission to update the bastion)", HostCIDR(ip), opts.Cluster.ClusterName)
+	result.AutoFixable = true
+	return result
+}
@@ -0,0 +1,71 @@
+package preflight
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicIP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("203.0.113.7\n"))
+	}))
+	defer server.Close()
+
+	ip, err := PublicIP(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("PublicIP() error = %v", err)
+	}
+	if !ip.Equal(net.ParseIP("203.0.113.7")) {
+		t.Errorf("PublicIP() = %s, want 203.0.113.7", ip)
+	}
+}
+
+func TestPublicIPInvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>captive portal</html>"))
+	}))
+	defer server.Close()
+
+	if _, err := PublicIP(context.Background(), server.URL); err == nil {
+		t.Error("PublicIP() error = nil, want an error for a non-IP response")
+	}
+}
+
+func TestAllowlistContains(t *testing.T) {
+	ip := net.ParseIP("203.0.113.7")
+
+	tests := []struct {
+		cidrs []string
+		want  bool
+	}{
+		{nil, false},
+		{[]string{"10.0.0.0/8"}, false},
+		{[]string{"bogus", "203.0.113.0/24"}, true},
+		{[]string{"0.0.0.0/0"}, true},
+	}
+
+	for _, tt := range tests {
+		if got := AllowlistContains(tt.cidrs, ip); got != tt.want {
+			t.Errorf("AllowlistContains(%v) = %v, want %v", tt.cidrs, got, tt.want)
+		}
+	}
+}
+
+func TestHostCIDR(t *testing.T) {
+	if got, want := HostCIDR(net.ParseIP("203.0.113.7")), "203.0.113.7/32"; got != want {
+		t.Errorf("HostCIDR() = %q, want %q", got, want)
+	}
+	if got, want := HostCIDR(net.ParseIP("2001:db8::1")), "2001:db8::1/128"; got != want {
+		t.Errorf("HostCIDR() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckBastionClientAllowlistNoClient(t *testing.T) {
+	result := CheckBastionClientAllowlist(context.Background(), &CheckOptions{})
+	if result.Status != StatusSkipped {
+		t.Errorf("Status = %v, want %v", result.Status, StatusSkipped)
+	}
+}
@@ -6,23 +6,25 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/oracle/oci-go-sdk/v65/bastion"
+	ocibastion "github.com/oracle/oci-go-sdk/v65/bastion"
 	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/bastion"
 	"github.com/scotttball/tunatap/internal/client"
 	"github.com/scotttball/tunatap/internal/config"
 )
 
 // CheckResult represents the result of a preflight check.
 type CheckResult struct {
-	Name        string
-	Status      CheckStatus
-	Message     string
-	Details     string
-	Suggestion  string
-	AutoFixable bool
+	Name        string      `json:"name" yaml:"name"`
+	Status      CheckStatus `json:"status" yaml:"status"`
+	Message     string      `json:"message" yaml:"message"`
+	Details     string      `json:"details,omitempty" yaml:"details,omitempty"`
+	Suggestion  string      `json:"suggestion,omitempty" yaml:"suggestion,omitempty"`
+	AutoFixable bool        `json:"auto_fixable" yaml:"auto_fixable"`
 }
 
 // CheckStatus represents the status of a check.
@@ -71,6 +73,7 @@ func (c *Checker) registerChecks() {
 		CheckClusterAccess,
 		CheckSSHAgentAvailable,
 		CheckBastionEndpointReachable,
+		CheckBastionClientAllowlist,
 	}
 }
 
@@ -101,6 +104,7 @@ func (c *Checker) RunForCluster(ctx context.Context) []CheckResult {
 
 	if !c.opts.SkipNetwork {
 		results = append(results, CheckBastionEndpointReachable(ctx, c.opts))
+		results = append(results, CheckBastionClientAllowlist(ctx, c.opts))
 	}
 
 	return results
@@ -191,18 +195,18 @@ func CheckBastionServiceHealth(ctx context.Context, opts *CheckOptions) CheckRes
 
 	// Check bastion lifecycle state
 	switch bastionInfo.LifecycleState {
-	case bastion.BastionLifecycleStateActive:
+	case ocibastion.BastionLifecycleStateActive:
 		result.Status = StatusOK
 		result.Message = fmt.Sprintf("Bastion '%s' is active", *bastionInfo.Name)
-	case bastion.BastionLifecycleStateCreating:
+	case ocibastion.BastionLifecycleStateCreating:
 		result.Status = StatusWarning
 		result.Message = fmt.Sprintf("Bastion '%s' is still being created", *bastionInfo.Name)
 		result.Suggestion = "Wait for bastion creation to complete"
-	case bastion.BastionLifecycleStateDeleted, bastion.BastionLifecycleStateDeleting:
+	case ocibastion.BastionLifecycleStateDeleted, ocibastion.BastionLifecycleStateDeleting:
 		result.Status = StatusError
 		result.Message = fmt.Sprintf("Bastion '%s' is deleted/deleting", *bastionInfo.Name)
 		result.Suggestion = "Create a new bastion or update cluster configuration"
-	case bastion.BastionLifecycleStateFailed:
+	case ocibastion.BastionLifecycleStateFailed:
 		result.Status = StatusError
 		result.Message = fmt.Sprintf("Bastion '%s' is in failed state", *bastionInfo.Name)
 		result.Suggestion = "Check the bastion service in OCI Console for errors"
@@ -357,7 +361,10 @@ func CheckBastionEndpointReachable(ctx context.Context, opts *CheckOptions) Chec
 	}
 
 	// Construct bastion host address
-	bastionHost := fmt.Sprintf("host.bastion.%s.oci.oraclecloud.com", opts.Cluster.Region)
+	bastionHost := bastion.GetBastionHost(*opts.Cluster.BastionId, opts.Cluster.Region)
+	if opts.Config != nil {
+		bastionHost = bastion.ClusterBastionHost(opts.Config, opts.Cluster)
+	}
 
 	// Try to resolve DNS
 	_, err := net.LookupHost(bastionHost)
@@ -406,7 +413,7 @@ func CheckClusterEndpointReachable(ctx context.Context, opts *CheckOptions) Chec
 	}
 
 	endpoint := opts.Cluster.Endpoints[0]
-	address := fmt.Sprintf("%s:%d", endpoint.Ip, endpoint.Port)
+	address := net.JoinHostPort(endpoint.Host(), strconv.Itoa(endpoint.Port))
 
 	// Note: This will typically fail since the cluster endpoint is private
 	// This check is mainly informational
@@ -0,0 +1,163 @@
+// Package secrets resolves secret references in config values, so that
+// passphrases, passwords and tokens need not be kept in plain text in the
+// config file. A reference is one of
+//
+//	vault:<secret OCID>           the current version of an OCI Vault secret
+//	keychain:<service>/<account>  a secret in the OS keychain
+//
+// and any other value is taken as it is.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/scotttball/tunatap/internal/keychain"
+	"github.com/scotttball/tunatap/pkg/utils"
+)
+
+const (
+	// VaultPrefix starts a reference to an OCI Vault secret.
+	VaultPrefix = "vault:"
+	// KeychainPrefix starts a reference to a secret in the OS keychain.
+	KeychainPrefix = "keychain:"
+)
+
+// IsReference reports whether value is a secret reference rather than a
+// plain value.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, VaultPrefix) || strings.HasPrefix(value, KeychainPrefix)
+}
+
+// Check returns an error if value is a malformed secret reference.
+func Check(value string) error {
+	switch {
+	case strings.HasPrefix(value, VaultPrefix):
+		_, err := parseVault(value)
+		return err
+	case strings.HasPrefix(value, KeychainPrefix):
+		_, _, err := ParseKeychain(value)
+		return err
+	}
+	return nil
+}
+
+// ParseKeychain returns the service and account of a keychain reference.
+func ParseKeychain(ref string) (service, account string, err error) {
+	name, ok := strings.CutPrefix(ref, KeychainPrefix)
+	if !ok {
+		return "", "", fmt.Errorf("'%s' is not a keychain reference", ref)
+	}
+	service, account, ok = strings.Cut(name, "/")
+	if !ok || service == "" || account == "" {
+		return "", "", fmt.Errorf("keychain reference '%s' must be keychain:<service>/<account>", ref)
+	}
+	return service, account, nil
+}
+
+// parseVault returns the secret OCID of a Vault reference.
+func parseVault(ref string) (string, error) {
+	id := strings.TrimPrefix(ref, VaultPrefix)
+	if parts := utils.ParseOCID(id); parts == nil || parts.ResourceType != "vaultsecret" {
+		return "", fmt.Errorf("vault reference '%s' must name a Vault secret OCID (ocid1.vaultsecret...)", ref)
+	}
+	return id, nil
+}
+
+// VaultReader reads OCI Vault secrets.
+type VaultReader interface {
+	// GetSecretContent returns the content of the current version of a
+	// secret.
+	GetSecretContent(ctx context.Context, secretID string) (string, error)
+}
+
+// Resolver resolves secret references, remembering the value of each so
+// that it is read once.
+type Resolver struct {
+	// Vault returns a reader of the Vault secrets of region, the region
+	// key of the secret's OCID such as "iad".
+	Vault func(region string) (VaultReader, error)
+	// Keychain holds the secrets of keychain references.
+	Keychain keychain.Keychain
+
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewResolver returns a resolver reading Vault secrets with the readers
+// vault returns and keychain secrets from the OS keychain.
+func NewResolver(vault func(region string) (VaultReader, error)) *Resolver {
+	return &Resolver{Vault: vault, Keychain: keychain.System()}
+}
+
+// Resolve returns the secret value references, or value itself if it is
+// not a reference. Errors name the reference but never hold a secret.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if secret, ok := r.values[value]; ok {
+		return secret, nil
+	}
+
+	var secret string
+	var err error
+	if strings.HasPrefix(value, VaultPrefix) {
+		secret, err = r.readVault(ctx, value)
+	} else {
+		secret, err = r.readKeychain(value)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if r.values == nil {
+		r.values = make(map[string]string)
+	}
+	r.values[value] = secret
+	return secret, nil
+}
+
+func (r *Resolver) readVault(ctx context.Context, ref string) (string, error) {
+	id, err := parseVault(ref)
+	if err != nil {
+		return "", err
+	}
+	if r.Vault == nil {
+		return "", fmt.Errorf("cannot read %s: OCI Vault is not available here", ref)
+	}
+	reader, err := r.Vault(utils.ExtractRegionFromOCID(id))
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", ref, err)
+	}
+	secret, err := reader.GetSecretContent(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	return strings.TrimRight(secret, "\r\n"), nil
+}
+
+func (r *Resolver) readKeychain(ref string) (string, error) {
+	service, account, err := ParseKeychain(ref)
+	if err != nil {
+		return "", err
+	}
+	kc := r.Keychain
+	if kc == nil {
+		kc = keychain.System()
+	}
+	secret, err := kc.Get(service, account)
+	if errors.Is(err, keychain.ErrNotFound) {
+		return "", fmt.Errorf("%s is not in the keychain; store it with 'tunatap config set-secret %s'", ref, ref)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,116 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/keychain"
+)
+
+// memoryKeychain is a keychain held in memory.
+type memoryKeychain map[string]string
+
+func (k memoryKeychain) Get(service, account string) (string, error) {
+	secret, ok := k[service+"/"+account]
+	if !ok {
+		return "", keychain.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (k memoryKeychain) Set(service, account, secret string) error {
+	k[service+"/"+account] = secret
+	return nil
+}
+
+// fakeVault holds Vault secrets by OCID, counting reads.
+type fakeVault struct {
+	secrets map[string]string
+	reads   int
+}
+
+func (v *fakeVault) GetSecretContent(ctx context.Context, secretID string) (string, error) {
+	v.reads++
+	secret, ok := v.secrets[secretID]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return secret, nil
+}
+
+const secretOCID = "ocid1.vaultsecret.oc1.iad.amaaaaaexample"
+
+func TestResolve(t *testing.T) {
+	vault := &fakeVault{secrets: map[string]string{secretOCID: "s3cret\n"}}
+	var region string
+	r := NewResolver(func(r string) (VaultReader, error) {
+		region = r
+		return vault, nil
+	})
+	r.Keychain = memoryKeychain{"tunatap/ssh-passphrase": "hunter2"}
+	ctx := context.Background()
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"", ""},
+		{"vault:" + secretOCID, "s3cret"},
+		{"keychain:tunatap/ssh-passphrase", "hunter2"},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(ctx, tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+	if region != "iad" {
+		t.Errorf("Vault reader for region %q, want the secret's region", region)
+	}
+
+	// Each reference is read once
+	if _, err := r.Resolve(ctx, "vault:"+secretOCID); err != nil || vault.reads != 1 {
+		t.Errorf("Resolve() again read the Vault %d times, %v", vault.reads, err)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	r := NewResolver(nil)
+	r.Keychain = memoryKeychain{}
+	ctx := context.Background()
+
+	for _, value := range []string{
+		"keychain:tunatap/missing",
+		"keychain:no-account",
+		"vault:ocid1.bastion.oc1.iad.example",
+		"vault:" + secretOCID,
+	} {
+		if _, err := r.Resolve(ctx, value); err == nil {
+			t.Errorf("Resolve(%q) = nil error", value)
+		}
+	}
+
+	_, err := r.Resolve(ctx, "keychain:tunatap/missing")
+	if err == nil || !strings.Contains(err.Error(), "tunatap config set-secret keychain:tunatap/missing") {
+		t.Errorf("Resolve() of a missing keychain secret error = %v, want how to store it", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for value, valid := range map[string]bool{
+		"plain":                             true,
+		"keychain:tunatap/ssh-passphrase":   true,
+		"keychain:tunatap/":                 false,
+		"keychain:tunatap":                  false,
+		"vault:" + secretOCID:               true,
+		"vault:ocid1.cluster.oc1.iad.aaaaa": false,
+		"vault:":                            false,
+	} {
+		if err := Check(value); (err == nil) != valid {
+			t.Errorf("Check(%q) = %v, want valid %v", value, err, valid)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+// Package service installs tunatap as a per-user system service: a systemd
+// user unit on Linux or a launchd agent on macOS, started at login.
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	// unitName is the systemd user unit tunatap installs.
+	unitName = "tunatap.service"
+
+	// launchdLabel is the label of the launchd agent tunatap installs.
+	launchdLabel = "com.github.scotttball.tunatap"
+)
+
+// ErrUnsupported is returned on platforms without a supported service manager.
+var ErrUnsupported = errors.New("services are only supported with systemd (Linux) and launchd (macOS)")
+
+// Spec describes the service to install.
+type Spec struct {
+	// Executable is the absolute path of the tunatap binary.
+	Executable string
+
+	// Args are the arguments the service runs tunatap with.
+	Args []string
+
+	// LogPath is the file the service's output is appended to.
+	LogPath string
+}
+
+// Status is the state of the installed service.
+type Status struct {
+	// Installed is set when the unit or agent file exists.
+	Installed bool
+
+	// Running is set when the service manager reports the service running.
+	Running bool
+
+	// Detail is the service manager's own description of the state.
+	Detail string
+}
+
+// Manager installs and inspects the service with one service manager.
+type Manager interface {
+	// Name is the service manager, "systemd" or "launchd".
+	Name() string
+
+	// Path is where the unit or agent file is installed.
+	Path() string
+
+	// Render returns the unit or agent file for spec.
+	Render(spec *Spec) ([]byte, error)
+
+	// Install writes the file for spec and starts the service, now and at
+	// every login.
+	Install(spec *Spec) error
+
+	// Uninstall stops the service and removes its file.
+	Uninstall() error
+
+	// Status reports whether the service is installed and running.
+	Status() (*Status, error)
+}
+
+// runFunc runs a service manager command and returns its combined output.
+type runFunc func(name string, args ...string) ([]byte, error)
+
+// runCommand is the runFunc running real commands.
+func runCommand(name string, args ...string) ([]byte, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, bytes.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// Detect returns the manager for the current platform.
+func Detect() (Manager, error) {
+	switch runtime.GOOS {
+	case "linux":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate config directory: %w", err)
+		}
+		return &systemdManager{dir: filepath.Join(dir, "systemd", "user"), run: runCommand}, nil
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate home directory: %w", err)
+		}
+		return &launchdManager{dir: filepath.Join(home, "Library", "LaunchAgents"), run: runCommand}, nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// writeFile writes a service file, creating its directory.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// systemdManager installs a systemd user unit.
+type systemdManager struct {
+	dir string
+	run runFunc
+}
+
+func (m *systemdManager) Name() string { return "systemd" }
+
+func (m *systemdManager) Path() string { return filepath.Join(m.dir, unitName) }
+
+func (m *systemdManager) Render(spec *Spec) ([]byte, error) {
+	if spec.Executable == "" {
+		return nil, fmt.Errorf("no executable to run")
+	}
+
+	words := make([]string, 0, len(spec.Args)+1)
+	for _, w := range append([]string{spec.Executable}, spec.Args...) {
+		words = append(words, systemdQuote(w))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=tunatap SSH tunnels\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("After=network-online.target\n\n")
+	b.WriteString("[Service]\n")
+	b.WriteString("Type=simple\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(words, " "))
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=10\n")
+	if spec.LogPath != "" {
+		fmt.Fprintf(&b, "StandardOutput=append:%s\n", systemdEscape(spec.LogPath))
+		fmt.Fprintf(&b, "StandardError=append:%s\n", systemdEscape(spec.LogPath))
+	}
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.Bytes(), nil
+}
+
+func (m *systemdManager) Install(spec *Spec) error {
+	data, err := m.Render(spec)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(m.Path(), data); err != nil {
+		return err
+	}
+	if _, err := m.run("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	// Restart picks up a changed unit when it was already running
+	if _, err := m.run("systemctl", "--user", "enable", unitName); err != nil {
+		return err
+	}
+	_, err = m.run("systemctl", "--user", "restart", unitName)
+	return err
+}
+
+func (m *systemdManager) Uninstall() error {
+	if !fileExists(m.Path()) {
+		return fmt.Errorf("no service installed at %s", m.Path())
+	}
+	if _, err := m.run("systemctl", "--user", "disable", "--now", unitName); err != nil {
+		return err
+	}
+	if err := os.Remove(m.Path()); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", m.Path(), err)
+	}
+	_, err := m.run("systemctl", "--user", "daemon-reload")
+	return err
+}
+
+func (m *systemdManager) Status() (*Status, error) {
+	status := &Status{Installed: fileExists(m.Path())}
+	if !status.Installed {
+		return status, nil
+	}
+
+	// is-active exits non-zero for every state but active
+	out, _ := m.run("systemctl", "--user", "is-active", unitName)
+	status.Detail = strings.TrimSpace(firstLine(out))
+	status.Running = status.Detail == "active"
+	return status, nil
+}
+
+// systemdQuote quotes a word of ExecStart.
+func systemdQuote(s string) string {
+	s = systemdEscape(s)
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// systemdEscape escapes the specifiers systemd expands in unit values.
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// firstLine returns the first line of out.
+func firstLine(out []byte) string {
+	line, _, _ := strings.Cut(string(out), "\n")
+	return line
+}
+
+// launchdManager installs a launchd user agent.
+type launchdManager struct {
+	dir string
+	run runFunc
+}
+
+func (m *launchdManager) Name() string { return "launchd" }
+
+func (m *launchdManager) Path() string { return filepath.Join(m.dir, launchdLabel+".plist") }
+
+func (m *launchdManager) Render(spec *Spec) ([]byte, error) {
+	if spec.Executable == "" {
+		return nil, fmt.Errorf("no executable to run")
+	}
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	writePlistString(&b, "Label", launchdLabel)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range append([]string{spec.Executable}, spec.Args...) {
+		b.WriteString("\t\t<string>")
+		_ = xml.EscapeText(&b, []byte(arg))
+		b.WriteString("</string>\n")
+	}
+	b.WriteString("\t</array>\n")
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	// Restart after a crash, but not after a clean shutdown
+	b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	b.WriteString("\t<key>ThrottleInterval</key>\n\t<integer>10</integer>\n")
+	if spec.LogPath != "" {
+		writePlistString(&b, "StandardOutPath", spec.LogPath)
+		writePlistString(&b, "StandardErrorPath", spec.LogPath)
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes(), nil
+}
+
+// writePlistString writes a string entry of a plist dict.
+func writePlistString(b *bytes.Buffer, key, value string) {
+	fmt.Fprintf(b, "\t<key>%s</key>\n\t<string>", key)
+	_ = xml.EscapeText(b, []byte(value))
+	b.WriteString("</string>\n")
+}
+
+func (m *launchdManager) Install(spec *Spec) error {
+	data, err := m.Render(spec)
+	if err != nil {
+		return err
+	}
+	// Unload a previous version so the new one is loaded
+	if fileExists(m.Path()) {
+		_, _ = m.run("launchctl", "unload", m.Path())
+	}
+	if err := writeFile(m.Path(), data); err != nil {
+		return err
+	}
+	_, err = m.run("launchctl", "load", "-w", m.Path())
+	return err
+}
+
+func (m *launchdManager) Uninstall() error {
+	if !fileExists(m.Path()) {
+		return fmt.Errorf("no service installed at %s", m.Path())
+	}
+	if _, err := m.run("launchctl", "unload", "-w", m.Path()); err != nil {
+		return err
+	}
+	if err := os.Remove(m.Path()); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", m.Path(), err)
+	}
+	return nil
+}
+
+func (m *launchdManager) Status() (*Status, error) {
+	status := &Status{Installed: fileExists(m.Path())}
+	if !status.Installed {
+		return status, nil
+	}
+
+	out, err := m.run("launchctl", "list", launchdLabel)
+	if err != nil {
+		status.Detail = "not loaded"
+		return status, nil
+	}
+	if pid := launchdPID(out); pid != "" {
+		status.Running = true
+		status.Detail = "running (pid " + pid + ")"
+	} else {
+		status.Detail = "loaded, not running"
+	}
+	return status, nil
+}
+
+// launchdPID returns the PID from the output of launchctl list <label>, or
+// "" if the agent is not running.
+func launchdPID(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.TrimSpace(key) != `"PID"` {
+			continue
+		}
+		return strings.TrimSuffix(strings.TrimSpace(value), ";")
+	}
+	return ""
+}
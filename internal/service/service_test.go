@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRun records commands and answers them from outputs, keyed by the
+// command line.
+type fakeRun struct {
+	calls   []string
+	outputs map[string]string
+	fail    map[string]bool
+}
+
+func (f *fakeRun) run(name string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, line)
+	if f.fail[line] {
+		return []byte(f.outputs[line]), errors.New("exit status 3")
+	}
+	return []byte(f.outputs[line]), nil
+}
+
+func TestSystemdRender(t *testing.T) {
+	m := &systemdManager{dir: t.TempDir()}
+	spec := &Spec{
+		Executable: "/usr/local/bin/tunatap",
+		Args:       []string{"connect", "prod", "--config", "/home/me/my config.yaml", "--health-endpoint", "localhost:9090"},
+		LogPath:    "/home/me/.tunatap/service.log",
+	}
+
+	data, err := m.Render(spec)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	unit := string(data)
+	for _, want := range []string{
+		`ExecStart=/usr/local/bin/tunatap connect prod --config "/home/me/my config.yaml" --health-endpoint localhost:9090`,
+		"StandardOutput=append:/home/me/.tunatap/service.log",
+		"Restart=on-failure",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit missing %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := map[string]string{
+		"prod":       "prod",
+		"my cluster": `"my cluster"`,
+		`say "hi"`:   `"say \"hi\""`,
+		"50%":        "50%%",
+		"":           `""`,
+	}
+	for in, want := range tests {
+		if got := systemdQuote(in); got != want {
+			t.Errorf("systemdQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSystemdInstallAndUninstall(t *testing.T) {
+	run := &fakeRun{}
+	m := &systemdManager{dir: t.TempDir(), run: run.run}
+
+	if err := m.Install(&Spec{Executable: "/usr/local/bin/tunatap", Args: []string{"daemon", "run"}}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if _, err := os.Stat(m.Path()); err != nil {
+		t.Fatalf("unit not written: %v", err)
+	}
+	if got := run.calls[len(run.calls)-1]; got != "systemctl --user restart tunatap.service" {
+		t.Errorf("last call = %q, want restart", got)
+	}
+
+	if err := m.Uninstall(); err != nil {
+		t.Fatalf("Uninstall() error = %v", err)
+	}
+	if _, err := os.Stat(m.Path()); !os.IsNotExist(err) {
+		t.Errorf("unit still present after Uninstall()")
+	}
+	if err := m.Uninstall(); err == nil {
+		t.Error("Uninstall() of a missing unit should fail")
+	}
+}
+
+func TestSystemdStatus(t *testing.T) {
+	run := &fakeRun{
+		outputs: map[string]string{"systemctl --user is-active tunatap.service": "failed\n"},
+		fail:    map[string]bool{"systemctl --user is-active tunatap.service": true},
+	}
+	m := &systemdManager{dir: t.TempDir(), run: run.run}
+
+	status, err := m.Status()
+	if err != nil || status.Installed {
+		t.Fatalf("Status() = %+v, %v; want not installed", status, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(m.dir, unitName), []byte("[Unit]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	status, err = m.Status()
+	if err != nil || !status.Installed || status.Running || status.Detail != "failed" {
+		t.Errorf("Status() = %+v, %v; want installed and failed", status, err)
+	}
+}
+
+func TestLaunchdRender(t *testing.T) {
+	m := &launchdManager{dir: t.TempDir()}
+	data, err := m.Render(&Spec{
+		Executable: "/opt/homebrew/bin/tunatap",
+		Args:       []string{"connect", "dev&test"},
+		LogPath:    "/Users/me/.tunatap/service.log",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	plist := string(data)
+	for _, want := range []string{
+		"<string>com.github.scotttball.tunatap</string>",
+		"<string>/opt/homebrew/bin/tunatap</string>",
+		"<string>dev&amp;test</string>",
+		"<key>StandardErrorPath</key>\n\t<string>/Users/me/.tunatap/service.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+}
+
+func TestLaunchdPID(t *testing.T) {
+	out := []byte("{\n\t\"LimitLoadToSessionType\" = \"Aqua\";\n\t\"PID\" = 4242;\n\t\"Label\" = \"com.github.scotttball.tunatap\";\n};\n")
+	if got := launchdPID(out); got != "4242" {
+		t.Errorf("launchdPID() = %q, want 4242", got)
+	}
+	if got := launchdPID([]byte("{\n\t\"LastExitStatus\" = 0;\n};\n")); got != "" {
+		t.Errorf("launchdPID() = %q, want none", got)
+	}
+}
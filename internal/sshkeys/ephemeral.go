@@ -1,29 +1,78 @@
 package sshkeys
 
 import (
+	"crypto"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
+// KeyAlgorithm is the algorithm of an ephemeral key pair.
+type KeyAlgorithm string
+
+const (
+	// AlgorithmED25519 generates ED25519 keys (the default).
+	AlgorithmED25519 KeyAlgorithm = "ed25519"
+	// AlgorithmRSA4096 generates 4096-bit RSA keys, for policies that do
+	// not accept ED25519.
+	AlgorithmRSA4096 KeyAlgorithm = "rsa-4096"
+)
+
+// ParseKeyAlgorithm parses a key algorithm name. An empty name is ED25519.
+func ParseKeyAlgorithm(name string) (KeyAlgorithm, error) {
+	switch KeyAlgorithm(name) {
+	case "", AlgorithmED25519:
+		return AlgorithmED25519, nil
+	case AlgorithmRSA4096:
+		return AlgorithmRSA4096, nil
+	default:
+		return "", fmt.Errorf("invalid ephemeral key algorithm %q: must be %s or %s", name, AlgorithmED25519, AlgorithmRSA4096)
+	}
+}
+
 // EphemeralKeyPair holds an in-memory SSH key pair.
 // The keys are never written to disk, providing enhanced security
 // for bastion session authentication.
 type EphemeralKeyPair struct {
-	privateKey ed25519.PrivateKey
-	publicKey  ed25519.PublicKey
-	signer     ssh.Signer
+	algorithm KeyAlgorithm
+	publicKey crypto.PublicKey
+	signer    ssh.Signer
+	created   time.Time
 }
 
 // GenerateEphemeralKeyPair generates a new ED25519 key pair in memory.
 // The keys are cryptographically secure and suitable for SSH authentication.
 // Returns an error if key generation fails.
 func GenerateEphemeralKeyPair() (*EphemeralKeyPair, error) {
-	pub, priv, err := ed25519.GenerateKey(rand.Reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate ED25519 key: %w", err)
+	return GenerateEphemeralKeyPairWithAlgorithm(AlgorithmED25519)
+}
+
+// GenerateEphemeralKeyPairWithAlgorithm generates a new key pair of the
+// given algorithm in memory.
+func GenerateEphemeralKeyPairWithAlgorithm(algorithm KeyAlgorithm) (*EphemeralKeyPair, error) {
+	var (
+		pub  crypto.PublicKey
+		priv crypto.Signer
+	)
+	switch algorithm {
+	case AlgorithmED25519:
+		edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ED25519 key: %w", err)
+		}
+		pub, priv = edPub, edPriv
+	case AlgorithmRSA4096:
+		rsaPriv, err := rsa.GenerateKey(rand.Reader, 4096)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		pub, priv = &rsaPriv.PublicKey, rsaPriv
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
 	}
 
 	signer, err := ssh.NewSignerFromKey(priv)
@@ -32,9 +81,10 @@ func GenerateEphemeralKeyPair() (*EphemeralKeyPair, error) {
 	}
 
 	return &EphemeralKeyPair{
-		privateKey: priv,
-		publicKey:  pub,
-		signer:     signer,
+		algorithm: algorithm,
+		publicKey: pub,
+		signer:    signer,
+		created:   time.Now(),
 	}, nil
 }
 
@@ -56,7 +106,18 @@ func (e *EphemeralKeyPair) AuthMethod() ssh.AuthMethod {
 	return ssh.PublicKeys(e.signer)
 }
 
-// PublicKey returns the raw ED25519 public key.
-func (e *EphemeralKeyPair) PublicKey() ed25519.PublicKey {
+// PublicKey returns the raw public key: an ed25519.PublicKey or an
+// *rsa.PublicKey.
+func (e *EphemeralKeyPair) PublicKey() crypto.PublicKey {
 	return e.publicKey
 }
+
+// Algorithm returns the key pair's algorithm.
+func (e *EphemeralKeyPair) Algorithm() KeyAlgorithm {
+	return e.algorithm
+}
+
+// Created returns when the key pair was generated.
+func (e *EphemeralKeyPair) Created() time.Time {
+	return e.created
+}
@@ -1,6 +1,7 @@
 package sshkeys
 
 import (
+	"crypto/rsa"
 	"strings"
 	"testing"
 
@@ -90,3 +91,40 @@ func TestEphemeralKeyPair_PublicKeyParseable(t *testing.T) {
 		t.Errorf("PublicKeyString() produced unparseable key: %v", err)
 	}
 }
+
+func TestGenerateEphemeralKeyPairWithAlgorithm_RSA(t *testing.T) {
+	keyPair, err := GenerateEphemeralKeyPairWithAlgorithm(AlgorithmRSA4096)
+	if err != nil {
+		t.Fatalf("GenerateEphemeralKeyPairWithAlgorithm() error = %v", err)
+	}
+
+	if keyPair.Algorithm() != AlgorithmRSA4096 {
+		t.Errorf("Algorithm() = %q, want %q", keyPair.Algorithm(), AlgorithmRSA4096)
+	}
+	if pubKeyStr := keyPair.PublicKeyString(); !strings.HasPrefix(pubKeyStr, "ssh-rsa ") {
+		t.Errorf("PublicKeyString() = %q, want prefix 'ssh-rsa '", pubKeyStr)
+	}
+	if rsaKey, ok := keyPair.PublicKey().(*rsa.PublicKey); !ok || rsaKey.N.BitLen() != 4096 {
+		t.Errorf("PublicKey() = %T, want a 4096-bit *rsa.PublicKey", keyPair.PublicKey())
+	}
+}
+
+func TestParseKeyAlgorithm(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    KeyAlgorithm
+		wantErr bool
+	}{
+		{"", AlgorithmED25519, false},
+		{"ed25519", AlgorithmED25519, false},
+		{"rsa-4096", AlgorithmRSA4096, false},
+		{"rsa", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseKeyAlgorithm(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseKeyAlgorithm(%q) = %q, %v, want %q, wantErr %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
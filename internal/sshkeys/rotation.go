@@ -0,0 +1,88 @@
+package sshkeys
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotator hands out ephemeral key pairs according to a rotation policy.
+// With no interval every call generates a new key pair, so no key material
+// is shared between sessions. Otherwise a key pair is reused until it is
+// interval old.
+type Rotator struct {
+	algorithm KeyAlgorithm
+	interval  time.Duration
+
+	mu      sync.Mutex
+	current *EphemeralKeyPair
+	since   time.Time
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewRotator creates a rotator for keys of the given algorithm that are
+// replaced every interval, or for every session if interval is zero.
+func NewRotator(algorithm KeyAlgorithm, interval time.Duration) *Rotator {
+	return &Rotator{
+		algorithm: algorithm,
+		interval:  interval,
+		now:       time.Now,
+	}
+}
+
+// Algorithm returns the algorithm of the keys the rotator generates.
+func (r *Rotator) Algorithm() KeyAlgorithm {
+	return r.algorithm
+}
+
+// Interval returns how long a key pair is reused; zero means never.
+func (r *Rotator) Interval() time.Duration {
+	return r.interval
+}
+
+// KeyPair returns the key pair for a new session, generating a new one if
+// the policy requires it.
+func (r *Rotator) KeyPair() (*EphemeralKeyPair, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current := r.reusable(); current != nil {
+		return current, nil
+	}
+
+	keyPair, err := GenerateEphemeralKeyPairWithAlgorithm(r.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	r.current = keyPair
+	r.since = r.now()
+	return keyPair, nil
+}
+
+// Match returns the current key pair if it may still be used and its public
+// key is publicKey, in authorized_keys format. Sessions carrying that key
+// can be reused.
+func (r *Rotator) Match(publicKey string) *EphemeralKeyPair {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.reusable()
+	if current == nil || strings.TrimSpace(current.PublicKeyString()) != strings.TrimSpace(publicKey) {
+		return nil
+	}
+	return current
+}
+
+// reusable returns the current key pair if the policy allows another use.
+// r.mu must be held.
+func (r *Rotator) reusable() *EphemeralKeyPair {
+	if r.interval <= 0 || r.current == nil {
+		return nil
+	}
+	if r.now().Sub(r.since) >= r.interval {
+		return nil
+	}
+	return r.current
+}
@@ -0,0 +1,64 @@
+package sshkeys
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotator_EverySession(t *testing.T) {
+	r := NewRotator(AlgorithmED25519, 0)
+
+	first, err := r.KeyPair()
+	if err != nil {
+		t.Fatalf("KeyPair() error = %v", err)
+	}
+	second, err := r.KeyPair()
+	if err != nil {
+		t.Fatalf("KeyPair() error = %v", err)
+	}
+
+	if first.PublicKeyString() == second.PublicKeyString() {
+		t.Error("KeyPair() reused a key without a rotation interval")
+	}
+	if r.Match(second.PublicKeyString()) != nil {
+		t.Error("Match() = key pair, want nil without a rotation interval")
+	}
+}
+
+func TestRotator_Interval(t *testing.T) {
+	now := time.Now()
+	r := NewRotator(AlgorithmED25519, time.Hour)
+	r.now = func() time.Time { return now }
+
+	first, err := r.KeyPair()
+	if err != nil {
+		t.Fatalf("KeyPair() error = %v", err)
+	}
+	second, err := r.KeyPair()
+	if err != nil {
+		t.Fatalf("KeyPair() error = %v", err)
+	}
+	if first != second {
+		t.Error("KeyPair() generated a new key within the rotation interval")
+	}
+	if r.Match(first.PublicKeyString()) != first {
+		t.Error("Match() did not return the current key pair")
+	}
+
+	other, _ := GenerateEphemeralKeyPair()
+	if r.Match(other.PublicKeyString()) != nil {
+		t.Error("Match() = key pair, want nil for a different key")
+	}
+
+	now = now.Add(time.Hour)
+	if r.Match(first.PublicKeyString()) != nil {
+		t.Error("Match() = key pair, want nil once the key is due for rotation")
+	}
+	third, err := r.KeyPair()
+	if err != nil {
+		t.Fatalf("KeyPair() error = %v", err)
+	}
+	if third == first {
+		t.Error("KeyPair() reused a key past the rotation interval")
+	}
+}
@@ -8,6 +8,7 @@ import (
 type State struct {
 	mu        sync.RWMutex
 	homePath  string
+	configDir string
 	tenancies map[string]*string // name -> OCID
 }
 
@@ -40,6 +41,24 @@ func (s *State) SetHomePath(path string) {
 	s.homePath = path
 }
 
+// GetConfigDir returns the directory of the config in use and its caches:
+// that of the selected config profile, or the home path.
+func (s *State) GetConfigDir() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.configDir != "" {
+		return s.configDir
+	}
+	return s.homePath
+}
+
+// SetConfigDir sets the directory of the config in use.
+func (s *State) SetConfigDir(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configDir = path
+}
+
 // GetTenancyByName looks up a tenancy OCID by name.
 func (s *State) GetTenancyByName(name string) (*string, bool) {
 	s.mu.RLock()
@@ -0,0 +1,118 @@
+// Package tokencache keeps the tokens of OKE clusters generated for kubectl
+// until they expire.
+//
+// kubectl runs the exec credential plugin of a kubeconfig for every
+// command, so without a cache each one reads the config, loads the OCI
+// credentials and signs a new token. Tokens are kept one file per cluster,
+// region, profile and auth type, sealed like the other caches when
+// cache_encryption is set (see package cachecrypt).
+package tokencache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/cachecrypt"
+)
+
+// DirName is the directory of the cache under the tunatap home.
+const DirName = "tokens"
+
+// MinRemaining is how long a cached token must still be valid to be used,
+// so that kubectl does not send it just as it expires.
+const MinRemaining = 30 * time.Second
+
+// Key identifies the token of a cluster signed with a set of credentials.
+type Key struct {
+	ClusterID string
+	Region    string
+	Profile   string
+	AuthType  string
+}
+
+// Token is a cached token and when it expires.
+type Token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Cache is a directory of cached tokens.
+type Cache struct {
+	dir     string
+	encrypt bool
+	now     func() time.Time
+}
+
+// New returns the cache in dir, writing tokens encrypted if encrypt is set.
+func New(dir string, encrypt bool) *Cache {
+	return &Cache{dir: dir, encrypt: encrypt, now: time.Now}
+}
+
+// Get returns the token cached for key, if there is one valid for at least
+// MinRemaining.
+func (c *Cache) Get(key Key) (*Token, bool) {
+	data, err := cachecrypt.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var t Token
+	if err := json.Unmarshal(data, &t); err != nil || t.Token == "" {
+		return nil, false
+	}
+	if t.ExpiresAt.Sub(c.now()) < MinRemaining {
+		return nil, false
+	}
+	return &t, true
+}
+
+// Put caches the token t for key.
+func (c *Cache) Put(key Key, t Token) error {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create token cache: %w", err)
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to encode token: %w", err)
+	}
+	if err := cachecrypt.WriteFile(c.path(key), data, 0o600, c.encrypt); err != nil {
+		return fmt.Errorf("failed to cache token: %w", err)
+	}
+	return nil
+}
+
+// Flush removes every cached token and returns how many there were.
+func (c *Cache) Flush() (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read token cache: %w", err)
+	}
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, e.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove cached token: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// path returns the file of key. Its name is a hash, so that OCIDs and
+// profile names need no escaping.
+func (c *Cache) path(key Key) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{key.ClusterID, key.Region, key.Profile, key.AuthType}, "\x00")))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
@@ -0,0 +1,52 @@
+package tokencache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	c := New(t.TempDir(), false)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	key := Key{ClusterID: "ocid1.cluster.oc1.iad.aaaa", Region: "us-ashburn-1", Profile: "DEFAULT"}
+	if _, ok := c.Get(key); ok {
+		t.Fatal("Get() of an empty cache found a token")
+	}
+	if err := c.Put(key, Token{Token: "abc", ExpiresAt: now.Add(4 * time.Minute)}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	got, ok := c.Get(key)
+	if !ok || got.Token != "abc" || !got.ExpiresAt.Equal(now.Add(4*time.Minute)) {
+		t.Errorf("Get() = %+v, %v", got, ok)
+	}
+	other := key
+	other.Profile = "PROD"
+	if _, ok := c.Get(other); ok {
+		t.Error("Get() found the token of another profile")
+	}
+
+	// Tokens about to expire are not used
+	now = now.Add(4*time.Minute - MinRemaining + time.Second)
+	if _, ok := c.Get(key); ok {
+		t.Error("Get() returned a token about to expire")
+	}
+
+	if err := c.Put(other, Token{Token: "def", ExpiresAt: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Flush(); err != nil || n != 2 {
+		t.Errorf("Flush() = %d, %v; want 2", n, err)
+	}
+	if _, ok := c.Get(other); ok {
+		t.Error("Get() found a token after Flush()")
+	}
+}
+
+func TestFlushMissing(t *testing.T) {
+	if n, err := New(t.TempDir()+"/missing", false).Flush(); err != nil || n != 0 {
+		t.Errorf("Flush() of a missing cache = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,154 @@
+// Package tracing exports OpenTelemetry traces of connecting to a cluster
+// over OTLP, so that the time a connect takes can be broken down into
+// discovery, bastion session creation, the SSH dial and the rest.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of tunatap's spans.
+const tracerName = "github.com/scotttball/tunatap"
+
+// Attributes tunatap sets on its spans.
+const (
+	ClusterKey = attribute.Key("tunatap.cluster")
+	BastionKey = attribute.Key("tunatap.bastion_id")
+	SessionKey = attribute.Key("tunatap.session_id")
+	AttemptKey = attribute.Key("tunatap.attempt")
+)
+
+// Resolver resolves a secret reference, such as an exporter header kept in
+// Vault.
+type Resolver func(ctx context.Context, value string) (string, error)
+
+// Enabled reports whether cfg or the OTEL_EXPORTER_OTLP_* environment
+// variables configure where to export traces.
+func Enabled(cfg *config.Tracing) bool {
+	if cfg != nil && cfg.Endpoint != "" {
+		return true
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup installs a tracer provider exporting spans in batches to the OTLP
+// endpoint of cfg, or of the environment, and returns a function that
+// exports the spans still buffered and stops it. When no endpoint is
+// configured spans are not recorded and the function does nothing.
+func Setup(ctx context.Context, cfg *config.Tracing, version string, resolve Resolver) (func(), error) {
+	if !Enabled(cfg) {
+		return func() {}, nil
+	}
+	if cfg == nil {
+		cfg = &config.Tracing{}
+	}
+
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		endpoint, err := endpointURL(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	if len(cfg.Headers) > 0 {
+		headers := make(map[string]string, len(cfg.Headers))
+		for name, value := range cfg.Headers {
+			if resolve != nil {
+				resolved, err := resolve(ctx, value)
+				if err != nil {
+					return nil, fmt.Errorf("tracing header %s: %w", name, err)
+				}
+				value = resolved
+			}
+			headers[name] = value
+		}
+		opts = append(opts, otlptracehttp.WithHeaders(headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName("tunatap"), semconv.ServiceVersion(version)),
+	)
+	if err != nil {
+		// Partial resources are still worth exporting with
+		log.Debug().Err(err).Msg("Failed to detect some trace resource attributes")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.GetSampleRatio()))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warn().Err(err).Msg("Failed to export traces")
+	}))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to export traces")
+		}
+	}, nil
+}
+
+// endpointURL returns the URL traces are posted to for the collector URL
+// endpoint, adding the standard OTLP/HTTP traces path when it has none.
+func endpointURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("tracing endpoint '%s' is not a URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// Start starts a span named name as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartRoot starts a span named name in a trace of its own, linked to the
+// span in ctx, for work that follows that trace long after it ended, such
+// as reconnecting a tunnel that has been up for hours.
+func StartRoot(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed with err unless err is nil or the work
+// was cancelled.
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, context.Canceled) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useProvider makes provider the global tracer provider for the test.
+func useProvider(t *testing.T, provider *sdktrace.TracerProvider) {
+	t.Helper()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+}
+
+func TestEndpointURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		{"https://otel.example.com:4318", "https://otel.example.com:4318/v1/traces", false},
+		{"https://otel.example.com:4318/", "https://otel.example.com:4318/v1/traces", false},
+		{"https://otel.example.com/otlp/v1/traces", "https://otel.example.com/otlp/v1/traces", false},
+		{"otel.example.com:4318", "", true},
+	}
+	for _, tt := range tests {
+		got, err := endpointURL(tt.endpoint)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("endpointURL(%q) = %q, %v; want %q", tt.endpoint, got, err, tt.want)
+		}
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	if Enabled(nil) || Enabled(&config.Tracing{SampleRatio: new(float64)}) {
+		t.Error("Enabled() without an endpoint should be false")
+	}
+	stop, err := Setup(context.Background(), nil, "1.0.0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://localhost:4318/v1/traces")
+	if !Enabled(nil) {
+		t.Error("Enabled() should be true with OTEL_EXPORTER_OTLP_TRACES_ENDPOINT set")
+	}
+}
+
+func TestSetupExports(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	cfg := &config.Tracing{
+		Endpoint: server.URL,
+		Headers:  map[string]string{"Api-Key": "keychain:tunatap/otlp-key"},
+	}
+	resolve := func(_ context.Context, value string) (string, error) {
+		return strings.ToUpper(value), nil
+	}
+	stop, err := Setup(context.Background(), cfg, "1.0.0", resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Start(context.Background(), "connect", ClusterKey.String("prod"))
+	End(span, nil)
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("collector received %d requests, want 1", len(requests))
+	}
+	if requests[0].URL.Path != "/v1/traces" {
+		t.Errorf("export path = %s, want /v1/traces", requests[0].URL.Path)
+	}
+	if got := requests[0].Header.Get("Api-Key"); got != "KEYCHAIN:TUNATAP/OTLP-KEY" {
+		t.Errorf("Api-Key header = %q, want the resolved secret", got)
+	}
+
+	_, err = Setup(context.Background(), cfg, "1.0.0", func(context.Context, string) (string, error) {
+		return "", errors.New("not in keychain")
+	})
+	if err == nil || !strings.Contains(err.Error(), "tracing header Api-Key") {
+		t.Errorf("Setup() with an unresolvable header error = %v", err)
+	}
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	useProvider(t, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	_, failed := Start(context.Background(), "failed")
+	End(failed, errors.New("session create failed"))
+	_, cancelled := Start(context.Background(), "cancelled")
+	End(cancelled, context.Canceled)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended %d spans, want 2", len(spans))
+	}
+	if s := spans[0].Status(); s.Code != codes.Error || s.Description != "session create failed" {
+		t.Errorf("failed span status = %+v", s)
+	}
+	if s := spans[1].Status(); s.Code != codes.Unset {
+		t.Errorf("cancelled span status = %+v, want unset", s)
+	}
+}
+
+func TestStartRoot(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	useProvider(t, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, connect := Start(context.Background(), "connect")
+	_, attempt := Start(ctx, "tunnel.connect")
+	_, reconnect := StartRoot(ctx, "tunnel.reconnect", AttemptKey.Int(2))
+	attempt.End()
+	reconnect.End()
+	connect.End()
+
+	spans := recorder.Ended()
+	connectCtx := spans[2].SpanContext()
+	if spans[0].Parent().SpanID() != connectCtx.SpanID() || spans[0].SpanContext().TraceID() != connectCtx.TraceID() {
+		t.Error("Start() should continue the trace of the span in ctx")
+	}
+	if spans[1].SpanContext().TraceID() == connectCtx.TraceID() || spans[1].Parent().IsValid() {
+		t.Error("StartRoot() should start a trace of its own")
+	}
+	if links := spans[1].Links(); len(links) != 1 || links[0].SpanContext.SpanID() != connectCtx.SpanID() {
+		t.Errorf("StartRoot() links = %v, want the span in ctx", links)
+	}
+}
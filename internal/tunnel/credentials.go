@@ -0,0 +1,44 @@
+package tunnel
+
+import (
+	"sync"
+
+	"golang.org/x/net/proxy"
+)
+
+var (
+	credentialsMu sync.RWMutex
+	keyPassphrase string
+	socksAuth     *proxy.Auth
+)
+
+// SetKeyPassphrase sets the passphrase GetPrivateKey decrypts encrypted
+// private key files with. An empty passphrase leaves them undecrypted.
+func SetKeyPassphrase(passphrase string) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	keyPassphrase = passphrase
+}
+
+// SetSocksAuth sets the user name and password SSH connections
+// authenticate to the SOCKS proxy with. An empty user means none.
+func SetSocksAuth(user, password string) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	socksAuth = nil
+	if user != "" {
+		socksAuth = &proxy.Auth{User: user, Password: password}
+	}
+}
+
+func currentKeyPassphrase() string {
+	credentialsMu.RLock()
+	defer credentialsMu.RUnlock()
+	return keyPassphrase
+}
+
+func currentSocksAuth() *proxy.Auth {
+	credentialsMu.RLock()
+	defer credentialsMu.RUnlock()
+	return socksAuth
+}
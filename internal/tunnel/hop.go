@@ -0,0 +1,86 @@
+package tunnel
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// Hop is an SSH server reached through the connection to the previous hop,
+// or to the tunnel's Server for the first hop.
+type Hop struct {
+	Server *Endpoint
+	Config *ssh.ClientConfig
+}
+
+// AddHop appends a jump host to the chain between the tunnel's Server and its
+// destinations. Remote, forwards and SOCKS destinations are dialed from the
+// last hop. Must be called before Start().
+func (tunnel *SSHTunnel) AddHop(server string, sshConfig *ssh.ClientConfig) *Hop {
+	hop := &Hop{
+		Server: NewEndpoint(server),
+		Config: sshConfig,
+	}
+	tunnel.Hops = append(tunnel.Hops, hop)
+	return hop
+}
+
+// Dial opens an SSH connection to server, through socksProxy if it is set,
+// and on through hops. Closing the returned client closes the whole chain.
+func Dial(server string, sshConfig *ssh.ClientConfig, socksProxy string, hops ...*Hop) (*ssh.Client, error) {
+	tunnel := NewSSHTunnel("", server, sshConfig, "", 0, 0, 0, socksProxy)
+	tunnel.Hops = hops
+	return tunnel.establishServerConnection()
+}
+
+// dialHops connects through each hop in turn, starting from client, and
+// returns the client for the last hop. Closing the returned client closes
+// the whole chain.
+func dialHops(client *ssh.Client, hops []*Hop) (*ssh.Client, error) {
+	if len(hops) == 0 {
+		return client, nil
+	}
+
+	chain := []*ssh.Client{client}
+	for i, hop := range hops {
+		log.Info().Msgf("Establishing SSH connection to hop %d/%d at %s", i+1, len(hops), hop.Server.String())
+		next, err := dialThrough(chain[len(chain)-1], hop)
+		if err != nil {
+			closeChain(chain)
+			return nil, fmt.Errorf("failed to connect to hop %s: %w", hop.Server.String(), err)
+		}
+		chain = append(chain, next)
+	}
+
+	last := chain[len(chain)-1]
+	go func() {
+		// Whichever end drops first, tear down the connections underneath
+		_ = last.Wait()
+		closeChain(chain)
+	}()
+	return last, nil
+}
+
+// dialThrough opens an SSH connection to hop over client.
+func dialThrough(client *ssh.Client, hop *Hop) (*ssh.Client, error) {
+	addr := hop.Server.String()
+	conn, err := client.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, withHostKeyAlgorithms(hop.Config, addr))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// closeChain closes clients from the innermost outwards.
+func closeChain(chain []*ssh.Client) {
+	for i := len(chain) - 1; i >= 0; i-- {
+		chain[i].Close()
+	}
+}
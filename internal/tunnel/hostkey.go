@@ -0,0 +1,323 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/state"
+	"github.com/scotttball/tunatap/pkg/utils"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// managedHostsFileName is the name of tunatap's own known_hosts file in its
+// home directory.
+const managedHostsFileName = "known_hosts"
+
+// HostKeyMode decides what happens when a bastion presents a host key that
+// is not in any known_hosts file. A key that differs from a known one is
+// always rejected, whatever the mode.
+type HostKeyMode string
+
+const (
+	// HostKeyModePrompt asks the user to confirm unknown keys. Without a
+	// terminal to ask on, unknown keys are rejected.
+	HostKeyModePrompt HostKeyMode = "prompt"
+
+	// HostKeyModeAcceptNew records unknown keys in the managed hosts file
+	// without asking, like OpenSSH's StrictHostKeyChecking=accept-new.
+	HostKeyModeAcceptNew HostKeyMode = "accept-new"
+
+	// HostKeyModeStrict rejects unknown keys.
+	HostKeyModeStrict HostKeyMode = "strict"
+)
+
+// ParseHostKeyMode parses a host key mode name. An empty name means prompt.
+func ParseHostKeyMode(s string) (HostKeyMode, error) {
+	switch HostKeyMode(s) {
+	case "", HostKeyModePrompt:
+		return HostKeyModePrompt, nil
+	case HostKeyModeAcceptNew:
+		return HostKeyModeAcceptNew, nil
+	case HostKeyModeStrict:
+		return HostKeyModeStrict, nil
+	default:
+		return "", fmt.Errorf("unknown host key policy %q (expected %q, %q or %q)",
+			s, HostKeyModePrompt, HostKeyModeAcceptNew, HostKeyModeStrict)
+	}
+}
+
+// HostKeyPromptFunc asks the user whether to trust key for host.
+type HostKeyPromptFunc func(host string, key ssh.PublicKey) (bool, error)
+
+// HostKeyPolicy controls how bastion host keys are verified.
+type HostKeyPolicy struct {
+	Mode HostKeyMode
+
+	// KnownHostsFiles are read-only known_hosts files consulted in addition
+	// to ManagedHostsFile. Files that do not exist are skipped.
+	KnownHostsFiles []string
+
+	// ManagedHostsFile is owned by tunatap; accepted keys are written here.
+	ManagedHostsFile string
+
+	// Prompt confirms unknown keys in HostKeyModePrompt. Nil means there is
+	// no one to ask.
+	Prompt HostKeyPromptFunc
+
+	// Insecure disables host key verification entirely.
+	Insecure bool
+}
+
+// GetManagedHostsFilePath returns the path to tunatap's own known_hosts
+// file, respecting the configured home path.
+func GetManagedHostsFilePath() string {
+	if homePath := state.GetInstance().GetHomePath(); homePath != "" {
+		return filepath.Join(homePath, managedHostsFileName)
+	}
+	return filepath.Join(utils.DefaultTunatapDir(), managedHostsFileName)
+}
+
+// DefaultHostKeyPolicy returns a policy that checks ~/.ssh/known_hosts and
+// the managed hosts file, and rejects unknown keys since it has no prompt.
+func DefaultHostKeyPolicy() *HostKeyPolicy {
+	return &HostKeyPolicy{
+		Mode:             HostKeyModePrompt,
+		KnownHostsFiles:  []string{GetHostKeyFilePath()},
+		ManagedHostsFile: GetManagedHostsFilePath(),
+	}
+}
+
+var (
+	hostKeyPolicyMu sync.RWMutex
+	hostKeyPolicy   *HostKeyPolicy
+)
+
+// SetHostKeyPolicy sets the policy used by the CreateSSHClientConfig
+// functions. Passing nil restores DefaultHostKeyPolicy.
+func SetHostKeyPolicy(policy *HostKeyPolicy) {
+	hostKeyPolicyMu.Lock()
+	defer hostKeyPolicyMu.Unlock()
+	hostKeyPolicy = policy
+}
+
+// CurrentHostKeyPolicy returns the policy used by the CreateSSHClientConfig
+// functions.
+func CurrentHostKeyPolicy() *HostKeyPolicy {
+	hostKeyPolicyMu.RLock()
+	defer hostKeyPolicyMu.RUnlock()
+	if hostKeyPolicy == nil {
+		return DefaultHostKeyPolicy()
+	}
+	return hostKeyPolicy
+}
+
+// HostKeyMismatchError is returned when a host presents a key different from
+// the one on record.
+type HostKeyMismatchError struct {
+	Host     string
+	Filename string
+	Line     int
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key for %s does not match the key recorded in %s:%d; "+
+		"the bastion may be impersonated. Remove that line only if the key change is expected",
+		e.Host, e.Filename, e.Line)
+}
+
+// Callback builds an ssh.HostKeyCallback that enforces the policy.
+func (p *HostKeyPolicy) Callback() (ssh.HostKeyCallback, error) {
+	if p.Insecure {
+		log.Warn().Msg("Host key verification is DISABLED (--insecure-host-key); connections can be intercepted")
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	if err := ensureFile(p.ManagedHostsFile); err != nil {
+		return nil, fmt.Errorf("failed to create managed hosts file: %w", err)
+	}
+
+	v := &hostKeyVerifier{policy: p}
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+	return v.check, nil
+}
+
+// knownHosts reads those of the managed hosts file and KnownHostsFiles that
+// exist.
+func (p *HostKeyPolicy) knownHosts() (ssh.HostKeyCallback, error) {
+	var files []string
+	for _, f := range append([]string{p.ManagedHostsFile}, p.KnownHostsFiles...) {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+	return knownhosts.New(files...)
+}
+
+// HostKeyAlgorithms returns the host key algorithms of the keys known for
+// addr, to ask the host for one of them, or nil if none are known or keys
+// are not verified. Without them a host with keys of several types could
+// present one of a type not on record, which would look like a changed key.
+func (p *HostKeyPolicy) HostKeyAlgorithms(addr string) []string {
+	if p.Insecure {
+		return nil
+	}
+	known, err := p.knownHosts()
+	if err != nil {
+		return nil
+	}
+
+	// Checking a key that matches nothing lists the keys known for addr
+	var keyErr *knownhosts.KeyError
+	if !errors.As(known(addr, &net.TCPAddr{IP: net.IPv4zero}, placeholderKey{}), &keyErr) {
+		return nil
+	}
+	var algorithms []string
+	for _, want := range keyErr.Want {
+		for _, algo := range keyAlgorithms(want.Key.Type()) {
+			if !slices.Contains(algorithms, algo) {
+				algorithms = append(algorithms, algo)
+			}
+		}
+	}
+	return algorithms
+}
+
+// keyAlgorithms returns the signature algorithms a host key of keyType can
+// be used with.
+func keyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// placeholderKey is a public key that matches no known key.
+type placeholderKey struct{}
+
+func (placeholderKey) Type() string                        { return "placeholder" }
+func (placeholderKey) Marshal() []byte                     { return []byte("placeholder") }
+func (placeholderKey) Verify([]byte, *ssh.Signature) error { return errors.New("placeholder key") }
+
+// withHostKeyAlgorithms returns config for dialing addr, restricted to the
+// host key algorithms of the keys known for it by the current policy.
+func withHostKeyAlgorithms(config *ssh.ClientConfig, addr string) *ssh.ClientConfig {
+	if len(config.HostKeyAlgorithms) > 0 {
+		return config
+	}
+	algorithms := CurrentHostKeyPolicy().HostKeyAlgorithms(addr)
+	if len(algorithms) == 0 {
+		return config
+	}
+	c := *config
+	c.HostKeyAlgorithms = algorithms
+	return &c
+}
+
+// hostKeyVerifier checks keys against the policy's known_hosts files and
+// reloads them after a key has been accepted.
+type hostKeyVerifier struct {
+	policy *HostKeyPolicy
+
+	mu    sync.Mutex
+	known ssh.HostKeyCallback
+}
+
+func (v *hostKeyVerifier) load() error {
+	known, err := v.policy.knownHosts()
+	if err != nil {
+		return fmt.Errorf("failed to load known hosts: %w", err)
+	}
+	v.known = known
+	return nil
+}
+
+func (v *hostKeyVerifier) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	// Serialized so that concurrent dials to a new host prompt only once
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	err := v.known(hostname, remote, key)
+	if err == nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return err
+	}
+	if len(keyErr.Want) > 0 {
+		want := keyErr.Want[0]
+		return &HostKeyMismatchError{Host: hostname, Filename: want.Filename, Line: want.Line}
+	}
+
+	if err := v.acceptUnknown(hostname, key); err != nil {
+		return err
+	}
+	if err := appendKnownHost(v.policy.ManagedHostsFile, hostname, key); err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	return v.load()
+}
+
+// acceptUnknown returns nil if the policy allows trusting an unknown key.
+func (v *hostKeyVerifier) acceptUnknown(hostname string, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	switch v.policy.Mode {
+	case HostKeyModeAcceptNew:
+		log.Warn().Msgf("Trusting new host key for %s (%s %s), recorded in %s",
+			hostname, key.Type(), fingerprint, v.policy.ManagedHostsFile)
+		return nil
+	case HostKeyModeStrict:
+		return fmt.Errorf("host key for %s (%s %s) is not known and host key policy is %s",
+			hostname, key.Type(), fingerprint, HostKeyModeStrict)
+	}
+
+	if v.policy.Prompt == nil {
+		return fmt.Errorf("host key for %s (%s %s) is not known and there is no terminal to confirm it; "+
+			"connect once interactively or set ssh_host_key_policy to %s",
+			hostname, key.Type(), fingerprint, HostKeyModeAcceptNew)
+	}
+
+	ok, err := v.policy.Prompt(hostname, key)
+	if err != nil {
+		return fmt.Errorf("failed to confirm host key: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("host key for %s rejected", hostname)
+	}
+	return nil
+}
+
+// ensureFile creates path and its directory if they do not exist.
+func ensureFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// appendKnownHost appends a known_hosts line for host to path.
+func appendKnownHost(path, host string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(knownhosts.Line([]string{knownhosts.Normalize(host)}, key) + "\n")
+	return err
+}
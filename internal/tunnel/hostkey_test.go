@@ -0,0 +1,288 @@
+package tunnel
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/scotttball/tunatap/internal/state"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert key: %v", err)
+	}
+	return key
+}
+
+func newTestPolicy(t *testing.T, mode HostKeyMode) *HostKeyPolicy {
+	t.Helper()
+	dir := t.TempDir()
+	return &HostKeyPolicy{
+		Mode:             mode,
+		KnownHostsFiles:  []string{filepath.Join(dir, "ssh_known_hosts")},
+		ManagedHostsFile: filepath.Join(dir, "tunatap", "known_hosts"),
+	}
+}
+
+var testHostAddr = &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}
+
+const testHost = "host.bastion.us-ashburn-1.oci.oraclecloud.com:22"
+
+func TestParseHostKeyMode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  HostKeyMode
+	}{
+		{"", HostKeyModePrompt},
+		{"prompt", HostKeyModePrompt},
+		{"accept-new", HostKeyModeAcceptNew},
+		{"strict", HostKeyModeStrict},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseHostKeyMode(tt.input)
+		if err != nil {
+			t.Errorf("ParseHostKeyMode(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseHostKeyMode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := ParseHostKeyMode("yes"); err == nil {
+		t.Error("ParseHostKeyMode(\"yes\") should error")
+	}
+}
+
+func TestHostKeyPolicyKnownHost(t *testing.T) {
+	policy := newTestPolicy(t, HostKeyModeStrict)
+	key := newTestHostKey(t)
+
+	if err := appendKnownHost(policy.KnownHostsFiles[0], testHost, key); err != nil {
+		t.Fatalf("appendKnownHost() error = %v", err)
+	}
+
+	callback, err := policy.Callback()
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if err := callback(testHost, testHostAddr, key); err != nil {
+		t.Errorf("callback() for known key error = %v", err)
+	}
+}
+
+func TestHostKeyPolicyMismatch(t *testing.T) {
+	policy := newTestPolicy(t, HostKeyModeAcceptNew)
+
+	if err := appendKnownHost(policy.KnownHostsFiles[0], testHost, newTestHostKey(t)); err != nil {
+		t.Fatalf("appendKnownHost() error = %v", err)
+	}
+
+	callback, err := policy.Callback()
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+
+	err = callback(testHost, testHostAddr, newTestHostKey(t))
+	var mismatch *HostKeyMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("callback() error = %v, want HostKeyMismatchError", err)
+	}
+	if mismatch.Filename != policy.KnownHostsFiles[0] || mismatch.Line != 1 {
+		t.Errorf("mismatch location = %s:%d, want %s:1", mismatch.Filename, mismatch.Line, policy.KnownHostsFiles[0])
+	}
+}
+
+func TestHostKeyPolicyHostKeyAlgorithms(t *testing.T) {
+	policy := newTestPolicy(t, HostKeyModeStrict)
+	if got := policy.HostKeyAlgorithms(testHost); got != nil {
+		t.Errorf("HostKeyAlgorithms() of an unknown host = %v, want nil", got)
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ssh.NewPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := appendKnownHost(policy.KnownHostsFiles[0], testHost, newTestHostKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureFile(policy.ManagedHostsFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendKnownHost(policy.ManagedHostsFile, testHost, ecdsaKey); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{ssh.KeyAlgoECDSA256, ssh.KeyAlgoED25519}
+	if got := policy.HostKeyAlgorithms(testHost); !slices.Equal(got, want) {
+		t.Errorf("HostKeyAlgorithms() = %v, want %v", got, want)
+	}
+	if got := keyAlgorithms(ssh.KeyAlgoRSA); !slices.Contains(got, ssh.KeyAlgoRSASHA256) {
+		t.Errorf("keyAlgorithms(%s) = %v, want the SHA-2 signatures", ssh.KeyAlgoRSA, got)
+	}
+
+	policy.Insecure = true
+	if got := policy.HostKeyAlgorithms(testHost); got != nil {
+		t.Errorf("HostKeyAlgorithms() without verification = %v, want nil", got)
+	}
+}
+
+func TestGetManagedHostsFilePath(t *testing.T) {
+	s := state.GetInstance()
+	orig := s.GetHomePath()
+	defer s.SetHomePath(orig)
+
+	home := t.TempDir()
+	s.SetHomePath(home)
+	if got, want := GetManagedHostsFilePath(), filepath.Join(home, "known_hosts"); got != want {
+		t.Errorf("GetManagedHostsFilePath() = %q, want %q", got, want)
+	}
+}
+
+func TestHostKeyPolicyStrictRejectsUnknown(t *testing.T) {
+	policy := newTestPolicy(t, HostKeyModeStrict)
+
+	callback, err := policy.Callback()
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if err := callback(testHost, testHostAddr, newTestHostKey(t)); err == nil {
+		t.Error("callback() should reject unknown key in strict mode")
+	}
+}
+
+func TestHostKeyPolicyAcceptNewRecordsKey(t *testing.T) {
+	policy := newTestPolicy(t, HostKeyModeAcceptNew)
+	key := newTestHostKey(t)
+
+	callback, err := policy.Callback()
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if err := callback(testHost, testHostAddr, key); err != nil {
+		t.Fatalf("callback() error = %v", err)
+	}
+
+	data, err := os.ReadFile(policy.ManagedHostsFile)
+	if err != nil {
+		t.Fatalf("Failed to read managed hosts file: %v", err)
+	}
+	if !strings.Contains(string(data), "host.bastion.us-ashburn-1.oci.oraclecloud.com") {
+		t.Errorf("managed hosts file = %q, should contain the host", data)
+	}
+
+	// The user's own known_hosts file is never written
+	if _, err := os.Stat(policy.KnownHostsFiles[0]); !os.IsNotExist(err) {
+		t.Error("accept-new should not create the user's known_hosts file")
+	}
+
+	// A strict policy over the same files now trusts the key
+	policy.Mode = HostKeyModeStrict
+	callback, err = policy.Callback()
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if err := callback(testHost, testHostAddr, key); err != nil {
+		t.Errorf("callback() after accept error = %v", err)
+	}
+}
+
+func TestHostKeyPolicyPrompt(t *testing.T) {
+	key := newTestHostKey(t)
+
+	t.Run("no terminal", func(t *testing.T) {
+		policy := newTestPolicy(t, HostKeyModePrompt)
+		callback, err := policy.Callback()
+		if err != nil {
+			t.Fatalf("Callback() error = %v", err)
+		}
+		if err := callback(testHost, testHostAddr, key); err == nil {
+			t.Error("callback() should reject unknown key without a prompt")
+		}
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		policy := newTestPolicy(t, HostKeyModePrompt)
+		policy.Prompt = func(string, ssh.PublicKey) (bool, error) { return false, nil }
+		callback, err := policy.Callback()
+		if err != nil {
+			t.Fatalf("Callback() error = %v", err)
+		}
+		if err := callback(testHost, testHostAddr, key); err == nil {
+			t.Error("callback() should reject a declined key")
+		}
+	})
+
+	t.Run("accepted once", func(t *testing.T) {
+		policy := newTestPolicy(t, HostKeyModePrompt)
+		prompts := 0
+		policy.Prompt = func(host string, got ssh.PublicKey) (bool, error) {
+			prompts++
+			if host != testHost {
+				t.Errorf("prompt host = %q, want %q", host, testHost)
+			}
+			return true, nil
+		}
+		callback, err := policy.Callback()
+		if err != nil {
+			t.Fatalf("Callback() error = %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := callback(testHost, testHostAddr, key); err != nil {
+				t.Fatalf("callback() error = %v", err)
+			}
+		}
+		if prompts != 1 {
+			t.Errorf("prompted %d times, want 1", prompts)
+		}
+	})
+}
+
+func TestHostKeyPolicyInsecure(t *testing.T) {
+	policy := newTestPolicy(t, HostKeyModeStrict)
+	policy.Insecure = true
+
+	callback, err := policy.Callback()
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if err := callback(testHost, testHostAddr, newTestHostKey(t)); err != nil {
+		t.Errorf("insecure callback() error = %v", err)
+	}
+}
+
+func TestSetHostKeyPolicy(t *testing.T) {
+	defer SetHostKeyPolicy(nil)
+
+	policy := newTestPolicy(t, HostKeyModeStrict)
+	SetHostKeyPolicy(policy)
+	if got := CurrentHostKeyPolicy(); got != policy {
+		t.Error("CurrentHostKeyPolicy() should return the policy that was set")
+	}
+
+	SetHostKeyPolicy(nil)
+	if got := CurrentHostKeyPolicy(); got.Mode != HostKeyModePrompt || got.ManagedHostsFile != GetManagedHostsFilePath() {
+		t.Errorf("CurrentHostKeyPolicy() after reset = %+v, want default policy", got)
+	}
+}
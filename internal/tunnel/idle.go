@@ -0,0 +1,90 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/pool"
+)
+
+// IdleAction is what a tunnel does once it has been idle for its IdleTimeout.
+type IdleAction string
+
+const (
+	// IdleActionShutdown closes the tunnel; Start returns ErrIdleTimeout.
+	IdleActionShutdown IdleAction = "shutdown"
+
+	// IdleActionShrink closes all idle pooled SSH connections but keeps the
+	// local listener open. Connections are re-established on the next request.
+	IdleActionShrink IdleAction = "shrink"
+)
+
+// maxIdleCheckInterval caps how often the idle watcher wakes up.
+const maxIdleCheckInterval = 30 * time.Second
+
+// ErrIdleTimeout is returned by Start when the tunnel shut itself down after
+// carrying no traffic for its IdleTimeout.
+var ErrIdleTimeout = errors.New("tunnel idle timeout reached")
+
+// ParseIdleAction parses an idle action name. An empty name means shutdown.
+func ParseIdleAction(s string) (IdleAction, error) {
+	switch IdleAction(s) {
+	case "", IdleActionShutdown:
+		return IdleActionShutdown, nil
+	case IdleActionShrink:
+		return IdleActionShrink, nil
+	default:
+		return "", fmt.Errorf("unknown idle action %q (expected %q or %q)", s, IdleActionShutdown, IdleActionShrink)
+	}
+}
+
+// SetIdleTimeout enables idle detection: after timeout without traffic and
+// with no open connections, action is taken. Must be called before Start().
+func (tunnel *SSHTunnel) SetIdleTimeout(timeout time.Duration, action IdleAction) {
+	tunnel.IdleTimeout = timeout
+	tunnel.IdleAction = action
+}
+
+// watchIdle applies the tunnel's IdleAction whenever it has carried no
+// traffic for IdleTimeout, until ctx is cancelled.
+func (tunnel *SSHTunnel) watchIdle(ctx context.Context, connPool *pool.ConnectionPool) {
+	interval := tunnel.IdleTimeout / 4
+	if interval > maxIdleCheckInterval {
+		interval = maxIdleCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if tunnel.Metrics.activeConns.Load() > 0 || tunnel.Metrics.idleFor() < tunnel.IdleTimeout {
+			tunnel.idleShrunk.Store(false)
+			continue
+		}
+
+		switch tunnel.IdleAction {
+		case IdleActionShrink:
+			// Shrink once per idle period; new traffic re-arms it
+			if tunnel.idleShrunk.Load() {
+				continue
+			}
+			closed := connPool.Shrink(0)
+			log.Info().Msgf("Tunnel idle for %s, closed %d pooled SSH connections", tunnel.IdleTimeout, closed)
+			tunnel.idleShrunk.Store(true)
+		default:
+			log.Info().Msgf("Tunnel idle for %s, shutting down", tunnel.IdleTimeout)
+			tunnel.idleShutdown.Store(true)
+			tunnel.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/pool"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseIdleAction(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    IdleAction
+		wantErr bool
+	}{
+		{"", IdleActionShutdown, false},
+		{"shutdown", IdleActionShutdown, false},
+		{"shrink", IdleActionShrink, false},
+		{"sleep", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIdleAction(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseIdleAction(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseIdleAction(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func newIdleTestPool(t *testing.T, warmup int) *pool.ConnectionPool {
+	t.Helper()
+	connPool, err := pool.NewConnectionPool(5, 10, func() (*ssh.Client, error) { return nil, nil }, warmup)
+	if err != nil {
+		t.Fatalf("NewConnectionPool() error = %v", err)
+	}
+	t.Cleanup(connPool.Close)
+	return connPool
+}
+
+func TestWatchIdleShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tunnel := &SSHTunnel{listener: listener}
+	tunnel.SetIdleTimeout(40*time.Millisecond, IdleActionShutdown)
+	tunnel.Metrics.touch()
+
+	done := make(chan struct{})
+	go func() {
+		tunnel.watchIdle(context.Background(), newIdleTestPool(t, 0))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchIdle did not shut down an idle tunnel")
+	}
+
+	if !tunnel.idleShutdown.Load() {
+		t.Error("idleShutdown should be set")
+	}
+	if _, err := listener.Accept(); err == nil {
+		t.Error("listener should be closed")
+	}
+}
+
+func TestWatchIdleShrink(t *testing.T) {
+	connPool := newIdleTestPool(t, 3)
+
+	tunnel := &SSHTunnel{}
+	tunnel.SetIdleTimeout(40*time.Millisecond, IdleActionShrink)
+	tunnel.Metrics.touch()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tunnel.watchIdle(ctx, connPool)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for connPool.Size() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("pool size = %d, want 0 after idle shrink", connPool.Size())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if tunnel.idleShutdown.Load() {
+		t.Error("shrink should not shut the tunnel down")
+	}
+}
+
+func TestWatchIdleActiveConnection(t *testing.T) {
+	connPool := newIdleTestPool(t, 2)
+
+	tunnel := &SSHTunnel{}
+	tunnel.SetIdleTimeout(20*time.Millisecond, IdleActionShrink)
+	tunnel.Metrics.connOpened()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go tunnel.watchIdle(ctx, connPool)
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	if connPool.Size() != 2 {
+		t.Errorf("pool size = %d, want 2 while a connection is open", connPool.Size())
+	}
+}
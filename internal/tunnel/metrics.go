@@ -0,0 +1,107 @@
+package tunnel
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics tracks traffic and connection statistics for a tunnel.
+// The zero value is ready to use and safe for concurrent updates.
+type Metrics struct {
+	bytesIn         atomic.Int64
+	bytesOut        atomic.Int64
+	activeConns     atomic.Int64
+	peakConns       atomic.Int64
+	totalConns      atomic.Int64
+	closedConns     atomic.Int64
+	totalDurationNs atomic.Int64
+	maxDurationNs   atomic.Int64
+	lastActivityNs  atomic.Int64
+}
+
+// Stats is a point-in-time snapshot of a tunnel's metrics.
+type Stats struct {
+	// BytesIn is the number of bytes received from remote endpoints.
+	BytesIn int64
+	// BytesOut is the number of bytes sent to remote endpoints.
+	BytesOut int64
+	// ActiveConnections is the number of connections currently being forwarded.
+	ActiveConnections int64
+	// PeakConnections is the most connections forwarded at once since start.
+	PeakConnections int64
+	// TotalConnections is the number of connections forwarded since start.
+	TotalConnections int64
+	// AvgConnectionDuration is the mean lifetime of closed connections.
+	AvgConnectionDuration time.Duration
+	// MaxConnectionDuration is the longest lifetime of a closed connection.
+	MaxConnectionDuration time.Duration
+	// PoolSize is the number of SSH connections in the pool.
+	PoolSize int
+	// PoolActiveUses is the number of forwarded connections using the pool.
+	PoolActiveUses int
+	// PoolCapacity is the maximum number of concurrent uses the pool allows.
+	PoolCapacity int
+}
+
+// connOpened records the start of a forwarded connection.
+func (m *Metrics) connOpened() {
+	active := m.activeConns.Add(1)
+	m.totalConns.Add(1)
+	m.touch()
+
+	for {
+		peak := m.peakConns.Load()
+		if active <= peak || m.peakConns.CompareAndSwap(peak, active) {
+			return
+		}
+	}
+}
+
+// connClosed records the end of a forwarded connection that lasted d.
+func (m *Metrics) connClosed(d time.Duration) {
+	m.activeConns.Add(-1)
+	m.closedConns.Add(1)
+	m.totalDurationNs.Add(int64(d))
+	m.touch()
+
+	for {
+		current := m.maxDurationNs.Load()
+		if int64(d) <= current || m.maxDurationNs.CompareAndSwap(current, int64(d)) {
+			return
+		}
+	}
+}
+
+// touch records that the tunnel carried traffic just now.
+func (m *Metrics) touch() {
+	m.lastActivityNs.Store(time.Now().UnixNano())
+}
+
+// idleFor returns how long the tunnel has gone without traffic, or zero if
+// it has never carried any.
+func (m *Metrics) idleFor() time.Duration {
+	last := m.lastActivityNs.Load()
+	if last == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, last))
+}
+
+// Snapshot returns the current traffic and connection counters.
+// Pool fields are left zero; use SSHTunnel.Stats for a complete view.
+func (m *Metrics) Snapshot() Stats {
+	stats := Stats{
+		BytesIn:               m.bytesIn.Load(),
+		BytesOut:              m.bytesOut.Load(),
+		ActiveConnections:     m.activeConns.Load(),
+		PeakConnections:       m.peakConns.Load(),
+		TotalConnections:      m.totalConns.Load(),
+		MaxConnectionDuration: time.Duration(m.maxDurationNs.Load()),
+	}
+
+	if closed := m.closedConns.Load(); closed > 0 {
+		stats.AvgConnectionDuration = time.Duration(m.totalDurationNs.Load() / closed)
+	}
+
+	return stats
+}
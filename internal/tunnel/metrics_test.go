@@ -0,0 +1,201 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMetricsSnapshot(t *testing.T) {
+	var m Metrics
+
+	m.connOpened()
+	m.connOpened()
+	m.connClosed(2 * time.Second)
+
+	stats := m.Snapshot()
+	if stats.ActiveConnections != 1 {
+		t.Errorf("ActiveConnections = %d, want 1", stats.ActiveConnections)
+	}
+	if stats.TotalConnections != 2 {
+		t.Errorf("TotalConnections = %d, want 2", stats.TotalConnections)
+	}
+	if stats.PeakConnections != 2 {
+		t.Errorf("PeakConnections = %d, want 2", stats.PeakConnections)
+	}
+
+	m.connClosed(4 * time.Second)
+
+	stats = m.Snapshot()
+	if stats.ActiveConnections != 0 {
+		t.Errorf("ActiveConnections = %d, want 0", stats.ActiveConnections)
+	}
+	if stats.AvgConnectionDuration != 3*time.Second {
+		t.Errorf("AvgConnectionDuration = %s, want 3s", stats.AvgConnectionDuration)
+	}
+	if stats.MaxConnectionDuration != 4*time.Second {
+		t.Errorf("MaxConnectionDuration = %s, want 4s", stats.MaxConnectionDuration)
+	}
+}
+
+func TestMetricsPeakConnections(t *testing.T) {
+	var m Metrics
+
+	for i := 0; i < 3; i++ {
+		m.connOpened()
+	}
+	for i := 0; i < 3; i++ {
+		m.connClosed(time.Second)
+	}
+	m.connOpened()
+
+	if stats := m.Snapshot(); stats.PeakConnections != 3 || stats.ActiveConnections != 1 {
+		t.Errorf("PeakConnections = %d with %d active, want 3 with 1", stats.PeakConnections, stats.ActiveConnections)
+	}
+}
+
+func TestPipeConnectionsCountsBytes(t *testing.T) {
+	tunnel := &SSHTunnel{}
+
+	// client <-> localConn and remoteConn <-> server
+	client, localConn := net.Pipe()
+	remoteConn, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan struct{})
+	go func() {
+		tunnel.pipeConnections(context.Background(), localConn, remoteConn)
+		close(done)
+	}()
+
+	// Server echoes a fixed-size reply
+	go func() {
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(server, buf); err != nil {
+			return
+		}
+		_, _ = server.Write([]byte("pong!!"))
+		server.Close()
+	}()
+
+	if _, err := client.Write([]byte("ping!")); err != nil {
+		t.Fatalf("client Write() error = %v", err)
+	}
+	reply, _ := io.ReadAll(client)
+	if string(reply) != "pong!!" {
+		t.Errorf("reply = %q, want %q", reply, "pong!!")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeConnections did not return")
+	}
+
+	stats := tunnel.Stats()
+	if stats.BytesOut != 5 {
+		t.Errorf("BytesOut = %d, want 5", stats.BytesOut)
+	}
+	if stats.BytesIn != 6 {
+		t.Errorf("BytesIn = %d, want 6", stats.BytesIn)
+	}
+	if stats.TotalConnections != 1 || stats.ActiveConnections != 0 {
+		t.Errorf("connections = %d total / %d active, want 1 / 0", stats.TotalConnections, stats.ActiveConnections)
+	}
+}
+
+// tcpPair returns both ends of a loopback TCP connection.
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	dialed, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	return dialed, accepted
+}
+
+func TestPipeConnectionsHalfClose(t *testing.T) {
+	tunnel := &SSHTunnel{}
+
+	client, localConn := tcpPair(t)
+	remoteConn, server := tcpPair(t)
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan struct{})
+	go func() {
+		tunnel.pipeConnections(context.Background(), localConn, remoteConn)
+		localConn.Close()
+		remoteConn.Close()
+		close(done)
+	}()
+
+	// Server reads the whole request, which only ends with the client's
+	// FIN, then replies on the still-open other half
+	go func() {
+		request, err := io.ReadAll(server)
+		if err != nil {
+			return
+		}
+		_, _ = server.Write(append([]byte("got "), request...))
+		server.Close()
+	}()
+
+	if _, err := client.Write([]byte("dump")); err != nil {
+		t.Fatalf("client Write() error = %v", err)
+	}
+	if err := client.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatalf("CloseWrite() error = %v", err)
+	}
+
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("client ReadAll() error = %v", err)
+	}
+	if string(reply) != "got dump" {
+		t.Errorf("reply = %q, want %q", reply, "got dump")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeConnections did not return")
+	}
+}
+
+func BenchmarkPipeConnections(b *testing.B) {
+	tunnel := &SSHTunnel{}
+	payload := make([]byte, copyBufferSize)
+
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+
+	client, localConn := net.Pipe()
+	remoteConn, server := net.Pipe()
+	go tunnel.pipeConnections(context.Background(), localConn, remoteConn)
+	go func() { _, _ = io.Copy(io.Discard, server) }()
+	defer client.Close()
+	defer server.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Write(payload); err != nil {
+			b.Fatalf("Write() error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package tunnel
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// minRateLimitBurst lets a full copy buffer through a limiter without waiting.
+const minRateLimitBurst = 32 * 1024
+
+// rateLimiter is a token bucket that limits throughput to a number of bytes
+// per second. A nil *rateLimiter imposes no limit.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter for bytesPerSecond, or nil if the limit
+// is not positive.
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	burst := float64(bytesPerSecond)
+	if burst < minRateLimitBurst {
+		burst = minRateLimitBurst
+	}
+
+	return &rateLimiter{
+		rate:   float64(bytesPerSecond),
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until n bytes may pass or ctx is done. Callers reserve bytes
+// up front, so concurrent callers queue behind each other fairly.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
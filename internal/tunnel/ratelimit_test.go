@@ -0,0 +1,64 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiterUnlimited(t *testing.T) {
+	if l := newRateLimiter(0); l != nil {
+		t.Errorf("newRateLimiter(0) = %v, want nil", l)
+	}
+
+	// A nil limiter never blocks
+	var l *rateLimiter
+	if err := l.wait(context.Background(), 1<<30); err != nil {
+		t.Errorf("nil limiter wait() error = %v", err)
+	}
+}
+
+func TestRateLimiterThrottles(t *testing.T) {
+	// 64 KiB/s with a 64 KiB burst: the second 64 KiB waits about a second
+	l := newRateLimiter(64 * 1024)
+
+	start := time.Now()
+	if err := l.wait(context.Background(), 64*1024); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("burst wait took %s, want immediate", elapsed)
+	}
+
+	start = time.Now()
+	if err := l.wait(context.Background(), 16*1024); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("throttled wait took %s, want about 250ms", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := newRateLimiter(1024)
+	_ = l.wait(context.Background(), minRateLimitBurst)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.wait(ctx, 1024*1024); err == nil {
+		t.Error("wait() should return an error when ctx is cancelled")
+	}
+}
+
+func TestSetBandwidthLimits(t *testing.T) {
+	tunnel := &SSHTunnel{}
+	tunnel.SetBandwidthLimits(10*1024*1024, 0)
+
+	if tunnel.bandwidth == nil {
+		t.Error("tunnel-wide limiter should be set")
+	}
+	if tunnel.MaxConnectionBandwidth != 0 {
+		t.Errorf("MaxConnectionBandwidth = %d, want 0", tunnel.MaxConnectionBandwidth)
+	}
+}
@@ -0,0 +1,130 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// SOCKS5 protocol constants (RFC 1928).
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone         = 0x00
+	socks5AuthNoAcceptable = 0xff
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5ReplySucceeded           = 0x00
+	socks5ReplyGeneralFailure      = 0x01
+	socks5ReplyHostUnreachable     = 0x04
+	socks5ReplyCommandNotSupported = 0x07
+	socks5ReplyAddrNotSupported    = 0x08
+)
+
+var (
+	errSocksVersion     = errors.New("unsupported SOCKS version")
+	errSocksAuth        = errors.New("no acceptable SOCKS authentication method")
+	errSocksCommand     = errors.New("unsupported SOCKS command")
+	errSocksAddressType = errors.New("unsupported SOCKS address type")
+)
+
+// readSocksRequest performs the SOCKS5 greeting on conn and reads a CONNECT
+// request, returning the requested destination as host:port.
+// Only the "no authentication" method and the CONNECT command are supported;
+// an appropriate failure reply is sent to the client for anything else.
+func readSocksRequest(conn net.Conn) (string, error) {
+	// Greeting: VER NMETHODS METHODS...
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", fmt.Errorf("failed to read SOCKS greeting: %w", err)
+	}
+	if header[0] != socks5Version {
+		return "", errSocksVersion
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", fmt.Errorf("failed to read SOCKS methods: %w", err)
+	}
+
+	noAuth := false
+	for _, m := range methods {
+		if m == socks5AuthNone {
+			noAuth = true
+			break
+		}
+	}
+	if !noAuth {
+		_, _ = conn.Write([]byte{socks5Version, socks5AuthNoAcceptable})
+		return "", errSocksAuth
+	}
+	if _, err := conn.Write([]byte{socks5Version, socks5AuthNone}); err != nil {
+		return "", fmt.Errorf("failed to write SOCKS method selection: %w", err)
+	}
+
+	// Request: VER CMD RSV ATYP DST.ADDR DST.PORT
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", fmt.Errorf("failed to read SOCKS request: %w", err)
+	}
+	if request[0] != socks5Version {
+		return "", errSocksVersion
+	}
+	if request[1] != socks5CmdConnect {
+		writeSocksReply(conn, socks5ReplyCommandNotSupported)
+		return "", errSocksCommand
+	}
+
+	var host string
+	switch request[3] {
+	case socks5AddrIPv4:
+		addr := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, addr); err != nil {
+			return "", fmt.Errorf("failed to read SOCKS address: %w", err)
+		}
+		host = net.IP(addr).String()
+	case socks5AddrIPv6:
+		addr := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(conn, addr); err != nil {
+			return "", fmt.Errorf("failed to read SOCKS address: %w", err)
+		}
+		host = net.IP(addr).String()
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return "", fmt.Errorf("failed to read SOCKS domain length: %w", err)
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", fmt.Errorf("failed to read SOCKS domain: %w", err)
+		}
+		host = string(domain)
+	default:
+		writeSocksReply(conn, socks5ReplyAddrNotSupported)
+		return "", errSocksAddressType
+	}
+
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(conn, portBytes); err != nil {
+		return "", fmt.Errorf("failed to read SOCKS port: %w", err)
+	}
+	port := binary.BigEndian.Uint16(portBytes)
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// writeSocksReply sends a SOCKS5 reply with the given status code.
+// The bound address is always reported as 0.0.0.0:0, which clients ignore
+// for CONNECT requests.
+func writeSocksReply(conn net.Conn, status byte) {
+	reply := []byte{socks5Version, status, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0}
+	_, _ = conn.Write(reply)
+}
@@ -0,0 +1,140 @@
+package tunnel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// runSocksRequest sends clientData to readSocksRequest over a pipe and returns
+// the parsed target, everything the server wrote back, and the error.
+func runSocksRequest(t *testing.T, clientData []byte) (string, []byte, error) {
+	t.Helper()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	type result struct {
+		target string
+		err    error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		target, err := readSocksRequest(server)
+		server.Close()
+		resultCh <- result{target, err}
+	}()
+
+	replies := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(client)
+		replies <- data
+	}()
+
+	_, _ = client.Write(clientData)
+
+	r := <-resultCh
+	return r.target, <-replies, r.err
+}
+
+func TestReadSocksRequest_IPv4(t *testing.T) {
+	data := []byte{
+		0x05, 0x01, 0x00, // greeting: no auth
+		0x05, 0x01, 0x00, 0x01, // CONNECT IPv4
+		10, 0, 2, 15, // 10.0.2.15
+		0x1f, 0x90, // port 8080
+	}
+
+	target, replies, err := runSocksRequest(t, data)
+	if err != nil {
+		t.Fatalf("readSocksRequest() error = %v", err)
+	}
+	if target != "10.0.2.15:8080" {
+		t.Errorf("target = %q, want %q", target, "10.0.2.15:8080")
+	}
+	if !bytes.Equal(replies, []byte{0x05, 0x00}) {
+		t.Errorf("server wrote %v, want method selection [5 0]", replies)
+	}
+}
+
+func TestReadSocksRequest_Domain(t *testing.T) {
+	domain := "db.internal"
+	data := []byte{0x05, 0x01, 0x00, 0x05, 0x01, 0x00, 0x03, byte(len(domain))}
+	data = append(data, domain...)
+	data = append(data, 0x15, 0x38) // port 5432
+
+	target, _, err := runSocksRequest(t, data)
+	if err != nil {
+		t.Fatalf("readSocksRequest() error = %v", err)
+	}
+	if target != "db.internal:5432" {
+		t.Errorf("target = %q, want %q", target, "db.internal:5432")
+	}
+}
+
+func TestReadSocksRequest_IPv6(t *testing.T) {
+	data := []byte{0x05, 0x01, 0x00, 0x05, 0x01, 0x00, 0x04}
+	data = append(data, net.ParseIP("fd00::1").To16()...)
+	data = append(data, 0x00, 0x16) // port 22
+
+	target, _, err := runSocksRequest(t, data)
+	if err != nil {
+		t.Fatalf("readSocksRequest() error = %v", err)
+	}
+	if target != "[fd00::1]:22" {
+		t.Errorf("target = %q, want %q", target, "[fd00::1]:22")
+	}
+}
+
+func TestReadSocksRequest_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		wantErr   error
+		wantReply []byte
+	}{
+		{
+			name:    "SOCKS4 greeting",
+			data:    []byte{0x04, 0x01},
+			wantErr: errSocksVersion,
+		},
+		{
+			name:      "auth required",
+			data:      []byte{0x05, 0x01, 0x02},
+			wantErr:   errSocksAuth,
+			wantReply: []byte{0x05, socks5AuthNoAcceptable},
+		},
+		{
+			name:    "BIND command",
+			data:    []byte{0x05, 0x01, 0x00, 0x05, 0x02, 0x00, 0x01},
+			wantErr: errSocksCommand,
+			wantReply: []byte{
+				0x05, 0x00, // method selection
+				0x05, socks5ReplyCommandNotSupported, 0x00, 0x01, 0, 0, 0, 0, 0, 0,
+			},
+		},
+		{
+			name:    "unknown address type",
+			data:    []byte{0x05, 0x01, 0x00, 0x05, 0x01, 0x00, 0x09},
+			wantErr: errSocksAddressType,
+			wantReply: []byte{
+				0x05, 0x00,
+				0x05, socks5ReplyAddrNotSupported, 0x00, 0x01, 0, 0, 0, 0, 0, 0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, replies, err := runSocksRequest(t, tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("readSocksRequest() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantReply != nil && !bytes.Equal(replies, tt.wantReply) {
+				t.Errorf("server wrote %v, want %v", replies, tt.wantReply)
+			}
+		})
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
-	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 // GetHomeDir returns the user's home directory.
@@ -25,81 +24,38 @@ func GetHostKeyFilePath() string {
 	return filepath.Join(GetHomeDir(), ".ssh", "known_hosts")
 }
 
-// GetPrivateKey loads and parses a private key from file.
+// GetPrivateKey loads and parses a private key from file, decrypting it
+// with the passphrase set by SetKeyPassphrase if it is encrypted.
 func GetPrivateKey(keyFilePath string) (ssh.Signer, error) {
 	keyFilePath = strings.ReplaceAll(keyFilePath, "~", GetHomeDir())
 	key, err := os.ReadFile(keyFilePath)
 	if err != nil {
 		return nil, err
 	}
-	return ssh.ParsePrivateKey(key)
-}
-
-// AddHostKey adds a host key to the known_hosts file.
-func AddHostKey(host string, key ssh.PublicKey) error {
-	khFilePath := GetHostKeyFilePath()
-	f, err := os.OpenFile(khFilePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
-	if err != nil {
-		return err
+	signer, err := ssh.ParsePrivateKey(key)
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return signer, err
 	}
-	defer f.Close()
-
-	knownHostLine := knownhosts.Line([]string{knownhosts.Normalize(host)}, key) + "\n"
-	_, err = f.WriteString(knownHostLine)
-	return err
-}
-
-// GetKnownHostsCallbackWithNewHost returns a host key callback that adds unknown hosts.
-func GetKnownHostsCallbackWithNewHost() (ssh.HostKeyCallback, error) {
-	khFilePath := GetHostKeyFilePath()
-
-	// Ensure the known_hosts file exists
-	if _, err := os.Stat(khFilePath); os.IsNotExist(err) {
-		// Create the .ssh directory if it doesn't exist
-		sshDir := filepath.Dir(khFilePath)
-		if err := os.MkdirAll(sshDir, 0700); err != nil {
-			return nil, err
-		}
-		// Create empty known_hosts file
-		f, err := os.OpenFile(khFilePath, os.O_CREATE|os.O_WRONLY, 0600)
-		if err != nil {
-			return nil, err
-		}
-		f.Close()
+	passphrase := currentKeyPassphrase()
+	if passphrase == "" {
+		return nil, fmt.Errorf("private key %s is encrypted: set ssh_private_key_passphrase or add the key to ssh-agent", keyFilePath)
 	}
-
-	knownHostsCallback, err := knownhosts.New(khFilePath)
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to decrypt private key %s: %w", keyFilePath, err)
 	}
+	return signer, nil
+}
 
-	return func(dialAddr string, addr net.Addr, key ssh.PublicKey) error {
-		err := knownHostsCallback(dialAddr, addr, key)
-		if err != nil {
-			var keyError *knownhosts.KeyError
-			if errors.As(err, &keyError) && len(keyError.Want) == 0 {
-				// Key missing; add the host key
-				log.Info().Msgf("Adding new host key for %s", dialAddr)
-				if addErr := AddHostKey(dialAddr, key); addErr != nil {
-					return addErr
-				}
-				// Reload callback after adding the new host key
-				knownHostsCallback, err = knownhosts.New(khFilePath)
-				if err != nil {
-					return err
-				}
-				// Revalidate with the updated known_hosts
-				return knownHostsCallback(dialAddr, addr, key)
-			}
-			return err // Key mismatch or other errors
-		}
-		return nil
-	}, nil
+// AddHostKey adds a host key to the known_hosts file.
+func AddHostKey(host string, key ssh.PublicKey) error {
+	return appendKnownHost(GetHostKeyFilePath(), host, key)
 }
 
 // CreateSSHClientConfig creates an SSH client config for bastion connections.
 func CreateSSHClientConfig(username, keyFilePath string) (*ssh.ClientConfig, error) {
-	customCallback, err := GetKnownHostsCallbackWithNewHost()
+	customCallback, err := CurrentHostKeyPolicy().Callback()
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +77,7 @@ func CreateSSHClientConfig(username, keyFilePath string) (*ssh.ClientConfig, err
 
 // CreateSSHClientConfigWithPublicKey creates an SSH config from a public key string.
 func CreateSSHClientConfigWithPublicKey(username, publicKey string) (*ssh.ClientConfig, error) {
-	customCallback, err := GetKnownHostsCallbackWithNewHost()
+	customCallback, err := CurrentHostKeyPolicy().Callback()
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +139,7 @@ func GetSSHAgentSigners() ([]ssh.Signer, error) {
 // CreateSSHClientConfigWithAgent creates an SSH client config using SSH agent.
 // Falls back to key file if agent is not available.
 func CreateSSHClientConfigWithAgent(username, keyFilePath string) (*ssh.ClientConfig, error) {
-	customCallback, err := GetKnownHostsCallbackWithNewHost()
+	customCallback, err := CurrentHostKeyPolicy().Callback()
 	if err != nil {
 		return nil, err
 	}
@@ -231,7 +187,7 @@ func CreateSSHClientConfigWithAgent(username, keyFilePath string) (*ssh.ClientCo
 // CreateSSHClientConfigWithSigner creates an SSH client config using a provided signer.
 // This is used for ephemeral in-memory keys that are never written to disk.
 func CreateSSHClientConfigWithSigner(username string, signer ssh.Signer) (*ssh.ClientConfig, error) {
-	customCallback, err := GetKnownHostsCallbackWithNewHost()
+	customCallback, err := CurrentHostKeyPolicy().Callback()
 	if err != nil {
 		return nil, err
 	}
@@ -249,7 +205,7 @@ func CreateSSHClientConfigWithSigner(username string, signer ssh.Signer) (*ssh.C
 // CreateSSHClientConfigPreferAgent creates an SSH client config preferring agent over key file.
 // If preferAgent is true, tries agent first. Otherwise, uses key file first.
 func CreateSSHClientConfigPreferAgent(username, keyFilePath string, preferAgent bool) (*ssh.ClientConfig, error) {
-	customCallback, err := GetKnownHostsCallbackWithNewHost()
+	customCallback, err := CurrentHostKeyPolicy().Callback()
 	if err != nil {
 		return nil, err
 	}
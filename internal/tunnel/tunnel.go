@@ -2,9 +2,13 @@ package tunnel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -25,17 +29,116 @@ type SSHTunnel struct {
 	SshConnectionPoolSize         int
 	SshWarmupConnectionCount      int
 
+	// Autoscale, when set, lets the connection pool grow and shrink between
+	// its bounds instead of using SshConnectionPoolSize as a fixed maximum.
+	Autoscale *pool.AutoscaleConfig
+
+	// MaxBandwidth caps the combined throughput of all forwarded connections
+	// in bytes per second. Zero means unlimited.
+	MaxBandwidth int64
+
+	// MaxConnectionBandwidth caps the throughput of each forwarded connection
+	// in bytes per second. Zero means unlimited.
+	MaxConnectionBandwidth int64
+
+	// KeepaliveInterval is how often pooled SSH connections send keepalives.
+	// Zero disables keepalives.
+	KeepaliveInterval time.Duration
+
+	// KeepaliveMax is how many consecutive keepalives may go unanswered
+	// before a pooled connection is closed.
+	KeepaliveMax int
+
 	// ActualLocalPort is set after Start() binds to the local port.
 	// Useful when Local.Port is 0 (ephemeral port allocation).
 	ActualLocalPort int
 
+	// LocalSocket is an optional Unix domain socket path. When set, the
+	// primary forward listens on this socket instead of Local.
+	LocalSocket string
+
+	// DynamicLocal is an optional local SOCKS5 listener address. When set, the
+	// tunnel also accepts SOCKS5 CONNECT requests (like ssh -D) and forwards
+	// them to arbitrary destinations through the same SSH connection pool.
+	DynamicLocal *Endpoint
+
+	// ActualDynamicPort is set after Start() binds the SOCKS5 listener.
+	ActualDynamicPort int
+
+	// Forwards are additional local listeners, each forwarded to its own
+	// remote destination through the same SSH connection pool.
+	Forwards []*Forward
+
+	// Hops are jump hosts chained after Server; see AddHop.
+	Hops []*Hop
+
+	// Metrics tracks traffic and connection statistics for all forwards.
+	Metrics Metrics
+
+	// IdleTimeout is how long the tunnel may go without traffic before
+	// IdleAction is taken. Zero disables idle detection.
+	IdleTimeout time.Duration
+
+	// IdleAction is what happens once the tunnel has been idle for IdleTimeout.
+	IdleAction IdleAction
+
 	// Ready is closed when the tunnel is ready to accept connections.
 	Ready chan struct{}
 
+	// listener holds the local listener for graceful shutdown.
+	listener net.Listener
+
+	// dynamicListener holds the SOCKS5 listener for graceful shutdown.
+	dynamicListener net.Listener
+
+	// activePool is the connection pool, set while the tunnel is running.
+	activePool atomic.Pointer[pool.ConnectionPool]
+
+	// idleShutdown is set when the tunnel is closed by the idle watcher.
+	idleShutdown atomic.Bool
+
+	// bandwidth enforces MaxBandwidth across all connections.
+	bandwidth *rateLimiter
+
+	// idleShrunk is set while the idle watcher has shrunk the pool, so
+	// autoscaling does not immediately refill it.
+	idleShrunk atomic.Bool
+}
+
+// Forward is an additional local-to-remote forward served by an SSHTunnel.
+type Forward struct {
+	Local  *Endpoint
+	Remote *Endpoint
+
+	// UDP forwards datagrams instead of TCP streams; see AddUDPForward.
+	UDP bool
+
+	// ActualLocalPort is set after Start() binds the local listener.
+	ActualLocalPort int
+
 	// listener holds the TCP listener for graceful shutdown.
 	listener net.Listener
+
+	// packetConn holds the UDP socket of a UDP forward.
+	packetConn net.PacketConn
 }
 
+// GetActualLocalPort returns the actual local port the forward is listening on.
+func (fwd *Forward) GetActualLocalPort() int {
+	if fwd.ActualLocalPort != 0 {
+		return fwd.ActualLocalPort
+	}
+	return fwd.Local.Port
+}
+
+const (
+	// socksHandshakeTimeout bounds how long a SOCKS5 client has to send its request.
+	socksHandshakeTimeout = 10 * time.Second
+
+	// autoscaleInterval is how often an autoscaling pool is resized.
+	autoscaleInterval = 2 * time.Second
+)
+
 // NewSSHTunnel creates a new SSH tunnel configuration.
 func NewSSHTunnel(localListener, server string, sshConfig *ssh.ClientConfig, destination string, poolSize, warmupCount, maxConcurrent int, socksProxy string) *SSHTunnel {
 	tunnel := &SSHTunnel{
@@ -56,6 +159,57 @@ func NewSSHTunnel(localListener, server string, sshConfig *ssh.ClientConfig, des
 	return tunnel
 }
 
+// EnableDynamicForwarding configures a local SOCKS5 listener on listenAddr
+// that routes arbitrary destinations through the bastion SSH connection.
+// Must be called before Start().
+func (tunnel *SSHTunnel) EnableDynamicForwarding(listenAddr string) {
+	tunnel.DynamicLocal = NewEndpoint(listenAddr)
+}
+
+// ListenOnSocket makes the primary forward listen on a Unix domain socket
+// at path instead of a TCP port. A stale socket file left at path is
+// replaced. Must be called before Start().
+func (tunnel *SSHTunnel) ListenOnSocket(path string) {
+	tunnel.LocalSocket = path
+}
+
+// LocalAddress returns a human-readable description of where the primary
+// forward listens: the socket path or localhost:<port>.
+func (tunnel *SSHTunnel) LocalAddress() string {
+	if tunnel.LocalSocket != "" {
+		return tunnel.LocalSocket
+	}
+	return fmt.Sprintf("localhost:%d", tunnel.GetActualLocalPort())
+}
+
+// SetKeepalive makes pooled SSH connections send a keepalive every interval
+// and close after maxMissed unanswered keepalives. Must be called before Start().
+func (tunnel *SSHTunnel) SetKeepalive(interval time.Duration, maxMissed int) {
+	tunnel.KeepaliveInterval = interval
+	tunnel.KeepaliveMax = maxMissed
+}
+
+// SetBandwidthLimits caps throughput in bytes per second for the whole
+// tunnel and for each forwarded connection. Zero disables a limit.
+// Must be called before Start().
+func (tunnel *SSHTunnel) SetBandwidthLimits(total, perConnection int64) {
+	tunnel.MaxBandwidth = total
+	tunnel.MaxConnectionBandwidth = perConnection
+	tunnel.bandwidth = newRateLimiter(total)
+}
+
+// AddForward configures an additional local listener forwarded to destination
+// over the same SSH connection pool as the primary forward.
+// Must be called before Start().
+func (tunnel *SSHTunnel) AddForward(localListener, destination string) *Forward {
+	fwd := &Forward{
+		Local:  NewEndpoint(localListener),
+		Remote: NewEndpoint(destination),
+	}
+	tunnel.Forwards = append(tunnel.Forwards, fwd)
+	return fwd
+}
+
 // GetActualLocalPort returns the actual local port the tunnel is listening on.
 // This is useful when the configured port is 0 (ephemeral port).
 func (tunnel *SSHTunnel) GetActualLocalPort() int {
@@ -65,27 +219,97 @@ func (tunnel *SSHTunnel) GetActualLocalPort() int {
 	return tunnel.Local.Port
 }
 
+// GetActualDynamicPort returns the port the SOCKS5 listener is bound to,
+// or 0 if dynamic forwarding is not enabled.
+func (tunnel *SSHTunnel) GetActualDynamicPort() int {
+	if tunnel.ActualDynamicPort != 0 {
+		return tunnel.ActualDynamicPort
+	}
+	if tunnel.DynamicLocal != nil {
+		return tunnel.DynamicLocal.Port
+	}
+	return 0
+}
+
+// Stats returns a snapshot of the tunnel's traffic, connection and
+// connection pool statistics.
+func (tunnel *SSHTunnel) Stats() Stats {
+	stats := tunnel.Metrics.Snapshot()
+	stats.PoolCapacity = tunnel.SshConnectionPoolSize * tunnel.SshConnectionMaxConcurrentUse
+
+	if connPool := tunnel.activePool.Load(); connPool != nil {
+		stats.PoolSize = connPool.Size()
+		stats.PoolActiveUses = connPool.ActiveCount()
+		stats.PoolCapacity = connPool.Capacity()
+	}
+
+	return stats
+}
+
+// RotateConnections moves the tunnel onto fresh SSH connections made with
+// its current Config, for example after the bastion session was replaced.
+// Forwarded connections on the old SSH connections carry on until they end.
+// Returns the number of old SSH connections still draining.
+func (tunnel *SSHTunnel) RotateConnections() (int, error) {
+	connPool := tunnel.activePool.Load()
+	if connPool == nil {
+		return 0, nil
+	}
+	return connPool.Rotate(tunnel.SshWarmupConnectionCount)
+}
+
+// DrainingConnections returns the number of replaced SSH connections still
+// carrying forwarded connections.
+func (tunnel *SSHTunnel) DrainingConnections() int {
+	connPool := tunnel.activePool.Load()
+	if connPool == nil {
+		return 0
+	}
+	return connPool.DrainingCount()
+}
+
 // Close gracefully shuts down the tunnel.
 func (tunnel *SSHTunnel) Close() error {
+	if tunnel.dynamicListener != nil {
+		tunnel.dynamicListener.Close()
+	}
+	for _, fwd := range tunnel.Forwards {
+		if fwd.listener != nil {
+			fwd.listener.Close()
+		}
+		if fwd.packetConn != nil {
+			fwd.packetConn.Close()
+		}
+	}
 	if tunnel.listener != nil {
 		return tunnel.listener.Close()
 	}
 	return nil
 }
 
-// establishServerConnection creates a new SSH connection to the server.
+// establishServerConnection creates a new SSH connection to the server and
+// through any hops after it.
 func (tunnel *SSHTunnel) establishServerConnection() (*ssh.Client, error) {
+	client, err := tunnel.dialServer()
+	if err != nil {
+		return nil, err
+	}
+	return dialHops(client, tunnel.Hops)
+}
+
+// dialServer creates a new SSH connection to the server.
+func (tunnel *SSHTunnel) dialServer() (*ssh.Client, error) {
 	if tunnel.SocksProxy != nil {
 		return tunnel.connectViaProxy()
 	}
 	log.Info().Msgf("Establishing SSH connection to %s", tunnel.Server.String())
-	return ssh.Dial("tcp", tunnel.Server.String(), tunnel.Config)
+	return ssh.Dial("tcp", tunnel.Server.String(), withHostKeyAlgorithms(tunnel.Config, tunnel.Server.String()))
 }
 
 // connectViaProxy connects to the SSH server through a SOCKS proxy.
 func (tunnel *SSHTunnel) connectViaProxy() (*ssh.Client, error) {
 	log.Info().Msgf("Establishing SSH connection via SOCKS proxy to %s", tunnel.Server.String())
-	dialer, err := proxy.SOCKS5("tcp", tunnel.SocksProxy.String(), nil, proxy.Direct)
+	dialer, err := proxy.SOCKS5("tcp", tunnel.SocksProxy.String(), currentSocksAuth(), proxy.Direct)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SOCKS dialer: %w", err)
 	}
@@ -95,7 +319,7 @@ func (tunnel *SSHTunnel) connectViaProxy() (*ssh.Client, error) {
 		return nil, fmt.Errorf("failed to dial SSH server via SOCKS proxy: %w", err)
 	}
 
-	c, chans, reqs, err := ssh.NewClientConn(conn, tunnel.Server.String(), tunnel.Config)
+	c, chans, reqs, err := ssh.NewClientConn(conn, tunnel.Server.String(), withHostKeyAlgorithms(tunnel.Config, tunnel.Server.String()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SSH client connection: %w", err)
 	}
@@ -105,14 +329,44 @@ func (tunnel *SSHTunnel) connectViaProxy() (*ssh.Client, error) {
 
 // NewConnectionPoolForRemote creates a connection pool for this tunnel.
 func (tunnel *SSHTunnel) NewConnectionPoolForRemote() (*pool.ConnectionPool, error) {
-	return pool.NewConnectionPool(
+	connPool, err := pool.NewConnectionPool(
 		tunnel.SshConnectionPoolSize,
 		tunnel.SshConnectionMaxConcurrentUse,
 		func() (*ssh.Client, error) {
-			return tunnel.establishServerConnection()
+			client, err := tunnel.establishServerConnection()
+			if err != nil {
+				return nil, err
+			}
+			pool.StartKeepalive(client, tunnel.KeepaliveInterval, tunnel.KeepaliveMax)
+			return client, nil
 		},
 		tunnel.SshWarmupConnectionCount,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tunnel.Autoscale != nil {
+		connPool.EnableAutoscaling(*tunnel.Autoscale)
+	}
+	return connPool, nil
+}
+
+// startAutoscale periodically resizes the connection pool to match demand.
+func (tunnel *SSHTunnel) startAutoscale(ctx context.Context, connPool *pool.ConnectionPool) {
+	ticker := time.NewTicker(autoscaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !tunnel.idleShrunk.Load() {
+				connPool.Autoscale()
+			}
+		}
+	}
 }
 
 // startHealthCheck periodically checks the health of the connection pool.
@@ -134,10 +388,8 @@ func (tunnel *SSHTunnel) startHealthCheck(ctx context.Context, connPool *pool.Co
 
 // Start starts the tunnel, listening for local connections and forwarding them.
 func (tunnel *SSHTunnel) Start() error {
-	log.Debug().Msgf("Setup local listener: %s", tunnel.Local)
-	listener, err := net.Listen("tcp", tunnel.Local.String())
+	listener, err := tunnel.listenLocal()
 	if err != nil {
-		log.Error().Err(err).Msgf("Failed to setup local listener: %s", tunnel.Local)
 		return err
 	}
 	tunnel.listener = listener
@@ -149,6 +401,47 @@ func (tunnel *SSHTunnel) Start() error {
 		log.Debug().Msgf("Bound to actual port: %d", tunnel.ActualLocalPort)
 	}
 
+	// Bind the SOCKS5 listener up front so port conflicts fail the tunnel early
+	if tunnel.DynamicLocal != nil {
+		log.Debug().Msgf("Setup SOCKS5 listener: %s", tunnel.DynamicLocal)
+		dynamicListener, err := net.Listen("tcp", tunnel.DynamicLocal.String())
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to setup SOCKS5 listener: %s", tunnel.DynamicLocal)
+			return err
+		}
+		tunnel.dynamicListener = dynamicListener
+		defer dynamicListener.Close()
+
+		if addr, ok := dynamicListener.Addr().(*net.TCPAddr); ok {
+			tunnel.ActualDynamicPort = addr.Port
+		}
+	}
+
+	// Likewise bind any additional forwards before connecting
+	for _, fwd := range tunnel.Forwards {
+		if fwd.UDP {
+			packetConn, err := fwd.listenUDP()
+			if err != nil {
+				return err
+			}
+			defer packetConn.Close()
+			continue
+		}
+
+		log.Debug().Msgf("Setup local listener: %s", fwd.Local)
+		fwdListener, err := net.Listen("tcp", fwd.Local.String())
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to setup local listener: %s", fwd.Local)
+			return err
+		}
+		fwd.listener = fwdListener
+		defer fwdListener.Close()
+
+		if addr, ok := fwdListener.Addr().(*net.TCPAddr); ok {
+			fwd.ActualLocalPort = addr.Port
+		}
+	}
+
 	connPool, err := tunnel.NewConnectionPoolForRemote()
 	if err != nil {
 		log.Error().Err(err).Msgf("Failed to setup connection pool: %s", tunnel.Remote)
@@ -156,6 +449,9 @@ func (tunnel *SSHTunnel) Start() error {
 	}
 	defer connPool.Close()
 
+	tunnel.activePool.Store(connPool)
+	defer tunnel.activePool.Store(nil)
+
 	errors := make(chan error, 10)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -164,11 +460,43 @@ func (tunnel *SSHTunnel) Start() error {
 	// Health check goroutine
 	go tunnel.startHealthCheck(ctx, connPool)
 
+	// SOCKS5, additional forward, network and idle watcher goroutines use
+	// their own context since ctx is replaced on forwarder errors
+	auxCtx, auxCancel := context.WithCancel(context.Background())
+	defer auxCancel()
+
+	go tunnel.watchNetwork(auxCtx, connPool)
+
+	if tunnel.Autoscale != nil {
+		go tunnel.startAutoscale(auxCtx, connPool)
+	}
+
+	if tunnel.IdleTimeout > 0 {
+		// A tunnel that never carries traffic is idle from the start
+		tunnel.Metrics.touch()
+		go tunnel.watchIdle(auxCtx, connPool)
+	}
+
+	if tunnel.dynamicListener != nil {
+		go tunnel.serveDynamic(auxCtx, tunnel.dynamicListener, connPool)
+		log.Info().Msgf("SOCKS5 proxy listening on localhost:%d", tunnel.ActualDynamicPort)
+	}
+
+	for _, fwd := range tunnel.Forwards {
+		if fwd.UDP {
+			go tunnel.serveUDPForward(auxCtx, fwd, connPool)
+			log.Info().Msgf("Forwarding UDP localhost:%d to %s", fwd.ActualLocalPort, fwd.Remote.String())
+			continue
+		}
+		go tunnel.serveForward(auxCtx, fwd, connPool, errors)
+		log.Info().Msgf("Forwarding localhost:%d to %s", fwd.ActualLocalPort, fwd.Remote.String())
+	}
+
 	// Signal that tunnel is ready
 	close(tunnel.Ready)
 
-	log.Info().Msgf("Tunnel ready. Listening on localhost:%d, forwarding to %s via %s",
-		tunnel.ActualLocalPort, tunnel.Remote.String(), tunnel.Server.String())
+	log.Info().Msgf("Tunnel ready. Listening on %s, forwarding to %s via %s",
+		tunnel.LocalAddress(), tunnel.Remote.String(), tunnel.Server.String())
 
 	// Create connection channel once outside the loop to avoid goroutine leaks
 	localConnections := make(chan net.Conn, 100)
@@ -176,7 +504,7 @@ func (tunnel *SSHTunnel) Start() error {
 	// Single worker goroutine to process incoming connections
 	go func() {
 		for localConn := range localConnections {
-			go tunnel.forward(ctx, localConn, connPool, errors)
+			go tunnel.forward(ctx, localConn, tunnel.Remote, connPool, errors)
 		}
 	}()
 
@@ -216,6 +544,9 @@ func (tunnel *SSHTunnel) Start() error {
 			if opErr, ok := err.(*net.OpError); ok && opErr.Err.Error() == "use of closed network connection" {
 				log.Debug().Msg("Listener closed, shutting down accept loop")
 				cancel()
+				if tunnel.idleShutdown.Load() {
+					return ErrIdleTimeout
+				}
 				return nil
 			}
 
@@ -255,8 +586,67 @@ func (tunnel *SSHTunnel) Start() error {
 	}
 }
 
-// forward forwards a local connection through the SSH tunnel.
-func (tunnel *SSHTunnel) forward(ctx context.Context, localConn net.Conn, connPool *pool.ConnectionPool, ch chan error) {
+// listenLocal binds the primary forward's listener, either on the configured
+// Unix socket or on the local TCP endpoint.
+func (tunnel *SSHTunnel) listenLocal() (net.Listener, error) {
+	if tunnel.LocalSocket == "" {
+		log.Debug().Msgf("Setup local listener: %s", tunnel.Local)
+		listener, err := net.Listen("tcp", tunnel.Local.String())
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to setup local listener: %s", tunnel.Local)
+			return nil, err
+		}
+		return listener, nil
+	}
+
+	log.Debug().Msgf("Setup local socket listener: %s", tunnel.LocalSocket)
+	if err := removeStaleSocket(tunnel.LocalSocket); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", tunnel.LocalSocket)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to setup local socket listener: %s", tunnel.LocalSocket)
+		return nil, err
+	}
+
+	// Only the owning user may connect through the tunnel
+	if err := os.Chmod(tunnel.LocalSocket, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	return listener, nil
+}
+
+// removeStaleSocket removes a socket file left behind by a previous run.
+// It refuses to remove anything that is not a socket, or a socket that
+// another process is still serving.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat socket path: %w", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is already in use", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
+
+// forward forwards a local connection to remote through the SSH tunnel.
+func (tunnel *SSHTunnel) forward(ctx context.Context, localConn net.Conn, remote *Endpoint, connPool *pool.ConnectionPool, ch chan error) {
 	defer localConn.Close()
 
 	trackedConn, err := connPool.Get()
@@ -267,7 +657,7 @@ func (tunnel *SSHTunnel) forward(ctx context.Context, localConn net.Conn, connPo
 
 	defer trackedConn.Decrement()
 
-	remoteConn, err := trackedConn.Client.Dial("tcp", tunnel.Remote.String())
+	remoteConn, err := trackedConn.Client.Dial("tcp", remote.String())
 	if err != nil {
 		trackedConn.Invalidate()
 		log.Error().Err(err).Msg("remote dial error")
@@ -275,50 +665,188 @@ func (tunnel *SSHTunnel) forward(ctx context.Context, localConn net.Conn, connPo
 	}
 	defer remoteConn.Close()
 
-	log.Debug().Msgf("Connected to remote endpoint: %s", tunnel.Remote.String())
+	log.Debug().Msgf("Connected to remote endpoint: %s", remote.String())
 
-	pipe := func(ctx context.Context, writer, reader net.Conn, done chan<- struct{}) {
-		defer func() {
-			done <- struct{}{}
-			writer.Close()
-		}()
+	tunnel.pipeConnections(ctx, localConn, remoteConn)
+}
 
-		buf := make([]byte, 32*1024)
-		for {
-			select {
-			case <-ctx.Done():
-				log.Debug().Msg("Pipe routine canceled due to context cancellation")
+// serveForward accepts connections for an additional forward until its
+// listener is closed.
+func (tunnel *SSHTunnel) serveForward(ctx context.Context, fwd *Forward, connPool *pool.ConnectionPool, ch chan error) {
+	for {
+		conn, err := fwd.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || ctx.Err() != nil {
+				log.Debug().Msgf("Listener for %s closed", fwd.Remote.String())
 				return
-			default:
-				n, err := reader.Read(buf)
-				if n > 0 {
-					if _, writeErr := writer.Write(buf[:n]); writeErr != nil {
-						log.Debug().Err(writeErr).Msg("Error writing to connection during piping")
-						return
-					}
-				}
-				if err != nil {
-					if err != io.EOF {
-						log.Debug().Err(err).Msg("Data transfer error during piping")
-					}
-					return
-				}
 			}
+			log.Error().Err(err).Msgf("Listener accept error for %s", fwd.Remote.String())
+			return
 		}
+		go tunnel.forward(ctx, conn, fwd.Remote, connPool, ch)
+	}
+}
+
+// serveDynamic accepts SOCKS5 clients until the listener is closed.
+func (tunnel *SSHTunnel) serveDynamic(ctx context.Context, listener net.Listener, connPool *pool.ConnectionPool) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || ctx.Err() != nil {
+				log.Debug().Msg("SOCKS5 listener closed")
+				return
+			}
+			log.Error().Err(err).Msg("SOCKS5 listener accept error")
+			return
+		}
+		go tunnel.forwardDynamic(ctx, conn, connPool)
+	}
+}
+
+// forwardDynamic handles a single SOCKS5 client, forwarding it to the
+// destination it requests through the SSH tunnel.
+func (tunnel *SSHTunnel) forwardDynamic(ctx context.Context, localConn net.Conn, connPool *pool.ConnectionPool) {
+	defer localConn.Close()
+
+	_ = localConn.SetDeadline(time.Now().Add(socksHandshakeTimeout))
+	target, err := readSocksRequest(localConn)
+	if err != nil {
+		log.Debug().Err(err).Msg("SOCKS5 handshake failed")
+		return
+	}
+	_ = localConn.SetDeadline(time.Time{})
+
+	trackedConn, err := connPool.Get()
+	if err != nil {
+		writeSocksReply(localConn, socks5ReplyGeneralFailure)
+		log.Error().Err(err).Msg("failed to get connection from pool for SOCKS5 request")
+		return
+	}
+	defer trackedConn.Decrement()
+
+	// A failed dial usually means the bastion refused this particular
+	// destination, so the pooled connection is not invalidated here.
+	remoteConn, err := trackedConn.Client.Dial("tcp", target)
+	if err != nil {
+		writeSocksReply(localConn, socks5ReplyHostUnreachable)
+		log.Warn().Err(err).Msgf("SOCKS5 dial to %s failed", target)
+		return
+	}
+	defer remoteConn.Close()
+
+	writeSocksReply(localConn, socks5ReplySucceeded)
+	log.Debug().Msgf("SOCKS5 connected to %s", target)
+
+	tunnel.pipeConnections(ctx, localConn, remoteConn)
+}
+
+// closeWriter is implemented by connections that can be half-closed, such
+// as TCP and unix connections and SSH channels.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// pipeConnections copies data in both directions until both sides are done
+// or ctx is cancelled, recording traffic in the tunnel's metrics. When one
+// side finishes sending, the other side's write half is closed so that the
+// peer sees EOF while its replies still flow back.
+func (tunnel *SSHTunnel) pipeConnections(ctx context.Context, localConn, remoteConn net.Conn) {
+	start := time.Now()
+	tunnel.Metrics.connOpened()
+	defer func() {
+		tunnel.Metrics.connClosed(time.Since(start))
+	}()
+
+	// Both directions of a connection share its bandwidth cap
+	connBandwidth := newRateLimiter(tunnel.MaxConnectionBandwidth)
+
+	pipe := func(ctx context.Context, writer, reader net.Conn, counter *atomic.Int64, done chan<- struct{}) {
+		defer func() { done <- struct{}{} }()
+
+		dst := &meteredWriter{
+			ctx:      ctx,
+			w:        writer,
+			counter:  counter,
+			metrics:  &tunnel.Metrics,
+			limiters: [2]*rateLimiter{connBandwidth, tunnel.bandwidth},
+		}
+
+		buf := copyBuffers.Get().(*[]byte)
+		defer copyBuffers.Put(buf)
+
+		// One side is always an SSH channel, so the kernel cannot splice;
+		// hiding the reader's WriterTo makes io.CopyBuffer use the pooled
+		// buffer instead of allocating its own.
+		_, err := io.CopyBuffer(dst, struct{ io.Reader }{reader}, *buf)
+		if err == nil {
+			// The reader sent EOF: pass it on, but keep the other
+			// direction open for the peer's remaining replies
+			if cw, ok := writer.(closeWriter); ok && cw.CloseWrite() == nil {
+				return
+			}
+			writer.Close()
+			return
+		}
+
+		if !errors.Is(err, net.ErrClosed) && !errors.Is(err, context.Canceled) {
+			log.Debug().Err(err).Msg("Data transfer error during piping")
+		}
+		// A broken connection ends both directions
+		writer.Close()
+		reader.Close()
 	}
 
 	done := make(chan struct{}, 2)
 
-	go pipe(ctx, localConn, remoteConn, done)
-	go pipe(ctx, remoteConn, localConn, done)
+	go pipe(ctx, localConn, remoteConn, &tunnel.Metrics.bytesIn, done)
+	go pipe(ctx, remoteConn, localConn, &tunnel.Metrics.bytesOut, done)
 
-	select {
-	case <-done:
-	case <-ctx.Done():
-		log.Debug().Msg("Forward routine canceled")
+	for range 2 {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			log.Debug().Msg("Forward routine canceled")
+			return
+		}
 	}
 }
 
+// copyBufferSize is the size of the pooled buffers used to pipe connections.
+// It matches minRateLimitBurst so a full buffer never waits on a limiter in
+// pieces.
+const copyBufferSize = 32 * 1024
+
+// copyBuffers pools pipe buffers across connections.
+var copyBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// meteredWriter counts the bytes written through it and waits on bandwidth
+// limiters before each write.
+type meteredWriter struct {
+	ctx      context.Context
+	w        io.Writer
+	counter  *atomic.Int64
+	metrics  *Metrics
+	limiters [2]*rateLimiter
+}
+
+func (mw *meteredWriter) Write(p []byte) (int, error) {
+	for _, limiter := range mw.limiters {
+		if err := limiter.wait(mw.ctx, len(p)); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := mw.w.Write(p)
+	mw.counter.Add(int64(n))
+	mw.metrics.touch()
+	return n, err
+}
+
 // StartAsync starts the tunnel in a goroutine and returns immediately.
 // Use the Ready channel to wait for the tunnel to be ready.
 // Returns an error channel that will receive any errors from the tunnel.
@@ -115,6 +115,81 @@ func TestIntegration_TunnelWithEphemeralSSH(t *testing.T) {
 	t.Log("Data successfully tunneled and echoed back")
 }
 
+// TestIntegration_TunnelThroughHop tests a tunnel chained through a jump host.
+func TestIntegration_TunnelThroughHop(t *testing.T) {
+	if os.Getenv("TEST_INTEGRATION") != "1" {
+		t.Skip("Skipping integration test (set TEST_INTEGRATION=1 to run)")
+	}
+
+	echoServer, err := startEchoServer()
+	if err != nil {
+		t.Fatalf("Failed to start echo server: %v", err)
+	}
+	defer echoServer.Close()
+
+	// The jump host forwards to the echo server; the bastion forwards to the jump host
+	jumpServer, err := startMockSSHServer(echoServer.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to start jump SSH server: %v", err)
+	}
+	defer jumpServer.Close()
+
+	bastionServer, err := startMockSSHServer(jumpServer.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to start bastion SSH server: %v", err)
+	}
+	defer bastionServer.Close()
+
+	sshConfig := &ssh.ClientConfig{
+		User:            "testuser",
+		Auth:            []ssh.AuthMethod{ssh.Password("testpass")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+
+	tunnel := &SSHTunnel{
+		Local:                         &Endpoint{Host: "localhost", Port: 0},
+		Server:                        parseEndpoint(bastionServer.Addr().String()),
+		Remote:                        parseEndpoint(echoServer.Addr().String()),
+		Config:                        sshConfig,
+		SshConnectionPoolSize:         2,
+		SshConnectionMaxConcurrentUse: 5,
+		SshWarmupConnectionCount:      1,
+		Ready:                         make(chan struct{}),
+	}
+	tunnel.AddHop(jumpServer.Addr().String(), sshConfig)
+
+	errCh := tunnel.StartAsync()
+	defer tunnel.Close()
+
+	select {
+	case <-tunnel.Ready:
+	case err := <-errCh:
+		t.Fatalf("Tunnel failed to start: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timeout waiting for tunnel to become ready")
+	}
+
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", tunnel.GetActualLocalPort()), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect through tunnel: %v", err)
+	}
+	defer conn.Close()
+
+	testData := "Hello through two hops!"
+	if _, err := conn.Write([]byte(testData)); err != nil {
+		t.Fatalf("Failed to write through tunnel: %v", err)
+	}
+
+	buf := make([]byte, len(testData))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Failed to read through tunnel: %v", err)
+	}
+	if string(buf) != testData {
+		t.Errorf("Expected %q, got %q", testData, string(buf))
+	}
+}
+
 // TestIntegration_TunnelConcurrentConnections tests multiple concurrent connections through tunnel.
 func TestIntegration_TunnelConcurrentConnections(t *testing.T) {
 	if os.Getenv("TEST_INTEGRATION") != "1" {
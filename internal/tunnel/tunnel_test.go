@@ -1,6 +1,9 @@
 package tunnel
 
 import (
+	"net"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"golang.org/x/crypto/ssh"
@@ -178,3 +181,165 @@ func TestSSHTunnelStartInvalidPort(t *testing.T) {
 		t.Log("Start() did not return immediately")
 	}
 }
+
+func TestSSHTunnelEnableDynamicForwarding(t *testing.T) {
+	tunnel := NewSSHTunnel(
+		"localhost:8080",
+		"bastion.example.com:22",
+		&ssh.ClientConfig{User: "testuser"},
+		"10.0.0.1:6443",
+		5, 2, 10,
+		"",
+	)
+
+	if tunnel.GetActualDynamicPort() != 0 {
+		t.Errorf("GetActualDynamicPort() = %d, want 0 before enabling", tunnel.GetActualDynamicPort())
+	}
+
+	tunnel.EnableDynamicForwarding("localhost:1080")
+
+	if tunnel.DynamicLocal == nil {
+		t.Fatal("DynamicLocal should not be nil")
+	}
+	if tunnel.DynamicLocal.Port != 1080 {
+		t.Errorf("DynamicLocal.Port = %d, want %d", tunnel.DynamicLocal.Port, 1080)
+	}
+	if tunnel.GetActualDynamicPort() != 1080 {
+		t.Errorf("GetActualDynamicPort() = %d, want %d", tunnel.GetActualDynamicPort(), 1080)
+	}
+}
+
+func TestSSHTunnelAddForward(t *testing.T) {
+	tunnel := NewSSHTunnel(
+		"localhost:8080",
+		"bastion.example.com:22",
+		&ssh.ClientConfig{User: "testuser"},
+		"10.0.0.1:6443",
+		5, 2, 10,
+		"",
+	)
+
+	fwd := tunnel.AddForward("localhost:15432", "10.0.0.3:5432")
+
+	if len(tunnel.Forwards) != 1 {
+		t.Fatalf("len(Forwards) = %d, want 1", len(tunnel.Forwards))
+	}
+	if fwd.Remote.Host != "10.0.0.3" || fwd.Remote.Port != 5432 {
+		t.Errorf("Remote = %s, want 10.0.0.3:5432", fwd.Remote.String())
+	}
+	if fwd.GetActualLocalPort() != 15432 {
+		t.Errorf("GetActualLocalPort() = %d, want %d", fwd.GetActualLocalPort(), 15432)
+	}
+}
+
+func TestSSHTunnelAddHop(t *testing.T) {
+	tunnel := NewSSHTunnel(
+		"localhost:8080",
+		"bastion.example.com:22",
+		&ssh.ClientConfig{User: "session"},
+		"10.0.0.1:6443",
+		5, 2, 10,
+		"",
+	)
+
+	first := tunnel.AddHop("10.0.1.5:22", &ssh.ClientConfig{User: "opc"})
+	second := tunnel.AddHop("10.0.2.5:2222", &ssh.ClientConfig{User: "ops"})
+
+	if len(tunnel.Hops) != 2 {
+		t.Fatalf("len(Hops) = %d, want 2", len(tunnel.Hops))
+	}
+	if tunnel.Hops[0] != first || tunnel.Hops[1] != second {
+		t.Error("Hops should keep the order they were added in")
+	}
+	if second.Server.Host != "10.0.2.5" || second.Server.Port != 2222 {
+		t.Errorf("Server = %s, want 10.0.2.5:2222", second.Server.String())
+	}
+	if second.Config.User != "ops" {
+		t.Errorf("Config.User = %q, want %q", second.Config.User, "ops")
+	}
+}
+
+func TestDialHopsWithoutHops(t *testing.T) {
+	client := &ssh.Client{}
+
+	got, err := dialHops(client, nil)
+	if err != nil {
+		t.Fatalf("dialHops() error = %v", err)
+	}
+	if got != client {
+		t.Error("dialHops() without hops should return the bastion client")
+	}
+}
+
+func TestSSHTunnelListenOnSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "prod.sock")
+
+	tunnel := NewSSHTunnel(
+		"localhost:8080",
+		"bastion.example.com:22",
+		&ssh.ClientConfig{User: "testuser"},
+		"10.0.0.1:6443",
+		5, 2, 10,
+		"",
+	)
+	tunnel.ListenOnSocket(socketPath)
+
+	if tunnel.LocalAddress() != socketPath {
+		t.Errorf("LocalAddress() = %q, want %q", tunnel.LocalAddress(), socketPath)
+	}
+
+	listener, err := tunnel.listenLocal()
+	if err != nil {
+		t.Fatalf("listenLocal() error = %v", err)
+	}
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("socket not created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+
+	// A live socket must not be replaced by a second tunnel
+	if _, err := tunnel.listenLocal(); err == nil {
+		t.Error("listenLocal() should fail while the socket is in use")
+	}
+
+	listener.Close()
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed on close, stat error = %v", err)
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := removeStaleSocket(filepath.Join(dir, "missing.sock")); err != nil {
+		t.Errorf("removeStaleSocket() on missing path error = %v", err)
+	}
+
+	regular := filepath.Join(dir, "file")
+	if err := os.WriteFile(regular, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeStaleSocket(regular); err == nil {
+		t.Error("removeStaleSocket() should refuse to remove a regular file")
+	}
+
+	// Leave a socket file behind without a listener
+	stale := filepath.Join(dir, "stale.sock")
+	listener, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	if err := removeStaleSocket(stale); err != nil {
+		t.Errorf("removeStaleSocket() error = %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale socket should be removed, stat error = %v", err)
+	}
+}
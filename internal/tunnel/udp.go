@@ -0,0 +1,210 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/pool"
+)
+
+const (
+	// udpSessionIdleTimeout closes the SSH channel of a UDP client that has
+	// not sent or received a datagram for this long.
+	udpSessionIdleTimeout = 60 * time.Second
+
+	// maxDatagramSize is the largest datagram that fits the 2-byte length
+	// prefix used on the SSH channel.
+	maxDatagramSize = 65535
+)
+
+// AddUDPForward configures an additional local UDP socket forwarded to
+// destination, which must be a DNS server. SSH can only open TCP channels to
+// the remote side, so each datagram is sent over a channel prefixed with its
+// length as a 2-byte big-endian integer, the framing DNS uses over TCP (RFC
+// 1035 section 4.2.2). Every DNS resolver accepts it on its TCP port; other
+// UDP services, such as StatsD, do not listen on TCP and would drop it.
+// Must be called before Start().
+func (tunnel *SSHTunnel) AddUDPForward(localListener, destination string) *Forward {
+	fwd := tunnel.AddForward(localListener, destination)
+	fwd.UDP = true
+	return fwd
+}
+
+// listenUDP binds the local UDP socket of a UDP forward.
+func (fwd *Forward) listenUDP() (net.PacketConn, error) {
+	log.Debug().Msgf("Setup local UDP socket: %s", fwd.Local)
+	packetConn, err := net.ListenPacket("udp", fwd.Local.String())
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to setup local UDP socket: %s", fwd.Local)
+		return nil, err
+	}
+	fwd.packetConn = packetConn
+
+	if addr, ok := packetConn.LocalAddr().(*net.UDPAddr); ok {
+		fwd.ActualLocalPort = addr.Port
+	}
+	return packetConn, nil
+}
+
+// udpSession relays datagrams for one local client over one SSH channel.
+type udpSession struct {
+	remote  net.Conn
+	tracked *pool.TrackedSSHConnection
+	idle    *time.Timer
+	once    sync.Once
+}
+
+// close tears down the session's channel and releases its pool slot.
+func (s *udpSession) close() {
+	s.once.Do(func() {
+		s.idle.Stop()
+		s.remote.Close()
+		s.tracked.Decrement()
+	})
+}
+
+// serveUDPForward relays datagrams between local UDP clients and the remote
+// destination until the forward's socket is closed. Each client address gets
+// its own SSH channel so replies are routed back to the right client.
+func (tunnel *SSHTunnel) serveUDPForward(ctx context.Context, fwd *Forward, connPool *pool.ConnectionPool) {
+	var mu sync.Mutex
+	sessions := make(map[string]*udpSession)
+
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, s := range sessions {
+			s.close()
+		}
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := fwd.packetConn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || ctx.Err() != nil {
+				log.Debug().Msgf("UDP socket for %s closed", fwd.Remote.String())
+				return
+			}
+			log.Error().Err(err).Msgf("UDP read error for %s", fwd.Remote.String())
+			return
+		}
+
+		key := addr.String()
+		mu.Lock()
+		session, ok := sessions[key]
+		mu.Unlock()
+
+		if !ok {
+			session, err = tunnel.openUDPSession(fwd, connPool)
+			if err != nil {
+				log.Warn().Err(err).Msgf("Dropping datagram from %s", key)
+				continue
+			}
+
+			remove := func() {
+				session.close()
+				mu.Lock()
+				if sessions[key] == session {
+					delete(sessions, key)
+				}
+				mu.Unlock()
+			}
+			session.idle = time.AfterFunc(udpSessionIdleTimeout, remove)
+
+			mu.Lock()
+			sessions[key] = session
+			mu.Unlock()
+
+			go tunnel.relayUDPReplies(fwd.packetConn, addr, session, remove)
+		}
+
+		session.idle.Reset(udpSessionIdleTimeout)
+		if err := writeDatagram(session.remote, buf[:n]); err != nil {
+			log.Debug().Err(err).Msgf("Failed to forward datagram from %s", key)
+			continue
+		}
+		tunnel.Metrics.bytesOut.Add(int64(n))
+		tunnel.Metrics.touch()
+	}
+}
+
+// openUDPSession opens an SSH channel to the forward's destination.
+func (tunnel *SSHTunnel) openUDPSession(fwd *Forward, connPool *pool.ConnectionPool) (*udpSession, error) {
+	trackedConn, err := connPool.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection from pool: %w", err)
+	}
+
+	remoteConn, err := trackedConn.Client.Dial("tcp", fwd.Remote.String())
+	if err != nil {
+		trackedConn.Decrement()
+		return nil, fmt.Errorf("failed to dial %s: %w", fwd.Remote.String(), err)
+	}
+
+	return &udpSession{remote: remoteConn, tracked: trackedConn}, nil
+}
+
+// relayUDPReplies sends datagrams read from the session's channel back to
+// the local client at addr, calling done when the channel closes.
+func (tunnel *SSHTunnel) relayUDPReplies(packetConn net.PacketConn, addr net.Addr, session *udpSession, done func()) {
+	start := time.Now()
+	tunnel.Metrics.connOpened()
+	defer func() {
+		done()
+		tunnel.Metrics.connClosed(time.Since(start))
+	}()
+
+	for {
+		payload, err := readDatagram(session.remote)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Debug().Err(err).Msgf("UDP reply stream for %s ended", addr)
+			}
+			return
+		}
+
+		session.idle.Reset(udpSessionIdleTimeout)
+		if _, err := packetConn.WriteTo(payload, addr); err != nil {
+			log.Debug().Err(err).Msgf("Failed to deliver datagram to %s", addr)
+			return
+		}
+		tunnel.Metrics.bytesIn.Add(int64(len(payload)))
+		tunnel.Metrics.touch()
+	}
+}
+
+// writeDatagram writes p to w prefixed with its length.
+func writeDatagram(w io.Writer, p []byte) error {
+	if len(p) > maxDatagramSize {
+		return fmt.Errorf("datagram of %d bytes exceeds %d", len(p), maxDatagramSize)
+	}
+
+	frame := make([]byte, 2+len(p))
+	binary.BigEndian.PutUint16(frame, uint16(len(p)))
+	copy(frame[2:], p)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// readDatagram reads one length-prefixed datagram from r.
+func readDatagram(r io.Reader) ([]byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
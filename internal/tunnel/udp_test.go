@@ -0,0 +1,75 @@
+package tunnel
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDatagramFraming(t *testing.T) {
+	var buf bytes.Buffer
+
+	for _, msg := range []string{"first", "", "third datagram"} {
+		if err := writeDatagram(&buf, []byte(msg)); err != nil {
+			t.Fatalf("writeDatagram(%q) error = %v", msg, err)
+		}
+	}
+
+	// A DNS-over-TCP reader sees the length prefix first
+	if got := buf.Bytes()[:2]; !bytes.Equal(got, []byte{0, 5}) {
+		t.Errorf("length prefix = %v, want [0 5]", got)
+	}
+
+	for _, want := range []string{"first", "", "third datagram"} {
+		got, err := readDatagram(&buf)
+		if err != nil {
+			t.Fatalf("readDatagram() error = %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("readDatagram() = %q, want %q", got, want)
+		}
+	}
+
+	if _, err := readDatagram(&buf); err == nil {
+		t.Error("readDatagram() on empty stream should error")
+	}
+}
+
+func TestWriteDatagramTooLarge(t *testing.T) {
+	if err := writeDatagram(&bytes.Buffer{}, make([]byte, maxDatagramSize+1)); err == nil {
+		t.Error("writeDatagram() should reject datagrams over the size limit")
+	}
+}
+
+func TestSSHTunnelAddUDPForward(t *testing.T) {
+	tunnel := NewSSHTunnel(
+		"localhost:8080",
+		"bastion.example.com:22",
+		&ssh.ClientConfig{User: "testuser"},
+		"10.0.0.1:6443",
+		5, 2, 10,
+		"",
+	)
+
+	fwd := tunnel.AddUDPForward("localhost:0", "169.254.169.254:53")
+	if !fwd.UDP {
+		t.Error("UDP should be set")
+	}
+	if len(tunnel.Forwards) != 1 {
+		t.Fatalf("len(Forwards) = %d, want 1", len(tunnel.Forwards))
+	}
+
+	packetConn, err := fwd.listenUDP()
+	if err != nil {
+		t.Fatalf("listenUDP() error = %v", err)
+	}
+	if fwd.GetActualLocalPort() == 0 {
+		t.Error("GetActualLocalPort() should report the bound UDP port")
+	}
+
+	tunnel.Close()
+	if _, _, err := packetConn.ReadFrom(make([]byte, 1)); err == nil {
+		t.Error("Close() should close UDP sockets")
+	}
+}
@@ -0,0 +1,88 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/pool"
+)
+
+const (
+	// wakeCheckInterval is how often the tunnel looks for suspend/resume and
+	// network changes.
+	wakeCheckInterval = 5 * time.Second
+
+	// sleepDetectThreshold is how far the wall clock must run ahead of the
+	// check interval before the host is assumed to have been suspended.
+	sleepDetectThreshold = 15 * time.Second
+)
+
+// watchNetwork rebuilds the connection pool when the host wakes from sleep
+// and re-checks it when network addresses change (Wi-Fi switch, VPN up/down).
+// Detecting dead connections through TCP timeouts alone can take minutes.
+// The local listeners are unaffected and keep accepting. New SSH connections
+// are established on the next request.
+func (tunnel *SSHTunnel) watchNetwork(ctx context.Context, connPool *pool.ConnectionPool) {
+	ticker := time.NewTicker(wakeCheckInterval)
+	defer ticker.Stop()
+
+	// Round(0) strips the monotonic reading, which stops during suspend
+	lastTick := time.Now().Round(0)
+	lastAddrs := localAddrs()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().Round(0)
+		addrs := localAddrs()
+		slept := resumedFromSleep(lastTick, now, wakeCheckInterval)
+		changed := addrs != lastAddrs
+		lastTick, lastAddrs = now, addrs
+
+		switch {
+		case slept:
+			// Connections rarely survive a suspend; drop them all, including
+			// ones stuck mid-transfer on a dead socket
+			closed := connPool.Reset()
+			log.Warn().Msgf("Detected resume from sleep, rebuilt connection pool (closed %d SSH connections)", closed)
+		case changed:
+			// Address changes are not always fatal (e.g. IPv6 address
+			// rotation), so only drop connections that stopped responding
+			log.Info().Msg("Detected network change, checking pooled SSH connections")
+			connPool.HealthCheck(pool.CheckSSHClientHealth)
+		}
+	}
+}
+
+// resumedFromSleep reports whether the wall clock advanced much further than
+// expected between two checks, which happens when the host was suspended.
+func resumedFromSleep(last, now time.Time, interval time.Duration) bool {
+	return now.Sub(last) > interval+sleepDetectThreshold
+}
+
+// localAddrs returns a stable fingerprint of the host's non-loopback
+// interface addresses, or "" if they cannot be read.
+func localAddrs() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+
+	var list []string
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.IsLoopback() {
+			continue
+		}
+		list = append(list, addr.String())
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,32 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResumedFromSleep(t *testing.T) {
+	last := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    bool
+	}{
+		{"on schedule", wakeCheckInterval, false},
+		{"scheduling delay", wakeCheckInterval + 2*time.Second, false},
+		{"overnight suspend", 8 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		if got := resumedFromSleep(last, last.Add(tt.elapsed), wakeCheckInterval); got != tt.want {
+			t.Errorf("%s: resumedFromSleep() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocalAddrsStable(t *testing.T) {
+	if first, second := localAddrs(), localAddrs(); first != second {
+		t.Errorf("localAddrs() changed between calls: %q != %q", first, second)
+	}
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/scotttball/tunatap/internal/exitcode"
+	"golang.org/x/term"
+)
+
+// NonInteractiveEnv disables prompts like --non-interactive when set to a
+// true value, such as 1 or true.
+const NonInteractiveEnv = "TUNATAP_NON_INTERACTIVE"
+
+var nonInteractive atomic.Bool
+
+// SetNonInteractive disables prompts and selectors for the whole process.
+func SetNonInteractive(v bool) {
+	nonInteractive.Store(v)
+}
+
+// NonInteractive reports whether prompts were disabled with
+// SetNonInteractive or NonInteractiveEnv.
+func NonInteractive() bool {
+	if nonInteractive.Load() {
+		return true
+	}
+	v, err := strconv.ParseBool(os.Getenv(NonInteractiveEnv))
+	return err == nil && v
+}
+
+// CanPrompt reports whether the user can be asked on the terminal: prompts
+// are not disabled and stdin is a terminal.
+func CanPrompt() bool {
+	return !NonInteractive() && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// PromptError is the error for a prompt that cannot be shown. action says
+// what needed the user, and hint how to do without them. It exits with the
+// usage code, since the missing answer can be given as an argument.
+func PromptError(action, hint string) error {
+	reason := "stdin is not a terminal"
+	if NonInteractive() {
+		reason = "running non-interactively"
+	}
+	return exitcode.Wrap(exitcode.Usage, fmt.Errorf("%s needs a prompt, but %s; %s", action, reason, hint))
+}
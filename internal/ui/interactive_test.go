@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNonInteractive(t *testing.T) {
+	t.Setenv(NonInteractiveEnv, "")
+	SetNonInteractive(false)
+	if NonInteractive() {
+		t.Error("NonInteractive() = true by default")
+	}
+
+	t.Setenv(NonInteractiveEnv, "1")
+	if !NonInteractive() || CanPrompt() {
+		t.Errorf("%s=1 did not disable prompts", NonInteractiveEnv)
+	}
+
+	t.Setenv(NonInteractiveEnv, "")
+	SetNonInteractive(true)
+	defer SetNonInteractive(false)
+	if !NonInteractive() || CanPrompt() {
+		t.Error("SetNonInteractive(true) did not disable prompts")
+	}
+}
+
+func TestPromptError(t *testing.T) {
+	SetNonInteractive(true)
+	defer SetNonInteractive(false)
+
+	err := PromptError("choosing a cluster", "name one of: dev, prod")
+	want := "choosing a cluster needs a prompt, but running non-interactively; name one of: dev, prod"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("PromptError() = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,226 @@
+// Package update checks whether a newer tunatap release is available.
+// Checks are rate-limited through a small state file, so that most runs do
+// not touch the network, and failures are silent.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultURL is the GitHub API endpoint of the latest release.
+	DefaultURL = "https://api.github.com/repos/scotttball/tunatap/releases/latest"
+
+	// DefaultInterval is how often the latest release is looked up.
+	DefaultInterval = 24 * time.Hour
+
+	// DefaultTimeout bounds a lookup, so that an offline machine is not
+	// held up.
+	DefaultTimeout = 3 * time.Second
+
+	// StateFileName is the name of the file remembering the last check.
+	StateFileName = "update-check.json"
+)
+
+// State is what the state file remembers between runs.
+type State struct {
+	// CheckedAt is when the latest release was last looked up, whether or
+	// not the lookup succeeded.
+	CheckedAt time.Time `json:"checked_at"`
+	// Latest is the version of the latest release found, if any.
+	Latest string `json:"latest,omitempty"`
+	// URL is the web page of that release.
+	URL string `json:"url,omitempty"`
+}
+
+// Checker looks up the latest release at most once per Interval.
+type Checker struct {
+	URL       string
+	StatePath string
+	Interval  time.Duration
+	Client    *http.Client
+
+	now func() time.Time
+}
+
+// NewChecker returns a checker of DefaultURL keeping its state in dir.
+func NewChecker(dir string) *Checker {
+	return &Checker{
+		URL:       DefaultURL,
+		StatePath: filepath.Join(dir, StateFileName),
+		Interval:  DefaultInterval,
+		Client:    &http.Client{Timeout: DefaultTimeout},
+		now:       time.Now,
+	}
+}
+
+// Check returns the latest release known, looking it up first if the last
+// lookup is older than the interval. The attempt is recorded before the
+// lookup, so that a machine without network access tries once per
+// interval rather than on every run.
+func (c *Checker) Check(ctx context.Context) (*State, error) {
+	state, _ := c.load()
+	if c.clock().Sub(state.CheckedAt) < c.Interval {
+		return state, nil
+	}
+
+	state.CheckedAt = c.clock()
+	if err := c.save(state); err != nil {
+		return nil, err
+	}
+
+	latest, url, err := c.fetch(ctx)
+	if err != nil {
+		return state, err
+	}
+	state.Latest, state.URL = latest, url
+	return state, c.save(state)
+}
+
+// Cached returns the latest release known without looking it up.
+func (c *Checker) Cached() *State {
+	state, _ := c.load()
+	return state
+}
+
+// fetch looks up the version and web page of the latest release.
+func (c *Checker) fetch(ctx context.Context) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.URL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up the latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to look up the latest release: HTTP %d", resp.StatusCode)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&release); err != nil {
+		return "", "", fmt.Errorf("invalid release response: %w", err)
+	}
+	if release.TagName == "" {
+		return "", "", fmt.Errorf("invalid release response: no tag_name")
+	}
+	return strings.TrimPrefix(release.TagName, "v"), release.HTMLURL, nil
+}
+
+// load reads the state file. A missing or unreadable file is an empty
+// state.
+func (c *Checker) load() (*State, error) {
+	state := &State{}
+	data, err := os.ReadFile(c.StatePath)
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return &State{}, err
+	}
+	return state, nil
+}
+
+// save writes the state file.
+func (c *Checker) save(state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.StatePath), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.StatePath, data, 0o600)
+}
+
+func (c *Checker) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// Newer reports whether latest is a later version than current. Versions
+// are dot-separated numbers, such as the CalVer 2026.01.2, optionally
+// starting with v; anything else, such as a dev build, is never out of
+// date.
+func Newer(current, latest string) bool {
+	cur, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	lat, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(cur) || i < len(lat); i++ {
+		var a, b int
+		if i < len(cur) {
+			a = cur[i]
+		}
+		if i < len(lat) {
+			b = lat[i]
+		}
+		if a != b {
+			return b > a
+		}
+	}
+	return false
+}
+
+// IsRelease reports whether v is a release version rather than, for
+// example, a dev build.
+func IsRelease(v string) bool {
+	_, ok := parseVersion(v)
+	return ok
+}
+
+// parseVersion splits a version into its numbers.
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if v == "" {
+		return nil, false
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// Notice is the message shown when latest is newer than current, or ""
+// if it is not.
+func Notice(current string, state *State) string {
+	if state == nil || !Newer(current, state.Latest) {
+		return ""
+	}
+	msg := fmt.Sprintf("A new version of tunatap is available: %s (you have %s)", state.Latest, strings.TrimPrefix(current, "v"))
+	if state.URL != "" {
+		msg += "\n" + state.URL
+	}
+	return msg
+}
@@ -0,0 +1,117 @@
+package update
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"2026.01.1", "2026.01.2", true},
+		{"2026.01.9", "2026.02.1", true},
+		{"v2026.01.1", "2026.01.1", false},
+		{"2026.03.1", "2026.02.5", false},
+		{"2026.01", "2026.01.1", true},
+		{"dev", "2026.01.1", false},
+		{"2026.01.1", "", false},
+		{"2026.01.1", "nightly", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestIsRelease(t *testing.T) {
+	for v, want := range map[string]bool{"2026.01.1": true, "v2026.01.1": true, "dev": false, "": false, "2026.01.1-rc1": false} {
+		if got := IsRelease(v); got != want {
+			t.Errorf("IsRelease(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestNotice(t *testing.T) {
+	state := &State{Latest: "2026.02.1", URL: "https://example.com/release"}
+	got := Notice("v2026.01.1", state)
+	if !strings.Contains(got, "2026.02.1 (you have 2026.01.1)") || !strings.Contains(got, state.URL) {
+		t.Errorf("Notice() = %q", got)
+	}
+	if got := Notice("2026.02.1", state); got != "" {
+		t.Errorf("Notice() = %q for the latest version, want none", got)
+	}
+	if got := Notice("2026.01.1", nil); got != "" {
+		t.Errorf("Notice() = %q without state, want none", got)
+	}
+}
+
+func TestCheckRateLimited(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"tag_name": "v2026.02.1", "html_url": "https://example.com/release"}`))
+	}))
+	defer srv.Close()
+
+	now := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+	c := NewChecker(t.TempDir())
+	c.URL = srv.URL
+	c.now = func() time.Time { return now }
+
+	state, err := c.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if state.Latest != "2026.02.1" || state.URL != "https://example.com/release" {
+		t.Errorf("Check() = %+v", state)
+	}
+
+	now = now.Add(time.Hour)
+	state, err = c.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if requests != 1 || state.Latest != "2026.02.1" {
+		t.Errorf("second Check() made %d requests, state %+v; want the cached release", requests, state)
+	}
+
+	now = now.Add(DefaultInterval)
+	if _, err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Check() after the interval made %d requests, want 2", requests)
+	}
+}
+
+func TestCheckOffline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	srv.Close()
+
+	now := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+	c := NewChecker(t.TempDir())
+	c.URL = srv.URL
+	c.now = func() time.Time { return now }
+
+	if _, err := c.Check(context.Background()); err == nil {
+		t.Fatal("Check() error = nil, want an error when offline")
+	}
+
+	// The failed attempt still counts, so the next run does not retry
+	state, err := c.Check(context.Background())
+	if err != nil {
+		t.Errorf("second Check() error = %v, want no retry", err)
+	}
+	if !state.CheckedAt.Equal(now) || state.Latest != "" {
+		t.Errorf("Cached state = %+v", state)
+	}
+}
@@ -0,0 +1,271 @@
+// Package tunatap opens tunnels through OCI Bastion from Go programs, using
+// the same configuration, discovery and reconnection logic as the tunatap CLI.
+//
+//	tun, err := tunatap.Connect(ctx, tunatap.ClusterRef{Name: "prod"}, tunatap.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	defer tun.Close()
+//
+//	select {
+//	case <-tun.Ready():
+//	case <-tun.Done():
+//		return tun.Err()
+//	}
+//	apiServer := fmt.Sprintf("https://localhost:%d", tun.Port())
+//
+// Logging goes through the global zerolog logger, as in the CLI.
+package tunatap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	"github.com/scotttball/tunatap/internal/audit"
+	"github.com/scotttball/tunatap/internal/bastion"
+	"github.com/scotttball/tunatap/internal/client"
+	"github.com/scotttball/tunatap/internal/cluster"
+	"github.com/scotttball/tunatap/internal/config"
+	"github.com/scotttball/tunatap/internal/secrets"
+	"github.com/scotttball/tunatap/internal/tunnel"
+)
+
+// ClusterRef identifies the cluster to connect to.
+type ClusterRef struct {
+	// Name is a cluster name from config, or a cluster name or OCID to
+	// discover in OCI.
+	Name string
+
+	// Region narrows discovery to a single region. Optional.
+	Region string
+
+	// Endpoint selects a named cluster endpoint. Default: the first one.
+	Endpoint string
+}
+
+// Options configures Connect. The zero value reads ~/.tunatap/config.yaml if
+// it exists and otherwise discovers the cluster (zero-touch mode).
+type Options struct {
+	// ConfigFile is the tunatap config file. Default: ~/.tunatap/config.yaml.
+	ConfigFile string
+
+	// LocalPort is the local port to listen on. Zero uses the cluster's
+	// local_port, or a free port if it has none.
+	LocalPort int
+
+	// OCIProfile overrides oci_profile from config.
+	OCIProfile string
+
+	// Bastion overrides the bastion name from config.
+	Bastion string
+
+	// NoCache skips the discovery cache.
+	NoCache bool
+
+	// MaxBandwidth caps the tunnel's total throughput in bytes per second,
+	// overriding the cluster's max_bandwidth. Zero keeps the config value.
+	MaxBandwidth int64
+
+	// HostKeyPolicy overrides ssh_host_key_policy from config. Embedded
+	// tunnels have no terminal, so under "prompt" unknown bastion host keys
+	// are rejected.
+	HostKeyPolicy string
+}
+
+// Tunnel is a tunnel started by Connect. It reconnects on failure, like the
+// CLI, until it is closed or its context is cancelled.
+type Tunnel struct {
+	cluster string
+
+	port      atomic.Int64
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	done   chan struct{}
+	err    error
+	cancel context.CancelFunc
+}
+
+// Connect resolves ref and starts a tunnel to it in the background. It
+// returns once the tunnel is starting; wait on Ready before using Port.
+// Cancelling ctx closes the tunnel.
+func Connect(ctx context.Context, ref ClusterRef, opts Options) (*Tunnel, error) {
+	configFile := opts.ConfigFile
+	if configFile == "" {
+		path, err := config.GetDefaultConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		configFile = path
+	}
+
+	cfg, cfgErr := config.ReadConfig(configFile)
+	if cfgErr != nil {
+		cfg = config.DefaultConfig()
+	} else if err := config.ConfigureGlobals(cfg); err != nil {
+		return nil, fmt.Errorf("failed to configure globals: %w", err)
+	}
+
+	if opts.OCIProfile != "" {
+		cfg.OCIProfile = opts.OCIProfile
+	}
+	if opts.HostKeyPolicy != "" {
+		cfg.SshHostKeyPolicy = opts.HostKeyPolicy
+	}
+
+	mode, err := tunnel.ParseHostKeyMode(cfg.SshHostKeyPolicy)
+	if err != nil {
+		return nil, err
+	}
+	policy := tunnel.DefaultHostKeyPolicy()
+	policy.Mode = mode
+	tunnel.SetHostKeyPolicy(policy)
+
+	resolver := secrets.NewResolver(func(region string) (secrets.VaultReader, error) {
+		return cluster.NewOCIClient(cfg, region)
+	})
+	passphrase, err := resolver.Resolve(ctx, cfg.SshPrivateKeyPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("ssh_private_key_passphrase: %w", err)
+	}
+	tunnel.SetKeyPassphrase(passphrase)
+	socksPassword, err := resolver.Resolve(ctx, cfg.SshSocksProxyPassword)
+	if err != nil {
+		return nil, fmt.Errorf("ssh_socks_proxy_password: %w", err)
+	}
+	tunnel.SetSocksAuth(cfg.SshSocksProxyUser, socksPassword)
+
+	selected, ociClient, err := cluster.Resolve(ctx, cfg, cfgErr == nil, ref.Name, ref.Region, opts.NoCache)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Bastion != "" {
+		selected.Bastion = &opts.Bastion
+	}
+
+	endpoint := config.GetClusterEndpoint(selected, ref.Endpoint)
+	if endpoint == nil {
+		return nil, fmt.Errorf("no endpoints configured for cluster '%s'", selected.ClusterName)
+	}
+
+	if ociClient == nil {
+		ociClient, err = cluster.NewClusterOCIClient(cfg, selected, selected.Region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OCI client: %w", err)
+		}
+	}
+
+	if err := cluster.ValidateAndUpdateCluster(ctx, ociClient, selected, true, opts.LocalPort); err != nil {
+		return nil, fmt.Errorf("failed to validate cluster: %w", err)
+	}
+
+	return start(ctx, selected.ClusterName, func(ctx context.Context, onReady bastion.ReadyCallback) error {
+		return runTunnel(ctx, ociClient, cfg, selected, endpoint, opts, onReady)
+	}), nil
+}
+
+// runTunnel runs the bastion tunnel until ctx is cancelled or it gives up.
+func runTunnel(ctx context.Context, ociClient *client.OCIClient, cfg *config.Config, c *config.Cluster, endpoint *config.ClusterEndpoint, opts Options, onReady bastion.ReadyCallback) error {
+	tunnelOpts := &bastion.TunnelOptions{
+		OnReady:      onReady,
+		MaxBandwidth: opts.MaxBandwidth,
+	}
+
+	if cfg.IsAuditLoggingEnabled() {
+		if auditLogger, err := audit.NewLogger(audit.DefaultLogDir()); err == nil {
+			defer auditLogger.Close()
+			if cfg.AuditHashChain {
+				key, err := audit.LoadOrCreateKey(audit.DefaultKeyPath())
+				if err == nil {
+					err = auditLogger.EnableHashChain(key)
+				}
+				if err != nil {
+					log.Warn().Err(err).Msg("Failed to enable the audit log hash chain; events are logged unchained")
+				}
+			}
+			resolver := secrets.NewResolver(func(region string) (secrets.VaultReader, error) {
+				return cluster.NewOCIClient(cfg, region)
+			})
+			deps := audit.SinkDeps{
+				Resolve: resolver.Resolve,
+				LogClient: func(region string) (audit.LogPutter, error) {
+					return cluster.NewOCIClient(cfg, region)
+				},
+			}
+			if err := auditLogger.AddSinks(ctx, cfg.AuditSinks, deps); err != nil {
+				log.Warn().Err(err).Msg("Failed to create audit sinks; events are still logged locally")
+			}
+			tunnelOpts.AuditLogger = auditLogger
+		}
+	}
+
+	return bastion.TunnelThroughBastionWithOptions(ctx, ociClient, cfg, c, endpoint, tunnelOpts)
+}
+
+// start runs fn in the background as the body of a new Tunnel.
+func start(ctx context.Context, clusterName string, fn func(ctx context.Context, onReady bastion.ReadyCallback) error) *Tunnel {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &Tunnel{
+		cluster: clusterName,
+		ready:   make(chan struct{}),
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+
+	go func() {
+		defer close(t.done)
+		err := fn(ctx, func(port int) {
+			// Called again after each reconnect; the port may change
+			t.port.Store(int64(port))
+			t.readyOnce.Do(func() { close(t.ready) })
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			t.err = err
+		}
+	}()
+
+	return t
+}
+
+// Cluster returns the name of the cluster the tunnel connects to.
+func (t *Tunnel) Cluster() string {
+	return t.cluster
+}
+
+// Ready is closed once the tunnel first accepts connections.
+func (t *Tunnel) Ready() <-chan struct{} {
+	return t.ready
+}
+
+// Port returns the local port the tunnel listens on, or 0 before Ready.
+func (t *Tunnel) Port() int {
+	return int(t.port.Load())
+}
+
+// Done is closed once the tunnel has stopped.
+func (t *Tunnel) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err returns why the tunnel stopped, or nil if it was closed. It is only
+// meaningful after Done is closed.
+func (t *Tunnel) Err() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the tunnel and waits for it to shut down.
+func (t *Tunnel) Close() error {
+	t.cancel()
+	<-t.done
+	return t.err
+}
@@ -0,0 +1,100 @@
+package tunatap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/scotttball/tunatap/internal/bastion"
+)
+
+func TestTunnelReadyAndClose(t *testing.T) {
+	tun := start(context.Background(), "prod", func(ctx context.Context, onReady bastion.ReadyCallback) error {
+		onReady(16443)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	select {
+	case <-tun.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("Ready() was not closed")
+	}
+
+	if tun.Port() != 16443 {
+		t.Errorf("Port() = %d, want %d", tun.Port(), 16443)
+	}
+	if tun.Cluster() != "prod" {
+		t.Errorf("Cluster() = %q, want %q", tun.Cluster(), "prod")
+	}
+	if err := tun.Err(); err != nil {
+		t.Errorf("Err() while running = %v, want nil", err)
+	}
+
+	if err := tun.Close(); err != nil {
+		t.Errorf("Close() error = %v, want nil", err)
+	}
+	select {
+	case <-tun.Done():
+	default:
+		t.Error("Done() should be closed after Close()")
+	}
+}
+
+func TestTunnelReconnectUpdatesPort(t *testing.T) {
+	tun := start(context.Background(), "prod", func(ctx context.Context, onReady bastion.ReadyCallback) error {
+		onReady(16443)
+		onReady(16444)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	defer tun.Close()
+
+	<-tun.Ready()
+	deadline := time.Now().Add(time.Second)
+	for tun.Port() != 16444 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if tun.Port() != 16444 {
+		t.Errorf("Port() = %d, want %d", tun.Port(), 16444)
+	}
+}
+
+func TestTunnelFailure(t *testing.T) {
+	failure := errors.New("max retry attempts exceeded")
+	tun := start(context.Background(), "prod", func(ctx context.Context, onReady bastion.ReadyCallback) error {
+		return failure
+	})
+
+	select {
+	case <-tun.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done() was not closed")
+	}
+
+	if !errors.Is(tun.Err(), failure) {
+		t.Errorf("Err() = %v, want %v", tun.Err(), failure)
+	}
+	if tun.Port() != 0 {
+		t.Errorf("Port() = %d, want 0 for a tunnel that never became ready", tun.Port())
+	}
+}
+
+func TestTunnelParentContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tun := start(ctx, "prod", func(ctx context.Context, onReady bastion.ReadyCallback) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	cancel()
+	select {
+	case <-tun.Done():
+	case <-time.After(time.Second):
+		t.Fatal("cancelling the parent context should stop the tunnel")
+	}
+	if err := tun.Err(); err != nil {
+		t.Errorf("Err() after cancel = %v, want nil", err)
+	}
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseBandwidth parses a bandwidth in bytes per second. It accepts a plain
+// number of bytes or a number with a K, M or G suffix (powers of 1024),
+// optionally followed by "B", "iB" and "/s", e.g. "512K", "10MB", "1GiB/s".
+// An empty string or "0" means unlimited and returns 0.
+func ParseBandwidth(s string) (int64, error) {
+	value := strings.TrimSpace(s)
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "/s"), "/S")
+	if value == "" {
+		return 0, nil
+	}
+
+	upper := strings.ToUpper(value)
+	upper = strings.TrimSuffix(upper, "IB")
+	upper = strings.TrimSuffix(upper, "B")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(upper, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(upper, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(upper, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		upper = upper[:len(upper)-1]
+	}
+
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q (examples: 512K, 10M, 1G)", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatBytes formats a byte count in a human-readable way, in powers of
+// 1024, e.g. "512B", "1.5KiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
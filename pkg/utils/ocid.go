@@ -81,6 +81,19 @@ func IsBastionOCID(ocid string) bool {
 	return parts != nil && parts.ResourceType == "bastion"
 }
 
+// IsCompartmentOCID checks if the OCID is for a compartment, or the tenancy
+// as the root compartment.
+func IsCompartmentOCID(ocid string) bool {
+	parts := ParseOCID(ocid)
+	return parts != nil && (parts.ResourceType == "compartment" || parts.ResourceType == "tenancy")
+}
+
+// IsInstanceOCID checks if the OCID is for a compute instance.
+func IsInstanceOCID(ocid string) bool {
+	parts := ParseOCID(ocid)
+	return parts != nil && parts.ResourceType == "instance"
+}
+
 // GetRealmDisplayName returns a human-readable name for the realm.
 func GetRealmDisplayName(realm string) string {
 	switch realm {
@@ -213,3 +213,44 @@ func TestIsBastionOCID(t *testing.T) {
 		})
 	}
 }
+
+func TestIsCompartmentOCID(t *testing.T) {
+	tests := []struct {
+		ocid string
+		want bool
+	}{
+		{"ocid1.compartment.oc1..aaa", true},
+		{"ocid1.tenancy.oc1..aaa", true},
+		{"ocid1.bastion.oc1.us-ashburn-1.aaa", false},
+		{"root/prod", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ocid, func(t *testing.T) {
+			result := IsCompartmentOCID(tt.ocid)
+			if result != tt.want {
+				t.Errorf("IsCompartmentOCID(%q) = %v, want %v", tt.ocid, result, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsInstanceOCID(t *testing.T) {
+	tests := []struct {
+		ocid string
+		want bool
+	}{
+		{"ocid1.instance.oc1.us-ashburn-1.aaa", true},
+		{"ocid1.bastion.oc1.us-ashburn-1.aaa", false},
+		{"my-instance", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ocid, func(t *testing.T) {
+			result := IsInstanceOCID(tt.ocid)
+			if result != tt.want {
+				t.Errorf("IsInstanceOCID(%q) = %v, want %v", tt.ocid, result, tt.want)
+			}
+		})
+	}
+}